@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -9,24 +11,45 @@ import (
 
 	"boop-airdrop-redeemer/pkg/api"
 	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
 	"boop-airdrop-redeemer/pkg/service"
 )
 
 func main() {
+	configFile, err := config.LoadFileFromFlags(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatalf("Invalid config file: %v", err)
+	}
+	flag.Parse()
+
 	// Create logger
-	logger := log.New(os.Stdout, "", log.LstdFlags)
+	closeLog, err := config.Bootstrap()
+	if err != nil {
+		log.Fatalf("Invalid logging or network configuration: %v", err)
+	}
+	defer closeLog()
+	logger := logging.For("airdrop")
+	if configFile != "" {
+		logger.Printf("Loaded configuration from %s", configFile)
+	}
 	logger.Println("Starting Boop Airdrop Redeemer...")
 
 	// Load configuration
 	cfg := config.NewConfig()
-	logger.Printf("Configured for wallet: %s", cfg.WalletAddress)
+	if cfg.WalletLabel != "" {
+		logger.SetPrefix(fmt.Sprintf("[%s] ", cfg.WalletLabel))
+	}
+	logger.Printf("Configured for wallet: %s (%s)", cfg.WalletName(), cfg.WalletAddress)
 	logger.Printf("Check interval: %s", cfg.CheckInterval)
 
 	// Create API client
 	boopClient := api.NewBoopClient(cfg, logger)
 
 	// Create airdrop store
-	store := service.NewInMemoryAirdropStore()
+	store, err := service.NewAirdropStore(cfg, logger)
+	if err != nil {
+		logging.Fatalf(logger, "Failed to open airdrop store: %v", err)
+	}
 
 	// Create airdrop monitor
 	monitor := service.NewAirdropMonitor(boopClient, store, cfg, logger)
@@ -37,7 +60,7 @@ func main() {
 
 	// Start the monitor
 	if err := monitor.Start(ctx); err != nil {
-		logger.Fatalf("Failed to start airdrop monitor: %v", err)
+		logging.Fatalf(logger, "Failed to start airdrop monitor: %v", err)
 	}
 
 	// Set up graceful shutdown
@@ -50,5 +73,8 @@ func main() {
 
 	// Stop the monitor
 	monitor.Stop()
+	if err := service.CloseAirdropStore(store); err != nil {
+		logging.Warnf(logger, "Warning: Failed to write airdrop store: %v", err)
+	}
 	logger.Println("Airdrop monitor stopped, goodbye!")
 }
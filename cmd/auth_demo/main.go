@@ -1,28 +1,44 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
 
 	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
 )
 
 func main() {
+	configFile, err := config.LoadFileFromFlags(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatalf("Invalid config file: %v", err)
+	}
+	flag.Parse()
+
 	// Check if private key is provided as argument
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: auth_demo <private-key>")
+	if flag.NArg() < 1 {
+		fmt.Println("Usage: auth_demo [--config config.yaml] <private-key>")
 		os.Exit(1)
 	}
 
-	privateKey := os.Args[1]
-	logger := log.New(os.Stdout, "AUTH DEMO: ", log.LstdFlags)
+	privateKey := flag.Arg(0)
+	closeLog, err := config.Bootstrap()
+	if err != nil {
+		log.Fatalf("Invalid logging or network configuration: %v", err)
+	}
+	defer closeLog()
+	logger := logging.For("auth-demo")
+	if configFile != "" {
+		logger.Printf("Loaded configuration from %s", configFile)
+	}
 
 	// Method 1: Using direct private key to tokens conversion
 	logger.Println("Method 1: Direct private key to tokens conversion")
-	privyAuth, privyToken, privyRefresh, err := config.GetPrivyTokensWithPrivateKey(privateKey, logger)
+	privyAuth, privyToken, privyRefresh, err := config.GetPrivyTokensWithPrivateKey(privateKey, config.LoadEndpoints(), logger)
 	if err != nil {
-		logger.Fatalf("Failed to authenticate: %v", err)
+		logging.Fatalf(logger, "Failed to authenticate: %v", err)
 	}
 
 	logger.Println("Successfully obtained Privy tokens")
@@ -32,9 +48,9 @@ func main() {
 
 	// Method 2: Using token manager
 	logger.Println("\nMethod 2: Using TokenManager")
-	tokenManager, err := config.NewTokenManagerWithPrivateKey(privateKey, logger)
+	tokenManager, err := config.NewTokenManagerWithPrivateKey(privateKey, config.LoadEndpoints(), logger)
 	if err != nil {
-		logger.Fatalf("Failed to create token manager: %v", err)
+		logging.Fatalf(logger, "Failed to create token manager: %v", err)
 	}
 
 	graphqlToken := tokenManager.GetAuthorizationHeader()
@@ -44,7 +60,7 @@ func main() {
 	logger.Println("\nMethod 3: Full configuration with private key")
 	cfg, err := config.NewConfigWithPrivateKey(privateKey)
 	if err != nil {
-		logger.Fatalf("Failed to create config: %v", err)
+		logging.Fatalf(logger, "Failed to create config: %v", err)
 	}
 
 	logger.Printf("Config initialized for wallet: %s", cfg.WalletAddress)
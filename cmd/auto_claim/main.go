@@ -2,33 +2,113 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"boop-airdrop-redeemer/pkg/autoclaim"
 	"boop-airdrop-redeemer/pkg/config"
-	"boop-airdrop-redeemer/pkg/notifications"
-	"boop-airdrop-redeemer/pkg/service"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/redeemer"
+	"boop-airdrop-redeemer/pkg/tenant"
 )
 
+// walletRun is one running auto claimer
+type walletRun struct {
+	logger   *log.Logger
+	redeemer *redeemer.Redeemer
+}
+
 func main() {
-	logger := log.New(os.Stdout, "AUTO-CLAIMER: ", log.LstdFlags)
+	configFile, err := config.LoadFileFromFlags(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatalf("Invalid config file: %v", err)
+	}
+	flag.Parse()
+
+	closeLog, err := config.Bootstrap()
+	if err != nil {
+		log.Fatalf("Invalid logging or network configuration: %v", err)
+	}
+	defer closeLog()
+	logger := logging.For("auto-claimer")
+	if configFile != "" {
+		logger.Printf("Loaded configuration from %s", configFile)
+	}
 	logger.Println("Starting Boop Auto Claimer Service...")
 
-	// Load configuration
+	// Create a context that we can cancel
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var runs []*walletRun
+	if tenantsFile := os.Getenv("TENANTS_FILE"); tenantsFile != "" {
+		tenants, err := tenant.Load(tenantsFile)
+		if err != nil {
+			logging.Fatalf(logger, "Failed to load tenants: %v", err)
+		}
+		logger.Printf("Operator mode: serving %d tenant(s) from %s", len(tenants), tenantsFile)
+		runs = startTenants(ctx, tenants, logger)
+	} else if keys := config.ReadWalletPrivateKeys(); len(keys) > 0 {
+		wallets, err := tenant.FromWallets(keys, splitList(os.Getenv("WALLET_LABELS")))
+		if err != nil {
+			logging.Fatalf(logger, "Failed to load wallets: %v", err)
+		}
+		logger.Printf("Multi-wallet mode: running %d wallet(s)", len(wallets))
+		runs = startTenants(ctx, wallets, logger)
+	} else {
+		runs = []*walletRun{startWallet(ctx, loadConfig(logger), logger)}
+	}
+
+	// The control API serves one wallet, several wallets would need one address each
+	if len(runs) == 1 {
+		api, err := autoclaim.NewControlAPI(runs[0].redeemer.Config(), runs[0].redeemer.Service(), runs[0].logger)
+		if err != nil {
+			logging.Fatalf(logger, "Failed to start the control API: %v", err)
+		}
+		api.Start(ctx)
+	} else if os.Getenv("CONTROL_API_ADDR") != "" {
+		logging.Warnf(logger, "WARNING: The control API serves a single wallet, it is disabled with several wallets")
+	}
+
+	// Create a channel to listen for OS signals
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	// Wait for termination signal
+	<-sigChan
+	logger.Println("Received termination signal. Shutting down...")
+	cancel()
+
+	// Let in-flight claims or sales finish before releasing resources
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelShutdown()
+	for _, run := range runs {
+		run.redeemer.Stop(shutdownCtx)
+	}
+	logger.Println("Resources cleaned up")
+}
+
+// loadConfig builds the single wallet configuration from the environment
+func loadConfig(logger *log.Logger) *config.Config {
 	var cfg *config.Config
 	var err error
 
-	// Check if wallet private key is provided in environment
-	privateKey := os.Getenv("WALLET_PRIVATE_KEY")
+	// Read the wallet private key from the environment, a hidden prompt or a unix socket
+	privateKey, err := config.ReadPrivateKey(os.Getenv("WALLET_KEY_SOURCE"), logger)
+	if err != nil {
+		logging.Fatalf(logger, "Failed to read private key: %v", err)
+	}
 	if privateKey != "" {
 		logger.Println("Private key found, initializing with private key authentication...")
 		cfg, err = config.NewConfigWithPrivateKey(privateKey)
 		if err != nil {
-			logger.Fatalf("Failed to initialize with private key: %v", err)
+			logging.Fatalf(logger, "Failed to initialize with private key: %v", err)
 		}
 		logger.Println("Successfully authenticated using wallet private key")
 	} else {
@@ -36,43 +116,58 @@ func main() {
 		cfg = config.NewConfig()
 	}
 
-	logger.Printf("Configured for wallet: %s", cfg.WalletAddress)
-	logger.Printf("Using minimum value threshold: $%.2f", cfg.MinimumUsdThreshold)
-
-	// Create Telegram notification client
-	telegramClient := notifications.NewTelegramClient(
-		cfg.TelegramBotToken,
-		cfg.TelegramChatID,
-		cfg.EnableTelegram,
-	)
-
-	// Create scanner and claimer services
-	store := service.NewInMemoryAirdropStore()
-	scanner := service.NewAirdropScanner(store, cfg, logger)
-	claimer := service.NewAirdropClaimer(store, cfg, logger, telegramClient)
+	if cfg.WalletLabel != "" {
+		// Tag every log line so output from several wallets can be told apart
+		logger.SetPrefix(fmt.Sprintf("AUTO-CLAIMER [%s]: ", cfg.WalletLabel))
+	}
+	return cfg
+}
 
-	// Create a context that we can cancel
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+// splitList splits a comma separated list, keeping empty entries so positions still line up
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	items := strings.Split(value, ",")
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items
+}
 
-	// Create a channel to listen for OS signals
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+// startTenants starts an isolated auto claimer for every tenant or wallet
+func startTenants(ctx context.Context, tenants []tenant.Tenant, logger *log.Logger) []*walletRun {
+	runs := make([]*walletRun, 0, len(tenants))
+	for _, t := range tenants {
+		cfg, err := t.Config()
+		if err != nil {
+			logging.Fatalf(logger, "Failed to configure tenant %s: %v", t.ID, err)
+		}
 
-	// Create auto claimer service
-	autoClaimService := autoclaim.NewService(cfg, scanner, claimer, telegramClient, logger)
+		tenantLogger := logging.With(logger, "wallet", t.Name())
+		runs = append(runs, startWallet(ctx, cfg, tenantLogger))
+	}
+	return runs
+}
 
-	// Run the auto claimer in a goroutine
-	go autoClaimService.Start(ctx)
+// startWallet creates the services for one wallet and runs its auto claimer in the background
+func startWallet(ctx context.Context, cfg *config.Config, logger *log.Logger) *walletRun {
+	logger.Printf("Configured for wallet: %s (%s)", cfg.WalletName(), cfg.WalletAddress)
 
-	// Wait for termination signal
-	<-sigChan
-	logger.Println("Received termination signal. Shutting down...")
-	cancel()
+	// Show the effective configuration so misconfigurations are caught before anything is claimed
+	logger.Println("Effective configuration:")
+	for _, line := range config.FormatSummary(cfg.Summary()) {
+		logger.Println(line)
+	}
 
-	// Clean up resources
-	claimer.CleanUp()
-	logger.Println("Resources cleaned up")
+	r, err := redeemer.New(redeemer.WithConfig(cfg), redeemer.WithLogger(logger))
+	if err != nil {
+		logging.Fatalf(logger, "Failed to create auto claimer: %v", err)
+	}
 
-	time.Sleep(2 * time.Second) // Give time for cleanup
+	// Run the auto claimer in the background, tied to the process context
+	if err := r.Start(ctx); err != nil {
+		logging.Fatalf(logger, "Failed to start auto claimer: %v", err)
+	}
+	return &walletRun{logger: logger, redeemer: r}
 }
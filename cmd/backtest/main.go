@@ -0,0 +1,204 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/backtest"
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/solana"
+	"boop-airdrop-redeemer/pkg/strategy"
+)
+
+func main() {
+	configFile, err := config.LoadFileFromFlags(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatalf("Invalid config file: %v", err)
+	}
+
+	closeLog, err := config.Bootstrap()
+	if err != nil {
+		log.Fatalf("Invalid logging or network configuration: %v", err)
+	}
+	defer closeLog()
+	logger := logging.For("backtest")
+	if configFile != "" {
+		logger.Printf("Loaded configuration from %s", configFile)
+	}
+
+	// Load configuration
+	cfg := config.NewConfig()
+
+	statsDir := flag.String("stats-dir", cfg.StatsDataDir, "Directory with recorded statistics and airdrop value history")
+	statsBackend := flag.String("stats-backend", cfg.StatsBackend, "Transaction stats backend: csv, sqlite or bolt")
+	period := flag.Duration("period", 30*24*time.Hour, "How far back to replay")
+	thresholds := flag.String("thresholds", strconv.FormatFloat(cfg.MinimumUsdThreshold, 'f', -1, 64), "Comma-separated claim thresholds in USD to compare")
+	stableMin := flag.Float64("stable-min", cfg.StableMinimumUsd, "Minimum USD value for stable price claims")
+	stableDuration := flag.Duration("stable-duration", cfg.StableDuration, "How long a value must be unchanged before a stable price claim")
+	feesSol := flag.Float64("fees-sol", 0, "Claim and swap fees per airdrop in SOL (0 = average from recorded stats)")
+	solPrice := flag.Float64("sol-price", 0, "SOL price in USD (0 = average from recorded outcomes)")
+	realization := flag.Float64("realization", 0, "Fraction of expected value realized when selling (0 = from recorded outcomes)")
+	tokenPerformance := flag.Bool("token-performance", false, "Show the per-token performance learned from history instead of replaying thresholds")
+	exportDecisions := flag.String("export-decisions", "", "Write the claim and sell decisions recorded in the period as CSV to this file (- for stdout) instead of replaying thresholds")
+	exportGains := flag.String("export-gains", "", "Write the gains realized on kept tokens sold in the period as CSV to this file (- for stdout) instead of replaying thresholds")
+	flag.Parse()
+
+	statsRecorder, err := solana.NewStatsRecorderWithBackend(*statsDir, *statsBackend)
+	if err != nil {
+		logging.Fatalf(logger, "Failed to open stats directory: %v", err)
+	}
+	defer statsRecorder.Close()
+
+	since := time.Now().Add(-*period)
+	if *exportDecisions != "" {
+		writeDecisions(statsRecorder, since, *exportDecisions, logger)
+		return
+	}
+	if *exportGains != "" {
+		writeGains(statsRecorder, since, *exportGains, logger)
+		return
+	}
+
+	history, err := statsRecorder.GetAirdropValues(since)
+	if err != nil {
+		logging.Fatalf(logger, "Failed to read airdrop value history: %v", err)
+	}
+	if len(history) == 0 {
+		logging.Fatalf(logger, "No airdrop value history in %s for the last %s, run the auto claimer to collect some", *statsDir, *period)
+	}
+
+	// Derive cost assumptions from what actually happened, unless overridden
+	transactions, err := statsRecorder.GetTransactions(since)
+	if err != nil {
+		logging.Warnf(logger, "Warning: Failed to read transactions: %v", err)
+	}
+	outcomes, err := statsRecorder.GetOutcomes(since)
+	if err != nil {
+		logging.Warnf(logger, "Warning: Failed to read outcomes: %v", err)
+	}
+
+	if *tokenPerformance {
+		printTokenPerformance(strategy.LearnTokenPerformance(history, outcomes, *stableDuration), logger)
+		return
+	}
+
+	costs := backtest.CostsFromStats(transactions, outcomes)
+	if *feesSol > 0 {
+		costs.FeesSolPerAirdrop = *feesSol
+	}
+	if *solPrice > 0 {
+		costs.SolPrice = *solPrice
+	}
+	if *realization > 0 {
+		costs.RealizationRatio = *realization
+	}
+	if costs.SolPrice <= 0 {
+		logging.Fatalf(logger, "SOL price unknown, no recorded outcomes to derive it from; pass -sol-price")
+	}
+
+	logger.Printf("Replaying %d observations since %s", len(history), since.Format("2006-01-02"))
+	logger.Printf("Assumptions - fees: %.6f SOL per airdrop, SOL price: $%.2f, realization: %.0f%%",
+		costs.FeesSolPerAirdrop, costs.SolPrice, costs.RealizationRatio*100)
+
+	for _, field := range strings.Split(*thresholds, ",") {
+		threshold, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			logging.Fatalf(logger, "Invalid threshold %q: %v", field, err)
+		}
+
+		rules := strategy.Strategy{
+			MinimumUsdThreshold: threshold,
+			StableMinimumUsd:    *stableMin,
+			StableDuration:      *stableDuration,
+		}
+
+		result := backtest.Run(history, rules, costs)
+
+		stableClaims := 0
+		for _, claim := range result.Claims {
+			if claim.Stable {
+				stableClaims++
+			}
+		}
+
+		logger.Printf("Threshold $%.2f: %d/%d airdrops claimed (%d stable), expected $%.2f, realized $%.2f, fees $%.2f, net $%.2f, unprofitable claims: %d",
+			threshold, len(result.Claims), result.Airdrops, stableClaims,
+			result.ExpectedUsd, result.RealizedUsd, result.FeesUsd, result.NetUsd, result.Unprofitable)
+	}
+}
+
+// printTokenPerformance logs the learned per-token performance, the fastest dumping tokens first
+func printTokenPerformance(model *strategy.TokenPerformanceModel, logger *log.Logger) {
+	logger.Printf("Token performance over a %.0f minute stability wait:", model.WaitMinutes)
+	for _, performance := range model.Sorted() {
+		action := "wait"
+		if performance.SkipStableWait {
+			action = "skip wait"
+		}
+		logger.Printf("  %-12s wait change %+6.1f%% (%d samples), claim+sell realized %.0f%% (%d), stable sell realized %.0f%% (%d) -> %s",
+			performance.Symbol, performance.AvgWaitChange*100, performance.WaitSamples,
+			performance.ClaimAndSellRatio*100, performance.ClaimAndSellCount,
+			performance.StableSellRatio*100, performance.StableSellCount, action)
+	}
+}
+
+// writeDecisions exports the decisions recorded since the given time as CSV to a file or stdout
+func writeDecisions(statsRecorder *solana.StatsRecorder, since time.Time, path string, logger *log.Logger) {
+	decisions, err := statsRecorder.GetDecisions(since)
+	if err != nil {
+		logging.Fatalf(logger, "Failed to read decisions: %v", err)
+	}
+
+	out := createOutput(path, logger)
+	defer out.Close()
+
+	if err := solana.ExportDecisions(out, decisions); err != nil {
+		logging.Fatalf(logger, "Failed to export decisions: %v", err)
+	}
+	if path != "-" {
+		logger.Printf("Exported %d decision(s) to %s", len(decisions), path)
+	}
+}
+
+// writeGains exports the gains realized since the given time as CSV to a file or stdout
+func writeGains(statsRecorder *solana.StatsRecorder, since time.Time, path string, logger *log.Logger) {
+	gains, err := statsRecorder.GetRealizedGains(since)
+	if err != nil {
+		logging.Fatalf(logger, "Failed to read realized gains: %v", err)
+	}
+
+	out := createOutput(path, logger)
+	defer out.Close()
+
+	if err := solana.ExportRealizedGains(out, gains); err != nil {
+		logging.Fatalf(logger, "Failed to export realized gains: %v", err)
+	}
+
+	if lots, err := statsRecorder.GetOpenCostBasisLots(); err == nil && len(lots) > 0 {
+		basis := 0.0
+		for _, lot := range lots {
+			basis += lot.BasisUsd
+		}
+		logger.Printf("%d kept lot(s) not sold yet, cost basis $%.2f", len(lots), basis)
+	}
+	if path != "-" {
+		logger.Printf("Exported %d realized gain(s) to %s", len(gains), path)
+	}
+}
+
+// createOutput creates the export file at path, stdout for "-"
+func createOutput(path string, logger *log.Logger) *os.File {
+	if path == "-" {
+		return os.Stdout
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		logging.Fatalf(logger, "Failed to create %s: %v", path, err)
+	}
+	return file
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"boop-airdrop-redeemer/pkg/config"
+)
+
+// Lists every supported option, as a table or as a JSON Schema for editors and config file validation
+func main() {
+	format := flag.String("format", "text", "Output format: text or json (JSON Schema)")
+	flag.Parse()
+
+	docs, err := config.Reference()
+	if err != nil {
+		log.Fatalf("Failed to build the option reference: %v", err)
+	}
+
+	switch *format {
+	case "text":
+		err = config.WriteReference(os.Stdout, docs)
+	case "json":
+		err = config.WriteSchema(os.Stdout, docs)
+	default:
+		log.Fatalf("Unknown format %q, expected text or json", *format)
+	}
+	if err != nil {
+		log.Fatalf("Failed to write the option reference: %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"golang.org/x/term"
+
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/keys"
+	"boop-airdrop-redeemer/pkg/logging"
+)
+
+func main() {
+	configFile, err := config.LoadFileFromFlags(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatalf("Invalid config file: %v", err)
+	}
+
+	out := flag.String("out", "wallet.key.age", "Encrypted key file to write")
+	flag.Parse()
+
+	closeLog, err := config.Bootstrap()
+	if err != nil {
+		log.Fatalf("Invalid logging or network configuration: %v", err)
+	}
+	defer closeLog()
+	logger := logging.For("encrypt-key")
+	if configFile != "" {
+		logger.Printf("Loaded configuration from %s", configFile)
+	}
+
+	// The key configured for the auto claimer is encrypted, or one entered on the terminal when there is none
+	source := os.Getenv("WALLET_KEY_SOURCE")
+	privateKey, err := config.ReadPrivateKey(source, logger)
+	if err == nil && privateKey == "" {
+		privateKey, err = config.ReadPrivateKey(config.KeySourcePrompt, logger)
+	}
+	if err != nil {
+		logging.Fatalf(logger, "Failed to read private key: %v", err)
+	}
+	key, err := keys.Parse(privateKey)
+	if err != nil {
+		logging.Fatalf(logger, "Failed to read private key: %v", err)
+	}
+
+	passphrase := readPassphrase(logger, "New passphrase: ")
+	if readPassphrase(logger, "Repeat passphrase: ") != passphrase {
+		logging.Fatalf(logger, "The passphrases do not match")
+	}
+	if passphrase == "" {
+		logging.Fatalf(logger, "The passphrase must not be empty")
+	}
+
+	encrypted, err := keys.Encrypt(key, passphrase)
+	if err != nil {
+		logging.Fatalf(logger, "Failed to encrypt key: %v", err)
+	}
+
+	// O_EXCL keeps an existing key file from being replaced by mistake
+	file, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		logging.Fatalf(logger, "Failed to create %s: %v", *out, err)
+	}
+	if _, err := file.Write(encrypted); err != nil {
+		logging.Fatalf(logger, "Failed to write %s: %v", *out, err)
+	}
+	if err := file.Close(); err != nil {
+		logging.Fatalf(logger, "Failed to write %s: %v", *out, err)
+	}
+
+	logger.Printf("Encrypted the key of wallet %s into %s", key.PublicKey(), *out)
+	logger.Printf("Set WALLET_ENCRYPTED_KEY_FILE=%s and remove the plain key from the environment", *out)
+}
+
+// readPassphrase asks for a passphrase on the terminal with echo disabled
+func readPassphrase(logger *log.Logger, prompt string) string {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		logging.Fatalf(logger, "Entering the passphrase requires an interactive terminal")
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		logging.Fatalf(logger, "Failed to read passphrase: %v", err)
+	}
+	return string(passphrase)
+}
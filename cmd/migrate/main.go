@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"boop-airdrop-redeemer/pkg/amount"
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/wallet"
+)
+
+func main() {
+	configFile, err := config.LoadFileFromFlags(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatalf("Invalid config file: %v", err)
+	}
+
+	closeLog, err := config.Bootstrap()
+	if err != nil {
+		log.Fatalf("Invalid logging or network configuration: %v", err)
+	}
+	defer closeLog()
+	logger := logging.For("migrate")
+	if configFile != "" {
+		logger.Printf("Loaded configuration from %s", configFile)
+	}
+
+	oldKeyFlag := flag.String("old-key", os.Getenv("WALLET_PRIVATE_KEY"), "Base58 private key of the wallet to migrate from")
+	newKeyFlag := flag.String("new-key", os.Getenv("NEW_WALLET_PRIVATE_KEY"), "Base58 private key of the wallet to migrate to")
+	rpcURL := flag.String("rpc", getEnv("SOLANA_RPC_URL", "https://api.mainnet-beta.solana.com"), "Solana RPC URL")
+	envFile := flag.String("env-file", "", "Run script or .env file to update with the new wallet settings")
+	dryRun := flag.Bool("dry-run", false, "Only list the assets that would be moved")
+	flag.Parse()
+
+	if *oldKeyFlag == "" || *newKeyFlag == "" {
+		logging.Fatalf(logger, "Both -old-key and -new-key (or WALLET_PRIVATE_KEY and NEW_WALLET_PRIVATE_KEY) are required")
+	}
+
+	oldWallet, err := solana.PrivateKeyFromBase58(*oldKeyFlag)
+	if err != nil {
+		logging.Fatalf(logger, "Invalid old private key: %v", err)
+	}
+	newWallet, err := solana.PrivateKeyFromBase58(*newKeyFlag)
+	if err != nil {
+		logging.Fatalf(logger, "Invalid new private key: %v", err)
+	}
+	if oldWallet.PublicKey().Equals(newWallet.PublicKey()) {
+		logging.Fatalf(logger, "Old and new keys belong to the same wallet")
+	}
+
+	logger.Printf("Migrating %s -> %s", oldWallet.PublicKey(), newWallet.PublicKey())
+
+	ctx := context.Background()
+	migrator := wallet.NewMigrator(rpc.New(*rpcURL), logger)
+
+	accounts, err := migrator.GetTokenAccounts(ctx, oldWallet.PublicKey())
+	if err != nil {
+		logging.Fatalf(logger, "Failed to list token accounts: %v", err)
+	}
+	logger.Printf("Found %d token account(s) to move and close", len(accounts))
+	for _, account := range accounts {
+		logger.Printf("  - %s: %d units (decimals %d)", account.Mint, account.Amount, account.Decimals)
+	}
+
+	if *dryRun {
+		logger.Println("Dry run, no transactions sent")
+		return
+	}
+
+	// Step 1: Move tokens first so the closed accounts' rent is included in the final SOL sweep
+	tokenSigs, err := migrator.SweepTokens(ctx, oldWallet, newWallet.PublicKey())
+	for _, sig := range tokenSigs {
+		logger.Printf("Token sweep confirmed: https://solscan.io/tx/%s", sig)
+	}
+	if err != nil {
+		logging.Fatalf(logger, "Token sweep stopped: %v", err)
+	}
+
+	// Step 2: Move the remaining SOL
+	solSig, lamports, err := migrator.SweepSol(ctx, oldWallet, newWallet.PublicKey())
+	if err != nil {
+		logging.Warnf(logger, "Warning: SOL sweep failed: %v", err)
+	} else {
+		logger.Printf("Moved %s: https://solscan.io/tx/%s", amount.Lamports(lamports), solSig)
+	}
+
+	// Step 3: Authenticate the new wallet with Privy
+	privyAuth, privyToken, privyRefresh, err := config.GetPrivyTokensWithPrivateKey(*newKeyFlag, config.LoadEndpoints(), logger)
+	if err != nil {
+		logging.Fatalf(logger, "Failed to authenticate new wallet with Privy: %v", err)
+	}
+	logger.Println("New wallet authenticated with Privy")
+
+	// Step 4: Point the configuration at the new wallet
+	if *envFile != "" {
+		err = config.UpdateEnvFile(*envFile, map[string]string{
+			"WALLET_PRIVATE_KEY":  *newKeyFlag,
+			"WALLET_ADDRESS":      newWallet.PublicKey().String(),
+			"PRIVY_AUTH":          privyAuth,
+			"PRIVY_TOKEN":         privyToken,
+			"PRIVY_REFRESH_TOKEN": privyRefresh,
+		})
+		if err != nil {
+			logging.Fatalf(logger, "Failed to update %s: %v", *envFile, err)
+		}
+		logger.Printf("Updated %s with the new wallet settings", *envFile)
+	} else {
+		logger.Printf("Set WALLET_PRIVATE_KEY to the new key and WALLET_ADDRESS=%s in your run script", newWallet.PublicKey())
+	}
+
+	logger.Println("Migration complete. Retire the old key.")
+}
+
+func getEnv(key, defaultValue string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/solana"
+)
+
+func main() {
+	configFile, err := config.LoadFileFromFlags(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatalf("Invalid config file: %v", err)
+	}
+
+	closeLog, err := config.Bootstrap()
+	if err != nil {
+		log.Fatalf("Invalid logging or network configuration: %v", err)
+	}
+	defer closeLog()
+	logger := logging.For("migrate-stats")
+	if configFile != "" {
+		logger.Printf("Loaded configuration from %s", configFile)
+	}
+
+	// Load configuration
+	cfg := config.NewConfig()
+
+	statsDir := flag.String("stats-dir", cfg.StatsDataDir, "Directory with the monthly transaction CSV files")
+	to := flag.String("to", solana.StatsBackendSQLite, "Backend to copy the transactions into")
+	flag.Parse()
+
+	if *to == solana.StatsBackendCSV {
+		logging.Fatalf(logger, "Transactions are already kept in CSV files, choose another backend with -to")
+	}
+
+	// Opening the recorder upgrades CSV files written by older versions before they are read
+	recorder, err := solana.NewStatsRecorder(*statsDir)
+	if err != nil {
+		logging.Fatalf(logger, "Failed to open stats directory: %v", err)
+	}
+	defer recorder.Close()
+
+	target, err := solana.OpenTransactionStore(*statsDir, *to)
+	if err != nil {
+		logging.Fatalf(logger, "Failed to open %s backend: %v", *to, err)
+	}
+	defer target.Close()
+
+	copied, err := solana.CopyTransactions(solana.NewCSVTransactionStore(*statsDir), target)
+	if err != nil {
+		logging.Fatalf(logger, "Migration stopped after %d transaction(s): %v", copied, err)
+	}
+
+	logger.Printf("Copied %d transaction(s) from %s into the %s backend, records already present were skipped",
+		copied, filepath.Join(*statsDir, "transactions_*.csv"), *to)
+	logger.Printf("Set STATS_BACKEND=%s to use it, the CSV files are left in place", *to)
+}
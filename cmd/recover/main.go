@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"boop-airdrop-redeemer/pkg/api"
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/journal"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/retry"
+	"boop-airdrop-redeemer/pkg/service"
+	"boop-airdrop-redeemer/pkg/solana"
+)
+
+func main() {
+	configFile, err := config.LoadFileFromFlags(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatalf("Invalid config file: %v", err)
+	}
+
+	closeLog, err := config.Bootstrap()
+	if err != nil {
+		log.Fatalf("Invalid logging or network configuration: %v", err)
+	}
+	defer closeLog()
+	logger := logging.For("recover")
+	if configFile != "" {
+		logger.Printf("Loaded configuration from %s", configFile)
+	}
+
+	// Load configuration
+	cfg := config.NewConfig()
+
+	journalPath := flag.String("journal", cfg.JournalPath, "Path to the operation journal")
+	statsDir := flag.String("stats-dir", cfg.StatsDataDir, "Directory to rebuild transaction statistics into")
+	statsBackend := flag.String("stats-backend", cfg.StatsBackend, "Transaction stats backend: csv, sqlite or bolt")
+	verify := flag.Bool("verify", true, "Verify journaled transactions on chain and use on-chain fees/earnings")
+	backfill := flag.Bool("backfill", false, "Also record claims the Boop API lists as claimed, including ones made outside the bot")
+	flag.Parse()
+
+	logger.Printf("Replaying journal %s into %s", *journalPath, *statsDir)
+
+	entries, err := journal.ReadEntries(*journalPath)
+	if err != nil {
+		logging.Fatalf(logger, "Failed to read journal: %v", err)
+	}
+
+	state := journal.Replay(entries)
+	logger.Printf("Replayed %d entries covering %d airdrop(s)", len(entries), len(state.Operations))
+
+	// Airdrops lost with the store are put back, so the bot does not claim or announce them again
+	if cfg.AirdropStore != "" && cfg.AirdropStore != service.StoreMemory {
+		store, err := service.NewAirdropStore(cfg, logger)
+		if err != nil {
+			logging.Fatalf(logger, "Failed to open airdrop store: %v", err)
+		}
+		restored := state.RestoreStore(store)
+		if err := service.CloseAirdropStore(store); err != nil {
+			logging.Fatalf(logger, "Failed to write airdrop store: %v", err)
+		}
+		logger.Printf("Restored %d airdrop(s) into the %s airdrop store", restored, cfg.AirdropStore)
+	}
+
+	statsRecorder, err := solana.NewStatsRecorderWithBackend(*statsDir, *statsBackend)
+	if err != nil {
+		logging.Fatalf(logger, "Failed to initialize stats recorder: %v", err)
+	}
+	defer statsRecorder.Close()
+
+	var solClient *rpc.Client
+	if *verify {
+		solClient = solana.NewRPCClient(cfg.SolanaRpcURL, retry.NewDefault(cfg.RetryMaxAttempts, cfg.RetryBudget, logger))
+	}
+
+	report, err := journal.Recover(state, statsRecorder, solClient, logger)
+	if err != nil {
+		logging.Fatalf(logger, "Recovery failed: %v", err)
+	}
+
+	logger.Printf("Recovery complete - claims restored: %d, sales restored: %d, already present: %d, unverified: %d",
+		report.ClaimsRestored, report.SalesRestored, report.AlreadyPresent, report.Unverified)
+
+	if *backfill {
+		claimed, err := api.NewBoopClient(cfg, logger).GetClaimedAirdrops(context.Background())
+		if err != nil {
+			logging.Fatalf(logger, "Failed to fetch claimed airdrops: %v", err)
+		}
+		// Claims are only verified on the chain with -verify
+		var verifier solana.RPCClient
+		if solClient != nil {
+			verifier = solClient
+		}
+		backfilled, err := journal.BackfillClaims(claimed, statsRecorder, verifier, logger)
+		if err != nil {
+			logging.Fatalf(logger, "Backfill failed: %v", err)
+		}
+		logger.Printf("Backfill complete - %d claimed airdrop(s) listed, claims recorded: %d, already present: %d, unverified: %d",
+			len(claimed), backfilled.ClaimsRestored, backfilled.AlreadyPresent, backfilled.Unverified)
+	}
+
+	for _, op := range state.SortedOperations() {
+		status := "pending"
+		switch {
+		case op.SellTx != "":
+			status = "sold"
+		case op.ClaimTx != "":
+			status = "claimed"
+		case op.ClaimFailed:
+			status = "claim failed"
+		}
+		logger.Printf("  - %s (%s): %s", op.AirdropID, op.TokenSymbol, status)
+	}
+}
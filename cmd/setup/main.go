@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	sln "github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"golang.org/x/term"
+
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/keys"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/notifications"
+	"boop-airdrop-redeemer/pkg/redact"
+	"boop-airdrop-redeemer/pkg/solana"
+)
+
+// publicRPCURL is the public mainnet endpoint, fine to start with but rate limited
+const publicRPCURL = "https://api.mainnet-beta.solana.com"
+
+// chatLinkTimeout is how long the wizard waits for the message linking the Telegram chat
+const chatLinkTimeout = 2 * time.Minute
+
+// wizard asks the setup questions on the terminal and collects the options of the config file
+type wizard struct {
+	in     *bufio.Reader
+	out    io.Writer
+	logger *log.Logger
+	dir    string            // Directory of the config file, files the wizard writes go next to it
+	values map[string]string // Options by environment variable name
+}
+
+func main() {
+	out := flag.String("out", "config.yaml", "Config file to write, an existing file is never replaced")
+	flag.Parse()
+
+	closeLog, err := config.Bootstrap()
+	if err != nil {
+		log.Fatalf("Invalid logging or network configuration: %v", err)
+	}
+	defer closeLog()
+	logger := logging.For("setup")
+
+	if _, err := os.Stat(*out); err == nil {
+		logging.Fatalf(logger, "%s already exists, choose another file with -out or remove it first", *out)
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		logging.Fatalf(logger, "The setup wizard requires an interactive terminal")
+	}
+
+	w := &wizard{
+		in:     bufio.NewReader(os.Stdin),
+		out:    os.Stdout,
+		logger: logger,
+		dir:    filepath.Dir(*out),
+		values: make(map[string]string),
+	}
+	fmt.Fprintln(w.out, "Boop airdrop redeemer setup. Press enter to accept the [default] of a question.")
+
+	w.setupWallet()
+	w.setupThreshold()
+	w.setupRPC()
+	w.setupTelegram()
+
+	if err := config.WriteFile(*out, w.values); err != nil {
+		logging.Fatalf(logger, "Failed to write %s: %v", *out, err)
+	}
+	fmt.Fprintf(w.out, "\nWrote %s. Start the auto claimer with:\n\n  go run ./cmd/auto_claim --config %s\n\n", *out, *out)
+	fmt.Fprintln(w.out, "It prints the effective configuration at startup, every option is listed in the README.")
+}
+
+// setupWallet imports the wallet key, or sets up a watch-only wallet from its address
+func (w *wizard) setupWallet() {
+	fmt.Fprintln(w.out, "\nWallet")
+	fmt.Fprintln(w.out, "  1. Use a solana-keygen keypair file")
+	fmt.Fprintln(w.out, "  2. Enter a private key or recovery phrase, stored encrypted with a passphrase")
+	fmt.Fprintln(w.out, "  3. Watch-only: enter the wallet address, nothing can be claimed until a key is added")
+
+	switch w.choice("Choice", 3, 2) {
+	case 1:
+		for {
+			path := w.ask("Keypair file", "")
+			key, err := keys.FromKeypairFile(path)
+			if err != nil {
+				fmt.Fprintf(w.out, "  %v\n", err)
+				continue
+			}
+			w.values["WALLET_KEYPAIR_FILE"] = path
+			fmt.Fprintf(w.out, "  Wallet %s\n", key.PublicKey())
+			return
+		}
+	case 2:
+		w.importKey()
+	default:
+		for {
+			address := w.ask("Wallet address", "")
+			if _, err := sln.PublicKeyFromBase58(address); err != nil {
+				fmt.Fprintf(w.out, "  Not a Solana address: %v\n", err)
+				continue
+			}
+			w.values["WALLET_ADDRESS"] = address
+			fmt.Fprintln(w.out, "  Without a key the redeemer signs in with PRIVY_TOKEN and PRIVY_REFRESH_TOKEN (see")
+			fmt.Fprintln(w.out, "  cmd/auth_demo) and cannot sign claims. Run the setup again to add a key later.")
+			return
+		}
+	}
+}
+
+// importKey reads a private key or recovery phrase without echo and writes it encrypted next to the config file
+func (w *wizard) importKey() {
+	var key sln.PrivateKey
+	for {
+		secret := strings.TrimSpace(w.askHidden("Private key (base58 or [..] byte array) or recovery phrase"))
+		var err error
+		if strings.Count(secret, " ") >= 11 {
+			key, err = keys.FromMnemonic(secret, "", "")
+		} else {
+			key, err = keys.Parse(secret)
+		}
+		if err == nil {
+			break
+		}
+		fmt.Fprintf(w.out, "  %v\n", err)
+	}
+	fmt.Fprintf(w.out, "  Wallet %s\n", key.PublicKey())
+
+	var passphrase string
+	for {
+		passphrase = w.askHidden("Passphrase for the key file")
+		if passphrase == "" {
+			fmt.Fprintln(w.out, "  The passphrase must not be empty")
+			continue
+		}
+		if w.askHidden("Repeat passphrase") != passphrase {
+			fmt.Fprintln(w.out, "  The passphrases do not match")
+			continue
+		}
+		break
+	}
+
+	encrypted, err := keys.Encrypt(key, passphrase)
+	if err != nil {
+		logging.Fatalf(w.logger, "Failed to encrypt key: %v", err)
+	}
+	path := filepath.Join(w.dir, "wallet.key.age")
+	// O_EXCL keeps an existing key file from being replaced by mistake
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		logging.Fatalf(w.logger, "Failed to create %s: %v", path, err)
+	}
+	if _, err := file.Write(encrypted); err != nil {
+		logging.Fatalf(w.logger, "Failed to write %s: %v", path, err)
+	}
+	if err := file.Close(); err != nil {
+		logging.Fatalf(w.logger, "Failed to write %s: %v", path, err)
+	}
+
+	w.values["WALLET_ENCRYPTED_KEY_FILE"] = path
+	fmt.Fprintf(w.out, "  Wrote %s, the auto claimer asks for the passphrase at startup\n", path)
+	fmt.Fprintln(w.out, "  (or set WALLET_KEY_PASSPHRASE for unattended runs)")
+}
+
+// setupThreshold asks for the USD value from which airdrops are claimed
+func (w *wizard) setupThreshold() {
+	fmt.Fprintln(w.out, "\nThreshold")
+	fmt.Fprintln(w.out, "  Airdrops worth less than this are skipped, claiming them would cost more in fees.")
+	for {
+		value := w.ask("Minimum USD value", "0.15")
+		threshold, err := strconv.ParseFloat(value, 64)
+		if err != nil || threshold < 0 {
+			fmt.Fprintln(w.out, "  Enter a USD amount such as 0.25")
+			continue
+		}
+		w.values["MINIMUM_USD_THRESHOLD"] = value
+		return
+	}
+}
+
+// setupRPC asks for the Solana RPC endpoint and checks that it answers
+func (w *wizard) setupRPC() {
+	fmt.Fprintln(w.out, "\nSolana RPC")
+	fmt.Fprintln(w.out, "  1. Public mainnet endpoint, rate limited and slow under load")
+	fmt.Fprintln(w.out, "  2. Your own endpoint (Helius, QuickNode, Triton, ...)")
+
+	url := publicRPCURL
+	for {
+		if w.choice("Choice", 2, 1) == 2 {
+			url = w.ask("RPC URL", "")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		slot, err := solana.NewRPCClient(url, nil).GetSlot(ctx, rpc.CommitmentFinalized)
+		cancel()
+		if err == nil {
+			fmt.Fprintf(w.out, "  Connected, finalized slot %d\n", slot)
+			break
+		}
+		fmt.Fprintf(w.out, "  The endpoint did not answer: %v\n", err)
+		if w.confirm("Keep it anyway", false) {
+			break
+		}
+		url = publicRPCURL
+	}
+	if url != publicRPCURL {
+		w.values["SOLANA_RPC_URL"] = url
+	}
+}
+
+// setupTelegram links a Telegram chat through the user's bot and sends a test message
+func (w *wizard) setupTelegram() {
+	fmt.Fprintln(w.out, "\nTelegram")
+	if !w.confirm("Send notifications to Telegram", true) {
+		return
+	}
+	fmt.Fprintln(w.out, "  Create a bot with @BotFather and paste its token.")
+
+	var client *notifications.TelegramClient
+	var username string
+	for {
+		token := strings.TrimSpace(w.askHidden("Bot token"))
+		redact.AddSecret(token)
+		client = notifications.NewTelegramClient(token, "", true)
+		var err error
+		if username, err = client.BotUsername(); err == nil {
+			break
+		}
+		fmt.Fprintf(w.out, "  Telegram rejected the token: %v\n", err)
+	}
+
+	for {
+		fmt.Fprintf(w.out, "  Send any message to @%s (or add it to a group and write there) to link the chat...\n", username)
+		chatID, err := client.WaitForChat(chatLinkTimeout)
+		if err != nil {
+			fmt.Fprintf(w.out, "  %v\n", err)
+			chatID = w.ask("Chat ID (empty to wait again)", "")
+			if chatID == "" {
+				continue
+			}
+		}
+		client.ChatID = chatID
+
+		err = client.SendMessage("✅ <b>Boop Airdrop Redeemer</b>\n\nThis chat is linked, notifications will arrive here.")
+		if err == nil && w.confirm(fmt.Sprintf("Sent a test message to chat %s, did it arrive", chatID), true) {
+			w.values["ENABLE_TELEGRAM"] = "true"
+			w.values["TELEGRAM_BOT_TOKEN"] = client.BotToken
+			w.values["TELEGRAM_CHAT_ID"] = chatID
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(w.out, "  Failed to send the test message: %v\n", err)
+		}
+		if !w.confirm("Try linking again", true) {
+			fmt.Fprintln(w.out, "  Telegram is left off, set TELEGRAM_BOT_TOKEN and TELEGRAM_CHAT_ID later.")
+			return
+		}
+	}
+}
+
+// ask reads an answer, fallback when it is empty
+func (w *wizard) ask(question, fallback string) string {
+	if fallback != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", question, fallback)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", question)
+	}
+	line, err := w.in.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		logging.Fatalf(w.logger, "Setup aborted: %v", err)
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer
+	}
+	return fallback
+}
+
+// askHidden reads an answer with echo disabled, for keys and tokens
+func (w *wizard) askHidden(question string) string {
+	fmt.Fprintf(w.out, "%s: ", question)
+	answer, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(w.out)
+	if err != nil {
+		logging.Fatalf(w.logger, "Setup aborted: %v", err)
+	}
+	return string(answer)
+}
+
+// choice reads the number of one of n options
+func (w *wizard) choice(question string, n, fallback int) int {
+	for {
+		answer, err := strconv.Atoi(w.ask(question, strconv.Itoa(fallback)))
+		if err == nil && answer >= 1 && answer <= n {
+			return answer
+		}
+		fmt.Fprintf(w.out, "  Enter a number from 1 to %d\n", n)
+	}
+}
+
+// confirm reads a yes or no answer
+func (w *wizard) confirm(question string, fallback bool) bool {
+	hint := "y/N"
+	if fallback {
+		hint = "Y/n"
+	}
+	for {
+		switch strings.ToLower(w.ask(question+" ("+hint+")", "")) {
+		case "":
+			return fallback
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+	}
+}
@@ -5,11 +5,17 @@ go 1.23.0
 toolchain go1.23.8
 
 require (
+	filippo.io/age v1.2.1
 	github.com/davecgh/go-spew v1.1.1
 	github.com/gagliardetto/binary v0.8.0
 	github.com/gagliardetto/solana-go v1.12.0
 	github.com/gagliardetto/treeout v0.1.4
+	github.com/mattn/go-sqlite3 v1.14.33
 	github.com/stretchr/testify v1.10.0
+	github.com/tyler-smith/go-bip39 v1.1.0
+	go.etcd.io/bbolt v1.4.3
+	golang.org/x/term v0.31.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -38,7 +44,5 @@ require (
 	golang.org/x/crypto v0.37.0 // indirect
 	golang.org/x/net v0.30.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
-	golang.org/x/term v0.31.0 // indirect
 	golang.org/x/time v0.11.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
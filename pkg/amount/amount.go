@@ -0,0 +1,215 @@
+// Package amount holds on-chain amounts as integers of their smallest unit and converts them exactly: lamports
+// of SOL and raw amounts of token mints. Formatting appends to a caller provided buffer, so hot paths such as
+// CSV writers and scan logs can format without allocating.
+package amount
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+const (
+	// SolDecimals is the number of decimals of SOL and wrapped SOL
+	SolDecimals = 9
+	// LamportsPerSol is the number of lamports in one SOL
+	LamportsPerSol = 1_000_000_000
+	// MaxDecimals is the most decimals formatted or parsed, 10^19 is the largest power of ten in a uint64
+	MaxDecimals = 19
+)
+
+// Exact formats every decimal of an amount, without trailing zeros, instead of a fixed number of places
+const Exact = -1
+
+// pow10 holds the powers of ten representable in a uint64
+var pow10 = [MaxDecimals + 1]uint64{
+	1, 10, 100, 1_000, 10_000, 100_000, 1_000_000, 10_000_000, 100_000_000, 1_000_000_000,
+	10_000_000_000, 100_000_000_000, 1_000_000_000_000, 10_000_000_000_000, 100_000_000_000_000,
+	1_000_000_000_000_000, 10_000_000_000_000_000, 100_000_000_000_000_000, 1_000_000_000_000_000_000,
+	10_000_000_000_000_000_000,
+}
+
+// Errors returned when parsing amounts
+var (
+	ErrSyntax   = errors.New("invalid amount")
+	ErrRange    = errors.New("amount out of range")
+	ErrDecimals = errors.New("amount has more decimals than the mint")
+)
+
+// Lamports is an amount of SOL in lamports
+type Lamports uint64
+
+// FromSol converts a SOL amount to lamports, rounded to the nearest lamport. Negative and NaN amounts are 0.
+func FromSol(sol float64) Lamports {
+	if !(sol > 0) {
+		return 0
+	}
+	lamports := math.Round(sol * LamportsPerSol)
+	if lamports >= math.MaxUint64 {
+		return math.MaxUint64
+	}
+	return Lamports(lamports)
+}
+
+// ParseSol parses a decimal SOL amount such as "0.000005" exactly
+func ParseSol(s string) (Lamports, error) {
+	value, err := ParseDecimal(s, SolDecimals)
+	return Lamports(value), err
+}
+
+// Sol returns the amount in SOL
+func (l Lamports) Sol() float64 {
+	return float64(l) / LamportsPerSol
+}
+
+// AppendSol appends the amount in SOL with the given number of decimal places, or Exact
+func (l Lamports) AppendSol(dst []byte, places int) []byte {
+	return AppendDecimal(dst, uint64(l), SolDecimals, places)
+}
+
+// FormatSol returns the amount in SOL with the given number of decimal places, or Exact
+func (l Lamports) FormatSol(places int) string {
+	var buf [32]byte
+	return string(l.AppendSol(buf[:0], places))
+}
+
+// String returns the exact amount followed by its unit, such as "0.000005 SOL"
+func (l Lamports) String() string {
+	var buf [40]byte
+	return string(append(l.AppendSol(buf[:0], Exact), " SOL"...))
+}
+
+// TokenAmount is a raw amount of a token together with the decimals of its mint
+type TokenAmount struct {
+	Raw      uint64
+	Decimals uint8
+}
+
+// ParseTokenAmount parses a raw amount, an integer in the smallest unit of the mint
+func ParseTokenAmount(raw string, decimals uint8) (TokenAmount, error) {
+	value, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return TokenAmount{}, fmt.Errorf("%w: %q", ErrRange, raw)
+		}
+		return TokenAmount{}, fmt.Errorf("%w: %q", ErrSyntax, raw)
+	}
+	return TokenAmount{Raw: value, Decimals: decimals}, nil
+}
+
+// Float returns the amount in whole tokens
+func (a TokenAmount) Float() float64 {
+	return float64(a.Raw) / math.Pow10(int(a.Decimals))
+}
+
+// Append appends the amount in whole tokens with the given number of decimal places, or Exact
+func (a TokenAmount) Append(dst []byte, places int) []byte {
+	return AppendDecimal(dst, a.Raw, a.Decimals, places)
+}
+
+// Format returns the amount in whole tokens with the given number of decimal places, or Exact
+func (a TokenAmount) Format(places int) string {
+	var buf [32]byte
+	return string(a.Append(buf[:0], places))
+}
+
+// String returns the exact amount in whole tokens
+func (a TokenAmount) String() string {
+	return a.Format(Exact)
+}
+
+// AppendDecimal appends raw, an integer amount with the given number of decimals, as a decimal number with
+// places decimal places, rounding half up. Exact appends every decimal without trailing zeros. Nothing is
+// allocated when dst has room for the result.
+func AppendDecimal(dst []byte, raw uint64, decimals uint8, places int) []byte {
+	// Mints with more decimals are shown to nineteen, the rest is dust
+	for decimals > MaxDecimals {
+		raw /= 10
+		decimals--
+	}
+
+	if places == Exact {
+		places = int(decimals)
+		for places > 0 && raw%pow10[int(decimals)-places+1] == 0 {
+			places--
+		}
+	}
+	if places < 0 {
+		places = 0
+	}
+
+	// Round to the requested places, extra places are padded with zeros below
+	shown := min(places, int(decimals))
+	if drop := int(decimals) - shown; drop > 0 {
+		scale := pow10[drop]
+		remainder := raw % scale
+		raw /= scale
+		if remainder >= scale-scale/2 {
+			raw++
+		}
+	}
+
+	unit := pow10[shown]
+	dst = strconv.AppendUint(dst, raw/unit, 10)
+	if places == 0 {
+		return dst
+	}
+
+	dst = append(dst, '.')
+	fraction := raw % unit
+	for i := shown - 1; i >= 0; i-- {
+		dst = append(dst, byte('0'+fraction/pow10[i]%10))
+	}
+	for i := shown; i < places; i++ {
+		dst = append(dst, '0')
+	}
+	return dst
+}
+
+// ParseDecimal parses a decimal number such as "12.5" into an integer amount with the given number of
+// decimals. Extra decimals are accepted only when they are zeros, so no amount is silently rounded.
+func ParseDecimal(s string, decimals uint8) (uint64, error) {
+	if decimals > MaxDecimals {
+		return 0, fmt.Errorf("%w: %d decimals", ErrRange, decimals)
+	}
+
+	var value uint64
+	digits, fractionDigits := 0, -1
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '.' && fractionDigits < 0:
+			fractionDigits = 0
+			continue
+		case c < '0' || c > '9':
+			return 0, fmt.Errorf("%w: %q", ErrSyntax, s)
+		}
+
+		digits++
+		if fractionDigits >= 0 {
+			if fractionDigits == int(decimals) {
+				if c != '0' {
+					return 0, fmt.Errorf("%w: %q", ErrDecimals, s)
+				}
+				continue
+			}
+			fractionDigits++
+		}
+
+		digit := uint64(c - '0')
+		if value > (math.MaxUint64-digit)/10 {
+			return 0, fmt.Errorf("%w: %q", ErrRange, s)
+		}
+		value = value*10 + digit
+	}
+	if digits == 0 {
+		return 0, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+
+	scale := pow10[int(decimals)-max(fractionDigits, 0)]
+	if value > math.MaxUint64/scale {
+		return 0, fmt.Errorf("%w: %q", ErrRange, s)
+	}
+	return value * scale, nil
+}
@@ -0,0 +1,105 @@
+package amount
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppendDecimal(t *testing.T) {
+	cases := []struct {
+		raw      uint64
+		decimals uint8
+		places   int
+		want     string
+	}{
+		{5000, 9, Exact, "0.000005"},
+		{5000, 9, 5, "0.00001"},
+		{5000, 9, 9, "0.000005000"},
+		{0, 9, Exact, "0"},
+		{0, 6, 2, "0.00"},
+		{1_500_000_000, 9, Exact, "1.5"},
+		{1_234_567, 6, 2, "1.23"},
+		{1_235_000, 6, 2, "1.24"},
+		{999_999, 6, 2, "1.00"},
+		{42, 0, 2, "42.00"},
+		{42, 0, Exact, "42"},
+		{1_999_999_999, 9, 0, "2"},
+		{math.MaxUint64, 9, Exact, "18446744073.709551615"},
+		{math.MaxUint64, 19, 2, "1.84"},
+		{math.MaxUint64, 20, Exact, "0.1844674407370955161"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, string(AppendDecimal(nil, c.raw, c.decimals, c.places)), "%d with %d decimals", c.raw, c.decimals)
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	cases := map[string]uint64{
+		"0.000000003":           3,
+		"0.000005000":           5000,
+		"1.5":                   1_500_000_000,
+		"12":                    12_000_000_000,
+		".25":                   250_000_000,
+		"3.":                    3_000_000_000,
+		"0.1234567890":          123_456_789,
+		"18446744073.709551615": math.MaxUint64,
+	}
+	for s, want := range cases {
+		got, err := ParseSol(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, Lamports(want), got, s)
+		}
+	}
+
+	for _, s := range []string{"", ".", "-1", "1e-9", "1.2.3", " 1"} {
+		_, err := ParseSol(s)
+		assert.ErrorIs(t, err, ErrSyntax, s)
+	}
+	_, err := ParseSol("0.0000000001")
+	assert.ErrorIs(t, err, ErrDecimals)
+	_, err = ParseSol("18446744074")
+	assert.ErrorIs(t, err, ErrRange)
+}
+
+func TestLamports(t *testing.T) {
+	assert.Equal(t, Lamports(5000), FromSol(0.000005))
+	assert.Equal(t, Lamports(3), FromSol(0.000000003))
+	assert.Equal(t, Lamports(0), FromSol(-1))
+	assert.Equal(t, Lamports(0), FromSol(math.NaN()))
+	assert.Equal(t, 0.000005, Lamports(5000).Sol())
+	assert.Equal(t, "0.000005 SOL", Lamports(5000).String())
+	assert.Equal(t, "0.00001", Lamports(5000).FormatSol(5))
+}
+
+func TestTokenAmount(t *testing.T) {
+	a, err := ParseTokenAmount("1234567", 6)
+	assert.NoError(t, err)
+	assert.Equal(t, TokenAmount{Raw: 1_234_567, Decimals: 6}, a)
+	assert.Equal(t, 1.234567, a.Float())
+	assert.Equal(t, "1.23", a.Format(2))
+	assert.Equal(t, "1.234567", a.String())
+
+	_, err = ParseTokenAmount("1.5", 6)
+	assert.ErrorIs(t, err, ErrSyntax)
+	_, err = ParseTokenAmount("99999999999999999999", 6)
+	assert.ErrorIs(t, err, ErrRange)
+}
+
+func TestAppendDoesNotAllocate(t *testing.T) {
+	buf := make([]byte, 0, 32)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = Lamports(123_456_789).AppendSol(buf[:0], 9)
+		buf = TokenAmount{Raw: 1_234_567, Decimals: 6}.Append(buf[:0], Exact)
+	})
+	assert.Zero(t, allocs)
+}
+
+func BenchmarkAppendSol(b *testing.B) {
+	buf := make([]byte, 0, 32)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = Lamports(i).AppendSol(buf[:0], 9)
+	}
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/models"
+)
+
+// airdropCacheVersion is the cache format written by this build, newer caches are ignored
+const airdropCacheVersion = 1
+
+// airdropCacheRewriteInterval is how long an unchanged cache is kept on disk before its fetch time is refreshed
+const airdropCacheRewriteInterval = 15 * time.Minute
+
+// cachedAirdrops is the on-disk format of the airdrop cache
+type cachedAirdrops struct {
+	Version  int                  `json:"version"`
+	CachedAt time.Time            `json:"cachedAt"`
+	Airdrops []models.AirdropNode `json:"airdrops"`
+}
+
+// AirdropCache keeps the last successfully fetched airdrops (including proofs) on disk,
+// so claims can continue from cached data while the Boop API is unavailable
+type AirdropCache struct {
+	path   string
+	maxAge time.Duration
+	data   cachedAirdrops
+	mu     sync.Mutex
+
+	// Digest of the airdrops on disk and when they were written, unchanged airdrops are not rewritten
+	written   [sha256.Size]byte
+	writtenAt time.Time
+}
+
+// NewAirdropCache creates a cache backed by the given file, loading any existing contents
+func NewAirdropCache(path string, maxAge time.Duration) *AirdropCache {
+	cache := &AirdropCache{
+		path:   path,
+		maxAge: maxAge,
+	}
+
+	if content, err := os.ReadFile(path); err == nil {
+		var data cachedAirdrops
+		// Caches without a version predate versioning and share the version 1 layout
+		if json.Unmarshal(content, &data) == nil && data.Version <= airdropCacheVersion {
+			cache.data = data
+		}
+	}
+
+	return cache
+}
+
+// Save replaces the cached airdrops and writes them to disk atomically. Airdrops equal to the ones on disk are
+// only rewritten every airdropCacheRewriteInterval, so the fetch time on disk lags by at most that interval.
+// The cache keeps a copy, callers may go on reading the airdrops while it changes.
+func (c *AirdropCache) Save(airdrops []models.AirdropNode) error {
+	encoded, err := json.Marshal(airdrops)
+	if err != nil {
+		return fmt.Errorf("failed to encode airdrop cache: %w", err)
+	}
+	digest := sha256.Sum256(encoded)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.data = cachedAirdrops{
+		Version:  airdropCacheVersion,
+		CachedAt: now,
+		Airdrops: append([]models.AirdropNode(nil), airdrops...),
+	}
+	if digest == c.written && now.Sub(c.writtenAt) < airdropCacheRewriteInterval {
+		return nil
+	}
+
+	if err := c.write(); err != nil {
+		return err
+	}
+	c.written, c.writtenAt = digest, now
+	return nil
+}
+
+// Load returns the cached airdrops and when they were fetched.
+// An error is returned when the cache is empty or older than the configured maximum age.
+func (c *AirdropCache) Load() ([]models.AirdropNode, time.Time, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.data.Airdrops) == 0 {
+		return nil, time.Time{}, fmt.Errorf("airdrop cache is empty")
+	}
+
+	if c.maxAge > 0 && time.Since(c.data.CachedAt) > c.maxAge {
+		return nil, c.data.CachedAt, fmt.Errorf("airdrop cache from %s is older than %s",
+			c.data.CachedAt.Format("2006-01-02 15:04:05"), c.maxAge)
+	}
+
+	airdrops := make([]models.AirdropNode, len(c.data.Airdrops))
+	copy(airdrops, c.data.Airdrops)
+	for i := range airdrops {
+		airdrops[i].FromCache = true
+	}
+
+	return airdrops, c.data.CachedAt, nil
+}
+
+// Remove drops an airdrop from the cache, e.g. after it has been claimed. The remaining airdrops go to a new
+// slice, the old one may still be read by a scan.
+func (c *AirdropCache) Remove(airdropID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	remaining := make([]models.AirdropNode, 0, len(c.data.Airdrops))
+	for _, airdrop := range c.data.Airdrops {
+		if airdrop.ID != airdropID {
+			remaining = append(remaining, airdrop)
+		}
+	}
+
+	if len(remaining) == len(c.data.Airdrops) {
+		return nil
+	}
+
+	c.data.Airdrops = remaining
+	c.written = [sha256.Size]byte{}
+	return c.write()
+}
+
+// write persists the cache through a temporary file so a crash never leaves a partial cache behind
+func (c *AirdropCache) write() error {
+	content, err := json.Marshal(c.data)
+	if err != nil {
+		return fmt.Errorf("failed to encode airdrop cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create airdrop cache directory: %w", err)
+	}
+
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write airdrop cache: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		return fmt.Errorf("failed to replace airdrop cache: %w", err)
+	}
+
+	return nil
+}
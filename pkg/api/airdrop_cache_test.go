@@ -0,0 +1,28 @@
+package api
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"boop-airdrop-redeemer/pkg/models"
+)
+
+func TestAirdropCacheRemoveKeepsCallerSlice(t *testing.T) {
+	cache := NewAirdropCache(filepath.Join(t.TempDir(), "airdrops.json"), time.Hour)
+	airdrops := []models.AirdropNode{{ID: "airdrop-1"}, {ID: "airdrop-2"}, {ID: "airdrop-3"}}
+	require.NoError(t, cache.Save(airdrops))
+
+	require.NoError(t, cache.Remove("airdrop-1"))
+	assert.Equal(t, []string{"airdrop-1", "airdrop-2", "airdrop-3"},
+		[]string{airdrops[0].ID, airdrops[1].ID, airdrops[2].ID}, "a scan reading the saved slice sees no shifts")
+
+	cached, _, err := cache.Load()
+	require.NoError(t, err)
+	require.Len(t, cached, 2)
+	assert.Equal(t, "airdrop-2", cached[0].ID)
+	assert.Equal(t, "airdrop-3", cached[1].ID)
+}
@@ -1,17 +1,21 @@
 package api
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
 	"boop-airdrop-redeemer/pkg/models"
+	"boop-airdrop-redeemer/pkg/network"
+	"boop-airdrop-redeemer/pkg/retry"
 )
 
 // BoopClient handles API communication with Boop API
@@ -19,22 +23,67 @@ type BoopClient struct {
 	config     *config.Config
 	httpClient *http.Client
 	logger     *log.Logger
+	cache      *AirdropCache
+	rateLimits *retry.RateLimits
+
+	schemaMu sync.Mutex
+	schema   map[string]*schemaType // Introspected types by name, nil when the schema has no such type
+
+	unpagedOnce sync.Once // Logs that airdrop lists are fetched without pagination
 }
 
-// NewBoopClient creates a new Boop API client
+// NewBoopClient creates a new Boop API client. Requests failing with network, rate limit or server errors are
+// retried with backoff.
 func NewBoopClient(cfg *config.Config, logger *log.Logger) *BoopClient {
+	var cache *AirdropCache
+	if cfg.AirdropCachePath != "" {
+		cache = NewAirdropCache(cfg.AirdropCachePath, cfg.AirdropCacheMaxAge)
+	}
+
+	retrier := retry.NewDefault(cfg.RetryMaxAttempts, cfg.RetryBudget, logger)
 	return &BoopClient{
 		config: cfg,
 		httpClient: &http.Client{
-			Timeout: 15 * time.Second,
+			Timeout:   15 * time.Second,
+			Transport: retry.NewTransport(network.Transport(network.Boop), retrier),
 		},
-		logger: logger,
+		logger:     logger,
+		cache:      cache,
+		rateLimits: retrier.RateLimits(),
 	}
 }
 
-// GetPendingAirdrops fetches all pending airdrops for the configured wallet
-func (c *BoopClient) GetPendingAirdrops(ctx context.Context) ([]models.AirdropNode, error) {
-	query := `
+// RateLimits returns the rate limit responses the client could not retry its way past
+func (c *BoopClient) RateLimits() *retry.RateLimits {
+	return c.rateLimits
+}
+
+// Pages of the wallet's airdrops
+const (
+	distributionPageSize = 500 // Airdrops requested per page
+	maxDistributionPages = 100 // Stops following cursors that never end
+)
+
+// accountDistributions lists a page of the wallet's airdrops, optionally filtered by claim status
+var accountDistributions = Operation{Name: "GetAccountDistributions", Query: `
+	query GetAccountDistributions($address: String!, $orderBy: StakingAirdropClaimSort, $status: StakingAirdropClaimStatus, $first: Int, $after: Cursor) {
+	  account(address: $address) {
+	    stakingAirdrops(orderBy: $orderBy, status: $status, first: $first, after: $after) {
+	      nodes {
+	        ...AccountAirdrop
+	      }
+	      pageInfo {
+	        hasNextPage
+	        endCursor
+	      }
+	    }
+	  }
+	}
+` + accountAirdropFragment}
+
+// unpagedAccountDistributions lists all of the wallet's airdrops in one response, for schemas whose airdrop list
+// takes no pagination arguments
+var unpagedAccountDistributions = Operation{Name: "GetAccountDistributions", Query: `
 	query GetAccountDistributions($address: String!, $orderBy: StakingAirdropClaimSort, $status: StakingAirdropClaimStatus) {
 	  account(address: $address) {
 	    stakingAirdrops(orderBy: $orderBy, status: $status) {
@@ -44,7 +93,10 @@ func (c *BoopClient) GetPendingAirdrops(ctx context.Context) ([]models.AirdropNo
 	    }
 	  }
 	}
+` + accountAirdropFragment}
 
+// accountAirdropFragment selects the fields of the wallet's airdrops
+const accountAirdropFragment = `
 	fragment AccountAirdrop on AccountStakingAirdrop {
 	  id
 	  amountLpt
@@ -62,120 +114,234 @@ func (c *BoopClient) GetPendingAirdrops(ctx context.Context) ([]models.AirdropNo
 	  }
 	}`
 
-	variables := map[string]string{
-		"address": c.config.WalletAddress,
-		"orderBy": "AMOUNT_DESC",
-		"status":  "PENDING", // Only look for pending claims
-	}
-
-	requestBody := models.GraphQLRequest{
-		Query:         query,
-		Variables:     variables,
-		OperationName: "GetAccountDistributions",
+// GetPendingAirdrops fetches all pending airdrops for the configured wallet
+func (c *BoopClient) GetPendingAirdrops(ctx context.Context) ([]models.AirdropNode, error) {
+	// Only look for pending claims
+	nodes, err := c.getAccountDistributions(ctx, statusPending)
+	if err != nil {
+		return nil, err
 	}
 
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, fmt.Errorf("error encoding request: %w", err)
+	// Keep the last known payload (with proofs) for claiming during outages
+	if c.cache != nil {
+		if err := c.cache.Save(nodes); err != nil {
+			logging.Warnf(c.logger, "Warning: Failed to update airdrop cache: %v", err)
+		}
 	}
 
-	return c.executeGraphQLRequest(ctx, jsonData)
+	return nodes, nil
 }
 
-// executeGraphQLRequest sends the GraphQL request and handles authentication
-func (c *BoopClient) executeGraphQLRequest(ctx context.Context, jsonData []byte) ([]models.AirdropNode, error) {
-	// Try with current auth token
-	nodes, err := c.doRequest(ctx, jsonData, false)
+// Claim statuses of the wallet's airdrops the client knows, the schema may list more
+const (
+	statusPending = "PENDING"
+	statusClaimed = "CLAIMED"
+	statusExpired = "EXPIRED"
+)
+
+// GetUpcomingAirdrops fetches the wallet's airdrops that are not claimable yet. When the schema lists the claim
+// statuses, only the airdrops in statuses other than pending, claimed and expired are fetched. Otherwise every
+// airdrop is fetched and the unclaimed ones are returned, expired and pending ones included, and exact is false.
+func (c *BoopClient) GetUpcomingAirdrops(ctx context.Context) ([]models.AirdropNode, bool, error) {
+	statusType, err := c.schemaType(ctx, "StakingAirdropClaimStatus")
 	if err != nil {
-		// If we get an auth error, try refreshing the token and retry
-		if isAuthError(err) && c.config.TokenManager != nil {
-			c.logger.Println("Authentication error, refreshing token and retrying...")
-			refreshErr := c.config.RefreshAuthToken()
-			if refreshErr != nil {
-				return nil, fmt.Errorf("failed to refresh auth token: %w", refreshErr)
+		logging.Debugf(c.logger, "Claim statuses unknown: %v", err)
+	}
+
+	var airdrops []models.AirdropNode
+	if statusType == nil {
+		all, err := c.getAccountDistributions(ctx, "")
+		if err != nil {
+			return nil, false, err
+		}
+		for _, airdrop := range all {
+			if airdrop.ClaimedAt == nil {
+				airdrops = append(airdrops, airdrop)
 			}
+		}
+		return airdrops, false, nil
+	}
 
-			// Retry with new token
-			return c.doRequest(ctx, jsonData, true)
+	for _, status := range statusType.enumValues() {
+		if status == statusPending || status == statusClaimed || status == statusExpired {
+			continue
 		}
-		return nil, err
+		nodes, err := c.getAccountDistributions(ctx, status)
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to fetch %s airdrops: %w", status, err)
+		}
+		airdrops = append(airdrops, nodes...)
 	}
+	return airdrops, true, nil
+}
 
-	return nodes, nil
+// GetClaimedAirdrops fetches the wallet's claimed airdrops with their claim time and transaction, including
+// claims made outside the bot
+func (c *BoopClient) GetClaimedAirdrops(ctx context.Context) ([]models.AirdropNode, error) {
+	return c.getAccountDistributions(ctx, statusClaimed)
 }
 
-// GraphQLErrorResponse represents a GraphQL response with errors
-type GraphQLErrorResponse struct {
-	Data struct {
-		Account interface{} `json:"account"`
-	} `json:"data"`
-	Errors []struct {
-		Message   string `json:"message"`
-		Locations []struct {
-			Line   int `json:"line"`
-			Column int `json:"column"`
-		} `json:"locations"`
-		Path []string `json:"path"`
-	} `json:"errors"`
-}
-
-// doRequest executes the HTTP request with proper authentication
-func (c *BoopClient) doRequest(ctx context.Context, jsonData []byte, isRetry bool) ([]models.AirdropNode, error) {
-	req, err := http.NewRequestWithContext(ctx, "POST", c.config.GraphQLURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
+// getAccountDistributions fetches every page of the wallet's airdrops with the given claim status, all of them
+// when status is empty. A page that fails fails the whole fetch, so callers never act on a partial list.
+func (c *BoopClient) getAccountDistributions(ctx context.Context, status string) ([]models.AirdropNode, error) {
+	variables := map[string]interface{}{
+		"address": c.config.WalletAddress,
+		"orderBy": "AMOUNT_DESC",
+	}
+	if status != "" {
+		variables["status"] = status
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	op := unpagedAccountDistributions
+	if c.paginates(ctx) {
+		op = accountDistributions
+		variables["first"] = distributionPageSize
+	}
 
-	// Use token manager if available
-	if c.config.TokenManager != nil {
-		req.Header.Set("Authorization", c.config.GetAuthToken())
-	} else {
-		req.Header.Set("Authorization", c.config.AuthToken)
+	var nodes []models.AirdropNode
+	for page := 1; ; page++ {
+		var data models.ResponseData
+		err := c.Execute(ctx, op, variables, &data)
+		pageNodes, err := c.validAirdrops(data, err)
+		if err != nil {
+			if page > 1 {
+				return nil, fmt.Errorf("failed to fetch page %d of airdrops: %w", page, err)
+			}
+			return nil, err
+		}
+		nodes = append(nodes, pageNodes...)
+
+		pageInfo := nextPage(data)
+		if pageInfo == nil {
+			return nodes, nil
+		}
+		if page == maxDistributionPages {
+			logging.Warnf(c.logger, "Warning: Stopped fetching airdrops after %d pages (%d airdrops)", page, len(nodes))
+			return nodes, nil
+		}
+		variables["after"] = pageInfo.EndCursor
 	}
+}
 
-	resp, err := c.httpClient.Do(req)
+// paginates reports whether the schema's airdrop list takes the first and after arguments. Lists are fetched in
+// one response as before pagination when it does not, or while the schema cannot be read.
+func (c *BoopClient) paginates(ctx context.Context) bool {
+	account, err := c.schemaType(ctx, "Account")
 	if err != nil {
-		return nil, fmt.Errorf("error executing request: %w", err)
+		logging.Debugf(c.logger, "Airdrop pagination unknown: %v", err)
+		return false
 	}
-	defer resp.Body.Close()
+	if !account.hasArgs("stakingAirdrops", "first", "after") {
+		c.unpagedOnce.Do(func() {
+			c.logger.Printf("The Boop API does not page airdrop lists, fetching them in one response")
+		})
+		return false
+	}
+	return true
+}
 
-	// Read the response body
-	bodyBytes := new(bytes.Buffer)
-	_, err = bodyBytes.ReadFrom(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %w", err)
+// nextPage returns the page info of a response with more pages, nil on the last page
+func nextPage(data models.ResponseData) *models.PageInfo {
+	if data.Account == nil {
+		return nil
+	}
+	pageInfo := data.Account.StakingAirdrops.PageInfo
+	if pageInfo == nil || !pageInfo.HasNextPage || pageInfo.EndCursor == "" {
+		return nil
 	}
+	return pageInfo
+}
 
-	// Check for HTTP error status codes
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d, response: %s", resp.StatusCode, bodyBytes.String())
+// GetCachedAirdrops returns the last successfully fetched pending airdrops and when they were fetched
+func (c *BoopClient) GetCachedAirdrops() ([]models.AirdropNode, time.Time, error) {
+	if c.cache == nil {
+		return nil, time.Time{}, fmt.Errorf("airdrop cache is disabled")
 	}
+	return c.cache.Load()
+}
 
-	// Check for GraphQL auth errors (which may come with 200 status code)
-	if hasGraphQLAuthError(bodyBytes.Bytes()) {
-		return nil, fmt.Errorf("GraphQL authorization error: %s", bodyBytes.String())
+// RemoveCachedAirdrop removes an airdrop from the cache so it is never claimed again from cached data
+func (c *BoopClient) RemoveCachedAirdrop(airdropID string) error {
+	if c.cache == nil {
+		return nil
 	}
+	return c.cache.Remove(airdropID)
+}
+
+// decodeAirdropResponse decodes the airdrops of a GraphQL response
+func (c *BoopClient) decodeAirdropResponse(body io.Reader) ([]models.AirdropNode, error) {
+	var data models.ResponseData
+	err := decodeResponse(body, &data)
+	return c.validAirdrops(data, err)
+}
 
-	// Parse the response for normal processing
-	var response models.GraphQLResponse
-	if err := json.Unmarshal(bodyBytes.Bytes(), &response); err != nil {
-		return nil, fmt.Errorf("error decoding response: %w", err)
+// validAirdrops returns the airdrops of a decoded response. Airdrops missing an id, token or amount are skipped
+// with a warning rather than tracked with zero values.
+func (c *BoopClient) validAirdrops(data models.ResponseData, err error) ([]models.AirdropNode, error) {
+	// GraphQL may return data alongside non-auth errors; only fail when there is nothing to use
+	var gqlErrs GraphQLErrors
+	if errors.As(err, &gqlErrs) {
+		c.logGraphQLErrors(gqlErrs)
+
+		if data.Account == nil {
+			return nil, fmt.Errorf("GraphQL request returned no data: %s", formatGraphQLErrors(gqlErrs))
+		}
+
+		c.logger.Printf("Using partial GraphQL response despite %d error(s)", len(gqlErrs))
+	} else if err != nil {
+		return nil, err
+	}
+
+	if data.Account == nil {
+		return nil, nil
 	}
 
-	return response.Data.Account.StakingAirdrops.Nodes, nil
+	nodes := make([]models.AirdropNode, 0, len(data.Account.StakingAirdrops.Nodes))
+	for _, node := range data.Account.StakingAirdrops.Nodes {
+		if err := node.Validate(); err != nil {
+			logging.Warnf(c.logger, "Warning: Skipping malformed airdrop: %v", err)
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
 }
 
-// hasGraphQLAuthError checks if the response contains GraphQL auth errors
-func hasGraphQLAuthError(responseBody []byte) bool {
-	var errorResp GraphQLErrorResponse
-	if err := json.Unmarshal(responseBody, &errorResp); err != nil {
-		return false
+// logGraphQLErrors logs each GraphQL error with its path and location
+func (c *BoopClient) logGraphQLErrors(errs []models.GraphQLError) {
+	for i, gqlErr := range errs {
+		path := make([]string, 0, len(gqlErr.Path))
+		for _, segment := range gqlErr.Path {
+			path = append(path, fmt.Sprint(segment))
+		}
+
+		location := ""
+		if len(gqlErr.Locations) > 0 {
+			location = fmt.Sprintf(" (line %d, column %d)", gqlErr.Locations[0].Line, gqlErr.Locations[0].Column)
+		}
+
+		code := ""
+		if value, ok := gqlErr.Extensions["code"]; ok {
+			code = fmt.Sprintf(" [%v]", value)
+		}
+
+		c.logger.Printf("GraphQL error %d/%d%s: %s, path=%s%s",
+			i+1, len(errs), code, gqlErr.Message, strings.Join(path, "."), location)
 	}
+}
+
+// formatGraphQLErrors joins GraphQL error messages into a single string
+func formatGraphQLErrors(errs []models.GraphQLError) string {
+	messages := make([]string, 0, len(errs))
+	for _, gqlErr := range errs {
+		messages = append(messages, gqlErr.Message)
+	}
+	return strings.Join(messages, "; ")
+}
 
-	// Check if we have errors related to authorization
-	for _, err := range errorResp.Errors {
+// hasGraphQLAuthError checks if the GraphQL errors include an authorization error
+func hasGraphQLAuthError(errs []models.GraphQLError) bool {
+	for _, err := range errs {
 		if strings.Contains(strings.ToLower(err.Message), "not authorized") ||
 			strings.Contains(strings.ToLower(err.Message), "unauthorized") {
 			return true
@@ -185,8 +351,8 @@ func hasGraphQLAuthError(responseBody []byte) bool {
 	return false
 }
 
-// isAuthError checks if the error is related to authentication
-func isAuthError(err error) bool {
+// IsAuthError checks if the error is related to authentication
+func IsAuthError(err error) bool {
 	if err == nil {
 		return false
 	}
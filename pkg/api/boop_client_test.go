@@ -0,0 +1,172 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/models"
+)
+
+// airdropResponse lists one valid airdrop and one without an amount
+const airdropResponse = `{"data": {"account": {"stakingAirdrops": {"nodes": [
+	{"id": "airdrop-1", "amountLpt": "1500000000", "amountUsd": "0.42", "proofs": [],
+	 "token": {"name": "Test", "address": "So11111111111111111111111111111111111111112", "symbol": "TEST"}},
+	{"id": "airdrop-2", "amountLpt": null, "token": {"address": "So11111111111111111111111111111111111111112"}}
+]}}}}`
+
+func newTestClient() *BoopClient {
+	return &BoopClient{logger: log.New(io.Discard, "", 0)}
+}
+
+func TestParseAirdropResponse(t *testing.T) {
+	client := newTestClient()
+
+	airdrops, err := client.decodeAirdropResponse(strings.NewReader(airdropResponse))
+	assert.NoError(t, err)
+	if assert.Len(t, airdrops, 1, "the airdrop without an amount is skipped") {
+		assert.Equal(t, "airdrop-1", airdrops[0].ID)
+	}
+
+	_, err = client.decodeAirdropResponse(strings.NewReader(`{"data": {"account": null}, "errors": [{"message": "boom", "path": ["account", 0]}]}`))
+	assert.Error(t, err)
+
+	airdrops, err = client.decodeAirdropResponse(strings.NewReader(`{"data": {"account": null}}`))
+	assert.NoError(t, err)
+	assert.Empty(t, airdrops)
+}
+
+func TestExecuteDecodesTypedData(t *testing.T) {
+	var request models.GraphQLRequest
+	responses := []string{
+		`{"data": {"markClaimed": {"id": "airdrop-1", "status": "CLAIMED"}}}`,
+		`{"data": {"markClaimed": null}, "errors": [{"message": "already claimed"}]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, "token", r.Header.Get("Authorization"))
+		io.WriteString(w, responses[0])
+		responses = responses[1:]
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{AuthToken: "token", Endpoints: config.Endpoints{GraphQLURL: server.URL}}
+	client := NewBoopClient(cfg, log.New(io.Discard, "", 0))
+	op := Operation{Name: "MarkClaimed", Query: `mutation MarkClaimed($id: ID!) { markClaimed(id: $id) { id status } }`}
+
+	var out struct {
+		MarkClaimed *struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+		} `json:"markClaimed"`
+	}
+	assert.NoError(t, client.Execute(context.Background(), op, map[string]interface{}{"id": "airdrop-1"}, &out))
+	assert.Equal(t, "MarkClaimed", request.OperationName)
+	assert.Equal(t, "airdrop-1", request.Variables["id"])
+	if assert.NotNil(t, out.MarkClaimed) {
+		assert.Equal(t, "CLAIMED", out.MarkClaimed.Status)
+	}
+
+	// Errors come back typed, so callers can tell them from transport failures
+	err := client.Execute(context.Background(), op, map[string]interface{}{"id": "airdrop-1"}, &out)
+	var gqlErrs GraphQLErrors
+	if assert.True(t, errors.As(err, &gqlErrs)) {
+		assert.Equal(t, "already claimed", gqlErrs[0].Message)
+	}
+	assert.False(t, IsAuthError(err))
+}
+
+func TestGetClaimedAirdropsFollowsPages(t *testing.T) {
+	pages := map[string]string{
+		"": `{"data": {"account": {"stakingAirdrops": {"nodes": [
+			{"id": "airdrop-1", "amountLpt": "1", "claimedAt": "2026-10-16T12:00:00Z", "txHash": "tx-1", "token": {"address": "So11111111111111111111111111111111111111112"}}
+		], "pageInfo": {"hasNextPage": true, "endCursor": "c1"}}}}}`,
+		"c1": `{"data": {"account": {"stakingAirdrops": {"nodes": [
+			{"id": "airdrop-2", "amountLpt": "2", "claimedAt": "2026-10-16T13:00:00Z", "txHash": "tx-2", "token": {"address": "So11111111111111111111111111111111111111112"}}
+		], "pageInfo": {"hasNextPage": false, "endCursor": "c2"}}}}}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request models.GraphQLRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		if request.OperationName == "TypeIntrospection" {
+			assert.Equal(t, "Account", request.Variables["name"])
+			io.WriteString(w, `{"data": {"__type": {"fields": [{"name": "stakingAirdrops", "args": [
+				{"name": "orderBy"}, {"name": "status"}, {"name": "first"}, {"name": "after"}]}]}}}`)
+			return
+		}
+		assert.Equal(t, "CLAIMED", request.Variables["status"])
+		after, _ := request.Variables["after"].(string)
+		io.WriteString(w, pages[after])
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{AuthToken: "token", Endpoints: config.Endpoints{GraphQLURL: server.URL}}
+	airdrops, err := NewBoopClient(cfg, log.New(io.Discard, "", 0)).GetClaimedAirdrops(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, airdrops, 2) {
+		assert.Equal(t, "tx-2", airdrops[1].ClaimTx())
+		claimedAt, ok := airdrops[1].ClaimTime()
+		assert.True(t, ok)
+		assert.Equal(t, 13, claimedAt.Hour())
+	}
+}
+
+func TestGetClaimedAirdropsWithoutPagination(t *testing.T) {
+	var fetches int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request models.GraphQLRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		if request.OperationName == "TypeIntrospection" {
+			io.WriteString(w, `{"data": null, "errors": [{"message": "introspection is disabled"}]}`)
+			return
+		}
+		// A schema that was not checked is only sent the arguments the list always took
+		fetches++
+		assert.NotContains(t, request.Variables, "first")
+		assert.NotContains(t, request.Query, "after:")
+		io.WriteString(w, `{"data": {"account": {"stakingAirdrops": {"nodes": [
+			{"id": "airdrop-1", "amountLpt": "1", "claimedAt": "2026-10-16T12:00:00Z", "txHash": "tx-1", "token": {"address": "So11111111111111111111111111111111111111112"}}
+		]}}}}`)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{AuthToken: "token", Endpoints: config.Endpoints{GraphQLURL: server.URL}}
+	client := NewBoopClient(cfg, log.New(io.Discard, "", 0))
+	for i := 0; i < 2; i++ {
+		airdrops, err := client.GetClaimedAirdrops(context.Background())
+		assert.NoError(t, err)
+		assert.Len(t, airdrops, 1)
+	}
+	assert.Equal(t, 2, fetches)
+}
+
+func FuzzParseAirdropResponse(f *testing.F) {
+	f.Add([]byte(airdropResponse))
+	f.Add([]byte(`{"data": null, "errors": [{"message": "not authorized", "locations": [{"line": 1}], "extensions": {"code": 1}}]}`))
+	f.Add([]byte(`{"data": {"account": {"stakingAirdrops": {"nodes": [null, {}]}}}, "errors": [null]}`))
+	f.Add([]byte(`[]`))
+
+	client := newTestClient()
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Any response body must be decoded or refused, never panic
+		airdrops, err := client.decodeAirdropResponse(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		for _, airdrop := range airdrops {
+			if verr := airdrop.Validate(); verr != nil {
+				t.Fatalf("malformed airdrop returned: %v", verr)
+			}
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"boop-airdrop-redeemer/pkg/models"
+	"boop-airdrop-redeemer/pkg/retry"
+)
+
+// Operation is a named GraphQL query or mutation of the Boop API
+type Operation struct {
+	Name  string // Operation name sent as operationName, must match the name in Query
+	Query string
+}
+
+// mutation reports whether the operation changes state, such as a claim, and must not be sent twice
+func (op Operation) mutation() bool {
+	return strings.HasPrefix(strings.TrimSpace(op.Query), "mutation")
+}
+
+// GraphQLErrors are the non-auth errors of a GraphQL response. Execute returns them after decoding whatever
+// data came along, callers that can use a partial response check for them with errors.As.
+type GraphQLErrors []models.GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	return "GraphQL request returned errors: " + formatGraphQLErrors(e)
+}
+
+// Execute sends a GraphQL query or mutation and decodes the data of the response into out. The request is
+// retried once with a refreshed token on auth errors.
+func (c *BoopClient) Execute(ctx context.Context, op Operation, variables map[string]interface{}, out interface{}) error {
+	requestBody := models.GraphQLRequest{
+		Query:         op.Query,
+		Variables:     variables,
+		OperationName: op.Name,
+	}
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return fmt.Errorf("error encoding %s request: %w", op.Name, err)
+	}
+
+	// Try with current auth token
+	idempotent := !op.mutation()
+	err = c.doRequest(ctx, jsonData, idempotent, out)
+	if err != nil && IsAuthError(err) && c.config.TokenManager != nil {
+		c.logger.Println("Authentication error, refreshing token and retrying...")
+		if refreshErr := c.config.RefreshAuthToken(); refreshErr != nil {
+			return fmt.Errorf("failed to refresh auth token: %w", refreshErr)
+		}
+
+		// Retry with new token
+		return c.doRequest(ctx, jsonData, idempotent, out)
+	}
+	return err
+}
+
+// doRequest executes the HTTP request with proper authentication. Only idempotent requests are retried on
+// server errors.
+func (c *BoopClient) doRequest(ctx context.Context, jsonData []byte, idempotent bool, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.config.Endpoints.GraphQLURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	c.config.Endpoints.SetOriginHeaders(req)
+	if idempotent {
+		retry.MarkIdempotent(req)
+	}
+
+	// Use token manager if available
+	if c.config.TokenManager != nil {
+		req.Header.Set("Authorization", c.config.GetAuthToken())
+	} else {
+		req.Header.Set("Authorization", c.config.AuthToken)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check for HTTP error status codes
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("error reading response body: %w", err)
+		}
+		return fmt.Errorf("unexpected status code: %d, response: %s", resp.StatusCode, body)
+	}
+
+	// Successful responses of wallets with thousands of airdrops are large, decode them while reading
+	return decodeResponse(resp.Body, out)
+}
+
+// decodeResponse decodes the data of a GraphQL response into out. Auth errors fail the response, other errors
+// are returned as GraphQLErrors after the data is decoded.
+func decodeResponse(body io.Reader, out interface{}) error {
+	// The data is decoded straight into out instead of being buffered
+	response := struct {
+		Data   interface{}           `json:"data"`
+		Errors []models.GraphQLError `json:"errors"`
+	}{Data: out}
+	if err := json.NewDecoder(body).Decode(&response); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+
+	// Check for GraphQL auth errors (which may come with 200 status code)
+	if hasGraphQLAuthError(response.Errors) {
+		return fmt.Errorf("GraphQL authorization error: %s", formatGraphQLErrors(response.Errors))
+	}
+
+	if len(response.Errors) > 0 {
+		return GraphQLErrors(response.Errors)
+	}
+	return nil
+}
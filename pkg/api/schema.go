@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// typeIntrospection reads the enum values and the fields with their arguments of a schema type
+var typeIntrospection = Operation{Name: "TypeIntrospection", Query: `
+	query TypeIntrospection($name: String!) {
+	  __type(name: $name) {
+	    enumValues {
+	      name
+	    }
+	    fields {
+	      name
+	      args {
+	        name
+	      }
+	    }
+	  }
+	}`}
+
+// schemaType is what the client checks of a schema type before relying on parts of the schema it was not
+// written against
+type schemaType struct {
+	EnumValues []struct {
+		Name string `json:"name"`
+	} `json:"enumValues"`
+	Fields []struct {
+		Name string `json:"name"`
+		Args []struct {
+			Name string `json:"name"`
+		} `json:"args"`
+	} `json:"fields"`
+}
+
+// enumValues returns the values of an enum type, none for other types
+func (t *schemaType) enumValues() []string {
+	if t == nil {
+		return nil
+	}
+	values := make([]string, 0, len(t.EnumValues))
+	for _, value := range t.EnumValues {
+		values = append(values, value.Name)
+	}
+	return values
+}
+
+// hasArgs reports whether a field of an object type takes all of the arguments
+func (t *schemaType) hasArgs(field string, args ...string) bool {
+	if t == nil {
+		return false
+	}
+	for _, f := range t.Fields {
+		if f.Name != field {
+			continue
+		}
+		names := make(map[string]bool, len(f.Args))
+		for _, arg := range f.Args {
+			names[arg.Name] = true
+		}
+		for _, arg := range args {
+			if !names[arg] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// schemaType introspects a type of the schema, nil when the schema has no such type or refuses introspection.
+// Types are read once per client, lookups that failed to reach the API are tried again on the next call.
+func (c *BoopClient) schemaType(ctx context.Context, name string) (*schemaType, error) {
+	c.schemaMu.Lock()
+	defer c.schemaMu.Unlock()
+	if t, ok := c.schema[name]; ok {
+		return t, nil
+	}
+
+	var data struct {
+		Type *schemaType `json:"__type"`
+	}
+	err := c.Execute(ctx, typeIntrospection, map[string]interface{}{"name": name}, &data)
+	var gqlErrs GraphQLErrors
+	if errors.As(err, &gqlErrs) {
+		c.logger.Printf("Schema introspection of %s refused: %s", name, formatGraphQLErrors(gqlErrs))
+		data.Type = nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to introspect %s: %w", name, err)
+	}
+	if c.schema == nil {
+		c.schema = make(map[string]*schemaType)
+	}
+	c.schema[name] = data.Type
+	return data.Type, nil
+}
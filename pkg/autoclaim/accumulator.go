@@ -0,0 +1,122 @@
+package autoclaim
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/models"
+	"boop-airdrop-redeemer/pkg/solana"
+)
+
+// Accumulator holds back the airdrops of tokens with an accumulation rule. Their drops are too small to be
+// worth a claim one by one, so they are collected until the pending drops of a token are worth the rule's
+// value together and its cool-down since the last round has passed, then all of them are released at once.
+// A round only counts once one of its claims succeeded, and the rounds are saved so a restart keeps the
+// cool-downs.
+type Accumulator struct {
+	rules  map[string]config.AccumulationRule
+	state  solana.StateStore
+	logger *log.Logger
+	now    func() time.Time
+
+	mu        sync.Mutex
+	lastRound map[string]time.Time // When a claim of each token's released drops last succeeded
+}
+
+// NewAccumulator creates an accumulator for the configured rules, nil when there are none. The last rounds
+// are read from state.
+func NewAccumulator(rules map[string]config.AccumulationRule, state solana.StateStore, logger *log.Logger) *Accumulator {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	lastRound := make(map[string]time.Time)
+	if _, err := state.Load(&lastRound); err != nil {
+		logging.Warnf(logger, "WARNING: Failed to read accumulation rounds, cool-downs start over: %v", err)
+		lastRound = make(map[string]time.Time)
+	}
+	return &Accumulator{
+		rules:     rules,
+		state:     state,
+		lastRound: lastRound,
+		logger:    logger,
+		now:       time.Now,
+	}
+}
+
+// Covers reports whether the token's claims are decided by an accumulation rule rather than one by one
+func (a *Accumulator) Covers(mint string) bool {
+	if a == nil {
+		return false
+	}
+	_, ok := a.rules[mint]
+	return ok
+}
+
+// Release returns the pending drops of every token whose rule is met, grouped by token, and logs the tokens
+// still accumulating. pending holds the unclaimed airdrops of covered tokens.
+func (a *Accumulator) Release(pending []models.AirdropNode) []models.AirdropNode {
+	if a == nil || len(pending) == 0 {
+		return nil
+	}
+
+	byMint := make(map[string][]models.AirdropNode)
+	var mints []string
+	for _, airdrop := range pending {
+		mint := airdrop.Token.Address
+		if _, seen := byMint[mint]; !seen {
+			mints = append(mints, mint)
+		}
+		byMint[mint] = append(byMint[mint], airdrop)
+	}
+	sort.Strings(mints)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var released []models.AirdropNode
+	now := a.now()
+	for _, mint := range mints {
+		drops, rule := byMint[mint], a.rules[mint]
+		symbol := drops[0].Token.Symbol
+
+		total := 0.0
+		for _, airdrop := range drops {
+			if usd, ok := airdrop.UsdValue(); ok {
+				total += usd
+			}
+		}
+
+		if total < rule.MinUsd {
+			a.logger.Printf("Accumulating %s: %d drop(s) worth $%.2f of $%.2f", symbol, len(drops), total, rule.MinUsd)
+			continue
+		}
+		if last, ok := a.lastRound[mint]; ok && now.Sub(last) < rule.Cooldown {
+			a.logger.Printf("Accumulating %s: %d drop(s) worth $%.2f, cool-down ends in %s",
+				symbol, len(drops), total, (rule.Cooldown - now.Sub(last)).Round(time.Second))
+			continue
+		}
+
+		a.logger.Printf("Claiming %d accumulated %s drop(s) worth $%.2f together", len(drops), symbol, total)
+		released = append(released, drops...)
+	}
+	return released
+}
+
+// Claimed starts the cool-down of the token of a claimed airdrop, nothing when the token has no rule
+func (a *Accumulator) Claimed(airdrop models.AirdropNode) {
+	if !a.Covers(airdrop.Token.Address) {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lastRound[airdrop.Token.Address] = a.now()
+	if err := a.state.Save(a.lastRound); err != nil {
+		logging.Warnf(a.logger, "Warning: Failed to save accumulation rounds: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package autoclaim
+
+import (
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/models"
+	"boop-airdrop-redeemer/pkg/solana"
+)
+
+func drop(id, mint string, usd float64) models.AirdropNode {
+	return models.AirdropNode{ID: id, AmountUsd: models.NewUSD(usd), Token: models.Token{Address: mint, Symbol: mint}}
+}
+
+func TestAccumulatorReleasesDropsTogether(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	rules := map[string]config.AccumulationRule{"often": {MinUsd: 0.5, Cooldown: time.Hour}}
+	state := solana.NewFileState(filepath.Join(t.TempDir(), "accumulation_rounds.json"))
+	accumulator := NewAccumulator(rules, state, log.New(io.Discard, "", 0))
+	accumulator.now = func() time.Time { return now }
+
+	assert.True(t, accumulator.Covers("often"))
+	assert.False(t, accumulator.Covers("other"))
+	assert.Nil(t, NewAccumulator(nil, nil, nil))
+	assert.False(t, (*Accumulator)(nil).Covers("often"))
+
+	// Held back until the drops add up to the rule's value
+	pending := []models.AirdropNode{drop("a", "often", 0.2), drop("b", "often", 0.2)}
+	assert.Empty(t, accumulator.Release(pending))
+
+	pending = append(pending, drop("c", "often", 0.15))
+	released := accumulator.Release(pending)
+	var ids []string
+	for _, airdrop := range released {
+		ids = append(ids, airdrop.ID)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, ids)
+
+	// A round whose claims all failed is released again
+	assert.Len(t, accumulator.Release(pending), 3)
+	accumulator.Claimed(released[0])
+
+	// The next round waits for the cool-down, even once enough has accumulated again and after a restart
+	pending = []models.AirdropNode{drop("d", "often", 0.4), drop("e", "often", 0.3)}
+	now = now.Add(30 * time.Minute)
+	assert.Empty(t, accumulator.Release(pending))
+	accumulator = NewAccumulator(rules, state, log.New(io.Discard, "", 0))
+	accumulator.now = func() time.Time { return now }
+	assert.Empty(t, accumulator.Release(pending))
+	now = now.Add(30 * time.Minute)
+	assert.Len(t, accumulator.Release(pending), 2)
+}
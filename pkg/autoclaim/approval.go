@@ -0,0 +1,152 @@
+package autoclaim
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/amount"
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/models"
+	"boop-airdrop-redeemer/pkg/notifications"
+)
+
+// approvalSender asks for claim approvals, implemented by the Telegram client
+type approvalSender interface {
+	SendApprovalRequest(key, tokenName, tokenSymbol string, tokens amount.TokenAmount, usdValue float64, timeout time.Duration, onAnswer func(notifications.ApprovalAnswer)) (*notifications.ApprovalRequest, error)
+	CloseApprovalRequest(request *notifications.ApprovalRequest, outcome string)
+}
+
+// Approvals holds back claims in approval mode until they are approved on Telegram. Each eligible airdrop is
+// asked about once. Approved airdrops are claimed by the next cycle that still finds them eligible, skipped
+// ones and those not answered within the timeout are not claimed.
+type Approvals struct {
+	sender  approvalSender // nil while Telegram is disabled, nothing is claimed then
+	timeout time.Duration
+	logger  *log.Logger
+	now     func() time.Time
+
+	mu       sync.Mutex
+	pending  map[string]pendingApproval
+	approved map[string]bool
+	skipped  map[string]bool
+}
+
+// pendingApproval is an approval request waiting for an answer
+type pendingApproval struct {
+	request *notifications.ApprovalRequest
+	asked   time.Time
+}
+
+// NewApprovals creates the approval gate, nil when approval mode is disabled
+func NewApprovals(cfg *config.Config, telegramClient *notifications.TelegramClient, logger *log.Logger) *Approvals {
+	if !cfg.ApprovalMode {
+		return nil
+	}
+
+	approvals := &Approvals{
+		timeout:  cfg.ApprovalTimeout,
+		logger:   logger,
+		now:      time.Now,
+		pending:  make(map[string]pendingApproval),
+		approved: make(map[string]bool),
+		skipped:  make(map[string]bool),
+	}
+	if telegramClient == nil || !telegramClient.Enabled {
+		logging.Warnf(logger, "WARNING: Approval mode needs Telegram, no airdrops are claimed until it is enabled")
+		return approvals
+	}
+	approvals.sender = telegramClient
+
+	logger.Printf("Approval mode enabled: claims wait for approval on Telegram, skipped after %s", cfg.ApprovalTimeout)
+	return approvals
+}
+
+// Filter returns the approved airdrops among the eligible ones and asks for approval of those not asked about
+// yet. Requests older than the timeout are closed and their airdrops skipped.
+func (a *Approvals) Filter(eligible []models.AirdropNode) []models.AirdropNode {
+	if a == nil {
+		return eligible
+	}
+
+	a.mu.Lock()
+	now := a.now()
+	var expired []*notifications.ApprovalRequest
+	for id, pending := range a.pending {
+		if now.Sub(pending.asked) >= a.timeout {
+			a.logger.Printf("Approval of airdrop %s not answered within %s, skipping it", id, a.timeout)
+			delete(a.pending, id)
+			a.skipped[id] = true
+			expired = append(expired, pending.request)
+		}
+	}
+
+	var approved, unasked []models.AirdropNode
+	for _, airdrop := range eligible {
+		_, pending := a.pending[airdrop.ID]
+		switch {
+		case a.approved[airdrop.ID]:
+			approved = append(approved, airdrop)
+		case a.skipped[airdrop.ID], pending:
+		default:
+			unasked = append(unasked, airdrop)
+		}
+	}
+	a.mu.Unlock()
+
+	for _, request := range expired {
+		a.sender.CloseApprovalRequest(request, "⌛ Not answered in time, skipped")
+	}
+	for _, airdrop := range unasked {
+		a.ask(airdrop)
+	}
+
+	return approved
+}
+
+// ask sends the approval request of an airdrop, it is asked again in the next cycle when sending fails
+func (a *Approvals) ask(airdrop models.AirdropNode) {
+	if a.sender == nil {
+		return
+	}
+
+	usdValue := airdrop.AmountUsd.Float()
+	request, err := a.sender.SendApprovalRequest(airdrop.ID, airdrop.Token.Name, airdrop.Token.Symbol,
+		airdrop.TokenAmount(), usdValue, a.timeout, a.answer)
+	if err != nil {
+		logging.Warnf(a.logger, "Warning: Failed to ask for approval of airdrop %s: %v", airdrop.ID, err)
+		return
+	}
+
+	a.mu.Lock()
+	a.pending[airdrop.ID] = pendingApproval{request: request, asked: a.now()}
+	a.mu.Unlock()
+	a.logger.Printf("Asked for approval to claim airdrop %s (%s) worth $%.2f", airdrop.ID, airdrop.Token.Symbol, usdValue)
+}
+
+// answer records the tap on a request's button and closes the request
+func (a *Approvals) answer(answer notifications.ApprovalAnswer) {
+	a.mu.Lock()
+	pending, ok := a.pending[answer.Key]
+	delete(a.pending, answer.Key)
+	if ok {
+		if answer.Answer == notifications.ApprovalClaim {
+			a.approved[answer.Key] = true
+		} else {
+			a.skipped[answer.Key] = true
+		}
+	}
+	a.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	if answer.Answer == notifications.ApprovalClaim {
+		a.logger.Printf("Claim of airdrop %s approved", answer.Key)
+		a.sender.CloseApprovalRequest(pending.request, "✅ Approved, claimed in the next cycle")
+	} else {
+		a.logger.Printf("Airdrop %s skipped on request", answer.Key)
+		a.sender.CloseApprovalRequest(pending.request, "⏭ Skipped")
+	}
+}
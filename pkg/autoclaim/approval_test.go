@@ -0,0 +1,59 @@
+package autoclaim
+
+import (
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"boop-airdrop-redeemer/pkg/amount"
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/models"
+	"boop-airdrop-redeemer/pkg/notifications"
+)
+
+// fakeApprovalSender records approval requests instead of sending them to Telegram
+type fakeApprovalSender struct {
+	asked    []string
+	onAnswer func(notifications.ApprovalAnswer)
+	closed   map[string]string
+}
+
+func (f *fakeApprovalSender) SendApprovalRequest(key, tokenName, tokenSymbol string, tokens amount.TokenAmount, usdValue float64, timeout time.Duration, onAnswer func(notifications.ApprovalAnswer)) (*notifications.ApprovalRequest, error) {
+	f.asked = append(f.asked, key)
+	f.onAnswer = onAnswer
+	return &notifications.ApprovalRequest{Key: key}, nil
+}
+
+func (f *fakeApprovalSender) CloseApprovalRequest(request *notifications.ApprovalRequest, outcome string) {
+	f.closed[request.Key] = outcome
+}
+
+func TestApprovalsClaimOnlyApprovedAirdrops(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	sender := &fakeApprovalSender{closed: make(map[string]string)}
+	approvals := NewApprovals(&config.Config{ApprovalMode: true, ApprovalTimeout: 30 * time.Minute}, nil, log.New(io.Discard, "", 0))
+	approvals.sender = sender
+	approvals.now = func() time.Time { return now }
+
+	eligible := []models.AirdropNode{drop("a", "mint", 1), drop("b", "mint", 1), drop("c", "mint", 1)}
+
+	// Every airdrop is asked about once, nothing is claimed before an answer
+	assert.Empty(t, approvals.Filter(eligible))
+	assert.Empty(t, approvals.Filter(eligible))
+	assert.Equal(t, []string{"a", "b", "c"}, sender.asked)
+
+	sender.onAnswer(notifications.ApprovalAnswer{Key: "a", Answer: notifications.ApprovalClaim})
+	sender.onAnswer(notifications.ApprovalAnswer{Key: "b", Answer: notifications.ApprovalSkip})
+	assert.Equal(t, []models.AirdropNode{eligible[0]}, approvals.Filter(eligible))
+
+	// Unanswered requests expire and are skipped
+	now = now.Add(time.Hour)
+	assert.Equal(t, []models.AirdropNode{eligible[0]}, approvals.Filter(eligible))
+	assert.Len(t, sender.closed, 3)
+	assert.Contains(t, sender.closed["c"], "Not answered")
+
+	assert.Equal(t, eligible, (*Approvals)(nil).Filter(eligible), "without approval mode everything is claimed")
+}
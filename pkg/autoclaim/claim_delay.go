@@ -0,0 +1,73 @@
+package autoclaim
+
+import (
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/models"
+)
+
+// ClaimDelay holds back claims of low and medium value airdrops for a random time within the configured window.
+// Many bots claim the moment a drop appears, which drives priority fees up for everyone; claiming a little
+// later at a random point trades immediacy for cheaper fees. Airdrops worth at least the urgent value are never
+// delayed. The delay is checked once per cycle, so it ends with the first cycle after it has passed.
+type ClaimDelay struct {
+	minDelay  time.Duration
+	maxDelay  time.Duration
+	urgentUsd float64
+	logger    *log.Logger
+	now       func() time.Time
+	random    func(n int64) int64
+
+	readyAt map[string]time.Time // When each delayed airdrop may be claimed
+}
+
+// NewClaimDelay creates the claim delay, nil when no delay window is configured
+func NewClaimDelay(cfg *config.Config, logger *log.Logger) *ClaimDelay {
+	if cfg.ClaimDelayMax <= 0 {
+		return nil
+	}
+
+	minDelay := min(max(cfg.ClaimDelayMin, 0), cfg.ClaimDelayMax)
+	logger.Printf("Randomized claim delay enabled: %s to %s for airdrops below $%.2f", minDelay, cfg.ClaimDelayMax, cfg.ClaimDelayUrgentUsd)
+	return &ClaimDelay{
+		minDelay:  minDelay,
+		maxDelay:  cfg.ClaimDelayMax,
+		urgentUsd: cfg.ClaimDelayUrgentUsd,
+		logger:    logger,
+		now:       time.Now,
+		random:    rand.Int64N,
+		readyAt:   make(map[string]time.Time),
+	}
+}
+
+// Hold reports whether the claim of the airdrop should wait. The first time an airdrop is seen its delay is
+// drawn from the window; it is held until that delay has passed and not again when a later cycle retries it.
+func (d *ClaimDelay) Hold(airdrop models.AirdropNode) bool {
+	if d == nil || airdrop.AmountUsd.Float() >= d.urgentUsd {
+		return false
+	}
+
+	now := d.now()
+	readyAt, ok := d.readyAt[airdrop.ID]
+	if !ok {
+		d.prune(now)
+		delay := d.minDelay + time.Duration(d.random(int64(d.maxDelay-d.minDelay)+1))
+		readyAt = now.Add(delay)
+		d.readyAt[airdrop.ID] = readyAt
+		d.logger.Printf("Delaying claim of %s ($%.2f) by %s", airdrop.Token.Symbol, airdrop.AmountUsd.Float(), delay.Round(time.Second))
+	}
+
+	return now.Before(readyAt)
+}
+
+// prune forgets delays that ended a day ago, their airdrops were claimed or are no longer eligible
+func (d *ClaimDelay) prune(now time.Time) {
+	for id, readyAt := range d.readyAt {
+		if now.Sub(readyAt) > 24*time.Hour {
+			delete(d.readyAt, id)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package autoclaim
+
+import (
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"boop-airdrop-redeemer/pkg/config"
+)
+
+func TestClaimDelayHoldsLowValueClaims(t *testing.T) {
+	cfg := &config.Config{ClaimDelayMin: time.Minute, ClaimDelayMax: 10 * time.Minute, ClaimDelayUrgentUsd: 5}
+	delay := NewClaimDelay(cfg, log.New(io.Discard, "", 0))
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	delay.now = func() time.Time { return now }
+	delay.random = func(n int64) int64 { return int64(2 * time.Minute) }
+
+	// Urgent airdrops are never held
+	assert.False(t, delay.Hold(drop("big", "mint", 5)))
+
+	assert.True(t, delay.Hold(drop("small", "mint", 1)))
+	now = now.Add(2 * time.Minute)
+	assert.True(t, delay.Hold(drop("small", "mint", 1)))
+	now = now.Add(time.Minute)
+	assert.False(t, delay.Hold(drop("small", "mint", 1)))
+
+	// A claim retried by a later cycle does not wait again
+	now = now.Add(time.Hour)
+	assert.False(t, delay.Hold(drop("small", "mint", 1)))
+
+	assert.Nil(t, NewClaimDelay(&config.Config{}, nil))
+	assert.False(t, (*ClaimDelay)(nil).Hold(drop("small", "mint", 1)))
+}
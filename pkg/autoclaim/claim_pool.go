@@ -0,0 +1,108 @@
+package autoclaim
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/jupiter"
+	"boop-airdrop-redeemer/pkg/models"
+	"boop-airdrop-redeemer/pkg/operation"
+	"boop-airdrop-redeemer/pkg/service"
+	"boop-airdrop-redeemer/pkg/solana"
+)
+
+// claimAirdrops claims the airdrops on up to ClaimConcurrency workers and returns once every claim is done.
+// Each worker waits ClaimPacing between its claims, so a cycle with many eligible airdrops does not send all
+// claims at once.
+func (s *Service) claimAirdrops(ctx context.Context, airdrops []models.AirdropNode) {
+	runClaimPool(ctx, airdrops, s.config.ClaimConcurrency, s.config.ClaimPacing, s.logger, s.claimEligibleAirdrop)
+}
+
+// runClaimPool calls claim for each airdrop on up to concurrency workers. claim reports whether it sent a
+// claim, only then does its worker wait pacing before the next one.
+func runClaimPool(ctx context.Context, airdrops []models.AirdropNode, concurrency int, pacing time.Duration, logger *log.Logger, claim func(context.Context, models.AirdropNode) bool) {
+	workers := min(max(concurrency, 1), len(airdrops))
+	if workers == 0 {
+		return
+	}
+	if workers > 1 {
+		logger.Printf("Claiming %d airdrop(s) on %d workers", len(airdrops), workers)
+	}
+
+	queue := make(chan models.AirdropNode)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			claimWorker(ctx, queue, pacing, logger, claim)
+		}()
+	}
+
+feed:
+	for _, airdrop := range airdrops {
+		select {
+		case queue <- airdrop:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// claimWorker claims airdrops from the queue until it is closed or ctx is cancelled
+func claimWorker(ctx context.Context, queue <-chan models.AirdropNode, pacing time.Duration, logger *log.Logger, claim func(context.Context, models.AirdropNode) bool) {
+	paced := false
+	for airdrop := range queue {
+		if paced {
+			logger.Printf("Waiting %s before claiming %s...", pacing, airdrop.Token.Symbol)
+			if !sleepWithContext(ctx, pacing) {
+				return
+			}
+		}
+		paced = claim(ctx, airdrop)
+	}
+}
+
+// claimEligibleAirdrop checks the airdrop once more right before claiming it and claims it, reporting whether
+// a claim was attempted
+func (s *Service) claimEligibleAirdrop(ctx context.Context, airdrop models.AirdropNode) bool {
+	if s.isClaimedOnChain(ctx, airdrop) {
+		s.audit.Record(airdrop, s.priceTracker.GetTokenPriceInfo(airdrop.ID), solana.DecisionSkip, "already claimed", checkOnChain)
+		return false
+	}
+
+	// The USD threshold ignores fees, skip airdrops whose quoted sale would not cover them by the margin.
+	// Accumulated drops were released for their combined net profit and are not judged one by one.
+	if !s.accumulator.Covers(airdrop.Token.Address) && !s.claimer.GetProfitCheck().Allows(airdrop) {
+		s.audit.Record(airdrop, s.priceTracker.GetTokenPriceInfo(airdrop.ID), solana.DecisionSkip, "net profit below margin", checkProfit)
+		return false
+	}
+
+	// Attempt to claim the airdrop, the claim, its retry and its result share one operation ID
+	claimCtx, _ := operation.Start(ctx)
+	claimCtx, trace := jupiter.TraceQuote(claimCtx)
+	txHash, err := s.claimer.ClaimAirdropByID(claimCtx, airdrop.ID)
+	// Refused claims mention tokens and are not auth errors, they are not retried
+	refused := errors.Is(err, service.ErrPoolUnderfunded) || errors.Is(err, service.ErrAlreadyClaimedOnChain) ||
+		errors.Is(err, service.ErrUnsupportedTokenProgram)
+	if err != nil && !refused {
+		// If error is auth-related, try refreshing the token and retry once
+		if strings.Contains(strings.ToLower(err.Error()), "unauthorized") ||
+			strings.Contains(strings.ToLower(err.Error()), "auth") ||
+			strings.Contains(strings.ToLower(err.Error()), "token") {
+			s.refreshAuthToken(ctx)
+			// Retry the claim after token refresh
+			txHash, err = s.claimer.ClaimAirdropByID(claimCtx, airdrop.ID)
+		}
+	}
+
+	s.handleClaimResult(claimCtx, airdrop, txHash, err)
+	s.audit.RecordQuote(airdrop, s.priceTracker.GetTokenPriceInfo(airdrop.ID), *trace)
+	return true
+}
@@ -0,0 +1,71 @@
+package autoclaim
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"boop-airdrop-redeemer/pkg/models"
+)
+
+func TestRunClaimPoolBoundsConcurrency(t *testing.T) {
+	airdrops := make([]models.AirdropNode, 10)
+	for i := range airdrops {
+		airdrops[i].ID = fmt.Sprint(i)
+	}
+
+	var running, peak atomic.Int32
+	var mu sync.Mutex
+	claimed := make(map[string]bool)
+	runClaimPool(context.Background(), airdrops, 3, time.Millisecond, log.New(io.Discard, "", 0), func(ctx context.Context, airdrop models.AirdropNode) bool {
+		now := running.Add(1)
+		for {
+			old := peak.Load()
+			if now <= old || peak.CompareAndSwap(old, now) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+
+		mu.Lock()
+		claimed[airdrop.ID] = true
+		mu.Unlock()
+		return true
+	})
+
+	assert.Len(t, claimed, len(airdrops))
+	assert.LessOrEqual(t, peak.Load(), int32(3))
+}
+
+func TestRunClaimPoolStopsWhenCancelled(t *testing.T) {
+	airdrops := make([]models.AirdropNode, 5)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var claims atomic.Int32
+	done := make(chan struct{})
+	go func() {
+		runClaimPool(ctx, airdrops, 1, time.Hour, log.New(io.Discard, "", 0), func(context.Context, models.AirdropNode) bool {
+			claims.Add(1)
+			return true
+		})
+		close(done)
+	}()
+
+	// The worker waits the pacing after its first claim, cancelling ends the wait and the pool
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pool did not stop")
+	}
+	assert.Equal(t, int32(1), claims.Load())
+}
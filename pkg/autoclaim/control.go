@@ -0,0 +1,120 @@
+package autoclaim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/jupiter"
+	"boop-airdrop-redeemer/pkg/models"
+	"boop-airdrop-redeemer/pkg/service"
+	"boop-airdrop-redeemer/pkg/solana"
+)
+
+// Errors returned by manual claims and sales
+var (
+	ErrCycleRunning   = errors.New("a scan cycle is running, retry when it is done")
+	ErrUnknownAirdrop = errors.New("airdrop not found")
+	ErrAlreadyClaimed = errors.New("airdrop already claimed")
+	ErrNotClaimed     = errors.New("airdrop not claimed yet")
+	ErrKeptToken      = errors.New("token is configured in KEEP_TOKENS and never sold")
+)
+
+// runCycle runs one scan cycle, holding off manual claims until it is done
+func (s *Service) runCycle(ctx context.Context) {
+	s.cycleMu.Lock()
+	defer s.cycleMu.Unlock()
+
+	s.processAirdrops(ctx)
+	s.lastCycle.Store(time.Now().Unix())
+}
+
+// Pause stops scanning, claiming and selling from the next cycle on, until Resume is called
+func (s *Service) Pause() {
+	if !s.paused.Swap(true) {
+		s.logger.Println("Paused, no airdrops are scanned or claimed until resumed")
+	}
+}
+
+// Resume restarts scan cycles after Pause
+func (s *Service) Resume() {
+	if s.paused.Swap(false) {
+		s.logger.Println("Resumed")
+	}
+}
+
+// Paused reports whether scan cycles are paused
+func (s *Service) Paused() bool {
+	return s.paused.Load()
+}
+
+// LastCycle returns when the last scan cycle finished, zero before the first
+func (s *Service) LastCycle() time.Time {
+	if unix := s.lastCycle.Load(); unix != 0 {
+		return time.Unix(unix, 0)
+	}
+	return time.Time{}
+}
+
+// Airdrops returns every airdrop seen by the scanner
+func (s *Service) Airdrops() []models.AirdropNode {
+	return s.scanner.Airdrops()
+}
+
+// ClaimNow claims a stored airdrop right away, whatever its value. It works while paused, the kill switch
+// still stops signing. It fails with ErrCycleRunning instead of waiting for a running cycle.
+func (s *Service) ClaimNow(ctx context.Context, airdropID string) (string, error) {
+	if !s.cycleMu.TryLock() {
+		return "", ErrCycleRunning
+	}
+	defer s.cycleMu.Unlock()
+
+	airdrop, ok := s.scanner.Airdrop(airdropID)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownAirdrop, airdropID)
+	}
+	if s.isAlreadyClaimed(airdrop) || s.isClaimedOnChain(ctx, airdrop) {
+		return "", fmt.Errorf("%w: %s", ErrAlreadyClaimed, airdropID)
+	}
+
+	s.logger.Printf("Manual claim of airdrop %s (%s) requested", airdrop.ID, airdrop.Token.Symbol)
+	ctx, trace := jupiter.TraceQuote(ctx)
+	txHash, err := s.claimer.ClaimAirdropByID(ctx, airdrop.ID)
+	s.handleClaimResult(ctx, airdrop, txHash, err)
+	s.audit.RecordQuote(airdrop, s.priceTracker.GetTokenPriceInfo(airdrop.ID), *trace)
+	return txHash, err
+}
+
+// SellNow sells the tokens of a claimed airdrop right away, for claims whose tokens were kept or whose sale
+// failed. Like ClaimNow it works while paused and fails with ErrCycleRunning instead of waiting.
+func (s *Service) SellNow(ctx context.Context, airdropID string) error {
+	if !s.cycleMu.TryLock() {
+		return ErrCycleRunning
+	}
+	defer s.cycleMu.Unlock()
+
+	airdrop, ok := s.scanner.Airdrop(airdropID)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownAirdrop, airdropID)
+	}
+	if airdrop.ClaimedAt == nil {
+		return fmt.Errorf("%w: %s", ErrNotClaimed, airdropID)
+	}
+	if s.config.KeepsToken(airdrop.Token.Address) {
+		return fmt.Errorf("%w: %s", ErrKeptToken, airdrop.Token.Symbol)
+	}
+
+	// A token whose sales are paused is still sold when asked for by hand
+	s.logger.Printf("Manual sale of airdrop %s (%s) requested", airdrop.ID, airdrop.Token.Symbol)
+	ctx, trace := jupiter.TraceQuote(service.ForceSale(ctx))
+	txHash, err := s.tokenSeller.SellToken(ctx, airdrop)
+	s.audit.RecordQuote(airdrop, s.priceTracker.GetTokenPriceInfo(airdrop.ID), *trace)
+	s.audit.RecordOutcome(airdrop, s.priceTracker.GetTokenPriceInfo(airdrop.ID), solana.DecisionSell, txHash, err)
+	return err
+}
+
+// SolPrice returns the current SOL price, false when no price service runs or it has no price yet
+func (s *Service) SolPrice() (solana.PriceQuote, bool) {
+	return s.tokenSeller.getSolPrice()
+}
@@ -0,0 +1,508 @@
+package autoclaim
+
+import (
+	"context"
+	"crypto/subtle"
+	"embed"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/amount"
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/service"
+	"boop-airdrop-redeemer/pkg/solana"
+)
+
+// dashboardFiles is the web dashboard served on top of the API. The page itself is public, it asks for the
+// API token and sends it with every API call.
+//
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// Limits of the history endpoints
+const (
+	defaultHistoryDays  = 30
+	maxHistoryDays      = 365
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+// ControlAPI serves the bot's status as JSON and a web dashboard, and accepts pause, resume and manual claim
+// and sale commands
+type ControlAPI struct {
+	addr    string
+	token   string
+	service *Service
+	logger  *log.Logger
+}
+
+// ErrControlAPIToken is returned when the control API is enabled without a token. Even on a loopback address,
+// any web page the user opens could otherwise send it commands.
+var ErrControlAPIToken = errors.New("CONTROL_API_TOKEN is required with CONTROL_API_ADDR")
+
+// NewControlAPI creates the control API, nil when no listen address is configured
+func NewControlAPI(cfg *config.Config, svc *Service, logger *log.Logger) (*ControlAPI, error) {
+	if cfg.ControlAPIAddr == "" {
+		return nil, nil
+	}
+	if cfg.ControlAPIToken == "" {
+		return nil, ErrControlAPIToken
+	}
+
+	return &ControlAPI{
+		addr:    cfg.ControlAPIAddr,
+		token:   cfg.ControlAPIToken,
+		service: svc,
+		logger:  logging.Component(logger, "control-api"),
+	}, nil
+}
+
+// Start serves the API in the background until ctx is cancelled
+func (a *ControlAPI) Start(ctx context.Context) {
+	if a == nil {
+		return
+	}
+
+	server := &http.Server{
+		Addr:              a.addr,
+		Handler:           a.Handler(ctx),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		a.logger.Printf("Control API listening on %s", a.addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Warnf(a.logger, "WARNING: Control API stopped: %v", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+}
+
+// Handler routes the API endpoints. Manual claims run with ctx, so they are not cut short when the
+// client disconnects.
+func (a *ControlAPI) Handler(ctx context.Context) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", a.handleDashboard("index.html"))
+	mux.HandleFunc("GET /dashboard.js", a.handleDashboard("dashboard.js"))
+	mux.HandleFunc("GET /health", a.handleHealth)
+	mux.HandleFunc("GET /airdrops", a.authorized(a.handleAirdrops))
+	mux.HandleFunc("GET /price/sol", a.authorized(a.handleSolPrice))
+	mux.HandleFunc("GET /stats/summary", a.authorized(a.handleStatsSummary))
+	mux.HandleFunc("GET /stats/profit", a.authorized(a.handleProfitHistory))
+	mux.HandleFunc("GET /stats/transactions", a.authorized(a.handleTransactions))
+	mux.HandleFunc("GET /stats/efficiency", a.authorized(a.handleEfficiency))
+	mux.HandleFunc("POST /claim/{id}", a.authorized(func(w http.ResponseWriter, r *http.Request) {
+		a.handleClaim(ctx, w, r)
+	}))
+	mux.HandleFunc("POST /sell/{id}", a.authorized(func(w http.ResponseWriter, r *http.Request) {
+		a.handleSell(ctx, w, r)
+	}))
+	mux.HandleFunc("POST /pause", a.authorized(a.handlePause))
+	mux.HandleFunc("POST /resume", a.authorized(a.handleResume))
+	return mux
+}
+
+// authorized requires the bearer token, and rejects requests sent by pages of other sites
+func (a *ControlAPI) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !sameOrigin(r) {
+			writeError(w, http.StatusForbidden, "cross-origin requests are not allowed")
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if a.token == "" || !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// sameOrigin reports whether a request comes from the dashboard or a script, browsers send the Origin of the
+// page with cross-site and POST requests
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	return err == nil && parsed.Host == r.Host
+}
+
+// handleDashboard serves a file of the web dashboard. The page only loads its own script and calls this API.
+func (a *ControlAPI) handleDashboard(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; style-src 'self' 'unsafe-inline'; frame-ancestors 'none'")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		http.ServeFileFS(w, r, dashboardFiles, "dashboard/"+name)
+	}
+}
+
+// healthResponse is the body of GET /health
+type healthResponse struct {
+	Status     string     `json:"status"` // ok, paused, stopped (kill switch) or stale
+	Paused     bool       `json:"paused"`
+	KillSwitch string     `json:"killSwitch,omitempty"` // Reason while the kill switch is engaged
+	LastCycle  *time.Time `json:"lastCycle,omitempty"`
+	Uptime     int64      `json:"uptimeSeconds"`
+}
+
+// handleHealth reports whether the bot is running. It answers 503 when no cycle finished for three check
+// intervals, so uptime monitors notice a stuck bot.
+func (a *ControlAPI) handleHealth(w http.ResponseWriter, r *http.Request) {
+	health := healthResponse{
+		Status: "ok",
+		Paused: a.service.Paused(),
+		Uptime: int64(time.Since(a.service.startedAt).Seconds()),
+	}
+	if last := a.service.LastCycle(); !last.IsZero() {
+		health.LastCycle = &last
+	}
+
+	status := http.StatusOK
+	if err := a.service.claimer.GetKillSwitch().Guard(); err != nil {
+		health.Status = "stopped"
+		health.KillSwitch = strings.TrimPrefix(err.Error(), service.ErrKillSwitchEngaged.Error()+": ")
+	} else if health.Paused {
+		health.Status = "paused"
+	} else if health.LastCycle != nil && time.Since(*health.LastCycle) > 3*a.service.config.CheckInterval+time.Minute {
+		health.Status = "stale"
+		status = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, status, health)
+}
+
+// airdropResponse is one airdrop in GET /airdrops
+type airdropResponse struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Symbol   string   `json:"symbol"`
+	Mint     string   `json:"mint"`
+	Amount   float64  `json:"amount"`             // Whole tokens
+	UsdValue *float64 `json:"usdValue,omitempty"` // Missing when the API could not price the token
+	SolValue *float64 `json:"solValue,omitempty"`
+	Claimed  bool     `json:"claimed"`
+}
+
+// handleAirdrops lists every airdrop seen by the scanner, highest USD value first
+func (a *ControlAPI) handleAirdrops(w http.ResponseWriter, r *http.Request) {
+	airdrops := a.service.Airdrops()
+	response := make([]airdropResponse, 0, len(airdrops))
+	for _, airdrop := range airdrops {
+		item := airdropResponse{
+			ID:      airdrop.ID,
+			Name:    airdrop.Token.Name,
+			Symbol:  airdrop.Token.Symbol,
+			Mint:    airdrop.Token.Address,
+			Amount:  airdrop.TokenAmount().Float(),
+			Claimed: airdrop.ClaimedAt != nil || a.service.scanner.IsClaimed(airdrop.ID),
+		}
+		if usd, ok := airdrop.UsdValue(); ok {
+			item.UsdValue = &usd
+		}
+		if sol, ok := airdrop.SolValue(); ok {
+			item.SolValue = &sol
+		}
+		response = append(response, item)
+	}
+
+	sort.SliceStable(response, func(i, j int) bool {
+		vi, vj := 0.0, 0.0
+		if response[i].UsdValue != nil {
+			vi = *response[i].UsdValue
+		}
+		if response[j].UsdValue != nil {
+			vj = *response[j].UsdValue
+		}
+		if vi != vj {
+			return vi > vj
+		}
+		return response[i].ID < response[j].ID
+	})
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// statsSummaryResponse is the body of GET /stats/summary, profits in SOL
+type statsSummaryResponse struct {
+	Last24h       float64 `json:"last24h"`
+	LastWeek      float64 `json:"lastWeek"`
+	ProjectedWeek float64 `json:"projectedWeek"`
+	Estimated     int     `json:"estimatedRecords"`
+}
+
+// handleStatsSummary returns the profit summary, ?estimates=true counts estimated swap records
+func (a *ControlAPI) handleStatsSummary(w http.ResponseWriter, r *http.Request) {
+	stats := a.service.claimer.GetStatsRecorder()
+	if stats == nil {
+		writeError(w, http.StatusServiceUnavailable, "stats recording is disabled")
+		return
+	}
+
+	summary, err := stats.GetProfitSummary(r.URL.Query().Get("estimates") == "true")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, statsSummaryResponse{
+		Last24h:       summary.Last24h,
+		LastWeek:      summary.LastWeek,
+		ProjectedWeek: summary.ProjectedWeek,
+		Estimated:     summary.Estimated,
+	})
+}
+
+// solPriceResponse is the body of GET /price/sol
+type solPriceResponse struct {
+	Price     float64   `json:"price"` // USD per SOL
+	Source    string    `json:"source"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Stale     bool      `json:"stale"`
+}
+
+// handleSolPrice returns the current SOL price of the price service
+func (a *ControlAPI) handleSolPrice(w http.ResponseWriter, r *http.Request) {
+	quote, ok := a.service.SolPrice()
+	if !ok {
+		writeError(w, http.StatusServiceUnavailable, "SOL price unknown")
+		return
+	}
+	writeJSON(w, http.StatusOK, solPriceResponse{
+		Price:     quote.Price,
+		Source:    quote.Source,
+		UpdatedAt: quote.UpdatedAt,
+		Stale:     quote.Stale,
+	})
+}
+
+// profitPointResponse is one point of GET /stats/profit, in SOL
+type profitPointResponse struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Profit     float64   `json:"profit"`
+	Cumulative float64   `json:"cumulative"`
+}
+
+// handleProfitHistory returns the cumulative profit of the last ?days=30 days, ?estimates=true counts
+// estimated swap records
+func (a *ControlAPI) handleProfitHistory(w http.ResponseWriter, r *http.Request) {
+	stats := a.service.claimer.GetStatsRecorder()
+	if stats == nil {
+		writeError(w, http.StatusServiceUnavailable, "stats recording is disabled")
+		return
+	}
+
+	since := time.Now().AddDate(0, 0, -queryInt(r, "days", defaultHistoryDays, maxHistoryDays))
+	points, err := stats.GetProfitHistory(since, r.URL.Query().Get("estimates") == "true")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := make([]profitPointResponse, 0, len(points))
+	for _, point := range points {
+		response = append(response, profitPointResponse{
+			Timestamp:  point.Timestamp,
+			Profit:     point.Profit,
+			Cumulative: point.Cumulative,
+		})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// efficiencyStatsResponse is the fees and sale proceeds of a group of transactions, in SOL
+type efficiencyStatsResponse struct {
+	Sales    int      `json:"sales"`
+	Fees     float64  `json:"fees"`
+	Proceeds float64  `json:"proceeds"`
+	Ratio    *float64 `json:"ratio"` // Fees over proceeds, 0 when nothing was spent or sold, null for fees without proceeds
+}
+
+// efficiencyResponse is the body of GET /stats/efficiency
+type efficiencyResponse struct {
+	Total   efficiencyStatsResponse            `json:"total"`
+	ByDay   map[string]efficiencyStatsResponse `json:"byDay"`
+	ByWeek  map[string]efficiencyStatsResponse `json:"byWeek"`
+	ByToken map[string]efficiencyStatsResponse `json:"byToken"`
+}
+
+// handleEfficiency returns the fees over sale proceeds of the last ?days=30 days overall, per day, ISO week and
+// token, ?estimates=true counts estimated records
+func (a *ControlAPI) handleEfficiency(w http.ResponseWriter, r *http.Request) {
+	stats := a.service.claimer.GetStatsRecorder()
+	if stats == nil {
+		writeError(w, http.StatusServiceUnavailable, "stats recording is disabled")
+		return
+	}
+
+	since := time.Now().AddDate(0, 0, -queryInt(r, "days", defaultHistoryDays, maxHistoryDays))
+	summary, err := stats.GetEfficiencySummary(since, r.URL.Query().Get("estimates") == "true")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, efficiencyResponse{
+		Total:   toEfficiencyResponse(summary.Total),
+		ByDay:   toEfficiencyResponses(summary.ByDay),
+		ByWeek:  toEfficiencyResponses(summary.ByWeek),
+		ByToken: toEfficiencyResponses(summary.ByToken),
+	})
+}
+
+func toEfficiencyResponse(stats solana.EfficiencyStats) efficiencyStatsResponse {
+	response := efficiencyStatsResponse{
+		Sales:    stats.Sales,
+		Fees:     stats.FeesSol,
+		Proceeds: stats.ProceedsSol,
+	}
+	// JSON has no infinity
+	if ratio := stats.Ratio(); !math.IsInf(ratio, 0) {
+		response.Ratio = &ratio
+	}
+	return response
+}
+
+func toEfficiencyResponses(groups map[string]solana.EfficiencyStats) map[string]efficiencyStatsResponse {
+	response := make(map[string]efficiencyStatsResponse, len(groups))
+	for key, stats := range groups {
+		response[key] = toEfficiencyResponse(stats)
+	}
+	return response
+}
+
+// transactionResponse is one record of GET /stats/transactions, amounts in SOL
+type transactionResponse struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Type        string    `json:"type"`
+	Token       string    `json:"token"`
+	Amount      string    `json:"amount"`
+	Expenses    float64   `json:"expenses"`
+	NetProfit   float64   `json:"netProfit"`
+	TxHash      string    `json:"txHash"`
+	Estimated   bool      `json:"estimated"`
+	OperationID string    `json:"operationId,omitempty"`
+}
+
+// handleTransactions returns the recorded claims, sales and fees of the last ?days=30 days, newest first and
+// at most ?limit=100
+func (a *ControlAPI) handleTransactions(w http.ResponseWriter, r *http.Request) {
+	stats := a.service.claimer.GetStatsRecorder()
+	if stats == nil {
+		writeError(w, http.StatusServiceUnavailable, "stats recording is disabled")
+		return
+	}
+
+	since := time.Now().AddDate(0, 0, -queryInt(r, "days", defaultHistoryDays, maxHistoryDays))
+	transactions, err := stats.GetTransactions(since)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].Timestamp.After(transactions[j].Timestamp)
+	})
+	if limit := queryInt(r, "limit", defaultHistoryLimit, maxHistoryLimit); len(transactions) > limit {
+		transactions = transactions[:limit]
+	}
+
+	response := make([]transactionResponse, 0, len(transactions))
+	for _, tx := range transactions {
+		response = append(response, transactionResponse{
+			Timestamp:   tx.Timestamp,
+			Type:        string(tx.TxType),
+			Token:       tx.TokenSymbol,
+			Amount:      tx.TokenAmount,
+			Expenses:    amount.Lamports(tx.Expenses).Sol(),
+			NetProfit:   amount.Lamports(tx.NetProfit).Sol(),
+			TxHash:      tx.TxHash,
+			Estimated:   tx.Estimated,
+			OperationID: tx.OperationID,
+		})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleClaim claims the airdrop in the path right away
+func (a *ControlAPI) handleClaim(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	txHash, err := a.service.ClaimNow(ctx, r.PathValue("id"))
+	switch {
+	case errors.Is(err, ErrUnknownAirdrop):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrCycleRunning), errors.Is(err, ErrAlreadyClaimed):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrKillSwitchEngaged):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	case err != nil:
+		writeError(w, http.StatusBadGateway, err.Error())
+	default:
+		writeJSON(w, http.StatusOK, map[string]string{"txHash": txHash})
+	}
+}
+
+// handleSell sells the tokens of the claimed airdrop in the path right away
+func (a *ControlAPI) handleSell(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	err := a.service.SellNow(ctx, r.PathValue("id"))
+	switch {
+	case errors.Is(err, ErrUnknownAirdrop):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrCycleRunning), errors.Is(err, ErrNotClaimed), errors.Is(err, ErrKeptToken):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrKillSwitchEngaged):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	case err != nil:
+		writeError(w, http.StatusBadGateway, err.Error())
+	default:
+		writeJSON(w, http.StatusOK, map[string]bool{"sold": true})
+	}
+}
+
+// handlePause pauses scan cycles
+func (a *ControlAPI) handlePause(w http.ResponseWriter, r *http.Request) {
+	a.service.Pause()
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": true})
+}
+
+// handleResume resumes scan cycles
+func (a *ControlAPI) handleResume(w http.ResponseWriter, r *http.Request) {
+	a.service.Resume()
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": false})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// queryInt reads a positive integer query parameter, fallback when it is missing or invalid and at most max
+func queryInt(r *http.Request, name string, fallback, max int) int {
+	value, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
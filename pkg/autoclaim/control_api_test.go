@@ -0,0 +1,53 @@
+package autoclaim
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"boop-airdrop-redeemer/pkg/config"
+)
+
+func TestNewControlAPIRequiresToken(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+
+	api, err := NewControlAPI(&config.Config{ControlAPIAddr: "127.0.0.1:8090"}, nil, logger)
+	assert.ErrorIs(t, err, ErrControlAPIToken)
+	assert.Nil(t, api)
+
+	api, err = NewControlAPI(&config.Config{}, nil, logger)
+	assert.NoError(t, err)
+	assert.Nil(t, api)
+}
+
+func TestControlAPIRejectsForeignOrigins(t *testing.T) {
+	api, err := NewControlAPI(&config.Config{ControlAPIAddr: "127.0.0.1:8090", ControlAPIToken: "secret"}, nil, log.New(io.Discard, "", 0))
+	require.NoError(t, err)
+	handler := api.authorized(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	send := func(origin, token string) int {
+		req := httptest.NewRequest(http.MethodPost, "http://127.0.0.1:8090/pause", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, send("", "secret"))
+	assert.Equal(t, http.StatusNoContent, send("http://127.0.0.1:8090", "secret"))
+	assert.Equal(t, http.StatusForbidden, send("https://evil.example", "secret"))
+	assert.Equal(t, http.StatusUnauthorized, send("", ""))
+	assert.Equal(t, http.StatusUnauthorized, send("", "wrong"))
+}
@@ -0,0 +1,216 @@
+// Dashboard of the control API. Every value from the API is inserted as text, never as HTML.
+"use strict";
+
+const refreshInterval = 30000;
+const tokenKey = "boopControlToken";
+
+let solPrice = null;
+let paused = false;
+
+const $ = (id) => document.getElementById(id);
+
+function showMessage(text) {
+  $("message").textContent = text || "";
+}
+
+async function api(method, path) {
+  const headers = {};
+  const token = localStorage.getItem(tokenKey);
+  if (token) {
+    headers["Authorization"] = "Bearer " + token;
+  }
+
+  const response = await fetch(path, { method, headers });
+  const body = await response.json().catch(() => ({}));
+  if (!response.ok) {
+    throw new Error(body.error || response.status + " " + response.statusText);
+  }
+  return body;
+}
+
+function cell(row, text, className) {
+  const td = row.insertCell();
+  td.textContent = text;
+  if (className) {
+    td.className = className;
+  }
+  return td;
+}
+
+function formatSol(value) {
+  let text = value.toFixed(4) + " SOL";
+  if (solPrice !== null) {
+    text += " ($" + (value * solPrice).toFixed(2) + ")";
+  }
+  return text;
+}
+
+async function refreshStatus() {
+  const health = await api("GET", "/health");
+  const status = $("status");
+  status.textContent = health.status + (health.killSwitch ? " (" + health.killSwitch + ")" : "");
+  status.className = health.status === "ok" ? "status-ok" : health.status === "paused" ? "status-warn" : "status-bad";
+
+  paused = health.paused;
+  const button = $("pause");
+  button.textContent = paused ? "Resume" : "Pause";
+  button.disabled = false;
+
+  try {
+    const price = await api("GET", "/price/sol");
+    solPrice = price.price;
+    $("sol-price").textContent = "$" + price.price.toFixed(2) + (price.stale ? " (stale)" : "");
+  } catch (err) {
+    solPrice = null;
+    $("sol-price").textContent = "unknown";
+  }
+}
+
+async function refreshProfit() {
+  const summary = await api("GET", "/stats/summary");
+  $("profit-24h").textContent = formatSol(summary.last24h);
+  $("profit-week").textContent = formatSol(summary.lastWeek);
+  $("profit-projected").textContent = formatSol(summary.projectedWeek);
+
+  drawChart(await api("GET", "/stats/profit?days=30"));
+}
+
+function svgElement(name, attributes) {
+  const element = document.createElementNS("http://www.w3.org/2000/svg", name);
+  for (const [key, value] of Object.entries(attributes)) {
+    element.setAttribute(key, value);
+  }
+  return element;
+}
+
+function drawChart(points) {
+  const chart = $("chart");
+  chart.replaceChildren();
+
+  const width = chart.clientWidth || 600;
+  const height = chart.clientHeight || 220;
+  const pad = 40;
+  chart.setAttribute("viewBox", "0 0 " + width + " " + height);
+
+  if (points.length === 0) {
+    const empty = svgElement("text", { x: pad, y: height / 2 });
+    empty.textContent = "No profit recorded in the last 30 days";
+    chart.appendChild(empty);
+    return;
+  }
+
+  const times = points.map((p) => Date.parse(p.timestamp));
+  const values = points.map((p) => p.cumulative);
+  const minTime = Math.min(...times);
+  const maxTime = Math.max(...times);
+  const minValue = Math.min(0, ...values);
+  const maxValue = Math.max(0, ...values);
+
+  const x = (t) => pad + (maxTime === minTime ? 0 : ((t - minTime) / (maxTime - minTime)) * (width - 2 * pad));
+  const y = (v) => height - pad - (maxValue === minValue ? 0 : ((v - minValue) / (maxValue - minValue)) * (height - 2 * pad));
+
+  chart.appendChild(svgElement("line", { class: "axis", x1: pad, y1: y(0), x2: width - pad, y2: y(0) }));
+  chart.appendChild(svgElement("polyline", {
+    class: "line",
+    points: points.map((p, i) => x(times[i]) + "," + y(p.cumulative)).join(" "),
+  }));
+
+  const labels = [
+    [pad, height - pad / 3, new Date(minTime).toLocaleDateString()],
+    [width - pad - 70, height - pad / 3, new Date(maxTime).toLocaleDateString()],
+    [4, y(maxValue) + 4, maxValue.toFixed(3)],
+    [4, y(minValue) + 4, minValue.toFixed(3)],
+  ];
+  for (const [lx, ly, text] of labels) {
+    const label = svgElement("text", { x: lx, y: ly });
+    label.textContent = text;
+    chart.appendChild(label);
+  }
+}
+
+async function refreshAirdrops() {
+  const airdrops = await api("GET", "/airdrops");
+  const body = $("airdrops");
+  body.replaceChildren();
+
+  for (const airdrop of airdrops) {
+    const row = body.insertRow();
+    cell(row, airdrop.symbol + " - " + airdrop.name);
+    cell(row, airdrop.amount.toLocaleString(undefined, { maximumFractionDigits: 2 }), "num");
+    cell(row, airdrop.usdValue === undefined ? "-" : "$" + airdrop.usdValue.toFixed(2), "num");
+    cell(row, airdrop.solValue === undefined ? "-" : airdrop.solValue.toFixed(5), "num");
+    cell(row, airdrop.claimed ? "claimed" : "pending");
+
+    const action = airdrop.claimed ? "sell" : "claim";
+    const button = document.createElement("button");
+    button.textContent = airdrop.claimed ? "Sell" : "Claim";
+    button.addEventListener("click", () => runAction(button, action, airdrop));
+    row.insertCell().appendChild(button);
+  }
+}
+
+async function refreshHistory() {
+  const transactions = await api("GET", "/stats/transactions?days=30&limit=100");
+  const body = $("history");
+  body.replaceChildren();
+
+  for (const tx of transactions) {
+    const row = body.insertRow();
+    cell(row, new Date(tx.timestamp).toLocaleString());
+    cell(row, tx.type + (tx.estimated ? " (estimated)" : ""));
+    cell(row, tx.token);
+    cell(row, tx.netProfit.toFixed(6), "num");
+    cell(row, tx.expenses.toFixed(6), "num");
+
+    const link = document.createElement("a");
+    link.href = "https://solscan.io/tx/" + encodeURIComponent(tx.txHash);
+    link.target = "_blank";
+    link.rel = "noopener noreferrer";
+    link.textContent = tx.txHash.slice(0, 12) + "...";
+    row.insertCell().appendChild(link);
+  }
+}
+
+async function runAction(button, action, airdrop) {
+  if (!confirm(action[0].toUpperCase() + action.slice(1) + " " + airdrop.symbol + " now?")) {
+    return;
+  }
+
+  button.disabled = true;
+  showMessage(action + " of " + airdrop.symbol + " in progress...");
+  try {
+    const result = await api("POST", "/" + action + "/" + encodeURIComponent(airdrop.id));
+    showMessage(action + " of " + airdrop.symbol + " done" + (result.txHash ? ": " + result.txHash : ""));
+  } catch (err) {
+    showMessage(action + " of " + airdrop.symbol + " failed: " + err.message);
+  } finally {
+    button.disabled = false;
+    refresh();
+  }
+}
+
+async function refresh() {
+  const results = await Promise.allSettled([refreshStatus(), refreshProfit(), refreshAirdrops(), refreshHistory()]);
+  const failed = results.find((result) => result.status === "rejected");
+  if (failed) {
+    showMessage("Refresh failed: " + failed.reason.message);
+  }
+}
+
+$("token").value = localStorage.getItem(tokenKey) || "";
+$("save-token").addEventListener("click", () => {
+  localStorage.setItem(tokenKey, $("token").value);
+  showMessage("");
+  refresh();
+});
+$("pause").addEventListener("click", async () => {
+  try {
+    await api("POST", paused ? "/resume" : "/pause");
+  } catch (err) {
+    showMessage("Failed: " + err.message);
+  }
+  refresh();
+});
+
+refresh();
+setInterval(refresh, refreshInterval);
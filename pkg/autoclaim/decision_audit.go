@@ -0,0 +1,153 @@
+package autoclaim
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/jupiter"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/models"
+	"boop-airdrop-redeemer/pkg/service"
+	"boop-airdrop-redeemer/pkg/solana"
+	"boop-airdrop-redeemer/pkg/strategy"
+)
+
+// Safety checks that can overrule a claim the strategy decided on
+const (
+	checkReprice    = "reprice"
+	checkCongestion = "congestion"
+	checkClaimDelay = "claim delay"
+	checkOnChain    = "on-chain status"
+	checkProfit     = "profit check"
+	checkQuote      = "quote freshness"
+	checkWhale      = "whale hold"
+	checkOutcome    = "outcome"
+
+	// checkPassed is the reason recorded when a check lets the claim go ahead
+	checkPassed = "passed"
+)
+
+// DecisionAudit records the claim and sell decisions made on each airdrop with their inputs and the outcome of
+// the claims and sales sent. Decisions are reevaluated every cycle, a decision is only recorded when it differs
+// from the last one recorded for the same airdrop and check.
+type DecisionAudit struct {
+	recorder      service.StatsStore
+	strategy      strategy.Strategy
+	decisionMaker *DecisionMaker
+	logger        *log.Logger
+
+	mu   sync.Mutex
+	last map[string]map[string]string // Last action and reason by airdrop and check
+}
+
+// NewDecisionAudit creates the decision audit recording the thresholds of the decision maker, nil without a
+// stats recorder
+func NewDecisionAudit(statsRecorder service.StatsStore, decisionMaker *DecisionMaker, logger *log.Logger) *DecisionAudit {
+	if statsRecorder == nil {
+		return nil
+	}
+
+	return &DecisionAudit{
+		recorder:      statsRecorder,
+		strategy:      decisionMaker.strategy,
+		decisionMaker: decisionMaker,
+		logger:        logger,
+		last:          make(map[string]map[string]string),
+	}
+}
+
+// Record records a decision on an airdrop unless it repeats the last one for the same check
+func (a *DecisionAudit) Record(airdrop models.AirdropNode, priceInfo *TokenPriceInfo, action, reason, check string) {
+	a.record(airdrop, priceInfo, action, reason, check, 0, "")
+}
+
+// RecordOutcome records the transaction hash or error of a claim or sale that was sent
+func (a *DecisionAudit) RecordOutcome(airdrop models.AirdropNode, priceInfo *TokenPriceInfo, action, txHash string, err error) {
+	if err != nil {
+		a.record(airdrop, priceInfo, action, "failed", checkOutcome, 0, err.Error())
+		return
+	}
+	a.record(airdrop, priceInfo, action, "sent", checkOutcome, 0, txHash)
+}
+
+// Prune forgets the last decisions on airdrops no longer pending, claimed or expired ones
+func (a *DecisionAudit) Prune(pending []models.AirdropNode) {
+	if a == nil {
+		return
+	}
+	ids := make(map[string]bool, len(pending))
+	for _, airdrop := range pending {
+		ids[airdrop.ID] = true
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for id := range a.last {
+		if !ids[id] {
+			delete(a.last, id)
+		}
+	}
+}
+
+// RecordQuote records the age of the quote a sale was sent with, nothing when no swap was sent
+func (a *DecisionAudit) RecordQuote(airdrop models.AirdropNode, priceInfo *TokenPriceInfo, trace jupiter.QuoteTrace) {
+	if trace.Age <= 0 {
+		return
+	}
+	reason := checkPassed
+	if trace.Requotes > 0 {
+		reason = fmt.Sprintf("re-quoted %d time(s)", trace.Requotes)
+	}
+	a.record(airdrop, priceInfo, solana.DecisionSell, reason, checkQuote, trace.Age, "")
+}
+
+// record records a decision unless it repeats the last one for the same check
+func (a *DecisionAudit) record(airdrop models.AirdropNode, priceInfo *TokenPriceInfo, action, reason, check string, quoteAge time.Duration, outcome string) {
+	if a == nil {
+		return
+	}
+
+	// Outcomes of different transactions differ, they are all recorded
+	last := action + "/" + reason + "/" + outcome
+	a.mu.Lock()
+	checks := a.last[airdrop.ID]
+	if checks == nil {
+		checks = make(map[string]string)
+		a.last[airdrop.ID] = checks
+	}
+	if checks[check] == last {
+		a.mu.Unlock()
+		return
+	}
+	checks[check] = last
+	a.mu.Unlock()
+
+	now := time.Now()
+	decision := solana.Decision{
+		Timestamp:        now,
+		AirdropID:        airdrop.ID,
+		TokenSymbol:      airdrop.Token.Symbol,
+		TokenMint:        airdrop.Token.Address,
+		Action:           action,
+		Reason:           reason,
+		Check:            check,
+		ValueUsd:         airdrop.AmountUsd.Float(),
+		MinimumUsd:       a.strategy.MinimumUsdThreshold,
+		StableMinimumUsd: a.strategy.StableMinimumUsd,
+		StableDuration:   a.strategy.StableDuration,
+		QuoteAge:         quoteAge,
+		Safety:           a.decisionMaker.SafetyResult(airdrop),
+		Outcome:          outcome,
+	}
+	decision.ValueSol, _ = airdrop.SolValue()
+	if priceInfo != nil {
+		decision.StableFor = now.Sub(priceInfo.LastChanged)
+		decision.ObservedFor = now.Sub(priceInfo.FirstObserved)
+	}
+
+	if err := a.recorder.RecordDecision(decision); err != nil {
+		logging.Warnf(a.logger, "Warning: Failed to record decision on airdrop %s: %v", airdrop.ID, err)
+	}
+}
@@ -0,0 +1,81 @@
+package autoclaim
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/solana"
+)
+
+func TestDecisionAuditRecordsChangedDecisions(t *testing.T) {
+	recorder, err := solana.NewStatsRecorder(t.TempDir())
+	require.NoError(t, err)
+	defer recorder.Close()
+
+	cfg := &config.Config{MinimumUsdThreshold: 1, StableMinimumUsd: 0.07, StableDuration: 10 * time.Minute}
+	audit := NewDecisionAudit(recorder, NewDecisionMaker(cfg), log.New(io.Discard, "", 0))
+	priceInfo := &TokenPriceInfo{LastChanged: time.Now().Add(-time.Minute), FirstObserved: time.Now().Add(-time.Hour)}
+
+	// Decisions repeated by later cycles are recorded once per airdrop and check
+	airdrop := drop("a", "mint", 0.5)
+	audit.Record(airdrop, priceInfo, solana.DecisionSkip, reasonWaitingForStable, "")
+	audit.Record(airdrop, priceInfo, solana.DecisionSkip, reasonWaitingForStable, "")
+	audit.Record(airdrop, priceInfo, solana.DecisionClaim, reasonStablePrice, "")
+	audit.Record(airdrop, priceInfo, solana.DecisionSkip, "net profit below margin", checkProfit)
+
+	decisions, err := recorder.GetDecisions(time.Time{})
+	require.NoError(t, err)
+	require.Len(t, decisions, 3)
+	assert.Equal(t, solana.DecisionSkip, decisions[0].Action)
+	assert.Equal(t, reasonStablePrice, decisions[1].Reason)
+	assert.Equal(t, checkProfit, decisions[2].Check)
+	assert.Equal(t, 0.5, decisions[0].ValueUsd)
+	assert.Equal(t, 1.0, decisions[0].MinimumUsd)
+	assert.Equal(t, 10*time.Minute, decisions[0].StableDuration)
+	assert.InDelta(t, time.Hour.Seconds(), decisions[0].ObservedFor.Seconds(), 5)
+
+	var export bytes.Buffer
+	require.NoError(t, solana.ExportDecisions(&export, decisions))
+	lines := strings.Split(strings.TrimSpace(export.String()), "\n")
+	assert.Len(t, lines, 4)
+	assert.True(t, strings.HasPrefix(lines[0], "Timestamp,Airdrop,Token,Mint,Action,Reason,Check"))
+
+	assert.Nil(t, NewDecisionAudit(nil, NewDecisionMaker(cfg), nil))
+}
+
+func TestDecisionAuditRecordsOutcomesAndForgetsGoneAirdrops(t *testing.T) {
+	recorder, err := solana.NewStatsRecorder(t.TempDir())
+	require.NoError(t, err)
+	defer recorder.Close()
+
+	audit := NewDecisionAudit(recorder, NewDecisionMaker(&config.Config{MinimumUsdThreshold: 1}), log.New(io.Discard, "", 0))
+	airdrop := drop("a", "mint", 2)
+	audit.RecordOutcome(airdrop, nil, solana.DecisionClaim, "", errors.New("pool underfunded"))
+	audit.RecordOutcome(airdrop, nil, solana.DecisionClaim, "claim-tx", nil)
+
+	decisions, err := recorder.GetDecisions(time.Time{})
+	require.NoError(t, err)
+	require.Len(t, decisions, 2)
+	assert.Equal(t, checkOutcome, decisions[0].Check)
+	assert.Equal(t, "pool underfunded", decisions[0].Outcome)
+	assert.Equal(t, "claim-tx", decisions[1].Outcome)
+	assert.Empty(t, decisions[1].Safety)
+
+	// Decisions on airdrops no longer pending are forgotten, the same decision is recorded again if it returns
+	audit.Record(airdrop, nil, solana.DecisionSkip, reasonNoPriceData, "")
+	audit.Prune(nil)
+	assert.Empty(t, audit.last)
+	audit.Record(airdrop, nil, solana.DecisionSkip, reasonNoPriceData, "")
+	decisions, err = recorder.GetDecisions(time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, decisions, 4)
+}
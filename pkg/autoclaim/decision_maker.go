@@ -2,88 +2,237 @@ package autoclaim
 
 import (
 	"log"
-	"strconv"
 	"time"
 
 	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
 	"boop-airdrop-redeemer/pkg/models"
+	"boop-airdrop-redeemer/pkg/safety"
+	"boop-airdrop-redeemer/pkg/strategy"
 )
 
 // DecisionMaker handles the logic for deciding when to claim airdrops
 type DecisionMaker struct {
-	config *config.Config
-	logger *log.Logger
+	config      *config.Config
+	strategy    strategy.Strategy
+	logger      *log.Logger
+	performance *strategy.TokenPerformanceModel
+	safety      *safety.Screener // nil without token safety screening
+	skipUnsafe  bool             // Tokens failing screening are not claimed, otherwise they are only flagged
 }
 
-// NewDecisionMaker creates a new decision maker
+// NewDecisionMaker creates a new decision maker using the configured claim threshold and stability rule
 func NewDecisionMaker(cfg *config.Config) *DecisionMaker {
+	return NewDecisionMakerWithStrategy(
+		cfg,
+		strategy.Strategy{
+			MinimumUsdThreshold: cfg.MinimumUsdThreshold,
+			StableMinimumUsd:    cfg.StableMinimumUsd,
+			StableDuration:      cfg.StableDuration,
+		},
+		logging.For("decision-maker"),
+	)
+}
+
+// NewDecisionMakerWithStrategy creates a decision maker applying the given strategy
+func NewDecisionMakerWithStrategy(cfg *config.Config, rules strategy.Strategy, logger *log.Logger) *DecisionMaker {
 	return &DecisionMaker{
-		config: cfg,
-		logger: log.New(log.Writer(), "DECISION-MAKER: ", log.LstdFlags),
+		config:   cfg,
+		strategy: rules,
+		logger:   logger,
 	}
 }
 
+// Reasons given for claim and sell decisions, kept free of values so repeated decisions are recognized
+const (
+	reasonAboveThreshold   = "above threshold"
+	reasonBelowThreshold   = "below threshold"
+	reasonStablePrice      = "price stable"
+	reasonWaitingForStable = "waiting for stable price"
+	reasonTokenPerformance = "token loses value while waiting"
+	reasonNoPriceData      = "no price data"
+	reasonSolThreshold     = "above SOL threshold"
+	reasonBelowSol         = "below SOL threshold"
+	reasonNoValue          = "no USD or SOL value"
+	reasonNoUsdValue       = "no USD value"
+	reasonNotClaimed       = "not claimed"
+	reasonBelowSellMinimum = "below sell minimum"
+	reasonUnsafeToken      = "failed safety screening"
+	reasonRisingPrice      = "selling into strength"
+)
+
 // ShouldClaim determines if an airdrop should be claimed based on various criteria
 func (d *DecisionMaker) ShouldClaim(airdrop models.AirdropNode, priceInfo *TokenPriceInfo) bool {
-	if priceInfo == nil {
-		return false
+	claim, _ := d.EvaluateClaim(airdrop, priceInfo)
+	return claim
+}
+
+// EvaluateSafety decides whether the token of an airdrop may be claimed or sold after safety screening and
+// returns why it failed screening, empty when it passed or was not screened. Tokens that failed are held in skip
+// mode, in flag mode they go ahead.
+func (d *DecisionMaker) EvaluateSafety(airdrop models.AirdropNode) (bool, string) {
+	report, screened := d.safety.Report(airdrop.Token.Address)
+	if !screened || report.Safe() {
+		return true, ""
+	}
+	return !d.skipUnsafe, report.Summary()
+}
+
+// SafetyResult returns the safety screening result of the token of an airdrop for the decision audit: "passed",
+// the failed checks, or empty when the token was not screened
+func (d *DecisionMaker) SafetyResult(airdrop models.AirdropNode) string {
+	report, screened := d.safety.Report(airdrop.Token.Address)
+	switch {
+	case !screened:
+		return ""
+	case report.Safe():
+		return checkPassed
+	default:
+		return report.Summary()
 	}
+}
+
+// EvaluateClaim decides whether an airdrop should be claimed and gives the reason. Tokens that failed safety
+// screening are not claimed in skip mode, in flag mode their claims go ahead with a warning.
+func (d *DecisionMaker) EvaluateClaim(airdrop models.AirdropNode, priceInfo *TokenPriceInfo) (bool, string) {
+	allowed, failure := d.EvaluateSafety(airdrop)
+	if !allowed {
+		return false, reasonUnsafeToken
+	}
+
+	claim, reason := d.evaluateValue(airdrop, priceInfo)
+	if claim && failure != "" {
+		logging.Warnf(d.logger, "Warning: Claiming %s (%s) although it failed safety screening: %s",
+			airdrop.ID, airdrop.Token.Symbol, failure)
+	}
+	return claim, reason
+}
 
-	// Parse USD value
-	usdValue, err := strconv.ParseFloat(airdrop.AmountUsd, 64)
-	if err != nil {
-		d.logger.Printf("Failed to parse USD value for airdrop %s: %v", airdrop.ID, err)
-		return false
+// evaluateValue decides on the airdrop's value and price stability
+func (d *DecisionMaker) evaluateValue(airdrop models.AirdropNode, priceInfo *TokenPriceInfo) (bool, string) {
+	usdValue, ok := airdrop.UsdValue()
+	if !ok {
+		return d.shouldClaimBySol(airdrop)
+	}
+
+	if priceInfo == nil {
+		return false, reasonNoPriceData
 	}
 
 	// Check if token meets regular threshold
-	if usdValue >= d.config.MinimumUsdThreshold {
-		return true
+	if usdValue >= d.strategy.MinimumUsdThreshold {
+		return true, reasonAboveThreshold
 	}
 
 	// Check if token meets special criteria
-	if usdValue > 0.07 { // > 7 cents
-		// Check if price has been stable for at least 10 minutes
+	if usdValue > d.strategy.StableMinimumUsd {
+		// Tokens that historically lose value while waiting are claimed without waiting for stability
+		if performance, skip := d.performance.SkipStableWait(airdrop.Token.Symbol); skip {
+			d.logger.Printf("Token %s at $%.2f historically changed %.0f%% over the stability wait (%d samples), will claim without waiting",
+				airdrop.Token.Symbol, usdValue, performance.AvgWaitChange*100, performance.WaitSamples)
+			return true, reasonTokenPerformance
+		}
+
+		// Check if price has been stable long enough
 		stableTime := time.Since(priceInfo.LastChanged)
 		observedTime := time.Since(priceInfo.FirstObserved)
 
-		if stableTime > 10*time.Minute && observedTime > 10*time.Minute {
+		if stableTime > d.strategy.StableDuration && observedTime > d.strategy.StableDuration {
 			d.logger.Printf("Token %s price stable at $%.2f for %.1f minutes (observed for %.1f minutes), will claim",
 				airdrop.Token.Symbol, usdValue, stableTime.Minutes(), observedTime.Minutes())
-			return true
-		} else if usdValue > 0.07 && (stableTime > 5*time.Minute || observedTime > 5*time.Minute) {
+			return true, reasonStablePrice
+		} else if stableTime > d.config.StableTrackingAfter || observedTime > d.config.StableTrackingAfter {
 			// Log but don't claim yet
 			d.logger.Printf("Tracking token %s at $%.2f - stable for %.1f minutes (observed for %.1f minutes)",
 				airdrop.Token.Symbol, usdValue, stableTime.Minutes(), observedTime.Minutes())
 		}
+		return false, reasonWaitingForStable
+	}
+
+	return false, reasonBelowThreshold
+}
+
+// shouldClaimBySol applies the SOL threshold while the API has no USD value for the airdrop, so claiming
+// does not halt during USD price feed outages
+func (d *DecisionMaker) shouldClaimBySol(airdrop models.AirdropNode) (bool, string) {
+	solValue, ok := airdrop.SolValue()
+	if !ok {
+		d.logger.Printf("Airdrop %s (%s) has neither a USD nor a SOL value, skipping", airdrop.ID, airdrop.Token.Symbol)
+		return false, reasonNoValue
 	}
+	if d.config.MinimumSolThreshold <= 0 || solValue < d.config.MinimumSolThreshold {
+		return false, reasonBelowSol
+	}
+
+	d.logger.Printf("No USD value for %s, claiming on SOL value %.5f SOL (threshold %.5f SOL)",
+		airdrop.Token.Symbol, solValue, d.config.MinimumSolThreshold)
+	return true, reasonSolThreshold
+}
+
+// SetTokenPerformance replaces the learned per-token performance used to bias claim decisions, nil disables it
+func (d *DecisionMaker) SetTokenPerformance(model *strategy.TokenPerformanceModel) {
+	d.performance = model
+}
 
-	return false
+// SetSafety sets the token screener whose reports are applied to claims, skip drops tokens that failed
+// screening instead of only flagging them. A nil screener disables it.
+func (d *DecisionMaker) SetSafety(screener *safety.Screener, skip bool) {
+	d.safety = screener
+	d.skipUnsafe = skip && screener != nil
 }
 
 // ShouldSellDirectly determines if a token should be sold directly
 // For tokens that have already been claimed but have stable prices
 func (d *DecisionMaker) ShouldSellDirectly(airdrop models.AirdropNode, priceInfo *TokenPriceInfo) bool {
-	if priceInfo == nil || airdrop.ClaimedAt == nil {
-		return false
+	sell, _ := d.EvaluateSell(airdrop, priceInfo)
+	return sell
+}
+
+// EvaluateSell decides whether an already claimed token should be sold directly and gives the reason. Like
+// claims, sales of tokens that failed safety screening are held in skip mode.
+func (d *DecisionMaker) EvaluateSell(airdrop models.AirdropNode, priceInfo *TokenPriceInfo) (bool, string) {
+	if priceInfo == nil {
+		return false, reasonNoPriceData
+	}
+	if airdrop.ClaimedAt == nil {
+		return false, reasonNotClaimed
+	}
+	allowed, failure := d.EvaluateSafety(airdrop)
+	if !allowed {
+		return false, reasonUnsafeToken
 	}
 
-	// Parse USD value
-	usdValue, err := strconv.ParseFloat(airdrop.AmountUsd, 64)
-	if err != nil {
-		return false
+	sell, reason := d.evaluateSale(airdrop, priceInfo)
+	if sell && failure != "" {
+		logging.Warnf(d.logger, "Warning: Selling %s (%s) although it failed safety screening: %s",
+			airdrop.ID, airdrop.Token.Symbol, failure)
 	}
+	return sell, reason
+}
+
+// evaluateSale decides on the claimed token's value and price stability
+func (d *DecisionMaker) evaluateSale(airdrop models.AirdropNode, priceInfo *TokenPriceInfo) (bool, string) {
+	if !airdrop.AmountUsd.Known() {
+		return false, reasonNoUsdValue
+	}
+	usdValue := airdrop.AmountUsd.Float()
 
 	// For tokens with significant value but minimal price changes over time
-	if usdValue >= 0.10 {
+	if usdValue >= d.config.StableSellMinimumUsd {
+		// A rising price is sold at or above its recent average instead of waiting for it to settle
+		if d.config.SellIntoStrength && priceInfo.Trend.Rising() && usdValue >= priceInfo.Trend.ShortAverage {
+			return true, reasonRisingPrice
+		}
+
 		stableTime := time.Since(priceInfo.LastChanged)
 		observedTime := time.Since(priceInfo.FirstObserved)
 
 		// If price has been stable for extended period with minimal changes
-		if stableTime >= 10*time.Minute && observedTime >= 10*time.Minute {
-			return true
+		if stableTime >= d.strategy.StableDuration && observedTime >= d.strategy.StableDuration {
+			return true, reasonStablePrice
 		}
+		return false, reasonWaitingForStable
 	}
 
-	return false
+	return false, reasonBelowSellMinimum
 }
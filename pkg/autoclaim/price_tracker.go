@@ -1,40 +1,77 @@
 package autoclaim
 
 import (
-	"strconv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"boop-airdrop-redeemer/pkg/models"
 )
 
+// priceTrendShortSamples is how many of the latest prices the short moving average covers
+const priceTrendShortSamples = 5
+
+// priceHistoryMaxAge is how long the prices of an airdrop that is no longer seen are kept in the saved history
+const priceHistoryMaxAge = 24 * time.Hour
+
 // TokenPriceInfo stores price tracking information for a token
 type TokenPriceInfo struct {
 	LastPrice     float64
 	LastChanged   time.Time
 	FirstObserved time.Time
+	Trend         PriceTrend // Computed from the recorded prices, zero until two are recorded
+}
+
+// PricePoint is a USD value of an airdrop observed during a scan cycle
+type PricePoint struct {
+	Time  time.Time `json:"time"`
+	Price float64   `json:"price"`
+}
+
+// PriceTrend summarizes the recent prices of an airdrop
+type PriceTrend struct {
+	Samples      int     // Recorded prices the trend is computed from
+	ShortAverage float64 // Mean of the latest priceTrendShortSamples prices
+	LongAverage  float64 // Mean of all recorded prices
+	Volatility   float64 // Standard deviation of the relative changes between consecutive prices
+}
+
+// Rising reports whether the recent prices are above the longer average, with more prices recorded than the
+// short average covers
+func (t PriceTrend) Rising() bool {
+	return t.Samples > priceTrendShortSamples && t.ShortAverage > t.LongAverage
 }
 
 // PriceTracker tracks token price history and stability
 type PriceTracker struct {
 	tokenPriceHistory map[string]TokenPriceInfo
+	samples           map[string][]PricePoint
+	maxSamples        int
 	mutex             *sync.Mutex
 }
 
-// NewPriceTracker creates a new price tracker
-func NewPriceTracker() *PriceTracker {
+// NewPriceTracker creates a new price tracker keeping the latest maxSamples prices of every airdrop,
+// 0 keeps no price history
+func NewPriceTracker(maxSamples int) *PriceTracker {
 	return &PriceTracker{
 		tokenPriceHistory: make(map[string]TokenPriceInfo),
+		samples:           make(map[string][]PricePoint),
+		maxSamples:        maxSamples,
 		mutex:             &sync.Mutex{},
 	}
 }
 
-// UpdatePriceData updates the price data for a given airdrop
-func (p *PriceTracker) UpdatePriceData(airdrop models.AirdropNode) {
-	// Parse USD value
-	usdValue, err := strconv.ParseFloat(airdrop.AmountUsd, 64)
-	if err != nil {
-		return
+// UpdatePriceData updates the price data for a given airdrop and reports whether the value is new or changed
+func (p *PriceTracker) UpdatePriceData(airdrop models.AirdropNode) bool {
+	// Without a USD value there is nothing to track, keep the last known price
+	usdValue, ok := airdrop.UsdValue()
+	if !ok {
+		return false
 	}
 
 	p.mutex.Lock()
@@ -42,6 +79,7 @@ func (p *PriceTracker) UpdatePriceData(airdrop models.AirdropNode) {
 
 	now := time.Now()
 	priceKey := airdrop.ID
+	p.recordSample(priceKey, PricePoint{Time: now, Price: usdValue})
 
 	// Check if we've seen this token before
 	priceInfo, exists := p.tokenPriceHistory[priceKey]
@@ -53,6 +91,7 @@ func (p *PriceTracker) UpdatePriceData(airdrop models.AirdropNode) {
 			LastChanged:   now,
 			FirstObserved: now,
 		}
+		return true
 	} else if priceInfo.LastPrice != usdValue {
 		// Price changed
 		p.tokenPriceHistory[priceKey] = TokenPriceInfo{
@@ -60,7 +99,22 @@ func (p *PriceTracker) UpdatePriceData(airdrop models.AirdropNode) {
 			LastChanged:   now,
 			FirstObserved: priceInfo.FirstObserved,
 		}
+		return true
+	}
+
+	return false
+}
+
+// recordSample appends a price to the airdrop's history, dropping the oldest beyond maxSamples
+func (p *PriceTracker) recordSample(priceKey string, point PricePoint) {
+	if p.maxSamples <= 0 {
+		return
 	}
+	history := append(p.samples[priceKey], point)
+	if len(history) > p.maxSamples {
+		history = history[len(history)-p.maxSamples:]
+	}
+	p.samples[priceKey] = history
 }
 
 // GetTokenPriceInfo returns price info for a token
@@ -73,8 +127,113 @@ func (p *PriceTracker) GetTokenPriceInfo(tokenID string) *TokenPriceInfo {
 			LastPrice:     info.LastPrice,
 			LastChanged:   info.LastChanged,
 			FirstObserved: info.FirstObserved,
+			Trend:         computeTrend(p.samples[tokenID]),
 		}
 	}
 
 	return nil
 }
+
+// GetPriceHistory returns the recorded prices of an airdrop, oldest first
+func (p *PriceTracker) GetPriceHistory(tokenID string) []PricePoint {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]PricePoint(nil), p.samples[tokenID]...)
+}
+
+// computeTrend computes the moving averages and volatility of the prices, zero for fewer than two
+func computeTrend(history []PricePoint) PriceTrend {
+	if len(history) < 2 {
+		return PriceTrend{}
+	}
+
+	trend := PriceTrend{Samples: len(history)}
+	short := history[max(len(history)-priceTrendShortSamples, 0):]
+	trend.ShortAverage = meanPrice(short)
+	trend.LongAverage = meanPrice(history)
+
+	var changes []float64
+	for i := 1; i < len(history); i++ {
+		if history[i-1].Price > 0 {
+			changes = append(changes, history[i].Price/history[i-1].Price-1)
+		}
+	}
+	if len(changes) > 0 {
+		var mean, variance float64
+		for _, change := range changes {
+			mean += change
+		}
+		mean /= float64(len(changes))
+		for _, change := range changes {
+			variance += (change - mean) * (change - mean)
+		}
+		trend.Volatility = math.Sqrt(variance / float64(len(changes)))
+	}
+	return trend
+}
+
+// meanPrice is the average of the prices
+func meanPrice(points []PricePoint) float64 {
+	var sum float64
+	for _, point := range points {
+		sum += point.Price
+	}
+	return sum / float64(len(points))
+}
+
+// Save writes the price history to path, so trends survive restarts. Airdrops not seen for
+// priceHistoryMaxAge are left out.
+func (p *PriceTracker) Save(path string) error {
+	p.mutex.Lock()
+	saved := make(map[string][]PricePoint, len(p.samples))
+	cutoff := time.Now().Add(-priceHistoryMaxAge)
+	for id, history := range p.samples {
+		if len(history) > 0 && history[len(history)-1].Time.After(cutoff) {
+			saved[id] = history
+		}
+	}
+	content, err := json.Marshal(saved)
+	p.mutex.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode price history: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create price history directory: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write price history: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace price history: %w", err)
+	}
+	return nil
+}
+
+// Load restores the price history saved at path, a missing file is no history. Only the trends are restored,
+// price stability is tracked again from the first observation.
+func (p *PriceTracker) Load(path string) error {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read price history: %w", err)
+	}
+
+	var saved map[string][]PricePoint
+	if err := json.Unmarshal(content, &saved); err != nil {
+		return fmt.Errorf("failed to decode price history: %w", err)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for id, history := range saved {
+		for _, point := range history {
+			p.recordSample(id, point)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package autoclaim
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"boop-airdrop-redeemer/pkg/config"
+)
+
+func TestPriceTrackerComputesTrends(t *testing.T) {
+	tracker := NewPriceTracker(8)
+	for _, price := range []float64{1, 1, 1, 1, 1, 1.1, 1.2, 1.3, 1.4, 1.5} {
+		tracker.UpdatePriceData(drop("a", "mint", price))
+	}
+
+	assert.Len(t, tracker.GetPriceHistory("a"), 8, "only the latest prices are kept")
+	trend := tracker.GetTokenPriceInfo("a").Trend
+	assert.Equal(t, 8, trend.Samples)
+	assert.InDelta(t, 1.3, trend.ShortAverage, 1e-9)
+	assert.InDelta(t, 1.1875, trend.LongAverage, 1e-9)
+	assert.Greater(t, trend.Volatility, 0.0)
+	assert.True(t, trend.Rising())
+
+	// A claimed airdrop rising in price is sold without waiting for stability when selling into strength
+	airdrop := drop("a", "mint", 1.5)
+	airdrop.ClaimedAt = "2026-10-16T12:00:00Z"
+	cfg := &config.Config{StableSellMinimumUsd: 0.1, StableDuration: time.Hour}
+	sell, reason := NewDecisionMaker(cfg).EvaluateSell(airdrop, tracker.GetTokenPriceInfo("a"))
+	assert.False(t, sell)
+	assert.Equal(t, reasonWaitingForStable, reason)
+
+	cfg.SellIntoStrength = true
+	sell, reason = NewDecisionMaker(cfg).EvaluateSell(airdrop, tracker.GetTokenPriceInfo("a"))
+	assert.True(t, sell)
+	assert.Equal(t, reasonRisingPrice, reason)
+
+	// The history survives a restart, price stability does not
+	path := filepath.Join(t.TempDir(), "price_history.json")
+	require.NoError(t, tracker.Save(path))
+	restored := NewPriceTracker(8)
+	require.NoError(t, restored.Load(path))
+	assert.Equal(t, len(tracker.GetPriceHistory("a")), len(restored.GetPriceHistory("a")))
+	assert.Nil(t, restored.GetTokenPriceInfo("a"))
+}
@@ -2,18 +2,52 @@ package autoclaim
 
 import (
 	"context"
+	"errors"
+	"io"
 	"log"
-	"strconv"
+	"math"
+	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/jupiter"
+	"boop-airdrop-redeemer/pkg/logging"
 	"boop-airdrop-redeemer/pkg/models"
 	"boop-airdrop-redeemer/pkg/notifications"
+	"boop-airdrop-redeemer/pkg/operation"
+	"boop-airdrop-redeemer/pkg/retry"
+	"boop-airdrop-redeemer/pkg/safety"
 	"boop-airdrop-redeemer/pkg/service"
+	"boop-airdrop-redeemer/pkg/solana"
+	"boop-airdrop-redeemer/pkg/strategy"
 )
 
+// Token learning relearns from the last 30 days of history every 6 hours
+const (
+	tokenLearningInterval = 6 * time.Hour
+	tokenLearningPeriod   = 30 * 24 * time.Hour
+)
+
+// Fee efficiency is checked every 6 hours over the last week, once it has a few sales to go by
+const (
+	efficiencyCheckInterval = 6 * time.Hour
+	efficiencyPeriod        = 7 * 24 * time.Hour
+	efficiencyMinSales      = 3
+)
+
+// Waits after failed scans, doubling with every scan that fails in a row. Jitter keeps several wallets from
+// scanning again at the same moment.
+var (
+	scanNetworkErrorBackoff = retry.Policy{Initial: 3 * time.Second, Max: time.Minute, Jitter: 0.2}
+	scanErrorBackoff        = retry.Policy{Initial: 30 * time.Second, Max: 5 * time.Minute, Jitter: 0.2}
+)
+
+// maxRateLimitFactor is the most the check interval is stretched while the API clients are rate limited
+const maxRateLimitFactor = 8
+
 // Service handles the orchestration of auto claiming airdrops
 type Service struct {
 	config         *config.Config
@@ -21,6 +55,8 @@ type Service struct {
 	claimer        *service.AirdropClaimer
 	priceTracker   *PriceTracker
 	decisionMaker  *DecisionMaker
+	shadow         *ShadowEvaluator
+	audit          *DecisionAudit
 	tokenSeller    *TokenSeller
 	telegramClient *notifications.TelegramClient
 	logger         *log.Logger
@@ -29,8 +65,68 @@ type Service struct {
 	claimedAirdrops map[string]bool
 	claimedMutex    *sync.Mutex
 
-	// Track auth token refresh
+	// Direct sales of claimed tokens running in the background, waited for when the service stops
+	sales sync.WaitGroup
+
+	// Track auth token refresh, claim workers may refresh at the same time
+	refreshMu        sync.Mutex
 	lastTokenRefresh time.Time
+
+	// Consecutive failed scans, lengthening the wait after each
+	scanFailures int
+
+	// Rate limit responses of the API clients: the count at the last check, how many times the check interval
+	// is stretched, when the current rate limiting started and whether it was alerted
+	rateLimitCount   int
+	rateLimitFactor  int
+	rateLimitedSince time.Time
+	rateLimitAlerted bool
+
+	// Track degraded mode (airdrops served from cache) to notify on transitions
+	degradedMode bool
+
+	// Track when the last weekly digest was sent, saved so a restart neither repeats nor skips it
+	lastDigest  time.Time
+	digestState solana.StateStore
+
+	// Track when fee efficiency was last checked and whether it was past the alert ratio
+	lastEfficiencyCheck time.Time
+	efficiencyDegraded  bool
+
+	// Network congestion monitor (nil when disabled) and when each deferred claim was first deferred
+	congestion     *solana.CongestionMonitor
+	deferredClaims map[string]time.Time
+
+	// Randomized delay of low value claims, nil when no delay window is configured
+	claimDelay *ClaimDelay
+
+	// Tokens whose small drops are held back and claimed together, nil without accumulation rules
+	accumulator *Accumulator
+
+	// Claims waiting for approval on Telegram, nil unless approval mode is enabled
+	approvals *Approvals
+
+	// Track when per-token performance was last learned from history
+	lastTokenLearning time.Time
+
+	// Airdrops listed for the wallet before they are claimable, nil when not watched
+	upcoming *service.UpcomingAirdropWatcher
+
+	// Records claims the API lists as claimed that are missing from the stats, nil without stats
+	claimReconciler *service.ClaimReconciler
+
+	// High-priority alerts for pending airdrops above the whale threshold, nil when disabled
+	whales *WhaleAlerts
+
+	// Token safety screening of pending airdrops, nil when disabled, and the mints already notified as unsafe
+	safety        *safety.Screener
+	unsafeAlerted map[string]bool
+
+	// Control API state: cycles and manual claims never overlap, paused skips cycles
+	cycleMu   sync.Mutex
+	paused    atomic.Bool
+	lastCycle atomic.Int64 // Unix time the last cycle finished, zero before the first
+	startedAt time.Time
 }
 
 // NewService creates a new auto claim service
@@ -41,49 +137,187 @@ func NewService(
 	telegramClient *notifications.TelegramClient,
 	logger *log.Logger,
 ) *Service {
+	decisionMaker := NewDecisionMaker(cfg)
+	screener := newSafetyFromConfig(cfg, claimer, logger)
+	decisionMaker.SetSafety(screener, cfg.SafetyMode == safetySkip)
+	if cfg.TokenLearning {
+		// Start from the last learned model until history has been read again
+		if model, err := strategy.LoadTokenPerformanceModel(tokenPerformancePath(cfg)); err == nil {
+			decisionMaker.SetTokenPerformance(model)
+		}
+	}
+
+	digestState := serviceState(cfg, claimer, "weekly_digest")
+
 	return &Service{
 		config:           cfg,
 		scanner:          scanner,
 		claimer:          claimer,
 		telegramClient:   telegramClient,
 		logger:           logger,
-		priceTracker:     NewPriceTracker(),
-		decisionMaker:    NewDecisionMaker(cfg),
+		priceTracker:     newPriceTrackerFromConfig(cfg, logger),
+		decisionMaker:    decisionMaker,
+		shadow:           newShadowFromConfig(cfg, logger),
+		audit:            NewDecisionAudit(claimer.GetStatsRecorder(), decisionMaker, logger),
 		tokenSeller:      NewTokenSeller(cfg, claimer, telegramClient, logger),
 		claimedAirdrops:  make(map[string]bool),
 		claimedMutex:     &sync.Mutex{},
 		lastTokenRefresh: time.Time{}, // Zero time
+		lastDigest:       loadLastDigest(digestState, logger),
+		digestState:      digestState,
+		congestion:       newCongestionFromConfig(cfg, claimer, logger),
+		deferredClaims:   make(map[string]time.Time),
+		claimDelay:       NewClaimDelay(cfg, logger),
+		accumulator:      NewAccumulator(cfg.AccumulationRules, serviceState(cfg, claimer, "accumulation_rounds"), logger),
+		approvals:        NewApprovals(cfg, telegramClient, logger),
+		upcoming:         service.NewUpcomingAirdropWatcher(cfg, scanner, claimer.GetStatsRecorder(), logger),
+		claimReconciler:  service.NewClaimReconciler(scanner, claimer, logger),
+		whales:           NewWhaleAlerts(cfg, telegramClient, logger),
+		safety:           screener,
+		unsafeAlerted:    make(map[string]bool),
+		startedAt:        time.Now(),
 	}
 }
 
-// Start begins the auto claiming service
+// Start begins the auto claiming service, returning once ctx is cancelled and the sales in flight have finished
 func (s *Service) Start(ctx context.Context) {
 	if s.telegramClient.Enabled {
+		// A bot token or chat Telegram rejects is logged and alerted by the client
+		s.telegramClient.Verify()
+
 		// Send welcome message with bot information and settings
 		s.telegramClient.SendWelcomeMessage(
 			s.config.WalletAddress,
 			s.config.MinimumUsdThreshold,
 			s.config.CheckInterval,
 		)
+		s.claimer.GetServiceFee().Disclose()
+		if s.config.StartupSummaryTelegram {
+			s.telegramClient.SendConfigSummary(config.FormatSummary(s.config.Summary()))
+		}
 	}
 
+	defer s.savePriceHistory()
+	// Sales started by the last cycle give up once ctx is done, those already sent are recorded first
+	defer s.sales.Wait()
+
 	// Run in a loop
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		default:
-			s.processAirdrops(ctx)
+			s.runCycle(ctx)
 
 			// Wait before the next scan
 			s.logger.Println("Waiting for next scan cycle...")
-			time.Sleep(s.config.CheckInterval)
+			if !sleepWithContext(ctx, s.nextScanWait()) {
+				return
+			}
+		}
+	}
+}
+
+// sleepWithContext waits for the given duration, returning false early if ctx is cancelled
+func sleepWithContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// nextScanWait returns the wait before the next scan. While the API clients are rate limited the check interval
+// is doubled for every cycle that ran into new rate limits, and the wait lasts at least until the latest
+// Retry-After has passed. The interval is restored once no rate limit arrived for the cooldown.
+func (s *Service) nextScanWait() time.Duration {
+	// Only this wallet's clients count, other wallets of the process pace their own scans
+	limits := append([]*retry.RateLimits{s.scanner.RateLimits()}, s.claimer.GetRateLimits()...)
+	status := retry.CombinedStatus(s.config.RateLimitCooldown, limits...)
+	newHits := status.Count > s.rateLimitCount
+	s.rateLimitCount = status.Count
+	s.checkRateLimitAlert(status)
+
+	if !status.Limited || s.config.RateLimitCooldown <= 0 {
+		if s.rateLimitFactor > 1 {
+			s.logger.Printf("No rate limits for %s, scanning every %s again", s.config.RateLimitCooldown, s.config.CheckInterval)
 		}
+		s.rateLimitFactor = 1
+		return s.config.CheckInterval
+	}
+
+	if newHits {
+		s.rateLimitFactor = min(max(s.rateLimitFactor, 1)*2, maxRateLimitFactor)
+	}
+	wait := s.config.CheckInterval * time.Duration(max(s.rateLimitFactor, 1))
+	wait = max(wait, time.Until(status.Until))
+	logging.Warnf(s.logger, "Warning: Rate limited by %s, waiting %s before the next scan",
+		rateLimitedHosts(status), wait.Round(time.Second))
+	return wait
+}
+
+// rateLimitedHosts names the hosts rate limited within the cooldown for the log
+func rateLimitedHosts(status retry.RateLimitStatus) string {
+	if len(status.Hosts) == 0 {
+		return "a server asking to wait"
+	}
+	return strings.Join(status.Hosts, ", ")
+}
+
+// checkRateLimitAlert notifies once rate limiting persisted for the alert delay, and again when it ends
+func (s *Service) checkRateLimitAlert(status retry.RateLimitStatus) {
+	if !status.Limited {
+		if s.rateLimitAlerted && s.telegramClient != nil && s.telegramClient.Enabled {
+			s.telegramClient.SendRateLimitNotification(false, nil, s.rateLimitedSince)
+		}
+		s.rateLimitedSince = time.Time{}
+		s.rateLimitAlerted = false
+		return
+	}
+
+	if s.rateLimitedSince.IsZero() {
+		s.rateLimitedSince = time.Now()
+	}
+	if s.config.RateLimitAlertAfter <= 0 || s.rateLimitAlerted || time.Since(s.rateLimitedSince) < s.config.RateLimitAlertAfter {
+		return
+	}
+	s.rateLimitAlerted = true
+
+	logging.Warnf(s.logger, "Warning: Rate limited for %s by %s", time.Since(s.rateLimitedSince).Round(time.Minute), rateLimitedHosts(status))
+	if s.telegramClient != nil && s.telegramClient.Enabled {
+		s.telegramClient.SendRateLimitNotification(true, status.Hosts, s.rateLimitedSince)
 	}
 }
 
 // processAirdrops scans for and processes available airdrops
 func (s *Service) processAirdrops(ctx context.Context) {
+	// Nothing is scanned, claimed or sold while the kill switch is engaged
+	if s.checkKillSwitch(ctx) {
+		return
+	}
+
+	if s.paused.Load() {
+		s.logger.Println("Paused, skipping cycle")
+		return
+	}
+
+	// Replace estimated claim and sale records with on-chain values once their transactions are available
+	s.tokenSeller.ReconcileEstimates(ctx)
+	// Record claims made outside the bot, such as in the web app
+	s.claimReconciler.ReconcileIfDue(ctx)
+
+	s.sendWeeklyDigestIfDue()
+	s.checkEfficiencyIfDue()
+	s.learnTokenPerformanceIfDue()
+	s.claimer.GetVault().SweepIfDue(ctx)
+	s.claimer.GetRentReclaimer().ReclaimIfDue(ctx)
+	s.claimer.GetPositionCap().CheckIfDue(ctx)
+	s.claimer.GetCostBasis().CheckIfDue(ctx)
+
 	// Scan for airdrops (including previously seen ones to update values)
 	s.logger.Println("Scanning for airdrops and updating values...")
 	valuableAirdrops, err := s.scanner.ScanAirdrops(ctx, 0.001) // Use a very low threshold to get all airdrops
@@ -93,53 +327,79 @@ func (s *Service) processAirdrops(ctx context.Context) {
 			strings.Contains(strings.ToLower(err.Error()), "auth") ||
 			strings.Contains(strings.ToLower(err.Error()), "token") {
 			// Refresh token on auth errors
-			s.refreshAuthToken()
+			s.refreshAuthToken(ctx)
 		}
 
-		s.handleScanError(err)
+		s.handleScanError(ctx, err)
 		return
 	}
+	s.scanFailures = 0
+
+	s.checkDegradedMode()
+	s.claimer.ResolveDecimals(ctx, valuableAirdrops)
+	s.notifyUpcomingAirdrops(ctx)
+	s.screenTokens(ctx, valuableAirdrops)
 
 	// Update price history and find claimable airdrops
 	filteredAirdrops := s.processAndFilterAirdrops(ctx, valuableAirdrops)
 	s.logger.Printf("Found %d valuable airdrop(s) meeting threshold", len(filteredAirdrops))
 
-	// Process each valuable airdrop
+	s.checkCongestion(ctx)
+
+	if s.config.RepriceBeforeClaim {
+		s.prefetchPrices(filteredAirdrops)
+	}
+
+	// Select the airdrops to claim, they are claimed in parallel below
+	var eligible []models.AirdropNode
 	for _, airdrop := range filteredAirdrops {
 		if s.isAlreadyClaimed(airdrop) {
 			continue
 		}
 
-		// Attempt to claim the airdrop
-		txHash, err := s.claimer.ClaimAirdropByID(ctx, airdrop.ID)
-		if err != nil {
-			// If error is auth-related, try refreshing the token and retry once
-			if strings.Contains(strings.ToLower(err.Error()), "unauthorized") ||
-				strings.Contains(strings.ToLower(err.Error()), "auth") ||
-				strings.Contains(strings.ToLower(err.Error()), "token") {
-				s.refreshAuthToken()
-				// Retry the claim after token refresh
-				txHash, err = s.claimer.ClaimAirdropByID(ctx, airdrop.ID)
+		// API values can be minutes old, confirm the claim still makes sense at the live price. Accumulated
+		// drops are claimed for their combined value, each of them alone is below the threshold.
+		if s.config.RepriceBeforeClaim && !s.accumulator.Covers(airdrop.Token.Address) {
+			var ok bool
+			if airdrop, ok = s.repriceAirdrop(airdrop); !ok {
+				continue
 			}
 		}
 
-		s.handleClaimResult(ctx, airdrop, txHash, err)
+		if s.deferClaim(airdrop) {
+			s.audit.Record(airdrop, s.priceTracker.GetTokenPriceInfo(airdrop.ID), solana.DecisionHold, "network congested", checkCongestion)
+			continue
+		}
+		if _, deferred := s.deferredClaims[airdrop.ID]; deferred {
+			s.audit.Record(airdrop, s.priceTracker.GetTokenPriceInfo(airdrop.ID), solana.DecisionClaim, checkPassed, checkCongestion)
+			delete(s.deferredClaims, airdrop.ID)
+		}
+
+		if s.claimDelay.Hold(airdrop) {
+			s.audit.Record(airdrop, s.priceTracker.GetTokenPriceInfo(airdrop.ID), solana.DecisionHold, "randomized delay", checkClaimDelay)
+			continue
+		}
 
-		// Wait between claims to avoid transaction failures
-		s.logger.Println("Waiting 60 seconds before checking for more airdrops...")
-		time.Sleep(60 * time.Second)
-		break
+		eligible = append(eligible, airdrop)
 	}
+
+	// In approval mode only airdrops approved on Telegram are claimed
+	eligible = s.approvals.Filter(eligible)
+
+	s.claimAirdrops(ctx, eligible)
 }
 
 // refreshAuthToken refreshes the authentication token if using private key auth
 // and it hasn't been refreshed recently
-func (s *Service) refreshAuthToken() {
+func (s *Service) refreshAuthToken(ctx context.Context) {
 	// Only proceed if using private key auth and token manager exists
 	if s.config.TokenManager == nil || s.config.WalletPrivateKey == "" {
 		return
 	}
 
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
 	// Check if we've refreshed recently (within last 30 minutes)
 	if !s.lastTokenRefresh.IsZero() && time.Since(s.lastTokenRefresh) < 30*time.Minute {
 		s.logger.Println("Token was refreshed recently, skipping refresh")
@@ -148,41 +408,43 @@ func (s *Service) refreshAuthToken() {
 
 	// Refresh the token
 	s.logger.Println("Refreshing authentication token...")
-	err := s.config.RefreshAuthToken()
+	err := s.config.RefreshAuthTokenWithContext(ctx)
 	if err != nil {
-		s.logger.Printf("Warning: Failed to refresh auth token: %v", err)
+		logging.Warnf(s.logger, "Warning: Failed to refresh auth token: %v", err)
 	} else {
 		s.lastTokenRefresh = time.Now()
 		s.logger.Println("Auth token refreshed successfully")
 	}
 }
 
-// handleScanError processes errors during airdrop scanning
-func (s *Service) handleScanError(err error) {
-	s.logger.Printf("Error scanning airdrops: %v", err)
+// handleScanError processes errors during airdrop scanning, backing off further with every scan that fails
+// in a row. Network errors are retried sooner than other errors.
+func (s *Service) handleScanError(ctx context.Context, err error) {
+	logging.Errorf(s.logger, "Error scanning airdrops: %v", err)
 
-	// Check for network error and retry quickly
-	if strings.Contains(err.Error(), "unexpected EOF") ||
-		strings.Contains(err.Error(), "connection refused") ||
-		strings.Contains(err.Error(), "i/o timeout") {
-		s.logger.Println("Network error detected, retrying in 3 seconds...")
-		time.Sleep(3 * time.Second)
-		return
+	s.scanFailures++
+	policy := scanErrorBackoff
+	if retry.Classify(err) == retry.Network {
+		policy = scanNetworkErrorBackoff
 	}
-
-	time.Sleep(30 * time.Second)
+	delay := policy.Delay(s.scanFailures)
+	logging.Warnf(s.logger, "Scan failed %d time(s) in a row, retrying in %s", s.scanFailures, delay.Round(time.Second))
+	sleepWithContext(ctx, delay)
 }
 
 // handleClaimResult processes the result of a claim attempt
 func (s *Service) handleClaimResult(ctx context.Context, airdrop models.AirdropNode, txHash string, err error) {
-	usdValue, _ := strconv.ParseFloat(airdrop.AmountUsd, 64)
+	logger := operation.Logger(ctx, s.logger)
+	s.audit.RecordOutcome(airdrop, s.priceTracker.GetTokenPriceInfo(airdrop.ID), solana.DecisionClaim, txHash, err)
+
+	usdValue := airdrop.AmountUsd.Float()
 
 	if err != nil {
-		s.logger.Printf("Failed to claim airdrop %s: %v", airdrop.ID, err)
+		logging.Errorf(logger, "Failed to claim airdrop %s: %v", airdrop.ID, err)
 
 		// If it's a permanent error, mark as claimed to prevent repeated attempts
 		if IsPermanentClaimError(err) {
-			s.logger.Printf("Marking airdrop %s as claimed due to permanent error", airdrop.ID)
+			logger.Printf("Marking airdrop %s as claimed due to permanent error", airdrop.ID)
 			s.claimedMutex.Lock()
 			s.claimedAirdrops[airdrop.ID] = true
 			s.claimedMutex.Unlock()
@@ -191,37 +453,607 @@ func (s *Service) handleClaimResult(ctx context.Context, airdrop models.AirdropN
 	}
 
 	// Mark as successfully claimed
-	s.logger.Printf("Successfully claimed airdrop %s (%s) worth $%.2f! Transaction hash: %s",
+	logger.Printf("Successfully claimed airdrop %s (%s) worth $%.2f! Transaction hash: %s",
 		airdrop.ID, airdrop.Token.Symbol, usdValue, txHash)
 	s.claimedMutex.Lock()
 	s.claimedAirdrops[airdrop.ID] = true
 	s.claimedMutex.Unlock()
+	s.scanner.MarkClaimed(airdrop.ID)
+	s.accumulator.Claimed(airdrop)
+}
+
+// sendWeeklyDigestIfDue sends the weekly profit and realization digest once every 7 days
+func (s *Service) sendWeeklyDigestIfDue() {
+	if time.Since(s.lastDigest) < 7*24*time.Hour {
+		return
+	}
+	s.lastDigest = time.Now()
+	if err := s.digestState.Save(digestState{LastSent: s.lastDigest}); err != nil {
+		logging.Warnf(s.logger, "Warning: Failed to save weekly digest time: %v", err)
+	}
+
+	statsRecorder := s.claimer.GetStatsRecorder()
+	if statsRecorder == nil || s.telegramClient == nil || !s.telegramClient.Enabled {
+		return
+	}
+
+	var profitSummary *notifications.ProfitSummary
+	if stats, err := statsRecorder.GetProfitSummary(s.config.ProfitIncludeEstimates); err == nil {
+		profitSummary = &notifications.ProfitSummary{
+			Last24h:       stats.Last24h,
+			LastWeek:      stats.LastWeek,
+			ProjectedWeek: stats.ProjectedWeek,
+		}
+	}
+
+	realization, err := statsRecorder.GetRealizationSummary(time.Now().Add(-7 * 24 * time.Hour))
+	if err != nil {
+		logging.Warnf(s.logger, "Warning: Failed to compute realization stats: %v", err)
+	}
+
+	byStrategy := make(map[string]notifications.RealizationStats)
+	for strategy, stats := range realization.ByStrategy {
+		byStrategy[strategy] = notifications.RealizationStats(stats)
+	}
+
+	s.logger.Printf("Weekly realization: %d sale(s), expected $%.2f, realized $%.2f (%.0f%%)",
+		realization.Total.Count, realization.Total.ExpectedUsd, realization.Total.RealizedUsd, realization.Total.Ratio()*100)
+
+	vaultTransfers := make(map[string]int)
+	transfers, err := statsRecorder.GetVaultTransfers(time.Now().Add(-7 * 24 * time.Hour))
+	if err != nil {
+		logging.Warnf(s.logger, "Warning: Failed to read vault transfers: %v", err)
+	}
+	for _, transfer := range transfers {
+		vaultTransfers[transfer.TokenSymbol]++
+	}
+
+	var efficiency *notifications.EfficiencyStats
+	if summary, err := statsRecorder.GetEfficiencySummary(time.Now().Add(-efficiencyPeriod), s.config.ProfitIncludeEstimates); err != nil {
+		logging.Warnf(s.logger, "Warning: Failed to compute fee efficiency: %v", err)
+	} else {
+		stats := notifications.EfficiencyStats(summary.Total)
+		efficiency = &stats
+	}
+
+	var gains *notifications.GainStats
+	if stats, err := statsRecorder.GetRealizedGainStats(time.Now().Add(-7 * 24 * time.Hour)); err != nil {
+		logging.Warnf(s.logger, "Warning: Failed to read realized gains: %v", err)
+	} else {
+		converted := notifications.GainStats(stats)
+		gains = &converted
+	}
+
+	byToken := make(map[string]notifications.RealizationStats)
+	for token, stats := range realization.ByToken {
+		byToken[token] = notifications.RealizationStats(stats)
+	}
+
+	s.telegramClient.SendWeeklyDigest(profitSummary, notifications.RealizationStats(realization.Total), byStrategy, byToken, vaultTransfers, efficiency, gains)
+}
+
+// serviceState returns the state saved under name with the statistics, in their database with the bolt backend
+func serviceState(cfg *config.Config, claimer *service.AirdropClaimer, name string) solana.StateStore {
+	if statsRecorder := claimer.GetStatsRecorder(); statsRecorder != nil {
+		return statsRecorder.State(name)
+	}
+	return solana.NewFileState(filepath.Join(cfg.StatsDataDir, name+".json"))
+}
+
+// digestState is the saved state of the weekly digest
+type digestState struct {
+	LastSent time.Time `json:"lastSent"`
+}
+
+// loadLastDigest returns when the weekly digest was last sent, now when it never was so the first digest
+// covers a full week
+func loadLastDigest(state solana.StateStore, logger *log.Logger) time.Time {
+	var saved digestState
+	found, err := state.Load(&saved)
+	if err != nil {
+		logging.Warnf(logger, "WARNING: Failed to read weekly digest time: %v", err)
+	}
+	if !found || err != nil || saved.LastSent.IsZero() {
+		now := time.Now()
+		if err := state.Save(digestState{LastSent: now}); err != nil {
+			logging.Warnf(logger, "WARNING: Failed to save weekly digest time: %v", err)
+		}
+		return now
+	}
+	return saved.LastSent
+}
+
+// checkEfficiencyIfDue compares the fees of the last week with the sale proceeds and notifies when their ratio
+// rises past the alert ratio or falls back below it
+func (s *Service) checkEfficiencyIfDue() {
+	if s.config.EfficiencyAlertRatio <= 0 || time.Since(s.lastEfficiencyCheck) < efficiencyCheckInterval {
+		return
+	}
+	s.lastEfficiencyCheck = time.Now()
+
+	statsRecorder := s.claimer.GetStatsRecorder()
+	if statsRecorder == nil {
+		return
+	}
+
+	summary, err := statsRecorder.GetEfficiencySummary(time.Now().Add(-efficiencyPeriod), s.config.ProfitIncludeEstimates)
+	if err != nil {
+		logging.Warnf(s.logger, "Warning: Failed to compute fee efficiency: %v", err)
+		return
+	}
+	if summary.Total.Sales < efficiencyMinSales {
+		return
+	}
+
+	degraded := summary.Total.Ratio() > s.config.EfficiencyAlertRatio
+	if degraded == s.efficiencyDegraded {
+		return
+	}
+	s.efficiencyDegraded = degraded
+
+	if degraded && math.IsInf(summary.Total.Ratio(), 1) {
+		logging.Warnf(s.logger, "Warning: %.6f SOL of fees were paid over the last week without sale proceeds",
+			summary.Total.FeesSol)
+	} else if degraded {
+		logging.Warnf(s.logger, "Warning: Fees were %.1f%% of sale proceeds over the last week, above the %.1f%% alert ratio",
+			summary.Total.Ratio()*100, s.config.EfficiencyAlertRatio*100)
+	} else {
+		s.logger.Printf("Fees are back to %.1f%% of sale proceeds over the last week", summary.Total.Ratio()*100)
+	}
+	if s.telegramClient != nil && s.telegramClient.Enabled {
+		s.telegramClient.SendEfficiencyNotification(degraded, notifications.EfficiencyStats(summary.Total), s.config.EfficiencyAlertRatio)
+	}
+}
+
+// learnTokenPerformanceIfDue relearns per-token performance from the recorded history every few hours
+// and hands it to the decision maker, keeping the model on disk for inspection
+func (s *Service) learnTokenPerformanceIfDue() {
+	if !s.config.TokenLearning || time.Since(s.lastTokenLearning) < tokenLearningInterval {
+		return
+	}
+	s.lastTokenLearning = time.Now()
+
+	statsRecorder := s.claimer.GetStatsRecorder()
+	if statsRecorder == nil {
+		return
+	}
+
+	since := time.Now().Add(-tokenLearningPeriod)
+	history, err := statsRecorder.GetAirdropValues(since)
+	if err != nil {
+		logging.Warnf(s.logger, "Warning: Failed to read airdrop value history for token learning: %v", err)
+		return
+	}
+	outcomes, err := statsRecorder.GetOutcomes(since)
+	if err != nil {
+		logging.Warnf(s.logger, "Warning: Failed to read outcomes for token learning: %v", err)
+	}
+
+	model := strategy.LearnTokenPerformance(history, outcomes, s.decisionMaker.strategy.StableDuration)
+	if err := model.Save(tokenPerformancePath(s.config)); err != nil {
+		logging.Warnf(s.logger, "Warning: Failed to save token performance model: %v", err)
+	}
+	s.decisionMaker.SetTokenPerformance(model)
+
+	skipped := 0
+	for _, performance := range model.Sorted() {
+		if performance.SkipStableWait {
+			skipped++
+			s.logger.Printf("Token learning: %s changed %.0f%% on average over the stability wait (%d samples), skipping the wait",
+				performance.Symbol, performance.AvgWaitChange*100, performance.WaitSamples)
+		}
+	}
+	s.logger.Printf("Token learning: updated %d token(s), %d skip the stability wait", len(model.Tokens), skipped)
+}
+
+// tokenPerformancePath returns where the learned token performance model is kept
+func tokenPerformancePath(cfg *config.Config) string {
+	return filepath.Join(cfg.StatsDataDir, "token_performance.json")
+}
+
+// priceHistoryPath is where the price history is kept between runs
+func priceHistoryPath(cfg *config.Config) string {
+	return filepath.Join(cfg.StatsDataDir, "price_history.json")
+}
+
+// newPriceTrackerFromConfig creates the price tracker, restoring the price history of the last run
+func newPriceTrackerFromConfig(cfg *config.Config, logger *log.Logger) *PriceTracker {
+	tracker := NewPriceTracker(cfg.PriceHistorySamples)
+	if cfg.PriceHistorySamples > 0 {
+		if err := tracker.Load(priceHistoryPath(cfg)); err != nil {
+			logging.Warnf(logger, "WARNING: Starting without price history: %v", err)
+		}
+	}
+	return tracker
+}
+
+// savePriceHistory keeps the price history for the next run
+func (s *Service) savePriceHistory() {
+	if s.config.PriceHistorySamples <= 0 {
+		return
+	}
+	if err := s.priceTracker.Save(priceHistoryPath(s.config)); err != nil {
+		logging.Warnf(s.logger, "Warning: Failed to save price history: %v", err)
+	}
+}
+
+// checkDegradedMode notifies when the scanner starts or stops serving airdrops from the cache
+func (s *Service) checkDegradedMode() {
+	usingCache, cachedAt := s.scanner.UsingCachedData()
+	if usingCache == s.degradedMode {
+		return
+	}
+	s.degradedMode = usingCache
+
+	if s.telegramClient != nil && s.telegramClient.Enabled {
+		s.telegramClient.SendDegradedModeNotification(usingCache, cachedAt)
+	}
 }
 
 // processAndFilterAirdrops processes all airdrops and returns those that should be claimed
 func (s *Service) processAndFilterAirdrops(ctx context.Context, airdrops []models.AirdropNode) []models.AirdropNode {
-	var filteredAirdrops []models.AirdropNode
+	var filteredAirdrops, accumulating, pending []models.AirdropNode
 
 	for _, airdrop := range airdrops {
 		// Skip if already claimed
 		if s.isAlreadyClaimed(airdrop) {
 			continue
 		}
+		pending = append(pending, airdrop)
 
-		// Update price tracking data
-		s.priceTracker.UpdatePriceData(airdrop)
+		// Update price tracking data, persisting changes for backtesting
+		if s.priceTracker.UpdatePriceData(airdrop) {
+			s.recordAirdropValue(airdrop)
+		}
+
+		// Drops of tokens with an accumulation rule are judged by their combined value below, once they passed
+		// safety screening
+		if s.accumulator.Covers(airdrop.Token.Address) {
+			if allowed, _ := s.decisionMaker.EvaluateSafety(airdrop); !allowed {
+				s.whales.Check(airdrop, "not claiming yet ("+reasonUnsafeToken+")")
+				s.audit.Record(airdrop, s.priceTracker.GetTokenPriceInfo(airdrop.ID), solana.DecisionSkip, reasonUnsafeToken, "")
+				continue
+			}
+			s.whales.Check(airdrop, "judged with the other held back drops of this token")
+			accumulating = append(accumulating, airdrop)
+			continue
+		}
 
 		// Check if we should claim this airdrop
 		priceInfo := s.priceTracker.GetTokenPriceInfo(airdrop.ID)
-		if s.decisionMaker.ShouldClaim(airdrop, priceInfo) {
+		shouldClaim, reason := s.decisionMaker.EvaluateClaim(airdrop, priceInfo)
+		s.shadow.EvaluateClaim(airdrop, priceInfo, shouldClaim)
+		if shouldClaim {
+			s.whales.Check(airdrop, "eligible for claiming ("+reason+")")
+			s.audit.Record(airdrop, priceInfo, solana.DecisionClaim, reason, "")
 			filteredAirdrops = append(filteredAirdrops, airdrop)
 		} else {
+			s.whales.Check(airdrop, "not claiming yet ("+reason+")")
+			s.audit.Record(airdrop, priceInfo, solana.DecisionSkip, reason, "")
+
 			// Check if stable token should be sold directly
 			s.handleStableTokenSale(ctx, airdrop, priceInfo)
 		}
 	}
 
-	return filteredAirdrops
+	s.shadow.LogReportIfDue()
+	s.whales.Prune(pending)
+	s.audit.Prune(pending)
+
+	return s.holdWhales(append(filteredAirdrops, s.releaseAccumulated(accumulating)...))
+}
+
+// releaseAccumulated returns the accumulated drops released by their rules whose estimated net profits, added
+// up per token, reach the profit margin
+func (s *Service) releaseAccumulated(accumulating []models.AirdropNode) []models.AirdropNode {
+	released := s.accumulator.Release(accumulating)
+
+	// Release groups the drops by token
+	var claimable []models.AirdropNode
+	for start := 0; start < len(released); {
+		end := start + 1
+		for end < len(released) && released[end].Token.Address == released[start].Token.Address {
+			end++
+		}
+		drops := released[start:end]
+		start = end
+
+		if !s.claimer.GetProfitCheck().AllowsTogether(drops) {
+			for _, airdrop := range drops {
+				s.audit.Record(airdrop, s.priceTracker.GetTokenPriceInfo(airdrop.ID), solana.DecisionSkip,
+					"combined net profit below margin", checkProfit)
+			}
+			continue
+		}
+		claimable = append(claimable, drops...)
+	}
+	return claimable
+}
+
+// holdWhales leaves out whale airdrops whose alert is more recent than the whale hold window
+func (s *Service) holdWhales(airdrops []models.AirdropNode) []models.AirdropNode {
+	claimable := airdrops[:0]
+	for _, airdrop := range airdrops {
+		if held, until := s.whales.Holds(airdrop); held {
+			s.audit.Record(airdrop, s.priceTracker.GetTokenPriceInfo(airdrop.ID), solana.DecisionHold,
+				"held after the whale alert until "+until.Format("15:04"), checkWhale)
+			continue
+		}
+		claimable = append(claimable, airdrop)
+	}
+	return claimable
+}
+
+// newCongestionFromConfig creates the network congestion monitor, or nil when congestion scheduling is disabled
+func newCongestionFromConfig(cfg *config.Config, claimer *service.AirdropClaimer, logger *log.Logger) *solana.CongestionMonitor {
+	if !cfg.CongestionScheduling {
+		return nil
+	}
+
+	client, ok := claimer.GetSolClient().(solana.CongestionClient)
+	if !ok {
+		logging.Warnf(logger, "WARNING: Congestion scheduling disabled, the Solana client cannot report priority fees")
+		return nil
+	}
+
+	logger.Printf("Congestion scheduling enabled - fee multiplier: %.1fx, max fee: %d µlamports/CU, urgent above: $%.2f, max deferral: %s",
+		cfg.CongestionMultiplier, cfg.CongestionMaxFee, cfg.CongestionUrgentUsd, cfg.CongestionMaxDefer)
+	return solana.NewCongestionMonitor(client, nil, cfg.CongestionMultiplier, uint64(cfg.CongestionMaxFee))
+}
+
+// Token safety modes, "off" disables screening
+const (
+	safetyFlag = "flag"
+	safetySkip = "skip"
+)
+
+// newSafetyFromConfig creates the token safety screener, or nil when screening is disabled
+func newSafetyFromConfig(cfg *config.Config, claimer *service.AirdropClaimer, logger *log.Logger) *safety.Screener {
+	if cfg.SafetyMode != safetyFlag && cfg.SafetyMode != safetySkip {
+		if cfg.SafetyMode != "" && cfg.SafetyMode != "off" {
+			logging.Warnf(logger, "WARNING: Unknown SAFETY_MODE %q, token safety screening disabled", cfg.SafetyMode)
+		}
+		return nil
+	}
+
+	accounts, ok := claimer.GetSolClient().(safety.AccountReader)
+	if !ok {
+		logging.Warnf(logger, "WARNING: Token safety screening disabled, the Solana client cannot read accounts")
+		return nil
+	}
+	var routes safety.RouteQuoter
+	if swap := claimer.GetSwapService(); swap != nil {
+		routes = swap
+	}
+
+	logger.Printf("Token safety screening enabled (%s mode), results reused for %s", cfg.SafetyMode, cfg.SafetyCheckTTL)
+	return safety.NewScreener(accounts, routes, cfg.SafetyMaxTopHolder, cfg.SafetyCheckTTL, logger)
+}
+
+// screenTokens screens the tokens of unclaimed airdrops and notifies once about each token that fails
+func (s *Service) screenTokens(ctx context.Context, airdrops []models.AirdropNode) {
+	if s.safety == nil {
+		return
+	}
+
+	var mints []string
+	pending := make(map[string]bool)
+	for _, airdrop := range airdrops {
+		if airdrop.ClaimedAt == nil && !s.isAlreadyClaimed(airdrop) {
+			mints = append(mints, airdrop.Token.Address)
+			pending[airdrop.Token.Address] = true
+		}
+	}
+	reports := s.safety.Screen(ctx, mints)
+
+	// Tokens without unclaimed airdrops are forgotten, a later failing drop of theirs is notified again
+	for mint := range s.unsafeAlerted {
+		if !pending[mint] {
+			delete(s.unsafeAlerted, mint)
+		}
+	}
+
+	for _, airdrop := range airdrops {
+		report, ok := reports[airdrop.Token.Address]
+		if !ok || report.Safe() || s.unsafeAlerted[report.Mint] {
+			continue
+		}
+		s.unsafeAlerted[report.Mint] = true
+
+		if s.telegramClient != nil && s.telegramClient.Enabled {
+			s.telegramClient.SendUnsafeTokenNotification(airdrop.Token.Name, airdrop.Token.Symbol, report.Mint,
+				airdrop.AmountUsd.Float(), report.Summary(), s.config.SafetyMode == safetySkip)
+		}
+	}
+}
+
+// checkKillSwitch checks the kill switch file and remote flag, alerting when it is engaged or released.
+// It returns true while signing is stopped.
+func (s *Service) checkKillSwitch(ctx context.Context) bool {
+	engaged, reason, changed := s.claimer.GetKillSwitch().Check(ctx)
+	if !changed {
+		if engaged {
+			s.logger.Printf("Kill switch engaged (%s), skipping cycle", reason)
+		}
+		return engaged
+	}
+
+	if engaged {
+		s.logger.Printf("KILL SWITCH ENGAGED: %s. All signing is stopped until it is released.", reason)
+	} else {
+		s.logger.Println("Kill switch released, signing resumes")
+	}
+
+	if s.telegramClient != nil && s.telegramClient.Enabled {
+		s.telegramClient.SendKillSwitchNotification(engaged, reason)
+	}
+	return engaged
+}
+
+// checkCongestion measures network congestion and notifies when claims start or stop being deferred
+func (s *Service) checkCongestion(ctx context.Context) {
+	if s.congestion == nil {
+		return
+	}
+
+	wasCongested := s.congestion.Status().Congested
+	status, err := s.congestion.Check(ctx)
+	if err != nil {
+		// Keep the previous state, claims are not held back by a failing fee query
+		logging.Warnf(s.logger, "Warning: Failed to measure network congestion: %v", err)
+		return
+	}
+
+	if status.Congested == wasCongested {
+		return
+	}
+
+	if status.Congested {
+		s.logger.Printf("Network congested (%s), deferring non-urgent claims", status.Reason())
+	} else {
+		s.logger.Printf("Network back to normal (%d µlamports/CU), resuming %d deferred claim(s)",
+			status.PriorityFee, len(s.deferredClaims))
+	}
+
+	if s.telegramClient != nil && s.telegramClient.Enabled {
+		s.telegramClient.SendCongestionNotification(*s.CongestionStatus())
+	}
+	if !status.Congested {
+		// Airdrops deferred earlier that are no longer listed would otherwise stay tracked forever
+		s.deferredClaims = make(map[string]time.Time)
+	}
+}
+
+// deferClaim reports whether a claim should wait for congestion to clear. Airdrops worth at least
+// the urgent threshold, and claims already deferred for the maximum deferral, are sent anyway.
+func (s *Service) deferClaim(airdrop models.AirdropNode) bool {
+	if s.congestion == nil || !s.congestion.Status().Congested {
+		return false
+	}
+
+	usdValue := airdrop.AmountUsd.Float()
+	if usdValue >= s.config.CongestionUrgentUsd {
+		s.logger.Printf("Network congested, claiming %s anyway: $%.2f is above the urgent threshold", airdrop.Token.Symbol, usdValue)
+		return false
+	}
+
+	deferredAt, ok := s.deferredClaims[airdrop.ID]
+	if !ok {
+		deferredAt = time.Now()
+		s.deferredClaims[airdrop.ID] = deferredAt
+	}
+	if s.config.CongestionMaxDefer > 0 && time.Since(deferredAt) >= s.config.CongestionMaxDefer {
+		s.logger.Printf("Network congested, claiming %s anyway: deferred for %s", airdrop.Token.Symbol, time.Since(deferredAt).Round(time.Minute))
+		return false
+	}
+
+	s.logger.Printf("Network congested, deferring claim of %s ($%.2f)", airdrop.Token.Symbol, usdValue)
+	return true
+}
+
+// CongestionStatus returns the network congestion state for status reports, nil when congestion scheduling is disabled
+func (s *Service) CongestionStatus() *notifications.CongestionStatus {
+	if s.congestion == nil {
+		return nil
+	}
+
+	status := s.congestion.Status()
+	return &notifications.CongestionStatus{
+		Congested:      status.Congested,
+		PriorityFee:    status.PriorityFee,
+		BaselineFee:    status.BaselineFee,
+		SlotTime:       status.SlotTime,
+		Since:          status.Since,
+		DeferredClaims: len(s.deferredClaims),
+	}
+}
+
+// newShadowFromConfig creates the shadow strategy evaluator, or nil when shadow mode is disabled
+func newShadowFromConfig(cfg *config.Config, logger *log.Logger) *ShadowEvaluator {
+	if cfg.ShadowMinimumUsd <= 0 {
+		return nil
+	}
+
+	rules := strategy.Strategy{
+		MinimumUsdThreshold: cfg.ShadowMinimumUsd,
+		StableMinimumUsd:    cfg.ShadowStableMinimumUsd,
+		StableDuration:      cfg.ShadowStableDuration,
+	}
+	logger.Printf("Shadow mode enabled - threshold: $%.2f, stable minimum: $%.2f, stable duration: %s",
+		rules.MinimumUsdThreshold, rules.StableMinimumUsd, rules.StableDuration)
+
+	shadowLogger := log.New(io.Discard, "", 0)
+	return NewShadowEvaluator(NewDecisionMakerWithStrategy(cfg, rules, shadowLogger), logger)
+}
+
+// prefetchPrices fetches the live prices of all airdrops about to be re-priced in one Price API request
+func (s *Service) prefetchPrices(airdrops []models.AirdropNode) {
+	prefetcher, ok := s.claimer.GetSwapService().(service.PricePrefetcher)
+	if !ok {
+		return
+	}
+
+	mints := make([]string, 0, len(airdrops))
+	for _, airdrop := range airdrops {
+		mints = append(mints, airdrop.Token.Address)
+	}
+	prefetcher.PrefetchPrices(mints)
+}
+
+// repriceAirdrop refreshes the airdrop's USD value from a live Jupiter price and re-runs the claim decision.
+// When no live price is available the API value is kept.
+func (s *Service) repriceAirdrop(airdrop models.AirdropNode) (models.AirdropNode, bool) {
+	price, err := s.claimer.GetSwapService().GetTokenUsdPrice(airdrop.Token.Address)
+	if err != nil {
+		s.logger.Printf("Could not re-price %s before claiming, using API value $%s: %v",
+			airdrop.Token.Symbol, airdrop.AmountUsd, err)
+		return airdrop, true
+	}
+
+	if !airdrop.AmountLpt.Valid() {
+		return airdrop, true
+	}
+
+	apiUsd, hasApiUsd := airdrop.UsdValue()
+	liveUsd := airdrop.TokenAmount().Float() * price
+	s.logger.Printf("Re-priced %s (%s): API $%.4f -> live $%.4f",
+		airdrop.ID, airdrop.Token.Symbol, apiUsd, liveUsd)
+
+	airdrop.AmountUsd = models.NewUSD(liveUsd)
+	if !hasApiUsd {
+		// The claim was decided on the SOL value, the live price only fills in the USD value for the records
+		return airdrop, true
+	}
+	priceInfo := s.priceTracker.GetTokenPriceInfo(airdrop.ID)
+	if claim, reason := s.decisionMaker.EvaluateClaim(airdrop, priceInfo); !claim {
+		s.logger.Printf("Skipping claim of %s (%s): live value $%.4f no longer meets the claim criteria",
+			airdrop.ID, airdrop.Token.Symbol, liveUsd)
+		s.audit.Record(airdrop, priceInfo, solana.DecisionSkip, reason, checkReprice)
+		return airdrop, false
+	}
+
+	s.audit.Record(airdrop, priceInfo, solana.DecisionClaim, checkPassed, checkReprice)
+	return airdrop, true
+}
+
+// recordAirdropValue persists an airdrop value observation to the stats history
+func (s *Service) recordAirdropValue(airdrop models.AirdropNode) {
+	statsRecorder := s.claimer.GetStatsRecorder()
+	if statsRecorder == nil {
+		return
+	}
+
+	usdValue := airdrop.AmountUsd.Float()
+	err := statsRecorder.RecordAirdropValue(solana.AirdropValue{
+		AirdropID:   airdrop.ID,
+		TokenSymbol: airdrop.Token.Symbol,
+		TokenMint:   airdrop.Token.Address,
+		AmountUsd:   usdValue,
+		Claimed:     airdrop.ClaimedAt != nil,
+	})
+	if err != nil {
+		logging.Warnf(s.logger, "Warning: Failed to record airdrop value history: %v", err)
+	}
 }
 
 // isAlreadyClaimed checks if an airdrop has already been claimed
@@ -230,14 +1062,15 @@ func (s *Service) isAlreadyClaimed(airdrop models.AirdropNode) bool {
 	defer s.claimedMutex.Unlock()
 
 	if s.claimedAirdrops[airdrop.ID] {
-		s.logger.Printf("Skipping already claimed airdrop: %s (%s)",
+		logging.Debugf(s.logger, "Skipping already claimed airdrop: %s (%s)",
 			airdrop.ID, airdrop.Token.Symbol)
 		return true
 	}
 
-	// Check if airdrop is already claimed according to the data
+	// Check if airdrop is already claimed according to the data. The store applies claims recorded by an
+	// earlier run to scanned airdrops, so they are caught here without a lookup per airdrop.
 	if airdrop.ClaimedAt != nil {
-		s.logger.Printf("Airdrop %s is already claimed", airdrop.ID)
+		logging.Debugf(s.logger, "Airdrop %s is already claimed", airdrop.ID)
 		s.claimedAirdrops[airdrop.ID] = true
 		return true
 	}
@@ -245,54 +1078,81 @@ func (s *Service) isAlreadyClaimed(airdrop models.AirdropNode) bool {
 	return false
 }
 
-// claimAirdrop attempts to claim an airdrop
-func (s *Service) claimAirdrop(ctx context.Context, airdrop models.AirdropNode) {
-	usdValue, _ := strconv.ParseFloat(airdrop.AmountUsd, 64)
-	s.logger.Printf("Claiming airdrop %s (%s) worth $%.2f...",
-		airdrop.ID, airdrop.Token.Symbol, usdValue)
-
-	txHash, err := s.claimer.ClaimAirdropByID(ctx, airdrop.ID)
-	if err != nil {
-		s.logger.Printf("Failed to claim airdrop %s: %v", airdrop.ID, err)
+// notifyUpcomingAirdrops reports airdrops announced for the wallet that are not claimable yet. It runs after
+// the scan, so airdrops that just became pending are already in the store and not reported.
+func (s *Service) notifyUpcomingAirdrops(ctx context.Context) {
+	upcoming := s.upcoming.CheckIfDue(ctx)
+	if len(upcoming) == 0 {
+		return
+	}
+	s.claimer.ResolveDecimals(ctx, upcoming)
 
-		// If it's a permanent error, mark as claimed to prevent repeated attempts
-		if IsPermanentClaimError(err) {
-			s.logger.Printf("Marking airdrop %s as claimed due to permanent error", airdrop.ID)
-			s.claimedMutex.Lock()
-			s.claimedAirdrops[airdrop.ID] = true
-			s.claimedMutex.Unlock()
+	for _, airdrop := range upcoming {
+		s.logger.Printf("Upcoming airdrop: ID=%s, Token=%s (%s), Amount=%s, USD=$%s",
+			airdrop.ID, airdrop.Token.Name, airdrop.Token.Symbol, airdrop.TokenAmount().Format(2), airdrop.AmountUsd)
+		if s.telegramClient != nil && s.telegramClient.Enabled {
+			s.telegramClient.SendUpcomingAirdropNotification(airdrop.Token.Name, airdrop.Token.Symbol,
+				airdrop.TokenAmount(), airdrop.AmountUsd.Float())
 		}
-		return
 	}
+}
 
-	// Mark as successfully claimed
-	s.logger.Printf("Successfully claimed airdrop %s! Transaction hash: %s", airdrop.ID, txHash)
+// isClaimedOnChain checks the wallet's claim status account, catching airdrops claimed outside this
+// service or by an earlier run whose claim was never recorded, before fees are spent on a failing claim
+func (s *Service) isClaimedOnChain(ctx context.Context, airdrop models.AirdropNode) bool {
+	claimed, err := s.claimer.IsClaimedOnChain(ctx, airdrop)
+	if err != nil {
+		logging.Warnf(s.logger, "Warning: Failed to check claim status of airdrop %s: %v", airdrop.ID, err)
+		return false
+	}
+	if !claimed {
+		return false
+	}
+
+	s.logger.Printf("Airdrop %s (%s) is already claimed on chain", airdrop.ID, airdrop.Token.Symbol)
 	s.claimedMutex.Lock()
 	s.claimedAirdrops[airdrop.ID] = true
 	s.claimedMutex.Unlock()
+	s.scanner.MarkClaimed(airdrop.ID)
+	return true
 }
 
 // handleStableTokenSale handles selling tokens that are already claimed and stable in price
 func (s *Service) handleStableTokenSale(ctx context.Context, airdrop models.AirdropNode, priceInfo *TokenPriceInfo) {
-	if airdrop.ClaimedAt == nil || priceInfo == nil {
+	if airdrop.ClaimedAt == nil || priceInfo == nil || !service.NewClaimConfig(s.config).AutoSell ||
+		s.config.KeepsToken(airdrop.Token.Address) {
 		return
 	}
 
-	// Check if this token should be sold
-	if s.decisionMaker.ShouldSellDirectly(airdrop, priceInfo) {
+	// Check if this token should be sold, screening it first as its claim may predate screening
+	s.safety.Screen(ctx, []string{airdrop.Token.Address})
+	shouldSell, reason := s.decisionMaker.EvaluateSell(airdrop, priceInfo)
+	s.shadow.EvaluateSell(airdrop, priceInfo, shouldSell)
+	if !shouldSell {
+		s.audit.Record(airdrop, priceInfo, solana.DecisionHold, reason, "")
+	} else {
 		// Check if already claimed by us (to avoid double handling)
 		s.claimedMutex.Lock()
 		isClaimed := s.claimedAirdrops[airdrop.ID]
 		s.claimedMutex.Unlock()
 
-		if !isClaimed {
-			usdValue, _ := strconv.ParseFloat(airdrop.AmountUsd, 64)
+		if allowed, until := s.claimer.GetSaleBreaker().Allow(airdrop.Token.Address); !allowed {
+			s.audit.Record(airdrop, priceInfo, solana.DecisionHold,
+				"sales paused until "+until.Format("2006-01-02 15:04")+" after failing repeatedly", "")
+		} else if !isClaimed {
+			s.audit.Record(airdrop, priceInfo, solana.DecisionSell, reason, "")
+			usdValue := airdrop.AmountUsd.Float()
 			s.logger.Printf("Token %s (%s) has stable price at $%.2f - attempting to sell directly",
 				airdrop.Token.Name, airdrop.Token.Symbol, usdValue)
 
 			// Sell token in a goroutine to not block the main process
+			s.sales.Add(1)
 			go func(airdropCopy models.AirdropNode) {
-				err := s.tokenSeller.SellToken(context.Background(), airdropCopy)
+				defer s.sales.Done()
+				saleCtx, trace := jupiter.TraceQuote(ctx)
+				txHash, err := s.tokenSeller.SellToken(saleCtx, airdropCopy)
+				s.audit.RecordQuote(airdropCopy, priceInfo, *trace)
+				s.audit.RecordOutcome(airdropCopy, priceInfo, solana.DecisionSell, txHash, err)
 				if err == nil {
 					// Mark as claimed/sold to prevent future attempts
 					s.claimedMutex.Lock()
@@ -302,7 +1162,7 @@ func (s *Service) handleStableTokenSale(ctx context.Context, airdrop models.Aird
 					strings.Contains(strings.ToLower(err.Error()), "auth") ||
 					strings.Contains(strings.ToLower(err.Error()), "token") {
 					// Refresh token on auth errors during sale
-					s.refreshAuthToken()
+					s.refreshAuthToken(ctx)
 				}
 			}(airdrop)
 		}
@@ -311,6 +1171,10 @@ func (s *Service) handleStableTokenSale(ctx context.Context, airdrop models.Aird
 
 // IsPermanentClaimError determines if an error during claiming is permanent and not worth retrying
 func IsPermanentClaimError(err error) bool {
+	if errors.Is(err, service.ErrAlreadyClaimedOnChain) || errors.Is(err, service.ErrUnsupportedTokenProgram) {
+		return true
+	}
+
 	errorMsg := err.Error()
 
 	// Check for common permanent errors
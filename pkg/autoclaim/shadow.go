@@ -0,0 +1,167 @@
+package autoclaim
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/models"
+)
+
+const (
+	// How often the shadow comparison report is logged
+	shadowReportInterval = time.Hour
+	// shadowMaxDecisions bounds the decisions kept for the report, the oldest are dropped first
+	shadowMaxDecisions = 1000
+)
+
+// shadowDecision tracks when each strategy first decided to act on an airdrop
+type shadowDecision struct {
+	tokenSymbol string
+	firstAt     time.Time // When either strategy first acted
+	liveAt      time.Time
+	liveUsd     float64
+	shadowAt    time.Time
+	shadowUsd   float64
+}
+
+// ShadowReport compares the live strategy with the shadow strategy
+type ShadowReport struct {
+	Airdrops      int     // Airdrops either strategy acted on, at most the last shadowMaxDecisions
+	LiveClaims    int     // Claims or sales decided by the live strategy
+	ShadowClaims  int     // Claims or sales the shadow strategy would have made
+	Both          int     // Airdrops both strategies acted on
+	ShadowEarlier int     // Of those, how often the shadow strategy decided first
+	LiveUsd       float64 // Value at decision time of the live strategy's actions
+	ShadowUsd     float64 // Value at decision time of the shadow strategy's actions
+}
+
+// ShadowEvaluator runs a second strategy alongside the live one without executing anything,
+// logging what it would have claimed or sold
+type ShadowEvaluator struct {
+	shadow     *DecisionMaker
+	decisions  map[string]*shadowDecision
+	mu         sync.Mutex
+	logger     *log.Logger
+	lastReport time.Time
+}
+
+// NewShadowEvaluator creates an evaluator for the given shadow decision maker
+func NewShadowEvaluator(shadow *DecisionMaker, logger *log.Logger) *ShadowEvaluator {
+	return &ShadowEvaluator{
+		shadow:     shadow,
+		decisions:  make(map[string]*shadowDecision),
+		logger:     logger,
+		lastReport: time.Now(),
+	}
+}
+
+// EvaluateClaim records the live claim decision and what the shadow strategy would have done
+func (e *ShadowEvaluator) EvaluateClaim(airdrop models.AirdropNode, priceInfo *TokenPriceInfo, liveClaim bool) {
+	if e == nil {
+		return
+	}
+	e.record(airdrop, "claim", liveClaim, e.shadow.ShouldClaim(airdrop, priceInfo))
+}
+
+// EvaluateSell records the live direct-sell decision and what the shadow strategy would have done
+func (e *ShadowEvaluator) EvaluateSell(airdrop models.AirdropNode, priceInfo *TokenPriceInfo, liveSell bool) {
+	if e == nil {
+		return
+	}
+	e.record(airdrop, "sell", liveSell, e.shadow.ShouldSellDirectly(airdrop, priceInfo))
+}
+
+// record stores the first decision of each strategy for an airdrop and logs divergences
+func (e *ShadowEvaluator) record(airdrop models.AirdropNode, action string, live, shadow bool) {
+	if !live && !shadow {
+		return
+	}
+
+	usdValue := airdrop.AmountUsd.Float()
+	now := time.Now()
+	key := action + ":" + airdrop.ID
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	decision, exists := e.decisions[key]
+	if !exists {
+		if len(e.decisions) >= shadowMaxDecisions {
+			e.dropOldest()
+		}
+		decision = &shadowDecision{tokenSymbol: airdrop.Token.Symbol, firstAt: now}
+		e.decisions[key] = decision
+	}
+
+	if live && decision.liveAt.IsZero() {
+		decision.liveAt = now
+		decision.liveUsd = usdValue
+	}
+	if shadow && decision.shadowAt.IsZero() {
+		decision.shadowAt = now
+		decision.shadowUsd = usdValue
+		if !live {
+			e.logger.Printf("[SHADOW] Would %s %s (%s) at $%.4f - live strategy is waiting",
+				action, airdrop.ID, airdrop.Token.Symbol, usdValue)
+		}
+	}
+	if live && !shadow && decision.shadowAt.IsZero() {
+		e.logger.Printf("[SHADOW] Would not %s %s (%s) at $%.4f - live strategy acts",
+			action, airdrop.ID, airdrop.Token.Symbol, usdValue)
+	}
+}
+
+// dropOldest forgets the decision first acted on the longest ago
+func (e *ShadowEvaluator) dropOldest() {
+	var oldestKey string
+	var oldest time.Time
+	for key, decision := range e.decisions {
+		if oldestKey == "" || decision.firstAt.Before(oldest) {
+			oldestKey, oldest = key, decision.firstAt
+		}
+	}
+	delete(e.decisions, oldestKey)
+}
+
+// Report summarizes the decisions recorded so far
+func (e *ShadowEvaluator) Report() ShadowReport {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	report := ShadowReport{Airdrops: len(e.decisions)}
+	for _, decision := range e.decisions {
+		live := !decision.liveAt.IsZero()
+		shadow := !decision.shadowAt.IsZero()
+
+		if live {
+			report.LiveClaims++
+			report.LiveUsd += decision.liveUsd
+		}
+		if shadow {
+			report.ShadowClaims++
+			report.ShadowUsd += decision.shadowUsd
+		}
+		if live && shadow {
+			report.Both++
+			if decision.shadowAt.Before(decision.liveAt) {
+				report.ShadowEarlier++
+			}
+		}
+	}
+
+	return report
+}
+
+// LogReportIfDue logs the comparison report once per report interval
+func (e *ShadowEvaluator) LogReportIfDue() {
+	if e == nil || time.Since(e.lastReport) < shadowReportInterval {
+		return
+	}
+	e.lastReport = time.Now()
+
+	report := e.Report()
+	e.logger.Printf("[SHADOW] Report - airdrops: %d, live: %d ($%.2f), shadow: %d ($%.2f), both: %d (shadow earlier: %d), live only: %d, shadow only: %d",
+		report.Airdrops, report.LiveClaims, report.LiveUsd, report.ShadowClaims, report.ShadowUsd,
+		report.Both, report.ShadowEarlier, report.LiveClaims-report.Both, report.ShadowClaims-report.Both)
+}
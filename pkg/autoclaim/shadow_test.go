@@ -0,0 +1,33 @@
+package autoclaim
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/strategy"
+)
+
+func TestShadowEvaluatorKeepsBoundedHistory(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	shadow := NewDecisionMakerWithStrategy(&config.Config{}, strategy.Strategy{MinimumUsdThreshold: 0.1}, logger)
+	evaluator := NewShadowEvaluator(shadow, logger)
+	priceInfo := &TokenPriceInfo{LastChanged: time.Now(), FirstObserved: time.Now()}
+
+	// The shadow strategy claims what the live one waits on, every airdrop is tracked until the bound
+	evaluator.EvaluateClaim(drop("first", "mint", 0.5), priceInfo, true)
+	time.Sleep(time.Millisecond)
+	for i := 0; i < shadowMaxDecisions+10; i++ {
+		evaluator.EvaluateClaim(drop(fmt.Sprintf("drop-%d", i), "mint", 0.5), priceInfo, false)
+	}
+
+	report := evaluator.Report()
+	assert.Equal(t, shadowMaxDecisions, report.Airdrops)
+	assert.Equal(t, shadowMaxDecisions, report.ShadowClaims)
+	assert.Zero(t, report.LiveClaims, "the oldest decision is dropped first")
+}
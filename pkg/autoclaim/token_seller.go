@@ -4,15 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"strconv"
-	"time"
-
-	"github.com/gagliardetto/solana-go/rpc"
 
+	"boop-airdrop-redeemer/pkg/amount"
 	"boop-airdrop-redeemer/pkg/config"
-	"boop-airdrop-redeemer/pkg/jupiter"
+	"boop-airdrop-redeemer/pkg/journal"
+	"boop-airdrop-redeemer/pkg/logging"
 	"boop-airdrop-redeemer/pkg/models"
 	"boop-airdrop-redeemer/pkg/notifications"
+	"boop-airdrop-redeemer/pkg/operation"
 	"boop-airdrop-redeemer/pkg/service"
 	"boop-airdrop-redeemer/pkg/solana"
 )
@@ -20,10 +19,17 @@ import (
 // TokenSeller handles selling tokens after they've been claimed
 type TokenSeller struct {
 	config         *config.Config
-	swapService    *jupiter.SwapService
-	priceService   *solana.PriceService
-	solClient      *rpc.Client
+	swapService    service.SwapProvider
+	priceService   service.PriceProvider
+	statsRecorder  service.StatsStore
+	solClient      solana.RPCClient
 	telegramClient *notifications.TelegramClient
+	webhookClient  *notifications.WebhookClient
+	journal        *journal.Journal
+	serviceFee     *service.ServiceFee
+	killSwitch     *service.KillSwitch
+	quoteGuard     *service.QuoteGuard
+	costBasis      *service.CostBasisWatch
 	logger         *log.Logger
 }
 
@@ -38,126 +44,224 @@ func NewTokenSeller(
 		config:         cfg,
 		swapService:    claimer.GetSwapService(),
 		priceService:   claimer.GetPriceService(),
+		statsRecorder:  claimer.GetStatsRecorder(),
 		solClient:      claimer.GetSolClient(),
 		telegramClient: telegramClient,
+		webhookClient:  claimer.GetWebhookClient(),
+		journal:        claimer.GetJournal(),
+		serviceFee:     claimer.GetServiceFee(),
+		killSwitch:     claimer.GetKillSwitch(),
+		quoteGuard:     claimer.GetQuoteGuard(),
+		costBasis:      claimer.GetCostBasis(),
 		logger:         logger,
 	}
 }
 
-// SellToken attempts to sell a token for SOL
-func (ts *TokenSeller) SellToken(ctx context.Context, airdrop models.AirdropNode) error {
-	// Parse token amount
-	tokenAmount, err := strconv.ParseUint(airdrop.AmountLpt, 10, 64)
-	if err != nil {
-		ts.logger.Printf("Failed to parse token amount for selling %s: %v", airdrop.ID, err)
-		return err
+// SellToken attempts to sell a token for SOL, or for USDC when that is the configured sell target, and returns
+// the hash of the swap transaction
+func (ts *TokenSeller) SellToken(ctx context.Context, airdrop models.AirdropNode) (string, error) {
+	ctx, opID := operation.Start(ctx)
+	logger := operation.Logger(ctx, ts.logger)
+
+	if !airdrop.AmountLpt.Valid() {
+		logging.Errorf(logger, "Failed to sell %s: no valid token amount", airdrop.ID)
+		return "", fmt.Errorf("airdrop %s has no valid token amount", airdrop.ID)
+	}
+	tokens := airdrop.TokenAmount()
+
+	if err := ts.killSwitch.Guard(); err != nil {
+		logger.Printf("Not selling %s: %v", airdrop.Token.Symbol, err)
+		return "", err
+	}
+
+	// A quote far below the API's SOL value is left for a later cycle rather than sold at a loss
+	if err := ts.quoteGuard.Check(airdrop, tokens.Raw); err != nil {
+		ts.quoteGuard.Alert(airdrop, err)
+		return "", err
 	}
 
 	// Get USD value for logging
-	usdValue, _ := strconv.ParseFloat(airdrop.AmountUsd, 64)
+	usdValue := airdrop.AmountUsd.Float()
 
 	// Attempt to sell the token
-	ts.logger.Printf("Selling token %s (%s) worth $%.2f...",
+	logger.Printf("Selling token %s (%s) worth $%.2f...",
 		airdrop.ID, airdrop.Token.Symbol, usdValue)
 
-	swapSig, err := ts.swapService.SwapTokenForSol(
+	if err := ts.journal.RecordIntent(journal.EntrySellIntent, airdrop); err != nil {
+		logging.Warnf(logger, "Warning: Failed to write operation journal: %v", err)
+	}
+
+	// The drop of a kept token's balance is realized below with the sale proceeds, not by the balance check
+	defer ts.costBasis.StartSale(airdrop.Token.Address)()
+
+	swap := ts.swapService.SwapTokenForSol
+	sellForUsdc := service.NewClaimConfig(ts.config).SellTarget == service.SellTargetUsdc
+	if sellForUsdc {
+		swap = ts.swapService.SwapTokenForUsdc
+	}
+
+	swapSig, err := swap(
 		ctx,
 		ts.config.WalletPrivateKey,
 		airdrop.Token.Address,
-		tokenAmount,
+		tokens.Raw,
 	)
 
 	if err != nil {
-		ts.handleSellError(airdrop, err, 1)
-		return err
+		ts.journal.RecordOutcome(journal.EntrySellOutcome, airdrop, "", err)
+		ts.handleSellError(ctx, airdrop, err)
+		return "", err
 	}
 
 	// Get transaction signature
 	txHash := swapSig.String()
+	ts.journal.RecordOutcome(journal.EntrySellOutcome, airdrop, txHash, nil)
 
-	// Give the transaction some time to settle
-	time.Sleep(2 * time.Second)
+	// SOL fees, earnings and profit stats do not apply to USDC sales
+	if sellForUsdc {
+		logger.Printf("Sold token %s for USDC: %s", airdrop.Token.Symbol, txHash)
+		ts.costBasis.RealizeSale(ctx, airdrop, usdValue, txHash)
+		if ts.telegramClient != nil {
+			ts.telegramClient.SendTokenSoldForUsdcNotification(airdrop.Token.Name, airdrop.Token.Symbol,
+				airdrop.TokenAmount(), airdrop.AmountUsd.Float(), txHash, opID)
+		}
+		return txHash, nil
+	}
 
-	// Get actual swap fees and earnings from the transaction
-	swapFees, swapEarnings, err := solana.GetTransactionFeesAndEarnings(ts.solClient, txHash, true)
+	// Get actual swap fees and earnings from the transaction once the RPC node serves it
+	result, err := solana.AwaitTransactionResult(ctx, ts.solClient, txHash, true)
 	if err != nil {
-		ts.logger.Printf("Warning: Failed to get transaction details: %v", err)
+		logging.Warnf(logger, "Warning: Failed to get transaction details: %v", err)
 		// Continue even if we couldn't get transaction details
-		// We'll just use an estimate in this case
-		ts.handleSuccessfulSaleWithEstimate(airdrop, txHash, usdValue)
-		return nil
+		// We'll just use an estimate in this case, reconciled from chain by a later cycle
+		ts.handleSuccessfulSaleWithEstimate(ctx, airdrop, txHash, usdValue)
+		return txHash, nil
 	}
 
-	// Convert from lamports to SOL (1 SOL = 1,000,000,000 lamports)
-	feesInSol := float64(swapFees) / 1_000_000_000
-	earningsInSol := float64(swapEarnings) / 1_000_000_000
+	if err := ts.journal.RecordSettlement(journal.EntrySellOutcome, airdrop, txHash, result); err != nil {
+		logging.Warnf(logger, "Warning: Failed to write operation journal: %v", err)
+	}
 
-	ts.logger.Printf("Transaction details - fees: %.6f SOL, earnings: %.6f SOL", feesInSol, earningsInSol)
+	// Rent the swap paid for new accounts is a cost of the sale like its fee
+	feesInSol := amount.Lamports(result.Fee).Sol() + float64(result.Rent)/amount.LamportsPerSol
+	earningsInSol := amount.Lamports(result.Earnings).Sol()
 
-	// Handle successful sale with real transaction data
-	ts.handleSuccessfulSale(airdrop, txHash, earningsInSol, feesInSol, usdValue)
-	return nil
-}
+	logger.Printf("Transaction details - fees: %.6f SOL, earnings: %.6f SOL", feesInSol, earningsInSol)
 
-// handleSellError processes errors during token selling
-func (ts *TokenSeller) handleSellError(airdrop models.AirdropNode, err error, attemptCount int) {
-	ts.logger.Printf("Failed to sell token %s: %v", airdrop.ID, err)
+	if ts.statsRecorder != nil {
+		if err := ts.statsRecorder.RecordSwapStats(airdrop.Token.Symbol, airdrop.AmountLpt.String(), result.Fee, result.Earnings, result.Rent, txHash, opID); err != nil {
+			logging.Warnf(logger, "Warning: Failed to record swap stats: %v", err)
+		}
 
-	// If Telegram is enabled, send error notification
-	if ts.telegramClient != nil && ts.telegramClient.Enabled {
-		errorMsg := err.Error()
-		if len(errorMsg) > 100 {
-			errorMsg = errorMsg[:100] + "..."
+		solPrice := 0.0
+		if quote, ok := ts.getSolPrice(); ok {
+			solPrice = quote.Price
+		}
+		err := ts.statsRecorder.RecordOutcome(solana.Outcome{
+			AirdropID:   airdrop.ID,
+			TokenSymbol: airdrop.Token.Symbol,
+			Strategy:    solana.StrategyStableSell,
+			ExpectedUsd: usdValue,
+			RealizedSol: earningsInSol,
+			SolPrice:    solPrice,
+			TxHash:      txHash,
+		})
+		if err != nil {
+			logging.Warnf(logger, "Warning: Failed to record outcome: %v", err)
 		}
-		ts.telegramClient.SendTokenSaleErrorNotification(
-			airdrop.Token.Name,
-			airdrop.Token.Symbol,
-			airdrop.AmountLpt,
-			airdrop.AmountUsd,
-			errorMsg,
-			attemptCount,
-		)
 	}
+
+	ts.webhookClient.Send(notifications.WebhookEvent{
+		Event:            notifications.EventSellConfirmed,
+		Wallet:           ts.config.WalletAddress,
+		AirdropID:        airdrop.ID,
+		TokenName:        airdrop.Token.Name,
+		TokenSymbol:      airdrop.Token.Symbol,
+		TokenMint:        airdrop.Token.Address,
+		Amount:           airdrop.AmountLpt.String(),
+		UsdValue:         airdrop.AmountUsd.String(),
+		TxHash:           txHash,
+		FeesLamports:     result.Fee,
+		EarningsLamports: result.Earnings,
+		OperationID:      opID,
+	})
+
+	// Transfer the operator's share of the realized SOL, if configured
+	serviceFee, err := ts.serviceFee.Charge(ctx, airdrop, result.Earnings)
+	if err != nil {
+		logging.Warnf(logger, "Warning: Failed to transfer service fee: %v", err)
+	}
+
+	// Handle successful sale with real transaction data
+	ts.handleSuccessfulSale(ctx, airdrop, txHash, earningsInSol, feesInSol, usdValue, serviceFee)
+	return txHash, nil
+}
+
+// handleSellError processes errors during token selling. Only the first failure in a row is notified, and the
+// first one that pauses the sales of the token.
+func (ts *TokenSeller) handleSellError(ctx context.Context, airdrop models.AirdropNode, err error) {
+	logger := operation.Logger(ctx, ts.logger)
+
+	logging.Errorf(logger, "Failed to sell token %s: %v", airdrop.ID, err)
+	service.NotifySaleFailure(ts.telegramClient, airdrop, err, operation.ID(ctx))
 }
 
 // handleSuccessfulSaleWithEstimate handles successful sale using estimated values
-func (ts *TokenSeller) handleSuccessfulSaleWithEstimate(airdrop models.AirdropNode, txHash string, usdValue float64) {
+func (ts *TokenSeller) handleSuccessfulSaleWithEstimate(ctx context.Context, airdrop models.AirdropNode, txHash string, usdValue float64) {
+	logger := operation.Logger(ctx, ts.logger)
+
 	// Estimate SOL received based on current SOL price
 	estimatedSolReceived := 0.0
-	if ts.priceService != nil {
-		solPrice := ts.priceService.GetCurrentPrice()
-		if solPrice > 0 {
-			estimatedSolReceived = usdValue / solPrice
-		}
+	if quote, ok := ts.getSolPrice(); ok {
+		estimatedSolReceived = usdValue / quote.Price
+	} else {
+		logging.Warnf(logger, "Warning: SOL price unknown, cannot estimate SOL received for %s", airdrop.Token.Symbol)
 	}
 
-	// Estimate fees (typically around 0.000005 SOL)
-	estimatedFees := 0.000005
+	// Estimate fees from the base fee of one signature
+	estimatedFees := amount.Lamports(solana.EstimatedTransactionFee).Sol()
 
-	// Net profit is earnings minus fees
-	// No need to calculate netProfit as we're passing the individual components to handleSuccessfulSale
+	// Record as an estimate so it stays out of real profit numbers until reconciled from chain
+	if ts.statsRecorder != nil {
+		err := ts.statsRecorder.RecordEstimatedSwapStats(
+			airdrop.Token.Symbol,
+			airdrop.AmountLpt.String(),
+			uint64(amount.FromSol(estimatedFees)),
+			uint64(amount.FromSol(estimatedSolReceived)),
+			txHash,
+			operation.ID(ctx),
+		)
+		if err != nil {
+			logging.Warnf(logger, "Warning: Failed to record estimated swap stats: %v", err)
+		}
+	}
 
-	ts.handleSuccessfulSale(airdrop, txHash, estimatedSolReceived, estimatedFees, usdValue)
+	// No service fee is charged on estimated earnings
+	ts.handleSuccessfulSale(ctx, airdrop, txHash, estimatedSolReceived, estimatedFees, usdValue, service.ServiceFeeCharge{})
 }
 
 // handleSuccessfulSale processes successful token sales
-func (ts *TokenSeller) handleSuccessfulSale(airdrop models.AirdropNode, txHash string, earningsInSol float64, feesInSol float64, usdValue float64) {
-	// Calculate net profit (earnings - fees)
-	netProfitSol := earningsInSol - feesInSol
+func (ts *TokenSeller) handleSuccessfulSale(ctx context.Context, airdrop models.AirdropNode, txHash string, earningsInSol float64, feesInSol float64, usdValue float64, serviceFee service.ServiceFeeCharge) {
+	logger := operation.Logger(ctx, ts.logger)
 
-	ts.logger.Printf("🎉 Successfully sold token %s for %.6f SOL (fees: %.6f SOL, net: %.6f SOL)! Transaction: %s",
+	// Calculate net profit (earnings - fees - service fee)
+	netProfitSol := earningsInSol - feesInSol - serviceFee.Sol()
+
+	logger.Printf("🎉 Successfully sold token %s for %.6f SOL (fees: %.6f SOL, net: %.6f SOL)! Transaction: %s",
 		airdrop.Token.Name, earningsInSol, feesInSol, netProfitSol, txHash)
 
-	// Get SOL price for USD conversion
+	// Get SOL price for USD conversion, 0 when unknown so notifications omit USD values
 	solPrice := 0.0
-	if ts.priceService != nil {
-		solPrice = ts.priceService.GetCurrentPrice()
+	if quote, ok := ts.getSolPrice(); ok {
+		solPrice = quote.Price
 	}
 
-	// Calculate USD values
-	earningsUsd := earningsInSol * solPrice
-	feesUsd := feesInSol * solPrice
-	netProfitUsd := netProfitSol * solPrice
+	// Proceeds in USD at the current SOL price, the API's USD value when the price is unknown
+	proceedsUsd := usdValue
+	if solPrice > 0 {
+		proceedsUsd = earningsInSol * solPrice
+	}
+	ts.costBasis.RealizeSale(ctx, airdrop, proceedsUsd, txHash)
 
 	// Create profit summary
 	profitSummary := &notifications.ProfitSummary{
@@ -167,19 +271,50 @@ func (ts *TokenSeller) handleSuccessfulSale(airdrop models.AirdropNode, txHash s
 	}
 
 	// Log detailed profit information
-	ts.logger.Printf("Profit details: Earnings: %.6f SOL ($%.2f), Fees: %.6f SOL ($%.2f), Net: %.6f SOL ($%.2f)",
-		earningsInSol, earningsUsd, feesInSol, feesUsd, netProfitSol, netProfitUsd)
+	if solPrice > 0 {
+		logger.Printf("Profit details: Earnings: %.6f SOL ($%.2f), Fees: %.6f SOL ($%.2f), Net: %.6f SOL ($%.2f)",
+			earningsInSol, earningsInSol*solPrice, feesInSol, feesInSol*solPrice, netProfitSol, netProfitSol*solPrice)
+	} else {
+		logger.Printf("Profit details: Earnings: %.6f SOL, Fees: %.6f SOL, Net: %.6f SOL (SOL price unknown)",
+			earningsInSol, feesInSol, netProfitSol)
+	}
 
 	// Send notification about successful sale
 	if ts.telegramClient != nil && ts.telegramClient.Enabled {
 		ts.telegramClient.SendTokenSoldNotification(
 			airdrop.Token.Name,
 			airdrop.Token.Symbol,
-			airdrop.AmountLpt,
-			fmt.Sprintf("%.6f", netProfitSol),
+			airdrop.TokenAmount(),
+			netProfitSol,
 			profitSummary,
 			solPrice,
 			txHash,
+			operation.ID(ctx),
 		)
 	}
+	ts.serviceFee.Notify(airdrop, serviceFee, solPrice)
+}
+
+// ReconcileEstimates replaces estimated claim and sale records with on-chain fees and earnings
+func (ts *TokenSeller) ReconcileEstimates(ctx context.Context) {
+	if ts.statsRecorder == nil {
+		return
+	}
+
+	reconciled, err := ts.statsRecorder.ReconcileEstimates(ctx, ts.solClient)
+	if err != nil && ctx.Err() == nil {
+		logging.Warnf(ts.logger, "Warning: Failed to reconcile estimated records: %v", err)
+		return
+	}
+	if reconciled > 0 {
+		ts.logger.Printf("Reconciled %d estimated record(s) from chain", reconciled)
+	}
+}
+
+// getSolPrice returns the current SOL price quote, false when no valid price is known
+func (ts *TokenSeller) getSolPrice() (solana.PriceQuote, bool) {
+	if ts.priceService == nil {
+		return solana.PriceQuote{}, false
+	}
+	return ts.priceService.GetPrice()
 }
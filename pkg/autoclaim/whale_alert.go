@@ -0,0 +1,117 @@
+package autoclaim
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/amount"
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/models"
+	"boop-airdrop-redeemer/pkg/notifications"
+)
+
+// whaleSender delivers whale alerts, implemented by the Telegram client
+type whaleSender interface {
+	SendWhaleAlertNotification(airdropID, tokenName, tokenSymbol, mint string, tokens amount.TokenAmount, usdValue, thresholdUsd float64, plan string)
+}
+
+// WhaleAlerts raises a high-priority alert, once per airdrop, when a pending airdrop is worth more than the
+// whale threshold. The airdrop still goes through the normal claim flow, but is held back for the hold window
+// after its alert so the user can step in before it is claimed.
+type WhaleAlerts struct {
+	thresholdUsd float64
+	hold         time.Duration
+	sender       whaleSender                   // nil while Telegram is disabled
+	pushover     *notifications.PushoverClient // nil without Pushover credentials
+	logger       *log.Logger
+
+	alerted map[string]time.Time // When each pending whale airdrop was alerted
+}
+
+// NewWhaleAlerts creates the whale alerts, nil when no whale threshold is configured
+func NewWhaleAlerts(cfg *config.Config, telegramClient *notifications.TelegramClient, logger *log.Logger) *WhaleAlerts {
+	if cfg.WhaleAlertUsd <= 0 {
+		return nil
+	}
+
+	alerts := &WhaleAlerts{
+		thresholdUsd: cfg.WhaleAlertUsd,
+		hold:         cfg.WhaleHold,
+		pushover:     notifications.NewPushoverClient(cfg.PushoverToken, cfg.PushoverUser, cfg.PushoverPriority),
+		logger:       logger,
+		alerted:      make(map[string]time.Time),
+	}
+	if telegramClient != nil && telegramClient.Enabled {
+		alerts.sender = telegramClient
+	}
+	if alerts.sender == nil && alerts.pushover == nil {
+		logging.Warnf(logger, "WARNING: Whale alerts need Telegram or Pushover, airdrops above $%.2f are only logged", cfg.WhaleAlertUsd)
+	}
+
+	logger.Printf("Whale alerts enabled for airdrops above $%.2f", cfg.WhaleAlertUsd)
+	return alerts
+}
+
+// Check alerts about the airdrop when it is above the threshold and was not alerted before. plan describes
+// what the automation does with the airdrop.
+func (w *WhaleAlerts) Check(airdrop models.AirdropNode, plan string) {
+	if w == nil {
+		return
+	}
+	usdValue := airdrop.AmountUsd.Float()
+	if _, alerted := w.alerted[airdrop.ID]; usdValue < w.thresholdUsd || alerted {
+		return
+	}
+	w.alerted[airdrop.ID] = time.Now()
+	if w.hold > 0 {
+		plan += fmt.Sprintf(", held for %s before claiming", w.hold)
+	}
+
+	w.logger.Printf("WHALE AIRDROP: ID=%s, Token=%s (%s), USD=$%.2f above $%.2f, %s",
+		airdrop.ID, airdrop.Token.Name, airdrop.Token.Symbol, usdValue, w.thresholdUsd, plan)
+
+	if w.sender != nil {
+		w.sender.SendWhaleAlertNotification(airdrop.ID, airdrop.Token.Name, airdrop.Token.Symbol, airdrop.Token.Address,
+			airdrop.TokenAmount(), usdValue, w.thresholdUsd, plan)
+	}
+
+	title := fmt.Sprintf("Whale airdrop: $%.2f", usdValue)
+	message := fmt.Sprintf("<b>%s (%s)</b> worth $%.2f is pending.\nAutomation: %s",
+		html.EscapeString(airdrop.Token.Name), html.EscapeString(airdrop.Token.Symbol), usdValue, html.EscapeString(plan))
+	if err := w.pushover.Send(title, message); err != nil {
+		logging.Warnf(w.logger, "Warning: Failed to push whale alert: %v", err)
+	}
+}
+
+// Holds reports whether the airdrop was alerted as a whale less than the hold window ago and must not be
+// claimed yet
+func (w *WhaleAlerts) Holds(airdrop models.AirdropNode) (bool, time.Time) {
+	if w == nil || w.hold <= 0 {
+		return false, time.Time{}
+	}
+	alertedAt, ok := w.alerted[airdrop.ID]
+	if !ok {
+		return false, time.Time{}
+	}
+	until := alertedAt.Add(w.hold)
+	return time.Now().Before(until), until
+}
+
+// Prune forgets alerts about airdrops no longer pending, claimed or expired ones
+func (w *WhaleAlerts) Prune(pending []models.AirdropNode) {
+	if w == nil {
+		return
+	}
+	ids := make(map[string]bool, len(pending))
+	for _, airdrop := range pending {
+		ids[airdrop.ID] = true
+	}
+	for id := range w.alerted {
+		if !ids[id] {
+			delete(w.alerted, id)
+		}
+	}
+}
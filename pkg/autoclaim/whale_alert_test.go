@@ -0,0 +1,60 @@
+package autoclaim
+
+import (
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"boop-airdrop-redeemer/pkg/amount"
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/models"
+)
+
+type fakeWhaleSender struct {
+	alerted []string
+}
+
+func (f *fakeWhaleSender) SendWhaleAlertNotification(airdropID, tokenName, tokenSymbol, mint string, tokens amount.TokenAmount, usdValue, thresholdUsd float64, plan string) {
+	f.alerted = append(f.alerted, airdropID)
+}
+
+func TestWhaleAlertsAlertOncePerAirdrop(t *testing.T) {
+	sender := &fakeWhaleSender{}
+	alerts := NewWhaleAlerts(&config.Config{WhaleAlertUsd: 500}, nil, log.New(io.Discard, "", 0))
+	alerts.sender = sender
+
+	alerts.Check(drop("small", "mint", 499.99), "")
+	alerts.Check(drop("whale", "mint", 500), "")
+	alerts.Check(drop("whale", "mint", 800), "")
+	assert.Equal(t, []string{"whale"}, sender.alerted)
+
+	assert.Nil(t, NewWhaleAlerts(&config.Config{}, nil, nil))
+	(*WhaleAlerts)(nil).Check(drop("whale", "mint", 800), "")
+}
+
+func TestWhaleAlertsHoldAndPrune(t *testing.T) {
+	alerts := NewWhaleAlerts(&config.Config{WhaleAlertUsd: 500, WhaleHold: time.Hour}, nil, log.New(io.Discard, "", 0))
+	whale := drop("whale", "mint", 800)
+
+	held, _ := alerts.Holds(whale)
+	assert.False(t, held, "not alerted yet")
+	alerts.Check(whale, "")
+	held, until := alerts.Holds(whale)
+	assert.True(t, held)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), until, time.Second)
+
+	alerts.alerted["whale"] = time.Now().Add(-2 * time.Hour)
+	held, _ = alerts.Holds(whale)
+	assert.False(t, held, "hold window passed")
+
+	alerts.Prune([]models.AirdropNode{drop("other", "mint", 800)})
+	assert.Empty(t, alerts.alerted)
+
+	alerts.hold = 0
+	alerts.Check(whale, "")
+	held, _ = alerts.Holds(whale)
+	assert.False(t, held, "no hold window")
+}
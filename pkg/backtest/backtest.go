@@ -0,0 +1,174 @@
+package backtest
+
+import (
+	"sort"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/amount"
+	sol "boop-airdrop-redeemer/pkg/solana"
+	"boop-airdrop-redeemer/pkg/strategy"
+)
+
+// Fallback fee per airdrop (claim + swap) in SOL when no stats are recorded
+const defaultFeesSol = 0.00001
+
+// Costs are the assumptions used to turn claimed value into profit
+type Costs struct {
+	FeesSolPerAirdrop float64 // Claim and swap fees per airdrop in SOL
+	SolPrice          float64 // SOL price in USD
+	RealizationRatio  float64 // Fraction of the expected USD value realized when selling
+}
+
+// Claim is a hypothetical claim made during a backtest
+type Claim struct {
+	AirdropID   string
+	TokenSymbol string
+	ClaimedAt   time.Time
+	ValueUsd    float64
+	Stable      bool // Claimed through the stable price rule rather than the threshold
+}
+
+// Result summarizes a backtest run
+type Result struct {
+	Strategy     strategy.Strategy
+	Airdrops     int
+	Claims       []Claim
+	ExpectedUsd  float64
+	RealizedUsd  float64
+	FeesUsd      float64
+	NetUsd       float64
+	Unprofitable int // Claims whose realized value did not cover the fees
+}
+
+// Run replays airdrop value history against a strategy and reports the hypothetical profit.
+// History only holds value changes, so a value is assumed to hold until the next observation
+// or, for the last one, until the end of the history.
+func Run(history []sol.AirdropValue, rules strategy.Strategy, costs Costs) Result {
+	result := Result{Strategy: rules}
+
+	byAirdrop := make(map[string][]sol.AirdropValue)
+	var order []string
+	var horizon time.Time
+	for _, value := range history {
+		if _, seen := byAirdrop[value.AirdropID]; !seen {
+			order = append(order, value.AirdropID)
+		}
+		byAirdrop[value.AirdropID] = append(byAirdrop[value.AirdropID], value)
+		if value.Timestamp.After(horizon) {
+			horizon = value.Timestamp
+		}
+	}
+	result.Airdrops = len(order)
+
+	feesUsd := costs.FeesSolPerAirdrop * costs.SolPrice
+
+	for _, id := range order {
+		values := byAirdrop[id]
+		sort.SliceStable(values, func(i, j int) bool {
+			return values[i].Timestamp.Before(values[j].Timestamp)
+		})
+
+		claim, ok := simulate(values, rules, horizon)
+		if !ok {
+			continue
+		}
+
+		realized := claim.ValueUsd * costs.RealizationRatio
+		result.Claims = append(result.Claims, claim)
+		result.ExpectedUsd += claim.ValueUsd
+		result.RealizedUsd += realized
+		result.FeesUsd += feesUsd
+		if realized < feesUsd {
+			result.Unprofitable++
+		}
+	}
+
+	result.NetUsd = result.RealizedUsd - result.FeesUsd
+	return result
+}
+
+// simulate returns the first point at which the strategy would have claimed an airdrop
+func simulate(values []sol.AirdropValue, rules strategy.Strategy, horizon time.Time) (Claim, bool) {
+	for i, value := range values {
+		if value.Claimed {
+			return Claim{}, false
+		}
+
+		claim := Claim{
+			AirdropID:   value.AirdropID,
+			TokenSymbol: value.TokenSymbol,
+			ClaimedAt:   value.Timestamp,
+			ValueUsd:    value.AmountUsd,
+		}
+
+		if value.AmountUsd >= rules.MinimumUsdThreshold {
+			return claim, true
+		}
+
+		if value.AmountUsd <= rules.StableMinimumUsd {
+			continue
+		}
+
+		// Stable once unchanged for the full duration, which also covers the observation time
+		stableAt := value.Timestamp.Add(rules.StableDuration)
+		validUntil := horizon
+		if i+1 < len(values) {
+			validUntil = values[i+1].Timestamp
+		}
+		if !stableAt.After(validUntil) {
+			claim.ClaimedAt = stableAt
+			claim.Stable = true
+			return claim, true
+		}
+	}
+
+	return Claim{}, false
+}
+
+// CostsFromStats derives cost assumptions from recorded transactions and outcomes.
+// Missing data falls back to the default fee, a realization ratio of 1 and a zero SOL price.
+func CostsFromStats(transactions []sol.TransactionStats, outcomes []sol.Outcome) Costs {
+	costs := Costs{
+		FeesSolPerAirdrop: defaultFeesSol,
+		RealizationRatio:  1,
+	}
+
+	// Average claim and swap fees including account rent, measured records only
+	var claimFees, swapFees int64
+	var claims, swaps int
+	for _, tx := range transactions {
+		if tx.Estimated {
+			continue
+		}
+		switch tx.TxType {
+		case sol.TypeClaim:
+			claimFees += int64(tx.Expenses) + tx.Rent
+			claims++
+		case sol.TypeSwap:
+			swapFees += int64(tx.Expenses) + tx.Rent
+			swaps++
+		}
+	}
+	if claims > 0 && swaps > 0 {
+		costs.FeesSolPerAirdrop = (float64(claimFees)/float64(claims) + float64(swapFees)/float64(swaps)) / amount.LamportsPerSol
+	}
+
+	// Average SOL price and realization ratio from valued outcomes
+	var priceSum, expected, realized float64
+	var priced int
+	for _, outcome := range outcomes {
+		if outcome.SolPrice <= 0 || outcome.ExpectedUsd <= 0 {
+			continue
+		}
+		priceSum += outcome.SolPrice
+		expected += outcome.ExpectedUsd
+		realized += outcome.RealizedUsd()
+		priced++
+	}
+	if priced > 0 {
+		costs.SolPrice = priceSum / float64(priced)
+		costs.RealizationRatio = realized / expected
+	}
+
+	return costs
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/logging"
+)
+
+// AccumulationRule holds back the claims of a token that re-drops often until its pending airdrops are worth
+// MinUsd together, then all of them are claimed in the same cycle
+type AccumulationRule struct {
+	MinUsd   float64
+	Cooldown time.Duration // Least time between two claim rounds of the token, 0 for none
+}
+
+// parseAccumulationRules reads ACCUMULATE_TOKENS entries of the form mint:minUsd or mint:minUsd:cooldown,
+// invalid entries are logged and ignored
+func parseAccumulationRules(entries []string, logger *log.Logger) map[string]AccumulationRule {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	rules := make(map[string]AccumulationRule, len(entries))
+	for _, entry := range entries {
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+			logging.Warnf(logger, "WARNING: Ignoring accumulation rule %q, expected mint:minUsd or mint:minUsd:cooldown", entry)
+			continue
+		}
+
+		minUsd, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || minUsd <= 0 {
+			logging.Warnf(logger, "WARNING: Ignoring accumulation rule %q, %q is not a positive USD value", entry, parts[1])
+			continue
+		}
+
+		rule := AccumulationRule{MinUsd: minUsd}
+		if len(parts) == 3 {
+			if rule.Cooldown, err = time.ParseDuration(parts[2]); err != nil || rule.Cooldown < 0 {
+				logging.Warnf(logger, "WARNING: Ignoring accumulation rule %q, %q is not a duration", entry, parts[2])
+				continue
+			}
+		}
+		rules[parts[0]] = rule
+	}
+	return rules
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"net/http"
+	"time"
+)
+
+// clockSkewWarning is the clock skew above which users are warned to sync their system clock
+const clockSkewWarning = 30 * time.Second
+
+// clockSkewTolerance is the skew below which the local clock is trusted, the Date header only has
+// second resolution
+const clockSkewTolerance = 2 * time.Second
+
+// measureClockSkew estimates how far the server's clock is ahead of the local one from the Date header of
+// a response to a request sent at sentAt and received at receivedAt. It returns false without a Date header.
+func measureClockSkew(resp *http.Response, sentAt, receivedAt time.Time) (time.Duration, bool) {
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0, false
+	}
+
+	// The header is truncated to the second and the server answered somewhere between sending and receiving
+	serverTime = serverTime.Add(500 * time.Millisecond)
+	localTime := sentAt.Add(receivedAt.Sub(sentAt) / 2)
+	return serverTime.Sub(localTime), true
+}
+
+// serverNow returns the current time on the server's clock, the local time when the skew is within tolerance
+func serverNow(skew time.Duration) time.Time {
+	if skew > -clockSkewTolerance && skew < clockSkewTolerance {
+		return time.Now()
+	}
+	return time.Now().Add(skew)
+}
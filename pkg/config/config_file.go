@@ -0,0 +1,433 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// optionKind is the type of value an option takes
+type optionKind int
+
+const (
+	kindString optionKind = iota
+	kindBool
+	kindInt
+	kindFloat
+	kindDuration
+	kindList // Comma separated in the environment, a list or a comma separated string in a config file
+)
+
+// options are the environment variables the commands read, each can be set in a config file as well
+var options = map[string]optionKind{
+	"ACCOUNT_CACHE_TTL":             kindDuration,
+	"ACCUMULATE_TOKENS":             kindList,
+	"AIRDROP_CACHE_MAX_AGE":         kindDuration,
+	"AIRDROP_CACHE_PATH":            kindString,
+	"AIRDROP_STORE":                 kindString,
+	"AIRDROP_STORE_FLUSH":           kindDuration,
+	"AIRDROP_STORE_PATH":            kindString,
+	"ALERT_EMAIL_TO":                kindList,
+	"APPROVAL_MODE":                 kindBool,
+	"APPROVAL_TIMEOUT":              kindDuration,
+	"ATOMIC_CLAIM_AND_SELL":         kindBool,
+	"AUTH_TOKEN":                    kindString,
+	"AUTO_SELL":                     kindBool,
+	"BOOP_API_URL":                  kindString,
+	"BOOP_ORIGIN":                   kindString,
+	"BOOP_PROXY_URL":                kindString,
+	"CHECK_INTERVAL":                kindDuration,
+	"CLAIM_CONCURRENCY":             kindInt,
+	"CLAIM_DELAY_MAX":               kindDuration,
+	"CLAIM_DELAY_MIN":               kindDuration,
+	"CLAIM_DELAY_URGENT_USD":        kindFloat,
+	"CLAIM_MIN_NET_PROFIT_SOL":      kindFloat,
+	"CLAIM_PACING":                  kindDuration,
+	"CLAIM_RACE_WINDOW":             kindDuration,
+	"COINGECKO_PROXY_URL":           kindString,
+	"COMPUTE_UNIT_MARGIN":           kindFloat,
+	"CONGESTION_FEE_MULTIPLIER":     kindFloat,
+	"CONGESTION_MAX_DEFER":          kindDuration,
+	"CONGESTION_MAX_PRIORITY_FEE":   kindInt,
+	"CONGESTION_SCHEDULING":         kindBool,
+	"CONGESTION_URGENT_USD":         kindFloat,
+	"CONTROL_API_ADDR":              kindString,
+	"CONTROL_API_TOKEN":             kindString,
+	"DATA_DIR":                      kindString,
+	"DEBUG":                         kindBool,
+	"EFFICIENCY_ALERT_RATIO":        kindFloat,
+	"ENABLE_TELEGRAM":               kindBool,
+	"EVENT_LOG_FILE":                kindString,
+	"FEATURES":                      kindList,
+	"JOURNAL_PATH":                  kindString,
+	"JUPITER_FEE_ACCOUNT":           kindString,
+	"JUPITER_PLATFORM_FEE_BPS":      kindInt,
+	"JUPITER_PROXY_URL":             kindString,
+	"KEEP_TOKENS":                   kindList,
+	"KEYRING_BACKEND":               kindString,
+	"KILLSWITCH_FILE":               kindString,
+	"KILLSWITCH_URL":                kindString,
+	"LOG_COMPRESS":                  kindBool,
+	"LOG_FILE":                      kindString,
+	"LOG_FORMAT":                    kindString,
+	"LOG_LEVEL":                     kindString,
+	"LOG_MAX_BACKUPS":               kindInt,
+	"LOG_MAX_SIZE_MB":               kindInt,
+	"LOG_ROTATE_INTERVAL":           kindDuration,
+	"MINIMUM_SOL_THRESHOLD":         kindFloat,
+	"MINIMUM_USD_THRESHOLD":         kindFloat,
+	"NEW_WALLET_PRIVATE_KEY":        kindString,
+	"NOTIFICATION_BATCH_INTERVAL":   kindDuration,
+	"POSITION_CAP_SELL":             kindBool,
+	"POSITION_CAP_USD":              kindFloat,
+	"POSITION_CHECK_INTERVAL":       kindDuration,
+	"PRICE_CACHE_TTL":               kindDuration,
+	"PRICE_HISTORY_SAMPLES":         kindInt,
+	"PRIVY_API_URL":                 kindString,
+	"PRIVY_AUTH":                    kindString,
+	"PRIVY_PROXY_URL":               kindString,
+	"PRIVY_REFRESH_TOKEN":           kindString,
+	"PRIVY_TOKEN":                   kindString,
+	"PROFIT_INCLUDE_ESTIMATES":      kindBool,
+	"PROFIT_PRECHECK":               kindBool,
+	"PROXY_URL":                     kindString,
+	"PUSHOVER_PRIORITY":             kindInt,
+	"PUSHOVER_TOKEN":                kindString,
+	"PUSHOVER_USER":                 kindString,
+	"PYTH_SOL_USD_ACCOUNT":          kindString,
+	"RATE_LIMIT_ALERT_AFTER":        kindDuration,
+	"RATE_LIMIT_COOLDOWN":           kindDuration,
+	"RECEIVING_WALLET":              kindString,
+	"RECLAIM_RENT":                  kindBool,
+	"RENT_RECLAIM_INTERVAL":         kindDuration,
+	"REPRICE_BEFORE_CLAIM":          kindBool,
+	"RETRY_BUDGET":                  kindInt,
+	"RETRY_MAX_ATTEMPTS":            kindInt,
+	"ROUTE_HINTS":                   kindList,
+	"SAFETY_CHECK_TTL":              kindDuration,
+	"SAFETY_MAX_TOP_HOLDER":         kindFloat,
+	"SAFETY_MODE":                   kindString,
+	"SELL_DELAY":                    kindDuration,
+	"SELL_FAILURE_COOLDOWN":         kindDuration,
+	"SELL_FAILURE_LIMIT":            kindInt,
+	"SELL_INTO_STRENGTH":            kindBool,
+	"SELL_TARGET":                   kindString,
+	"SERVICE_FEE_BPS":               kindInt,
+	"SERVICE_FEE_WALLET":            kindString,
+	"SHADOW_MINIMUM_USD_THRESHOLD":  kindFloat,
+	"SHADOW_STABLE_DURATION":        kindDuration,
+	"SHADOW_STABLE_MINIMUM_USD":     kindFloat,
+	"SIMULATE_CLAIMS":               kindBool,
+	"SIMULATE_SWAPS":                kindBool,
+	"SLIPPAGE_AUTO_TUNE":            kindBool,
+	"SLIPPAGE_MAX_BPS":              kindInt,
+	"SLIPPAGE_MIN_BPS":              kindInt,
+	"SMTP_URL":                      kindString,
+	"SOLANA_RPC_URL":                kindString,
+	"SOL_PRICE_SOURCE":              kindString,
+	"STABLE_DURATION":               kindDuration,
+	"STABLE_MINIMUM_USD":            kindFloat,
+	"STABLE_SELL_MINIMUM_USD":       kindFloat,
+	"STABLE_TRACKING_LOG_AFTER":     kindDuration,
+	"STARTUP_SUMMARY_TELEGRAM":      kindBool,
+	"STATS_BACKEND":                 kindString,
+	"STATS_DATA_DIR":                kindString,
+	"SWAP_QUOTE_MAX_AGE":            kindDuration,
+	"SWAP_QUOTE_MAX_SHORTFALL":      kindFloat,
+	"SWAP_QUOTE_RECHECK_DELAY":      kindDuration,
+	"SWAP_SIMULATION_TOLERANCE":     kindFloat,
+	"TELEGRAM_BOT_TOKEN":            kindString,
+	"TELEGRAM_CHAT_ID":              kindString,
+	"TELEGRAM_FALLBACK_AFTER":       kindDuration,
+	"TELEGRAM_FALLBACK_WEBHOOK_URL": kindString,
+	"TELEGRAM_PROXY_URL":            kindString,
+	"TELEGRAM_REMINDER_INTERVAL":    kindDuration,
+	"TENANTS_FILE":                  kindString,
+	"TLS_CA_FILE":                   kindString,
+	"TOKEN_LEARNING":                kindBool,
+	"TX_REBROADCAST_INTERVAL":       kindDuration,
+	"UPCOMING_CHECK_INTERVAL":       kindDuration,
+	"VAULT_MIN_AMOUNT":              kindFloat,
+	"VAULT_TRANSFER_INTERVAL":       kindDuration,
+	"VAULT_WALLET":                  kindString,
+	"WALLET_ADDRESS":                kindString,
+	"WALLET_DERIVATION_PATH":        kindString,
+	"WALLET_ENCRYPTED_KEY_FILE":     kindString,
+	"WALLET_KEYPAIR_FILE":           kindString,
+	"WALLET_KEY_PASSPHRASE":         kindString,
+	"WALLET_KEY_SOURCE":             kindString,
+	"WALLET_LABEL":                  kindString,
+	"WALLET_LABELS":                 kindList,
+	"WALLET_MNEMONIC":               kindString,
+	"WALLET_MNEMONIC_PASSPHRASE":    kindString,
+	"WALLET_PRIVATE_KEY":            kindString,
+	"WALLET_PRIVATE_KEYS":           kindList,
+	"WATCH_UPCOMING_AIRDROPS":       kindBool,
+	"WEBHOOK_SECRET":                kindString,
+	"WEBHOOK_URLS":                  kindList,
+	"WHALE_ALERT_USD":               kindFloat,
+	"WHALE_HOLD":                    kindDuration,
+}
+
+// LoadFileFromFlags registers the --config flag and loads the config file it names, or the one in CONFIG_FILE.
+// Commands take flag defaults from the configuration, so the file is loaded before the flags are parsed. The
+// path of the loaded file is returned, empty when there is none.
+func LoadFileFromFlags(flags *flag.FlagSet, args []string) (string, error) {
+	flags.String("config", "", "YAML config file, environment variables override its options")
+
+	path := os.Getenv("CONFIG_FILE")
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			value = args[i+1]
+		}
+		path = value
+	}
+
+	if path != "" {
+		if err := LoadFile(path); err != nil {
+			return path, err
+		}
+	}
+	return path, nil
+}
+
+// LoadFile reads a YAML config file and sets its options as environment variables, which the configuration
+// is read from. Options are named like the environment variables, in any case, and may be grouped:
+//
+//	minimum_usd_threshold: 0.25
+//	check_interval: 2m
+//	telegram:
+//	  bot_token: "123:abc"
+//	  chat_id: "42"
+//	keep_tokens: [mintA, mintB]
+//
+// Variables already set in the environment win over the file. Unknown options and values that do not parse
+// are reported together, with their line.
+func LoadFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values, err := parseConfigFile(path, content)
+	if err != nil {
+		return err
+	}
+
+	for name, value := range values {
+		if _, exists := os.LookupEnv(name); exists {
+			continue
+		}
+		if err := os.Setenv(name, value); err != nil {
+			return fmt.Errorf("failed to apply %s from %s: %w", name, path, err)
+		}
+	}
+	return nil
+}
+
+// WriteFile writes options, by environment variable name, as a new YAML config file readable only by its owner,
+// since it may hold secrets. The options are checked like a loaded file, and an existing file is never replaced.
+func WriteFile(path string, values map[string]string) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, name := range names {
+		value := &yaml.Node{Kind: yaml.ScalarNode, Value: values[name]}
+		if kind := options[name]; kind == kindString || kind == kindList {
+			// Quoted where needed, so a token like 123:abc or a numeric chat ID stays a string
+			value.Tag = "!!str"
+		}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: strings.ToLower(name)}, value)
+	}
+	document := &yaml.Node{
+		Kind:        yaml.DocumentNode,
+		HeadComment: "Boop airdrop redeemer configuration, environment variables override its options",
+		Content:     []*yaml.Node{root},
+	}
+	content, err := yaml.Marshal(document)
+	if err != nil {
+		return fmt.Errorf("failed to encode config file: %w", err)
+	}
+	if _, err := parseConfigFile(path, content); err != nil {
+		return err
+	}
+
+	// O_EXCL keeps an existing config file from being replaced by mistake
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create config file: %w", err)
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// parseConfigFile returns the options of a YAML config file by environment variable name
+func parseConfigFile(path string, content []byte) (map[string]string, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	if len(document.Content) == 0 {
+		return values, nil
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid config file %s: line %d: expected option: value pairs", path, root.Line)
+	}
+
+	var errs []error
+	collectOptions(root, "", values, &errs)
+	for i, err := range errs {
+		errs[i] = fmt.Errorf("%s:%w", path, err)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return values, nil
+}
+
+// collectOptions adds the options of a mapping to values, the keys of nested mappings are joined to their
+// parent's with an underscore
+func collectOptions(mapping *yaml.Node, prefix string, values map[string]string, errs *[]error) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+		name := prefix + strings.ToUpper(strings.ReplaceAll(key.Value, "-", "_"))
+
+		if value.Kind == yaml.MappingNode {
+			collectOptions(value, name+"_", values, errs)
+			continue
+		}
+
+		kind, known := options[name]
+		if !known {
+			*errs = append(*errs, fmt.Errorf("%d: unknown option %s%s", key.Line, key.Value, suggestOption(name)))
+			continue
+		}
+
+		// An empty option keeps its default
+		if value.Tag == "!!null" {
+			continue
+		}
+
+		text, err := optionValue(value, kind)
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("%d: %s: %w", value.Line, key.Value, err))
+			continue
+		}
+		values[name] = text
+	}
+}
+
+// optionValue returns the value of an option as the environment variable holds it, checking it parses
+func optionValue(node *yaml.Node, kind optionKind) (string, error) {
+	if node.Kind == yaml.SequenceNode {
+		if kind != kindList {
+			return "", fmt.Errorf("expected a single value, not a list")
+		}
+		items := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return "", fmt.Errorf("line %d: expected a plain list item", item.Line)
+			}
+			items = append(items, item.Value)
+		}
+		return strings.Join(items, ","), nil
+	}
+	if node.Kind != yaml.ScalarNode {
+		return "", fmt.Errorf("expected a value")
+	}
+
+	value := node.Value
+	switch kind {
+	case kindBool:
+		switch strings.ToLower(value) {
+		case "true", "yes", "1":
+			return "true", nil
+		case "false", "no", "0":
+			return "false", nil
+		}
+		return "", fmt.Errorf("invalid value %q, expected true or false", value)
+	case kindInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return "", fmt.Errorf("invalid value %q, expected a whole number", value)
+		}
+	case kindFloat:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "", fmt.Errorf("invalid value %q, expected a number", value)
+		}
+	case kindDuration:
+		if _, err := time.ParseDuration(value); err != nil {
+			return "", fmt.Errorf("invalid value %q, expected a duration such as 30s, 5m or 1h", value)
+		}
+	}
+	return value, nil
+}
+
+// suggestOption returns a hint naming the known option closest to an unknown one, empty when none is close
+func suggestOption(name string) string {
+	names := make([]string, 0, len(options))
+	for option := range options {
+		names = append(names, option)
+	}
+	sort.Strings(names)
+
+	best, bestDistance := "", 4
+	for _, option := range names {
+		if distance := editDistance(name, option); distance < bestDistance {
+			best, bestDistance = option, distance
+		}
+	}
+	if best == "" {
+		return ", see the configuration table in the README"
+	}
+	return fmt.Sprintf(", did you mean %s?", strings.ToLower(best))
+}
+
+// editDistance returns the Levenshtein distance of two option names
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseConfigFile(t *testing.T) {
+	values, err := parseConfigFile("config.yaml", []byte(`
+minimum_usd_threshold: 0.25
+CHECK_INTERVAL: 2m
+auto-sell: no
+receiving_wallet:
+telegram:
+  bot_token: "123:abc"
+  chat_id: 42
+keep_tokens: [mintA, mintB]
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"MINIMUM_USD_THRESHOLD": "0.25",
+		"CHECK_INTERVAL":        "2m",
+		"AUTO_SELL":             "false",
+		"TELEGRAM_BOT_TOKEN":    "123:abc",
+		"TELEGRAM_CHAT_ID":      "42",
+		"KEEP_TOKENS":           "mintA,mintB",
+	}, values)
+
+	// Every problem is reported with its line
+	_, err = parseConfigFile("config.yaml", []byte(`
+minimum_usd_treshold: 0.25
+check_interval: 5
+claim_concurrency: [1, 2]
+`))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "config.yaml:2: unknown option minimum_usd_treshold, did you mean minimum_usd_threshold?")
+		assert.Contains(t, err.Error(), `config.yaml:3: check_interval: invalid value "5", expected a duration such as 30s, 5m or 1h`)
+		assert.Contains(t, err.Error(), "config.yaml:4: claim_concurrency: expected a single value, not a list")
+	}
+}
+
+func TestLoadFileKeepsEnvironment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("check_interval: 2m\nsell_delay: 1h\n"), 0600))
+	t.Setenv("CHECK_INTERVAL", "30s")
+	t.Setenv("SELL_DELAY", "")
+	os.Unsetenv("SELL_DELAY")
+
+	loaded, err := LoadFileFromFlags(flag.NewFlagSet("test", flag.ContinueOnError), []string{"--config", path})
+	assert.NoError(t, err)
+	assert.Equal(t, path, loaded)
+	assert.Equal(t, "30s", os.Getenv("CHECK_INTERVAL"))
+	assert.Equal(t, "1h", os.Getenv("SELL_DELAY"))
+}
+
+func TestWriteFileRoundTrips(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	values := map[string]string{
+		"MINIMUM_USD_THRESHOLD": "0.5",
+		"TELEGRAM_BOT_TOKEN":    "123:abc",
+		"TELEGRAM_CHAT_ID":      "42",
+		"ENABLE_TELEGRAM":       "true",
+	}
+	assert.NoError(t, WriteFile(path, values))
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "minimum_usd_threshold: 0.5\n")
+	parsed, err := parseConfigFile(path, content)
+	assert.NoError(t, err)
+	assert.Equal(t, values, parsed)
+
+	// Existing files are kept, invalid options are not written
+	assert.Error(t, WriteFile(path, values))
+	assert.Error(t, WriteFile(filepath.Join(t.TempDir(), "invalid.yaml"), map[string]string{"CHECK_INTERVAL": "5"}))
+}
+
+// TestOptionsCoverEnvironment keeps the options of config files in step with the environment variables read
+func TestOptionsCoverEnvironment(t *testing.T) {
+	kinds := map[string]optionKind{
+		"getEnv": kindString, "getSecret": kindString, "getEnvBool": kindBool, "getEnvInt": kindInt,
+		"getEnvFloat": kindFloat, "parseEnvDuration": kindDuration, "getEnvList": kindList,
+	}
+	read := regexp.MustCompile(`(getEnv|getSecret|getEnvBool|getEnvInt|getEnvFloat|parseEnvDuration|getEnvList)\("([A-Z0-9_]+)"`)
+
+	files, err := filepath.Glob("*.go")
+	assert.NoError(t, err)
+	for _, file := range files {
+		source, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		for _, match := range read.FindAllStringSubmatch(string(source), -1) {
+			kind, ok := options[match[2]]
+			if assert.True(t, ok, "%s reads %s, which config files cannot set", file, match[2]) && kind != kindList {
+				// Thresholds read as strings are parsed as numbers right after
+				if kinds[match[1]] != kindString {
+					assert.Equal(t, kinds[match[1]], kind, "kind of %s", match[2])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Endpoints are the Boop and Privy addresses of one environment. The origin must match the GraphQL
+// endpoint, Privy only issues tokens to the site an app is registered for.
+type Endpoints struct {
+	GraphQLURL  string `env:"BOOP_API_URL" default:"https://graphql-mainnet.boop.works/graphql" doc:"Boop GraphQL API"`
+	Origin      string `env:"BOOP_ORIGIN" default:"https://boop.fun" doc:"Boop web app origin sent with every request and signed into the login message"`
+	PrivyAPIURL string `env:"PRIVY_API_URL" default:"https://auth.privy.io/api/v1" doc:"Privy auth API base URL"`
+}
+
+// LoadEndpoints reads the endpoints from the environment, defaulting to Boop mainnet
+func LoadEndpoints() Endpoints {
+	return Endpoints{
+		GraphQLURL:  getEnv("BOOP_API_URL", "https://graphql-mainnet.boop.works/graphql"),
+		Origin:      strings.TrimSuffix(getEnv("BOOP_ORIGIN", "https://boop.fun"), "/"),
+		PrivyAPIURL: strings.TrimSuffix(getEnv("PRIVY_API_URL", "https://auth.privy.io/api/v1"), "/"),
+	}
+}
+
+// Referer returns the referer browsers send for the origin
+func (e Endpoints) Referer() string {
+	return e.Origin + "/"
+}
+
+// Domain returns the host of the origin, used as the domain in the sign-in message
+func (e Endpoints) Domain() string {
+	if parsed, err := url.Parse(e.Origin); err == nil && parsed.Host != "" {
+		return parsed.Host
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(e.Origin, "https://"), "http://")
+}
+
+// PrivyURL returns the address of a Privy API path such as "/sessions"
+func (e Endpoints) PrivyURL(path string) string {
+	return e.PrivyAPIURL + path
+}
+
+// SetOriginHeaders sets the Origin and Referer headers of the Boop web app
+func (e Endpoints) SetOriginHeaders(req *http.Request) {
+	req.Header.Set("Origin", e.Origin)
+	req.Header.Set("Referer", e.Referer())
+}
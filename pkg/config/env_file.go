@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// UpdateEnvFile rewrites KEY=value assignments in a shell/batch run script or .env file.
+// Existing assignments keep their "export"/"set" prefix and quoting; missing keys are appended
+// in the style used by the rest of the file.
+func UpdateEnvFile(path string, values map[string]string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read env file: %w", err)
+	}
+
+	lines := strings.Split(string(content), "\n")
+	updated := make(map[string]bool)
+	prefix := ""
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		linePrefix := ""
+		for _, candidate := range []string{"export ", "set "} {
+			if strings.HasPrefix(trimmed, candidate) {
+				linePrefix = candidate
+				break
+			}
+		}
+
+		assignment := strings.TrimPrefix(trimmed, linePrefix)
+		eq := strings.Index(assignment, "=")
+		if eq <= 0 {
+			continue
+		}
+
+		if linePrefix != "" {
+			prefix = linePrefix
+		}
+
+		key := assignment[:eq]
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+
+		quote := ""
+		if old := assignment[eq+1:]; strings.HasPrefix(old, `"`) {
+			quote = `"`
+		}
+
+		lines[i] = fmt.Sprintf("%s%s=%s%s%s", linePrefix, key, quote, value, quote)
+		updated[key] = true
+	}
+
+	// Append keys that were not present, in a stable order, keeping the trailing newline
+	trailingNewline := len(lines) > 0 && lines[len(lines)-1] == ""
+	if trailingNewline {
+		lines = lines[:len(lines)-1]
+	}
+
+	var missing []string
+	for key := range values {
+		if !updated[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+
+	for _, key := range missing {
+		lines = append(lines, fmt.Sprintf("%s%s=%s", prefix, key, values[key]))
+	}
+
+	if trailingNewline {
+		lines = append(lines, "")
+	}
+
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0600); err != nil {
+		return fmt.Errorf("failed to write env file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"boop-airdrop-redeemer/pkg/logging"
+)
+
+// Feature names a risky subsystem that can be switched on on its own, so it can be adopted incrementally
+type Feature string
+
+// Feature flags accepted in FEATURES
+const (
+	FeatureAtomicClaimAndSell   Feature = "atomic_claim_and_sell" // Sell in the claim transaction itself
+	FeatureCongestionScheduling Feature = "congestion_scheduling" // Defer claims while the network is congested
+	FeatureSlippageAutoTune     Feature = "slippage_auto_tune"    // Tune per-token slippage from fills
+	FeatureTokenLearning        Feature = "token_learning"        // Skip the stability wait for dumping tokens
+)
+
+// featureSettings maps every feature flag to the setting it toggles
+func (c *Config) featureSettings() map[Feature]*bool {
+	return map[Feature]*bool{
+		FeatureAtomicClaimAndSell:   &c.AtomicClaimAndSell,
+		FeatureCongestionScheduling: &c.CongestionScheduling,
+		FeatureSlippageAutoTune:     &c.SlippageAutoTune,
+		FeatureTokenLearning:        &c.TokenLearning,
+	}
+}
+
+// applyFeatureFlags enables the listed features on top of their own settings, unknown names are logged and ignored
+func (c *Config) applyFeatureFlags(names []string, logger *log.Logger) {
+	settings := c.featureSettings()
+	for _, name := range names {
+		setting, ok := settings[Feature(strings.ToLower(name))]
+		if !ok {
+			logging.Warnf(logger, "WARNING: Ignoring unknown feature flag %q", name)
+			continue
+		}
+		*setting = true
+	}
+}
+
+// ActiveFeatures returns the enabled feature flags in alphabetical order
+func (c *Config) ActiveFeatures() []Feature {
+	var active []Feature
+	for feature, setting := range c.featureSettings() {
+		if *setting {
+			active = append(active, feature)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i] < active[j] })
+	return active
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/term"
+
+	"boop-airdrop-redeemer/pkg/keys"
+	"boop-airdrop-redeemer/pkg/redact"
+)
+
+// Private key sources selected with WALLET_KEY_SOURCE
+const (
+	// KeySourceEnv reads the key from WALLET_PRIVATE_KEY, WALLET_KEYPAIR_FILE, WALLET_MNEMONIC or
+	// WALLET_ENCRYPTED_KEY_FILE
+	KeySourceEnv = "env"
+	// KeySourcePrompt asks for the key on the terminal without echoing it
+	KeySourcePrompt = "prompt"
+	// KeySourceSocket waits for the key on a private unix socket
+	KeySourceSocket = "socket"
+	// KeySourceKeyring reads the key from the OS keyring selected with KEYRING_BACKEND
+	KeySourceKeyring = "keyring"
+)
+
+// ReadPrivateKey obtains the wallet private key from the given source. Prompt and socket keys
+// are only held in memory, they are never written to the environment or to disk.
+func ReadPrivateKey(source string, logger *log.Logger) (string, error) {
+	switch source {
+	case "", KeySourceEnv:
+		return envPrivateKey()
+	case KeySourcePrompt:
+		return promptPrivateKey()
+	case KeySourceSocket:
+		return receivePrivateKey(logger)
+	case KeySourceKeyring:
+		return keyringPrivateKey()
+	default:
+		return "", fmt.Errorf("unknown WALLET_KEY_SOURCE %q, expected env, prompt, socket or keyring", source)
+	}
+}
+
+// ReadWalletPrivateKeys returns the comma separated private keys in WALLET_PRIVATE_KEYS, read from the
+// environment or the OS keyring, for running several wallets of one owner from a single process
+func ReadWalletPrivateKeys() []string {
+	var keys []string
+	for _, key := range strings.Split(getSecret("WALLET_PRIVATE_KEYS", ""), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	redact.AddSecret(keys...)
+	return keys
+}
+
+// envPrivateKey reads the key from whichever of WALLET_PRIVATE_KEY, WALLET_KEYPAIR_FILE, WALLET_MNEMONIC and
+// WALLET_ENCRYPTED_KEY_FILE is set, returned as base58
+func envPrivateKey() (string, error) {
+	privateKey := os.Getenv("WALLET_PRIVATE_KEY")
+	keypairFile := os.Getenv("WALLET_KEYPAIR_FILE")
+	mnemonic := getSecret("WALLET_MNEMONIC", "")
+	encryptedFile := os.Getenv("WALLET_ENCRYPTED_KEY_FILE")
+	redact.AddSecret(mnemonic)
+
+	set := 0
+	for _, value := range []string{privateKey, keypairFile, mnemonic, encryptedFile} {
+		if value != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return "", fmt.Errorf("set only one of WALLET_PRIVATE_KEY, WALLET_KEYPAIR_FILE, WALLET_MNEMONIC and WALLET_ENCRYPTED_KEY_FILE")
+	}
+
+	switch {
+	case encryptedFile != "":
+		return decryptPrivateKey(encryptedFile)
+	case keypairFile != "":
+		key, err := keys.FromKeypairFile(keypairFile)
+		if err != nil {
+			return "", err
+		}
+		return key.String(), nil
+	case mnemonic != "":
+		key, err := keys.FromMnemonic(mnemonic, getSecret("WALLET_MNEMONIC_PASSPHRASE", ""), os.Getenv("WALLET_DERIVATION_PATH"))
+		if err != nil {
+			return "", err
+		}
+		return key.String(), nil
+	}
+	return privateKey, nil
+}
+
+// decryptPrivateKey decrypts the key file with the passphrase in WALLET_KEY_PASSPHRASE, or one asked for on the
+// terminal. Neither the passphrase nor the key is written anywhere.
+func decryptPrivateKey(path string) (string, error) {
+	passphrase := getSecret("WALLET_KEY_PASSPHRASE", "")
+	if passphrase == "" {
+		var err error
+		passphrase, err = readHidden(fmt.Sprintf("Passphrase for %s: ", path))
+		if errors.Is(err, errNoTerminal) {
+			return "", fmt.Errorf("WALLET_ENCRYPTED_KEY_FILE needs WALLET_KEY_PASSPHRASE or an interactive terminal")
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase: %w", err)
+		}
+	}
+	redact.AddSecret(passphrase)
+
+	key, err := keys.FromEncryptedFile(path, passphrase)
+	if err != nil {
+		return "", err
+	}
+	return key.String(), nil
+}
+
+// keyringPrivateKey reads the key stored under WALLET_PRIVATE_KEY in the OS keyring
+func keyringPrivateKey() (string, error) {
+	keyring, err := NewKeyring(os.Getenv("KEYRING_BACKEND"))
+	if err != nil {
+		return "", err
+	}
+	if keyring == nil {
+		return "", fmt.Errorf("WALLET_KEY_SOURCE=keyring requires KEYRING_BACKEND")
+	}
+
+	key, err := keyring.Get("WALLET_PRIVATE_KEY")
+	if err != nil {
+		return "", fmt.Errorf("failed to read private key from keyring: %w", err)
+	}
+	return strings.TrimSpace(key), nil
+}
+
+// promptPrivateKey reads the key from the terminal with echo disabled
+func promptPrivateKey() (string, error) {
+	key, err := readHidden("Wallet private key (base58): ")
+	if errors.Is(err, errNoTerminal) {
+		return "", fmt.Errorf("WALLET_KEY_SOURCE=prompt requires an interactive terminal")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read private key: %w", err)
+	}
+	return strings.TrimSpace(key), nil
+}
+
+// errNoTerminal is returned by readHidden when stdin is not a terminal
+var errNoTerminal = errors.New("stdin is not a terminal")
+
+// readHidden asks for a secret on the terminal with echo disabled and returns it as entered
+func readHidden(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errNoTerminal
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	secret, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// receivePrivateKey listens on a unix socket inside a new owner-only directory and reads one key from
+// the first connection. The socket is removed as soon as the key has been received.
+func receivePrivateKey(logger *log.Logger) (string, error) {
+	dir, err := os.MkdirTemp("", "boop-key-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create key socket directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "key.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return "", fmt.Errorf("failed to listen on key socket: %w", err)
+	}
+	defer listener.Close()
+
+	logger.Printf("Waiting for the private key on %s (e.g. nc -U %s)", path, path)
+
+	conn, err := listener.Accept()
+	if err != nil {
+		return "", fmt.Errorf("failed to accept key socket connection: %w", err)
+	}
+	defer conn.Close()
+
+	key, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil && key == "" {
+		return "", fmt.Errorf("failed to read private key from socket: %w", err)
+	}
+
+	return strings.TrimSpace(key), nil
+}
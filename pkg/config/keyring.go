@@ -0,0 +1,201 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"boop-airdrop-redeemer/pkg/logging"
+)
+
+// KeyringService is the service name secrets are stored under in the OS keyring
+const KeyringService = "boop-airdrop-redeemer"
+
+// Keyring backends selected with KEYRING_BACKEND
+const (
+	// KeyringNone disables the keyring, secrets are only read from the environment
+	KeyringNone = "none"
+	// KeyringAuto picks the native keyring of the current OS
+	KeyringAuto = "auto"
+	// KeyringKeychain uses the macOS login keychain through the security tool
+	KeyringKeychain = "keychain"
+	// KeyringSecretService uses the freedesktop Secret Service (GNOME Keyring, KWallet) through secret-tool
+	KeyringSecretService = "secret-service"
+	// KeyringWincred is the Windows Credential Manager, which has no built-in tool able to read secrets back
+	KeyringWincred = "wincred"
+)
+
+// ErrSecretNotFound is returned when the keyring has no entry for a secret
+var ErrSecretNotFound = errors.New("secret not found in keyring")
+
+// Keyring stores named secrets in an OS credential store
+type Keyring interface {
+	// Get returns the secret stored under name, or ErrSecretNotFound
+	Get(name string) (string, error)
+	// Set creates or replaces the secret stored under name
+	Set(name, value string) error
+}
+
+// NewKeyring creates the keyring for the given backend, returning nil when the keyring is disabled
+func NewKeyring(backend string) (Keyring, error) {
+	backend = strings.ToLower(strings.TrimSpace(backend))
+	if backend == KeyringAuto {
+		switch runtime.GOOS {
+		case "darwin":
+			backend = KeyringKeychain
+		case "windows":
+			backend = KeyringWincred
+		default:
+			backend = KeyringSecretService
+		}
+	}
+
+	switch backend {
+	case "", KeyringNone:
+		return nil, nil
+	case KeyringKeychain:
+		if _, err := exec.LookPath("security"); err != nil {
+			return nil, fmt.Errorf("keychain backend requires the security tool: %w", err)
+		}
+		return keychainKeyring{}, nil
+	case KeyringSecretService:
+		if _, err := exec.LookPath("secret-tool"); err != nil {
+			return nil, fmt.Errorf("secret-service backend requires secret-tool (libsecret-tools): %w", err)
+		}
+		return secretServiceKeyring{}, nil
+	case KeyringWincred:
+		return nil, fmt.Errorf("the Windows Credential Manager cannot be read without extra tooling, use environment variables or WALLET_KEY_SOURCE=prompt")
+	default:
+		return nil, fmt.Errorf("unknown KEYRING_BACKEND %q, expected none, auto, keychain or secret-service", backend)
+	}
+}
+
+// keychainKeyring stores secrets as generic passwords in the macOS login keychain
+type keychainKeyring struct{}
+
+// Get reads a generic password, the security tool exits with 44 when the item does not exist
+func (keychainKeyring) Get(name string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", KeyringService, "-a", name, "-w").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 {
+			return "", ErrSecretNotFound
+		}
+		return "", fmt.Errorf("failed to read %s from keychain: %w", name, err)
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// Set writes a generic password. The command is passed on stdin so the secret never shows up in
+// the process list.
+func (keychainKeyring) Set(name, value string) error {
+	if strings.ContainsAny(value, "\"\\\r\n") || strings.ContainsAny(name, "\"\\\r\n") {
+		return fmt.Errorf("secret %s contains characters the keychain tool cannot quote", name)
+	}
+
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s \"%s\" -a \"%s\" -w \"%s\"\n", KeyringService, name, value))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to store %s in keychain: %w: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// secretServiceKeyring stores secrets through the freedesktop Secret Service API
+type secretServiceKeyring struct{}
+
+// Get looks the secret up by its service and key attributes. secret-tool prints nothing when
+// there is no match.
+func (secretServiceKeyring) Get(name string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "lookup", "service", KeyringService, "key", name)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if stdout.Len() == 0 {
+		if err != nil && stderr.Len() > 0 {
+			return "", fmt.Errorf("failed to read %s from secret service: %s", name, strings.TrimSpace(stderr.String()))
+		}
+		return "", ErrSecretNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from secret service: %w", name, err)
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+// Set stores the secret, which secret-tool reads from stdin
+func (secretServiceKeyring) Set(name, value string) error {
+	cmd := exec.Command("secret-tool", "store", "--label", KeyringService+" "+name, "service", KeyringService, "key", name)
+	cmd.Stdin = strings.NewReader(value)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to store %s in secret service: %w: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+var (
+	keyringOnce    sync.Once
+	defaultKeyring Keyring
+	keyringLogger  = logging.For("config")
+)
+
+// DefaultKeyring returns the keyring selected with KEYRING_BACKEND, or nil when it is disabled or unavailable
+func DefaultKeyring() Keyring {
+	keyringOnce.Do(func() {
+		keyring, err := NewKeyring(os.Getenv("KEYRING_BACKEND"))
+		if err != nil {
+			logging.Warnf(keyringLogger, "WARNING: Keyring disabled: %v", err)
+			return
+		}
+		defaultKeyring = keyring
+	})
+	return defaultKeyring
+}
+
+// getSecret reads a secret from the environment, falling back to the OS keyring when the variable is unset
+func getSecret(key, defaultValue string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+
+	keyring := DefaultKeyring()
+	if keyring == nil {
+		return defaultValue
+	}
+
+	value, err := keyring.Get(key)
+	if err != nil {
+		if !errors.Is(err, ErrSecretNotFound) {
+			logging.Warnf(keyringLogger, "WARNING: %v", err)
+		}
+		return defaultValue
+	}
+	return value
+}
+
+// storeSecret writes a rotated secret back to the OS keyring. Secrets provided through the environment
+// are left alone since the environment takes precedence on the next start anyway.
+func storeSecret(key, value string) {
+	if value == "" {
+		return
+	}
+	if _, exists := os.LookupEnv(key); exists {
+		return
+	}
+
+	keyring := DefaultKeyring()
+	if keyring == nil {
+		return
+	}
+
+	if err := keyring.Set(key, value); err != nil {
+		logging.Warnf(keyringLogger, "WARNING: %v", err)
+	}
+}
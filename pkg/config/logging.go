@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/redact"
+)
+
+// LoadLogSettings reads the log output format (LOG_FORMAT) and lowest logged level (LOG_LEVEL). Commands
+// read them before the rest of the configuration, so loading it is already logged in the chosen format.
+func LoadLogSettings() (format, level string) {
+	format = strings.ToLower(getEnv("LOG_FORMAT", "plain"))
+	level = strings.ToLower(getEnv("LOG_LEVEL", "info"))
+	return format, level
+}
+
+// Bootstrap sets up a command's process logger from LOG_FORMAT and LOG_LEVEL, writing to standard output and
+// LOG_FILE when one is set, and routes the API clients through the proxy and TLS settings. Commands call it
+// before anything else, once a config file is loaded, so all of them log and connect alike. The returned
+// function closes the log file.
+func Bootstrap() (func() error, error) {
+	format, level := LoadLogSettings()
+	output, closeLog, err := LoadLogFiles().Output(redact.Stdout)
+	if err != nil {
+		return nil, err
+	}
+	if err := logging.Setup(output, format, level); err != nil {
+		closeLog()
+		return nil, err
+	}
+	// Commands signing in before they build the configuration route their API clients from here on
+	if err := LoadNetwork().Apply(); err != nil {
+		closeLog()
+		return nil, fmt.Errorf("invalid proxy or TLS settings: %w", err)
+	}
+	return closeLog, nil
+}
+
+// LogFiles are the optional files the log and the event stream are written to, and how they are rotated
+type LogFiles struct {
+	LogFile        string        `env:"LOG_FILE" doc:"File the log is also written to, in the LOG_FORMAT of standard output"`
+	EventLogFile   string        `env:"EVENT_LOG_FILE" doc:"File every confirmed claim and sale is appended to as a JSON line"`
+	MaxSizeMB      int           `env:"LOG_MAX_SIZE_MB" default:"100" doc:"Size in MB after which a log file is rotated, 0 never rotates on size"`
+	RotateInterval time.Duration `env:"LOG_ROTATE_INTERVAL" default:"0" doc:"Age after which a log file is rotated, 0 never rotates on age"`
+	MaxBackups     int           `env:"LOG_MAX_BACKUPS" default:"7" doc:"Rotated files kept per log file, 0 keeps all"`
+	Compress       bool          `env:"LOG_COMPRESS" default:"true" doc:"Gzip rotated log files"`
+}
+
+// LoadLogFiles reads the log file settings. Like the log settings they are read before the rest of the
+// configuration.
+func LoadLogFiles() LogFiles {
+	return LogFiles{
+		LogFile:        expandPath(getEnv("LOG_FILE", "")),
+		EventLogFile:   expandPath(getEnv("EVENT_LOG_FILE", "")),
+		MaxSizeMB:      getEnvInt("LOG_MAX_SIZE_MB", 100),
+		RotateInterval: parseEnvDuration("LOG_ROTATE_INTERVAL", 0),
+		MaxBackups:     getEnvInt("LOG_MAX_BACKUPS", 7),
+		Compress:       getEnvBool("LOG_COMPRESS", true),
+	}
+}
+
+// Rotation returns the rotation options of the log files
+func (f LogFiles) Rotation() logging.RotateOptions {
+	return logging.RotateOptions{
+		MaxSize:    int64(f.MaxSizeMB) << 20,
+		Interval:   f.RotateInterval,
+		MaxBackups: f.MaxBackups,
+		Compress:   f.Compress,
+	}
+}
+
+// Output returns stdout, also writing to LogFile when one is set. The file is redacted like standard output.
+// The returned function closes the file.
+func (f LogFiles) Output(stdout io.Writer) (io.Writer, func() error, error) {
+	if f.LogFile == "" {
+		return stdout, func() error { return nil }, nil
+	}
+	file, err := logging.OpenRotatingFile(f.LogFile, f.Rotation())
+	if err != nil {
+		return nil, nil, fmt.Errorf("LOG_FILE %s: %w", f.LogFile, err)
+	}
+	return io.MultiWriter(stdout, redact.NewWriter(file)), file.Close, nil
+}
+
+// summary describes the log files and their rotation
+func (f LogFiles) summary() string {
+	var files []string
+	if f.LogFile != "" {
+		files = append(files, "log "+f.LogFile)
+	}
+	if f.EventLogFile != "" {
+		files = append(files, "events "+f.EventLogFile)
+	}
+	if len(files) == 0 {
+		return "off"
+	}
+
+	var rotation []string
+	if f.MaxSizeMB > 0 {
+		rotation = append(rotation, fmt.Sprintf("%d MB", f.MaxSizeMB))
+	}
+	if f.RotateInterval > 0 {
+		rotation = append(rotation, "every "+f.RotateInterval.String())
+	}
+	if len(rotation) == 0 {
+		return strings.Join(files, ", ") + ", never rotated"
+	}
+	kept := "all kept"
+	if f.MaxBackups > 0 {
+		kept = fmt.Sprintf("%d kept", f.MaxBackups)
+	}
+	if f.Compress {
+		kept += " compressed"
+	}
+	return fmt.Sprintf("%s, rotated at %s, %s", strings.Join(files, ", "), strings.Join(rotation, " or "), kept)
+}
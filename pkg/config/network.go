@@ -0,0 +1,80 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+
+	"boop-airdrop-redeemer/pkg/network"
+)
+
+// Network are the proxies and certificate authorities of the Privy, Boop, Jupiter, CoinGecko and Telegram clients
+type Network struct {
+	ProxyURL          string `env:"PROXY_URL" doc:"Proxy of the external API clients: http, https, socks5 or socks5h URL, HTTPS_PROXY when empty"`
+	BoopProxyURL      string `env:"BOOP_PROXY_URL" doc:"Proxy of the Boop GraphQL API, overriding PROXY_URL, direct for none"`
+	PrivyProxyURL     string `env:"PRIVY_PROXY_URL" doc:"Proxy of the Privy auth API, overriding PROXY_URL, direct for none"`
+	JupiterProxyURL   string `env:"JUPITER_PROXY_URL" doc:"Proxy of the Jupiter quote and swap API, overriding PROXY_URL, direct for none"`
+	CoinGeckoProxyURL string `env:"COINGECKO_PROXY_URL" doc:"Proxy of the CoinGecko price API, overriding PROXY_URL, direct for none"`
+	TelegramProxyURL  string `env:"TELEGRAM_PROXY_URL" doc:"Proxy of the Telegram bot API, overriding PROXY_URL, direct for none"`
+	CAFile            string `env:"TLS_CA_FILE" doc:"PEM file of certificate authorities trusted besides the system ones, e.g. of a TLS inspecting proxy"`
+}
+
+// LoadNetwork reads the proxy and TLS settings. They are applied when the config file is loaded and again when
+// the configuration is built, before it signs in to Privy and Boop.
+func LoadNetwork() Network {
+	return Network{
+		ProxyURL:          getEnv("PROXY_URL", ""),
+		BoopProxyURL:      getEnv("BOOP_PROXY_URL", ""),
+		PrivyProxyURL:     getEnv("PRIVY_PROXY_URL", ""),
+		JupiterProxyURL:   getEnv("JUPITER_PROXY_URL", ""),
+		CoinGeckoProxyURL: getEnv("COINGECKO_PROXY_URL", ""),
+		TelegramProxyURL:  getEnv("TELEGRAM_PROXY_URL", ""),
+		CAFile:            expandPath(getEnv("TLS_CA_FILE", "")),
+	}
+}
+
+// services returns the proxy of each service
+func (n Network) services() map[string]string {
+	return map[string]string{
+		network.Boop:      n.BoopProxyURL,
+		network.Privy:     n.PrivyProxyURL,
+		network.Jupiter:   n.JupiterProxyURL,
+		network.CoinGecko: n.CoinGeckoProxyURL,
+		network.Telegram:  n.TelegramProxyURL,
+	}
+}
+
+// Apply routes the external API clients as configured
+func (n Network) Apply() error {
+	return network.Configure(network.Settings{Proxy: n.ProxyURL, Proxies: n.services(), CAFile: n.CAFile})
+}
+
+// summary describes the proxies by service, without their credentials
+func (n Network) summary() string {
+	var parts []string
+	if n.ProxyURL != "" {
+		parts = append(parts, "all via "+proxyHost(n.ProxyURL))
+	}
+	for _, service := range []string{network.Boop, network.Privy, network.Jupiter, network.CoinGecko, network.Telegram} {
+		if proxy := n.services()[service]; proxy != "" {
+			parts = append(parts, service+" via "+proxyHost(proxy))
+		}
+	}
+	if n.CAFile != "" {
+		parts = append(parts, "CA "+n.CAFile)
+	}
+	if len(parts) == 0 {
+		return "environment"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// proxyHost returns the scheme and host of a proxy URL, leaving out its credentials
+func proxyHost(proxy string) string {
+	if proxy == network.Direct {
+		return proxy
+	}
+	if parsed, err := url.Parse(proxy); err == nil && parsed.Host != "" {
+		return parsed.Scheme + "://" + parsed.Host
+	}
+	return proxy
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// appDirName is the directory the redeemer keeps its files in below the per-user config and cache directories
+const appDirName = "boop-airdrop-redeemer"
+
+// legacyDataDir is the data directory earlier versions always used, relative to the working directory
+const legacyDataDir = "data"
+
+// windowsReservedNames are device names Windows does not allow as file names, with or without an extension
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// dataDirs returns the directory on-disk state is kept in and the one rebuildable caches are kept in. DATA_DIR
+// moves both. Without it the ./data directory of earlier versions is kept when it exists, otherwise the
+// per-user directories of the platform are used: %AppData% and %LocalAppData% on Windows, Application Support
+// and Caches on macOS, and the XDG config and cache directories elsewhere.
+func dataDirs() (dataDir, cacheDir string) {
+	if dir := expandPath(getEnv("DATA_DIR", "")); dir != "" {
+		return dir, dir
+	}
+	if info, err := os.Stat(legacyDataDir); err == nil && info.IsDir() {
+		return legacyDataDir, legacyDataDir
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		// No home directory, as for some service accounts
+		return legacyDataDir, legacyDataDir
+	}
+	dataDir = filepath.Join(configDir, appDirName)
+	cacheDir = dataDir
+	if userCacheDir, err := os.UserCacheDir(); err == nil {
+		cacheDir = filepath.Join(userCacheDir, appDirName)
+	}
+	return dataDir, cacheDir
+}
+
+// getEnvPath reads a path option, expanding a leading ~ and environment variables, and defaults to name in dir
+func getEnvPath(key, dir, name string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return expandPath(value)
+	}
+	return filepath.Join(dir, name)
+}
+
+// expandPath expands a leading ~ to the home directory and $VAR or ${VAR} references, and converts forward
+// slashes to the platform separator. An empty path stays empty, it switches the file off.
+func expandPath(path string) string {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return ""
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = home + path[1:]
+		}
+	}
+	return filepath.Clean(filepath.FromSlash(os.ExpandEnv(path)))
+}
+
+// validatePaths checks that the on-disk paths are usable on this platform, and that directories and files are
+// not in each other's place
+func (c *Config) validatePaths() error {
+	var errs []error
+	check := func(option, path string, wantDir bool) {
+		if path == "" {
+			return
+		}
+		if err := checkPathName(path, runtime.GOOS); err != nil {
+			errs = append(errs, fmt.Errorf("%s %q: %w", option, path, err))
+			return
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return
+		}
+		if wantDir && !info.IsDir() {
+			errs = append(errs, fmt.Errorf("%s %q is a file, expected a directory", option, path))
+		}
+		if !wantDir && info.IsDir() {
+			errs = append(errs, fmt.Errorf("%s %q is a directory, expected a file", option, path))
+		}
+	}
+
+	if c.StatsDataDir == "" {
+		errs = append(errs, errors.New("STATS_DATA_DIR is empty"))
+	}
+	check("DATA_DIR", c.DataDir, true)
+	check("STATS_DATA_DIR", c.StatsDataDir, true)
+	check("JOURNAL_PATH", c.JournalPath, false)
+	check("AIRDROP_CACHE_PATH", c.AirdropCachePath, false)
+	check("KILLSWITCH_FILE", c.KillSwitchFile, false)
+	if c.AirdropStore != "memory" {
+		check("AIRDROP_STORE_PATH", c.AirdropStoreFile(), false)
+	}
+	return errors.Join(errs...)
+}
+
+// checkPaths validates the on-disk paths
+func (c *Config) checkPaths() error {
+	if err := c.validatePaths(); err != nil {
+		return fmt.Errorf("invalid path configuration: %w", err)
+	}
+	return nil
+}
+
+// checkPathName rejects path names the platform cannot create. Windows does not allow <>:"|?* in names, a
+// colon only after the drive letter, nor device names like NUL or COM1 with any extension.
+func checkPathName(path, goos string) error {
+	if strings.ContainsRune(path, 0) {
+		return errors.New("contains a NUL character")
+	}
+	if goos != "windows" {
+		return nil
+	}
+
+	if len(path) >= 2 && path[1] == ':' && ('a' <= path[0]|0x20 && path[0]|0x20 <= 'z') {
+		path = path[2:]
+	}
+	// UNC paths like \\server\share and device paths like \\?\C:\ are left to the system
+	if strings.HasPrefix(path, `\\`) {
+		return nil
+	}
+	for _, name := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if i := strings.IndexAny(name, `<>:"|?*`); i >= 0 {
+			return fmt.Errorf("%q contains %q, which Windows does not allow in names", name, name[i])
+		}
+		base := strings.ToUpper(strings.TrimRight(name, ". "))
+		if dot := strings.IndexByte(base, '.'); dot >= 0 {
+			base = base[:dot]
+		}
+		if windowsReservedNames[base] {
+			return fmt.Errorf("%q is a device name on Windows", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataDirRelocatesDefaultPaths(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DATA_DIR", dir)
+	t.Setenv("JOURNAL_PATH", "~/journal.jsonl")
+	home, err := os.UserHomeDir()
+	assert.NoError(t, err)
+
+	dataDir, cacheDir := dataDirs()
+	assert.Equal(t, dir, dataDir)
+	assert.Equal(t, dir, cacheDir, "DATA_DIR also moves the caches")
+	assert.Equal(t, filepath.Join(dir, "stats"), getEnvPath("STATS_DATA_DIR", dataDir, "stats"))
+	assert.Equal(t, filepath.Join(home, "journal.jsonl"), getEnvPath("JOURNAL_PATH", dataDir, "journal.jsonl"))
+}
+
+func TestValidatePaths(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "journal.jsonl")
+	assert.NoError(t, os.WriteFile(file, nil, 0644))
+
+	cfg := Config{DataDir: dir, StatsDataDir: filepath.Join(dir, "stats"), JournalPath: file, AirdropStore: "memory"}
+	assert.NoError(t, cfg.validatePaths())
+
+	cfg.StatsDataDir = file
+	cfg.KillSwitchFile = dir
+	err := cfg.validatePaths()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "STATS_DATA_DIR")
+		assert.Contains(t, err.Error(), "KILLSWITCH_FILE")
+	}
+
+	assert.NoError(t, checkPathName(`C:\Users\me\data\stats`, "windows"))
+	assert.NoError(t, checkPathName("data/aux:stats", "linux"))
+	assert.Error(t, checkPathName(`C:\data\aux.json`, "windows"))
+	assert.Error(t, checkPathName(`data\stats:old`, "windows"))
+}
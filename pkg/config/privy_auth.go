@@ -10,11 +10,14 @@ import (
 	"time"
 
 	"github.com/gagliardetto/solana-go"
+
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/network"
 )
 
 const (
-	privyInitURL         = "https://auth.privy.io/api/v1/siws/init"
-	privyAuthenticateURL = "https://auth.privy.io/api/v1/siws/authenticate"
+	privyInitPath         = "/siws/init"
+	privyAuthenticatePath = "/siws/authenticate"
 )
 
 // PrivyConfig for the headers used in privy requests
@@ -54,9 +57,9 @@ type PrivyAuthResponse struct {
 }
 
 // GetPrivyTokensWithPrivateKey obtains Privy authentication tokens using a wallet private key
-func GetPrivyTokensWithPrivateKey(privateKeyBase58 string, logger *log.Logger) (string, string, string, error) {
+func GetPrivyTokensWithPrivateKey(privateKeyBase58 string, endpoints Endpoints, logger *log.Logger) (string, string, string, error) {
 	if logger == nil {
-		logger = log.New(log.Writer(), "[PRIVY AUTH] ", log.LstdFlags)
+		logger = logging.For("privy-auth")
 	}
 
 	// Parse private key
@@ -72,15 +75,25 @@ func GetPrivyTokensWithPrivateKey(privateKeyBase58 string, logger *log.Logger) (
 	logger.Printf("Authenticating with Privy for wallet: %s", walletAddress)
 
 	// Step 1: Initialize SIWS (Sign In With Solana) process
-	nonce, err := initPrivySignIn(walletAddress)
+	nonce, skew, err := initPrivySignIn(walletAddress, endpoints)
 	if err != nil {
 		return "", "", "", fmt.Errorf("failed to initialize Privy sign-in: %w", err)
 	}
 
 	logger.Printf("Received nonce: %s", nonce)
 
+	// A skewed local clock puts "Issued At" outside the window Privy accepts, sign with Privy's time instead
+	if skew >= clockSkewWarning || skew <= -clockSkewWarning {
+		direction := "behind"
+		if skew < 0 {
+			direction = "ahead of"
+		}
+		logging.Warnf(logger, "WARNING: Local clock is %s %s Privy's, using Privy's time to sign in. "+
+			"Sync the system clock (NTP) to avoid authentication failures.", skew.Abs().Round(time.Second), direction)
+	}
+
 	// Step 2: Generate the message to sign
-	message := generatePrivySignMessage(walletAddress, nonce)
+	message := generatePrivySignMessage(walletAddress, nonce, endpoints, serverNow(skew))
 	logger.Printf("Generated message to sign: %s", message)
 
 	// Step 3: Sign the message
@@ -90,7 +103,7 @@ func GetPrivyTokensWithPrivateKey(privateKeyBase58 string, logger *log.Logger) (
 	}
 
 	// Step 4: Authenticate with the signed message
-	authResponse, err := authenticateWithPrivy(walletAddress, message, signature)
+	authResponse, err := authenticateWithPrivy(walletAddress, message, signature, endpoints)
 	if err != nil {
 		return "", "", "", fmt.Errorf("failed to authenticate with Privy: %w", err)
 	}
@@ -103,8 +116,9 @@ func GetPrivyTokensWithPrivateKey(privateKeyBase58 string, logger *log.Logger) (
 	return token, authResponse.IdentityToken, authResponse.RefreshToken, nil
 }
 
-// initPrivySignIn initializes the Sign In With Solana process
-func initPrivySignIn(walletAddress string) (string, error) {
+// initPrivySignIn initializes the Sign In With Solana process, returning the nonce and how far Privy's clock
+// is ahead of the local one
+func initPrivySignIn(walletAddress string, endpoints Endpoints) (string, time.Duration, error) {
 	// Create the payload
 	payload := map[string]string{
 		"address": walletAddress,
@@ -112,48 +126,52 @@ func initPrivySignIn(walletAddress string) (string, error) {
 
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal payload: %w", err)
+		return "", 0, fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
 	// Create HTTP request
-	req, err := http.NewRequest("POST", privyInitURL, bytes.NewBuffer(payloadBytes))
+	req, err := http.NewRequest("POST", endpoints.PrivyURL(privyInitPath), bytes.NewBuffer(payloadBytes))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return "", 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
-	setPrivyHeaders(req)
+	setPrivyHeaders(req, endpoints)
 
 	// Send the request
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := newAuthHTTPClient(network.Privy)
+	sentAt := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to send init request: %w", err)
+		return "", 0, fmt.Errorf("failed to send init request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	// Compare clocks using the response's Date header
+	skew, _ := measureClockSkew(resp, sentAt, time.Now())
+
 	// Check response status
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes := new(bytes.Buffer)
 		bodyBytes.ReadFrom(resp.Body)
-		return "", fmt.Errorf("init request failed with status %s: %s", resp.Status, bodyBytes.String())
+		return "", 0, fmt.Errorf("init request failed with status %s: %s", resp.Status, bodyBytes.String())
 	}
 
 	// Parse response
 	var initResp PrivyInitResponse
 	if err := json.NewDecoder(resp.Body).Decode(&initResp); err != nil {
-		return "", fmt.Errorf("failed to decode init response: %w", err)
+		return "", 0, fmt.Errorf("failed to decode init response: %w", err)
 	}
 
-	return initResp.Nonce, nil
+	return initResp.Nonce, skew, nil
 }
 
-// generatePrivySignMessage generates the message to sign for Privy authentication
-func generatePrivySignMessage(walletAddress, nonce string) string {
-	now := time.Now().UTC().Format("2006-01-02T15:04:05.999Z")
+// generatePrivySignMessage generates the message to sign for Privy authentication, issued at issuedAt
+func generatePrivySignMessage(walletAddress, nonce string, endpoints Endpoints, issuedAt time.Time) string {
+	now := issuedAt.UTC().Format("2006-01-02T15:04:05.999Z")
 
 	return fmt.Sprintf(
-		"boop.fun wants you to sign in with your Solana account:\n"+
+		"%s wants you to sign in with your Solana account:\n"+
 			"%s\n\n"+
 			"You are proving you own %s.\n\n"+
 			"URI: %s\n"+
@@ -162,9 +180,10 @@ func generatePrivySignMessage(walletAddress, nonce string) string {
 			"Nonce: %s\n"+
 			"Issued At: %s\n"+
 			"Resources:\n- %s",
+		endpoints.Domain(),
 		walletAddress,
 		walletAddress,
-		"https://boop.fun",
+		endpoints.Origin,
 		"1",
 		"mainnet",
 		nonce,
@@ -187,7 +206,7 @@ func signPrivyMessage(privateKey solana.PrivateKey, message string) (string, err
 }
 
 // authenticateWithPrivy completes authentication with the signed message
-func authenticateWithPrivy(walletAddress, message, signature string) (*PrivyAuthResponse, error) {
+func authenticateWithPrivy(walletAddress, message, signature string, endpoints Endpoints) (*PrivyAuthResponse, error) {
 	// Create the payload
 	payload := map[string]interface{}{
 		"message":          message,
@@ -204,16 +223,16 @@ func authenticateWithPrivy(walletAddress, message, signature string) (*PrivyAuth
 	}
 
 	// Create HTTP request
-	req, err := http.NewRequest("POST", privyAuthenticateURL, bytes.NewBuffer(payloadBytes))
+	req, err := http.NewRequest("POST", endpoints.PrivyURL(privyAuthenticatePath), bytes.NewBuffer(payloadBytes))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create auth request: %w", err)
 	}
 
 	// Set headers
-	setPrivyHeaders(req)
+	setPrivyHeaders(req, endpoints)
 
 	// Send the request
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := newAuthHTTPClient(network.Privy)
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send auth request: %w", err)
@@ -237,12 +256,11 @@ func authenticateWithPrivy(walletAddress, message, signature string) (*PrivyAuth
 }
 
 // setPrivyHeaders sets the required headers for Privy API requests
-func setPrivyHeaders(req *http.Request) {
+func setPrivyHeaders(req *http.Request, endpoints Endpoints) {
 	config := DefaultPrivyConfig
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Origin", "https://boop.fun")
-	req.Header.Set("Referer", "https://boop.fun/")
+	endpoints.SetOriginHeaders(req)
 	req.Header.Set("privy-app-id", config.AppID)
 	req.Header.Set("privy-ca-id", config.ClientID)
 	req.Header.Set("privy-client", config.Client)
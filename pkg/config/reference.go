@@ -0,0 +1,189 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// durationPattern matches the Go durations options are written in, such as 90s or 1h30m
+const durationPattern = `^(0|([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+)$`
+
+// standaloneOptions describe options that are read outside the configuration or only by a command, and so
+// have no Config field to carry their tags
+var standaloneOptions = map[string]OptionDoc{
+	"FEATURES":                   {Description: "Comma separated feature flags to switch on"},
+	"KEYRING_BACKEND":            {Description: "OS keyring for secrets missing from the environment: none, auto, keychain or secret-service"},
+	"NEW_WALLET_PRIVATE_KEY":     {Description: "Private key of the wallet cmd/migrate moves all assets to"},
+	"TENANTS_FILE":               {Description: "JSON file listing the wallets served in operator mode"},
+	"WALLET_DERIVATION_PATH":     {Description: "Derivation path of the account in WALLET_MNEMONIC, m/44'/501'/0'/0' when empty"},
+	"WALLET_ENCRYPTED_KEY_FILE":  {Description: "Passphrase encrypted key file to decrypt the private key from at startup"},
+	"WALLET_KEYPAIR_FILE":        {Description: "solana-cli JSON keypair file to read the private key from"},
+	"WALLET_KEY_PASSPHRASE":      {Description: "Passphrase of WALLET_ENCRYPTED_KEY_FILE, asked for on the terminal when not set", Secret: true},
+	"WALLET_KEY_SOURCE":          {Description: "Where the auto claimer reads the private key: env, prompt, socket or keyring, env when empty"},
+	"WALLET_LABELS":              {Description: "Comma separated nicknames for the wallets in WALLET_PRIVATE_KEYS, in the same order"},
+	"WALLET_MNEMONIC":            {Description: "BIP39 recovery phrase to derive the private key from", Secret: true},
+	"WALLET_MNEMONIC_PASSPHRASE": {Description: "Optional BIP39 passphrase of the recovery phrase", Secret: true},
+	"WALLET_PRIVATE_KEYS":        {Description: "Comma separated private keys of several wallets to run from one process", Secret: true},
+}
+
+// OptionDoc describes one configuration option
+type OptionDoc struct {
+	Name        string
+	Type        string // string, bool, int, float, duration or list
+	Default     string // Empty when the option has no default
+	Description string
+	Secret      bool // Also looked up in the OS keyring
+}
+
+// String returns the name of the kind as shown in the reference
+func (k optionKind) String() string {
+	switch k {
+	case kindBool:
+		return "bool"
+	case kindInt:
+		return "int"
+	case kindFloat:
+		return "float"
+	case kindDuration:
+		return "duration"
+	case kindList:
+		return "list"
+	default:
+		return "string"
+	}
+}
+
+// Reference lists every supported option with its type, default and description, sorted by name. Options are
+// documented by the env, default and doc tags of the Config fields they are read into, and of the fields of
+// nested structs such as Network.
+func Reference() ([]OptionDoc, error) {
+	docs := make(map[string]OptionDoc, len(options))
+	for name, doc := range standaloneOptions {
+		docs[name] = doc
+	}
+	if err := collectTags(reflect.TypeOf(Config{}), docs); err != nil {
+		return nil, err
+	}
+
+	result := make([]OptionDoc, 0, len(docs))
+	for name, doc := range docs {
+		kind, ok := options[name]
+		if !ok {
+			return nil, fmt.Errorf("%s is documented but not a known option", name)
+		}
+		doc.Name = name
+		doc.Type = kind.String()
+		result = append(result, doc)
+	}
+	for name := range options {
+		if _, ok := docs[name]; !ok {
+			return nil, fmt.Errorf("%s has no env tag or standalone description", name)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// collectTags documents the options of a struct's tagged fields, descending into untagged struct fields
+func collectTags(t reflect.Type, docs map[string]OptionDoc) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		env, ok := field.Tag.Lookup("env")
+		if !ok {
+			if field.Type.Kind() == reflect.Struct && field.IsExported() {
+				if err := collectTags(field.Type, docs); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		name, flag, _ := strings.Cut(env, ",")
+		if _, exists := docs[name]; exists {
+			return fmt.Errorf("%s is tagged on more than one field", name)
+		}
+		docs[name] = OptionDoc{
+			Default:     field.Tag.Get("default"),
+			Description: field.Tag.Get("doc"),
+			Secret:      flag == "secret",
+		}
+	}
+	return nil
+}
+
+// WriteReference writes the options as an aligned table
+func WriteReference(w io.Writer, docs []OptionDoc) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "OPTION\tTYPE\tDEFAULT\tDESCRIPTION")
+	for _, doc := range docs {
+		defaultValue := doc.Default
+		if defaultValue == "" {
+			defaultValue = "-"
+		}
+		description := doc.Description
+		if doc.Secret {
+			description = strings.TrimSpace(description + " (secret, also read from the OS keyring)")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", doc.Name, doc.Type, defaultValue, description)
+	}
+	return tw.Flush()
+}
+
+// WriteSchema writes the options as a JSON Schema of a config file or environment
+func WriteSchema(w io.Writer, docs []OptionDoc) error {
+	properties := make(map[string]map[string]any, len(docs))
+	for _, doc := range docs {
+		property := map[string]any{"description": doc.Description}
+		switch doc.Type {
+		case "bool":
+			property["type"] = "boolean"
+			if value, err := strconv.ParseBool(doc.Default); err == nil {
+				property["default"] = value
+			}
+		case "int":
+			property["type"] = "integer"
+			if value, err := strconv.Atoi(doc.Default); err == nil {
+				property["default"] = value
+			}
+		case "float":
+			property["type"] = "number"
+			if value, err := strconv.ParseFloat(doc.Default, 64); err == nil {
+				property["default"] = value
+			}
+		case "list":
+			property["type"] = "array"
+			property["items"] = map[string]string{"type": "string"}
+		case "duration":
+			property["type"] = "string"
+			property["pattern"] = durationPattern
+			if doc.Default != "" {
+				property["default"] = doc.Default
+			}
+		default:
+			property["type"] = "string"
+			if doc.Default != "" {
+				property["default"] = doc.Default
+			}
+		}
+		if doc.Secret {
+			property["writeOnly"] = true
+		}
+		properties[doc.Name] = property
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]any{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                "boop-airdrop-redeemer configuration",
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	})
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferenceDocumentsEveryOption(t *testing.T) {
+	docs, err := Reference()
+	assert.NoError(t, err)
+
+	byName := make(map[string]OptionDoc, len(docs))
+	for _, doc := range docs {
+		byName[doc.Name] = doc
+		assert.NotEmpty(t, doc.Description, "%s needs a doc tag on its Config field or a standalone description", doc.Name)
+	}
+	for name := range options {
+		assert.Contains(t, byName, name)
+	}
+
+	assert.Equal(t, OptionDoc{Name: "STABLE_DURATION", Type: "duration", Default: "10m0s",
+		Description: "How long a value must be unchanged and observed before a stable price claim"}, byName["STABLE_DURATION"])
+	assert.Equal(t, "0.15", byName["MINIMUM_USD_THRESHOLD"].Default)
+	assert.True(t, byName["PUSHOVER_TOKEN"].Secret)
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteSchema(&buf, docs))
+	var schema struct {
+		Properties map[string]map[string]any `json:"properties"`
+	}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &schema))
+	assert.Equal(t, map[string]any{"type": "boolean", "default": true, "description": byName["AUTO_SELL"].Description},
+		schema.Properties["AUTO_SELL"])
+	assert.Equal(t, durationPattern, schema.Properties["STABLE_DURATION"]["pattern"])
+	assert.Regexp(t, durationPattern, "1h30m")
+	assert.Regexp(t, durationPattern, "0")
+	assert.NotRegexp(t, durationPattern, "PT10M")
+}
+
+func TestDefaultTagsMatchLoadedDefaults(t *testing.T) {
+	for name := range options {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	dir := t.TempDir()
+	t.Setenv("DATA_DIR", dir)
+	t.Setenv("KEYRING_BACKEND", KeyringNone)
+
+	cfg, err := Load()
+	if !assert.NoError(t, err) {
+		return
+	}
+	checkDefaults(t, reflect.ValueOf(*cfg), strings.NewReplacer("<DATA_DIR>", dir, "<cache dir>", dir))
+}
+
+// checkDefaults compares the fields of a loaded struct with the defaults their tags document
+func checkDefaults(t *testing.T, value reflect.Value, paths *strings.Replacer) {
+	for i := 0; i < value.NumField(); i++ {
+		field, fieldValue := value.Type().Field(i), value.Field(i)
+		env, ok := field.Tag.Lookup("env")
+		if !ok {
+			if field.Type.Kind() == reflect.Struct && field.IsExported() {
+				checkDefaults(t, fieldValue, paths)
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(env, ",")
+		defaultValue := field.Tag.Get("default")
+
+		var want any
+		switch fieldValue.Interface().(type) {
+		case time.Duration:
+			want, _ = time.ParseDuration(defaultValue)
+		case bool:
+			want, _ = strconv.ParseBool(defaultValue)
+		case int:
+			want, _ = strconv.Atoi(defaultValue)
+		case float64:
+			want, _ = strconv.ParseFloat(defaultValue, 64)
+		case string:
+			want = filepath.FromSlash(paths.Replace(defaultValue))
+			if name == "DATA_DIR" {
+				continue
+			}
+		default:
+			assert.Empty(t, defaultValue, "%s has a default of a type that cannot be checked", name)
+			continue
+		}
+		assert.Equal(t, want, fieldValue.Interface(), "%s loads a different default than its tag documents", name)
+	}
+}
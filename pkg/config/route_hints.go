@@ -0,0 +1,68 @@
+package config
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"boop-airdrop-redeemer/pkg/logging"
+)
+
+// RouteHint restricts the routes Jupiter may quote for selling a token to the ones its owner trusts
+type RouteHint struct {
+	Dexes            []string // DEX labels the route may use, such as "Raydium CLMM", any when empty
+	AmmKey           string   // Pool the route must go through, any when empty
+	OnlyDirectRoutes bool     // Swap straight to the output token without intermediate tokens
+	MaxHops          int      // Most swaps in a row, 0 for no limit
+}
+
+// parseRouteHints reads ROUTE_HINTS entries of the form mint:option=value:..., with the options dexes (labels
+// separated by |), amm, direct and hops. Invalid entries are logged and ignored.
+func parseRouteHints(entries []string, logger *log.Logger) map[string]RouteHint {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	hints := make(map[string]RouteHint, len(entries))
+entries:
+	for _, entry := range entries {
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || parts[0] == "" {
+			logging.Warnf(logger, "WARNING: Ignoring route hint %q, expected mint:option=value with dexes, amm, direct or hops", entry)
+			continue
+		}
+
+		var hint RouteHint
+		for _, option := range parts[1:] {
+			key, value, _ := strings.Cut(option, "=")
+			value = strings.TrimSpace(value)
+			var err error
+			switch strings.TrimSpace(key) {
+			case "dexes":
+				for _, dex := range strings.Split(value, "|") {
+					if dex = strings.TrimSpace(dex); dex != "" {
+						hint.Dexes = append(hint.Dexes, dex)
+					}
+				}
+			case "amm":
+				hint.AmmKey = value
+			case "direct":
+				hint.OnlyDirectRoutes, err = strconv.ParseBool(value)
+			case "hops":
+				hint.MaxHops, err = strconv.Atoi(value)
+				if err == nil && hint.MaxHops < 1 {
+					err = strconv.ErrRange
+				}
+			default:
+				logging.Warnf(logger, "WARNING: Ignoring route hint %q, unknown option %q", entry, key)
+				continue entries
+			}
+			if err != nil {
+				logging.Warnf(logger, "WARNING: Ignoring route hint %q, invalid value of %q", entry, key)
+				continue entries
+			}
+		}
+		hints[parts[0]] = hint
+	}
+	return hints
+}
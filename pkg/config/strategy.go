@@ -0,0 +1,93 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+
+	"boop-airdrop-redeemer/pkg/logging"
+)
+
+// strategySettings are the decision strategy settings validated at startup, and whether each is a duration
+var strategySettings = []struct {
+	key      string
+	duration bool
+}{
+	{"MINIMUM_USD_THRESHOLD", false},
+	{"STABLE_MINIMUM_USD", false},
+	{"STABLE_DURATION", true},
+	{"STABLE_TRACKING_LOG_AFTER", true},
+	{"STABLE_SELL_MINIMUM_USD", false},
+	{"SELL_DELAY", true},
+}
+
+// validateStrategy rejects strategy and sale settings that cannot be parsed, which would otherwise fall back to
+// their defaults unnoticed, and values no rule can work with
+func (c *Config) validateStrategy() error {
+	var errs []error
+	for _, setting := range strategySettings {
+		value, ok := os.LookupEnv(setting.key)
+		if !ok {
+			continue
+		}
+		if setting.duration {
+			if _, err := time.ParseDuration(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s=%q is not a duration", setting.key, value))
+			}
+		} else if _, err := strconv.ParseFloat(value, 64); err != nil {
+			errs = append(errs, fmt.Errorf("%s=%q is not a number", setting.key, value))
+		}
+	}
+
+	if c.MinimumUsdThreshold < 0 {
+		errs = append(errs, fmt.Errorf("MINIMUM_USD_THRESHOLD $%.2f is negative", c.MinimumUsdThreshold))
+	}
+	if c.StableMinimumUsd < 0 {
+		errs = append(errs, fmt.Errorf("STABLE_MINIMUM_USD $%.2f is negative", c.StableMinimumUsd))
+	}
+	if c.StableSellMinimumUsd < 0 {
+		errs = append(errs, fmt.Errorf("STABLE_SELL_MINIMUM_USD $%.2f is negative", c.StableSellMinimumUsd))
+	}
+	if c.StableDuration <= 0 {
+		errs = append(errs, fmt.Errorf("STABLE_DURATION %s must be positive", c.StableDuration))
+	}
+	if c.StableTrackingAfter < 0 {
+		errs = append(errs, fmt.Errorf("STABLE_TRACKING_LOG_AFTER %s is negative", c.StableTrackingAfter))
+	}
+	// Any other target would be sold for SOL without a word
+	if c.SellTarget != "SOL" && c.SellTarget != "USDC" {
+		errs = append(errs, fmt.Errorf("SELL_TARGET=%q must be SOL or USDC", c.SellTarget))
+	}
+	if c.SellDelay < 0 {
+		errs = append(errs, fmt.Errorf("SELL_DELAY %s is negative", c.SellDelay))
+	}
+	// An invalid address would leave the claimed tokens in the claiming wallet and sell them
+	if c.ReceivingWallet != "" {
+		if _, err := solana.PublicKeyFromBase58(c.ReceivingWallet); err != nil {
+			errs = append(errs, fmt.Errorf("RECEIVING_WALLET=%q is not a wallet address: %w", c.ReceivingWallet, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkStrategy validates the strategy settings, and warns about settings that switch a rule off without saying so
+func (c *Config) checkStrategy(logger *log.Logger) error {
+	if err := c.validateStrategy(); err != nil {
+		return fmt.Errorf("invalid strategy configuration: %w", err)
+	}
+
+	if c.StableMinimumUsd >= c.MinimumUsdThreshold {
+		logging.Warnf(logger, "WARNING: STABLE_MINIMUM_USD $%.2f is not below MINIMUM_USD_THRESHOLD $%.2f, no airdrop is claimed for a stable value",
+			c.StableMinimumUsd, c.MinimumUsdThreshold)
+	}
+	if c.StableTrackingAfter >= c.StableDuration {
+		logging.Warnf(logger, "WARNING: STABLE_TRACKING_LOG_AFTER %s is not below STABLE_DURATION %s, airdrops waiting for a stable value are not logged",
+			c.StableTrackingAfter, c.StableDuration)
+	}
+	return nil
+}
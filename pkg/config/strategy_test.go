@@ -0,0 +1,40 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateStrategy(t *testing.T) {
+	valid := Config{
+		MinimumUsdThreshold:  0.15,
+		StableMinimumUsd:     0.07,
+		StableDuration:       10 * time.Minute,
+		StableTrackingAfter:  5 * time.Minute,
+		StableSellMinimumUsd: 0.10,
+		SellTarget:           "USDC",
+	}
+	assert.NoError(t, valid.validateStrategy())
+
+	invalid := valid
+	invalid.StableMinimumUsd = -0.01
+	invalid.StableDuration = 0
+	invalid.SellTarget = "USDT"
+	invalid.ReceivingWallet = "not-a-wallet"
+	err := invalid.validateStrategy()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "STABLE_MINIMUM_USD")
+		assert.Contains(t, err.Error(), "STABLE_DURATION")
+		assert.Contains(t, err.Error(), "SELL_TARGET")
+		assert.Contains(t, err.Error(), "RECEIVING_WALLET")
+	}
+
+	// A typo would otherwise fall back to the default unnoticed
+	t.Setenv("STABLE_DURATION", "10 minutes")
+	err = valid.validateStrategy()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "STABLE_DURATION")
+	}
+}
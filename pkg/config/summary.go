@@ -0,0 +1,490 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SummarySection is a titled group of settings in the configuration summary
+type SummarySection struct {
+	Title    string
+	Settings []Setting
+}
+
+// Setting is one effective setting and its value as shown to the user
+type Setting struct {
+	Name  string
+	Value string
+}
+
+// Summary describes the effective configuration so misconfigurations show up at startup. Secrets are left out
+// and URLs are reduced to their host, RPC and webhook URLs often carry API keys in their path or query.
+func (c *Config) Summary() []SummarySection {
+	return []SummarySection{
+		{Title: "Wallet", Settings: []Setting{
+			{"Wallet", c.walletSummary()},
+			{"Authentication", c.authMethod()},
+		}},
+		{Title: "Features", Settings: []Setting{
+			{"Active", c.featureSummary()},
+		}},
+		{Title: "Strategy", Settings: []Setting{
+			{"Minimum USD threshold", fmt.Sprintf("$%.2f", c.MinimumUsdThreshold)},
+			{"Minimum SOL threshold", fmt.Sprintf("%g SOL", c.MinimumSolThreshold)},
+			{"Check interval", c.CheckInterval.String()},
+			{"Claims", c.claimSummary()},
+			{"Claim delay", c.claimDelaySummary()},
+			{"Token safety", c.safetySummary()},
+			{"Stable price rule", c.stableSummary()},
+			{"Price history", c.priceHistorySummary()},
+			{"Reprice before claim", onOff(c.RepriceBeforeClaim)},
+			{"Price cache", durationOrOff(c.PriceCacheTTL)},
+			{"Token learning", onOff(c.TokenLearning)},
+			{"Shadow strategy", c.shadowSummary()},
+			{"Congestion scheduling", c.congestionSummary()},
+			{"Profit pre-check", c.profitPrecheckSummary()},
+			{"Accumulated tokens", c.accumulationSummary()},
+			{"Claim race window", durationOrOff(c.ClaimRaceWindow)},
+			{"Manual approval", c.approvalSummary()},
+		}},
+		{Title: "Selling", Settings: []Setting{
+			{"Auto-sell", c.sellSummary()},
+			{"Atomic claim and sell", onOff(c.AtomicClaimAndSell)},
+			{"Receiving wallet", addressOrNone(c.ReceivingWallet)},
+			{"Kept tokens", c.keepSummary()},
+			{"Position cap", c.positionCapSummary()},
+			{"Slippage", c.slippageSummary()},
+			{"Failing sales", c.sellFailureSummary()},
+			{"Quote max age", durationOrOff(c.QuoteMaxAge)},
+			{"Route hints", c.routeHintSummary()},
+			{"Quote max shortfall", fmt.Sprintf("%.0f%%", c.QuoteMaxShortfall*100)},
+			{"Swap simulation", c.swapSimulationSummary()},
+			{"Rent reclamation", c.rentReclaimSummary()},
+		}},
+		{Title: "Fees", Settings: []Setting{
+			{"Service fee", feeSummary(c.ServiceFeeBps, c.ServiceFeeWallet)},
+			{"Jupiter platform fee", feeSummary(c.JupiterPlatformFeeBps, c.JupiterFeeAccount)},
+		}},
+		{Title: "Endpoints", Settings: []Setting{
+			{"Solana RPC", hostOf(c.SolanaRpcURL)},
+			{"Boop API", hostOf(c.Endpoints.GraphQLURL)},
+			{"Privy API", hostOf(c.Endpoints.PrivyAPIURL)},
+			{"Proxies", c.Network.summary()},
+			{"SOL price source", c.SolPriceSource},
+			{"Data directory", c.DataDir},
+			{"Airdrop store", c.airdropStoreSummary()},
+			{"Stats backend", c.StatsBackend},
+			{"Account cache", durationOrOff(c.AccountCacheTTL)},
+			{"Retries", c.retrySummary()},
+			{"Rate limit slowdown", c.rateLimitSummary()},
+		}},
+		{Title: "Notifications", Settings: []Setting{
+			{"Telegram", c.telegramSummary()},
+			{"Alert email", c.alertEmailSummary()},
+			{"Webhooks", c.webhookSummary()},
+			{"Kill switch", c.killSwitchSummary()},
+			{"Upcoming airdrops", c.upcomingSummary()},
+			{"Whale alerts", c.whaleAlertSummary()},
+			{"Efficiency alert", c.efficiencyAlertSummary()},
+			{"Control API", c.controlAPISummary()},
+			{"Logging", fmt.Sprintf("%s, level %s", c.LogFormat, c.LogLevel)},
+			{"Log files", c.LogFiles.summary()},
+		}},
+	}
+}
+
+// FormatSummary renders the configuration summary as plain text lines
+func FormatSummary(sections []SummarySection) []string {
+	var lines []string
+	for _, section := range sections {
+		lines = append(lines, section.Title+":")
+		for _, setting := range section.Settings {
+			lines = append(lines, fmt.Sprintf("  %-24s %s", setting.Name+":", setting.Value))
+		}
+	}
+	return lines
+}
+
+func (c *Config) walletSummary() string {
+	if c.WalletAddress == "" {
+		return "not configured"
+	}
+	if c.WalletLabel == "" {
+		return c.WalletAddress
+	}
+	return fmt.Sprintf("%s (%s)", c.WalletLabel, c.WalletAddress)
+}
+
+// authMethod names how the wallet authenticates with Boop
+func (c *Config) authMethod() string {
+	if c.WalletPrivateKey != "" {
+		return "private key"
+	}
+	if c.PrivyRefreshToken != "" {
+		return "Privy tokens"
+	}
+	if c.AuthToken != "" {
+		return "auth token"
+	}
+	return "none"
+}
+
+func (c *Config) featureSummary() string {
+	active := c.ActiveFeatures()
+	if len(active) == 0 {
+		return "none"
+	}
+	names := make([]string, len(active))
+	for i, feature := range active {
+		names[i] = string(feature)
+	}
+	return strings.Join(names, ", ")
+}
+
+func (c *Config) airdropStoreSummary() string {
+	switch c.AirdropStore {
+	case "sqlite", "bolt":
+		return fmt.Sprintf("%s (%s)", c.AirdropStore, c.AirdropStoreFile())
+	case "json":
+		return fmt.Sprintf("json (%s, flushed every %s)", c.AirdropStoreFile(), c.AirdropStoreFlush)
+	}
+	return c.AirdropStore
+}
+
+func (c *Config) retrySummary() string {
+	if c.RetryMaxAttempts <= 1 {
+		return "off"
+	}
+	if c.RetryBudget <= 0 {
+		return fmt.Sprintf("up to %d attempts", c.RetryMaxAttempts)
+	}
+	return fmt.Sprintf("up to %d attempts, %d retries per minute", c.RetryMaxAttempts, c.RetryBudget)
+}
+
+func (c *Config) rateLimitSummary() string {
+	if c.RateLimitCooldown <= 0 {
+		return "off"
+	}
+	summary := fmt.Sprintf("%s cooldown", c.RateLimitCooldown)
+	if c.RateLimitAlertAfter > 0 {
+		summary += fmt.Sprintf(", alert after %s", c.RateLimitAlertAfter)
+	}
+	return summary
+}
+
+func (c *Config) shadowSummary() string {
+	if c.ShadowMinimumUsd <= 0 {
+		return "off"
+	}
+	return fmt.Sprintf("$%.2f threshold, $%.2f stable after %s", c.ShadowMinimumUsd, c.ShadowStableMinimumUsd, c.ShadowStableDuration)
+}
+
+func (c *Config) congestionSummary() string {
+	if !c.CongestionScheduling {
+		return "off"
+	}
+	return fmt.Sprintf("%gx fee baseline, urgent from $%.2f, deferred at most %s", c.CongestionMultiplier, c.CongestionUrgentUsd, c.CongestionMaxDefer)
+}
+
+func (c *Config) profitPrecheckSummary() string {
+	if !c.ProfitPrecheck {
+		return "off"
+	}
+	return fmt.Sprintf("claims must net at least %g SOL after fees", c.ClaimMinNetProfit)
+}
+
+// accumulationSummary lists the tokens whose drops are claimed together and the value they wait for
+func (c *Config) accumulationSummary() string {
+	if len(c.AccumulationRules) == 0 {
+		return "none"
+	}
+
+	mints := make([]string, 0, len(c.AccumulationRules))
+	for mint := range c.AccumulationRules {
+		mints = append(mints, mint)
+	}
+	sort.Strings(mints)
+
+	rules := make([]string, 0, len(mints))
+	for _, mint := range mints {
+		rule := c.AccumulationRules[mint]
+		text := fmt.Sprintf("%s from $%.2f", ShortenAddress(mint), rule.MinUsd)
+		if rule.Cooldown > 0 {
+			text += ", every " + rule.Cooldown.String()
+		}
+		rules = append(rules, text)
+	}
+	return strings.Join(rules, "; ")
+}
+
+// routeHintSummary lists the tokens whose sales are restricted to trusted routes and the restrictions
+func (c *Config) routeHintSummary() string {
+	if len(c.RouteHints) == 0 {
+		return "none"
+	}
+
+	mints := make([]string, 0, len(c.RouteHints))
+	for mint := range c.RouteHints {
+		mints = append(mints, mint)
+	}
+	sort.Strings(mints)
+
+	hints := make([]string, 0, len(mints))
+	for _, mint := range mints {
+		hint := c.RouteHints[mint]
+		var rules []string
+		if len(hint.Dexes) > 0 {
+			rules = append(rules, "via "+strings.Join(hint.Dexes, " or "))
+		}
+		if hint.AmmKey != "" {
+			rules = append(rules, "pool "+ShortenAddress(hint.AmmKey))
+		}
+		if hint.OnlyDirectRoutes {
+			rules = append(rules, "direct")
+		}
+		if hint.MaxHops > 0 {
+			rules = append(rules, fmt.Sprintf("at most %d hop(s)", hint.MaxHops))
+		}
+		if len(rules) == 0 {
+			rules = append(rules, "any route")
+		}
+		hints = append(hints, ShortenAddress(mint)+" "+strings.Join(rules, ", "))
+	}
+	return strings.Join(hints, "; ")
+}
+
+func (c *Config) claimDelaySummary() string {
+	if c.ClaimDelayMax <= 0 {
+		return "off"
+	}
+	return fmt.Sprintf("random %s to %s below $%.2f", c.ClaimDelayMin, c.ClaimDelayMax, c.ClaimDelayUrgentUsd)
+}
+
+func (c *Config) safetySummary() string {
+	if c.SafetyMode == "" || c.SafetyMode == "off" {
+		return "off"
+	}
+	text := fmt.Sprintf("%s tokens failing screening, rechecked every %s", c.SafetyMode, c.SafetyCheckTTL)
+	if c.SafetyMaxTopHolder > 0 {
+		text += fmt.Sprintf(", top holder up to %.0f%%", c.SafetyMaxTopHolder*100)
+	}
+	return text
+}
+
+// claimSummary describes how many airdrops are claimed at once and how claims are paced
+func (c *Config) claimSummary() string {
+	return fmt.Sprintf("%d at a time, %s apart per worker", max(c.ClaimConcurrency, 1), c.ClaimPacing)
+}
+
+func (c *Config) sellSummary() string {
+	switch {
+	case c.ReceivingWallet != "":
+		return "off (tokens go to the receiving wallet)"
+	case !c.AutoSell:
+		return "off"
+	case c.SellDelay > 0:
+		return fmt.Sprintf("to %s after %s", c.SellTarget, c.SellDelay)
+	default:
+		return "to " + c.SellTarget
+	}
+}
+
+func (c *Config) keepSummary() string {
+	if len(c.KeepTokens) == 0 {
+		return "none"
+	}
+	summary := fmt.Sprintf("%d token(s)", len(c.KeepTokens))
+	if c.VaultWallet != "" {
+		summary += fmt.Sprintf(", moved to vault %s every %s", ShortenAddress(c.VaultWallet), c.VaultTransferInterval)
+	}
+	return summary
+}
+
+func (c *Config) rentReclaimSummary() string {
+	if !c.ReclaimRent {
+		return "off"
+	}
+	return "empty token accounts closed every " + c.RentReclaimInterval.String()
+}
+
+func (c *Config) stableSummary() string {
+	return fmt.Sprintf("claim above $%.2f after %s stable, sell from $%.2f, logged after %s",
+		c.StableMinimumUsd, c.StableDuration, c.StableSellMinimumUsd, c.StableTrackingAfter)
+}
+
+func (c *Config) priceHistorySummary() string {
+	if c.PriceHistorySamples <= 0 {
+		return "off"
+	}
+	summary := fmt.Sprintf("latest %d prices per airdrop", c.PriceHistorySamples)
+	if c.SellIntoStrength {
+		summary += ", selling into strength"
+	}
+	return summary
+}
+
+func (c *Config) approvalSummary() string {
+	if !c.ApprovalMode {
+		return "off"
+	}
+	return fmt.Sprintf("on Telegram, skipped after %s", c.ApprovalTimeout)
+}
+
+func (c *Config) swapSimulationSummary() string {
+	if !c.SimulateSwaps {
+		return "off"
+	}
+	return fmt.Sprintf("on, %.0f%% below the quote tolerated", c.SimulationTolerance*100)
+}
+
+func (c *Config) positionCapSummary() string {
+	if c.PositionCapUsd <= 0 {
+		return "off"
+	}
+	action := "alert"
+	if c.PositionCapSell {
+		action = "sell down"
+	}
+	return fmt.Sprintf("$%.2f per token, %s, checked every %s", c.PositionCapUsd, action, c.PositionCheckInterval)
+}
+
+func (c *Config) slippageSummary() string {
+	if !c.SlippageAutoTune {
+		return "fixed"
+	}
+	return fmt.Sprintf("auto-tuned between %d and %d bps", c.SlippageMinBps, c.SlippageMaxBps)
+}
+
+func (c *Config) sellFailureSummary() string {
+	if c.SellFailureLimit <= 0 {
+		return "retried every cycle"
+	}
+	return fmt.Sprintf("paused for %s after %d failures in a row", c.SellFailureCooldown, c.SellFailureLimit)
+}
+
+func (c *Config) telegramSummary() string {
+	if !c.EnableTelegram {
+		return "off"
+	}
+	if c.TelegramBotToken == "" || c.TelegramChatID == "" {
+		return "on, but the bot token or chat ID is missing"
+	}
+	summary := fmt.Sprintf("on, low priority batched every %s", c.NotificationBatch)
+	if c.TelegramFallbackURL != "" {
+		summary += fmt.Sprintf(", falls back to %s after %s", hostOf(c.TelegramFallbackURL), c.TelegramFallbackAfter)
+	}
+	return summary
+}
+
+func (c *Config) alertEmailSummary() string {
+	if c.SmtpURL == "" || len(c.AlertEmailTo) == 0 {
+		return "off"
+	}
+	return fmt.Sprintf("%s through %s", strings.Join(c.AlertEmailTo, ", "), hostOf(c.SmtpURL))
+}
+
+func (c *Config) webhookSummary() string {
+	if len(c.WebhookURLs) == 0 {
+		return "none"
+	}
+	hosts := make([]string, len(c.WebhookURLs))
+	for i, webhook := range c.WebhookURLs {
+		hosts[i] = hostOf(webhook)
+	}
+	summary := strings.Join(hosts, ", ")
+	if c.WebhookSecret == "" {
+		summary += " (unsigned)"
+	}
+	return summary
+}
+
+func (c *Config) killSwitchSummary() string {
+	parts := []string{"file " + c.KillSwitchFile}
+	if c.KillSwitchURL != "" {
+		parts = append(parts, "remote "+hostOf(c.KillSwitchURL))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (c *Config) upcomingSummary() string {
+	if !c.WatchUpcomingAirdrops {
+		return "off"
+	}
+	return "checked every " + c.UpcomingCheckInterval.String()
+}
+
+func (c *Config) whaleAlertSummary() string {
+	if c.WhaleAlertUsd <= 0 {
+		return "off"
+	}
+	summary := fmt.Sprintf("above $%.2f", c.WhaleAlertUsd)
+	if c.WhaleHold > 0 {
+		summary += ", held for " + c.WhaleHold.String()
+	}
+	if c.PushoverToken != "" && c.PushoverUser != "" {
+		summary += fmt.Sprintf(", Pushover priority %d", c.PushoverPriority)
+	}
+	return summary
+}
+
+func (c *Config) efficiencyAlertSummary() string {
+	if c.EfficiencyAlertRatio <= 0 {
+		return "off"
+	}
+	return fmt.Sprintf("fees above %.0f%% of proceeds", c.EfficiencyAlertRatio*100)
+}
+
+func (c *Config) controlAPISummary() string {
+	if c.ControlAPIAddr == "" {
+		return "off"
+	}
+	if c.ControlAPIToken == "" {
+		return c.ControlAPIAddr + ", no token"
+	}
+	return c.ControlAPIAddr + ", token required"
+}
+
+// feeSummary describes a fee in basis points and the account receiving it
+func feeSummary(bps int, recipient string) string {
+	if bps <= 0 || recipient == "" {
+		return "off"
+	}
+	return fmt.Sprintf("%.2f%% to %s", float64(bps)/100, ShortenAddress(recipient))
+}
+
+// hostOf reduces a URL to its scheme and host, dropping credentials, path and query
+func hostOf(rawURL string) string {
+	if rawURL == "" {
+		return "none"
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return "(invalid URL)"
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+func addressOrNone(address string) string {
+	if address == "" {
+		return "none"
+	}
+	return ShortenAddress(address)
+}
+
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
+
+// durationOrOff formats a duration, "off" when it is zero
+func durationOrOff(d time.Duration) string {
+	if d <= 0 {
+		return "off"
+	}
+	return d.String()
+}
@@ -2,17 +2,18 @@ package config
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
 	"time"
-)
 
-const (
-	graphqlEndpoint = "https://graphql-mainnet.boop.works/graphql"
-	privyEndpoint   = "https://auth.privy.io/api/v1/sessions"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/network"
+	"boop-airdrop-redeemer/pkg/redact"
+	"boop-airdrop-redeemer/pkg/retry"
 )
 
 // PrivyConfig holds Privy authentication tokens
@@ -48,12 +49,27 @@ type PrivyTokenResponse struct {
 // TokenManager handles refreshing authentication tokens
 type TokenManager struct {
 	privyConfig  PrivyConfig
+	endpoints    Endpoints
 	graphqlToken string
 	logger       *log.Logger
 }
 
+// authRequestAttempts is how often login and Privy requests are tried when rate limited or failing
+const authRequestAttempts = 3
+
+// newAuthHTTPClient returns the client of login and Privy requests to service, retrying rate limits and failed
+// connections within its timeout. Server errors are not retried, a refresh may have used up the refresh token.
+func newAuthHTTPClient(service string) *http.Client {
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: retry.NewTransport(network.Transport(service), retry.NewDefault(authRequestAttempts, 0, nil)),
+	}
+}
+
 // NewTokenManager creates a new token manager
-func NewTokenManager(privyAuth string, privyToken string, privyRefreshToken string, logger *log.Logger) *TokenManager {
+func NewTokenManager(privyAuth string, privyToken string, privyRefreshToken string, endpoints Endpoints, logger *log.Logger) *TokenManager {
+	redact.AddSecret(privyAuth, privyToken, privyRefreshToken)
+
 	return &TokenManager{
 		privyConfig: PrivyConfig{
 			Authentication: privyAuth,
@@ -63,27 +79,28 @@ func NewTokenManager(privyAuth string, privyToken string, privyRefreshToken stri
 			PrivyClientID:  DefaultPrivyConfig.ClientID,
 			PrivyClient:    DefaultPrivyConfig.Client,
 		},
-		logger: logger,
+		endpoints: endpoints,
+		logger:    logger,
 	}
 }
 
 // NewTokenManagerWithPrivateKey creates a new token manager using a wallet private key
-func NewTokenManagerWithPrivateKey(privateKey string, logger *log.Logger) (*TokenManager, error) {
+func NewTokenManagerWithPrivateKey(privateKey string, endpoints Endpoints, logger *log.Logger) (*TokenManager, error) {
 	if logger == nil {
-		logger = log.New(log.Writer(), "[TOKEN_MANAGER] ", log.LstdFlags)
+		logger = logging.For("token-manager")
 	}
 
 	// Get Privy tokens using the private key
-	privyAuth, privyToken, privyRefreshToken, err := GetPrivyTokensWithPrivateKey(privateKey, logger)
+	privyAuth, privyToken, privyRefreshToken, err := GetPrivyTokensWithPrivateKey(privateKey, endpoints, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to authenticate with private key: %w", err)
 	}
 
 	// Create token manager with obtained tokens
-	tm := NewTokenManager(privyAuth, privyToken, privyRefreshToken, logger)
+	tm := NewTokenManager(privyAuth, privyToken, privyRefreshToken, endpoints, logger)
 
 	// Immediately try to get GraphQL token
-	err = tm.refreshGraphQLToken()
+	err = tm.refreshGraphQLToken(context.Background())
 	if err != nil {
 		return nil, fmt.Errorf("failed to obtain GraphQL token: %w", err)
 	}
@@ -93,21 +110,31 @@ func NewTokenManagerWithPrivateKey(privateKey string, logger *log.Logger) (*Toke
 
 // RefreshToken refreshes the GraphQL authentication token using Privy credentials
 func (tm *TokenManager) RefreshToken() error {
+	return tm.RefreshTokenWithContext(context.Background())
+}
+
+// RefreshTokenWithContext refreshes the GraphQL authentication token, aborting in-flight requests when ctx is cancelled
+func (tm *TokenManager) RefreshTokenWithContext(ctx context.Context) error {
 	tm.logger.Println("Refreshing GraphQL authentication token...")
 
 	// Try to get GraphQL token with current Privy tokens
-	err := tm.refreshGraphQLToken()
+	err := tm.refreshGraphQLToken(ctx)
 	if err != nil {
-		tm.logger.Printf("Failed to refresh GraphQL token: %v. Trying to refresh Privy tokens...", err)
+		// Don't fall through to a Privy refresh during shutdown
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		logging.Errorf(tm.logger, "Failed to refresh GraphQL token: %v. Trying to refresh Privy tokens...", err)
 
 		// If GraphQL refresh fails, try refreshing Privy tokens first
-		err = tm.refreshPrivyTokens()
+		err = tm.refreshPrivyTokens(ctx)
 		if err != nil {
 			return fmt.Errorf("failed to refresh Privy tokens: %w", err)
 		}
 
 		// Try GraphQL refresh again with new Privy tokens
-		err = tm.refreshGraphQLToken()
+		err = tm.refreshGraphQLToken(ctx)
 		if err != nil {
 			return fmt.Errorf("failed to refresh GraphQL token with new Privy tokens: %w", err)
 		}
@@ -117,7 +144,7 @@ func (tm *TokenManager) RefreshToken() error {
 }
 
 // refreshGraphQLToken refreshes just the GraphQL token using current Privy tokens
-func (tm *TokenManager) refreshGraphQLToken() error {
+func (tm *TokenManager) refreshGraphQLToken(ctx context.Context) error {
 	// Prepare GraphQL query for the loginWithPrivy mutation
 	query := `
     mutation LoginWithPrivy {
@@ -139,7 +166,7 @@ func (tm *TokenManager) refreshGraphQLToken() error {
 	}
 
 	// Create HTTP request
-	req, err := http.NewRequest("POST", graphqlEndpoint, bytes.NewBuffer(payloadBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", tm.endpoints.GraphQLURL, bytes.NewBuffer(payloadBytes))
 	if err != nil {
 		return fmt.Errorf("failed to create login request: %w", err)
 	}
@@ -148,10 +175,10 @@ func (tm *TokenManager) refreshGraphQLToken() error {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("privy-authentication", tm.privyConfig.Authentication)
 	req.Header.Set("privy-token", tm.privyConfig.Token)
-	req.Header.Set("Origin", "https://boop.fun")
+	tm.endpoints.SetOriginHeaders(req)
 
 	// Send the request
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := newAuthHTTPClient(network.Boop)
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send login request: %w", err)
@@ -178,13 +205,14 @@ func (tm *TokenManager) refreshGraphQLToken() error {
 
 	// Update token
 	tm.graphqlToken = tokenResp.Data.LoginWithPrivy.Token
+	redact.AddSecret(tm.graphqlToken)
 
 	tm.logger.Println("Successfully refreshed GraphQL authentication token")
 	return nil
 }
 
 // refreshPrivyTokens refreshes the Privy authentication tokens
-func (tm *TokenManager) refreshPrivyTokens() error {
+func (tm *TokenManager) refreshPrivyTokens(ctx context.Context) error {
 	tm.logger.Println("Refreshing Privy authentication tokens...")
 
 	// Prepare request payload with refresh token
@@ -198,7 +226,7 @@ func (tm *TokenManager) refreshPrivyTokens() error {
 	}
 
 	// Create HTTP request
-	req, err := http.NewRequest("POST", privyEndpoint, bytes.NewBuffer(payloadBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", tm.endpoints.PrivyURL("/sessions"), bytes.NewBuffer(payloadBytes))
 	if err != nil {
 		return fmt.Errorf("failed to create Privy refresh request: %w", err)
 	}
@@ -214,11 +242,10 @@ func (tm *TokenManager) refreshPrivyTokens() error {
 	req.Header.Set("privy-app-id", tm.privyConfig.PrivyAppID)
 	req.Header.Set("privy-ca-id", tm.privyConfig.PrivyClientID)
 	req.Header.Set("privy-client", tm.privyConfig.PrivyClient)
-	req.Header.Set("referer", "https://boop.fun/")
-	req.Header.Set("Origin", "https://boop.fun")
+	tm.endpoints.SetOriginHeaders(req)
 
 	// Send the request
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := newAuthHTTPClient(network.Privy)
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send Privy refresh request: %w", err)
@@ -248,6 +275,7 @@ func (tm *TokenManager) refreshPrivyTokens() error {
 	if privyResp.RefreshToken != "" {
 		tm.privyConfig.RefreshToken = privyResp.RefreshToken
 	}
+	redact.AddSecret(privyResp.Token, privyResp.PrivyAccessToken, privyResp.IdentityToken, privyResp.RefreshToken)
 
 	tm.logger.Println("Successfully refreshed Privy authentication tokens")
 	return nil
@@ -257,9 +285,9 @@ func (tm *TokenManager) refreshPrivyTokens() error {
 func (tm *TokenManager) GetAuthorizationHeader() string {
 	// Ensure we have a token
 	if tm.graphqlToken == "" {
-		tm.logger.Println("WARNING: No GraphQL token available, attempting to refresh")
+		logging.Warnf(tm.logger, "WARNING: No GraphQL token available, attempting to refresh")
 		if err := tm.RefreshToken(); err != nil {
-			tm.logger.Printf("ERROR: Failed to refresh token: %v", err)
+			logging.Errorf(tm.logger, "ERROR: Failed to refresh token: %v", err)
 			return ""
 		}
 	}
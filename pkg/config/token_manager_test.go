@@ -0,0 +1,56 @@
+package config
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// waitForGoroutines waits until the goroutine count drops back to the baseline
+func waitForGoroutines(baseline int) int {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if n := runtime.NumGoroutine(); n <= baseline {
+			return n
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return runtime.NumGoroutine()
+}
+
+func TestTokenManagerRefreshStopsOnContextCancel(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	received := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body) // Lets the server notice the client hanging up
+		received <- struct{}{}
+		<-r.Context().Done() // Never answers, like an API hanging during shutdown
+	}))
+	endpoints := Endpoints{GraphQLURL: server.URL, PrivyAPIURL: server.URL}
+	tm := NewTokenManager("auth", "token", "refresh", endpoints, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- tm.RefreshTokenWithContext(ctx) }()
+
+	<-received
+	cancel()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled, "the refresh gives up without trying Privy")
+	case <-time.After(2 * time.Second):
+		t.Fatal("token refresh did not stop on context cancel")
+	}
+	assert.Len(t, received, 0, "no Privy refresh is attempted during shutdown")
+
+	server.Close()
+	assert.LessOrEqual(t, waitForGoroutines(baseline), baseline, "token refresh leaked after context cancel")
+}
@@ -0,0 +1,186 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/models"
+	sol "boop-airdrop-redeemer/pkg/solana"
+)
+
+// EntryType represents the kind of journal entry
+type EntryType string
+
+const (
+	// EntryClaimIntent is written before a claim transaction is sent
+	EntryClaimIntent EntryType = "CLAIM_INTENT"
+	// EntryClaimOutcome is written once a claim transaction was sent or failed
+	EntryClaimOutcome EntryType = "CLAIM_OUTCOME"
+	// EntrySellIntent is written before a swap transaction is sent
+	EntrySellIntent EntryType = "SELL_INTENT"
+	// EntrySellOutcome is written once a swap transaction was sent or failed
+	EntrySellOutcome EntryType = "SELL_OUTCOME"
+)
+
+// Entry is a single line in the journal
+type Entry struct {
+	Timestamp        time.Time           `json:"timestamp"`
+	Type             EntryType           `json:"type"`
+	AirdropID        string              `json:"airdropId"`
+	Airdrop          *models.AirdropNode `json:"airdrop,omitempty"` // Snapshot written with intents
+	TokenSymbol      string              `json:"tokenSymbol"`
+	TokenMint        string              `json:"tokenMint"`
+	Amount           string              `json:"amount"`
+	TxHash           string              `json:"txHash,omitempty"`
+	Success          bool                `json:"success"`
+	Error            string              `json:"error,omitempty"`
+	FeesLamports     uint64              `json:"feesLamports,omitempty"`
+	RentLamports     int64               `json:"rentLamports,omitempty"`
+	EarningsLamports uint64              `json:"earningsLamports,omitempty"`
+}
+
+// Journal is an append-only JSON lines log of operation intents and outcomes
+type Journal struct {
+	path string
+	file *os.File
+	mu   sync.Mutex
+}
+
+// NewJournal opens (or creates) the journal file at the given path
+func NewJournal(path string) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal file: %w", err)
+	}
+
+	return &Journal{
+		path: path,
+		file: file,
+	}, nil
+}
+
+// Append writes an entry to the journal and syncs it to disk.
+// A nil journal silently ignores writes so callers don't need to check if journaling is enabled.
+func (j *Journal) Append(entry Entry) error {
+	if j == nil {
+		return nil
+	}
+
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+
+	return j.file.Sync()
+}
+
+// RecordIntent appends an intent entry for the given airdrop
+func (j *Journal) RecordIntent(entryType EntryType, airdrop models.AirdropNode) error {
+	return j.Append(Entry{
+		Type:        entryType,
+		AirdropID:   airdrop.ID,
+		Airdrop:     &airdrop,
+		TokenSymbol: airdrop.Token.Symbol,
+		TokenMint:   airdrop.Token.Address,
+		Amount:      airdrop.AmountLpt.String(),
+	})
+}
+
+// RecordOutcome appends an outcome entry for the given airdrop
+func (j *Journal) RecordOutcome(entryType EntryType, airdrop models.AirdropNode, txHash string, opErr error) error {
+	entry := Entry{
+		Type:        entryType,
+		AirdropID:   airdrop.ID,
+		TokenSymbol: airdrop.Token.Symbol,
+		TokenMint:   airdrop.Token.Address,
+		Amount:      airdrop.AmountLpt.String(),
+		TxHash:      txHash,
+		Success:     opErr == nil,
+	}
+	if opErr != nil {
+		entry.Error = opErr.Error()
+	}
+
+	return j.Append(entry)
+}
+
+// RecordSettlement appends a successful outcome entry with the fees, rent and earnings read from chain once the
+// transaction is served, so a recovery without on-chain verification restores them too
+func (j *Journal) RecordSettlement(entryType EntryType, airdrop models.AirdropNode, txHash string, result sol.TransactionResult) error {
+	return j.Append(Entry{
+		Type:             entryType,
+		AirdropID:        airdrop.ID,
+		TokenSymbol:      airdrop.Token.Symbol,
+		TokenMint:        airdrop.Token.Address,
+		Amount:           airdrop.AmountLpt.String(),
+		TxHash:           txHash,
+		Success:          true,
+		FeesLamports:     result.Fee,
+		RentLamports:     result.Rent,
+		EarningsLamports: result.Earnings,
+	})
+}
+
+// Close closes the underlying journal file
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.file.Close()
+}
+
+// ReadEntries reads all entries from a journal file.
+// A truncated trailing line (e.g. from a crash mid-write) is skipped rather than treated as an error.
+func ReadEntries(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal file: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return entries, fmt.Errorf("failed to read journal file: %w", err)
+	}
+
+	return entries, nil
+}
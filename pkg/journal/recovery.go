@@ -0,0 +1,287 @@
+package journal
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"boop-airdrop-redeemer/pkg/models"
+	sol "boop-airdrop-redeemer/pkg/solana"
+)
+
+// OperationState is the reconstructed state of a single airdrop
+type OperationState struct {
+	AirdropID   string
+	Airdrop     *models.AirdropNode
+	TokenSymbol string
+	Amount      string
+
+	ClaimTx     string
+	ClaimedAt   time.Time
+	ClaimFees   uint64
+	ClaimRent   int64
+	ClaimFailed bool
+
+	SellTx       string
+	SoldAt       time.Time
+	SellFees     uint64
+	SellRent     int64
+	SellEarnings uint64
+	SellFailed   bool
+}
+
+// ReplayState holds all operations reconstructed from a journal
+type ReplayState struct {
+	Operations map[string]*OperationState
+}
+
+// SortedOperations returns operations ordered by claim time
+func (r *ReplayState) SortedOperations() []*OperationState {
+	ops := make([]*OperationState, 0, len(r.Operations))
+	for _, op := range r.Operations {
+		ops = append(ops, op)
+	}
+
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].ClaimedAt.Before(ops[j].ClaimedAt)
+	})
+	return ops
+}
+
+// Replay folds journal entries into the latest known state per airdrop
+func Replay(entries []Entry) *ReplayState {
+	state := &ReplayState{Operations: make(map[string]*OperationState)}
+
+	for _, entry := range entries {
+		op, exists := state.Operations[entry.AirdropID]
+		if !exists {
+			op = &OperationState{AirdropID: entry.AirdropID}
+			state.Operations[entry.AirdropID] = op
+		}
+
+		if entry.Airdrop != nil {
+			op.Airdrop = entry.Airdrop
+		}
+		if entry.TokenSymbol != "" {
+			op.TokenSymbol = entry.TokenSymbol
+		}
+		if entry.Amount != "" {
+			op.Amount = entry.Amount
+		}
+
+		switch entry.Type {
+		case EntryClaimOutcome:
+			if entry.Success && entry.TxHash != "" {
+				op.ClaimTx = entry.TxHash
+				op.ClaimedAt = entry.Timestamp
+				op.ClaimFees = entry.FeesLamports
+				op.ClaimRent = entry.RentLamports
+				op.ClaimFailed = false
+			} else if op.ClaimTx == "" {
+				op.ClaimFailed = true
+			}
+		case EntrySellOutcome:
+			if entry.Success && entry.TxHash != "" {
+				op.SellTx = entry.TxHash
+				op.SoldAt = entry.Timestamp
+				op.SellFees = entry.FeesLamports
+				op.SellRent = entry.RentLamports
+				op.SellEarnings = entry.EarningsLamports
+				op.SellFailed = false
+			} else if op.SellTx == "" {
+				op.SellFailed = true
+			}
+		}
+	}
+
+	return state
+}
+
+// airdropSaver is the subset of the airdrop store needed to restore state
+type airdropSaver interface {
+	SaveAirdrop(airdrop models.AirdropNode)
+	HasAirdropWithID(id string) bool
+}
+
+// RestoreStore saves every journaled airdrop missing from the store, marking claimed ones with their claim time
+// and tx hash. Airdrops the store still has are newer than their journal snapshot and left alone.
+func (r *ReplayState) RestoreStore(store airdropSaver) int {
+	restored := 0
+	for _, op := range r.Operations {
+		if op.Airdrop == nil || store.HasAirdropWithID(op.AirdropID) {
+			continue
+		}
+
+		airdrop := *op.Airdrop
+		if op.ClaimTx != "" {
+			airdrop.ClaimedAt = op.ClaimedAt.Format(time.RFC3339)
+			airdrop.TxHash = op.ClaimTx
+		}
+
+		store.SaveAirdrop(airdrop)
+		restored++
+	}
+	return restored
+}
+
+// RecoveryReport summarizes what Recover did
+type RecoveryReport struct {
+	ClaimsRestored int
+	SalesRestored  int
+	AlreadyPresent int
+	Unverified     int
+}
+
+// Recover rebuilds missing stats records from the replayed journal.
+// When solClient is set, each transaction is verified on chain and its fees/earnings are taken from the chain;
+// transactions that cannot be found are reported as unverified and skipped.
+func Recover(state *ReplayState, statsRecorder *sol.StatsRecorder, solClient *rpc.Client, logger *log.Logger) (RecoveryReport, error) {
+	report := RecoveryReport{}
+
+	existing, err := statsRecorder.GetTransactionHashes()
+	if err != nil {
+		return report, err
+	}
+
+	for _, op := range state.SortedOperations() {
+		for _, txType := range []sol.TransactionType{sol.TypeClaim, sol.TypeSwap} {
+			record, ok := op.statsRecord(txType)
+			if !ok {
+				continue
+			}
+
+			if existing[record.TxHash] {
+				report.AlreadyPresent++
+				continue
+			}
+
+			if solClient != nil {
+				result, err := sol.GetTransactionResult(solClient, record.TxHash, txType == sol.TypeSwap)
+				if err != nil {
+					logger.Printf("Could not verify %s transaction %s for airdrop %s: %v", txType, record.TxHash, op.AirdropID, err)
+					report.Unverified++
+					continue
+				}
+				record.Expenses = result.Fee
+				record.Rent = result.Rent
+				if txType == sol.TypeSwap {
+					record.GrossProfit = result.Earnings
+				}
+			}
+
+			if txType == sol.TypeSwap {
+				netProfit := int64(record.GrossProfit) - int64(record.Expenses) - record.Rent
+				if netProfit < 0 {
+					netProfit = 0
+				}
+				record.NetProfit = uint64(netProfit)
+			}
+
+			if err := statsRecorder.RecordTransaction(record); err != nil {
+				return report, err
+			}
+
+			if txType == sol.TypeClaim {
+				report.ClaimsRestored++
+			} else {
+				report.SalesRestored++
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// ClaimStore is where backfilled claims are recorded, the stats recorder
+type ClaimStore interface {
+	GetTransactionHashes() (map[string]bool, error)
+	RecordTransaction(stats sol.TransactionStats) error
+}
+
+// BackfillClaims records the claims of airdrops the API lists as claimed whose transaction is not in the
+// stats yet, such as claims made outside the bot. When solClient is set, fees and rent are read from the
+// chain and claims that cannot be found are reported as unverified and skipped. Without it they are recorded
+// as estimated, without fees, until the estimates are reconciled.
+func BackfillClaims(claimed []models.AirdropNode, statsRecorder ClaimStore, solClient sol.RPCClient, logger *log.Logger) (RecoveryReport, error) {
+	report := RecoveryReport{}
+
+	existing, err := statsRecorder.GetTransactionHashes()
+	if err != nil {
+		return report, err
+	}
+
+	for _, airdrop := range claimed {
+		txHash := airdrop.ClaimTx()
+		if txHash == "" {
+			continue
+		}
+		if existing[txHash] {
+			report.AlreadyPresent++
+			continue
+		}
+
+		// Claims without a usable time are recorded at the current time
+		claimedAt, _ := airdrop.ClaimTime()
+		record := sol.TransactionStats{
+			Timestamp:   claimedAt,
+			TokenSymbol: airdrop.Token.Symbol,
+			TokenAmount: airdrop.AmountLpt.String(),
+			TxHash:      txHash,
+			TxType:      sol.TypeClaim,
+			Estimated:   true,
+		}
+
+		if solClient != nil {
+			result, err := sol.GetTransactionResult(solClient, txHash, false)
+			if err != nil {
+				logger.Printf("Could not verify claim transaction %s for airdrop %s: %v", txHash, airdrop.ID, err)
+				report.Unverified++
+				continue
+			}
+			record.Expenses = result.Fee
+			record.Rent = result.Rent
+			record.Estimated = false
+		}
+
+		if err := statsRecorder.RecordTransaction(record); err != nil {
+			return report, err
+		}
+		existing[txHash] = true
+		report.ClaimsRestored++
+	}
+
+	return report, nil
+}
+
+// statsRecord builds the stats record for the claim or sale of this operation, if one was sent
+func (op *OperationState) statsRecord(txType sol.TransactionType) (sol.TransactionStats, bool) {
+	record := sol.TransactionStats{
+		TokenSymbol: op.TokenSymbol,
+		TokenAmount: op.Amount,
+		TxType:      txType,
+	}
+
+	switch txType {
+	case sol.TypeClaim:
+		if op.ClaimTx == "" {
+			return record, false
+		}
+		record.Timestamp = op.ClaimedAt
+		record.Expenses = op.ClaimFees
+		record.Rent = op.ClaimRent
+		record.TxHash = op.ClaimTx
+	case sol.TypeSwap:
+		if op.SellTx == "" {
+			return record, false
+		}
+		record.Timestamp = op.SoldAt
+		record.Expenses = op.SellFees
+		record.Rent = op.SellRent
+		record.GrossProfit = op.SellEarnings
+		record.TxHash = op.SellTx
+	}
+
+	return record, true
+}
@@ -0,0 +1,44 @@
+package journal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"boop-airdrop-redeemer/pkg/models"
+)
+
+// fakeStore keeps restored airdrops by ID
+type fakeStore map[string]models.AirdropNode
+
+func (s fakeStore) SaveAirdrop(airdrop models.AirdropNode) { s[airdrop.ID] = airdrop }
+func (s fakeStore) HasAirdropWithID(id string) bool        { _, ok := s[id]; return ok }
+
+func TestReplayRestoresSettledFeesAndStore(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	claimed := models.AirdropNode{ID: "claimed"}
+	pending := models.AirdropNode{ID: "pending"}
+	entries := []Entry{
+		{Timestamp: now, Type: EntryClaimIntent, AirdropID: "claimed", Airdrop: &claimed},
+		{Timestamp: now, Type: EntryClaimOutcome, AirdropID: "claimed", TxHash: "claim-tx", Success: true},
+		{Timestamp: now, Type: EntryClaimOutcome, AirdropID: "claimed", TxHash: "claim-tx", Success: true, FeesLamports: 5000, RentLamports: 2039280},
+		{Timestamp: now, Type: EntrySellOutcome, AirdropID: "claimed", TxHash: "sell-tx", Success: true},
+		{Timestamp: now, Type: EntrySellOutcome, AirdropID: "claimed", TxHash: "sell-tx", Success: true, FeesLamports: 7000, EarningsLamports: 90000000},
+		{Timestamp: now, Type: EntryClaimIntent, AirdropID: "pending", Airdrop: &pending},
+		{Timestamp: now, Type: EntryClaimIntent, AirdropID: "stored", Airdrop: &models.AirdropNode{ID: "stored", TxHash: "old"}},
+	}
+
+	state := Replay(entries)
+	op := state.Operations["claimed"]
+	assert.Equal(t, uint64(5000), op.ClaimFees)
+	assert.Equal(t, int64(2039280), op.ClaimRent)
+	assert.Equal(t, uint64(7000), op.SellFees)
+	assert.Equal(t, uint64(90000000), op.SellEarnings)
+
+	store := fakeStore{"stored": {ID: "stored", TxHash: "newer"}}
+	assert.Equal(t, 2, state.RestoreStore(store))
+	assert.Equal(t, "claim-tx", store["claimed"].TxHash)
+	assert.Nil(t, store["pending"].ClaimedAt)
+	assert.Equal(t, "newer", store["stored"].TxHash)
+}
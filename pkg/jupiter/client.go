@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -14,26 +15,79 @@ import (
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
+
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/network"
+	"boop-airdrop-redeemer/pkg/retry"
 )
 
+// ErrNoRoute is returned for quotes of tokens Jupiter cannot route or trade
+var ErrNoRoute = errors.New("no swap route")
+
+// noRouteErrorCodes are the error codes Jupiter answers 400 with for tokens it cannot route or trade, other
+// 400 answers such as an invalid amount are request errors
+var noRouteErrorCodes = map[string]bool{
+	"COULD_NOT_FIND_ANY_ROUTE": true,
+	"NO_ROUTES_FOUND":          true,
+	"TOKEN_NOT_TRADABLE":       true,
+}
+
+// isNoRouteError reports whether a Quote API error body is for a token that cannot be routed or traded
+func isNoRouteError(body []byte) bool {
+	var apiErr struct {
+		ErrorCode string `json:"errorCode"`
+	}
+	return json.Unmarshal(body, &apiErr) == nil && noRouteErrorCodes[apiErr.ErrorCode]
+}
+
 // Client represents a Jupiter API client
 type Client struct {
-	httpClient *http.Client
-	logger     *log.Logger
+	httpClient     *http.Client
+	feeAccount     string               // WSOL token account receiving the platform fee
+	platformFeeBps int                  // Platform fee charged on swaps to SOL, 0 charges none
+	prices         *PriceCache          // nil fetches every price request
+	routeHints     map[string]RouteHint // Restricted sale routes by input mint
+	logger         *log.Logger
 }
 
 // NewClient creates a new Jupiter API client
 func NewClient(logger *log.Logger) *Client {
 	return &Client{
 		httpClient: &http.Client{
-			Timeout: 15 * time.Second,
+			Timeout:   15 * time.Second,
+			Transport: network.Transport(network.Jupiter),
 		},
-		logger: logger,
+		logger: logging.Component(logger, "jupiter"),
 	}
 }
 
-// GetPrices fetches USD prices for given token mints
+// SetPlatformFee charges platformFeeBps on swaps to SOL, paid into feeAccount, a WSOL token account
+func (c *Client) SetPlatformFee(feeAccount string, platformFeeBps int) {
+	c.feeAccount = feeAccount
+	c.platformFeeBps = platformFeeBps
+}
+
+// SetRetrier retries requests failing with network, rate limit or server errors within each request's timeout.
+// Swap requests only build a transaction, so they are retried like quotes.
+func (c *Client) SetRetrier(retrier *retry.Retrier) {
+	c.httpClient.Transport = retry.NewIdempotentTransport(c.httpClient.Transport, retrier)
+}
+
+// SetPriceCache keeps fetched prices for ttl and coalesces concurrent price requests
+func (c *Client) SetPriceCache(ttl time.Duration) {
+	c.prices = NewPriceCache(ttl, c.fetchPrices)
+}
+
+// GetPrices fetches USD prices for given token mints, served from the price cache when it is enabled
 func (c *Client) GetPrices(tokenMints []string) (map[string]float64, error) {
+	if c.prices != nil {
+		return c.prices.Get(tokenMints)
+	}
+	return c.fetchPrices(tokenMints)
+}
+
+// fetchPrices calls the Price API for the given token mints
+func (c *Client) fetchPrices(tokenMints []string) (map[string]float64, error) {
 	if len(tokenMints) == 0 {
 		return make(map[string]float64), nil
 	}
@@ -65,9 +119,21 @@ func (c *Client) GetPrices(tokenMints []string) (map[string]float64, error) {
 
 // GetSwapQuote fetches a swap quote from Jupiter
 func (c *Client) GetSwapQuote(inputMint, outputMint string, amount uint64) (*QuoteResponse, error) {
-	url := fmt.Sprintf("%s?inputMint=%s&outputMint=%s&amount=%d&slippageBps=%d&onlyDirectRoutes=false",
-		JupiterQuoteAPI, inputMint, outputMint, amount, SlippageBps)
+	return c.GetSwapQuoteWithSlippage(inputMint, outputMint, amount, SlippageBps)
+}
 
+// GetSwapQuoteWithSlippage fetches a swap quote from Jupiter with the given slippage tolerance
+func (c *Client) GetSwapQuoteWithSlippage(inputMint, outputMint string, amount uint64, slippageBps int) (*QuoteResponse, error) {
+	hint, hinted := c.routeHints[inputMint]
+	url := fmt.Sprintf("%s?inputMint=%s&outputMint=%s&amount=%d&slippageBps=%d&onlyDirectRoutes=%t",
+		JupiterQuoteAPI, inputMint, outputMint, amount, slippageBps, hint.OnlyDirectRoutes)
+	url += hint.query()
+	if c.platformFeeBps > 0 && outputMint == WrappedSolMint {
+		// The fee is taken from the output, the fee account only holds WSOL
+		url += fmt.Sprintf("&platformFeeBps=%d", c.platformFeeBps)
+	}
+
+	requestedAt := time.Now()
 	resp, err := c.httpClient.Get(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call Jupiter Quote API: %w", err)
@@ -77,6 +143,9 @@ func (c *Client) GetSwapQuote(inputMint, outputMint string, amount uint64) (*Quo
 	if resp.StatusCode != http.StatusOK {
 		buf := new(bytes.Buffer)
 		buf.ReadFrom(resp.Body)
+		if resp.StatusCode == http.StatusBadRequest && isNoRouteError(buf.Bytes()) {
+			return nil, fmt.Errorf("%w: %s -> %s: %s", ErrNoRoute, inputMint, outputMint, buf.String())
+		}
 		return nil, fmt.Errorf("Jupiter Quote API returned non-OK status: %d - %s", resp.StatusCode, buf.String())
 	}
 
@@ -84,11 +153,17 @@ func (c *Client) GetSwapQuote(inputMint, outputMint string, amount uint64) (*Quo
 	if err := json.NewDecoder(resp.Body).Decode(&quoteResp); err != nil {
 		return nil, fmt.Errorf("failed to decode Jupiter Quote API response: %w", err)
 	}
+	quoteResp.FetchedAt = requestedAt
 
 	// Basic validation: Check if we got a valid quote (outAmount > 0)
 	outAmount, _ := strconv.ParseUint(quoteResp.OutAmount, 10, 64)
 	if outAmount == 0 {
-		return nil, fmt.Errorf("Jupiter Quote API returned a quote with 0 output amount for %s -> %s", inputMint, outputMint)
+		return nil, fmt.Errorf("%w: Jupiter Quote API returned a quote with 0 output amount for %s -> %s", ErrNoRoute, inputMint, outputMint)
+	}
+	if hinted {
+		if err := hint.check(&quoteResp); err != nil {
+			return nil, fmt.Errorf("%w: %s -> %s does not follow its route hint: %v", ErrNoRoute, inputMint, outputMint, err)
+		}
 	}
 
 	return &quoteResp, nil
@@ -101,42 +176,73 @@ func (c *Client) GetSwapTransaction(quote *QuoteResponse, userPubKey solana.Publ
 
 // GetSwapTransactionWithOptions gets a transaction for a swap with options
 func (c *Client) GetSwapTransactionWithOptions(quote *QuoteResponse, userPubKey solana.PublicKey, useSharedAccounts bool) (*SwapResponse, error) {
+	var swapResp SwapResponse
+	if err := c.postSwapRequest(JupiterSwapAPI, "Swap", c.newSwapRequest(quote, userPubKey, useSharedAccounts), &swapResp); err != nil {
+		return nil, err
+	}
+
+	if swapResp.SwapTransaction == "" {
+		return nil, fmt.Errorf("Jupiter Swap API returned empty transaction")
+	}
+
+	return &swapResp, nil
+}
+
+// GetSwapInstructions gets the instructions of a swap with the given quote, to compose it into another transaction
+func (c *Client) GetSwapInstructions(quote *QuoteResponse, userPubKey solana.PublicKey, useSharedAccounts bool) (*SwapInstructionsResponse, error) {
+	var instructionsResp SwapInstructionsResponse
+	if err := c.postSwapRequest(JupiterSwapInstructionsAPI, "Swap Instructions", c.newSwapRequest(quote, userPubKey, useSharedAccounts), &instructionsResp); err != nil {
+		return nil, err
+	}
+
+	if instructionsResp.SwapInstruction.ProgramID == "" {
+		return nil, fmt.Errorf("Jupiter Swap Instructions API returned no swap instruction")
+	}
+
+	return &instructionsResp, nil
+}
+
+// newSwapRequest builds the swap request shared by the swap and swap instructions endpoints,
+// naming the fee account when the quote includes a platform fee
+func (c *Client) newSwapRequest(quote *QuoteResponse, userPubKey solana.PublicKey, useSharedAccounts bool) SwapRequest {
 	swapReq := SwapRequest{
 		UserPublicKey:                 userPubKey.String(),
 		QuoteResponse:                 *quote,
 		WrapAndUnwrapSol:              true,              // Automatically handle SOL wrapping/unwrapping if needed
 		UseSharedAccounts:             useSharedAccounts, // Use Jupiter's shared accounts (can be turned off)
 		AsLegacyTransaction:           false,             // Use Versioned Transactions by default
-		ComputeUnitPriceMicroLamports: 300000,            // Optional: Add priority fee here if desired
+		ComputeUnitPriceMicroLamports: SwapComputeUnitPrice,
 	}
+	if quote.PlatformFee != nil {
+		swapReq.FeeAccount = c.feeAccount
+	}
+	return swapReq
+}
 
+// postSwapRequest posts a swap request to a Jupiter swap endpoint and decodes the response into out
+func (c *Client) postSwapRequest(url, apiName string, swapReq SwapRequest, out interface{}) error {
 	jsonData, err := json.Marshal(swapReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal swap request: %w", err)
+		return fmt.Errorf("failed to marshal swap request: %w", err)
 	}
 
-	resp, err := c.httpClient.Post(JupiterSwapAPI, "application/json", bytes.NewBuffer(jsonData))
+	resp, err := c.httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to call Jupiter Swap API: %w", err)
+		return fmt.Errorf("failed to call Jupiter %s API: %w", apiName, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		buf := new(bytes.Buffer)
 		buf.ReadFrom(resp.Body)
-		return nil, fmt.Errorf("Jupiter Swap API returned non-OK status: %d - %s", resp.StatusCode, buf.String())
+		return fmt.Errorf("Jupiter %s API returned non-OK status: %d - %s", apiName, resp.StatusCode, buf.String())
 	}
 
-	var swapResp SwapResponse
-	if err := json.NewDecoder(resp.Body).Decode(&swapResp); err != nil {
-		return nil, fmt.Errorf("failed to decode Jupiter Swap API response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode Jupiter %s API response: %w", apiName, err)
 	}
 
-	if swapResp.SwapTransaction == "" {
-		return nil, fmt.Errorf("Jupiter Swap API returned empty transaction")
-	}
-
-	return &swapResp, nil
+	return nil
 }
 
 // SignAndSendTransaction signs and sends the swap transaction
@@ -0,0 +1,14 @@
+package jupiter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsNoRouteError(t *testing.T) {
+	assert.True(t, isNoRouteError([]byte(`{"error":"Could not find any route","errorCode":"COULD_NOT_FIND_ANY_ROUTE"}`)))
+	assert.True(t, isNoRouteError([]byte(`{"error":"The token is not tradable","errorCode":"TOKEN_NOT_TRADABLE"}`)))
+	assert.False(t, isNoRouteError([]byte(`{"error":"Invalid amount","errorCode":"INVALID_AMOUNT"}`)))
+	assert.False(t, isNoRouteError([]byte(`Bad Request`)))
+}
@@ -0,0 +1,100 @@
+package jupiter
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	addresslookuptable "github.com/gagliardetto/solana-go/programs/address-lookup-table"
+)
+
+// SwapInstructions is a swap ready to be appended to another transaction
+type SwapInstructions struct {
+	Instructions  []solana.Instruction                       // Setup, swap and cleanup instructions, without compute budget
+	AddressTables map[solana.PublicKey]solana.PublicKeySlice // Lookup tables the swap needs to fit in a transaction
+	QuotedOut     uint64                                     // Quoted output in lamports
+	PlatformFee   uint64                                     // Platform fee taken from the output in lamports
+}
+
+// SwapInstructionsForSol quotes selling amount tokens for SOL and returns the swap as instructions for owner.
+// The caller adds its own compute budget, so Jupiter's compute budget instructions are left out.
+func (s *SwapService) SwapInstructionsForSol(ctx context.Context, owner solana.PublicKey, inputMint string, amount uint64) (*SwapInstructions, error) {
+	quote, err := s.client.GetSwapQuoteWithSlippage(inputMint, WrappedSolMint, amount, s.slippage.SlippageBps(inputMint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get swap quote: %w", err)
+	}
+
+	resp, err := s.client.GetSwapInstructions(quote, owner, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get swap instructions: %w", err)
+	}
+
+	swap := &SwapInstructions{AddressTables: make(map[solana.PublicKey]solana.PublicKeySlice)}
+	swap.QuotedOut = uint64(quote.OutLamports())
+	swap.PlatformFee = quote.PlatformFeeAmount()
+
+	swap.Instructions, err = resp.Instructions()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, address := range resp.AddressLookupTableAddresses {
+		tableKey, err := solana.PublicKeyFromBase58(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address lookup table %s: %w", address, err)
+		}
+
+		table, err := addresslookuptable.GetAddressLookupTable(ctx, s.solClient, tableKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get address lookup table %s: %w", address, err)
+		}
+		swap.AddressTables[tableKey] = table.Addresses
+	}
+
+	return swap, nil
+}
+
+// Instructions decodes the setup, swap and cleanup instructions in execution order. Compute budget
+// instructions are left out.
+func (r *SwapInstructionsResponse) Instructions() ([]solana.Instruction, error) {
+	instructionData := append([]InstructionData{}, r.SetupInstructions...)
+	instructionData = append(instructionData, r.SwapInstruction)
+	if r.CleanupInstruction != nil {
+		instructionData = append(instructionData, *r.CleanupInstruction)
+	}
+
+	instructions := make([]solana.Instruction, 0, len(instructionData))
+	for _, data := range instructionData {
+		instruction, err := data.toInstruction()
+		if err != nil {
+			return nil, err
+		}
+		instructions = append(instructions, instruction)
+	}
+	return instructions, nil
+}
+
+// toInstruction decodes an instruction returned by the Jupiter Swap Instructions API
+func (d InstructionData) toInstruction() (solana.Instruction, error) {
+	programID, err := solana.PublicKeyFromBase58(d.ProgramID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid program id %s: %w", d.ProgramID, err)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(d.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode instruction data: %w", err)
+	}
+
+	accounts := make(solana.AccountMetaSlice, 0, len(d.Accounts))
+	for _, account := range d.Accounts {
+		key, err := solana.PublicKeyFromBase58(account.Pubkey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid account %s: %w", account.Pubkey, err)
+		}
+		accounts = append(accounts, solana.NewAccountMeta(key, account.IsWritable, account.IsSigner))
+	}
+
+	return solana.NewInstruction(programID, accounts, data), nil
+}
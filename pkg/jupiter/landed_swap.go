@@ -0,0 +1,120 @@
+package jupiter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"boop-airdrop-redeemer/pkg/logging"
+)
+
+// recentSignatureLimit is how many recent token account signatures are scanned for an already landed swap
+const recentSignatureLimit = 20
+
+// checkBeforeRetry makes sure a previous attempt did not already sell the tokens before a swap is retried.
+// It returns the signature of a landed swap when one is found, or an error when the tokens are gone
+// without a matching swap, in which case retrying would only fail.
+func (s *SwapService) checkBeforeRetry(ctx context.Context, owner solana.PublicKey, inputMint string, amount uint64, since time.Time) (solana.Signature, bool, error) {
+	mint, err := solana.PublicKeyFromBase58(inputMint)
+	if err != nil {
+		return solana.Signature{}, false, fmt.Errorf("invalid token mint: %w", err)
+	}
+
+	ata, _, err := solana.FindAssociatedTokenAddress(owner, mint)
+	if err != nil {
+		return solana.Signature{}, false, fmt.Errorf("failed to find associated token address: %w", err)
+	}
+
+	balance, err := s.tokenAccountBalance(ctx, ata)
+	if err != nil {
+		// Cannot tell, the landing check already ruled out the previous attempt
+		logging.Warnf(s.logger, "Warning: Failed to re-check %s balance before retry: %v", inputMint, err)
+		return solana.Signature{}, false, nil
+	}
+	if balance >= amount {
+		return solana.Signature{}, false, nil
+	}
+
+	sig, found, err := s.findLandedSwap(ctx, owner, ata, mint, amount, since)
+	if err != nil {
+		logging.Warnf(s.logger, "Warning: Failed to scan recent signatures of %s: %v", ata, err)
+	}
+	if found {
+		return sig, true, nil
+	}
+
+	return solana.Signature{}, false, fmt.Errorf("token balance %d is below the swap amount %d, tokens were already moved", balance, amount)
+}
+
+// tokenAccountBalance returns the raw balance of a token account, 0 when the account does not exist
+func (s *SwapService) tokenAccountBalance(ctx context.Context, account solana.PublicKey) (uint64, error) {
+	result, err := s.solClient.GetTokenAccountBalance(ctx, account, rpc.CommitmentConfirmed)
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find account") {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if result == nil || result.Value == nil {
+		return 0, nil
+	}
+	return strconv.ParseUint(result.Value.Amount, 10, 64)
+}
+
+// findLandedSwap scans recent successful transactions of the token account for one that
+// reduced the owner's balance of the mint by exactly the swap amount
+func (s *SwapService) findLandedSwap(ctx context.Context, owner, ata, mint solana.PublicKey, amount uint64, since time.Time) (solana.Signature, bool, error) {
+	limit := recentSignatureLimit
+	signatures, err := s.solClient.GetSignaturesForAddressWithOpts(ctx, ata, &rpc.GetSignaturesForAddressOpts{
+		Limit:      &limit,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return solana.Signature{}, false, err
+	}
+
+	maxVersion := uint64(0)
+	for _, signature := range signatures {
+		if signature.Err != nil {
+			continue
+		}
+		if signature.BlockTime != nil && signature.BlockTime.Time().Before(since) {
+			// Signatures are returned newest first
+			break
+		}
+
+		tx, err := s.solClient.GetTransaction(ctx, signature.Signature, &rpc.GetTransactionOpts{
+			Commitment:                     rpc.CommitmentConfirmed,
+			MaxSupportedTransactionVersion: &maxVersion,
+		})
+		if err != nil || tx == nil || tx.Meta == nil {
+			continue
+		}
+
+		pre := ownerTokenAmount(tx.Meta.PreTokenBalances, owner, mint)
+		post := ownerTokenAmount(tx.Meta.PostTokenBalances, owner, mint)
+		if pre >= post && pre-post == amount {
+			return signature.Signature, true, nil
+		}
+	}
+
+	return solana.Signature{}, false, nil
+}
+
+// ownerTokenAmount sums the raw amounts the owner holds of a mint in a transaction's token balances
+func ownerTokenAmount(balances []rpc.TokenBalance, owner, mint solana.PublicKey) uint64 {
+	var total uint64
+	for _, balance := range balances {
+		if balance.Owner == nil || !balance.Owner.Equals(owner) || !balance.Mint.Equals(mint) || balance.UiTokenAmount == nil {
+			continue
+		}
+		amount, _ := strconv.ParseUint(balance.UiTokenAmount.Amount, 10, 64)
+		total += amount
+	}
+	return total
+}
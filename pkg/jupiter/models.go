@@ -1,5 +1,12 @@
 package jupiter
 
+import (
+	"strconv"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/amount"
+)
+
 // PriceResponse represents the Jupiter Price API response
 type PriceResponse struct {
 	Data map[string]struct {
@@ -39,6 +46,28 @@ type QuoteResponse struct {
 	} `json:"routePlan"`
 	ContextSlot uint64  `json:"contextSlot"`
 	TimeTaken   float64 `json:"timeTaken"`
+
+	FetchedAt time.Time `json:"-"` // When the quote was requested
+}
+
+// PlatformFeeAmount returns the platform fee taken from the output, 0 when the quote has none
+func (q *QuoteResponse) PlatformFeeAmount() uint64 {
+	if q.PlatformFee == nil {
+		return 0
+	}
+	fee, _ := strconv.ParseUint(q.PlatformFee.Amount, 10, 64)
+	return fee
+}
+
+// OutTokens returns the quoted output in a mint with the given decimals, 0 when the quote has no valid amount
+func (q *QuoteResponse) OutTokens(decimals uint8) amount.TokenAmount {
+	out, _ := strconv.ParseUint(q.OutAmount, 10, 64)
+	return amount.TokenAmount{Raw: out, Decimals: decimals}
+}
+
+// OutLamports returns the quoted output of a sale for SOL
+func (q *QuoteResponse) OutLamports() amount.Lamports {
+	return amount.Lamports(q.OutTokens(amount.SolDecimals).Raw)
 }
 
 // SwapRequest represents the Jupiter Swap API request
@@ -59,6 +88,26 @@ type SwapResponse struct {
 	LastErrorTs     int64  `json:"lastErrorTs"`
 }
 
+// SwapInstructionsResponse represents the Jupiter Swap Instructions API response
+type SwapInstructionsResponse struct {
+	ComputeBudgetInstructions   []InstructionData `json:"computeBudgetInstructions"`
+	SetupInstructions           []InstructionData `json:"setupInstructions"`
+	SwapInstruction             InstructionData   `json:"swapInstruction"`
+	CleanupInstruction          *InstructionData  `json:"cleanupInstruction"`
+	AddressLookupTableAddresses []string          `json:"addressLookupTableAddresses"`
+}
+
+// InstructionData is an instruction returned by the Jupiter Swap Instructions API
+type InstructionData struct {
+	ProgramID string `json:"programId"`
+	Accounts  []struct {
+		Pubkey     string `json:"pubkey"`
+		IsSigner   bool   `json:"isSigner"`
+		IsWritable bool   `json:"isWritable"`
+	} `json:"accounts"`
+	Data string `json:"data"` // base64 encoded
+}
+
 // TokenBalance represents a token balance with its price information
 type TokenBalance struct {
 	Mint     string
@@ -0,0 +1,126 @@
+package jupiter
+
+import (
+	"sync"
+	"time"
+)
+
+// maxPriceIDs is how many mints are requested from the Price API at once
+const maxPriceIDs = 100
+
+// PriceCache keeps Jupiter prices for a short time and coalesces requests, so the scanner, the seller and the
+// position checks of one cycle share Price API calls instead of each fetching overlapping mint lists. Mints
+// requested while a fetch of them is in flight wait for its result instead of fetching again.
+type PriceCache struct {
+	ttl   time.Duration
+	fetch func(mints []string) (map[string]float64, error)
+	now   func() time.Time
+
+	mu       sync.Mutex
+	entries  map[string]cachedPrice
+	inflight map[string]chan struct{}
+}
+
+// cachedPrice is a fetched price, 0 when the Price API had none for the mint
+type cachedPrice struct {
+	price     float64
+	fetchedAt time.Time
+}
+
+// NewPriceCache creates a cache keeping prices fetched with fetch for ttl
+func NewPriceCache(ttl time.Duration, fetch func(mints []string) (map[string]float64, error)) *PriceCache {
+	return &PriceCache{
+		ttl:      ttl,
+		fetch:    fetch,
+		now:      time.Now,
+		entries:  make(map[string]cachedPrice),
+		inflight: make(map[string]chan struct{}),
+	}
+}
+
+// Get returns the prices of the mints, fetching those not cached in batches. Mints without a price are left
+// out of the result, like the Price API does.
+func (c *PriceCache) Get(mints []string) (map[string]float64, error) {
+	prices := make(map[string]float64, len(mints))
+
+	for {
+		var missing []string
+		var waits []chan struct{}
+
+		c.mu.Lock()
+		now := c.now()
+		for _, mint := range mints {
+			if _, seen := prices[mint]; seen {
+				continue
+			}
+			if entry, ok := c.entries[mint]; ok && now.Sub(entry.fetchedAt) < c.ttl {
+				prices[mint] = entry.price
+				continue
+			}
+			if wait, ok := c.inflight[mint]; ok {
+				waits = append(waits, wait)
+				continue
+			}
+			missing = append(missing, mint)
+		}
+		done := make(chan struct{})
+		for _, mint := range missing {
+			c.inflight[mint] = done
+		}
+		c.mu.Unlock()
+
+		if len(missing) == 0 && len(waits) == 0 {
+			break
+		}
+		if err := c.fetchMissing(missing, done); err != nil {
+			return nil, err
+		}
+
+		// The next round picks up the fetched prices, and fetches those whose other request failed
+		for _, wait := range waits {
+			<-wait
+		}
+	}
+
+	for mint, price := range prices {
+		if price <= 0 {
+			delete(prices, mint)
+		}
+	}
+	return prices, nil
+}
+
+// fetchMissing fetches the missing mints in batches and caches the results, releasing requests waiting on them
+func (c *PriceCache) fetchMissing(missing []string, done chan struct{}) error {
+	if len(missing) == 0 {
+		return nil
+	}
+
+	var err error
+	fetched := make(map[string]float64, len(missing))
+	for start := 0; start < len(missing) && err == nil; start += maxPriceIDs {
+		var batch map[string]float64
+		batch, err = c.fetch(missing[start:min(start+maxPriceIDs, len(missing))])
+		for mint, price := range batch {
+			fetched[mint] = price
+		}
+	}
+
+	c.mu.Lock()
+	now := c.now()
+	for mint, entry := range c.entries {
+		if now.Sub(entry.fetchedAt) >= c.ttl {
+			delete(c.entries, mint)
+		}
+	}
+	for _, mint := range missing {
+		delete(c.inflight, mint)
+		if err == nil {
+			c.entries[mint] = cachedPrice{price: fetched[mint], fetchedAt: now}
+		}
+	}
+	c.mu.Unlock()
+	close(done)
+
+	return err
+}
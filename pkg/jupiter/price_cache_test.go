@@ -0,0 +1,39 @@
+package jupiter
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceCacheCoalescesRequests(t *testing.T) {
+	var mu sync.Mutex
+	var requests [][]string
+	cache := NewPriceCache(30*time.Second, func(mints []string) (map[string]float64, error) {
+		mu.Lock()
+		requests = append(requests, mints)
+		mu.Unlock()
+		return map[string]float64{"a": 1, "b": 2}, nil
+	})
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	prices, err := cache.Get([]string{"a", "b", "none"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"a": 1, "b": 2}, prices, "mints without a price are left out")
+
+	// Cached mints, including those without a price, are not requested again
+	prices, err = cache.Get([]string{"b", "none"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"b": 2}, prices)
+	assert.Len(t, requests, 1)
+
+	// Only the expired prices and new mints are fetched, together
+	now = now.Add(time.Minute)
+	_, err = cache.Get([]string{"a", "c"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"a", "b", "none"}, {"a", "c"}}, requests)
+}
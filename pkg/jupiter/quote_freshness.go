@@ -0,0 +1,63 @@
+package jupiter
+
+import (
+	"context"
+	"time"
+)
+
+// maxRequotes is how often a stale quote is fetched again within one swap attempt before the attempt fails
+const maxRequotes = 3
+
+// Age returns how long ago the quote was requested, 0 for quotes not fetched by the client
+func (q *QuoteResponse) Age() time.Duration {
+	if q.FetchedAt.IsZero() {
+		return 0
+	}
+	return time.Since(q.FetchedAt)
+}
+
+// SetQuoteMaxAge re-quotes swaps whose quote is older than maxAge by the time the transaction is sent, 0 sends
+// quotes of any age
+func (s *SwapService) SetQuoteMaxAge(maxAge time.Duration) {
+	s.quoteMaxAge = maxAge
+}
+
+// quoteStale reports whether the quote is too old to send its swap
+func (s *SwapService) quoteStale(quote *QuoteResponse) bool {
+	return s.quoteMaxAge > 0 && quote.Age() > s.quoteMaxAge
+}
+
+// heldBy reports whether a fresh quote still pays at least what the quote did. The transaction built from the
+// quote can then be sent as it is, its minimum output still holds at the current price.
+func (q *QuoteResponse) heldBy(fresh *QuoteResponse) bool {
+	return fresh.OutTokens(0).Raw >= q.OutTokens(0).Raw
+}
+
+// QuoteTrace is filled in by a swap with the quote its transaction was sent with
+type QuoteTrace struct {
+	Age      time.Duration // Age of the quote when the last attempt was sent
+	Requotes int           // Quotes fetched again because they went stale, over all attempts
+}
+
+type quoteTraceKey struct{}
+
+// TraceQuote returns a context whose swaps report the age of their quote in the returned trace
+func TraceQuote(ctx context.Context) (context.Context, *QuoteTrace) {
+	trace := &QuoteTrace{}
+	return context.WithValue(ctx, quoteTraceKey{}, trace), trace
+}
+
+// quoteTraceFrom returns the trace of the context, nil when the caller did not ask for one
+func quoteTraceFrom(ctx context.Context) *QuoteTrace {
+	trace, _ := ctx.Value(quoteTraceKey{}).(*QuoteTrace)
+	return trace
+}
+
+// record stores the quote a swap attempt is sent with
+func (t *QuoteTrace) record(age time.Duration, requotes int) {
+	if t == nil {
+		return
+	}
+	t.Age = age
+	t.Requotes = requotes
+}
@@ -0,0 +1,31 @@
+package jupiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteFreshness(t *testing.T) {
+	s := &SwapService{}
+	fresh := &QuoteResponse{FetchedAt: time.Now()}
+	stale := &QuoteResponse{FetchedAt: time.Now().Add(-time.Minute)}
+
+	assert.False(t, s.quoteStale(stale))
+	s.SetQuoteMaxAge(10 * time.Second)
+	assert.False(t, s.quoteStale(fresh))
+	assert.True(t, s.quoteStale(stale))
+	assert.Zero(t, (&QuoteResponse{}).Age())
+
+	prepared := &QuoteResponse{OutAmount: "1000"}
+	assert.True(t, prepared.heldBy(&QuoteResponse{OutAmount: "1000"}))
+	assert.True(t, prepared.heldBy(&QuoteResponse{OutAmount: "1200"}))
+	assert.False(t, prepared.heldBy(&QuoteResponse{OutAmount: "999"}))
+
+	ctx, trace := TraceQuote(context.Background())
+	quoteTraceFrom(ctx).record(2*time.Second, 1)
+	assert.Equal(t, QuoteTrace{Age: 2 * time.Second, Requotes: 1}, *trace)
+	quoteTraceFrom(context.Background()).record(time.Second, 0)
+}
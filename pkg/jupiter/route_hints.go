@@ -0,0 +1,57 @@
+package jupiter
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// RouteHint restricts the routes quoted for selling a token to the ones its owner trusts
+type RouteHint struct {
+	Dexes            []string // DEX labels the route may use, any when empty
+	AmmKey           string   // Pool the route must go through, any when empty
+	OnlyDirectRoutes bool     // Swap straight to the output token without intermediate tokens
+	MaxHops          int      // Most swaps in a row, 0 for no limit
+}
+
+// SetRouteHints restricts the quotes for selling the tokens, by mint
+func (c *Client) SetRouteHints(hints map[string]RouteHint) {
+	c.routeHints = hints
+}
+
+// query returns the quote request parameters restricting the route, empty when Jupiter's defaults apply
+func (h RouteHint) query() string {
+	if len(h.Dexes) == 0 {
+		return ""
+	}
+	return "&dexes=" + url.QueryEscape(strings.Join(h.Dexes, ","))
+}
+
+// check returns an error when the quoted route does not follow the parts of the hint Jupiter cannot filter on
+func (h RouteHint) check(quote *QuoteResponse) error {
+	if h.AmmKey != "" {
+		found := false
+		for _, step := range quote.RoutePlan {
+			if step.SwapInfo.AmmKey == h.AmmKey {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("route does not use pool %s", h.AmmKey)
+		}
+	}
+	if h.MaxHops > 0 && quote.Hops() > h.MaxHops {
+		return fmt.Errorf("route has %d hops, at most %d allowed", quote.Hops(), h.MaxHops)
+	}
+	return nil
+}
+
+// Hops returns the number of swaps in a row of the quoted route, a step split across pools counts once
+func (q *QuoteResponse) Hops() int {
+	inputs := make(map[string]bool, len(q.RoutePlan))
+	for _, step := range q.RoutePlan {
+		inputs[step.SwapInfo.InputMint] = true
+	}
+	return len(inputs)
+}
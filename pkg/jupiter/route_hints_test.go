@@ -0,0 +1,26 @@
+package jupiter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteHintCheck(t *testing.T) {
+	var quote QuoteResponse
+	require.NoError(t, json.Unmarshal([]byte(`{"routePlan":[
+		{"swapInfo":{"ammKey":"pool-a","inputMint":"TOKEN","outputMint":"USDC"},"percent":100},
+		{"swapInfo":{"ammKey":"pool-b","inputMint":"USDC","outputMint":"SOL"},"percent":60},
+		{"swapInfo":{"ammKey":"pool-c","inputMint":"USDC","outputMint":"SOL"},"percent":40}
+	]}`), &quote))
+
+	assert.Equal(t, 2, quote.Hops())
+	assert.NoError(t, RouteHint{AmmKey: "pool-c", MaxHops: 2}.check(&quote))
+	assert.Error(t, RouteHint{AmmKey: "pool-d"}.check(&quote))
+	assert.Error(t, RouteHint{MaxHops: 1}.check(&quote))
+
+	assert.Equal(t, "&dexes=Raydium+CLMM%2CMeteora+DLMM", RouteHint{Dexes: []string{"Raydium CLMM", "Meteora DLMM"}}.query())
+	assert.Empty(t, RouteHint{OnlyDirectRoutes: true}.query())
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -13,6 +12,11 @@ import (
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 
+	"boop-airdrop-redeemer/pkg/amount"
+	"boop-airdrop-redeemer/pkg/keys"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/operation"
+	"boop-airdrop-redeemer/pkg/retry"
 	sln "boop-airdrop-redeemer/pkg/solana"
 )
 
@@ -20,11 +24,21 @@ import (
 type SwapService struct {
 	client    *Client
 	solClient *rpc.Client
+	slippage  *SlippageTuner // nil keeps the default slippage for every token
+	feeStats  PlatformFeeRecorder
 	logger    *log.Logger
+
+	// Swaps are simulated before sending when enabled, rejected when the output falls short of the quote
+	simulate            bool
+	simulationTolerance float64
+
+	rebroadcast time.Duration // Interval at which sent swaps are broadcast again, 0 sends them once
+	quoteMaxAge time.Duration // Oldest a quote may be when its swap is sent, 0 sends quotes of any age
 }
 
 // NewSwapService creates a new swap service
 func NewSwapService(solClient *rpc.Client, logger *log.Logger) *SwapService {
+	logger = logging.Component(logger, "jupiter")
 	return &SwapService{
 		client:    NewClient(logger),
 		solClient: solClient,
@@ -32,14 +46,62 @@ func NewSwapService(solClient *rpc.Client, logger *log.Logger) *SwapService {
 	}
 }
 
+// PlatformFeeRecorder records platform fees collected on swaps
+type PlatformFeeRecorder interface {
+	RecordPlatformFeeStats(tokenSymbol string, fee uint64, txHash, operationID string) error
+}
+
+// SetPlatformFee charges platformFeeBps on swaps to SOL into feeAccount, a WSOL token account.
+// Collected fees are recorded with recorder when it is not nil.
+func (s *SwapService) SetPlatformFee(feeAccount string, platformFeeBps int, recorder PlatformFeeRecorder) {
+	s.client.SetPlatformFee(feeAccount, platformFeeBps)
+	s.feeStats = recorder
+}
+
+// SetRetrier retries Jupiter API requests that fail with network, rate limit or server errors
+func (s *SwapService) SetRetrier(retrier *retry.Retrier) {
+	s.client.SetRetrier(retrier)
+}
+
+// SetPriceCache shares Price API results between callers for ttl
+func (s *SwapService) SetPriceCache(ttl time.Duration) {
+	s.client.SetPriceCache(ttl)
+}
+
+// PrefetchPrices fetches the prices of the mints in one request, so the price lookups that follow are served
+// from the cache
+func (s *SwapService) PrefetchPrices(mints []string) {
+	if s.client.prices == nil || len(mints) == 0 {
+		return
+	}
+	if _, err := s.client.GetPrices(mints); err != nil {
+		logging.Warnf(s.logger, "Warning: Failed to prefetch token prices: %v", err)
+	}
+}
+
+// SetRebroadcast broadcasts sent swaps again every interval until they land or expire
+func (s *SwapService) SetRebroadcast(interval time.Duration) {
+	s.rebroadcast = interval
+}
+
+// SetRouteHints restricts the routes of token sales to the trusted ones, by mint
+func (s *SwapService) SetRouteHints(hints map[string]RouteHint) {
+	s.client.SetRouteHints(hints)
+}
+
+// SetSlippageTuner enables per-token slippage tuning for swaps to SOL
+func (s *SwapService) SetSlippageTuner(tuner *SlippageTuner) {
+	s.slippage = tuner
+}
+
 // GetWallet retrieves the wallet from the private key
 func (s *SwapService) GetWallet(privateKeyBase58 string) (solana.PrivateKey, error) {
 	if privateKeyBase58 == "" {
 		return solana.PrivateKey{}, fmt.Errorf("private key not provided")
 	}
-	wallet, err := solana.PrivateKeyFromBase58(privateKeyBase58)
+	wallet, err := keys.Parse(privateKeyBase58)
 	if err != nil {
-		return solana.PrivateKey{}, fmt.Errorf("invalid private key: %w", err)
+		return solana.PrivateKey{}, err
 	}
 	return wallet, nil
 }
@@ -71,7 +133,7 @@ func (s *SwapService) GetTokenBalances(ctx context.Context, owner solana.PublicK
 	for _, account := range accounts.Value {
 		// We need to process the parsed JSON account data manually
 		if account.Account.Data.GetRawJSON() == nil {
-			s.logger.Printf("⚠️ Skipping account %s: not JSON parsed data", account.Pubkey)
+			logging.Warnf(s.logger, "⚠️ Skipping account %s: not JSON parsed data", account.Pubkey)
 			continue
 		}
 
@@ -80,7 +142,7 @@ func (s *SwapService) GetTokenBalances(ctx context.Context, owner solana.PublicK
 		var parsedData map[string]interface{}
 		err = json.Unmarshal(data, &parsedData)
 		if err != nil {
-			s.logger.Printf("⚠️ Failed to unmarshal account data: %v", err)
+			logging.Warnf(s.logger, "⚠️ Failed to unmarshal account data: %v", err)
 			continue
 		}
 		if parsedData["parsed"] == nil {
@@ -140,7 +202,7 @@ func (s *SwapService) GetTokenBalances(ctx context.Context, owner solana.PublicK
 	if len(mints) > 0 {
 		prices, err := s.client.GetPrices(mints)
 		if err != nil {
-			s.logger.Printf("Warning: failed to get token prices: %v", err)
+			logging.Warnf(s.logger, "Warning: failed to get token prices: %v", err)
 		} else {
 			// Update balances with price information
 			for mint, price := range prices {
@@ -161,46 +223,7 @@ func (s *SwapService) GetTokenBalances(ctx context.Context, owner solana.PublicK
 
 // SwapTokenForUsdc swaps a token for USDC
 func (s *SwapService) SwapTokenForUsdc(ctx context.Context, privateKeyBase58 string, inputMint string, amount uint64) (solana.Signature, error) {
-	// Get wallet
-	wallet, err := s.GetWallet(privateKeyBase58)
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to get wallet: %w", err)
-	}
-
-	// Get public key
-	pubKey := wallet.PublicKey()
-
-	// Step 1: Get quote
-	s.logger.Printf("Getting swap quote for %d units of %s -> USDC...", amount, inputMint)
-	quote, err := s.client.GetSwapQuote(inputMint, QuoteCurrencyMint, amount)
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to get swap quote: %w", err)
-	}
-
-	// Calculate output amount in USDC (with 6 decimals)
-	outAmountRaw, _ := strconv.ParseUint(quote.OutAmount, 10, 64)
-	outAmountFormatted := float64(outAmountRaw) / math.Pow10(6) // USDC has 6 decimals
-
-	s.logger.Printf("Got quote - Will receive: %.2f USDC", outAmountFormatted)
-
-	// Step 2: Get transaction
-	s.logger.Printf("Getting swap transaction...")
-	swapResp, err := s.client.GetSwapTransaction(quote, pubKey)
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to get swap transaction: %w", err)
-	}
-
-	// Step 3: Sign and send transaction
-	s.logger.Printf("Signing and sending transaction...")
-	sig, err := s.client.SignAndSendTransaction(ctx, s.solClient, swapResp.SwapTransaction, wallet)
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to sign and send transaction: %w", err)
-	}
-
-	s.logger.Printf("🎉 Swap transaction sent! Signature: %s", sig.String())
-	s.logger.Printf("View on Solscan: https://solscan.io/tx/%s", sig.String())
-
-	return sig, nil
+	return s.swapWithRetries(ctx, privateKeyBase58, inputMint, QuoteCurrencyMint, amount, 10, 3*time.Second)
 }
 
 // GetTokensToSell identifies tokens that meet the threshold for selling
@@ -229,6 +252,18 @@ func (s *SwapService) SwapTokenForSol(ctx context.Context, privateKeyBase58 stri
 
 // SwapTokenForSolWithRetries swaps a token for Wrapped SOL with retry mechanism
 func (s *SwapService) SwapTokenForSolWithRetries(ctx context.Context, privateKeyBase58 string, inputMint string, amount uint64, maxRetries int, retryDelay time.Duration) (solana.Signature, error) {
+	return s.swapWithRetries(ctx, privateKeyBase58, inputMint, WrappedSolMint, amount, maxRetries, retryDelay)
+}
+
+// swapWithRetries swaps a token for SOL or USDC, waiting for each attempt to land or expire before retrying
+func (s *SwapService) swapWithRetries(ctx context.Context, privateKeyBase58 string, inputMint string, outputMint string, amount uint64, maxRetries int, retryDelay time.Duration) (solana.Signature, error) {
+	logger := operation.Logger(ctx, s.logger)
+
+	outputName, outputDecimals := "SOL", uint8(9)
+	if outputMint == QuoteCurrencyMint {
+		outputName, outputDecimals = "USDC", 6
+	}
+
 	var (
 		lastErr           error
 		useSharedAccounts = true // Start with shared accounts
@@ -243,62 +278,63 @@ func (s *SwapService) SwapTokenForSolWithRetries(ctx context.Context, privateKey
 	// Get public key
 	pubKey := wallet.PublicKey()
 
+	// Allow for block time granularity when matching swaps landed by earlier attempts
+	startedAt := time.Now().Add(-time.Minute)
+	trace := quoteTraceFrom(ctx)
+	requotes := 0
+
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		// Step 1: Get quote
-		s.logger.Printf("Getting swap quote for %d units of %s -> SOL (attempt %d/%d)...",
-			amount, inputMint, attempt, maxRetries)
-		quote, err := s.client.GetSwapQuote(inputMint, WrappedSolMint, amount)
-		if err != nil {
-			lastErr = fmt.Errorf("failed to get swap quote: %w", err)
-			s.logger.Printf("Retry %d/%d: %v", attempt, maxRetries, lastErr)
-			time.Sleep(retryDelay)
-			continue
+		// A previous attempt may have landed after all, never sell the same tokens twice
+		if attempt > 1 {
+			landedSig, landed, err := s.checkBeforeRetry(ctx, pubKey, inputMint, amount, startedAt)
+			if err != nil {
+				return solana.Signature{}, fmt.Errorf("stopped retrying swap: %w", err)
+			}
+			if landed {
+				logger.Printf("Swap of %s already landed in %s, skipping retry", inputMint, landedSig)
+				return landedSig, nil
+			}
 		}
 
-		// Calculate output amount in SOL (with 9 decimals)
-		outAmountRaw, _ := strconv.ParseUint(quote.OutAmount, 10, 64)
-		outAmountFormatted := float64(outAmountRaw) / math.Pow10(9) // SOL has 9 decimals
-
-		s.logger.Printf("Got quote - Will receive: %.5f SOL", outAmountFormatted)
-
-		// Step 2: Get transaction
-		s.logger.Printf("Getting swap transaction...")
-		swapResp, err := s.client.GetSwapTransactionWithOptions(quote, pubKey, useSharedAccounts)
-		if err != nil {
-			// Check if it's the specific error about shared accounts
-			if strings.Contains(err.Error(), "Simple AMMs are not supported with shared accounts") {
-				// If so, try without shared accounts on the next attempt
-				useSharedAccounts = false
-				s.logger.Printf("Detected Simple AMM error, will retry without shared accounts")
+		// Steps 1 to 3: quote, build and simulate the swap
+		logger.Printf("Getting swap quote for %d units of %s -> %s (attempt %d/%d)...",
+			amount, inputMint, outputName, attempt, maxRetries)
+		quote, decodedTx, block, err := s.prepareSwap(ctx, pubKey, inputMint, outputMint, amount, &useSharedAccounts)
+
+		// Building and simulating takes time, a quote that went stale meanwhile is checked against a fresh one.
+		// Only a fresh quote below the prepared one builds and simulates a new transaction.
+		for requote := 1; err == nil && s.quoteStale(quote) && requote <= maxRequotes; requote++ {
+			logger.Printf("Quote is %s old, older than %s, re-quoting (%d/%d)",
+				quote.Age().Round(time.Millisecond), s.quoteMaxAge, requote, maxRequotes)
+			requotes++
+			var fresh *QuoteResponse
+			if fresh, err = s.client.GetSwapQuoteWithSlippage(inputMint, outputMint, amount, s.slippage.SlippageBps(inputMint)); err != nil {
+				err = fmt.Errorf("failed to get swap quote: %w", err)
+				break
 			}
-
-			lastErr = fmt.Errorf("failed to get swap transaction: %w", err)
-			s.logger.Printf("Retry %d/%d: %v", attempt, maxRetries, lastErr)
-			time.Sleep(retryDelay)
-			continue
+			if quote.heldBy(fresh) {
+				quote.FetchedAt = fresh.FetchedAt
+				continue
+			}
+			quote, decodedTx, block, err = s.prepareSwap(ctx, pubKey, inputMint, outputMint, amount, &useSharedAccounts)
 		}
-
-		// Step 3: Get blockhash and set it in the transaction
-		block, err := sln.BlockhashCache.GetBlockhash(s.solClient)
-		if err != nil {
-			lastErr = fmt.Errorf("failed to get latest blockhash: %w", err)
-			s.logger.Printf("Retry %d/%d: %v", attempt, maxRetries, lastErr)
-			time.Sleep(retryDelay)
-			continue
+		if err == nil && s.quoteStale(quote) {
+			err = fmt.Errorf("quote still %s old after %d re-quotes", quote.Age().Round(time.Millisecond), maxRequotes)
 		}
-
-		decodedTx, err := solana.TransactionFromBase64(swapResp.SwapTransaction)
 		if err != nil {
-			lastErr = fmt.Errorf("failed to decode transaction: %w", err)
-			s.logger.Printf("Retry %d/%d: %v", attempt, maxRetries, lastErr)
+			lastErr = err
+			logger.Printf("Retry %d/%d: %v", attempt, maxRetries, lastErr)
 			time.Sleep(retryDelay)
 			continue
 		}
 
-		decodedTx.Message.RecentBlockhash = block.Block.Blockhash
+		// SOL has 9 decimals, USDC 6
+		logger.Printf("Sending swap for %s %s with a quote %s old",
+			quote.OutTokens(outputDecimals).Format(5), outputName, quote.Age().Round(time.Millisecond))
+		trace.record(quote.Age(), requotes)
 
 		// Step 4: Sign and send transaction
-		s.logger.Printf("Signing and sending transaction...")
+		logger.Printf("Signing and sending transaction...")
 		signers := []solana.PrivateKey{wallet}
 
 		if _, err = decodedTx.Sign(
@@ -312,34 +348,121 @@ func (s *SwapService) SwapTokenForSolWithRetries(ctx context.Context, privateKey
 			},
 		); err != nil {
 			lastErr = fmt.Errorf("failed to sign transaction: %w", err)
-			s.logger.Printf("Retry %d/%d: %v", attempt, maxRetries, lastErr)
+			logger.Printf("Retry %d/%d: %v", attempt, maxRetries, lastErr)
 			time.Sleep(retryDelay)
 			continue
 		}
 
-		sig, err := s.solClient.SendTransactionWithOpts(
-			ctx,
-			decodedTx,
-			rpc.TransactionOpts{
-				SkipPreflight: true,
-			},
-		)
+		// Rebroadcast until the swap lands or its blockhash expires, so a retry can never sell twice
+		sender := sln.NewSender(s.solClient, rpc.TransactionOpts{SkipPreflight: true}, s.rebroadcast, sln.LogSendStatus(logger, "Swap"))
+		landing, err := sender.SendAndAwait(ctx, decodedTx, block.LastValidBlockHeight)
 		if err != nil {
-			lastErr = fmt.Errorf("failed to send transaction: %w", err)
-			s.logger.Printf("Retry %d/%d: %v", attempt, maxRetries, lastErr)
-			time.Sleep(retryDelay)
-			continue
+			return landing.Signature, fmt.Errorf("swap transaction %s has unknown outcome, not retrying: %w", landing.Signature, err)
 		}
 
-		// Success! Return the signature
-		s.logger.Printf("🎉 Successfully swapped %s for SOL on attempt %d/%d", inputMint, attempt, maxRetries)
-		return sig, nil
+		switch landing.Status {
+		case sln.LandingConfirmed:
+			// Success! Return the signature
+			logger.Printf("🎉 Successfully swapped %s for %s on attempt %d/%d", inputMint, outputName, attempt, maxRetries)
+			if outputMint == WrappedSolMint {
+				// Only SOL received can be read back from the transaction for tuning
+				s.recordFill(ctx, inputMint, uint64(quote.OutLamports()), landing.Signature)
+				s.recordPlatformFee(ctx, inputMint, quote.PlatformFeeAmount(), landing.Signature)
+			}
+			return landing.Signature, nil
+		case sln.LandingFailed:
+			lastErr = fmt.Errorf("swap transaction %s failed: %v", landing.Signature, landing.TxErr)
+			if isSlippageError(landing.TxErr) {
+				s.slippage.RecordSlippageFailure(inputMint)
+			}
+		default:
+			lastErr = fmt.Errorf("swap transaction %s expired without landing", landing.Signature)
+			if landing.SendErr != nil {
+				lastErr = fmt.Errorf("failed to send transaction: %w", landing.SendErr)
+			}
+		}
+		logger.Printf("Retry %d/%d: %v", attempt, maxRetries, lastErr)
+		time.Sleep(retryDelay)
 	}
 
 	// If we get here, all attempts failed
 	return solana.Signature{}, fmt.Errorf("all %d attempts failed to swap token: %w", maxRetries, lastErr)
 }
 
+// prepareSwap quotes the swap, builds its transaction with a recent blockhash and checks it in a simulation.
+// useSharedAccounts is cleared when Jupiter cannot route the swap through shared accounts.
+func (s *SwapService) prepareSwap(ctx context.Context, pubKey solana.PublicKey, inputMint, outputMint string, amount uint64, useSharedAccounts *bool) (*QuoteResponse, *solana.Transaction, *rpc.LatestBlockhashResult, error) {
+	logger := operation.Logger(ctx, s.logger)
+
+	quote, err := s.client.GetSwapQuoteWithSlippage(inputMint, outputMint, amount, s.slippage.SlippageBps(inputMint))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get swap quote: %w", err)
+	}
+
+	logger.Printf("Getting swap transaction...")
+	swapResp, err := s.client.GetSwapTransactionWithOptions(quote, pubKey, *useSharedAccounts)
+	if err != nil {
+		// Check if it's the specific error about shared accounts
+		if strings.Contains(err.Error(), "Simple AMMs are not supported with shared accounts") {
+			// If so, try without shared accounts on the next attempt
+			*useSharedAccounts = false
+			logger.Printf("Detected Simple AMM error, will retry without shared accounts")
+		}
+		return nil, nil, nil, fmt.Errorf("failed to get swap transaction: %w", err)
+	}
+
+	block, err := sln.BlockhashCache.GetBlockhash(s.solClient)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get latest blockhash: %w", err)
+	}
+
+	decodedTx, err := solana.TransactionFromBase64(swapResp.SwapTransaction)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	decodedTx.Message.RecentBlockhash = block.Blockhash
+
+	// A stale quote or a changed route shows in the simulated balances, re-quote before money moves
+	if err := s.simulateSwap(ctx, decodedTx, pubKey, quote); err != nil {
+		return nil, nil, nil, err
+	}
+	return quote, decodedTx, block, nil
+}
+
+// recordFill feeds the SOL a landed swap received against its quote into the slippage tuner
+func (s *SwapService) recordFill(ctx context.Context, inputMint string, quotedOut uint64, sig solana.Signature) {
+	logger := operation.Logger(ctx, s.logger)
+
+	if s.slippage == nil {
+		return
+	}
+
+	_, received, err := sln.GetTransactionFeesAndEarnings(s.solClient, sig.String(), true)
+	if err != nil {
+		logging.Warnf(logger, "Warning: Failed to read swap output of %s for slippage tuning: %v", sig, err)
+		return
+	}
+	if received == 0 {
+		// The WSOL transfer was not found, a zero would look like a total loss
+		return
+	}
+	s.slippage.RecordFill(inputMint, quotedOut, received)
+}
+
+// recordPlatformFee records the platform fee a landed swap paid into the fee account
+func (s *SwapService) recordPlatformFee(ctx context.Context, inputMint string, fee uint64, sig solana.Signature) {
+	logger := operation.Logger(ctx, s.logger)
+
+	if s.feeStats == nil || fee == 0 {
+		return
+	}
+
+	logger.Printf("Platform fee of %s collected on %s", amount.Lamports(fee), sig)
+	if err := s.feeStats.RecordPlatformFeeStats(inputMint, fee, sig.String(), operation.ID(ctx)); err != nil {
+		logging.Warnf(logger, "Warning: Failed to record platform fee stats: %v", err)
+	}
+}
+
 // GetSwapTransactionData gets transaction data for a swap without sending it
 func (s *SwapService) GetSwapTransactionData(ctx context.Context, inputMint string, outputMint string, amount uint64, userPubKey solana.PublicKey) (string, error) {
 	// Step 1: Get quote
@@ -357,6 +480,21 @@ func (s *SwapService) GetSwapTransactionData(ctx context.Context, inputMint stri
 	return swapResp.SwapTransaction, nil
 }
 
+// GetTokenUsdPrice fetches the current USD price of one whole token from the Jupiter Price API
+func (s *SwapService) GetTokenUsdPrice(tokenMint string) (float64, error) {
+	prices, err := s.client.GetPrices([]string{tokenMint})
+	if err != nil {
+		return 0, err
+	}
+
+	price, ok := prices[tokenMint]
+	if !ok || price <= 0 {
+		return 0, fmt.Errorf("no Jupiter price available for %s", tokenMint)
+	}
+
+	return price, nil
+}
+
 // EstimateSwapOutputAmount estimates the amount of SOL to be received when swapping a token
 func (s *SwapService) EstimateSwapOutputAmount(tokenMint string, amount uint64) (float64, error) {
 	// Get quote
@@ -365,9 +503,5 @@ func (s *SwapService) EstimateSwapOutputAmount(tokenMint string, amount uint64)
 		return 0, fmt.Errorf("failed to get swap quote: %w", err)
 	}
 
-	// Calculate output amount in SOL (with 9 decimals)
-	outAmountRaw, _ := strconv.ParseUint(quote.OutAmount, 10, 64)
-	outAmountFormatted := float64(outAmountRaw) / math.Pow10(9) // SOL has 9 decimals
-
-	return outAmountFormatted, nil
+	return quote.OutLamports().Sol(), nil
 }
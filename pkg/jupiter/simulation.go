@@ -0,0 +1,162 @@
+package jupiter
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/operation"
+)
+
+// tokenAccountAmountOffset is where the amount is stored in the data of a token account
+const tokenAccountAmountOffset = 64
+
+// SetSwapSimulation simulates every swap before it is sent and rejects it when the simulated balance changes
+// do not match the quote. tolerance is how far the received amount may fall short of the quoted output
+// (0.02 = 2%).
+func (s *SwapService) SetSwapSimulation(tolerance float64) {
+	s.simulate = true
+	s.simulationTolerance = tolerance
+}
+
+// simulateSwap simulates the unsigned swap transaction and checks the balance changes of the wallet against
+// the quote. The tokens sold must not exceed the quoted input, and the output received, after the transaction
+// fee for SOL, must reach the quoted output within the tolerance. A stale quote or a changed route fails the
+// check and the caller asks for a new quote. The swap is sent unchecked when the simulation itself cannot run.
+func (s *SwapService) simulateSwap(ctx context.Context, tx *solana.Transaction, owner solana.PublicKey, quote *QuoteResponse) error {
+	if !s.simulate {
+		return nil
+	}
+	logger := operation.Logger(ctx, s.logger)
+
+	inputMint, err := solana.PublicKeyFromBase58(quote.InputMint)
+	if err != nil {
+		return fmt.Errorf("invalid input mint: %w", err)
+	}
+	inputAccount, _, err := solana.FindAssociatedTokenAddress(owner, inputMint)
+	if err != nil {
+		return fmt.Errorf("failed to find associated token address: %w", err)
+	}
+
+	// Swaps to SOL unwrap into the wallet itself, swaps to USDC pay into its token account
+	outputAccount := owner
+	toSol := quote.OutputMint == WrappedSolMint
+	if !toSol {
+		outputMint, err := solana.PublicKeyFromBase58(quote.OutputMint)
+		if err != nil {
+			return fmt.Errorf("invalid output mint: %w", err)
+		}
+		if outputAccount, _, err = solana.FindAssociatedTokenAddress(owner, outputMint); err != nil {
+			return fmt.Errorf("failed to find associated token address: %w", err)
+		}
+	}
+	addresses := []solana.PublicKey{inputAccount, outputAccount}
+
+	before, err := s.solClient.GetMultipleAccountsWithOpts(ctx, addresses, &rpc.GetMultipleAccountsOpts{
+		Encoding:   solana.EncodingBase64,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil || before == nil || len(before.Value) != len(addresses) {
+		logging.Warnf(logger, "Warning: Failed to read balances before simulating swap, sending unchecked: %v", err)
+		return nil
+	}
+
+	result, err := s.solClient.SimulateTransactionWithOpts(ctx, tx, &rpc.SimulateTransactionOpts{
+		SigVerify:              false,
+		ReplaceRecentBlockhash: true,
+		Commitment:             rpc.CommitmentConfirmed,
+		Accounts: &rpc.SimulateTransactionAccountsOpts{
+			Encoding:  solana.EncodingBase64,
+			Addresses: addresses,
+		},
+	})
+	if err != nil || result == nil || result.Value == nil {
+		logging.Warnf(logger, "Warning: Failed to simulate swap, sending unchecked: %v", err)
+		return nil
+	}
+	if result.Value.Err != nil {
+		return fmt.Errorf("swap simulation failed: %v", result.Value.Err)
+	}
+	if len(result.Value.Accounts) != len(addresses) {
+		logging.Warnf(logger, "Warning: Swap simulation returned no balances, sending unchecked")
+		return nil
+	}
+
+	spent := int64(tokenAccountAmount(before.Value[0])) - int64(tokenAccountAmount(result.Value.Accounts[0]))
+	var received int64
+	if toSol {
+		fee, err := s.transactionFee(ctx, tx)
+		if err != nil {
+			logging.Warnf(logger, "Warning: Failed to get swap fee, sending unchecked: %v", err)
+			return nil
+		}
+		received = int64(accountLamports(result.Value.Accounts[1])) - int64(accountLamports(before.Value[1])) + int64(fee)
+	} else {
+		received = int64(tokenAccountAmount(result.Value.Accounts[1])) - int64(tokenAccountAmount(before.Value[1]))
+	}
+
+	return checkSwapDeltas(quote, spent, received, s.simulationTolerance)
+}
+
+// checkSwapDeltas compares the simulated tokens spent and output received with the quote
+func checkSwapDeltas(quote *QuoteResponse, spent, received int64, tolerance float64) error {
+	inAmount, err := strconv.ParseInt(quote.InAmount, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid quote input amount %q: %w", quote.InAmount, err)
+	}
+	outAmount, err := strconv.ParseInt(quote.OutAmount, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid quote output amount %q: %w", quote.OutAmount, err)
+	}
+
+	if spent > inAmount {
+		return fmt.Errorf("simulated swap spends %d tokens, quote is for %d", spent, inAmount)
+	}
+	if minimum := float64(outAmount) * (1 - tolerance); float64(received) < minimum {
+		return fmt.Errorf("simulated swap receives %d, %.1f%% below the quoted %d", received, (1-float64(received)/float64(outAmount))*100, outAmount)
+	}
+	return nil
+}
+
+// transactionFee returns the fee the network charges for the transaction, including its priority fee
+func (s *SwapService) transactionFee(ctx context.Context, tx *solana.Transaction) (uint64, error) {
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	result, err := s.solClient.GetFeeForMessage(ctx, base64.StdEncoding.EncodeToString(message), rpc.CommitmentConfirmed)
+	if err != nil {
+		return 0, err
+	}
+	if result == nil || result.Value == nil {
+		return 0, fmt.Errorf("no fee for the swap message")
+	}
+	return *result.Value, nil
+}
+
+// tokenAccountAmount returns the amount held by a token account, 0 when it does not exist
+func tokenAccountAmount(account *rpc.Account) uint64 {
+	if account == nil || account.Data == nil {
+		return 0
+	}
+	data := account.Data.GetBinary()
+	if len(data) < tokenAccountAmountOffset+8 {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(data[tokenAccountAmountOffset:])
+}
+
+// accountLamports returns the lamports of an account, 0 when it does not exist
+func accountLamports(account *rpc.Account) uint64 {
+	if account == nil {
+		return 0
+	}
+	return account.Lamports
+}
@@ -0,0 +1,224 @@
+package jupiter
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/logging"
+)
+
+const (
+	// slippageLoosenFactor widens the tolerance of a token after a swap failed on slippage
+	slippageLoosenFactor = 1.5
+	// slippageTightenFactor narrows the tolerance of a token after a run of clean fills
+	slippageTightenFactor = 0.8
+	// slippageTightenAfterFills is the number of fills without a slippage failure needed before tightening
+	slippageTightenAfterFills = 3
+	// slippageShortfallHeadroom is how many times the average observed shortfall the tolerance keeps
+	slippageShortfallHeadroom = 3
+	// slippageShortfallWeight is the weight of the latest fill in the average shortfall
+	slippageShortfallWeight = 0.3
+	// jupiterSlippageErrorCode is the custom program error Jupiter returns when the output is below the minimum
+	jupiterSlippageErrorCode = `"Custom":6001`
+)
+
+// TokenSlippage is the tuned slippage tolerance of a token and the fills it was derived from
+type TokenSlippage struct {
+	Bps          int       `json:"bps"`
+	Fills        int       `json:"fills"`        // Swaps that landed
+	Failures     int       `json:"failures"`     // Swaps that failed on slippage
+	CleanFills   int       `json:"cleanFills"`   // Fills since the last slippage failure or change
+	ShortfallBps float64   `json:"shortfallBps"` // Average received below quoted output, in basis points
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// SlippageTuner adjusts the slippage tolerance per token from realized execution quality. Tokens whose
+// fills come in close to the quote get a tighter tolerance, limiting how much value a bad fill can leak,
+// and a swap failing on slippage loosens it again so sales keep landing.
+type SlippageTuner struct {
+	minBps int
+	maxBps int
+	path   string
+	logger *log.Logger
+
+	mu     sync.Mutex
+	tokens map[string]TokenSlippage
+}
+
+// NewSlippageTuner creates a tuner keeping per-token slippage within minBps and maxBps. State is loaded
+// from and saved to path when it is not empty.
+func NewSlippageTuner(minBps, maxBps int, path string, logger *log.Logger) (*SlippageTuner, error) {
+	if minBps <= 0 || maxBps < minBps {
+		return nil, fmt.Errorf("invalid slippage bounds %d-%d bps", minBps, maxBps)
+	}
+
+	t := &SlippageTuner{
+		minBps: minBps,
+		maxBps: maxBps,
+		path:   path,
+		logger: logger,
+		tokens: make(map[string]TokenSlippage),
+	}
+
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read slippage state: %w", err)
+		}
+		if err == nil {
+			if err := json.Unmarshal(content, &t.tokens); err != nil {
+				return nil, fmt.Errorf("failed to decode slippage state: %w", err)
+			}
+		}
+	}
+
+	// Bounds may have changed since the state was saved
+	for mint, token := range t.tokens {
+		token.Bps = t.clamp(token.Bps)
+		t.tokens[mint] = token
+	}
+
+	return t, nil
+}
+
+// SlippageBps returns the slippage tolerance to quote the token with, the default clamped to the
+// bounds until the token has been tuned. A nil tuner always returns the default.
+func (t *SlippageTuner) SlippageBps(mint string) int {
+	if t == nil {
+		return SlippageBps
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.tokenLocked(mint).Bps
+}
+
+// RecordFill records a landed swap with its quoted and received output. After enough fills without a
+// slippage failure the tolerance is tightened towards a few times the average shortfall.
+func (t *SlippageTuner) RecordFill(mint string, quotedOut, receivedOut uint64) {
+	if t == nil || quotedOut == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	token := t.tokenLocked(mint)
+	shortfall := 0.0
+	if receivedOut < quotedOut {
+		shortfall = float64(quotedOut-receivedOut) / float64(quotedOut) * 10000
+	}
+	if token.Fills == 0 {
+		token.ShortfallBps = shortfall
+	} else {
+		token.ShortfallBps = token.ShortfallBps*(1-slippageShortfallWeight) + shortfall*slippageShortfallWeight
+	}
+	token.Fills++
+	token.CleanFills++
+
+	if token.CleanFills >= slippageTightenAfterFills {
+		target := t.clamp(int(token.ShortfallBps * slippageShortfallHeadroom))
+		tightened := int(float64(token.Bps) * slippageTightenFactor)
+		if tightened < target {
+			tightened = target
+		}
+		if tightened < token.Bps {
+			t.logger.Printf("Tightened slippage for %s from %d to %d bps (average shortfall %.0f bps over %d fills)",
+				mint, token.Bps, tightened, token.ShortfallBps, token.Fills)
+			token.Bps = tightened
+			token.CleanFills = 0
+		}
+	}
+
+	t.saveLocked(mint, token)
+}
+
+// RecordSlippageFailure records a swap that failed because the output fell below the tolerance and
+// loosens the tolerance, so the retry has a better chance of landing
+func (t *SlippageTuner) RecordSlippageFailure(mint string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	token := t.tokenLocked(mint)
+	token.Failures++
+	token.CleanFills = 0
+
+	loosened := t.clamp(int(float64(token.Bps) * slippageLoosenFactor))
+	if loosened > token.Bps {
+		t.logger.Printf("Loosened slippage for %s from %d to %d bps after a slippage failure", mint, token.Bps, loosened)
+		token.Bps = loosened
+	} else {
+		logging.Warnf(t.logger, "Swap of %s failed on slippage at the maximum of %d bps", mint, token.Bps)
+	}
+
+	t.saveLocked(mint, token)
+}
+
+// tokenLocked returns the state of a token, starting untuned tokens at the clamped default, t.mu must be held
+func (t *SlippageTuner) tokenLocked(mint string) TokenSlippage {
+	if token, ok := t.tokens[mint]; ok {
+		return token
+	}
+	return TokenSlippage{Bps: t.clamp(SlippageBps)}
+}
+
+// saveLocked stores the token state and writes all state to disk, t.mu must be held
+func (t *SlippageTuner) saveLocked(mint string, token TokenSlippage) {
+	token.UpdatedAt = time.Now()
+	t.tokens[mint] = token
+
+	if t.path == "" {
+		return
+	}
+
+	content, err := json.MarshalIndent(t.tokens, "", "  ")
+	if err != nil {
+		logging.Warnf(t.logger, "Warning: Failed to encode slippage state: %v", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil {
+		logging.Warnf(t.logger, "Warning: Failed to create slippage state directory: %v", err)
+		return
+	}
+	tmpPath := t.path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0644); err != nil {
+		logging.Warnf(t.logger, "Warning: Failed to write slippage state: %v", err)
+		return
+	}
+	if err := os.Rename(tmpPath, t.path); err != nil {
+		logging.Warnf(t.logger, "Warning: Failed to replace slippage state: %v", err)
+	}
+}
+
+// clamp keeps a tolerance within the configured bounds
+func (t *SlippageTuner) clamp(bps int) int {
+	if bps < t.minBps {
+		return t.minBps
+	}
+	if bps > t.maxBps {
+		return t.maxBps
+	}
+	return bps
+}
+
+// isSlippageError reports whether a transaction error is Jupiter's slippage tolerance exceeded error
+func isSlippageError(txErr interface{}) bool {
+	if txErr == nil {
+		return false
+	}
+	encoded, err := json.Marshal(txErr)
+	if err != nil {
+		return strings.Contains(fmt.Sprint(txErr), "6001")
+	}
+	return strings.Contains(string(encoded), jupiterSlippageErrorCode)
+}
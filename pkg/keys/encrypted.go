@@ -0,0 +1,86 @@
+package keys
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/gagliardetto/solana-go"
+)
+
+// scryptWorkFactor is the log2 scrypt cost of new key files, around a second on current hardware as with age
+var scryptWorkFactor = 18
+
+// FromEncryptedFile decrypts a key file encrypted with a passphrase by Encrypt or by `age -p`. The file holds
+// the key in any format Parse accepts, the decrypted key is only kept in memory.
+func FromEncryptedFile(path, passphrase string) (solana.PrivateKey, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open encrypted key file: %w", err)
+	}
+	defer file.Close()
+
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("invalid passphrase: %w", err)
+	}
+
+	// Both the ASCII armored and the binary age formats are accepted
+	reader := bufio.NewReader(file)
+	var source io.Reader = reader
+	if header, _ := reader.Peek(len(armor.Header)); string(header) == armor.Header {
+		source = armor.NewReader(reader)
+	}
+
+	plaintext, err := age.Decrypt(source, identity)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return nil, fmt.Errorf("failed to decrypt %s: wrong passphrase", path)
+		}
+		return nil, fmt.Errorf("failed to decrypt %s: %w", path, err)
+	}
+
+	content, err := io.ReadAll(plaintext)
+	defer clear(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", path, err)
+	}
+
+	privateKey, err := Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return privateKey, nil
+}
+
+// Encrypt returns the key in base58, encrypted with a passphrase into an ASCII armored age file
+func Encrypt(privateKey solana.PrivateKey, passphrase string) ([]byte, error) {
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("invalid passphrase: %w", err)
+	}
+	recipient.SetWorkFactor(scryptWorkFactor)
+
+	var encrypted bytes.Buffer
+	armored := armor.NewWriter(&encrypted)
+	writer, err := age.Encrypt(armored, recipient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt key: %w", err)
+	}
+	if _, err := io.WriteString(writer, privateKey.String()); err != nil {
+		return nil, fmt.Errorf("failed to encrypt key: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encrypt key: %w", err)
+	}
+	if err := armored.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encrypt key: %w", err)
+	}
+	return encrypted.Bytes(), nil
+}
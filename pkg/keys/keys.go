@@ -0,0 +1,159 @@
+// Package keys loads the wallet private key from the formats Solana wallets export it in: a base58 string, a
+// solana-cli JSON keypair file, or a BIP39 mnemonic with a derivation path.
+package keys
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// DefaultDerivationPath is the path Phantom, Solflare and solana-keygen's prompt://?key=0/0 derive the first
+// account from
+const DefaultDerivationPath = "m/44'/501'/0'/0'"
+
+// hardenedOffset is added to the index of hardened path segments
+const hardenedOffset = 0x80000000
+
+// Parse decodes a private key given as base58 or as the JSON byte array of a solana-cli keypair file
+func Parse(key string) (solana.PrivateKey, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil, fmt.Errorf("private key not provided")
+	}
+	if strings.HasPrefix(key, "[") {
+		return fromKeypairJSON([]byte(key))
+	}
+
+	privateKey, err := solana.PrivateKeyFromBase58(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	return checked(privateKey)
+}
+
+// FromKeypairFile reads a keypair file written by solana-keygen, a JSON array of the 64 key bytes
+func FromKeypairFile(path string) (solana.PrivateKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keypair file: %w", err)
+	}
+
+	privateKey, err := fromKeypairJSON(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return privateKey, nil
+}
+
+// FromMnemonic derives the key at path from a BIP39 mnemonic and optional passphrase. An empty path derives
+// DefaultDerivationPath. Only hardened segments are possible for ed25519 keys, as in m/44'/501'/1'/0'.
+func FromMnemonic(mnemonic, passphrase, path string) (solana.PrivateKey, error) {
+	// Mnemonics are often pasted with line breaks or double spaces
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+
+	// The checksum catches mistyped and swapped words, which would otherwise derive an unrelated wallet
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mnemonic: %w", err)
+	}
+
+	if path == "" {
+		path = DefaultDerivationPath
+	}
+	return deriveKey(seed, path)
+}
+
+// fromKeypairJSON decodes the JSON byte array of a keypair file
+func fromKeypairJSON(content []byte) (solana.PrivateKey, error) {
+	// Decoding into []byte would expect a base64 string, the file holds an array of numbers
+	var values []int
+	if err := json.Unmarshal(content, &values); err != nil {
+		return nil, fmt.Errorf("invalid keypair, expected a JSON array of bytes: %w", err)
+	}
+
+	privateKey := make(solana.PrivateKey, 0, len(values))
+	for _, value := range values {
+		if value < 0 || value > 255 {
+			return nil, fmt.Errorf("invalid keypair, %d is not a byte", value)
+		}
+		privateKey = append(privateKey, byte(value))
+	}
+	return checked(privateKey)
+}
+
+// checked verifies the key holds the 32 byte seed followed by the public key derived from it, so a truncated
+// or hand-edited key is rejected before it signs anything
+func checked(privateKey solana.PrivateKey) (solana.PrivateKey, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key length %d, expected %d bytes", len(privateKey), ed25519.PrivateKeySize)
+	}
+	expected := ed25519.NewKeyFromSeed(privateKey[:ed25519.SeedSize])
+	if !expected.Equal(ed25519.PrivateKey(privateKey)) {
+		return nil, fmt.Errorf("invalid private key, its public key does not match its seed")
+	}
+	return privateKey, nil
+}
+
+// deriveKey derives the ed25519 key at path from a BIP39 seed following SLIP-0010
+func deriveKey(seed []byte, path string) (solana.PrivateKey, error) {
+	indexes, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, chainCode := slip10(hmacSHA512([]byte("ed25519 seed"), seed))
+	for _, index := range indexes {
+		data := make([]byte, 0, 1+len(key)+4)
+		data = append(data, 0)
+		data = append(data, key...)
+		data = binary.BigEndian.AppendUint32(data, index)
+		key, chainCode = slip10(hmacSHA512(chainCode, data))
+	}
+	return solana.PrivateKey(ed25519.NewKeyFromSeed(key)), nil
+}
+
+// parsePath returns the child indexes of a derivation path such as m/44'/501'/0'/0'
+func parsePath(path string) ([]uint32, error) {
+	segments := strings.Split(strings.TrimSpace(path), "/")
+	if segments[0] != "m" {
+		return nil, fmt.Errorf("invalid derivation path %q, expected it to start with m/", path)
+	}
+
+	indexes := make([]uint32, 0, len(segments)-1)
+	for _, segment := range segments[1:] {
+		number, hardened := strings.CutSuffix(segment, "'")
+		if !hardened {
+			number, hardened = strings.CutSuffix(segment, "h")
+		}
+		if !hardened {
+			return nil, fmt.Errorf("invalid derivation path %q, ed25519 keys only have hardened segments such as %s'", path, segment)
+		}
+		index, err := strconv.ParseUint(number, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("invalid derivation path %q: segment %q", path, segment)
+		}
+		indexes = append(indexes, uint32(index)+hardenedOffset)
+	}
+	return indexes, nil
+}
+
+// slip10 splits an HMAC-SHA512 digest into the key and the chain code
+func slip10(digest []byte) (key, chainCode []byte) {
+	return digest[:32], digest[32:]
+}
+
+func hmacSHA512(key, data []byte) []byte {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
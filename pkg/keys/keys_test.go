@@ -0,0 +1,103 @@
+package keys
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeriveKeyFollowsSlip10(t *testing.T) {
+	// Test vector 1 for ed25519 of SLIP-0010
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+
+	key, err := deriveKey(seed, "m")
+	require.NoError(t, err)
+	assert.Equal(t, "2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7", hex.EncodeToString(key[:32]))
+
+	key, err = deriveKey(seed, "m/0'/1h")
+	require.NoError(t, err)
+	assert.Equal(t, "b1d0bad404bf35da785a64ca1ac54b2617211d2777696fbffaf208f746ae84f2", hex.EncodeToString(key[:32]))
+
+	_, err = deriveKey(seed, "m/44'/501'/0")
+	assert.ErrorContains(t, err, "hardened")
+}
+
+func TestFromMnemonicChecksWords(t *testing.T) {
+	mnemonic := strings.Repeat("abandon ", 11) + "about"
+
+	key, err := FromMnemonic(mnemonic, "", "")
+	require.NoError(t, err)
+	same, err := FromMnemonic(" "+strings.ReplaceAll(mnemonic, " ", "\n  ")+"\n", "", DefaultDerivationPath)
+	require.NoError(t, err)
+	assert.Equal(t, key, same)
+
+	other, err := FromMnemonic(mnemonic, "", "m/44'/501'/1'/0'")
+	require.NoError(t, err)
+	assert.NotEqual(t, key.PublicKey(), other.PublicKey())
+
+	_, err = FromMnemonic(strings.Repeat("abandon ", 12), "", "")
+	assert.ErrorContains(t, err, "invalid mnemonic")
+}
+
+func TestKeypairFileAndParse(t *testing.T) {
+	key, err := solana.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	content, err := json.Marshal(toInts(key))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "id.json")
+	require.NoError(t, os.WriteFile(path, content, 0o600))
+
+	loaded, err := FromKeypairFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, key, loaded)
+
+	parsed, err := Parse(key.String())
+	require.NoError(t, err)
+	assert.Equal(t, key, parsed)
+	parsed, err = Parse(string(content))
+	require.NoError(t, err)
+	assert.Equal(t, key, parsed)
+
+	// A key whose public half does not belong to its seed is rejected
+	tampered := toInts(key)
+	tampered[40] ^= 1
+	content, _ = json.Marshal(tampered)
+	_, err = Parse(string(content))
+	assert.ErrorContains(t, err, "does not match")
+}
+
+func toInts(key solana.PrivateKey) []int {
+	values := make([]int, len(key))
+	for i, b := range key {
+		values[i] = int(b)
+	}
+	return values
+}
+
+func TestEncryptedFile(t *testing.T) {
+	scryptWorkFactor = 10
+
+	key, err := solana.NewRandomPrivateKey()
+	require.NoError(t, err)
+	encrypted, err := Encrypt(key, "correct horse")
+	require.NoError(t, err)
+	assert.NotContains(t, string(encrypted), key.String())
+
+	path := filepath.Join(t.TempDir(), "wallet.key.age")
+	require.NoError(t, os.WriteFile(path, encrypted, 0o600))
+
+	decrypted, err := FromEncryptedFile(path, "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, key, decrypted)
+
+	_, err = FromEncryptedFile(path, "wrong horse")
+	assert.ErrorContains(t, err, "wrong passphrase")
+}
@@ -0,0 +1,191 @@
+// Package logging builds the process logger on log/slog. Components keep logging through a *log.Logger, which
+// is bridged to slog: every line becomes a record carrying the component and its fields. Lines printed on the
+// logger are info records, Debugf, Warnf and Errorf log at their level.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Output formats selected with LOG_FORMAT
+const (
+	FormatPlain = "plain" // Timestamp, component and message, like the standard logger
+	FormatText  = "text"  // slog key=value records
+	FormatJSON  = "json"  // slog JSON records, one per line
+)
+
+// Setup makes a logger writing to w in the given format the slog default, dropping records below level.
+// Loggers from For pick it up even when they were created before.
+func Setup(w io.Writer, format, level string) error {
+	var minLevel slog.Level
+	if err := minLevel.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid LOG_LEVEL %q, expected debug, info, warn or error", level)
+	}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", FormatPlain:
+		handler = newPlainHandler(w, minLevel)
+	case FormatText:
+		handler = slog.NewTextHandler(w, &slog.HandlerOptions{Level: minLevel})
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, &slog.HandlerOptions{Level: minLevel})
+	default:
+		return fmt.Errorf("invalid LOG_FORMAT %q, expected plain, text or json", format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// For returns a logger for the component, writing through the slog default logger
+func For(component string) *log.Logger {
+	return log.New(&bridge{component: component}, "", 0)
+}
+
+// Component returns the logger with its component replaced, keeping its other fields. Loggers not created
+// by this package, such as discarding loggers in tests, are returned unchanged.
+func Component(logger *log.Logger, component string) *log.Logger {
+	b, ok := logger.Writer().(*bridge)
+	if !ok {
+		return logger
+	}
+	return log.New(&bridge{component: component, attrs: b.attrs}, "", 0)
+}
+
+// With returns the logger with extra fields, given as alternating keys and values. Loggers not created by
+// this package are returned unchanged.
+func With(logger *log.Logger, args ...any) *log.Logger {
+	b, ok := logger.Writer().(*bridge)
+	if !ok {
+		return logger
+	}
+	attrs := append(append([]any{}, b.attrs...), args...)
+	return log.New(&bridge{component: b.component, attrs: attrs}, "", 0)
+}
+
+// DebugEnabled reports whether debug records are logged, for callers that log more detail at LOG_LEVEL=debug
+func DebugEnabled() bool {
+	return slog.Default().Enabled(context.Background(), slog.LevelDebug)
+}
+
+// bridge turns the lines of a *log.Logger into slog records
+type bridge struct {
+	component string
+	attrs     []any
+}
+
+func (b *bridge) Write(p []byte) (int, error) {
+	b.log(slog.LevelInfo, strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
+
+// log writes the message as a record at level
+func (b *bridge) log(level slog.Level, message string) {
+	logger := slog.Default()
+	ctx := context.Background()
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	args := make([]any, 0, len(b.attrs)+2)
+	if b.component != "" {
+		args = append(args, "component", b.component)
+	}
+	args = append(args, b.attrs...)
+	logger.Log(ctx, level, message, args...)
+}
+
+// Debugf logs a line at debug level, only written with LOG_LEVEL=debug
+func Debugf(logger *log.Logger, format string, args ...any) {
+	logAt(logger, slog.LevelDebug, format, args)
+}
+
+// Warnf logs a line at warning level
+func Warnf(logger *log.Logger, format string, args ...any) {
+	logAt(logger, slog.LevelWarn, format, args)
+}
+
+// Errorf logs a line at error level
+func Errorf(logger *log.Logger, format string, args ...any) {
+	logAt(logger, slog.LevelError, format, args)
+}
+
+// Fatalf logs a line at error level and exits, like log.Fatalf
+func Fatalf(logger *log.Logger, format string, args ...any) {
+	logAt(logger, slog.LevelError, format, args)
+	os.Exit(1)
+}
+
+// logAt logs a line at level. Loggers not created by this package have no levels and print every line.
+func logAt(logger *log.Logger, level slog.Level, format string, args []any) {
+	message := fmt.Sprintf(format, args...)
+	if b, ok := logger.Writer().(*bridge); ok {
+		b.log(level, message)
+		return
+	}
+	logger.Output(3, message)
+}
+
+// plainHandler writes records as "2006/01/02 15:04:05 [component] message key=value", the layout of the
+// standard logger. Groups are flattened.
+type plainHandler struct {
+	w     io.Writer
+	mu    *sync.Mutex
+	level slog.Level
+	attrs []slog.Attr
+}
+
+func newPlainHandler(w io.Writer, level slog.Level) *plainHandler {
+	return &plainHandler{w: w, mu: &sync.Mutex{}, level: level}
+}
+
+func (h *plainHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *plainHandler) Handle(_ context.Context, record slog.Record) error {
+	attrs := append([]slog.Attr{}, h.attrs...)
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+
+	line := record.Time.AppendFormat(nil, "2006/01/02 15:04:05 ")
+	var fields []byte
+	for _, attr := range attrs {
+		if attr.Key == "component" {
+			line = append(line, '[')
+			line = append(line, attr.Value.String()...)
+			line = append(line, "] "...)
+			continue
+		}
+		fields = append(fields, ' ')
+		fields = append(fields, attr.Key...)
+		fields = append(fields, '=')
+		fields = append(fields, attr.Value.String()...)
+	}
+	line = append(line, record.Message...)
+	line = append(line, fields...)
+	line = append(line, '\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(line)
+	return err
+}
+
+func (h *plainHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &plainHandler{w: h.w, mu: h.mu, level: h.level, attrs: append(append([]slog.Attr{}, h.attrs...), attrs...)}
+}
+
+func (h *plainHandler) WithGroup(string) slog.Handler {
+	return h
+}
@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupTest makes a logger writing to the returned buffer the slog default for the duration of the test
+func setupTest(t *testing.T, format, level string) *bytes.Buffer {
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	var out bytes.Buffer
+	assert.NoError(t, Setup(&out, format, level))
+	return &out
+}
+
+func TestLevelsAreExplicit(t *testing.T) {
+	out := setupTest(t, FormatText, "debug")
+
+	logger := For("claimer")
+	logger.Println("Failed attempts are retried")
+	Debugf(logger, "Quote took %dms", 120)
+	Warnf(logger, "Warning: SOL price is stale")
+	Errorf(logger, "Failed to claim airdrop: %v", "timeout")
+
+	assert.Contains(t, out.String(), `level=INFO msg="Failed attempts are retried"`)
+	assert.Contains(t, out.String(), `level=DEBUG msg="Quote took 120ms"`)
+	assert.Contains(t, out.String(), `level=WARN msg="Warning: SOL price is stale"`)
+	assert.Contains(t, out.String(), `level=ERROR msg="Failed to claim airdrop: timeout"`)
+
+	// Loggers of other packages print every line
+	var plain bytes.Buffer
+	Debugf(log.New(&plain, "", 0), "Quote took %dms", 120)
+	assert.Equal(t, "Quote took 120ms\n", plain.String())
+}
+
+func TestJSONRecordsCarryComponentAndFields(t *testing.T) {
+	out := setupTest(t, FormatJSON, "info")
+
+	logger := With(Component(For("auto-claimer"), "scanner"), "wallet", "main")
+	Warnf(logger, "Warning: %d airdrops without price", 2)
+
+	var record map[string]any
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &record))
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "Warning: 2 airdrops without price", record["msg"])
+	assert.Equal(t, "scanner", record["component"])
+	assert.Equal(t, "main", record["wallet"])
+}
+
+func TestLevelFiltersRecords(t *testing.T) {
+	out := setupTest(t, FormatText, "warn")
+
+	logger := For("claimer")
+	logger.Println("Claiming airdrop")
+	Errorf(logger, "Failed to claim airdrop")
+
+	assert.NotContains(t, out.String(), "Claiming airdrop")
+	assert.Contains(t, out.String(), `level=ERROR msg="Failed to claim airdrop" component=claimer`)
+}
+
+func TestPlainFormat(t *testing.T) {
+	out := setupTest(t, FormatPlain, "info")
+
+	With(For("auto-claimer"), "wallet", "main").Println("Starting")
+
+	line := out.String()
+	assert.Regexp(t, `^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} \[auto-claimer\] Starting wallet=main\n$`, line)
+}
+
+func TestSetupRejectsUnknownSettings(t *testing.T) {
+	assert.Error(t, Setup(io.Discard, "xml", "info"))
+	assert.Error(t, Setup(io.Discard, FormatJSON, "verbose"))
+}
+
+func TestForeignLoggersAreUnchanged(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	assert.Same(t, logger, Component(logger, "scanner"))
+	assert.Same(t, logger, With(logger, "wallet", "main"))
+}
@@ -0,0 +1,242 @@
+package logging
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat names rotated files by the time they were rotated. It sorts by time and has no colons,
+// which Windows does not allow in names.
+const backupTimeFormat = "20060102T150405.000"
+
+// RotateOptions set when a log file is rotated and how many rotated files are kept
+type RotateOptions struct {
+	MaxSize    int64         // Bytes after which the file is rotated, 0 never rotates on size
+	Interval   time.Duration // Age after which the file is rotated, 0 never rotates on age
+	MaxBackups int           // Rotated files kept, older ones are removed, 0 keeps all
+	Compress   bool          // Gzip rotated files
+}
+
+// RotatingFile is a log file that is moved aside and started anew once it grows past its size or age, so
+// long-running servers do not fill their disks. Rotated files are named after the file with the rotation
+// time before the extension, events-20261016T120000.000.jsonl for events.jsonl.
+type RotatingFile struct {
+	path string
+	opts RotateOptions
+	now  func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+
+	// compressing tracks rotated files being compressed, Close waits for them
+	compressing sync.WaitGroup
+}
+
+// OpenRotatingFile opens (or creates) the log file at path, appending to it
+func OpenRotatingFile(path string, opts RotateOptions) (*RotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	f := &RotatingFile{path: path, opts: opts, now: time.Now}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open opens the file at the path. An existing file counts as opened now, its age is not known everywhere.
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	f.file, f.size, f.opened = file, info.Size(), f.now()
+	return nil
+}
+
+// Write appends p, rotating first when p would take the file past its size or the file is past its age.
+// Entries are written in one call, so an entry is never split across files.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.rotationDue(len(p)) {
+		if err := f.rotate(); err != nil {
+			// Keep writing to the current file, rotation is tried again once it grows by another MaxSize
+			// or is another Interval older
+			fmt.Fprintf(os.Stderr, "Warning: Failed to rotate log file %s: %v\n", f.path, err)
+			f.size, f.opened = 0, f.now()
+			if f.file == nil {
+				return 0, err
+			}
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotationDue reports whether the file is rotated before writing n more bytes. An empty file is never
+// rotated, an entry larger than MaxSize gets a file of its own.
+func (f *RotatingFile) rotationDue(n int) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.opts.MaxSize > 0 && f.size+int64(n) > f.opts.MaxSize {
+		return true
+	}
+	return f.opts.Interval > 0 && f.now().Sub(f.opened) >= f.opts.Interval
+}
+
+// rotate moves the file aside and opens a new one. The file is closed first, Windows does not rename open
+// files.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	f.file = nil
+
+	ext := filepath.Ext(f.path)
+	backup := strings.TrimSuffix(f.path, ext) + "-" + f.now().UTC().Format(backupTimeFormat) + ext
+	renameErr := os.Rename(f.path, backup)
+	if err := f.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return fmt.Errorf("failed to move log file aside: %w", renameErr)
+	}
+
+	if !f.opts.Compress {
+		f.prune()
+		return nil
+	}
+	f.compressing.Add(1)
+	go func() {
+		defer f.compressing.Done()
+		// A backup pruned by an earlier rotation before it was compressed needs no compression
+		if err := compressFile(backup); err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to compress rotated log file %s: %v\n", backup, err)
+		}
+		f.prune()
+	}()
+	return nil
+}
+
+// prune removes the oldest rotated files beyond MaxBackups
+func (f *RotatingFile) prune() {
+	if f.opts.MaxBackups <= 0 {
+		return
+	}
+	backups, err := f.Backups()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to list rotated log files of %s: %v\n", f.path, err)
+		return
+	}
+	for i := 0; i < len(backups)-f.opts.MaxBackups; i++ {
+		if err := os.Remove(backups[i]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to remove rotated log file %s: %v\n", backups[i], err)
+		}
+	}
+}
+
+// Backups lists the rotated files, oldest first. A file being compressed is listed once.
+func (f *RotatingFile) Backups() ([]string, error) {
+	ext := filepath.Ext(f.path)
+	stem := strings.TrimSuffix(filepath.Base(f.path), ext) + "-"
+	entries, err := os.ReadDir(filepath.Dir(f.path))
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		uncompressed := strings.TrimSuffix(name, ".gz")
+		if !strings.HasPrefix(name, stem) || !strings.HasSuffix(uncompressed, ext) || seen[uncompressed] {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(uncompressed, stem), ext)
+		if _, err := time.Parse(backupTimeFormat, stamp); err != nil {
+			continue
+		}
+		seen[uncompressed] = true
+		backups = append(backups, filepath.Join(filepath.Dir(f.path), uncompressed))
+	}
+	sort.Strings(backups)
+
+	for i, backup := range backups {
+		if _, err := os.Stat(backup); errors.Is(err, os.ErrNotExist) {
+			backups[i] = backup + ".gz"
+		}
+	}
+	return backups, nil
+}
+
+// Close closes the file and waits for rotated files being compressed
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+
+	f.compressing.Wait()
+	return err
+}
+
+// compressFile gzips path to path.gz through a temporary file and removes path, so an interrupted
+// compression leaves the uncompressed file behind
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmpPath := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path+".gz"); err != nil {
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
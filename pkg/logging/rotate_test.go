@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingFileKeepsBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	f, err := OpenRotatingFile(path, RotateOptions{MaxSize: 10, MaxBackups: 2, Compress: true})
+	require.NoError(t, err)
+
+	clock := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { clock = clock.Add(time.Second); return clock }
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "fourth\n", string(content))
+
+	backups, err := f.Backups()
+	require.NoError(t, err)
+	require.Len(t, backups, 2, "the oldest backup is removed")
+	assert.True(t, strings.HasSuffix(backups[1], ".jsonl.gz"), backups[1])
+
+	file, err := os.Open(backups[1])
+	require.NoError(t, err)
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	require.NoError(t, err)
+	rotated, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, "third\n", string(rotated))
+}
+
+func TestRotatingFileRotatesOnAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redeemer.log")
+	f, err := OpenRotatingFile(path, RotateOptions{Interval: time.Hour})
+	require.NoError(t, err)
+	defer f.Close()
+
+	clock := time.Now()
+	f.now = func() time.Time { return clock }
+	f.opened = clock
+
+	_, _ = f.Write([]byte("before\n"))
+	clock = clock.Add(30 * time.Minute)
+	_, _ = f.Write([]byte("still\n"))
+	clock = clock.Add(30 * time.Minute)
+	_, _ = f.Write([]byte("after\n"))
+
+	backups, err := f.Backups()
+	require.NoError(t, err)
+	assert.Len(t, backups, 1)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "after\n", string(content))
+}
@@ -1,5 +1,17 @@
 package models
 
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/amount"
+)
+
+// DefaultTokenDecimals is assumed for tokens whose mint decimals have not been read, Boop tokens have 9 decimals
+const DefaultTokenDecimals = 9
+
 // Token represents token information from Boop API
 type Token struct {
 	Name      string `json:"name"`
@@ -11,31 +23,141 @@ type Token struct {
 
 // AirdropNode represents a single airdrop from Boop API
 type AirdropNode struct {
-	ID           string          `json:"id"`
-	AmountLpt    string          `json:"amountLpt"`
-	AmountUsd    string          `json:"amountUsd"`
-	AmountSolLpt string          `json:"amountSolLpt"`
-	Proofs       [][]interface{} `json:"proofs"`    // Using interface{} as proofs structure isn't important for finding new airdrops
-	ClaimedAt    interface{}     `json:"claimedAt"` // Can be null
-	TxHash       interface{}     `json:"txHash"`    // Can be null
-	Token        Token           `json:"token"`
+	ID           string            `json:"id"`
+	AmountLpt    RawAmount         `json:"amountLpt"`
+	AmountUsd    USD               `json:"amountUsd"`
+	AmountSolLpt LamportValue      `json:"amountSolLpt"`
+	Proofs       []json.RawMessage `json:"proofs"`    // Raw proof nodes, only decoded by ClaimProofs when claiming
+	ClaimedAt    interface{}       `json:"claimedAt"` // Can be null
+	TxHash       interface{}       `json:"txHash"`    // Can be null
+	Token        Token             `json:"token"`
+	FromCache    bool              `json:"-"` // Set when served from the local cache during an API outage
+	Decimals     *uint8            `json:"-"` // Decimals of the token mint read on chain, nil until resolved
+}
+
+// TokenDecimals returns the decimals of the token mint, DefaultTokenDecimals until they are resolved
+func (a AirdropNode) TokenDecimals() uint8 {
+	if a.Decimals == nil {
+		return DefaultTokenDecimals
+	}
+	return *a.Decimals
+}
+
+// TokenAmount returns the airdropped amount with the decimals of the mint, zero when the amount is invalid
+func (a AirdropNode) TokenAmount() amount.TokenAmount {
+	return a.AmountLpt.Tokens(a.TokenDecimals())
+}
+
+// Validate reports fields an airdrop cannot be tracked or claimed without. The proofs are checked when claiming.
+func (a AirdropNode) Validate() error {
+	if a.ID == "" {
+		return fmt.Errorf("airdrop has no id")
+	}
+	if a.Token.Address == "" {
+		return fmt.Errorf("airdrop %s has no token address", a.ID)
+	}
+	if !a.AmountLpt.Valid() {
+		return fmt.Errorf("airdrop %s has no valid amount", a.ID)
+	}
+	return nil
+}
+
+// ClaimProofs converts the merkle proof to the 32 byte nodes the claim instruction takes. Proof nodes are
+// JSON number arrays, anything but 32 whole numbers from 0 to 255 per node is refused, since a truncated or
+// wrapped proof only fails on chain. Decoding them only here keeps scans of thousands of airdrops cheap.
+func (a AirdropNode) ClaimProofs() ([][32]uint8, error) {
+	proofBytes := make([][32]uint8, 0, len(a.Proofs))
+
+	for n, node := range a.Proofs {
+		var proof []float64
+		if err := json.Unmarshal(node, &proof); err != nil {
+			return nil, fmt.Errorf("invalid proof node %d: %w", n, err)
+		}
+		if len(proof) != 32 {
+			return nil, fmt.Errorf("proof node %d has %d bytes, expected 32", n, len(proof))
+		}
+
+		var fixed [32]uint8
+		for i, floatVal := range proof {
+			if floatVal < 0 || floatVal > math.MaxUint8 || floatVal != math.Trunc(floatVal) {
+				return nil, fmt.Errorf("invalid proof value %v in node %d, expected a byte", floatVal, n)
+			}
+			fixed[i] = uint8(floatVal)
+		}
+
+		proofBytes = append(proofBytes, fixed)
+	}
+
+	return proofBytes, nil
+}
+
+// UsdValue returns the USD value reported by the API. It is not available when the API could not price
+// the token, which shows up as a missing or zero amountUsd while amountSolLpt is still set. Negative and
+// non-finite values are never available.
+func (a AirdropNode) UsdValue() (float64, bool) {
+	if !a.AmountUsd.Known() {
+		return 0, false
+	}
+	if a.AmountUsd.Float() <= 0 {
+		if _, ok := a.SolValue(); ok {
+			return 0, false
+		}
+	}
+	return a.AmountUsd.Float(), true
+}
+
+// SolValue returns the SOL value reported by the API in amountSolLpt (lamports)
+func (a AirdropNode) SolValue() (float64, bool) {
+	if a.AmountSolLpt.Lamports() == 0 {
+		return 0, false
+	}
+	return a.AmountSolLpt.Lamports().Sol(), true
+}
+
+// ClaimTx returns the hash of the claim transaction reported by the API, empty when it has none
+func (a AirdropNode) ClaimTx() string {
+	txHash, _ := a.TxHash.(string)
+	return txHash
+}
+
+// ClaimTime returns when the API reports the airdrop was claimed, false when it was not or the time is not
+// RFC 3339
+func (a AirdropNode) ClaimTime() (time.Time, bool) {
+	claimedAt, ok := a.ClaimedAt.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, claimedAt)
+	return t, err == nil
 }
 
 // GraphQLRequest represents the structure for GraphQL API requests
 type GraphQLRequest struct {
-	Query         string            `json:"query"`
-	Variables     map[string]string `json:"variables"`
-	OperationName string            `json:"operationName"`
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
 }
 
 // GraphQLResponse represents the structure for GraphQL API responses
 type GraphQLResponse struct {
-	Data ResponseData `json:"data"`
+	Data   ResponseData   `json:"data"`
+	Errors []GraphQLError `json:"errors"`
+}
+
+// GraphQLError represents a single entry in the errors list of a GraphQL response
+type GraphQLError struct {
+	Message   string `json:"message"`
+	Locations []struct {
+		Line   int `json:"line"`
+		Column int `json:"column"`
+	} `json:"locations"`
+	Path       []interface{}          `json:"path"` // Field names and list indexes
+	Extensions map[string]interface{} `json:"extensions"`
 }
 
 // ResponseData represents the account data in API response
 type ResponseData struct {
-	Account AccountData `json:"account"`
+	Account *AccountData `json:"account"` // Nil when the API returned no data
 }
 
 // AccountData represents user account information
@@ -45,5 +167,12 @@ type AccountData struct {
 
 // StakingAirdropsData represents a collection of airdrops
 type StakingAirdropsData struct {
-	Nodes []AirdropNode `json:"nodes"`
+	Nodes    []AirdropNode `json:"nodes"`
+	PageInfo *PageInfo     `json:"pageInfo"` // Nil when the API does not paginate
+}
+
+// PageInfo tells whether a connection has more pages and where the next one starts
+type PageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
 }
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// airdropJSON is an airdrop as returned by the Boop API, with a one node proof
+const airdropJSON = `{
+	"id": "airdrop-1",
+	"amountLpt": "1500000000",
+	"amountUsd": "0.42",
+	"amountSolLpt": "2500000",
+	"proofs": [[1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21,22,23,24,25,26,27,28,29,30,31,255]],
+	"claimedAt": null,
+	"txHash": null,
+	"token": {"name": "Test", "address": "So11111111111111111111111111111111111111112", "symbol": "TEST"}
+}`
+
+func TestClaimProofs(t *testing.T) {
+	var airdrop AirdropNode
+	assert.NoError(t, json.Unmarshal([]byte(airdropJSON), &airdrop))
+	assert.NoError(t, airdrop.Validate())
+
+	proofs, err := airdrop.ClaimProofs()
+	assert.NoError(t, err)
+	if assert.Len(t, proofs, 1) {
+		assert.Equal(t, uint8(1), proofs[0][0])
+		assert.Equal(t, uint8(255), proofs[0][31])
+	}
+
+	for name, proof := range map[string]string{
+		"short node":   `[1,2,3]`,
+		"out of range": strings.Replace(`[1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21,22,23,24,25,26,27,28,29,30,31,255]`, "255", "256", 1),
+		"negative":     strings.Replace(`[1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21,22,23,24,25,26,27,28,29,30,31,255]`, "255", "-1", 1),
+		"fraction":     strings.Replace(`[1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21,22,23,24,25,26,27,28,29,30,31,255]`, "255", "1.5", 1),
+		"not a number": strings.Replace(`[1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21,22,23,24,25,26,27,28,29,30,31,255]`, "255", `"ff"`, 1),
+	} {
+		var malformed AirdropNode
+		assert.NoError(t, json.Unmarshal([]byte(airdropJSON), &malformed))
+		assert.NoError(t, json.Unmarshal([]byte("["+proof+"]"), &malformed.Proofs))
+
+		_, err := malformed.ClaimProofs()
+		assert.Error(t, err, name)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var airdrop AirdropNode
+	assert.NoError(t, json.Unmarshal([]byte(airdropJSON), &airdrop))
+
+	missingID := airdrop
+	missingID.ID = ""
+	assert.Error(t, missingID.Validate())
+
+	badAmount := airdrop
+	assert.NoError(t, json.Unmarshal([]byte(`"1.5e9"`), &badAmount.AmountLpt))
+	assert.Error(t, badAmount.Validate())
+}
+
+func TestAmountValuesJSON(t *testing.T) {
+	var airdrop AirdropNode
+	assert.NoError(t, json.Unmarshal([]byte(airdropJSON), &airdrop))
+	assert.Equal(t, uint64(1_500_000_000), airdrop.AmountLpt.Units())
+	assert.Equal(t, 0.42, airdrop.AmountUsd.Float())
+	assert.Equal(t, 0.0025, airdrop.AmountSolLpt.Lamports().Sol())
+
+	// Values keep the string format of the API, so caches and stores read them back unchanged
+	encoded, err := json.Marshal(airdrop)
+	assert.NoError(t, err)
+	assert.Contains(t, string(encoded), `"amountLpt":"1500000000","amountUsd":"0.42","amountSolLpt":"2500000"`)
+	var decoded AirdropNode
+	assert.NoError(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, airdrop, decoded)
+
+	// Numbers are accepted, missing and unreadable values are unknown instead of zero
+	var values AirdropNode
+	assert.NoError(t, json.Unmarshal([]byte(`{"amountLpt": 42, "amountUsd": "n/a", "amountSolLpt": 1.5e6}`), &values))
+	assert.True(t, values.AmountLpt.Valid())
+	assert.False(t, values.AmountUsd.Known())
+	assert.Equal(t, uint64(1_500_000), uint64(values.AmountSolLpt.Lamports()))
+	_, ok := values.UsdValue()
+	assert.False(t, ok)
+
+	var missing AirdropNode
+	assert.NoError(t, json.Unmarshal([]byte(`{"amountLpt": null}`), &missing))
+	assert.False(t, missing.AmountLpt.Valid())
+	assert.False(t, missing.AmountSolLpt.Known())
+
+	assert.Error(t, json.Unmarshal([]byte(`{"amountUsd": {"value": 1}}`), &missing))
+}
+
+func FuzzAirdropNodeJSON(f *testing.F) {
+	f.Add([]byte(airdropJSON))
+	f.Add([]byte(`{"id": "a", "amountLpt": "", "proofs": [[]], "token": {}}`))
+	f.Add([]byte(`{"proofs": [null, [1e300, -0, "x", null, {}]]}`))
+	f.Add([]byte(`{"amountLpt": "NaN", "amountUsd": "Inf", "amountSolLpt": "-1"}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var airdrop AirdropNode
+		if err := json.Unmarshal(data, &airdrop); err != nil {
+			return
+		}
+
+		// Decodable airdrops must never panic, and accepted proofs must keep every node
+		proofs, err := airdrop.ClaimProofs()
+		if err == nil && len(proofs) != len(airdrop.Proofs) {
+			t.Fatalf("%d proof nodes converted from %d", len(proofs), len(airdrop.Proofs))
+		}
+
+		_ = airdrop.Validate()
+		_ = airdrop.TokenAmount()
+		if usd, ok := airdrop.UsdValue(); ok && (usd < 0 || math.IsNaN(usd) || math.IsInf(usd, 0)) {
+			t.Fatalf("usd value %v reported as available", usd)
+		}
+		if sol, ok := airdrop.SolValue(); ok && (sol <= 0 || math.IsNaN(sol) || math.IsInf(sol, 0)) {
+			t.Fatalf("sol value %v reported as available", sol)
+		}
+	})
+}
@@ -0,0 +1,212 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"boop-airdrop-redeemer/pkg/amount"
+)
+
+// The Boop API reports amounts as decimal strings. The value types below parse them once, when the response is
+// decoded, and marshal back to the same strings so caches and stores keep their format. A value the API left
+// out or sent unreadable is decoded as unknown rather than failing the whole response, since one bad airdrop
+// must not hide the others.
+
+// USD is a dollar value reported by the Boop API
+type USD struct {
+	value float64
+	known bool
+}
+
+// NewUSD returns a known dollar value, negative and non-finite values are unknown
+func NewUSD(value float64) USD {
+	if value < 0 || math.IsNaN(value) || math.IsInf(value, 0) {
+		return USD{}
+	}
+	return USD{value: value, known: true}
+}
+
+// ParseUSD parses a dollar value such as "0.42"
+func ParseUSD(s string) (USD, error) {
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return USD{}, fmt.Errorf("invalid USD value %q", s)
+	}
+	usd := NewUSD(value)
+	if !usd.known {
+		return USD{}, fmt.Errorf("invalid USD value %q", s)
+	}
+	return usd, nil
+}
+
+// Float returns the value in dollars, 0 when it is unknown
+func (u USD) Float() float64 {
+	return u.value
+}
+
+// Known reports whether the API sent a readable value
+func (u USD) Known() bool {
+	return u.known
+}
+
+// String returns the value as the API reports it, empty when it is unknown
+func (u USD) String() string {
+	if !u.known {
+		return ""
+	}
+	return strconv.FormatFloat(u.value, 'f', -1, 64)
+}
+
+// MarshalJSON encodes the value as a decimal string
+func (u USD) MarshalJSON() ([]byte, error) {
+	return json.Marshal(u.String())
+}
+
+// UnmarshalJSON decodes a decimal string or number, anything unreadable is unknown
+func (u *USD) UnmarshalJSON(data []byte) error {
+	text, err := apiNumber(data)
+	if err != nil {
+		return err
+	}
+	*u, _ = ParseUSD(text)
+	return nil
+}
+
+// LamportValue is a SOL value reported by the Boop API in lamports
+type LamportValue struct {
+	lamports amount.Lamports
+	known    bool
+}
+
+// NewLamportValue returns a known SOL value
+func NewLamportValue(lamports amount.Lamports) LamportValue {
+	return LamportValue{lamports: lamports, known: true}
+}
+
+// ParseLamportValue parses a lamport amount. Whole numbers are read exactly, fractional lamports are rounded.
+func ParseLamportValue(s string) (LamportValue, error) {
+	if lamports, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return NewLamportValue(amount.Lamports(lamports)), nil
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(value >= 0 && value < math.MaxUint64) {
+		return LamportValue{}, fmt.Errorf("invalid lamport value %q", s)
+	}
+	return NewLamportValue(amount.Lamports(math.Round(value))), nil
+}
+
+// Lamports returns the value in lamports, 0 when it is unknown
+func (v LamportValue) Lamports() amount.Lamports {
+	return v.lamports
+}
+
+// Known reports whether the API sent a readable value
+func (v LamportValue) Known() bool {
+	return v.known
+}
+
+// String returns the lamports as the API reports them, empty when the value is unknown
+func (v LamportValue) String() string {
+	if !v.known {
+		return ""
+	}
+	return strconv.FormatUint(uint64(v.lamports), 10)
+}
+
+// MarshalJSON encodes the value as an integer string
+func (v LamportValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.String())
+}
+
+// UnmarshalJSON decodes an integer string or number, anything unreadable is unknown
+func (v *LamportValue) UnmarshalJSON(data []byte) error {
+	text, err := apiNumber(data)
+	if err != nil {
+		return err
+	}
+	*v, _ = ParseLamportValue(text)
+	return nil
+}
+
+// RawAmount is a token amount in base units of the mint, reported by the Boop API as an integer string.
+// The mint decimals are not part of the API response, Tokens combines both.
+type RawAmount struct {
+	units uint64
+	valid bool
+}
+
+// NewRawAmount returns a valid amount of base units
+func NewRawAmount(units uint64) RawAmount {
+	return RawAmount{units: units, valid: true}
+}
+
+// ParseRawAmount parses an integer amount of base units
+func ParseRawAmount(s string) (RawAmount, error) {
+	units, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return RawAmount{}, fmt.Errorf("invalid token amount %q", s)
+	}
+	return NewRawAmount(units), nil
+}
+
+// Units returns the amount in base units, 0 when it is invalid
+func (r RawAmount) Units() uint64 {
+	return r.units
+}
+
+// Valid reports whether the API sent a whole, non-negative number that fits in a uint64
+func (r RawAmount) Valid() bool {
+	return r.valid
+}
+
+// Tokens returns the amount with the decimals of its mint
+func (r RawAmount) Tokens(decimals uint8) amount.TokenAmount {
+	return amount.TokenAmount{Raw: r.units, Decimals: decimals}
+}
+
+// String returns the base units as the API reports them, empty when the amount is invalid
+func (r RawAmount) String() string {
+	if !r.valid {
+		return ""
+	}
+	return strconv.FormatUint(r.units, 10)
+}
+
+// MarshalJSON encodes the amount as an integer string
+func (r RawAmount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// UnmarshalJSON decodes an integer string or number, anything else is invalid
+func (r *RawAmount) UnmarshalJSON(data []byte) error {
+	text, err := apiNumber(data)
+	if err != nil {
+		return err
+	}
+	*r, _ = ParseRawAmount(text)
+	return nil
+}
+
+// apiNumber returns the text of a JSON string or number, empty for null. Objects, arrays and booleans are
+// refused like they were for the string fields the API documents.
+func apiNumber(data []byte) (string, error) {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		return "", nil
+	case len(data) > 0 && data[0] == '"':
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return "", err
+		}
+		return text, nil
+	case len(data) > 0 && (data[0] == '-' || (data[0] >= '0' && data[0] <= '9')):
+		return string(data), nil
+	default:
+		return "", fmt.Errorf("expected a number or a string, got %s", data)
+	}
+}
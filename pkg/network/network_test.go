@@ -0,0 +1,45 @@
+package network
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigureRoutesServicesThroughProxies(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "direct")
+	}))
+	defer target.Close()
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "proxied %s", r.URL.Host)
+	}))
+	defer proxy.Close()
+	t.Cleanup(func() { fallback, transports = nil, nil })
+
+	// Clients created before Configure follow it
+	boop := &http.Client{Transport: Transport(Boop)}
+	telegram := &http.Client{Transport: Transport(Telegram)}
+
+	require.NoError(t, Configure(Settings{Proxy: proxy.URL, Proxies: map[string]string{Telegram: Direct, Jupiter: ""}}))
+
+	get := func(client *http.Client) string {
+		resp, err := client.Get(target.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+	assert.Equal(t, "proxied "+target.Listener.Addr().String(), get(boop))
+	assert.Equal(t, "direct", get(telegram))
+
+	assert.Error(t, Configure(Settings{Proxy: "ftp://proxy:21"}))
+	assert.Error(t, Configure(Settings{Proxies: map[string]string{Privy: "proxy:8080"}}))
+	assert.Error(t, Configure(Settings{CAFile: t.TempDir() + "/missing.pem"}))
+}
@@ -0,0 +1,286 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/amount"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/network"
+	"boop-airdrop-redeemer/pkg/redact"
+)
+
+// Answers of the approval buttons, sent back as the callback data "<answer>:<key>"
+const (
+	ApprovalClaim = "claim"
+	ApprovalSkip  = "skip"
+)
+
+// Long polling of button taps
+const (
+	approvalPollTimeout = 25 * time.Second
+	approvalRetryDelay  = 5 * time.Second
+	maxCallbackData     = 64 // Bytes Telegram allows in the callback data of a button
+)
+
+// telegramPollClient outlasts the long poll of a getUpdates request
+var telegramPollClient = &http.Client{
+	Timeout:   approvalPollTimeout + 10*time.Second,
+	Transport: network.Transport(network.Telegram),
+}
+
+// ApprovalAnswer is a tap on the Claim or Skip button of an approval request
+type ApprovalAnswer struct {
+	Key    string
+	Answer string // ApprovalClaim or ApprovalSkip
+}
+
+// ApprovalRequest is an approval request message waiting for an answer
+type ApprovalRequest struct {
+	Key       string
+	messageID int
+	text      string
+}
+
+// approvalPollers holds the poller of each bot. Telegram serves the updates of a bot to one poller at a time,
+// and the clients of several wallets share the bot.
+var (
+	approvalPollersMu sync.Mutex
+	approvalPollers   = make(map[string]*approvalPoller)
+)
+
+// approvalPoller long polls the updates of one bot while approval requests are open and hands each button tap
+// to the client that sent the request
+type approvalPoller struct {
+	client  *TelegramClient // Client of the bot making the API calls
+	mu      sync.Mutex
+	open    map[string]approvalHandler
+	running bool
+	offset  int64
+}
+
+// approvalHandler receives the answer to an open request, only accepted from the chat the request went to
+type approvalHandler struct {
+	chatID   string
+	onAnswer func(ApprovalAnswer)
+}
+
+// telegramUpdate is the part of a getUpdates result carrying button taps
+type telegramUpdate struct {
+	UpdateID      int64 `json:"update_id"`
+	CallbackQuery *struct {
+		ID      string `json:"id"`
+		Data    string `json:"data"`
+		Message *struct {
+			Chat struct {
+				ID int64 `json:"id"`
+			} `json:"chat"`
+		} `json:"message"`
+	} `json:"callback_query"`
+}
+
+// SendApprovalRequest asks whether to claim an airdrop with Claim and Skip buttons. onAnswer is called once
+// with the tap on either button, from the goroutine polling Telegram. The request bypasses the dispatch queue,
+// its message ID is needed to close it.
+func (t *TelegramClient) SendApprovalRequest(key, tokenName, tokenSymbol string, tokens amount.TokenAmount, usdValue float64, timeout time.Duration, onAnswer func(ApprovalAnswer)) (*ApprovalRequest, error) {
+	if !t.Enabled || t.BotToken == "" || t.ChatID == "" {
+		return nil, fmt.Errorf("telegram is not configured")
+	}
+	if len(ApprovalClaim)+1+len(key) > maxCallbackData {
+		return nil, fmt.Errorf("approval key %q is too long for a button", key)
+	}
+
+	text := t.withLabel(redact.String(fmt.Sprintf(
+		"🙋 <b>Claim Approval</b>\n\n"+
+			"🪙 <b>Token:</b> %s\n"+
+			"💰 <b>Amount:</b> %s\n"+
+			"💵 <b>USD Value:</b> $%.2f\n\n"+
+			"Skipped unless approved within %s.",
+		tokenLabel(tokenName, tokenSymbol), tokens.Format(2), usdValue, formatDuration(timeout),
+	)))
+
+	payload := map[string]interface{}{
+		"chat_id":                  t.ChatID,
+		"text":                     text,
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+		"reply_markup": map[string]interface{}{
+			"inline_keyboard": [][]map[string]string{{
+				{"text": "✅ Claim", "callback_data": ApprovalClaim + ":" + key},
+				{"text": "⏭ Skip", "callback_data": ApprovalSkip + ":" + key},
+			}},
+		},
+	}
+
+	var message struct {
+		MessageID int `json:"message_id"`
+	}
+	if err := t.callAPI(telegramHTTPClient, "sendMessage", payload, &message); err != nil {
+		return nil, err
+	}
+
+	t.approvalPoller().register(key, approvalHandler{chatID: t.ChatID, onAnswer: onAnswer})
+	return &ApprovalRequest{Key: key, messageID: message.MessageID, text: text}, nil
+}
+
+// CloseApprovalRequest replaces the buttons of a request with its outcome. Taps on a closed request are
+// answered as expired.
+func (t *TelegramClient) CloseApprovalRequest(request *ApprovalRequest, outcome string) {
+	if request == nil {
+		return
+	}
+	t.approvalPoller().unregister(request.Key)
+
+	// An edit without reply_markup removes the buttons
+	payload := map[string]interface{}{
+		"chat_id":                  t.ChatID,
+		"message_id":               request.messageID,
+		"text":                     request.text + "\n\n" + outcome,
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	}
+	if err := t.callAPI(telegramHTTPClient, "editMessageText", payload, nil); err != nil {
+		logging.Errorf(telegramLogger, "Failed to close approval request: %v", err)
+	}
+}
+
+// approvalPoller returns the poller of the client's bot
+func (t *TelegramClient) approvalPoller() *approvalPoller {
+	approvalPollersMu.Lock()
+	defer approvalPollersMu.Unlock()
+
+	poller, ok := approvalPollers[t.BotToken]
+	if !ok {
+		poller = &approvalPoller{client: t, open: make(map[string]approvalHandler)}
+		approvalPollers[t.BotToken] = poller
+	}
+	return poller
+}
+
+// register opens a request, starting to poll when it is the only one
+func (p *approvalPoller) register(key string, handler approvalHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.open[key] = handler
+	if !p.running {
+		p.running = true
+		go p.run()
+	}
+}
+
+func (p *approvalPoller) unregister(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.open, key)
+}
+
+// run polls until no request is open anymore
+func (p *approvalPoller) run() {
+	for {
+		p.mu.Lock()
+		if len(p.open) == 0 {
+			p.running = false
+			p.mu.Unlock()
+			return
+		}
+		offset := p.offset
+		p.mu.Unlock()
+
+		payload := map[string]interface{}{
+			"offset":          offset,
+			"timeout":         int(approvalPollTimeout.Seconds()),
+			"allowed_updates": []string{"callback_query"},
+		}
+		var updates []telegramUpdate
+		if err := p.client.callAPI(telegramPollClient, "getUpdates", payload, &updates); err != nil {
+			logging.Errorf(telegramLogger, "Failed to poll approval answers: %v", err)
+			time.Sleep(approvalRetryDelay)
+			continue
+		}
+
+		for _, update := range updates {
+			p.handle(update)
+		}
+	}
+}
+
+// handle hands a button tap to the client whose request it answers and acknowledges it
+func (p *approvalPoller) handle(update telegramUpdate) {
+	query := update.CallbackQuery
+
+	p.mu.Lock()
+	p.offset = max(p.offset, update.UpdateID+1)
+	if query == nil {
+		p.mu.Unlock()
+		return
+	}
+	answer, key, _ := strings.Cut(query.Data, ":")
+	handler, ok := p.open[key]
+	if ok && (query.Message == nil || strconv.FormatInt(query.Message.Chat.ID, 10) != handler.chatID) {
+		ok = false
+	}
+	if ok && answer != ApprovalClaim && answer != ApprovalSkip {
+		ok = false
+	}
+	if ok {
+		delete(p.open, key)
+	}
+	p.mu.Unlock()
+
+	reply := "This request is no longer open"
+	if ok {
+		reply = "Claim approved"
+		if answer == ApprovalSkip {
+			reply = "Airdrop skipped"
+		}
+	}
+	if err := p.client.callAPI(telegramHTTPClient, "answerCallbackQuery", map[string]interface{}{
+		"callback_query_id": query.ID,
+		"text":              reply,
+	}, nil); err != nil {
+		logging.Errorf(telegramLogger, "Failed to answer approval button: %v", err)
+	}
+
+	if ok {
+		handler.onAnswer(ApprovalAnswer{Key: key, Answer: answer})
+	}
+}
+
+// callAPI makes one Bot API request and decodes its result into result unless it is nil
+func (t *TelegramClient) callAPI(client *http.Client, method string, payload interface{}, result interface{}) error {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal telegram payload: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/bot%s/%s", telegramAPIURL, t.BotToken, method)
+	resp, err := client.Post(url, "application/json", bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		return fmt.Errorf("telegram %s failed: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		telegramResponse
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("telegram %s returned status %d: %w", method, resp.StatusCode, err)
+	}
+	if !body.OK {
+		return fmt.Errorf("telegram %s: %w", method, &telegramError{Status: resp.StatusCode, Description: body.Description})
+	}
+	if result != nil {
+		if err := json.Unmarshal(body.Result, result); err != nil {
+			return fmt.Errorf("failed to decode telegram %s result: %w", method, err)
+		}
+	}
+	return nil
+}
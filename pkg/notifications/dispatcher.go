@@ -0,0 +1,192 @@
+package notifications
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/logging"
+)
+
+// Priority decides whether a notification is sent right away or batched
+type Priority int
+
+const (
+	// PriorityHigh messages are sent one by one, in the order they were queued
+	PriorityHigh Priority = iota
+	// PriorityLow messages are collected and sent together every batch interval
+	PriorityLow
+)
+
+const (
+	// dispatcherQueueSize is the number of messages that can wait before senders block
+	dispatcherQueueSize = 100
+	// maxBatchLength keeps combined messages below Telegram's 4096 character limit
+	maxBatchLength = 4000
+	// batchSeparator separates messages combined into one batch
+	batchSeparator = "\n\n──────────\n\n"
+)
+
+// dispatcherLogger logs messages the dispatcher failed to deliver
+var dispatcherLogger = logging.For("notifications")
+
+// queuedMessage is a message waiting in the dispatch queue
+type queuedMessage struct {
+	text     string
+	priority Priority
+}
+
+// Dispatcher sends notifications from a single goroutine, so messages reach the chat in the order
+// they were queued (a claim success is never overtaken by the sale error that followed it)
+type Dispatcher struct {
+	send          func(ctx context.Context, message string) error
+	batchInterval time.Duration
+	queue         chan queuedMessage
+
+	mu      sync.Mutex
+	running bool
+	cancel  context.CancelFunc
+	done    <-chan struct{} // Closed when the dispatch loop is told to stop
+	wg      sync.WaitGroup
+	batch   []string
+}
+
+// NewDispatcher creates a dispatcher delivering messages through send. Its ctx is done once the dispatcher is
+// stopping, send should stop waiting to retry then.
+func NewDispatcher(send func(ctx context.Context, message string) error, batchInterval time.Duration) *Dispatcher {
+	if batchInterval <= 0 {
+		batchInterval = time.Minute
+	}
+	return &Dispatcher{
+		send:          send,
+		batchInterval: batchInterval,
+		queue:         make(chan queuedMessage, dispatcherQueueSize),
+	}
+}
+
+// Start runs the dispatch loop until ctx is cancelled or Stop is called
+func (d *Dispatcher) Start(ctx context.Context) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.running {
+		return
+	}
+
+	ctx, d.cancel = context.WithCancel(ctx)
+	d.done = ctx.Done()
+	d.running = true
+
+	d.wg.Add(1)
+	go d.run(ctx)
+}
+
+// Stop delivers everything still queued and stops the dispatch loop. Safe to call more than once.
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	d.running = false
+	cancel := d.cancel
+	d.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	d.wg.Wait()
+}
+
+// Enqueue queues a message. Once the dispatcher is stopped, messages are sent synchronously instead.
+func (d *Dispatcher) Enqueue(message string, priority Priority) {
+	d.mu.Lock()
+	if !d.running {
+		d.mu.Unlock()
+		d.deliver(context.Background(), message)
+		return
+	}
+	// Holding the lock keeps the loop from draining before this message is in the queue. While the queue is full
+	// the loop may be stopping and waiting for the lock, so the message is then sent here instead.
+	select {
+	case d.queue <- queuedMessage{text: message, priority: priority}:
+		d.mu.Unlock()
+	case <-d.done:
+		d.mu.Unlock()
+		d.deliver(context.Background(), message)
+	}
+}
+
+// run processes the queue and flushes the low priority batch periodically
+func (d *Dispatcher) run(ctx context.Context) {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.batchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.mu.Lock()
+			d.running = false
+			d.mu.Unlock()
+
+			d.drain(ctx)
+			return
+		case message := <-d.queue:
+			d.handle(ctx, message)
+		case <-ticker.C:
+			d.flushBatch(ctx)
+		}
+	}
+}
+
+// drain handles every message still in the queue and flushes the batch
+func (d *Dispatcher) drain(ctx context.Context) {
+	for {
+		select {
+		case message := <-d.queue:
+			d.handle(ctx, message)
+		default:
+			d.flushBatch(ctx)
+			return
+		}
+	}
+}
+
+// handle sends a high priority message or adds a low priority one to the batch
+func (d *Dispatcher) handle(ctx context.Context, message queuedMessage) {
+	if message.priority == PriorityLow {
+		d.batch = append(d.batch, message.text)
+		return
+	}
+	d.deliver(ctx, message.text)
+}
+
+// flushBatch sends the collected low priority messages, combined into as few messages as fit
+func (d *Dispatcher) flushBatch(ctx context.Context) {
+	var combined []string
+	length := 0
+	for _, message := range d.batch {
+		if len(combined) > 0 && length+len(batchSeparator)+len(message) > maxBatchLength {
+			d.deliver(ctx, strings.Join(combined, batchSeparator))
+			combined, length = nil, 0
+		}
+		combined = append(combined, message)
+		length += len(message) + len(batchSeparator)
+	}
+	if len(combined) > 0 {
+		d.deliver(ctx, strings.Join(combined, batchSeparator))
+	}
+	d.batch = nil
+}
+
+// deliver sends one message, logging failures since callers no longer wait for the result. Refusals for a wrong
+// bot token or chat were logged and alerted when they started, they are only logged at debug level.
+func (d *Dispatcher) deliver(ctx context.Context, message string) {
+	err := d.send(ctx, message)
+	switch {
+	case err == nil:
+	case isConfigError(err):
+		logging.Debugf(dispatcherLogger, "Notification not delivered: %v", err)
+	default:
+		logging.Errorf(dispatcherLogger, "Failed to deliver notification: %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package notifications
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatcherStopsWithFullQueue(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var sent []string
+	d := NewDispatcher(func(ctx context.Context, message string) error {
+		<-release
+		mu.Lock()
+		sent = append(sent, message)
+		mu.Unlock()
+		return nil
+	}, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	d.Start(ctx)
+
+	// One message is being sent, the queue is full and the sender waits for room
+	enqueued := make(chan struct{})
+	go func() {
+		for i := 0; i < 2*dispatcherQueueSize; i++ {
+			d.Enqueue("message", PriorityHigh)
+		}
+		close(enqueued)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// The loop sees the cancellation while a sender holds the queue
+	cancel()
+	close(release)
+	stopped := make(chan struct{})
+	go func() {
+		d.Stop()
+		close(stopped)
+	}()
+
+	for _, done := range []chan struct{}{enqueued, stopped} {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("dispatcher deadlocked")
+		}
+	}
+	assert.Len(t, sent, 2*dispatcherQueueSize)
+}
@@ -0,0 +1,75 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// Notifier delivers a formatted notification message
+type Notifier interface {
+	SendMessage(message string) error
+}
+
+// Notifiers sends every message to each of its notifiers, trying all of them even when one fails
+type Notifiers []Notifier
+
+// SendMessage sends the message to each notifier and returns their errors joined
+func (n Notifiers) SendMessage(message string) error {
+	var errs []error
+	for _, notifier := range n {
+		if err := notifier.SendMessage(message); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// htmlTagPattern matches the HTML tags used in Telegram message templates
+var htmlTagPattern = regexp.MustCompile(`<[^>]+>`)
+
+// WebhookNotifier posts notification messages as plain text to a chat webhook (Slack, Mattermost, Discord via /slack)
+type WebhookNotifier struct {
+	URL        string
+	httpClient *http.Client
+}
+
+// NewWebhookNotifier creates a notifier posting to the given incoming webhook URL
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		URL: url,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// plainText strips the Telegram HTML formatting of a message
+func plainText(message string) string {
+	return html.UnescapeString(htmlTagPattern.ReplaceAllString(message, ""))
+}
+
+// SendMessage strips the Telegram HTML formatting and posts the message as {"text": ...}
+func (n *WebhookNotifier) SendMessage(message string) error {
+	payload, err := json.Marshal(map[string]string{"text": plainText(message)})
+	if err != nil {
+		return fmt.Errorf("failed to marshal notifier payload: %w", err)
+	}
+
+	resp, err := n.httpClient.Post(n.URL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notification webhook returned non-2xx status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
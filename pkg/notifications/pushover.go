@@ -0,0 +1,74 @@
+package notifications
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Pushover emergency priority settings: the alert repeats every retry until acknowledged or expired
+const (
+	PushoverEmergency      = 2
+	pushoverEmergencyRetry = time.Minute
+	pushoverEmergencyTTL   = time.Hour
+)
+
+// pushoverAPIURL is the Pushover message endpoint, replaced in tests
+var pushoverAPIURL = "https://api.pushover.net/1/messages.json"
+
+// PushoverClient pushes urgent alerts to a phone through Pushover, which can ring through do-not-disturb
+type PushoverClient struct {
+	Token      string
+	User       string
+	Priority   int // -2 to 2, 2 repeats the alert until it is acknowledged
+	httpClient *http.Client
+}
+
+// NewPushoverClient creates a Pushover client, nil when the application token or user key is missing
+func NewPushoverClient(token, user string, priority int) *PushoverClient {
+	if token == "" || user == "" {
+		return nil
+	}
+	return &PushoverClient{
+		Token:    token,
+		User:     user,
+		Priority: max(min(priority, PushoverEmergency), -2),
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// Send pushes a message with the configured priority. Basic HTML tags such as <b> are rendered.
+func (p *PushoverClient) Send(title, message string) error {
+	if p == nil {
+		return nil
+	}
+
+	form := url.Values{
+		"token":    {p.Token},
+		"user":     {p.User},
+		"title":    {title},
+		"message":  {message},
+		"html":     {"1"},
+		"priority": {strconv.Itoa(p.Priority)},
+	}
+	if p.Priority == PushoverEmergency {
+		form.Set("retry", strconv.Itoa(int(pushoverEmergencyRetry.Seconds())))
+		form.Set("expire", strconv.Itoa(int(pushoverEmergencyTTL.Seconds())))
+	}
+
+	resp, err := p.httpClient.Post(pushoverAPIURL, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to send Pushover alert: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("pushover returned non-2xx status: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package notifications
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushoverSend(t *testing.T) {
+	var form map[string]string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		form = map[string]string{}
+		for key := range r.PostForm {
+			form[key] = r.PostForm.Get(key)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	defer func(url string) { pushoverAPIURL = url }(pushoverAPIURL)
+	pushoverAPIURL = server.URL
+
+	client := NewPushoverClient("app", "user", 5)
+	require.NoError(t, client.Send("Whale airdrop", "<b>BOOP</b>"))
+	assert.Equal(t, "app", form["token"])
+	assert.Equal(t, "2", form["priority"], "priority is capped at emergency")
+	assert.Equal(t, "60", form["retry"])
+	assert.Equal(t, "3600", form["expire"])
+
+	client.Priority = 1
+	require.NoError(t, client.Send("Whale airdrop", "message"))
+	assert.NotContains(t, form, "retry")
+
+	status = http.StatusBadRequest
+	assert.Error(t, client.Send("Whale airdrop", "message"))
+
+	assert.Nil(t, NewPushoverClient("", "user", 1))
+	assert.NoError(t, (*PushoverClient)(nil).Send("title", "message"))
+}
@@ -0,0 +1,64 @@
+package notifications
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Maximum lengths, in characters, of untrusted values shown in notifications
+const (
+	maxTokenNameLength   = 32
+	maxTokenSymbolLength = 12
+	maxErrorLength       = 200
+)
+
+// sanitizeText makes an untrusted string safe for Telegram HTML parse mode. Control and invisible
+// formatting characters (including RTL overrides used to spoof text) are dropped, whitespace is
+// collapsed, the result is truncated to maxLength characters and HTML special characters are escaped.
+func sanitizeText(value string, maxLength int) string {
+	var b strings.Builder
+	lastSpace := false
+	for _, r := range value {
+		switch {
+		case unicode.IsSpace(r):
+			if !lastSpace && b.Len() > 0 {
+				b.WriteRune(' ')
+			}
+			lastSpace = true
+			continue
+		case unicode.IsControl(r), unicode.Is(unicode.Cf, r), r == unicode.ReplacementChar:
+			continue
+		}
+		b.WriteRune(r)
+		lastSpace = false
+	}
+
+	runes := []rune(strings.TrimSpace(b.String()))
+	if len(runes) == 0 {
+		return "?"
+	}
+	if len(runes) > maxLength {
+		runes = append(runes[:maxLength-1], '…')
+	}
+
+	return html.EscapeString(string(runes))
+}
+
+// tokenLabel formats a token name and symbol for notifications
+func tokenLabel(tokenName, tokenSymbol string) string {
+	return sanitizeText(tokenName, maxTokenNameLength) + " (" + sanitizeText(tokenSymbol, maxTokenSymbolLength) + ")"
+}
+
+// base58Alphabet lists the characters that can appear in a transaction signature
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// solscanTxURL returns the Solscan link for a transaction signature, dropping anything that is not base58
+func solscanTxURL(txID string) string {
+	return "https://solscan.io/tx/" + strings.Map(func(r rune) rune {
+		if !strings.ContainsRune(base58Alphabet, r) {
+			return -1
+		}
+		return r
+	}, txID)
+}
@@ -2,22 +2,69 @@ package notifications
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"math"
+	"html"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"boop-airdrop-redeemer/pkg/amount"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/network"
+	"boop-airdrop-redeemer/pkg/redact"
 )
 
 // TelegramClient handles sending notifications to Telegram
 type TelegramClient struct {
-	BotToken string
-	ChatID   string
-	Enabled  bool
+	BotToken      string
+	ChatID        string
+	Enabled       bool
+	WalletLabel   string        // Prefixed to every message so chats shared by several wallets stay readable
+	Fallback      Notifier      // Optional notifier used while Telegram is unreachable
+	FallbackAfter time.Duration // How long Telegram must keep failing before the fallback is used
+	Alert         Notifier      // Optional notifier told when Telegram rejects the bot token or chat, besides the log
+	Reminder      time.Duration // How often a rejected configuration is reported again while it lasts, 0 reports it once
+
+	mu           sync.Mutex
+	failingSince time.Time
+	rejection    *rejection
+	dispatcher   *Dispatcher
 }
 
+// rejection is Telegram refusing messages for a configuration error. It is reported once, later refusals are
+// only counted and reminded of.
+type rejection struct {
+	err      error
+	since    time.Time
+	rejected int       // Messages refused since
+	reminded time.Time // When the rejection was last reported
+}
+
+// errNotConfigured is the configuration error of an enabled client without a bot token or chat ID
+var errNotConfigured = errors.New("bot token or chat ID missing")
+
+// Telegram delivery retry settings
+const (
+	telegramMaxAttempts    = 4
+	telegramInitialBackoff = time.Second
+	telegramMaxBackoff     = 30 * time.Second
+)
+
+// telegramAPIURL is the Bot API endpoint, replaced in tests
+var telegramAPIURL = "https://api.telegram.org"
+
+// telegramHTTPClient bounds each delivery attempt so retries are not stuck behind a hanging connection
+var telegramHTTPClient = &http.Client{Timeout: 10 * time.Second, Transport: network.Transport(network.Telegram)}
+
+// telegramLogger logs delivery failures of the Telegram client
+var telegramLogger = logging.For("telegram")
+
 // NewTelegramClient creates a new Telegram client
 func NewTelegramClient(botToken, chatID string, enabled bool) *TelegramClient {
 	return &TelegramClient{
@@ -27,13 +74,95 @@ func NewTelegramClient(botToken, chatID string, enabled bool) *TelegramClient {
 	}
 }
 
-// SendMessage sends a plain text message to Telegram
+// SendMessage sends a plain text message to Telegram, through the dispatch queue when it is running
 func (t *TelegramClient) SendMessage(message string) error {
+	return t.sendWithPriority(message, PriorityHigh)
+}
+
+// SendLowPriorityMessage sends a message that may be batched with other low priority messages
+func (t *TelegramClient) SendLowPriorityMessage(message string) error {
+	return t.sendWithPriority(message, PriorityLow)
+}
+
+// StartQueue routes messages through a dispatch queue preserving their order and batching low priority ones
+func (t *TelegramClient) StartQueue(ctx context.Context, batchInterval time.Duration) {
+	if !t.Enabled {
+		return
+	}
+
+	t.mu.Lock()
+	if t.dispatcher == nil {
+		t.dispatcher = NewDispatcher(t.deliver, batchInterval)
+	}
+	dispatcher := t.dispatcher
+	t.mu.Unlock()
+
+	dispatcher.Start(ctx)
+}
+
+// StopQueue delivers all queued messages; later messages are sent directly
+func (t *TelegramClient) StopQueue() {
+	t.mu.Lock()
+	dispatcher := t.dispatcher
+	t.mu.Unlock()
+
+	if dispatcher != nil {
+		dispatcher.Stop()
+	}
+}
+
+// sendWithPriority queues the message when the dispatcher exists, otherwise delivers it right away
+func (t *TelegramClient) sendWithPriority(message string, priority Priority) error {
 	if !t.Enabled || t.BotToken == "" || t.ChatID == "" {
 		return nil // Silently ignore if Telegram is not configured
 	}
 
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.BotToken)
+	// Error strings may carry secrets, mask them before anything leaves the process
+	message = redact.String(message)
+
+	t.mu.Lock()
+	dispatcher := t.dispatcher
+	t.mu.Unlock()
+
+	if dispatcher != nil {
+		dispatcher.Enqueue(message, priority)
+		return nil
+	}
+	return t.deliver(context.Background(), message)
+}
+
+// deliver sends a message to Telegram, retrying transient failures.
+// When Telegram has been unreachable for longer than FallbackAfter, the message goes to the fallback notifier.
+// Messages Telegram refuses for a wrong bot token or chat return the refusal, isConfigError tells it apart.
+// Retries stop once ctx is done.
+func (t *TelegramClient) deliver(ctx context.Context, message string) error {
+	message = t.withLabel(message)
+
+	err := t.sendWithRetries(ctx, message)
+	t.recordRejection(err)
+	if !t.recordDelivery(err) || t.Fallback == nil {
+		return err
+	}
+
+	if fallbackErr := t.Fallback.SendMessage(message); fallbackErr != nil {
+		return fmt.Errorf("%w (fallback also failed: %v)", err, fallbackErr)
+	}
+	telegramLogger.Printf("Telegram unreachable, message delivered through fallback notifier")
+	return nil
+}
+
+// withLabel prefixes a message with the wallet label, if any
+func (t *TelegramClient) withLabel(message string) string {
+	if t.WalletLabel == "" {
+		return message
+	}
+	return fmt.Sprintf("👛 <b>%s</b>\n%s", sanitizeText(t.WalletLabel, maxTokenNameLength), message)
+}
+
+// sendWithRetries posts a message, backing off between attempts and honoring Telegram's retry_after. It returns
+// ctx's error when ctx is done while waiting to retry.
+func (t *TelegramClient) sendWithRetries(ctx context.Context, message string) error {
+	url := fmt.Sprintf("%s/bot%s/sendMessage", telegramAPIURL, t.BotToken)
 
 	payload := map[string]interface{}{
 		"chat_id":                  t.ChatID,
@@ -47,136 +176,697 @@ func (t *TelegramClient) SendMessage(message string) error {
 		return fmt.Errorf("failed to marshal telegram payload: %w", err)
 	}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(payloadBytes))
+	var lastErr error
+	backoff := telegramInitialBackoff
+	for attempt := 1; attempt <= telegramMaxAttempts; attempt++ {
+		retryAfter, retryable, err := t.post(url, payloadBytes)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retryable || attempt == telegramMaxAttempts {
+			break
+		}
+
+		wait := backoff
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		if wait > telegramMaxBackoff {
+			wait = telegramMaxBackoff
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+
+	return lastErr
+}
+
+// telegramResponse is the error part of a Telegram Bot API response
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+// telegramError is a failed Bot API request
+type telegramError struct {
+	Status      int
+	Description string
+}
+
+func (e *telegramError) Error() string {
+	if e.Description == "" {
+		return fmt.Sprintf("telegram API returned non-OK status: %d", e.Status)
+	}
+	return fmt.Sprintf("telegram API returned non-OK status: %d: %s", e.Status, e.Description)
+}
+
+// isConfigError reports whether Telegram refused a request for a wrong bot token or chat, which retrying does
+// not fix: an unknown token, a chat that does not exist, or a bot that was blocked or removed from the chat.
+// Other bad requests, like a message Telegram cannot parse, are not configuration errors.
+func isConfigError(err error) bool {
+	if errors.Is(err, errNotConfigured) {
+		return true
+	}
+	var apiErr *telegramError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.Status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return true
+	case http.StatusBadRequest:
+		return strings.Contains(strings.ToLower(apiErr.Description), "chat")
+	default:
+		return false
+	}
+}
+
+// post sends one request and reports whether a failure is worth retrying and how long Telegram asked to wait
+func (t *TelegramClient) post(url string, payload []byte) (time.Duration, bool, error) {
+	resp, err := telegramHTTPClient.Post(url, "application/json", bytes.NewBuffer(payload))
 	if err != nil {
-		return fmt.Errorf("failed to send telegram message: %w", err)
+		return 0, true, fmt.Errorf("failed to send telegram message: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("telegram API returned non-OK status: %d", resp.StatusCode)
+	if resp.StatusCode == http.StatusOK {
+		return 0, false, nil
 	}
 
+	var body telegramResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return time.Duration(body.Parameters.RetryAfter) * time.Second, retryable, &telegramError{Status: resp.StatusCode, Description: body.Description}
+}
+
+// recordRejection tracks deliveries Telegram refuses for a configuration error. The first refusal is logged and
+// sent to the Alert notifier, later ones are counted and reported again every Reminder.
+func (t *TelegramClient) recordRejection(err error) {
+	if err != nil && !isConfigError(err) {
+		return
+	}
+
+	t.mu.Lock()
+	now := time.Now()
+	current := t.rejection
+	var alert string
+	switch {
+	case err == nil:
+		t.rejection = nil
+		if current != nil {
+			telegramLogger.Printf("Telegram accepts messages again, %d message(s) were rejected since %s",
+				current.rejected, current.since.Format("2006-01-02 15:04:05"))
+		}
+	case current == nil:
+		t.rejection = &rejection{err: err, since: now, rejected: 1, reminded: now}
+		logging.Warnf(telegramLogger, "Warning: Telegram rejects the bot token or chat, notifications will not arrive until "+
+			"TELEGRAM_BOT_TOKEN and TELEGRAM_CHAT_ID are fixed: %v", err)
+		alert = t.rejectionAlert(err, 0, now)
+	default:
+		current.err = err
+		current.rejected++
+		if t.Reminder > 0 && now.Sub(current.reminded) >= t.Reminder {
+			current.reminded = now
+			logging.Warnf(telegramLogger, "Warning: Telegram still rejects the bot token or chat, %d message(s) not delivered since %s: %v",
+				current.rejected, current.since.Format("2006-01-02 15:04:05"), err)
+			alert = t.rejectionAlert(err, current.rejected, current.since)
+		}
+	}
+	alertNotifier := t.Alert
+	t.mu.Unlock()
+
+	if alert != "" && alertNotifier != nil {
+		if alertErr := alertNotifier.SendMessage(alert); alertErr != nil {
+			logging.Errorf(telegramLogger, "Failed to send Telegram rejection alert: %v", alertErr)
+		}
+	}
+}
+
+// rejectionAlert is the message telling the Alert notifier that Telegram rejects the configuration, rejected
+// counts the messages lost since, 0 for the first report
+func (t *TelegramClient) rejectionAlert(err error, rejected int, since time.Time) string {
+	message := "⚠️ <b>Telegram Notifications Not Delivered</b> ⚠️\n\n"
+	if t.WalletLabel != "" {
+		message += fmt.Sprintf("👛 <b>Wallet:</b> %s\n", html.EscapeString(t.WalletLabel))
+	}
+	message += fmt.Sprintf("❌ <b>Error:</b> %s\n", html.EscapeString(err.Error()))
+	if rejected > 0 {
+		message += fmt.Sprintf("📭 <b>Not delivered:</b> %d message(s) since %s\n", rejected, since.Format("2006-01-02 15:04:05"))
+	}
+	return message + "\nCheck TELEGRAM_BOT_TOKEN and TELEGRAM_CHAT_ID, and that the bot was not blocked or removed from the chat."
+}
+
+// Verify checks the bot token with getMe and the chat with getChat. A rejected token or chat is reported like a
+// rejected message, so a misconfiguration shows up at startup instead of with the first notification. When
+// Telegram cannot be reached that is only logged, the first message checks again.
+func (t *TelegramClient) Verify() error {
+	if !t.Enabled {
+		return nil
+	}
+	err := t.verify()
+	if err != nil && !isConfigError(err) {
+		logging.Warnf(telegramLogger, "Warning: Could not verify the Telegram configuration: %v", err)
+		return err
+	}
+	t.recordRejection(err)
+	return err
+}
+
+func (t *TelegramClient) verify() error {
+	if t.BotToken == "" || t.ChatID == "" {
+		return errNotConfigured
+	}
+
+	username, err := t.BotUsername()
+	if err != nil {
+		return fmt.Errorf("bot token check failed: %w", err)
+	}
+
+	var chat struct {
+		Type string `json:"type"`
+	}
+	if err := t.callAPI(telegramHTTPClient, "getChat", map[string]any{"chat_id": t.ChatID}, &chat); err != nil {
+		return fmt.Errorf("chat %s check failed, send @%s a message or add it to the group: %w", t.ChatID, username, err)
+	}
+
+	telegramLogger.Printf("Telegram bot @%s verified for %s chat %s", username, chat.Type, t.ChatID)
 	return nil
 }
 
-// SendTokenClaimedNotification notifies about successfully claimed tokens
-func (t *TelegramClient) SendTokenClaimedNotification(tokenName, tokenSymbol, amount, usdValue, txID string) {
-	// Convert amount to a number, divide by 10^9 and format
-	amountFloat, _ := strconv.ParseFloat(amount, 64)
-	formattedAmount := fmt.Sprintf("%.2f", amountFloat/1e9)
+// BotUsername returns the username of the bot, failing when Telegram rejects the bot token
+func (t *TelegramClient) BotUsername() (string, error) {
+	var bot struct {
+		Username string `json:"username"`
+	}
+	if err := t.callAPI(telegramHTTPClient, "getMe", map[string]any{}, &bot); err != nil {
+		return "", err
+	}
+	return bot.Username, nil
+}
+
+// WaitForChat waits up to timeout for a message to the bot and returns the ID of the chat it was sent in, so a
+// chat is linked by messaging the bot instead of looking up its ID
+func (t *TelegramClient) WaitForChat(timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	var offset int64
+	for time.Now().Before(deadline) {
+		payload := map[string]any{
+			"offset":          offset,
+			"timeout":         int(min(approvalPollTimeout, time.Until(deadline)).Seconds()),
+			"allowed_updates": []string{"message"},
+		}
+		var updates []struct {
+			UpdateID int64 `json:"update_id"`
+			Message  *struct {
+				Chat struct {
+					ID int64 `json:"id"`
+				} `json:"chat"`
+			} `json:"message"`
+		}
+		if err := t.callAPI(telegramPollClient, "getUpdates", payload, &updates); err != nil {
+			return "", err
+		}
+		for _, update := range updates {
+			offset = max(offset, update.UpdateID+1)
+			if update.Message != nil {
+				return strconv.FormatInt(update.Message.Chat.ID, 10), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no message to the bot within %s", timeout)
+}
 
-	// Format USD value with 2 decimal places
-	usdFloat, _ := strconv.ParseFloat(usdValue, 64)
-	formattedUsd := fmt.Sprintf("%.2f", usdFloat)
+// recordDelivery tracks how long Telegram has been failing and reports whether the fallback should be used
+func (t *TelegramClient) recordDelivery(err error) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err == nil {
+		t.failingSince = time.Time{}
+		return false
+	}
+
+	if t.failingSince.IsZero() {
+		t.failingSince = time.Now()
+	}
+	return time.Since(t.failingSince) >= t.FallbackAfter
+}
+
+// SendTokenClaimedNotification notifies about successfully claimed tokens
+func (t *TelegramClient) SendTokenClaimedNotification(tokenName, tokenSymbol string, tokens amount.TokenAmount, usdValue float64, txID, operationID string) {
+	formattedAmount := tokens.Format(2)
+	formattedUsd := fmt.Sprintf("%.2f", usdValue)
 
 	// Format the message with emojis
 	message := fmt.Sprintf(
 		"🎉 <b>Token Claimed Successfully!</b> 🎉\n\n"+
-			"🪙 <b>Token:</b> %s (%s)\n"+
+			"🪙 <b>Token:</b> %s\n"+
 			"💰 <b>Amount:</b> %s\n"+
 			"💵 <b>USD Value:</b> $%s\n"+
 			"🕒 <b>Time:</b> %s\n"+
-			"🔗 <b>Transaction:</b> <a href=\"https://solscan.io/tx/%s\">View on Solscan</a>",
-		tokenName, tokenSymbol, formattedAmount, formattedUsd,
+			"🔗 <b>Transaction:</b> <a href=\"%s\">View on Solscan</a>%s",
+		tokenLabel(tokenName, tokenSymbol), formattedAmount, formattedUsd,
 		time.Now().Format("2006-01-02 15:04:05"),
-		txID,
+		solscanTxURL(txID), formatOperationLine(operationID),
 	)
 
 	if err := t.SendMessage(message); err != nil {
-		log.Printf("Failed to send token claimed notification: %v", err)
+		logging.Errorf(telegramLogger, "Failed to send token claimed notification: %v", err)
 	}
 }
 
-// SendTokenSoldNotification notifies about successfully sold tokens
-func (t *TelegramClient) SendTokenSoldNotification(tokenName, tokenSymbol, amount, totalProfit string, profitSummary *ProfitSummary, solPrice float64, txID string) {
-	// Convert amount to a number, divide by 10^9 and format
-	amountFloat, _ := strconv.ParseFloat(amount, 64)
-	formattedAmount := fmt.Sprintf("%.2f", amountFloat/1e9)
+// SendTokenSoldForUsdcNotification notifies about tokens sold for USDC
+func (t *TelegramClient) SendTokenSoldForUsdcNotification(tokenName, tokenSymbol string, tokens amount.TokenAmount, usdValue float64, txID, operationID string) {
+	message := fmt.Sprintf(
+		"💵 <b>Sold for USDC</b>\n\n"+
+			"🪙 <b>Token:</b> %s\n"+
+			"💰 <b>Amount Sold:</b> %s\n"+
+			"💵 <b>USD Value at Claim:</b> $%.2f\n"+
+			"🕒 <b>Time:</b> %s\n"+
+			"🔗 <b>Transaction:</b> <a href=\"%s\">View on Solscan</a>%s",
+		tokenLabel(tokenName, tokenSymbol), tokens.Format(2), usdValue,
+		time.Now().Format("2006-01-02 15:04:05"),
+		solscanTxURL(txID), formatOperationLine(operationID),
+	)
 
-	// Parse the profit value
-	profitFloat, _ := strconv.ParseFloat(totalProfit, 64)
+	if err := t.SendMessage(message); err != nil {
+		logging.Errorf(telegramLogger, "Failed to send token sold notification: %v", err)
+	}
+}
 
-	// Calculate USD equivalent of profit
-	profitUsd := profitFloat * solPrice
+// SendTokenSoldNotification notifies about successfully sold tokens
+func (t *TelegramClient) SendTokenSoldNotification(tokenName, tokenSymbol string, tokens amount.TokenAmount, netProfit float64, profitSummary *ProfitSummary, solPrice float64, txID, operationID string) {
+	formattedAmount := tokens.Format(2)
 
-	// Format the message with emojis
+	// Format the message with emojis, USD values are omitted when the SOL price is unknown
 	message := fmt.Sprintf(
 		"💎 <b>Transaction Complete!</b> 💎\n\n"+
-			"🪙 <b>Token:</b> %s (%s)\n"+
+			"🪙 <b>Token:</b> %s\n"+
 			"💰 <b>Amount Sold:</b> %s\n"+
-			"✨ <b>Net Profit:</b> %.5f SOL ($%.2f)\n"+
+			"✨ <b>Net Profit:</b> %.5f SOL%s\n"+
 			"🕒 <b>Time:</b> %s\n"+
-			"🔗 <b>Transaction:</b> <a href=\"https://solscan.io/tx/%s\">View on Solscan</a>",
-		tokenName, tokenSymbol, formattedAmount,
-		profitFloat, profitUsd,
+			"🔗 <b>Transaction:</b> <a href=\"%s\">View on Solscan</a>%s",
+		tokenLabel(tokenName, tokenSymbol), formattedAmount,
+		netProfit, formatUsdSuffix(netProfit, solPrice),
 		time.Now().Format("2006-01-02 15:04:05"),
-		txID,
+		solscanTxURL(txID), formatOperationLine(operationID),
 	)
 
 	// Add profit summary if available
 	if profitSummary != nil {
-		// Calculate USD equivalents
-		profit24hUsd := profitSummary.Last24h * solPrice
-		profitWeekUsd := profitSummary.LastWeek * solPrice
-		projectedWeekUsd := profitSummary.ProjectedWeek * solPrice
-
 		summaryText := fmt.Sprintf(
 			"\n\n📈 <b>Profit Summary:</b>\n"+
-				"• <b>Last 24h:</b> %.5f SOL ($%.2f)\n"+
-				"• <b>Last week:</b> %.5f SOL ($%.2f)\n"+
-				"• <b>Projected weekly:</b> %.5f SOL ($%.2f)",
-			profitSummary.Last24h, profit24hUsd,
-			profitSummary.LastWeek, profitWeekUsd,
-			profitSummary.ProjectedWeek, projectedWeekUsd,
+				"• <b>Last 24h:</b> %.5f SOL%s\n"+
+				"• <b>Last week:</b> %.5f SOL%s\n"+
+				"• <b>Projected weekly:</b> %.5f SOL%s",
+			profitSummary.Last24h, formatUsdSuffix(profitSummary.Last24h, solPrice),
+			profitSummary.LastWeek, formatUsdSuffix(profitSummary.LastWeek, solPrice),
+			profitSummary.ProjectedWeek, formatUsdSuffix(profitSummary.ProjectedWeek, solPrice),
 		)
 
 		message += summaryText
 	}
 
+	if solPrice <= 0 {
+		message += "\n\n<i>SOL price unavailable, USD values omitted</i>"
+	}
+
 	if err := t.SendMessage(message); err != nil {
-		log.Printf("Failed to send token sold notification: %v", err)
+		logging.Errorf(telegramLogger, "Failed to send token sold notification: %v", err)
 	}
 }
 
-// SendTokenSaleErrorNotification notifies about failures when selling tokens
-func (t *TelegramClient) SendTokenSaleErrorNotification(tokenName, tokenSymbol, amount, usdValue, errorMessage string, attempts int) {
-	// Convert amount to a number, divide by 10^9 and format
-	amountFloat, _ := strconv.ParseFloat(amount, 64)
-	formattedAmount := fmt.Sprintf("%.2f", amountFloat/1e9)
+// SendServiceFeeNotification discloses a service fee transferred to the operator after a sale
+func (t *TelegramClient) SendServiceFeeNotification(tokenSymbol string, feeSol float64, bps int, operatorWallet string, solPrice float64, txID string) {
+	message := fmt.Sprintf(
+		"🤝 <b>Service Fee Paid</b>\n\n"+
+			"🪙 <b>Sale:</b> %s\n"+
+			"💸 <b>Fee:</b> %.6f SOL%s (%.2f%% of realized SOL)\n"+
+			"👤 <b>Operator:</b> <code>%s</code>\n"+
+			"🔗 <b>Transaction:</b> <a href=\"%s\">View on Solscan</a>",
+		sanitizeText(tokenSymbol, maxTokenSymbolLength),
+		feeSol, formatUsdSuffix(feeSol, solPrice), float64(bps)/100,
+		html.EscapeString(operatorWallet),
+		solscanTxURL(txID),
+	)
+
+	if err := t.SendMessage(message); err != nil {
+		logging.Errorf(telegramLogger, "Failed to send service fee notification: %v", err)
+	}
+}
+
+// SendPoolUnderfundedNotification warns that a claim is deferred because the distributor pool holds too few tokens
+func (t *TelegramClient) SendPoolUnderfundedNotification(tokenName, tokenSymbol string, available, claimAmount amount.TokenAmount) {
+	message := fmt.Sprintf(
+		"⏸️ <b>Claim Deferred</b>\n\n"+
+			"🪙 <b>Token:</b> %s\n"+
+			"🏊 <b>Pool balance:</b> %s\n"+
+			"💰 <b>Claim amount:</b> %s\n\n"+
+			"The distributor pool is underfunded, the claim would fail on chain. It is retried every cycle until the pool is funded.",
+		tokenLabel(tokenName, tokenSymbol), available.Format(2), claimAmount.Format(2),
+	)
+
+	if err := t.SendMessage(message); err != nil {
+		logging.Errorf(telegramLogger, "Failed to send pool underfunded notification: %v", err)
+	}
+}
+
+// SendUpcomingAirdropNotification notifies about an airdrop listed for the wallet that cannot be claimed yet
+func (t *TelegramClient) SendUpcomingAirdropNotification(tokenName, tokenSymbol string, tokens amount.TokenAmount, usdValue float64) {
+	message := fmt.Sprintf(
+		"📅 <b>Upcoming Airdrop</b>\n\n"+
+			"🪙 <b>Token:</b> %s\n"+
+			"💰 <b>Amount:</b> %s\n"+
+			"💵 <b>USD Value:</b> $%.2f\n\n"+
+			"Boop lists this airdrop for your wallet but it is not claimable yet. Check the Boop app for any "+
+			"eligibility requirements, it is claimed automatically once it becomes pending.",
+		tokenLabel(tokenName, tokenSymbol), tokens.Format(2), usdValue,
+	)
+
+	if err := t.SendMessage(message); err != nil {
+		logging.Errorf(telegramLogger, "Failed to send upcoming airdrop notification: %v", err)
+	}
+}
+
+// SendWhaleAlertNotification raises a high-priority alert for a pending airdrop worth more than the whale
+// threshold, so a large drop can be handled by hand before the automation claims and sells it
+func (t *TelegramClient) SendWhaleAlertNotification(airdropID, tokenName, tokenSymbol, mint string, tokens amount.TokenAmount, usdValue, thresholdUsd float64, plan string) {
+	message := fmt.Sprintf(
+		"🐋🚨 <b>WHALE AIRDROP</b> 🚨🐋\n\n"+
+			"🪙 <b>Token:</b> %s\n"+
+			"📍 <b>Mint:</b> <code>%s</code>\n"+
+			"💰 <b>Amount:</b> %s\n"+
+			"💵 <b>USD Value:</b> $%.2f (alert above $%.2f)\n"+
+			"🆔 <b>Airdrop:</b> <code>%s</code>\n\n"+
+			"🤖 <b>Automation:</b> %s\n\n"+
+			"To handle it by hand, pause the bot through the control API or create the kill switch file.",
+		tokenLabel(tokenName, tokenSymbol), html.EscapeString(mint), tokens.Format(2), usdValue, thresholdUsd,
+		html.EscapeString(airdropID), html.EscapeString(plan),
+	)
+
+	if err := t.SendMessage(message); err != nil {
+		logging.Errorf(telegramLogger, "Failed to send whale alert notification: %v", err)
+	}
+}
 
-	// Format USD value with 2 decimal places
-	usdFloat, _ := strconv.ParseFloat(usdValue, 64)
-	formattedUsd := fmt.Sprintf("%.2f", usdFloat)
+// SendVaultTransferNotification notifies about kept tokens moved to the vault wallet
+func (t *TelegramClient) SendVaultTransferNotification(mint string, tokens amount.TokenAmount, vaultWallet, txID string) {
+	message := fmt.Sprintf(
+		"🏦 <b>Moved to Vault</b>\n\n"+
+			"🪙 <b>Token:</b> <code>%s</code>\n"+
+			"💰 <b>Amount:</b> %s\n"+
+			"👤 <b>Vault:</b> <code>%s</code>\n"+
+			"🔗 <b>Transaction:</b> <a href=\"%s\">View on Solscan</a>",
+		html.EscapeString(mint), tokens.Format(4), html.EscapeString(vaultWallet), solscanTxURL(txID),
+	)
+
+	if err := t.SendMessage(message); err != nil {
+		logging.Errorf(telegramLogger, "Failed to send vault transfer notification: %v", err)
+	}
+}
+
+// SendPositionCapNotification warns that a token position in the wallet is worth more than the position cap
+func (t *TelegramClient) SendPositionCapNotification(mint string, tokens amount.TokenAmount, usdValue, capUsd float64, sellDown bool) {
+	action := "Sell some of it to reduce the exposure to a single token."
+	if sellDown {
+		action = "It is being sold down to the cap."
+	}
+
+	message := fmt.Sprintf(
+		"📈 <b>Position Above Cap</b>\n\n"+
+			"🪙 <b>Token:</b> <code>%s</code>\n"+
+			"💰 <b>Amount:</b> %s\n"+
+			"💵 <b>Value:</b> $%.2f (cap $%.2f)\n"+
+			"%s",
+		html.EscapeString(mint), tokens.Format(2), usdValue, capUsd, action,
+	)
+
+	if err := t.SendMessage(message); err != nil {
+		logging.Errorf(telegramLogger, "Failed to send position cap notification: %v", err)
+	}
+}
+
+// SendPositionSoldDownNotification reports the sale of the part of a position above the position cap
+func (t *TelegramClient) SendPositionSoldDownNotification(mint string, tokens amount.TokenAmount, usdValue float64, txID string) {
+	message := fmt.Sprintf(
+		"✂️ <b>Position Sold Down to Cap</b>\n\n"+
+			"🪙 <b>Token:</b> <code>%s</code>\n"+
+			"💰 <b>Sold:</b> %s (about $%.2f)\n"+
+			"🔗 <b>Transaction:</b> <a href=\"%s\">View on Solscan</a>",
+		html.EscapeString(mint), tokens.Format(2), usdValue, solscanTxURL(txID),
+	)
+
+	if err := t.SendMessage(message); err != nil {
+		logging.Errorf(telegramLogger, "Failed to send position sold down notification: %v", err)
+	}
+}
+
+// SendServiceFeeDisclosure announces the service fee charged on every sale
+func (t *TelegramClient) SendServiceFeeDisclosure(bps int, operatorWallet string) {
+	message := fmt.Sprintf(
+		"🤝 <b>Service Fee Notice</b>\n\n"+
+			"This bot is run by an operator who receives <b>%.2f%%</b> of the SOL realized from each sale.\n"+
+			"👤 <b>Operator wallet:</b> <code>%s</code>\n"+
+			"Every fee transfer is reported separately and recorded in the statistics.",
+		float64(bps)/100,
+		html.EscapeString(operatorWallet),
+	)
+
+	if err := t.SendMessage(message); err != nil {
+		logging.Errorf(telegramLogger, "Failed to send service fee disclosure: %v", err)
+	}
+}
+
+// SendTokenSaleErrorNotification notifies about failures when selling tokens
+func (t *TelegramClient) SendTokenSaleErrorNotification(tokenName, tokenSymbol string, tokens amount.TokenAmount, usdValue float64, errorMessage string, attempts int, operationID string) {
+	formattedAmount := tokens.Format(2)
+	formattedUsd := fmt.Sprintf("%.2f", usdValue)
 
 	// Format the message with emojis
 	message := fmt.Sprintf(
 		"❌ <b>Token Sale Failed!</b> ❌\n\n"+
-			"🪙 <b>Token:</b> %s (%s)\n"+
+			"🪙 <b>Token:</b> %s\n"+
 			"💰 <b>Amount:</b> %s\n"+
 			"💵 <b>USD Value:</b> $%s\n"+
 			"🔄 <b>Attempts:</b> %d\n"+
 			"⚠️ <b>Error:</b> %s\n"+
+			"🕒 <b>Time:</b> %s%s",
+		tokenLabel(tokenName, tokenSymbol), formattedAmount, formattedUsd, attempts,
+		sanitizeText(errorMessage, maxErrorLength),
+		time.Now().Format("2006-01-02 15:04:05"), formatOperationLine(operationID),
+	)
+
+	if err := t.SendMessage(message); err != nil {
+		logging.Errorf(telegramLogger, "Failed to send token sale error notification: %v", err)
+	}
+}
+
+// SendSalesPausedNotification notifies once when the sales of a token are paused after failing repeatedly,
+// instead of an error notification for each failure
+func (t *TelegramClient) SendSalesPausedNotification(tokenName, tokenSymbol string, failures int, errorMessage string, until time.Time) {
+	message := fmt.Sprintf(
+		"⛔ <b>Sales Paused</b>\n\n"+
+			"🪙 <b>Token:</b> %s\n"+
+			"🔄 <b>Failed Sales in a Row:</b> %d\n"+
+			"⚠️ <b>Last Error:</b> %s\n"+
+			"⏳ <b>Retried After:</b> %s\n"+
+			"The tokens stay in the wallet, a manual sale is still possible.",
+		tokenLabel(tokenName, tokenSymbol), failures, sanitizeText(errorMessage, maxErrorLength),
+		until.Format("2006-01-02 15:04:05"),
+	)
+
+	if err := t.SendMessage(message); err != nil {
+		logging.Errorf(telegramLogger, "Failed to send sales paused notification: %v", err)
+	}
+}
+
+// SendBadQuoteNotification notifies when a sale is held back because the swap quote is far below the
+// SOL value reported by the Boop API
+func (t *TelegramClient) SendBadQuoteNotification(tokenName, tokenSymbol string, tokens amount.TokenAmount, expectedSol float64, reason string) {
+	message := fmt.Sprintf(
+		"⏸️ <b>Sale Delayed: Bad Swap Quote</b>\n\n"+
+			"🪙 <b>Token:</b> %s\n"+
+			"💰 <b>Amount:</b> %s\n"+
+			"◎ <b>Expected:</b> %.6f SOL\n"+
+			"⚠️ <b>Reason:</b> %s\n"+
+			"The tokens stay in the wallet until the quote recovers.\n"+
 			"🕒 <b>Time:</b> %s",
-		tokenName, tokenSymbol, formattedAmount, formattedUsd, attempts,
-		errorMessage,
+		tokenLabel(tokenName, tokenSymbol), tokens.Format(2), expectedSol,
+		sanitizeText(reason, maxErrorLength),
 		time.Now().Format("2006-01-02 15:04:05"),
 	)
 
 	if err := t.SendMessage(message); err != nil {
-		log.Printf("Failed to send token sale error notification: %v", err)
+		logging.Errorf(telegramLogger, "Failed to send bad quote notification: %v", err)
+	}
+}
+
+// SendUnsafeTokenNotification notifies about a pending airdrop whose token failed safety screening. skipped
+// tells whether the airdrop is not claimed because of it or only flagged.
+func (t *TelegramClient) SendUnsafeTokenNotification(tokenName, tokenSymbol, mint string, usdValue float64, failures string, skipped bool) {
+	action := "It is still claimed, check the token before its tokens are sold."
+	if skipped {
+		action = "It is not claimed while the token fails screening."
+	}
+	message := fmt.Sprintf(
+		"🚩 <b>Token Failed Safety Screening</b>\n\n"+
+			"🪙 <b>Token:</b> %s\n"+
+			"📍 <b>Mint:</b> <code>%s</code>\n"+
+			"💵 <b>USD Value:</b> $%.2f\n"+
+			"⚠️ <b>Failed:</b> %s\n\n"+
+			"%s",
+		tokenLabel(tokenName, tokenSymbol), html.EscapeString(mint), usdValue,
+		sanitizeText(failures, maxErrorLength), action,
+	)
+
+	if err := t.SendMessage(message); err != nil {
+		logging.Errorf(telegramLogger, "Failed to send unsafe token notification: %v", err)
+	}
+}
+
+// SendKillSwitchNotification notifies when the kill switch stops or resumes transaction signing
+func (t *TelegramClient) SendKillSwitchNotification(engaged bool, reason string) {
+	var message string
+	if engaged {
+		message = fmt.Sprintf(
+			"🛑 <b>Kill Switch Engaged</b> 🛑\n\n"+
+				"All claims, sales and transfers are stopped.\n"+
+				"📝 <b>Reason:</b> %s\n"+
+				"🕒 <b>Time:</b> %s",
+			sanitizeText(reason, maxErrorLength),
+			time.Now().Format("2006-01-02 15:04:05"),
+		)
+	} else {
+		message = fmt.Sprintf(
+			"✅ <b>Kill Switch Released</b>\n\n"+
+				"Signing resumes with the next cycle.\n"+
+				"🕒 <b>Time:</b> %s",
+			time.Now().Format("2006-01-02 15:04:05"),
+		)
+	}
+
+	if err := t.SendMessage(message); err != nil {
+		logging.Errorf(telegramLogger, "Failed to send kill switch notification: %v", err)
+	}
+}
+
+// SendDegradedModeNotification notifies when claiming switches to or from cached airdrop data
+func (t *TelegramClient) SendDegradedModeNotification(active bool, cachedAt time.Time) {
+	var message string
+	if active {
+		message = fmt.Sprintf(
+			"⚠️ <b>Degraded Mode: Boop API Unavailable</b> ⚠️\n\n"+
+				"📦 <b>Using cached airdrops from:</b> %s\n"+
+				"💵 USD values may be stale, claims continue with cached proofs.\n"+
+				"🕒 <b>Time:</b> %s",
+			cachedAt.Format("2006-01-02 15:04:05"),
+			time.Now().Format("2006-01-02 15:04:05"),
+		)
+	} else {
+		message = fmt.Sprintf(
+			"✅ <b>Boop API Available Again</b>\n\n"+
+				"Live airdrop data restored.\n"+
+				"🕒 <b>Time:</b> %s",
+			time.Now().Format("2006-01-02 15:04:05"),
+		)
+	}
+
+	if err := t.SendMessage(message); err != nil {
+		logging.Errorf(telegramLogger, "Failed to send degraded mode notification: %v", err)
+	}
+}
+
+// SendEfficiencyNotification notifies when the fees of the last week exceed the alert ratio of the sale
+// proceeds, or fall back below it
+func (t *TelegramClient) SendEfficiencyNotification(degraded bool, stats EfficiencyStats, alertRatio float64) {
+	var message string
+	if degraded {
+		message = fmt.Sprintf(
+			"⛽ <b>Fee Efficiency Degraded</b> ⛽\n\n"+
+				"📉 <b>Fees (7d):</b> %s of proceeds, alert above %.1f%%\n"+
+				"• <b>Sales:</b> %d\n"+
+				"• <b>Fees:</b> %.5f SOL\n"+
+				"• <b>Proceeds:</b> %.5f SOL\n\n"+
+				"Consider raising the minimum thresholds or revisiting the fee settings.\n"+
+				"🕒 <b>Time:</b> %s",
+			formatEfficiency(stats), alertRatio*100, stats.Sales, stats.FeesSol, stats.ProceedsSol,
+			time.Now().Format("2006-01-02 15:04:05"),
+		)
+	} else {
+		message = fmt.Sprintf(
+			"✅ <b>Fee Efficiency Recovered</b>\n\n"+
+				"📈 <b>Fees (7d):</b> %s of proceeds, alert above %.1f%%\n"+
+				"🕒 <b>Time:</b> %s",
+			formatEfficiency(stats), alertRatio*100,
+			time.Now().Format("2006-01-02 15:04:05"),
+		)
+	}
+
+	if err := t.SendMessage(message); err != nil {
+		logging.Errorf(telegramLogger, "Failed to send efficiency notification: %v", err)
 	}
 }
 
-// FormatTokenAmount formats a token amount with appropriate decimal places
-func FormatTokenAmount(amount float64, decimals int) string {
-	// Always divide by 10^9 to show whole tokens for Solana tokens
-	amount = amount / math.Pow10(9)
+// SendRateLimitNotification notifies when the API clients stay rate limited, and when the rate limiting ends
+func (t *TelegramClient) SendRateLimitNotification(active bool, hosts []string, since time.Time) {
+	var message string
+	if active {
+		names := make([]string, 0, len(hosts))
+		for _, host := range hosts {
+			names = append(names, "<code>"+html.EscapeString(host)+"</code>")
+		}
+		if len(names) == 0 {
+			names = append(names, "a server asking to wait")
+		}
+		message = fmt.Sprintf(
+			"🐢 <b>Rate Limited</b> 🐢\n\n"+
+				"🌐 <b>By:</b> %s\n"+
+				"⏳ <b>Since:</b> %s (%s)\n"+
+				"Scans are slowed down until the rate limits stop. Consider a dedicated RPC endpoint "+
+				"or a longer CHECK_INTERVAL.\n"+
+				"🕒 <b>Time:</b> %s",
+			strings.Join(names, ", "),
+			since.Format("2006-01-02 15:04:05"), formatDuration(time.Since(since)),
+			time.Now().Format("2006-01-02 15:04:05"),
+		)
+	} else {
+		message = fmt.Sprintf(
+			"✅ <b>Rate Limits Cleared</b>\n\n"+
+				"Scanning at the configured interval again after %s of rate limiting.\n"+
+				"🕒 <b>Time:</b> %s",
+			formatDuration(time.Since(since)),
+			time.Now().Format("2006-01-02 15:04:05"),
+		)
+	}
+
+	if err := t.SendMessage(message); err != nil {
+		logging.Errorf(telegramLogger, "Failed to send rate limit notification: %v", err)
+	}
+}
 
-	// Format with 2 decimal places
-	formatted := fmt.Sprintf("%.2f", amount)
+// formatUsdSuffix returns " ($x.xx)" for a SOL amount, or an empty string when the SOL price is unknown
+func formatUsdSuffix(solAmount, solPrice float64) string {
+	if solPrice <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" ($%.2f)", solAmount*solPrice)
+}
 
-	return formatted
+// formatOperationLine returns the line naming the operation of a claim or sale, empty when there is none
+func formatOperationLine(operationID string) string {
+	if operationID == "" {
+		return ""
+	}
+	return fmt.Sprintf("\n🧭 <b>Operation:</b> <code>%s</code>", html.EscapeString(operationID))
 }
 
 // SendWelcomeMessage sends an initial welcome message with bot information and settings
@@ -204,7 +894,16 @@ func (t *TelegramClient) SendWelcomeMessage(walletAddress string, minimumUsdThre
 	)
 
 	if err := t.SendMessage(message); err != nil {
-		log.Printf("Failed to send welcome message: %v", err)
+		logging.Errorf(telegramLogger, "Failed to send welcome message: %v", err)
+	}
+}
+
+// SendConfigSummary sends the effective configuration summary, one line per setting
+func (t *TelegramClient) SendConfigSummary(lines []string) {
+	message := "🧾 <b>Effective Configuration</b>\n\n<pre>" + html.EscapeString(strings.Join(lines, "\n")) + "</pre>"
+
+	if err := t.SendMessage(message); err != nil {
+		logging.Errorf(telegramLogger, "Failed to send configuration summary: %v", err)
 	}
 }
 
@@ -242,12 +941,23 @@ func (t *TelegramClient) SendHelpMessage() {
 	)
 
 	if err := t.SendMessage(message); err != nil {
-		log.Printf("Failed to send help message: %v", err)
+		logging.Errorf(telegramLogger, "Failed to send help message: %v", err)
 	}
 }
 
-// SendStatusMessage sends a status update about the bot's operation
-func (t *TelegramClient) SendStatusMessage(claimedCount int, scannedCount int, uptime time.Duration, lastCheckTime time.Time) {
+// CongestionStatus describes Solana network congestion and the claims deferred because of it
+type CongestionStatus struct {
+	Congested      bool
+	PriorityFee    uint64 // micro-lamports per CU
+	BaselineFee    uint64 // micro-lamports per CU
+	SlotTime       time.Duration
+	Since          time.Time
+	DeferredClaims int
+}
+
+// SendStatusMessage sends a status update about the bot's operation. A nil congestion status
+// means congestion based scheduling is disabled.
+func (t *TelegramClient) SendStatusMessage(claimedCount int, scannedCount int, uptime time.Duration, lastCheckTime time.Time, congestion *CongestionStatus) {
 	// Format the status message
 	message := fmt.Sprintf(
 		"📊 <b>Bot Status Update</b> 📊\n\n"+
@@ -256,18 +966,72 @@ func (t *TelegramClient) SendStatusMessage(claimedCount int, scannedCount int, u
 			"✅ <b>Airdrops claimed:</b> %d\n"+
 			"🕒 <b>Last check:</b> %s\n\n"+
 			"🤖 <b>System:</b> Running normally\n"+
+			"%s"+
 			"🔄 <b>Next check:</b> In progress...",
 		formatDuration(uptime),
 		scannedCount,
 		claimedCount,
 		lastCheckTime.Format("2006-01-02 15:04:05"),
+		formatCongestionLine(congestion),
 	)
 
-	if err := t.SendMessage(message); err != nil {
-		log.Printf("Failed to send status message: %v", err)
+	if err := t.SendLowPriorityMessage(message); err != nil {
+		logging.Errorf(telegramLogger, "Failed to send status message: %v", err)
 	}
 }
 
+// SendCongestionNotification notifies when claims start or stop being deferred because of network congestion
+func (t *TelegramClient) SendCongestionNotification(status CongestionStatus) {
+	var message string
+	if status.Congested {
+		message = fmt.Sprintf(
+			"🚦 <b>Network Congested</b>\n\n"+
+				"💸 <b>Priority fees:</b> %d µlamports/CU (baseline %d)\n"+
+				"%s"+
+				"⏸️ Non-urgent claims are deferred until fees normalize.\n"+
+				"🕒 <b>Time:</b> %s",
+			status.PriorityFee,
+			status.BaselineFee,
+			formatSlotTime(status.SlotTime),
+			time.Now().Format("2006-01-02 15:04:05"),
+		)
+	} else {
+		message = fmt.Sprintf(
+			"🟢 <b>Network Back to Normal</b>\n\n"+
+				"💸 <b>Priority fees:</b> %d µlamports/CU\n"+
+				"▶️ Deferred claims resume now (%d waiting).\n"+
+				"🕒 <b>Time:</b> %s",
+			status.PriorityFee,
+			status.DeferredClaims,
+			time.Now().Format("2006-01-02 15:04:05"),
+		)
+	}
+
+	if err := t.SendLowPriorityMessage(message); err != nil {
+		logging.Errorf(telegramLogger, "Failed to send congestion notification: %v", err)
+	}
+}
+
+// formatCongestionLine returns the network line of the status message
+func formatCongestionLine(congestion *CongestionStatus) string {
+	if congestion == nil {
+		return ""
+	}
+	if !congestion.Congested {
+		return fmt.Sprintf("🟢 <b>Network:</b> Normal (%d µlamports/CU)\n", congestion.PriorityFee)
+	}
+	return fmt.Sprintf("🚦 <b>Network:</b> Congested since %s (%d µlamports/CU, baseline %d), %d claim(s) deferred\n",
+		congestion.Since.Format("15:04"), congestion.PriorityFee, congestion.BaselineFee, congestion.DeferredClaims)
+}
+
+// formatSlotTime returns the slot time line of congestion messages, or nothing when it is unknown
+func formatSlotTime(slotTime time.Duration) string {
+	if slotTime <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("🐢 <b>Slot time:</b> %s\n", slotTime.Round(time.Millisecond))
+}
+
 // formatDuration formats a duration in a human-readable way
 func formatDuration(d time.Duration) string {
 	days := int(d.Hours() / 24)
@@ -282,6 +1046,144 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%dm", minutes)
 }
 
+// RealizationStats compares expected and realized USD values for a group of sold airdrops
+type RealizationStats struct {
+	Count       int
+	ExpectedUsd float64
+	RealizedUsd float64
+}
+
+// EfficiencyStats compares the fees spent on claiming and selling with the proceeds of the sales, in SOL
+type EfficiencyStats struct {
+	Sales       int
+	FeesSol     float64
+	ProceedsSol float64
+}
+
+// GainStats sums the gains realized on kept tokens, in USD
+type GainStats struct {
+	Disposals   int
+	BasisUsd    float64
+	ProceedsUsd float64
+}
+
+// formatEfficiency formats fees as a share of proceeds, "n/a" when nothing was spent or sold
+func formatEfficiency(stats EfficiencyStats) string {
+	if stats.ProceedsSol <= 0 {
+		if stats.FeesSol > 0 {
+			return "fees without proceeds"
+		}
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f%%", stats.FeesSol/stats.ProceedsSol*100)
+}
+
+// digestTokens is the number of tokens listed in the weekly digest's realization by token, the most valuable first
+const digestTokens = 10
+
+// SendWeeklyDigest sends a weekly summary of profit and how much of the expected airdrop value was realized
+// vaultTransfers counts the week's vault transfers per token mint, efficiency is nil when it is unknown.
+func (t *TelegramClient) SendWeeklyDigest(profitSummary *ProfitSummary, total RealizationStats, byStrategy, byToken map[string]RealizationStats, vaultTransfers map[string]int, efficiency *EfficiencyStats, gains *GainStats) {
+	message := "📅 <b>Weekly Digest</b> 📅\n"
+
+	if profitSummary != nil {
+		message += fmt.Sprintf("\n✨ <b>Net profit (7d):</b> %.5f SOL\n", profitSummary.LastWeek)
+	}
+
+	if efficiency != nil && efficiency.Sales > 0 {
+		message += fmt.Sprintf("⛽ <b>Fees:</b> %s of proceeds (%.5f of %.5f SOL)\n",
+			formatEfficiency(*efficiency), efficiency.FeesSol, efficiency.ProceedsSol)
+	}
+
+	if total.Count == 0 {
+		message += "\n📊 No sales with known expected and realized value this week."
+	} else {
+		message += fmt.Sprintf(
+			"\n📊 <b>Realization:</b> %s of expected\n"+
+				"• <b>Sales:</b> %d\n"+
+				"• <b>Expected:</b> $%.2f\n"+
+				"• <b>Realized:</b> $%.2f\n",
+			formatRealizationRatio(total), total.Count, total.ExpectedUsd, total.RealizedUsd,
+		)
+
+		strategies := make([]string, 0, len(byStrategy))
+		for strategy := range byStrategy {
+			strategies = append(strategies, strategy)
+		}
+		sort.Strings(strategies)
+
+		if len(strategies) > 0 {
+			message += "\n🧭 <b>By strategy:</b>\n"
+		}
+		for _, strategy := range strategies {
+			stats := byStrategy[strategy]
+			message += fmt.Sprintf("• <b>%s:</b> %s (%d sales, $%.2f of $%.2f)\n",
+				html.EscapeString(strategy), formatRealizationRatio(stats), stats.Count, stats.RealizedUsd, stats.ExpectedUsd)
+		}
+
+		message += formatRealizationByToken(byToken)
+	}
+
+	if gains != nil && gains.Disposals > 0 {
+		message += fmt.Sprintf("\n💰 <b>Kept tokens:</b> %+.2f USD realized on %d disposal(s), $%.2f against a basis of $%.2f\n",
+			gains.ProceedsUsd-gains.BasisUsd, gains.Disposals, gains.ProceedsUsd, gains.BasisUsd)
+	}
+
+	if len(vaultTransfers) > 0 {
+		mints := make([]string, 0, len(vaultTransfers))
+		for mint := range vaultTransfers {
+			mints = append(mints, mint)
+		}
+		sort.Strings(mints)
+
+		message += "\n🏦 <b>Moved to vault:</b>\n"
+		for _, mint := range mints {
+			message += fmt.Sprintf("• <code>%s</code>: %d transfer(s)\n", html.EscapeString(mint), vaultTransfers[mint])
+		}
+	}
+
+	if err := t.SendLowPriorityMessage(message); err != nil {
+		logging.Errorf(telegramLogger, "Failed to send weekly digest: %v", err)
+	}
+}
+
+// formatRealizationByToken lists the realization of the tokens with the highest expected value
+func formatRealizationByToken(byToken map[string]RealizationStats) string {
+	if len(byToken) == 0 {
+		return ""
+	}
+
+	tokens := make([]string, 0, len(byToken))
+	for token := range byToken {
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if byToken[tokens[i]].ExpectedUsd != byToken[tokens[j]].ExpectedUsd {
+			return byToken[tokens[i]].ExpectedUsd > byToken[tokens[j]].ExpectedUsd
+		}
+		return tokens[i] < tokens[j]
+	})
+
+	message := "\n🪙 <b>By token:</b>\n"
+	for _, token := range tokens[:min(len(tokens), digestTokens)] {
+		stats := byToken[token]
+		message += fmt.Sprintf("• <b>%s:</b> %s (%d sales, $%.2f of $%.2f)\n",
+			html.EscapeString(token), formatRealizationRatio(stats), stats.Count, stats.RealizedUsd, stats.ExpectedUsd)
+	}
+	if len(tokens) > digestTokens {
+		message += fmt.Sprintf("• … and %d more\n", len(tokens)-digestTokens)
+	}
+	return message
+}
+
+// formatRealizationRatio formats realized over expected value as a percentage
+func formatRealizationRatio(stats RealizationStats) string {
+	if stats.ExpectedUsd <= 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.0f%%", stats.RealizedUsd/stats.ExpectedUsd*100)
+}
+
 // ProfitSummary contains summary profit statistics
 type ProfitSummary struct {
 	Last24h       float64 // Profit in SOL for last 24 hours
@@ -0,0 +1,112 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingNotifier keeps the messages it is sent
+type recordingNotifier struct {
+	messages []string
+}
+
+func (n *recordingNotifier) SendMessage(message string) error {
+	n.messages = append(n.messages, message)
+	return nil
+}
+
+func TestTelegramRejectedChatIsReportedOnce(t *testing.T) {
+	var chatFixed atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/getMe"):
+			fmt.Fprint(w, `{"ok":true,"result":{"username":"boop_bot"}}`)
+		case chatFixed.Load():
+			fmt.Fprint(w, `{"ok":true,"result":{"type":"private"}}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"ok":false,"description":"Bad Request: chat not found"}`)
+		}
+	}))
+	defer server.Close()
+	defer func(url string) { telegramAPIURL = url }(telegramAPIURL)
+	telegramAPIURL = server.URL
+
+	alerts := &recordingNotifier{}
+	client := NewTelegramClient("token", "123", true)
+	client.Alert = alerts
+
+	err := client.Verify()
+	require.Error(t, err)
+	assert.True(t, isConfigError(err))
+	assert.Contains(t, err.Error(), "chat not found")
+	require.Len(t, alerts.messages, 1)
+
+	// Messages refused for the same reason are counted without another alert, the refusal is still returned
+	err = client.SendMessage("first")
+	assert.True(t, isConfigError(err))
+	assert.Error(t, client.SendMessage("second"))
+	assert.Len(t, alerts.messages, 1)
+
+	// Reminders repeat the alert with the count of messages lost
+	client.Reminder = 1
+	assert.Error(t, client.SendMessage("third"))
+	require.Len(t, alerts.messages, 2)
+	assert.Contains(t, alerts.messages[1], "4 message(s)")
+
+	chatFixed.Store(true)
+	assert.NoError(t, client.SendMessage("delivered"))
+	assert.Nil(t, client.rejection)
+}
+
+func TestIsConfigError(t *testing.T) {
+	assert.True(t, isConfigError(&telegramError{Status: http.StatusUnauthorized}))
+	assert.True(t, isConfigError(&telegramError{Status: http.StatusForbidden, Description: "Forbidden: bot was blocked by the user"}))
+	assert.True(t, isConfigError(fmt.Errorf("verify: %w", errNotConfigured)))
+	assert.False(t, isConfigError(&telegramError{Status: http.StatusBadRequest, Description: "Bad Request: can't parse entities"}))
+	assert.False(t, isConfigError(&telegramError{Status: http.StatusTooManyRequests}))
+}
+
+func TestFormatRealizationByToken(t *testing.T) {
+	byToken := map[string]RealizationStats{"<B>": {Count: 1, ExpectedUsd: 5, RealizedUsd: 4}}
+	for i := 0; i < digestTokens; i++ {
+		byToken[fmt.Sprintf("T%02d", i)] = RealizationStats{Count: 1, ExpectedUsd: float64(i) / 10, RealizedUsd: float64(i) / 20}
+	}
+
+	message := formatRealizationByToken(byToken)
+	assert.Contains(t, message, "• <b>&lt;B&gt;:</b> 80% (1 sales, $4.00 of $5.00)")
+	assert.NotContains(t, message, "T00", "the least valuable token is left out")
+	assert.Contains(t, message, "… and 1 more")
+	assert.Empty(t, formatRealizationByToken(nil))
+}
+
+func TestTelegramRetryWaitStopsOnContextCancel(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"ok":false,"description":"Too Many Requests","parameters":{"retry_after":30}}`)
+	}))
+	defer server.Close()
+	defer func(url string) { telegramAPIURL = url }(telegramAPIURL)
+	telegramAPIURL = server.URL
+
+	client := NewTelegramClient("token", "123", true)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := client.sendWithRetries(ctx, "message")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), 5*time.Second, "the retry_after wait is cut short")
+	assert.EqualValues(t, 1, requests.Load())
+}
@@ -0,0 +1,305 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/retry"
+)
+
+// Webhook event types
+const (
+	EventClaimConfirmed = "claim.confirmed"
+	EventSellConfirmed  = "sell.confirmed"
+)
+
+// Webhook request headers
+const (
+	WebhookSignatureHeader = "X-Boop-Signature"
+	WebhookTimestampHeader = "X-Boop-Timestamp"
+	WebhookEventHeader     = "X-Boop-Event"
+)
+
+const (
+	// webhookQueueSize is the number of deliveries that can wait before senders block
+	webhookQueueSize = 100
+	// webhookAttempts is how often a delivery failing with a network, rate limit or server error is tried
+	webhookAttempts = 4
+)
+
+// webhookLogger logs failed webhook deliveries
+var webhookLogger = logging.For("webhook")
+
+// webhookDelivery is a signed payload waiting to be posted to one URL
+type webhookDelivery struct {
+	url       string
+	event     string
+	timestamp string
+	signature string
+	body      []byte
+}
+
+// WebhookEvent is the JSON payload delivered to webhook endpoints
+type WebhookEvent struct {
+	Event            string    `json:"event"`
+	Timestamp        time.Time `json:"timestamp"`
+	Wallet           string    `json:"wallet"`
+	WalletLabel      string    `json:"walletLabel,omitempty"`
+	AirdropID        string    `json:"airdropId"`
+	TokenName        string    `json:"tokenName"`
+	TokenSymbol      string    `json:"tokenSymbol"`
+	TokenMint        string    `json:"tokenMint"`
+	Amount           string    `json:"amount"`   // Raw token amount
+	UsdValue         string    `json:"usdValue"` // USD value reported by the Boop API
+	TxHash           string    `json:"txHash"`
+	FeesLamports     uint64    `json:"feesLamports"`
+	EarningsLamports uint64    `json:"earningsLamports,omitempty"`
+	OperationID      string    `json:"operationId,omitempty"` // Claim or sale the event belongs to
+}
+
+// WebhookClient delivers signed event payloads to external HTTP endpoints
+type WebhookClient struct {
+	URLs        []string
+	Secret      string
+	Enabled     bool
+	WalletLabel string // Added to events that don't carry a label yet
+	httpClient  *http.Client
+	retrier     *retry.Retrier
+
+	// In-process subscribers, called with every event whether or not URLs are configured
+	mu          sync.Mutex
+	subscribers map[int]func(WebhookEvent)
+	nextID      int
+
+	// Delivery queue, deliveries are posted by the caller while it is not running
+	queue   chan webhookDelivery
+	running bool
+	cancel  context.CancelFunc
+	done    <-chan struct{} // Closed when the delivery loop is told to stop
+	wg      sync.WaitGroup
+}
+
+// NewWebhookClient creates a new webhook client, enabled when at least one URL is configured
+func NewWebhookClient(urls []string, secret string) *WebhookClient {
+	return &WebhookClient{
+		URLs:    urls,
+		Secret:  secret,
+		Enabled: len(urls) > 0,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		retrier: retry.NewDefault(webhookAttempts, 0, webhookLogger),
+		queue:   make(chan webhookDelivery, webhookQueueSize),
+	}
+}
+
+// StartQueue delivers events from a background goroutine until ctx is cancelled or StopQueue is called, so slow
+// endpoints and their retries don't hold up the claims and sales sending them
+func (w *WebhookClient) StartQueue(ctx context.Context) {
+	if w == nil || !w.Enabled {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.running {
+		return
+	}
+
+	ctx, w.cancel = context.WithCancel(ctx)
+	w.done = ctx.Done()
+	w.running = true
+
+	w.wg.Add(1)
+	go w.run(ctx)
+}
+
+// StopQueue tries every queued delivery once more and stops the delivery loop; later events are delivered
+// directly. Safe to call more than once.
+func (w *WebhookClient) StopQueue() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	w.running = false
+	cancel := w.cancel
+	w.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	w.wg.Wait()
+}
+
+// Subscribe calls fn with every event sent from now on, for programs embedding the redeemer. fn runs on the
+// sending goroutine and must not block. The returned function removes the subscription.
+func (w *WebhookClient) Subscribe(fn func(WebhookEvent)) func() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.subscribers == nil {
+		w.subscribers = make(map[int]func(WebhookEvent))
+	}
+	id := w.nextID
+	w.nextID++
+	w.subscribers[id] = fn
+
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.subscribers, id)
+	}
+}
+
+// Send passes an event to the subscribers and queues its delivery to every configured URL. Without a running
+// queue the event is delivered directly, returning the last delivery error.
+func (w *WebhookClient) Send(event WebhookEvent) error {
+	if w == nil {
+		return nil
+	}
+
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	if event.WalletLabel == "" {
+		event.WalletLabel = w.WalletLabel
+	}
+
+	w.mu.Lock()
+	subscribers := make([]func(WebhookEvent), 0, len(w.subscribers))
+	for _, fn := range w.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	w.mu.Unlock()
+	for _, fn := range subscribers {
+		fn(event)
+	}
+
+	if !w.Enabled {
+		return nil // Silently ignore if webhooks are not configured
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(event.Timestamp.Unix(), 10)
+	signature := SignWebhookPayload(w.Secret, timestamp, body)
+
+	var lastErr error
+	for _, url := range w.URLs {
+		delivery := webhookDelivery{url: url, event: event.Event, timestamp: timestamp, signature: signature, body: body}
+		if err := w.enqueue(delivery); err != nil {
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+// enqueue queues a delivery, or delivers it right away while the queue is not running
+func (w *WebhookClient) enqueue(delivery webhookDelivery) error {
+	w.mu.Lock()
+	if !w.running {
+		w.mu.Unlock()
+		return w.deliver(context.Background(), delivery)
+	}
+	// Holding the lock keeps the loop from draining before the delivery is queued, as in Dispatcher.Enqueue
+	select {
+	case w.queue <- delivery:
+		w.mu.Unlock()
+		return nil
+	case <-w.done:
+		w.mu.Unlock()
+		return w.deliver(context.Background(), delivery)
+	}
+}
+
+// run delivers queued events until ctx is cancelled, then tries the rest once
+func (w *WebhookClient) run(ctx context.Context) {
+	defer w.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.mu.Lock()
+			w.running = false
+			w.mu.Unlock()
+
+			for {
+				select {
+				case delivery := <-w.queue:
+					w.deliver(ctx, delivery)
+				default:
+					return
+				}
+			}
+		case delivery := <-w.queue:
+			w.deliver(ctx, delivery)
+		}
+	}
+}
+
+// deliver posts a delivery, retrying network, rate limit and server errors until the attempts run out or ctx
+// is done, and logs when it could not be delivered
+func (w *WebhookClient) deliver(ctx context.Context, delivery webhookDelivery) error {
+	err := w.retrier.Do(ctx, delivery.event+" webhook", func() error {
+		return w.post(delivery)
+	})
+	if err != nil {
+		logging.Errorf(webhookLogger, "Failed to deliver %s webhook to %s: %v", delivery.event, delivery.url, err)
+	}
+	return err
+}
+
+// post sends a signed payload to its URL once
+func (w *WebhookClient) post(delivery webhookDelivery) error {
+	req, err := http.NewRequest("POST", delivery.url, bytes.NewReader(delivery.body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, delivery.event)
+	req.Header.Set(WebhookTimestampHeader, delivery.timestamp)
+	if delivery.signature != "" {
+		req.Header.Set(WebhookSignatureHeader, "sha256="+delivery.signature)
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook endpoint returned non-2xx status: %w", &retry.StatusError{Code: resp.StatusCode})
+	}
+
+	return nil
+}
+
+// SignWebhookPayload computes the hex HMAC-SHA256 of "<timestamp>.<body>" with the shared secret.
+// Receivers should recompute it and compare against the X-Boop-Signature header.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	if secret == "" {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
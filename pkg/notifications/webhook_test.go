@@ -0,0 +1,71 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"boop-airdrop-redeemer/pkg/retry"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	body := []byte(`{"event":"claim.confirmed"}`)
+	assert.Equal(t, "b5ab9fdf2f67d2ec2a39f5c7b7067bac42a9b29b3c33d99dbff09502c2249459",
+		SignWebhookPayload("secret", "1700000000", body))
+	assert.Empty(t, SignWebhookPayload("", "1700000000", body), "payloads are not signed without a secret")
+}
+
+func TestWebhookDeliveryRetriesFromQueue(t *testing.T) {
+	var attempts atomic.Int32
+	delivered := make(chan *http.Request, 1)
+	var payload WebhookEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(body, &payload))
+		assert.Equal(t, "sha256="+SignWebhookPayload("secret", r.Header.Get(WebhookTimestampHeader), body),
+			r.Header.Get(WebhookSignatureHeader))
+		delivered <- r
+	}))
+	defer server.Close()
+
+	client := NewWebhookClient([]string{server.URL}, "secret")
+	client.WalletLabel = "main"
+	client.retrier = retry.New(retry.Policies{retry.Unavailable: {MaxAttempts: 2}}, nil, nil)
+	client.StartQueue(context.Background())
+	defer client.StopQueue()
+
+	timestamp := time.Unix(1700000000, 0).UTC()
+	require.NoError(t, client.Send(WebhookEvent{
+		Event:     EventClaimConfirmed,
+		Timestamp: timestamp,
+		AirdropID: "airdrop-1",
+		TxHash:    "tx-1",
+	}))
+
+	select {
+	case r := <-delivered:
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, EventClaimConfirmed, r.Header.Get(WebhookEventHeader))
+		assert.Equal(t, strconv.FormatInt(timestamp.Unix(), 10), r.Header.Get(WebhookTimestampHeader))
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+	assert.EqualValues(t, 2, attempts.Load(), "a server error is retried")
+	assert.Equal(t, "airdrop-1", payload.AirdropID)
+	assert.Equal(t, "tx-1", payload.TxHash)
+	assert.Equal(t, "main", payload.WalletLabel)
+}
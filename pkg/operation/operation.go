@@ -0,0 +1,61 @@
+// Package operation correlates everything a single claim or sale of an airdrop produces. An operation ID is
+// created when the claim or sale starts and carried in its context, and log lines, stats rows, webhook events
+// and Telegram messages of that airdrop are tagged with it.
+package operation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"strconv"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/logging"
+)
+
+// LogField is the log field holding the operation ID
+const LogField = "op"
+
+type contextKey struct{}
+
+// NewID returns a random operation ID of 12 hex characters
+func NewID() string {
+	var b [6]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// Still unique enough to correlate the outputs of one process
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// Start returns ctx carrying a new operation ID and the ID. A context that already carries one is returned
+// unchanged, so a sale started by a claim stays part of the claim's operation.
+func Start(ctx context.Context) (context.Context, string) {
+	if id := ID(ctx); id != "" {
+		return ctx, id
+	}
+	id := NewID()
+	return WithID(ctx, id), id
+}
+
+// WithID returns ctx carrying the operation ID
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// ID returns the operation ID carried by ctx, empty when there is none
+func ID(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Logger returns the logger with the operation ID of ctx as a field. The logger is returned unchanged when
+// ctx carries no operation.
+func Logger(ctx context.Context, logger *log.Logger) *log.Logger {
+	id := ID(ctx)
+	if id == "" {
+		return logger
+	}
+	return logging.With(logger, LogField, id)
+}
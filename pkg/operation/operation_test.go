@@ -0,0 +1,45 @@
+package operation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"boop-airdrop-redeemer/pkg/logging"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartKeepsExistingOperation(t *testing.T) {
+	ctx, id := Start(context.Background())
+	assert.Len(t, id, 12)
+	assert.Equal(t, id, ID(ctx))
+
+	// A sale started within a claim keeps the claim's ID
+	saleCtx, saleID := Start(ctx)
+	assert.Equal(t, id, saleID)
+	assert.Equal(t, ctx, saleCtx)
+
+	_, otherID := Start(context.Background())
+	assert.NotEqual(t, id, otherID)
+}
+
+func TestLoggerCarriesOperationID(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	var out bytes.Buffer
+	assert.NoError(t, logging.Setup(&out, logging.FormatJSON, "info"))
+
+	logger := logging.For("claimer")
+	assert.Same(t, logger, Logger(context.Background(), logger))
+
+	Logger(WithID(context.Background(), "a1b2c3d4e5f6"), logger).Println("Claiming airdrop")
+
+	var record map[string]any
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &record))
+	assert.Equal(t, "a1b2c3d4e5f6", record[LogField])
+	assert.Equal(t, "claimer", record["component"])
+}
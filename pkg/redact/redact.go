@@ -0,0 +1,118 @@
+// Package redact masks secrets in log output and notifications. Secret values are registered as they are
+// read or refreshed, and common token formats are masked even when they were never registered.
+//
+// Importing the package routes the standard logger through a redacting writer.
+package redact
+
+import (
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces every redacted value
+const Mask = "[REDACTED]"
+
+// minSecretLength keeps short values, which would mask unrelated text, from being registered
+const minSecretLength = 8
+
+// maxSecrets bounds the registered values. Tokens are registered again on every refresh, once the limit is
+// reached the oldest, long expired ones are dropped.
+const maxSecrets = 256
+
+// patterns mask secrets by their format, each keeps its first group
+var patterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-_.~+/]+=*`),
+	regexp.MustCompile(`()eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]*`),
+	regexp.MustCompile(`(?i)("(?:refresh_token|access_token|privy_access_token|identity_token|token|private_?key)"\s*:\s*")[^"]*`),
+	regexp.MustCompile(`(bot\d{6,}:)[A-Za-z0-9_-]{30,}`),
+}
+
+var (
+	mu       sync.RWMutex
+	secrets  []string            // Oldest first
+	known    = map[string]bool{} // The values in secrets
+	replacer *strings.Replacer   // Masks secrets, nil before the first is registered
+)
+
+// Stdout is standard output with secrets redacted
+var Stdout io.Writer = NewWriter(os.Stdout)
+
+func init() {
+	log.SetOutput(NewWriter(log.Writer()))
+}
+
+// AddSecret registers values to mask wherever they appear. Empty, very short and already registered values are
+// ignored.
+func AddSecret(values ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	added := false
+	for _, value := range values {
+		value = strings.TrimSpace(strings.TrimPrefix(value, "Bearer "))
+		if len(value) < minSecretLength || known[value] {
+			continue
+		}
+		secrets = append(secrets, value)
+		known[value] = true
+		added = true
+	}
+	if !added {
+		return
+	}
+	for len(secrets) > maxSecrets {
+		delete(known, secrets[0])
+		secrets = secrets[1:]
+	}
+
+	// At the same position the replacer picks the first matching value, longest first masks a secret
+	// containing another whole
+	longestFirst := append([]string(nil), secrets...)
+	sort.SliceStable(longestFirst, func(i, j int) bool { return len(longestFirst[i]) > len(longestFirst[j]) })
+	pairs := make([]string, 0, 2*len(longestFirst))
+	for _, secret := range longestFirst {
+		pairs = append(pairs, secret, Mask)
+	}
+	replacer = strings.NewReplacer(pairs...)
+}
+
+// String masks registered secrets and known secret formats in s
+func String(s string) string {
+	mu.RLock()
+	if replacer != nil {
+		s = replacer.Replace(s)
+	}
+	mu.RUnlock()
+
+	for _, pattern := range patterns {
+		s = pattern.ReplaceAllString(s, "${1}"+Mask)
+	}
+	return s
+}
+
+// writer redacts everything written through it
+type writer struct {
+	w io.Writer
+}
+
+// NewWriter returns a writer masking secrets before writing to w. Loggers write each entry in one call,
+// so secrets are never split across writes.
+func NewWriter(w io.Writer) io.Writer {
+	if _, ok := w.(*writer); ok {
+		return w
+	}
+	return &writer{w: w}
+}
+
+// Write writes the redacted p, reporting the length of p so callers never see a short write
+func (r *writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
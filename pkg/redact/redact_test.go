@@ -0,0 +1,58 @@
+package redact
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringMasksRegisteredSecrets(t *testing.T) {
+	AddSecret("5KQwrPbwdL6PhXujxW37FSSQZ1JiwsST4cqQzDeyXtP", "short")
+
+	masked := String("invalid private key 5KQwrPbwdL6PhXujxW37FSSQZ1JiwsST4cqQzDeyXtP for short")
+	assert.Equal(t, "invalid private key [REDACTED] for short", masked)
+}
+
+func TestAddSecretDeduplicatesAndBounds(t *testing.T) {
+	AddSecret("duplicate-secret", "duplicate-secret", "duplicate-secret-longer")
+	mu.RLock()
+	assert.Equal(t, len(secrets), len(known))
+	mu.RUnlock()
+	assert.Equal(t, "[REDACTED] and [REDACTED]", String("duplicate-secret-longer and duplicate-secret"))
+
+	for i := 0; i <= maxSecrets; i++ {
+		AddSecret(fmt.Sprintf("rotated-token-%04d", i))
+	}
+	mu.RLock()
+	assert.Len(t, secrets, maxSecrets)
+	mu.RUnlock()
+	assert.Equal(t, "rotated-token-0000", String("rotated-token-0000"), "the oldest secret is dropped")
+	assert.Equal(t, Mask, String(fmt.Sprintf("rotated-token-%04d", maxSecrets)))
+}
+
+func TestStringMasksSecretFormats(t *testing.T) {
+	tests := map[string]string{
+		"authorization: Bearer abc.def-123":                                                                  "authorization: Bearer [REDACTED]",
+		"token eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.sig_value":                                               "token [REDACTED]",
+		`{"refresh_token":"r-123","user":"me"}`:                                                              `{"refresh_token":"[REDACTED]","user":"me"}`,
+		"post https://api.telegram.org/bot123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw/sendMessage":          "post https://api.telegram.org/bot123456789:[REDACTED]/sendMessage",
+		"signature 5VERv8NMvzbJMEkV8xnrLkEaWRtSz9CosKDYjCJjBRnbJLgp8uirBgmQpjKhoR4tjF3ZpRzrFmBV6UjKdiSZkQUW": "signature 5VERv8NMvzbJMEkV8xnrLkEaWRtSz9CosKDYjCJjBRnbJLgp8uirBgmQpjKhoR4tjF3ZpRzrFmBV6UjKdiSZkQUW",
+	}
+
+	for input, expected := range tests {
+		assert.Equal(t, expected, String(input), input)
+	}
+}
+
+func TestWriterRedactsLogEntries(t *testing.T) {
+	AddSecret("refresh-secret-value")
+
+	var buf bytes.Buffer
+	logger := log.New(NewWriter(&buf), "", 0)
+	logger.Printf("refresh failed with refresh-secret-value")
+
+	assert.Equal(t, "refresh failed with [REDACTED]\n", buf.String())
+}
@@ -0,0 +1,277 @@
+// Package redeemer runs the whole airdrop pipeline, scanning, claiming, selling and notifying, for one wallet
+// so it can be embedded in another Go program:
+//
+//	r, err := redeemer.New(redeemer.WithPrivateKey(key), redeemer.WithLogger(logger))
+//	if err != nil {
+//		return err
+//	}
+//	unsubscribe := r.Subscribe(func(event redeemer.Event) {
+//		log.Printf("%s %s: %s", event.Event, event.TokenSymbol, event.TxHash)
+//	})
+//	defer unsubscribe()
+//	if err := r.Start(ctx); err != nil {
+//		return err
+//	}
+//	defer r.Stop(shutdownCtx)
+package redeemer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+
+	"boop-airdrop-redeemer/pkg/autoclaim"
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/notifications"
+	"boop-airdrop-redeemer/pkg/service"
+)
+
+// Event is a confirmed claim or sale, the payload webhooks receive
+type Event = notifications.WebhookEvent
+
+// Event types
+const (
+	EventClaimConfirmed = notifications.EventClaimConfirmed
+	EventSellConfirmed  = notifications.EventSellConfirmed
+)
+
+// ErrAlreadyStarted is returned by Start when the redeemer runs or has run, a stopped redeemer is not restarted
+var ErrAlreadyStarted = errors.New("redeemer already started")
+
+// Option configures a Redeemer
+type Option func(*options)
+
+type options struct {
+	config         *config.Config
+	privateKey     string
+	logger         *log.Logger
+	telegramClient *notifications.TelegramClient
+	store          service.AirdropStore
+}
+
+// WithConfig uses the given configuration instead of reading it from the environment
+func WithConfig(cfg *config.Config) Option {
+	return func(o *options) { o.config = cfg }
+}
+
+// WithPrivateKey reads the configuration from the environment and authenticates with the wallet private key
+func WithPrivateKey(privateKey string) Option {
+	return func(o *options) { o.privateKey = privateKey }
+}
+
+// WithLogger logs to the given logger instead of the "redeemer" component logger
+func WithLogger(logger *log.Logger) Option {
+	return func(o *options) { o.logger = logger }
+}
+
+// WithTelegram sends notifications through the given client instead of one built from the configuration
+func WithTelegram(client *notifications.TelegramClient) Option {
+	return func(o *options) { o.telegramClient = client }
+}
+
+// WithStore keeps airdrops in the given store instead of the configured backend
+func WithStore(store service.AirdropStore) Option {
+	return func(o *options) { o.store = store }
+}
+
+// Redeemer is the auto claimer of one wallet and the services it runs on
+type Redeemer struct {
+	config         *config.Config
+	logger         *log.Logger
+	service        *autoclaim.Service
+	claimer        *service.AirdropClaimer
+	store          service.AirdropStore
+	ownsStore      bool // The store was opened by New and is closed by Stop
+	telegramClient *notifications.TelegramClient
+
+	mu       sync.Mutex
+	started  bool
+	cancel   context.CancelFunc
+	done     chan struct{}
+	eventLog *logging.RotatingFile
+}
+
+// New builds the redeemer. Without WithConfig the configuration is read from the environment, as the
+// auto_claim command does.
+func New(opts ...Option) (*Redeemer, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.logger == nil {
+		o.logger = logging.For("redeemer")
+	}
+	cfg := o.config
+	if cfg != nil {
+		// A configuration built by the caller has not routed the API clients yet
+		if err := cfg.Network.Apply(); err != nil {
+			return nil, fmt.Errorf("invalid proxy or TLS settings: %w", err)
+		}
+	} else {
+		if o.privateKey != "" {
+			var err error
+			if cfg, err = config.NewConfigWithPrivateKey(o.privateKey); err != nil {
+				return nil, fmt.Errorf("failed to initialize with private key: %w", err)
+			}
+		} else {
+			var err error
+			if cfg, err = config.Load(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	telegramClient := o.telegramClient
+	if telegramClient == nil {
+		telegramClient = NewTelegramClient(cfg)
+	}
+
+	store, ownsStore := o.store, o.store == nil
+	if ownsStore {
+		var err error
+		if store, err = service.NewAirdropStore(cfg, o.logger); err != nil {
+			return nil, fmt.Errorf("failed to open airdrop store: %w", err)
+		}
+	}
+
+	scanner := service.NewAirdropScanner(store, cfg, o.logger)
+	claimer := service.NewAirdropClaimer(store, cfg, o.logger, telegramClient)
+	return &Redeemer{
+		config:         cfg,
+		logger:         o.logger,
+		service:        autoclaim.NewService(cfg, scanner, claimer, telegramClient, o.logger),
+		claimer:        claimer,
+		store:          store,
+		ownsStore:      ownsStore,
+		telegramClient: telegramClient,
+	}, nil
+}
+
+// NewTelegramClient creates the Telegram client of a configuration, with its fallback webhook, and the email
+// and the fallback webhook told when Telegram rejects the bot token or chat
+func NewTelegramClient(cfg *config.Config) *notifications.TelegramClient {
+	telegramClient := notifications.NewTelegramClient(cfg.TelegramBotToken, cfg.TelegramChatID, cfg.EnableTelegram)
+	telegramClient.WalletLabel = cfg.WalletLabel
+	telegramClient.Reminder = cfg.TelegramReminder
+
+	var alerts notifications.Notifiers
+	if cfg.TelegramFallbackURL != "" {
+		telegramClient.Fallback = notifications.NewWebhookNotifier(cfg.TelegramFallbackURL)
+		telegramClient.FallbackAfter = cfg.TelegramFallbackAfter
+		alerts = append(alerts, telegramClient.Fallback)
+	}
+	email, err := notifications.NewEmailNotifier(cfg.SmtpURL, cfg.AlertEmailTo)
+	if err != nil {
+		logging.Warnf(logging.For("redeemer"), "WARNING: SMTP_URL: %v, alerts are not emailed", err)
+	} else if email != nil {
+		alerts = append(alerts, email)
+	}
+	if len(alerts) > 0 {
+		telegramClient.Alert = alerts
+	}
+	return telegramClient
+}
+
+// Start runs the auto claimer in the background until ctx is done or Stop is called
+func (r *Redeemer) Start(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.started {
+		return ErrAlreadyStarted
+	}
+
+	if path := r.config.LogFiles.EventLogFile; path != "" {
+		eventLog, err := logging.OpenRotatingFile(path, r.config.LogFiles.Rotation())
+		if err != nil {
+			return fmt.Errorf("failed to open event log: %w", err)
+		}
+		r.eventLog = eventLog
+		r.Subscribe(writeEvents(eventLog, r.logger))
+	}
+	r.started = true
+
+	// Deliver notifications in order from a single queue, it outlives the auto claimer so late messages are kept
+	r.telegramClient.StartQueue(context.Background(), r.config.NotificationBatch)
+
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.done = make(chan struct{})
+	r.claimer.Start(ctx)
+	go func() {
+		r.service.Start(ctx)
+		close(r.done)
+	}()
+	return nil
+}
+
+// Stop stops the auto claimer, lets in-flight claims and sales finish until ctx is done, and releases its
+// resources. It returns ctx's error when the auto claimer did not stop in time.
+func (r *Redeemer) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	cancel, done := r.cancel, r.done
+	r.cancel = nil
+	r.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+
+	cancel()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		r.logger.Println("Timed out waiting for the auto claimer to stop")
+	}
+
+	r.claimer.CleanUp()
+	// A store passed in with WithStore belongs to the caller, it is only flushed
+	closeStore := service.FlushAirdropStore
+	if r.ownsStore {
+		closeStore = service.CloseAirdropStore
+	}
+	if storeErr := closeStore(r.store); storeErr != nil {
+		logging.Warnf(r.logger, "Warning: Failed to write airdrop store: %v", storeErr)
+	}
+	r.telegramClient.StopQueue()
+	if r.eventLog != nil {
+		if closeErr := r.eventLog.Close(); closeErr != nil {
+			logging.Warnf(r.logger, "Warning: Failed to close event log: %v", closeErr)
+		}
+	}
+	return err
+}
+
+// writeEvents returns a subscriber appending every event to w as a JSON line
+func writeEvents(w io.Writer, logger *log.Logger) func(Event) {
+	return func(event Event) {
+		line, err := json.Marshal(event)
+		if err == nil {
+			_, err = w.Write(append(line, '\n'))
+		}
+		if err != nil {
+			logging.Warnf(logger, "Warning: Failed to write %s event to the event log: %v", event.Event, err)
+		}
+	}
+}
+
+// Subscribe calls fn with every confirmed claim and sale. fn runs on the claiming goroutine and must not
+// block. The returned function removes the subscription.
+func (r *Redeemer) Subscribe(fn func(Event)) func() {
+	return r.claimer.GetWebhookClient().Subscribe(fn)
+}
+
+// Config returns the configuration the redeemer runs with
+func (r *Redeemer) Config() *config.Config {
+	return r.config
+}
+
+// Service returns the auto claim service, to pause, resume or claim by hand
+func (r *Redeemer) Service() *autoclaim.Service {
+	return r.service
+}
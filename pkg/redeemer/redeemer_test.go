@@ -0,0 +1,36 @@
+package redeemer
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/service"
+)
+
+func TestNewReturnsConfigErrors(t *testing.T) {
+	t.Setenv("STABLE_DURATION", "ten minutes")
+
+	r, err := New(WithLogger(log.New(io.Discard, "", 0)))
+	assert.ErrorContains(t, err, "STABLE_DURATION")
+	assert.Nil(t, r)
+}
+
+func TestStopWithoutStart(t *testing.T) {
+	cfg := &config.Config{
+		StatsDataDir:  t.TempDir(),
+		CheckInterval: time.Minute,
+		AutoSell:      true,
+	}
+	r, err := New(WithConfig(cfg), WithStore(service.NewInMemoryAirdropStore()), WithLogger(log.New(io.Discard, "", 0)))
+	require.NoError(t, err)
+	assert.Same(t, cfg, r.Config())
+
+	assert.NoError(t, r.Stop(context.Background()))
+}
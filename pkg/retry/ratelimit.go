@@ -0,0 +1,92 @@
+package retry
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RateLimits tracks the rate limit responses a client could not retry its way past, so callers can slow down as
+// a whole instead of each client retrying on its own. Every retrier keeps its own, so the clients of one wallet
+// are not slowed down by the rate limits of another.
+type RateLimits struct {
+	now func() time.Time
+
+	mu    sync.Mutex
+	count int                  // Hits recorded
+	until time.Time            // End of the latest wait asked for with Retry-After
+	hosts map[string]time.Time // Latest hit per host
+}
+
+// RateLimitStatus is the state of the rate limits at one moment
+type RateLimitStatus struct {
+	Limited bool      // A host was rate limited within the cooldown, or asked to wait until after now
+	Count   int       // Hits recorded since the start, compared between checks to tell whether new ones arrived
+	Until   time.Time // End of the latest wait asked for with Retry-After, zero when none was
+	Hosts   []string  // Hosts rate limited within the cooldown, sorted
+}
+
+// NewRateLimits creates an empty rate limit tracker
+func NewRateLimits() *RateLimits {
+	return &RateLimits{now: time.Now, hosts: make(map[string]time.Time)}
+}
+
+// Record records a rate limit response of host and the wait it asked for, 0 when it named none
+func (l *RateLimits) Record(host string, retryAfter time.Duration) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.count++
+	l.hosts[host] = now
+	if retryAfter > 0 && now.Add(retryAfter).After(l.until) {
+		l.until = now.Add(retryAfter)
+	}
+}
+
+// Status returns the rate limits as of now, limited while a host was rate limited within cooldown or the
+// latest Retry-After has not passed
+func (l *RateLimits) Status(cooldown time.Duration) RateLimitStatus {
+	if l == nil {
+		return RateLimitStatus{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	status := RateLimitStatus{Count: l.count, Until: l.until, Limited: now.Before(l.until)}
+	for host, last := range l.hosts {
+		if now.Sub(last) < cooldown {
+			status.Hosts = append(status.Hosts, host)
+		}
+	}
+	sort.Strings(status.Hosts)
+	if len(status.Hosts) > 0 {
+		status.Limited = true
+	}
+	return status
+}
+
+// CombinedStatus returns the rate limits of several clients as of now as one status, limited while any of them is
+func CombinedStatus(cooldown time.Duration, limits ...*RateLimits) RateLimitStatus {
+	var combined RateLimitStatus
+	for _, l := range limits {
+		status := l.Status(cooldown)
+		combined.Limited = combined.Limited || status.Limited
+		combined.Count += status.Count
+		if status.Until.After(combined.Until) {
+			combined.Until = status.Until
+		}
+		for _, host := range status.Hosts {
+			if !slices.Contains(combined.Hosts, host) {
+				combined.Hosts = append(combined.Hosts, host)
+			}
+		}
+	}
+	sort.Strings(combined.Hosts)
+	return combined
+}
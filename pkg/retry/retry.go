@@ -0,0 +1,264 @@
+// Package retry retries failed calls with exponential backoff and jitter. Errors are sorted into classes,
+// each retried by its own policy, and a budget caps the retries across calls so an outage does not multiply
+// the load on a server that is already struggling.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Class is the kind of failure an error reports, deciding whether and how a call is retried
+type Class int
+
+const (
+	Permanent   Class = iota // Retrying does not help: bad requests, auth errors, cancelled calls
+	Network                  // No response arrived: connection refused or reset, timeouts
+	RateLimited              // The server asked to slow down, HTTP 429
+	Unavailable              // The server failed, HTTP 5xx
+)
+
+// String returns the name of the class as logged
+func (c Class) String() string {
+	switch c {
+	case Network:
+		return "network"
+	case RateLimited:
+		return "rate limit"
+	case Unavailable:
+		return "server"
+	default:
+		return "permanent"
+	}
+}
+
+// Policy is how calls failing with one class of error are retried
+type Policy struct {
+	MaxAttempts int           // Attempts including the first, 1 does not retry
+	Initial     time.Duration // Delay before the first retry, doubled for each further one
+	Max         time.Duration // Longest delay
+	Jitter      float64       // Share of each delay that is random, 0.5 waits between half and all of it
+}
+
+// Delay is the wait before the given retry, counted from 1. Retries of many clients failing at once are
+// spread by the jitter instead of arriving together.
+func (p Policy) Delay(retry int) time.Duration {
+	delay := p.Initial
+	for i := 1; i < retry && delay < p.Max; i++ {
+		delay *= 2
+	}
+	if p.Max > 0 {
+		delay = min(delay, p.Max)
+	}
+	if p.Jitter > 0 {
+		jitter := min(p.Jitter, 1)
+		delay = time.Duration(float64(delay) * (1 - jitter*rand.Float64()))
+	}
+	return delay
+}
+
+// Policies are the policies by error class. Classes without a policy, Permanent always, are not retried.
+type Policies map[Class]Policy
+
+// DefaultPolicies retry network failures quickly, rate limits patiently and server failures a few times,
+// each with at most maxAttempts attempts
+func DefaultPolicies(maxAttempts int) Policies {
+	policies := Policies{
+		Network:     {MaxAttempts: 4, Initial: 500 * time.Millisecond, Max: 5 * time.Second, Jitter: 0.5},
+		RateLimited: {MaxAttempts: 5, Initial: 2 * time.Second, Max: 30 * time.Second, Jitter: 0.5},
+		Unavailable: {MaxAttempts: 3, Initial: time.Second, Max: 10 * time.Second, Jitter: 0.5},
+	}
+	for class, policy := range policies {
+		policy.MaxAttempts = min(policy.MaxAttempts, max(maxAttempts, 1))
+		policies[class] = policy
+	}
+	return policies
+}
+
+// Budget caps the retries of all calls sharing it within a sliding window. A nil Budget allows every retry.
+type Budget struct {
+	max    int
+	window time.Duration
+
+	mu      sync.Mutex
+	retries []time.Time
+}
+
+// NewBudget allows max retries per window, 0 allows no retries at all
+func NewBudget(max int, window time.Duration) *Budget {
+	return &Budget{max: max, window: window}
+}
+
+// Allow takes a retry from the budget, reporting false when the window's retries are used up
+func (b *Budget) Allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	kept := b.retries[:0]
+	for _, at := range b.retries {
+		if now.Sub(at) < b.window {
+			kept = append(kept, at)
+		}
+	}
+	b.retries = kept
+	if len(b.retries) >= b.max {
+		return false
+	}
+	b.retries = append(b.retries, now)
+	return true
+}
+
+// Retrier runs calls, retrying their failures by the policy of the error's class within its budget
+type Retrier struct {
+	policies   Policies
+	budget     *Budget
+	logger     *log.Logger
+	rateLimits *RateLimits // Rate limit responses of the transports using it that were not retried away
+}
+
+// New creates a retrier. A nil budget does not cap retries, a nil logger does not log them.
+func New(policies Policies, budget *Budget, logger *log.Logger) *Retrier {
+	return &Retrier{policies: policies, budget: budget, logger: logger, rateLimits: NewRateLimits()}
+}
+
+// RateLimits returns the rate limits the retrier's transports ran into, nil for a nil retrier
+func (r *Retrier) RateLimits() *RateLimits {
+	if r == nil {
+		return nil
+	}
+	return r.rateLimits
+}
+
+// NewDefault creates a retrier with the default policies, at most maxAttempts attempts per call and
+// budgetPerMinute retries per minute, 0 does not cap them
+func NewDefault(maxAttempts, budgetPerMinute int, logger *log.Logger) *Retrier {
+	var budget *Budget
+	if budgetPerMinute > 0 {
+		budget = NewBudget(budgetPerMinute, time.Minute)
+	}
+	return New(DefaultPolicies(maxAttempts), budget, logger)
+}
+
+// Do calls fn until it succeeds, fails with an error that is not retried, runs out of attempts or budget, or
+// ctx is done, and returns its last error. A nil Retrier calls fn once.
+func (r *Retrier) Do(ctx context.Context, name string, fn func() error) error {
+	if r == nil {
+		return fn()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+
+		class := Classify(err)
+		policy, ok := r.policies[class]
+		if !ok || attempt >= policy.MaxAttempts {
+			return err
+		}
+		if !r.budget.Allow() {
+			r.logf("Warning: Not retrying %s, the retry budget is used up: %v", name, err)
+			return err
+		}
+
+		delay := policy.Delay(attempt)
+		if after := RetryAfter(err); after > delay {
+			// A server asking for a longer wait than the policy allows is not kept waiting on
+			if policy.Max > 0 && after > policy.Max {
+				return err
+			}
+			delay = after
+		}
+		r.logf("Retrying %s in %s after a %s error (attempt %d/%d): %v",
+			name, delay.Round(time.Millisecond), class, attempt, policy.MaxAttempts, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+func (r *Retrier) logf(format string, args ...any) {
+	if r.logger != nil {
+		r.logger.Printf(format, args...)
+	}
+}
+
+// StatusError is an HTTP response with a status code worth retrying
+type StatusError struct {
+	Code       int
+	RetryAfter time.Duration // Wait the server asked for, 0 when it named none
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HTTP status %d", e.Code)
+}
+
+// StatusClass returns the class of an HTTP status code
+func StatusClass(code int) Class {
+	switch {
+	case code == http.StatusTooManyRequests:
+		return RateLimited
+	case code >= 500 && code != http.StatusNotImplemented:
+		return Unavailable
+	default:
+		return Permanent
+	}
+}
+
+// RetryAfter returns the wait the server asked for with the error, 0 when it named none
+func RetryAfter(err error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.RetryAfter
+	}
+	return 0
+}
+
+// networkMessages are failures of clients that wrap errors as text, which errors.As cannot see through
+var networkMessages = []string{"unexpected EOF", "connection refused", "connection reset", "i/o timeout", "no such host", "TLS handshake timeout"}
+
+// Classify returns the class of an error. Cancelled calls are permanent, the caller gave up on them.
+func Classify(err error) Class {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return Permanent
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return StatusClass(statusErr.Code)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return Network
+	}
+
+	message := err.Error()
+	for _, network := range networkMessages {
+		if strings.Contains(message, network) {
+			return Network
+		}
+	}
+	return Permanent
+}
@@ -0,0 +1,170 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fastPolicies retry every class without waiting
+var fastPolicies = Policies{
+	Network:     {MaxAttempts: 3},
+	RateLimited: {MaxAttempts: 3},
+	Unavailable: {MaxAttempts: 3},
+}
+
+func TestPolicyDelayBacksOffWithJitter(t *testing.T) {
+	policy := Policy{Initial: time.Second, Max: 5 * time.Second}
+	assert.Equal(t, time.Second, policy.Delay(1))
+	assert.Equal(t, 4*time.Second, policy.Delay(3))
+	assert.Equal(t, 5*time.Second, policy.Delay(10))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay := policy.Delay(2)
+		assert.True(t, delay >= time.Second && delay <= 2*time.Second, delay)
+	}
+}
+
+func TestDoRetriesByClass(t *testing.T) {
+	retrier := New(fastPolicies, nil, nil)
+
+	calls := 0
+	err := retrier.Do(context.Background(), "scan", func() error {
+		calls++
+		if calls < 3 {
+			return fmt.Errorf("fetch failed: %w", io.ErrUnexpectedEOF)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = retrier.Do(context.Background(), "scan", func() error {
+		calls++
+		return errors.New("GraphQL authorization error")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls, "permanent errors are not retried")
+
+	// The budget is shared by all calls
+	retrier = New(fastPolicies, NewBudget(1, time.Minute), nil)
+	calls = 0
+	_ = retrier.Do(context.Background(), "scan", func() error {
+		calls++
+		return &StatusError{Code: http.StatusServiceUnavailable}
+	})
+	assert.Equal(t, 2, calls)
+}
+
+func TestTransportRetriesRetryableStatuses(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, `{"query":"airdrops"}`, string(body), "the body is sent again")
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	retrier := New(fastPolicies, nil, nil)
+	client := &http.Client{Transport: NewTransport(nil, retrier)}
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{"query":"airdrops"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.EqualValues(t, 2, requests.Load())
+	assert.False(t, retrier.RateLimits().Status(time.Minute).Limited, "a rate limit a retry got past is not recorded")
+
+	// Without retries left the last response is returned to the caller and the rate limit is recorded
+	resp, err = client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	status := retrier.RateLimits().Status(time.Minute)
+	assert.Equal(t, 1, status.Count)
+	assert.Equal(t, []string{strings.TrimPrefix(server.URL, "http://")}, status.Hosts)
+	assert.False(t, New(fastPolicies, nil, nil).RateLimits().Status(time.Minute).Limited, "other clients are not slowed down")
+}
+
+func TestTransportRetriesServerErrorsOnlyForIdempotentRequests(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Idempotency-Key"), "the marker is not sent")
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport(nil, New(fastPolicies, nil, nil))}
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{"query":"mutation claim"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.EqualValues(t, 1, requests.Load(), "a POST that may have been carried out is not sent again")
+
+	requests.Store(0)
+	req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"query":"airdrops"}`))
+	require.NoError(t, err)
+	MarkIdempotent(req)
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.EqualValues(t, 3, requests.Load())
+
+	requests.Store(0)
+	client.Transport = NewIdempotentTransport(nil, New(fastPolicies, nil, nil))
+	resp, err = client.Post(server.URL, "application/json", strings.NewReader(`{"method":"sendTransaction"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.EqualValues(t, 3, requests.Load())
+}
+
+func TestRateLimitsCooldown(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	limits := NewRateLimits()
+	limits.now = func() time.Time { return now }
+	assert.False(t, limits.Status(time.Minute).Limited)
+
+	limits.Record("api.example", 0)
+	limits.Record("rpc.example", 5*time.Minute)
+	status := limits.Status(time.Minute)
+	assert.True(t, status.Limited)
+	assert.Equal(t, 2, status.Count)
+	assert.Equal(t, []string{"api.example", "rpc.example"}, status.Hosts)
+
+	// The cooldown passed but the Retry-After did not
+	now = now.Add(2 * time.Minute)
+	status = limits.Status(time.Minute)
+	assert.True(t, status.Limited)
+	assert.Empty(t, status.Hosts)
+
+	now = now.Add(5 * time.Minute)
+	assert.False(t, limits.Status(time.Minute).Limited)
+
+	other := NewRateLimits()
+	other.now = limits.now
+	other.Record("api.example", 0)
+	combined := CombinedStatus(time.Minute, limits, other, nil)
+	assert.True(t, combined.Limited)
+	assert.Equal(t, 3, combined.Count)
+	assert.Equal(t, []string{"api.example"}, combined.Hosts)
+}
@@ -0,0 +1,144 @@
+package retry
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Transport is an http.RoundTripper retrying requests that fail with a network error or get a rate limit or
+// server error status. When the retries run out the last response is returned as it came, so callers handle
+// its status as before. Requests whose body cannot be replayed are sent once. A rate limit response that is
+// returned this way is recorded in the retrier's RateLimits, one that a retry got past is not.
+//
+// Only idempotent requests are retried on server and network errors, a POST may have been carried out before it
+// failed. Other requests are only retried when they were refused with a rate limit or never got connected.
+// Requests count as idempotent by their method, or by an Idempotency-Key header as net/http has it, which
+// MarkIdempotent sets without sending it.
+type Transport struct {
+	base       http.RoundTripper
+	retrier    *Retrier
+	idempotent bool // Every request may be repeated
+}
+
+// NewTransport wraps base, http.DefaultTransport when nil, retrying with retrier. A nil retrier sends each
+// request once and does not record its rate limits.
+func NewTransport(base http.RoundTripper, retrier *Retrier) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{base: base, retrier: retrier}
+}
+
+// NewIdempotentTransport is NewTransport for APIs whose requests can all be repeated, such as Solana RPC where
+// sending a signed transaction again cannot execute it twice
+func NewIdempotentTransport(base http.RoundTripper, retrier *Retrier) *Transport {
+	t := NewTransport(base, retrier)
+	t.idempotent = true
+	return t
+}
+
+// MarkIdempotent marks a request as safe to repeat, such as a GraphQL query sent with POST. The header is not
+// sent, net/http leaves out keys without a value.
+func MarkIdempotent(req *http.Request) {
+	req.Header["Idempotency-Key"] = nil
+}
+
+// isIdempotent reports whether a request may be sent again after a server or network error
+func (t *Transport) isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	_, key := req.Header["Idempotency-Key"]
+	_, xKey := req.Header["X-Idempotency-Key"]
+	return t.idempotent || key || xKey
+}
+
+// notConnected reports whether a request failed before it reached the server
+func notConnected(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && (opErr.Op == "dial" || opErr.Op == "proxyconnect")
+}
+
+// RoundTrip sends the request, retrying it as the retrier allows
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		resp, err := t.base.RoundTrip(req)
+		if err == nil {
+			t.recordRateLimit(req, resp)
+		}
+		return resp, err
+	}
+
+	idempotent := t.isIdempotent(req)
+	var resp *http.Response
+	var sendErr error // Failure of a request that is not repeated
+	attempt := 0
+	err := t.retrier.Do(req.Context(), req.Method+" "+req.URL.Host+req.URL.Path, func() error {
+		if resp != nil {
+			// Read the body so the connection is reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+			resp = nil
+		}
+
+		attemptReq := req
+		if attempt++; attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		r, err := t.base.RoundTrip(attemptReq)
+		if err != nil {
+			if !idempotent && !notConnected(err) {
+				sendErr = err
+				return nil
+			}
+			return err
+		}
+		resp = r
+		// A rate limited request was refused before it was handled, a server error may come after it was
+		if StatusClass(r.StatusCode) != Permanent && (idempotent || r.StatusCode == http.StatusTooManyRequests) {
+			return &StatusError{Code: r.StatusCode, RetryAfter: parseRetryAfter(r.Header.Get("Retry-After"))}
+		}
+		return nil
+	})
+	if sendErr != nil {
+		return nil, sendErr
+	}
+	if resp != nil {
+		// A response with a retryable status that was not retried again is the caller's to handle
+		t.recordRateLimit(req, resp)
+		return resp, nil
+	}
+	return nil, err
+}
+
+// recordRateLimit records a rate limit response handed to the caller, with the wait it asked for
+func (t *Transport) recordRateLimit(req *http.Request, resp *http.Response) {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		t.retrier.RateLimits().Record(req.URL.Host, parseRetryAfter(resp.Header.Get("Retry-After")))
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date, 0 when it is missing
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
@@ -0,0 +1,274 @@
+// Package safety screens airdropped tokens for signs of scams before they are claimed or sold
+package safety
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"boop-airdrop-redeemer/pkg/jupiter"
+	"boop-airdrop-redeemer/pkg/logging"
+)
+
+// mintsPerRead is how many mint accounts are read with one getMultipleAccounts call
+const mintsPerRead = 100
+
+// Names of the screening checks
+const (
+	CheckMintAuthority   = "mint authority"
+	CheckFreezeAuthority = "freeze authority"
+	CheckTopHolder       = "top holder"
+	CheckRoute           = "swap route"
+)
+
+// AccountReader reads mint accounts
+type AccountReader interface {
+	GetMultipleAccountsWithOpts(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error)
+}
+
+// HolderReader lists the largest token accounts of a mint, the top holder check is skipped without it
+type HolderReader interface {
+	GetTokenLargestAccounts(ctx context.Context, tokenMint solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetTokenLargestAccountsResult, error)
+}
+
+var (
+	_ AccountReader = (*rpc.Client)(nil)
+	_ HolderReader  = (*rpc.Client)(nil)
+)
+
+// RouteQuoter quotes a sale of the token for SOL, implemented by the Jupiter swap service
+type RouteQuoter interface {
+	EstimateSwapOutputAmount(tokenMint string, amount uint64) (float64, error)
+}
+
+// Check is the result of one screening check
+type Check struct {
+	Name   string
+	Passed bool
+	Detail string
+}
+
+// Report is the screening result of a token mint
+type Report struct {
+	Mint      string
+	Checks    []Check
+	CheckedAt time.Time
+}
+
+// Safe reports whether the token passed every check
+func (r Report) Safe() bool {
+	return len(r.Failed()) == 0
+}
+
+// Failed returns the checks the token failed
+func (r Report) Failed() []Check {
+	var failed []Check
+	for _, check := range r.Checks {
+		if !check.Passed {
+			failed = append(failed, check)
+		}
+	}
+	return failed
+}
+
+// Summary lists the failed checks with their details, "passed" when there are none
+func (r Report) Summary() string {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return "passed"
+	}
+	parts := make([]string, len(failed))
+	for i, check := range failed {
+		parts[i] = check.Name + ": " + check.Detail
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Screener checks token mints for a mint authority that can inflate the supply, a freeze authority that can
+// lock the wallet's tokens, a single holder owning most of the supply and the lack of a Jupiter route to sell
+// them. Reports are cached per mint; a mint whose checks could not run is screened again on the next call.
+type Screener struct {
+	accounts     AccountReader
+	holders      HolderReader // nil skips the top holder check
+	routes       RouteQuoter
+	maxTopHolder float64 // Largest share of the supply a single account may hold, 0 skips the check
+	ttl          time.Duration
+	logger       *log.Logger
+	now          func() time.Time
+
+	mu      sync.Mutex
+	reports map[string]Report
+}
+
+// NewScreener creates a screener keeping reports for ttl. maxTopHolder is the largest share of the supply
+// (0.5 = 50%) a single token account may hold, 0 disables the top holder check.
+func NewScreener(accounts AccountReader, routes RouteQuoter, maxTopHolder float64, ttl time.Duration, logger *log.Logger) *Screener {
+	holders, _ := accounts.(HolderReader)
+	return &Screener{
+		accounts:     accounts,
+		holders:      holders,
+		routes:       routes,
+		maxTopHolder: maxTopHolder,
+		ttl:          ttl,
+		logger:       logger,
+		now:          time.Now,
+		reports:      make(map[string]Report),
+	}
+}
+
+// Report returns the cached report of a mint, false when it was not screened yet
+func (s *Screener) Report(mint string) (Report, bool) {
+	if s == nil {
+		return Report{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	report, ok := s.reports[mint]
+	return report, ok
+}
+
+// Screen checks the mints without a fresh report and returns the reports of all of them. Mints whose
+// checks failed to run have no report.
+func (s *Screener) Screen(ctx context.Context, mints []string) map[string]Report {
+	if s == nil {
+		return nil
+	}
+
+	reports := make(map[string]Report, len(mints))
+	seen := make(map[string]bool, len(mints))
+	var missing []solana.PublicKey
+	s.mu.Lock()
+	now := s.now()
+	for _, mint := range mints {
+		if seen[mint] {
+			continue
+		}
+		seen[mint] = true
+
+		if report, ok := s.reports[mint]; ok && now.Sub(report.CheckedAt) < s.ttl {
+			reports[mint] = report
+			continue
+		}
+		key, err := solana.PublicKeyFromBase58(mint)
+		if err != nil {
+			continue
+		}
+		missing = append(missing, key)
+	}
+	s.mu.Unlock()
+
+	for start := 0; start < len(missing); start += mintsPerRead {
+		s.screenBatch(ctx, missing[start:min(start+mintsPerRead, len(missing))], reports)
+	}
+	return reports
+}
+
+// screenBatch reads the mint accounts with one call and screens them, adding their reports to reports
+func (s *Screener) screenBatch(ctx context.Context, mints []solana.PublicKey, reports map[string]Report) {
+	result, err := s.accounts.GetMultipleAccountsWithOpts(ctx, mints, &rpc.GetMultipleAccountsOpts{
+		Encoding:   solana.EncodingBase64,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil || result == nil || len(result.Value) != len(mints) {
+		logging.Warnf(s.logger, "Warning: Failed to read %d mint account(s) for safety screening: %v", len(mints), err)
+		return
+	}
+
+	for i, mint := range mints {
+		report, err := s.screen(ctx, mint, result.Value[i])
+		if err != nil {
+			logging.Warnf(s.logger, "Warning: Failed to screen token %s: %v", mint, err)
+			continue
+		}
+		if !report.Safe() {
+			logging.Warnf(s.logger, "Token %s failed safety screening: %s", mint, report.Summary())
+		}
+
+		s.mu.Lock()
+		s.reports[report.Mint] = report
+		s.mu.Unlock()
+		reports[report.Mint] = report
+	}
+}
+
+// screen runs the checks of one mint
+func (s *Screener) screen(ctx context.Context, mint solana.PublicKey, account *rpc.Account) (Report, error) {
+	if account == nil || account.Data == nil {
+		return Report{}, fmt.Errorf("mint account not found")
+	}
+	var mintInfo token.Mint
+	if err := bin.NewBinDecoder(account.Data.GetBinary()).Decode(&mintInfo); err != nil {
+		return Report{}, fmt.Errorf("failed to decode mint: %w", err)
+	}
+
+	report := Report{Mint: mint.String(), CheckedAt: s.now()}
+	report.Checks = append(report.Checks,
+		authorityCheck(CheckMintAuthority, mintInfo.MintAuthority, "the supply can still be inflated"),
+		authorityCheck(CheckFreezeAuthority, mintInfo.FreezeAuthority, "the wallet's tokens can be frozen"),
+	)
+
+	if s.holders != nil && s.maxTopHolder > 0 && mintInfo.Supply > 0 {
+		check, err := s.topHolderCheck(ctx, mint, mintInfo.Supply)
+		if err != nil {
+			return Report{}, err
+		}
+		report.Checks = append(report.Checks, check)
+	}
+
+	if s.routes != nil {
+		// Quote one whole token, airdrops are rarely smaller
+		oneToken := uint64(math.Pow10(int(mintInfo.Decimals)))
+		_, err := s.routes.EstimateSwapOutputAmount(mint.String(), oneToken)
+		switch {
+		case errors.Is(err, jupiter.ErrNoRoute):
+			report.Checks = append(report.Checks, Check{Name: CheckRoute, Detail: "Jupiter has no route to sell it"})
+		case err != nil:
+			return Report{}, fmt.Errorf("failed to quote a sale: %w", err)
+		default:
+			report.Checks = append(report.Checks, Check{Name: CheckRoute, Passed: true})
+		}
+	}
+
+	return report, nil
+}
+
+// topHolderCheck fails when the largest token account holds more than the allowed share of the supply
+func (s *Screener) topHolderCheck(ctx context.Context, mint solana.PublicKey, supply uint64) (Check, error) {
+	largest, err := s.holders.GetTokenLargestAccounts(ctx, mint, rpc.CommitmentConfirmed)
+	if err != nil {
+		return Check{}, fmt.Errorf("failed to get the largest holders: %w", err)
+	}
+	if largest == nil || len(largest.Value) == 0 || largest.Value[0] == nil {
+		return Check{Name: CheckTopHolder, Passed: true}, nil
+	}
+
+	top, err := strconv.ParseUint(largest.Value[0].Amount, 10, 64)
+	if err != nil {
+		return Check{}, fmt.Errorf("invalid holder amount %q: %w", largest.Value[0].Amount, err)
+	}
+	share := float64(top) / float64(supply)
+	if share > s.maxTopHolder {
+		return Check{Name: CheckTopHolder, Detail: fmt.Sprintf("%s holds %.0f%% of the supply", largest.Value[0].Address, share*100)}, nil
+	}
+	return Check{Name: CheckTopHolder, Passed: true}, nil
+}
+
+// authorityCheck fails when the authority is set
+func authorityCheck(name string, authority *solana.PublicKey, risk string) Check {
+	if authority == nil || authority.IsZero() {
+		return Check{Name: name, Passed: true}
+	}
+	return Check{Name: name, Detail: fmt.Sprintf("held by %s, %s", authority, risk)}
+}
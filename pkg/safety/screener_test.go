@@ -0,0 +1,94 @@
+package safety
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+
+	"boop-airdrop-redeemer/pkg/jupiter"
+)
+
+type fakeAccounts struct {
+	mints map[solana.PublicKey]token.Mint
+	calls int
+}
+
+func (f *fakeAccounts) GetMultipleAccountsWithOpts(_ context.Context, accounts []solana.PublicKey, _ *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error) {
+	f.calls++
+	result := &rpc.GetMultipleAccountsResult{}
+	for _, account := range accounts {
+		mint, ok := f.mints[account]
+		if !ok {
+			result.Value = append(result.Value, nil)
+			continue
+		}
+		var buf bytes.Buffer
+		if err := bin.NewBinEncoder(&buf).Encode(&mint); err != nil {
+			return nil, err
+		}
+		result.Value = append(result.Value, &rpc.Account{Lamports: 1, Data: rpc.DataBytesOrJSONFromBytes(buf.Bytes())})
+	}
+	return result, nil
+}
+
+type fakeRoutes struct {
+	errs map[string]error
+}
+
+func (f *fakeRoutes) EstimateSwapOutputAmount(tokenMint string, _ uint64) (float64, error) {
+	return 0.01, f.errs[tokenMint]
+}
+
+func TestScreenerFlagsAuthoritiesAndMissingRoutes(t *testing.T) {
+	safe := solana.NewWallet().PublicKey()
+	mintable := solana.NewWallet().PublicKey()
+	unroutable := solana.NewWallet().PublicKey()
+	flaky := solana.NewWallet().PublicKey()
+	authority := solana.NewWallet().PublicKey()
+
+	accounts := &fakeAccounts{mints: map[solana.PublicKey]token.Mint{
+		safe:       {Decimals: 6, Supply: 1000, IsInitialized: true},
+		mintable:   {Decimals: 6, Supply: 1000, IsInitialized: true, MintAuthority: &authority, FreezeAuthority: &authority},
+		unroutable: {Decimals: 6, Supply: 1000, IsInitialized: true},
+		flaky:      {Decimals: 6, Supply: 1000, IsInitialized: true},
+	}}
+	routes := &fakeRoutes{errs: map[string]error{
+		unroutable.String(): fmt.Errorf("failed to get swap quote: %w", jupiter.ErrNoRoute),
+		flaky.String():      errors.New("timeout"),
+	}}
+	screener := NewScreener(accounts, routes, 0, time.Hour, log.New(&bytes.Buffer{}, "", 0))
+
+	reports := screener.Screen(context.Background(), []string{safe.String(), mintable.String(), unroutable.String(), flaky.String(), safe.String()})
+	assert.True(t, reports[safe.String()].Safe())
+
+	failed := reports[mintable.String()].Failed()
+	if assert.Len(t, failed, 2) {
+		assert.Equal(t, CheckMintAuthority, failed[0].Name)
+		assert.Equal(t, CheckFreezeAuthority, failed[1].Name)
+	}
+
+	failed = reports[unroutable.String()].Failed()
+	if assert.Len(t, failed, 1) {
+		assert.Equal(t, CheckRoute, failed[0].Name)
+	}
+
+	_, ok := reports[flaky.String()]
+	assert.False(t, ok, "a failed quote is not a missing route")
+
+	screener.Screen(context.Background(), []string{safe.String(), flaky.String()})
+	assert.Equal(t, 2, accounts.calls, "only the token whose checks failed to run is read again")
+
+	report, ok := screener.Report(mintable.String())
+	assert.True(t, ok)
+	assert.False(t, report.Safe())
+}
@@ -2,22 +2,25 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"strconv"
+	"path/filepath"
+	"sync"
 	"time"
 
+	"boop-airdrop-redeemer/pkg/amount"
 	"boop-airdrop-redeemer/pkg/config"
-	"boop-airdrop-redeemer/pkg/jupiter"
-	"boop-airdrop-redeemer/pkg/solana/associated_token_account_extended"
-	"boop-airdrop-redeemer/pkg/solana/boop"
-
-	sol "boop-airdrop-redeemer/pkg/solana"
-
+	"boop-airdrop-redeemer/pkg/journal"
+	"boop-airdrop-redeemer/pkg/keys"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/models"
 	"boop-airdrop-redeemer/pkg/notifications"
+	"boop-airdrop-redeemer/pkg/operation"
+	"boop-airdrop-redeemer/pkg/retry"
+	sol "boop-airdrop-redeemer/pkg/solana"
 
 	"github.com/gagliardetto/solana-go"
-	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
 	"github.com/gagliardetto/solana-go/rpc"
 )
 
@@ -27,14 +30,40 @@ var (
 	BoopMerkleDistribution = solana.MustPublicKeyFromBase58("boopEtkTLx8x8moK7mMBQZUfzaEiA96Qn7gQeNdcQMg")
 )
 
+// defaultClaimComputeUnitLimit is used until enough simulated claims have been recorded
+const defaultClaimComputeUnitLimit = 200000
+
+// Sell targets selected with SELL_TARGET
+const (
+	SellTargetSol  = "SOL"
+	SellTargetUsdc = "USDC"
+)
+
 // ClaimConfig holds configuration for the claim process
 type ClaimConfig struct {
-	AutoSellToSol bool // Whether to automatically sell claimed tokens for SOL
+	AutoSell   bool          // Whether to automatically sell claimed tokens
+	SellTarget string        // SellTargetSol or SellTargetUsdc
+	SellDelay  time.Duration // Wait between the claim and the sale
 }
 
 // DefaultClaimConfig provides default settings for claiming
 var DefaultClaimConfig = ClaimConfig{
-	AutoSellToSol: true,
+	AutoSell:   true,
+	SellTarget: SellTargetSol,
+}
+
+// NewClaimConfig builds the claim settings from the configuration, selling for SOL unless USDC is selected.
+// Tokens moved to a receiving wallet are not in the claiming wallet anymore, so they are never sold.
+func NewClaimConfig(cfg *config.Config) ClaimConfig {
+	claimConfig := ClaimConfig{
+		AutoSell:   cfg.AutoSell && cfg.ReceivingWallet == "",
+		SellTarget: SellTargetSol,
+		SellDelay:  cfg.SellDelay,
+	}
+	if cfg.SellTarget == SellTargetUsdc {
+		claimConfig.SellTarget = SellTargetUsdc
+	}
+	return claimConfig
 }
 
 // ClaimRequest represents a request to claim an airdrop
@@ -54,53 +83,129 @@ type ClaimResponse struct {
 
 // AirdropClaimer handles claiming airdrops via transaction
 type AirdropClaimer struct {
-	config         *config.Config
-	store          AirdropStore
-	logger         *log.Logger
-	solClient      *rpc.Client
-	swapSvc        *jupiter.SwapService
-	telegramClient *notifications.TelegramClient
-	statsRecorder  *sol.StatsRecorder
-	priceService   *sol.PriceService
+	config          *config.Config
+	store           AirdropStore
+	logger          *log.Logger
+	solClient       sol.RPCClient
+	swapSvc         SwapProvider    // Sales go through saleBreaker
+	saleBreaker     *CircuitBreaker // nil retries failing sales every time
+	telegramClient  *notifications.TelegramClient
+	statsRecorder   StatsStore
+	priceService    PriceProvider
+	webhookClient   *notifications.WebhookClient
+	journal         *journal.Journal
+	serviceFee      *ServiceFee
+	killSwitch      *KillSwitch
+	quoteGuard      *QuoteGuard
+	profitCheck     *ProfitCheck
+	vault           *Vault
+	rentReclaimer   *RentReclaimer
+	positionCap     *PositionCap
+	costBasis       *CostBasisWatch
+	accountCache    *sol.AccountCache   // nil when account caching is disabled
+	accountClient   sol.AccountClient   // nil when the RPC client cannot read accounts
+	mintDecimals    *sol.MintDecimals   // nil when the RPC client cannot read accounts
+	sigClient       sol.SignatureClient // nil when the RPC client cannot list transactions
+	claimAccounts   claimAccountDeriver
+	receiver        solana.PublicKey // zero when claimed tokens stay in the claiming wallet
+	atomicFallbacks keySet           // Tokens claimed and sold separately after a combined transaction failed
+	poolAlerts      keySet           // Airdrops whose underfunded pool was already alerted
+	sentClaims      keySet           // Signatures of claim transactions sent by this claimer
+	delayedSales    sync.WaitGroup   // Sales scheduled after SELL_DELAY
+	rateLimits      []*retry.RateLimits
 }
 
-// NewAirdropClaimer creates a new claimer with the provided dependencies
+// NewAirdropClaimer creates a new claimer with the default dependencies built from the configuration
 func NewAirdropClaimer(store AirdropStore, cfg *config.Config, logger *log.Logger, telegramClient *notifications.TelegramClient) *AirdropClaimer {
-	// Initialize Solana RPC client
-	solClient := rpc.New(cfg.SolanaRpcURL)
+	return NewAirdropClaimerWithDeps(store, cfg, logger, telegramClient, DefaultClaimerDeps(cfg, logger))
+}
 
-	// Initialize Jupiter swap service
-	swapSvc := jupiter.NewSwapService(solClient, logger)
+// NewAirdropClaimerWithDeps creates a new claimer using the given RPC client, swap service, stats recorder and price service
+func NewAirdropClaimerWithDeps(store AirdropStore, cfg *config.Config, logger *log.Logger, telegramClient *notifications.TelegramClient, deps ClaimerDeps) *AirdropClaimer {
+	logger = logging.Component(logger, "claimer")
 
-	// Initialize stats recorder
-	statsRecorder, err := sol.NewStatsRecorder(cfg.StatsDataDir)
-	if err != nil {
-		logger.Printf("WARNING: Failed to initialize stats recorder: %v", err)
+	// Initialize webhook client for external event delivery
+	webhookClient := notifications.NewWebhookClient(cfg.WebhookURLs, cfg.WebhookSecret)
+	webhookClient.WalletLabel = cfg.WalletLabel
+
+	// Initialize operation journal
+	var opJournal *journal.Journal
+	if cfg.JournalPath != "" {
+		var err error
+		opJournal, err = journal.NewJournal(cfg.JournalPath)
+		if err != nil {
+			logging.Warnf(logger, "WARNING: Failed to initialize operation journal: %v", err)
+		}
 	}
 
-	// Initialize price service
-	priceService := sol.NewPriceService(logger)
-	priceService.Start()
+	killSwitch := NewKillSwitch(cfg, logger)
+
+	// Cache hot account reads when the RPC client can read accounts
+	var accountCache *sol.AccountCache
+	var mintDecimals *sol.MintDecimals
+	accountClient, _ := deps.SolClient.(sol.AccountClient)
+	if accountClient != nil {
+		mintDecimals = sol.NewMintDecimals(accountClient)
+		if cfg.AccountCacheTTL > 0 {
+			accountCache = sol.NewAccountCache(accountClient, cfg.AccountCacheTTL)
+		}
+	}
+
+	sigClient, _ := deps.SolClient.(sol.SignatureClient)
+
+	// Every seller shares the swap service, so failures of any sale count towards pausing the token. The state
+	// is kept with the statistics, in their database with the bolt backend.
+	saleFailures := sol.NewFileState(filepath.Join(cfg.StatsDataDir, "sale_failures.json"))
+	if deps.StatsRecorder != nil {
+		saleFailures = deps.StatsRecorder.State("sale_failures")
+	}
+	saleBreaker, err := NewCircuitBreaker(cfg.SellFailureLimit, cfg.SellFailureCooldown, saleFailures, logger)
+	if err != nil {
+		logging.Warnf(logger, "WARNING: Pausing failing sales disabled: %v", err)
+	}
+	swapSvc := guardSales(deps.SwapService, saleBreaker)
+	receiver := newReceivingWallet(cfg, logger)
 
 	return &AirdropClaimer{
 		config:         cfg,
 		store:          store,
 		logger:         logger,
-		solClient:      solClient,
+		solClient:      deps.SolClient,
 		swapSvc:        swapSvc,
+		saleBreaker:    saleBreaker,
 		telegramClient: telegramClient,
-		statsRecorder:  statsRecorder,
-		priceService:   priceService,
+		statsRecorder:  deps.StatsRecorder,
+		priceService:   deps.PriceService,
+		webhookClient:  webhookClient,
+		journal:        opJournal,
+		serviceFee:     NewServiceFee(cfg, deps.SolClient, deps.StatsRecorder, telegramClient, killSwitch, logger),
+		killSwitch:     killSwitch,
+		quoteGuard:     NewQuoteGuard(cfg, deps.SwapService, telegramClient, logger),
+		profitCheck:    NewProfitCheck(cfg, deps.SwapService, deps.StatsRecorder, logger),
+		vault:          NewVault(cfg, deps.SolClient, deps.StatsRecorder, telegramClient, killSwitch, logger),
+		rentReclaimer:  NewRentReclaimer(cfg, store, deps.SolClient, deps.StatsRecorder, killSwitch, logger),
+		positionCap:    NewPositionCap(cfg, swapSvc, telegramClient, killSwitch, logger),
+		costBasis:      NewCostBasisWatch(cfg, swapSvc, deps.StatsRecorder, mintDecimals, receiver, logger),
+		accountCache:   accountCache,
+		accountClient:  accountClient,
+		mintDecimals:   mintDecimals,
+		sigClient:      sigClient,
+		receiver:       receiver,
+		rateLimits:     deps.RateLimits,
 	}
 }
 
 // ClaimAirdropByID claims an airdrop by its ID
 func (c *AirdropClaimer) ClaimAirdropByID(ctx context.Context, airdropID string) (string, error) {
-	return c.ClaimAirdropByIDWithConfig(ctx, airdropID, DefaultClaimConfig)
+	return c.ClaimAirdropByIDWithConfig(ctx, airdropID, NewClaimConfig(c.config))
 }
 
 // ClaimAirdropByIDWithConfig claims an airdrop by its ID with specific configuration options
 func (c *AirdropClaimer) ClaimAirdropByIDWithConfig(ctx context.Context, airdropID string, config ClaimConfig) (string, error) {
+	// Every output of the claim and its sale carries the operation ID
+	ctx, opID := operation.Start(ctx)
+	logger := operation.Logger(ctx, c.logger)
+
 	// Get airdrop information from the store
 	airdrop, err := c.store.ClaimAirdrop(airdropID)
 	if err != nil {
@@ -112,323 +217,702 @@ func (c *AirdropClaimer) ClaimAirdropByIDWithConfig(ctx context.Context, airdrop
 		return "", fmt.Errorf("airdrop %s is already claimed", airdropID)
 	}
 
-	c.logger.Printf("Claiming airdrop: %s, Token: %s (%s), Amount: %s",
+	airdrops := []models.AirdropNode{airdrop}
+	c.ResolveDecimals(ctx, airdrops)
+	airdrop = airdrops[0]
+
+	logger.Printf("Claiming airdrop: %s, Token: %s (%s), Amount: %s",
 		airdrop.ID, airdrop.Token.Name, airdrop.Token.Symbol, airdrop.AmountLpt)
+	if airdrop.FromCache {
+		logger.Printf("Airdrop %s is served from the local cache (Boop API unavailable)", airdrop.ID)
+	}
+
+	if err := c.killSwitch.Guard(); err != nil {
+		return "", err
+	}
 
 	// Load private key from config
 	if c.config.WalletPrivateKey == "" {
 		return "", fmt.Errorf("wallet private key not configured")
 	}
 
-	feePayer, err := solana.PrivateKeyFromBase58(c.config.WalletPrivateKey)
+	feePayer, err := keys.Parse(c.config.WalletPrivateKey)
 	if err != nil {
-		return "", fmt.Errorf("invalid private key: %w", err)
+		return "", err
 	}
 
 	signers := []solana.PrivateKey{feePayer}
 
-	block, err := sol.BlockhashCache.GetBlockhash(c.solClient)
+	// A fresh blockhash per claim, claims running in parallel must not share one close to expiry
+	block, err := sol.BlockhashCache.Latest(ctx, c.solClient)
 	if err != nil {
 		return "", fmt.Errorf("failed to get blockhash: %w", err)
 	}
 
 	tokenAddress := solana.MustPublicKeyFromBase58(airdrop.Token.Address)
 
-	ata, _, err := solana.FindAssociatedTokenAddress(feePayer.PublicKey(), tokenAddress)
+	accounts, err := c.claimAccounts.derive(feePayer.PublicKey(), tokenAddress)
 	if err != nil {
-		return "", fmt.Errorf("failed to find associated token address: %w", err)
+		return "", err
 	}
 
-	instrs := []solana.Instruction{
-		computebudget.NewSetComputeUnitLimitInstruction(200000).Build(),
-		computebudget.NewSetComputeUnitPriceInstruction(375000).Build(),
-		associated_token_account_extended.NewCreateIdempotentInstruction(
-			feePayer.PublicKey(),
-			feePayer.PublicKey(),
-			tokenAddress,
-		).Build(),
+	if !airdrop.AmountLpt.Valid() {
+		return "", fmt.Errorf("airdrop %s has no valid token amount", airdrop.ID)
 	}
+	tokens := airdrop.TokenAmount()
 
-	tokenAmount, err := strconv.ParseUint(airdrop.AmountLpt, 10, 64)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse token amount: %w", err)
+	if err := c.checkClaimAccounts(ctx, airdrop, accounts, tokens.Raw); err != nil {
+		if errors.Is(err, ErrAlreadyClaimedOnChain) {
+			// Remember it, so the claim is not attempted again
+			if markErr := c.store.MarkClaimed(airdrop.ID, ""); markErr != nil {
+				logging.Warnf(logger, "Warning: %v", markErr)
+			}
+		}
+		return "", err
+	}
+
+	if err := c.checkClaimRace(ctx, accounts); err != nil {
+		return "", err
 	}
 
-	proofs := [][]interface{}{}
-	proofs = append(proofs, airdrop.Proofs...)
-	proofBytes := [][32]uint8{}
+	if err := c.checkReceivingWalletMint(ctx, tokenAddress); err != nil {
+		return "", err
+	}
 
-	for _, proof := range proofs {
-		// Create a fixed size array for the proof
-		var fixed [32]uint8
+	proofs, err := airdrop.ClaimProofs()
+	if err != nil {
+		return "", err
+	}
 
-		// JSON-decoded proofs will be float64 values, not bytes
-		for i, val := range proof {
-			if i >= 32 {
-				break
-			}
+	// Sell in the claim transaction when enabled, so the claim and the sale land together
+	atomicSwap := c.atomicSellInstructions(ctx, airdrop, config, feePayer.PublicKey(), tokens.Raw)
+	claim := claimTransaction{
+		owner:    feePayer.PublicKey(),
+		mint:     tokenAddress,
+		receiver: c.receiver,
+		accounts: accounts,
+		amount:   tokens.Raw,
+		proofs:   proofs,
+		swap:     atomicSwap,
+	}
 
-			// Convert float64 to uint8
-			if floatVal, ok := val.(float64); ok {
-				fixed[i] = uint8(floatVal)
-			} else {
-				return "", fmt.Errorf("invalid proof value type: %T, expected float64", val)
-			}
+	buildTx := func(computeUnitLimit uint32) (*solana.Transaction, error) {
+		tx, err := claim.build(computeUnitLimit, block.Blockhash)
+		if err != nil {
+			return nil, err
 		}
 
-		proofBytes = append(proofBytes, fixed)
-	}
+		if _, err = tx.Sign(
+			func(key solana.PublicKey) *solana.PrivateKey {
+				for _, payer := range signers {
+					if payer.PublicKey().Equals(key) {
+						return &payer
+					}
+				}
+				return nil
+			},
+		); err != nil {
+			return nil, fmt.Errorf("failed to sign transaction: %w", err)
+		}
 
-	tokenDistributor, err := sol.FindMerkleDistributorPDA(BoopTokenDistributor, tokenAddress, BoopMerkleDistribution, 0)
-	if err != nil {
-		return "", fmt.Errorf("failed to find merkle distributor pda: %w", err)
+		return tx, nil
 	}
 
-	claimStatus, err := sol.FindClaimStatusPDA(feePayer.PublicKey(), tokenDistributor, BoopMerkleDistribution)
-	if err != nil {
-		return "", fmt.Errorf("failed to find claim status pda: %w", err)
+	// A claim that also sells needs room for the swap, its samples are kept apart from plain claims
+	operationType, fallbackLimit := sol.OperationClaim, uint32(defaultClaimComputeUnitLimit)
+	if atomicSwap != nil {
+		operationType, fallbackLimit = sol.OperationClaimAndSell, defaultClaimComputeUnitLimit+estimatedSwapComputeUnits
 	}
-
-	boopPool, err := sol.FindBoopPoolAddress(tokenAddress, tokenDistributor, true)
+	tx, err := buildTx(c.claimComputeUnitLimit(ctx, operationType, fallbackLimit, buildTx))
 	if err != nil {
-		return "", fmt.Errorf("failed to find boop pool address: %w", err)
-	}
-
-	// Create the claim instruction and call Build() to get the actual instruction
-	newClaimInstruction := boop.NewNewClaimInstructionBuilder(
-		tokenAmount,
-		0,
-		proofBytes,
-		tokenDistributor,
-		claimStatus,
-		boopPool,
-		ata,
-		feePayer.PublicKey(),
-	).Build()
-
-	instrs = append(instrs, newClaimInstruction)
-
-	c.logger.Printf("Creating transaction with %d instructions", len(instrs))
-
-	tx, err := solana.NewTransaction(
-		instrs,
-		block.Block.Blockhash,
-		solana.TransactionPayer(feePayer.PublicKey()),
-	)
+		return "", err
+	}
+	logging.Debugf(logger, "Created transaction with %d instructions", len(tx.Message.Instructions))
+
+	c.recordJournal(c.journal.RecordIntent(journal.EntryClaimIntent, airdrop))
+	c.sentClaims.add(tx.Signatures[0].String())
+
+	// Rebroadcast the claim until it lands or its blockhash expires, so a failed claim is known to be safe to
+	// retry. An expired claim is rebuilt by the next cycle, after the on-chain claim status is checked again.
+	sender := sol.NewSender(c.solClient, rpc.TransactionOpts{SkipPreflight: true},
+		c.config.RebroadcastInterval, sol.LogSendStatus(logger, "Claim"))
+	landing, err := sender.SendAndAwait(ctx, tx, block.LastValidBlockHeight)
+	sig := landing.Signature
+	// Whatever the outcome, the claim may have changed its accounts
+	c.invalidateClaimAccounts(accounts)
 	if err != nil {
-		return "", fmt.Errorf("failed to create transaction: %w", err)
+		// The claim may still land, leave the intent open in the journal instead of recording a failure
+		return "", fmt.Errorf("claim transaction %s has unknown outcome: %w", sig, err)
 	}
 
-	if _, err = tx.Sign(
-		func(key solana.PublicKey) *solana.PrivateKey {
-			for _, payer := range signers {
-				if payer.PublicKey().Equals(key) {
-					return &payer
-				}
-			}
-			return nil
-		},
-	); err != nil {
-		return "", fmt.Errorf("failed to sign transaction: %w", err)
+	switch landing.Status {
+	case sol.LandingExpired:
+		err = fmt.Errorf("claim transaction %s expired without landing", sig)
+		if landing.SendErr != nil {
+			err = fmt.Errorf("failed to send transaction: %w", landing.SendErr)
+		}
+	case sol.LandingFailed:
+		err = fmt.Errorf("claim transaction %s failed: %v", sig, landing.TxErr)
+		if atomicSwap != nil {
+			// The included sale may be what failed, claim and sell separately next time
+			c.atomicFallbacks.add(airdrop.Token.Address)
+		}
 	}
-
-	sig, err := c.solClient.SendTransactionWithOpts(
-		ctx,
-		tx,
-		rpc.TransactionOpts{
-			SkipPreflight: true,
-			//MaxRetries:    &maxRetries,
-		},
-	)
 	if err != nil {
-		return "", fmt.Errorf("failed to send transaction: %w", err)
+		c.recordJournal(c.journal.RecordOutcome(journal.EntryClaimOutcome, airdrop, "", err))
+		return "", err
 	}
 
-	c.logger.Printf("Claim transaction complete for airdrop %s. Signature: %s", airdropID, sig.String())
+	c.recordJournal(c.journal.RecordOutcome(journal.EntryClaimOutcome, airdrop, sig.String(), nil))
+	if err := c.store.MarkClaimed(airdrop.ID, sig.String()); err != nil {
+		logging.Warnf(logger, "Warning: %v", err)
+	}
 
-	// Record transaction fees
-	if c.statsRecorder != nil {
-		// Wait a moment for the transaction to be confirmed
-		time.Sleep(5 * time.Second)
+	logger.Printf("Claim transaction complete for airdrop %s. Signature: %s", airdropID, sig.String())
 
-		fees, _, err := sol.GetTransactionFeesAndEarnings(c.solClient, sig.String(), false)
-		if err != nil {
-			c.logger.Printf("Warning: Failed to get transaction fees: %v", err)
+	// Record transaction fees and rent, kept for the profit of the sale
+	var claimResult sol.TransactionResult
+	if c.statsRecorder != nil {
+		result, err := sol.AwaitTransactionResult(ctx, c.solClient, sig.String(), false)
+		if errors.Is(err, sol.ErrTransactionUnavailable) {
+			// Recorded as an estimate, reconciled from chain once the RPC node serves the transaction
+			logging.Warnf(logger, "Warning: Failed to get transaction fees, recording an estimate: %v", err)
+			if err := c.statsRecorder.RecordEstimatedClaimStats(
+				airdrop.Token.Symbol,
+				airdrop.AmountLpt.String(),
+				sol.EstimatedTransactionFee,
+				sig.String(),
+				opID,
+			); err != nil {
+				logging.Warnf(logger, "Warning: Failed to record estimated claim stats: %v", err)
+			}
+		} else if err != nil {
+			logging.Warnf(logger, "Warning: Failed to get transaction fees: %v", err)
 		} else {
-			c.logger.Printf("Transaction fees: %d lamports (%.5f SOL)", fees, float64(fees)/1_000_000_000)
+			fees := result.Fee
+			logger.Printf("Transaction fees: %d lamports (%s)", fees, amount.Lamports(fees))
+
+			// A sale in the claim transaction records the fee and rent with the swap of the same transaction,
+			// so they are not counted twice
+			rent := result.Rent
+			if atomicSwap != nil {
+				fees, rent = 0, 0
+			}
+			if rent != 0 {
+				logger.Printf("Account rent: %d lamports", rent)
+			}
+			claimResult = sol.TransactionResult{Fee: fees, Rent: rent}
+			c.recordJournal(c.journal.RecordSettlement(journal.EntryClaimOutcome, airdrop, sig.String(), claimResult))
 
 			err = c.statsRecorder.RecordClaimStats(
 				airdrop.Token.Symbol,
-				airdrop.AmountLpt,
+				airdrop.AmountLpt.String(),
 				fees,
+				rent,
 				sig.String(),
+				opID,
 			)
 			if err != nil {
-				c.logger.Printf("Warning: Failed to record claim stats: %v", err)
+				logging.Warnf(logger, "Warning: Failed to record claim stats: %v", err)
 			} else {
-				c.logger.Printf("Recorded claim statistics for airdrop %s", airdropID)
+				logging.Debugf(logger, "Recorded claim statistics for airdrop %s", airdropID)
 			}
 		}
 	}
 
+	// The claim landed, notify webhooks whether or not its fees could be read
+	c.webhookClient.Send(notifications.WebhookEvent{
+		Event:        notifications.EventClaimConfirmed,
+		Wallet:       c.config.WalletAddress,
+		AirdropID:    airdrop.ID,
+		TokenName:    airdrop.Token.Name,
+		TokenSymbol:  airdrop.Token.Symbol,
+		TokenMint:    airdrop.Token.Address,
+		Amount:       airdrop.AmountLpt.String(),
+		UsdValue:     airdrop.AmountUsd.String(),
+		TxHash:       sig.String(),
+		FeesLamports: claimResult.Fee,
+		OperationID:  opID,
+	})
+
 	// Send Telegram notification for successful claim
 	if c.telegramClient != nil {
-		usdValue := airdrop.AmountUsd
-		// Pass raw token amount directly to the notification function
-		amount := airdrop.AmountLpt
 		c.telegramClient.SendTokenClaimedNotification(
 			airdrop.Token.Name,
 			airdrop.Token.Symbol,
-			amount,
-			usdValue,
+			airdrop.TokenAmount(),
+			airdrop.AmountUsd.Float(),
 			sig.String(),
+			opID,
 		)
 	}
 
-	// If auto-sell is enabled, sell the token for SOL
-	if config.AutoSellToSol {
-		// Wait a bit for the claim transaction to confirm
-		time.Sleep(5 * time.Second)
+	if atomicSwap != nil {
+		c.recordJournal(c.journal.RecordIntent(journal.EntrySellIntent, airdrop))
+		c.completeSale(ctx, airdrop, sig, sol.TransactionResult{})
+		if c.statsRecorder != nil && atomicSwap.PlatformFee > 0 {
+			if err := c.statsRecorder.RecordPlatformFeeStats(airdrop.Token.Address, atomicSwap.PlatformFee, sig.String(), opID); err != nil {
+				logging.Warnf(logger, "Warning: Failed to record platform fee stats: %v", err)
+			}
+		}
+		return sig.String(), nil
+	}
 
-		c.logger.Printf("Auto-selling claimed tokens for SOL...")
+	// Kept tokens are held, wherever they go, their value at claim time is the basis of a later sale
+	if c.config.KeepsToken(airdrop.Token.Address) {
+		c.costBasis.RecordClaim(ctx, airdrop, sig.String())
+	}
 
-		// Store claim transaction fees for profit calculation
-		var claimFees uint64 = 0
-		if c.statsRecorder != nil {
-			// Get transaction fees for the claim
-			claimFeesFromTx, _, err := sol.GetTransactionFeesAndEarnings(c.solClient, sig.String(), false)
-			if err == nil {
-				claimFees = claimFeesFromTx
-			}
+	if !c.receiver.IsZero() {
+		logger.Printf("Moved %s tokens to receiving wallet %s", airdrop.Token.Symbol, c.receiver)
+		return sig.String(), nil
+	}
+
+	if !config.AutoSell {
+		logger.Printf("Auto-sell disabled, holding %s tokens", airdrop.Token.Symbol)
+		return sig.String(), nil
+	}
+
+	if c.config.KeepsToken(airdrop.Token.Address) {
+		logger.Printf("%s is on the keep-list, holding the tokens", airdrop.Token.Symbol)
+		return sig.String(), nil
+	}
+
+	// A delayed sale is scheduled so the claims after this one are not held up
+	if config.SellDelay > 0 {
+		c.scheduleSale(ctx, airdrop, tokens.Raw, claimResult, config)
+		return sig.String(), nil
+	}
+
+	c.sellClaimed(ctx, airdrop, tokens.Raw, claimResult, config)
+	return sig.String(), nil
+}
+
+// scheduleSale sells the claimed tokens in the background once the sell delay is over. A sale still waiting
+// when ctx is done is dropped and the tokens stay in the wallet, CleanUp waits for sales already sent.
+func (c *AirdropClaimer) scheduleSale(ctx context.Context, airdrop models.AirdropNode, raw uint64, claim sol.TransactionResult, config ClaimConfig) {
+	logger := operation.Logger(ctx, c.logger)
+	logger.Printf("Selling %s in %s", airdrop.Token.Symbol, config.SellDelay)
+
+	c.delayedSales.Add(1)
+	go func() {
+		defer c.delayedSales.Done()
+		timer := time.NewTimer(config.SellDelay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			logger.Printf("Stopped before selling %s, the tokens stay in the wallet", airdrop.Token.Symbol)
+			return
+		case <-timer.C:
 		}
+		c.sellClaimed(ctx, airdrop, raw, claim, config)
+	}()
+}
 
-		// Perform the swap
-		swapSig, err := c.swapSvc.SwapTokenForSol(ctx, c.config.WalletPrivateKey, airdrop.Token.Address, tokenAmount)
-		if err != nil {
-			c.logger.Printf("Warning: Failed to auto-sell tokens after all retry attempts: %v", err)
+// sellClaimed sells the tokens of a claim to the sell target. claim holds the fees and rent of the claim
+// transaction, they count against the profit of a sale for SOL.
+func (c *AirdropClaimer) sellClaimed(ctx context.Context, airdrop models.AirdropNode, raw uint64, claim sol.TransactionResult, config ClaimConfig) {
+	logger := operation.Logger(ctx, c.logger)
 
-			// If Telegram is enabled, send error notification
-			if c.telegramClient != nil && c.telegramClient.Enabled {
-				// Pass raw token amount directly to the notification function
-				amount := airdrop.AmountLpt
+	if config.SellTarget == SellTargetUsdc {
+		c.sellClaimedTokensForUsdc(ctx, airdrop, raw)
+		return
+	}
 
-				// Create a more readable error message
-				errorMsg := err.Error()
-				if len(errorMsg) > 100 {
-					errorMsg = errorMsg[:100] + "..."
-				}
+	// Sell the token for SOL
+	logger.Printf("Auto-selling claimed tokens for SOL...")
 
-				c.telegramClient.SendTokenSaleErrorNotification(
-					airdrop.Token.Name,
-					airdrop.Token.Symbol,
-					amount,
-					airdrop.AmountUsd,
-					errorMsg,
-					10, // Max attempts
-				)
-			}
+	// Perform the swap
+	c.recordJournal(c.journal.RecordIntent(journal.EntrySellIntent, airdrop))
+	swapSig, err := c.sellClaimedTokens(ctx, airdrop, raw)
+	if err != nil {
+		c.recordJournal(c.journal.RecordOutcome(journal.EntrySellOutcome, airdrop, "", err))
+
+		logging.Warnf(logger, "Warning: Failed to auto-sell tokens after all retry attempts: %v", err)
+		NotifySaleFailure(c.telegramClient, airdrop, err, operation.ID(ctx))
+		return
+	}
+
+	c.completeSale(ctx, airdrop, swapSig, claim)
+}
+
+// completeSale records the stats, outcome and service fee of a landed sale for SOL and announces it.
+// claim holds the fees and rent of the claim transaction, zero when the sale landed in the claim transaction.
+func (c *AirdropClaimer) completeSale(ctx context.Context, airdrop models.AirdropNode, swapSig solana.Signature, claim sol.TransactionResult) {
+	logger := operation.Logger(ctx, c.logger)
+
+	logger.Printf("🎉 Successfully sold tokens for SOL! Transaction: %s", swapSig.String())
+	c.recordJournal(c.journal.RecordOutcome(journal.EntrySellOutcome, airdrop, swapSig.String(), nil))
+
+	// Variables for profit calculation
+	var swap sol.TransactionResult
+	var netProfit float64 = 0.0
+	var serviceFee ServiceFeeCharge
+
+	// Record swap transaction statistics
+	if c.statsRecorder != nil {
+		// Get fees and earnings from the transaction once the RPC node serves it
+		var err error
+		swap, err = sol.AwaitTransactionResult(ctx, c.solClient, swapSig.String(), true)
+		if errors.Is(err, sol.ErrTransactionUnavailable) {
+			c.recordEstimatedSale(ctx, airdrop, swapSig, err)
+		} else if err != nil {
+			logging.Warnf(logger, "Warning: Failed to get swap transaction fees and earnings: %v", err)
 		} else {
-			c.logger.Printf("🎉 Successfully sold tokens for SOL! Transaction: %s", swapSig.String())
-
-			// Variables for profit calculation
-			var swapFees, swapEarnings uint64 = 0, 0
-			var netProfit float64 = 0.0
-
-			// Record swap transaction statistics
-			if c.statsRecorder != nil {
-				// Wait a moment for the transaction to be confirmed
-				time.Sleep(5 * time.Second)
-
-				// Get fees and earnings from the transaction
-				var err error
-				swapFees, swapEarnings, err = sol.GetTransactionFeesAndEarnings(c.solClient, swapSig.String(), true)
-				if err != nil {
-					c.logger.Printf("Warning: Failed to get swap transaction fees and earnings: %v", err)
-				} else {
-					c.logger.Printf("Swap fees: %d lamports (%.5f SOL)", swapFees, float64(swapFees)/1_000_000_000)
-					c.logger.Printf("Earnings: %d lamports (%.5f SOL)", swapEarnings, float64(swapEarnings)/1_000_000_000)
-
-					err = c.statsRecorder.RecordSwapStats(
-						airdrop.Token.Symbol,
-						airdrop.AmountLpt,
-						swapFees,
-						swapEarnings,
-						swapSig.String(),
-					)
-					if err != nil {
-						c.logger.Printf("Warning: Failed to record swap stats: %v", err)
-					} else {
-						c.logger.Printf("Recorded swap statistics for token %s", airdrop.Token.Symbol)
-					}
+			logger.Printf("Swap fees: %d lamports (%s)", swap.Fee, amount.Lamports(swap.Fee))
+			c.recordJournal(c.journal.RecordSettlement(journal.EntrySellOutcome, airdrop, swapSig.String(), swap))
+			logger.Printf("Earnings: %d lamports (%s)", swap.Earnings, amount.Lamports(swap.Earnings))
+			if swap.Rent != 0 {
+				logger.Printf("Account rent: %d lamports", swap.Rent)
+			}
 
-					// Calculate net profit (earnings - all fees)
-					netProfit = c.statsRecorder.CalculateNetProfitFromClaimAndSwap(claimFees, swapFees, swapEarnings)
-					c.logger.Printf("Net profit for transaction: %.5f SOL", netProfit)
-				}
+			err = c.statsRecorder.RecordSwapStats(
+				airdrop.Token.Symbol,
+				airdrop.AmountLpt.String(),
+				swap.Fee,
+				swap.Earnings,
+				swap.Rent,
+				swapSig.String(),
+				operation.ID(ctx),
+			)
+			if err != nil {
+				logging.Warnf(logger, "Warning: Failed to record swap stats: %v", err)
+			} else {
+				logging.Debugf(logger, "Recorded swap statistics for token %s", airdrop.Token.Symbol)
 			}
 
-			// Get profit summary for the notification
-			var profitSummary *notifications.ProfitSummary
-			solPrice := 0.0
-
-			// Calculate profit stats if possible
-			if c.statsRecorder != nil {
-				stats, err := c.statsRecorder.GetProfitSummary()
-				if err == nil {
-					// Convert to notifications.ProfitSummary
-					profitSummary = &notifications.ProfitSummary{
-						Last24h:       stats.Last24h,
-						LastWeek:      stats.LastWeek,
-						ProjectedWeek: stats.ProjectedWeek,
-					}
-				}
+			// Calculate net profit (earnings - all fees and rent)
+			netProfit = c.statsRecorder.CalculateNetProfitFromClaimAndSwap(claim, swap)
+			// Transfer the operator's share of the realized SOL, if configured
+			serviceFee, err = c.serviceFee.Charge(ctx, airdrop, swap.Earnings)
+			if err != nil {
+				logging.Warnf(logger, "Warning: Failed to transfer service fee: %v", err)
+			}
+			netProfit -= serviceFee.Sol()
 
-				// Get SOL price
-				if c.priceService != nil {
-					solPrice = c.priceService.GetCurrentPrice()
-					c.logger.Printf("Current SOL price: $%.2f", solPrice)
-				}
+			logger.Printf("Net profit for transaction: %.5f SOL", netProfit)
+
+			c.recordOutcome(airdrop, sol.StrategyClaimAndSell, swap.Earnings, swapSig.String())
+		}
+	}
+
+	// The sale landed, notify webhooks whether or not its fees and earnings could be read
+	c.webhookClient.Send(notifications.WebhookEvent{
+		Event:            notifications.EventSellConfirmed,
+		Wallet:           c.config.WalletAddress,
+		AirdropID:        airdrop.ID,
+		TokenName:        airdrop.Token.Name,
+		TokenSymbol:      airdrop.Token.Symbol,
+		TokenMint:        airdrop.Token.Address,
+		Amount:           airdrop.AmountLpt.String(),
+		UsdValue:         airdrop.AmountUsd.String(),
+		TxHash:           swapSig.String(),
+		FeesLamports:     swap.Fee,
+		EarningsLamports: swap.Earnings,
+		OperationID:      operation.ID(ctx),
+	})
+
+	// Get profit summary for the notification
+	var profitSummary *notifications.ProfitSummary
+	solPrice := 0.0
+
+	// Calculate profit stats if possible
+	if c.statsRecorder != nil {
+		stats, err := c.statsRecorder.GetProfitSummary(c.config.ProfitIncludeEstimates)
+		if err == nil {
+			// Convert to notifications.ProfitSummary
+			profitSummary = &notifications.ProfitSummary{
+				Last24h:       stats.Last24h,
+				LastWeek:      stats.LastWeek,
+				ProjectedWeek: stats.ProjectedWeek,
 			}
+		}
 
-			// Send notification about successful sale
-			if c.telegramClient != nil {
-				// Pass raw token amount directly to the notification function
-				amount := airdrop.AmountLpt
-				c.telegramClient.SendTokenSoldNotification(
-					airdrop.Token.Name,
-					airdrop.Token.Symbol,
-					amount,
-					fmt.Sprintf("%.5f", netProfit),
-					profitSummary,
-					solPrice,
-					swapSig.String(),
-				)
+		// Get SOL price, left at 0 when unknown so the notification omits USD values
+		if c.priceService != nil {
+			if quote, ok := c.priceService.GetPrice(); ok {
+				solPrice = quote.Price
+				logger.Printf("Current SOL price: $%.2f (%s, %s old)", solPrice, quote.Source, quote.Age().Round(time.Second))
+			} else {
+				logging.Warnf(logger, "Warning: SOL price unknown, USD values will be omitted")
 			}
 		}
 	}
 
-	return sig.String(), nil
+	// Send notification about successful sale
+	if c.telegramClient != nil {
+		c.telegramClient.SendTokenSoldNotification(
+			airdrop.Token.Name,
+			airdrop.Token.Symbol,
+			airdrop.TokenAmount(),
+			netProfit,
+			profitSummary,
+			solPrice,
+			swapSig.String(),
+			operation.ID(ctx),
+		)
+	}
+	c.serviceFee.Notify(airdrop, serviceFee, solPrice)
+}
+
+// recordEstimatedSale records a sale whose transaction the RPC node did not serve as an estimate from the API's
+// SOL value, reconciled from chain by a later cycle. No service fee is charged on estimated earnings.
+func (c *AirdropClaimer) recordEstimatedSale(ctx context.Context, airdrop models.AirdropNode, swapSig solana.Signature, err error) {
+	logger := operation.Logger(ctx, c.logger)
+	logging.Warnf(logger, "Warning: Failed to get swap transaction fees and earnings, recording an estimate: %v", err)
+
+	estimatedEarnings := airdrop.AmountSolLpt.Lamports()
+	if !airdrop.AmountSolLpt.Known() {
+		logging.Warnf(logger, "Warning: SOL value of %s unknown, recording zero estimated earnings", airdrop.Token.Symbol)
+	}
+
+	if err := c.statsRecorder.RecordEstimatedSwapStats(
+		airdrop.Token.Symbol,
+		airdrop.AmountLpt.String(),
+		sol.EstimatedTransactionFee,
+		uint64(estimatedEarnings),
+		swapSig.String(),
+		operation.ID(ctx),
+	); err != nil {
+		logging.Warnf(logger, "Warning: Failed to record estimated swap stats: %v", err)
+	}
+}
+
+// recordOutcome records the expected USD value of an airdrop against the SOL its sale realized
+func (c *AirdropClaimer) recordOutcome(airdrop models.AirdropNode, strategy string, earnings uint64, txHash string) {
+	expectedUsd := airdrop.AmountUsd.Float()
+
+	solPrice := 0.0
+	if c.priceService != nil {
+		if quote, ok := c.priceService.GetPrice(); ok {
+			solPrice = quote.Price
+		}
+	}
+
+	err := c.statsRecorder.RecordOutcome(sol.Outcome{
+		AirdropID:   airdrop.ID,
+		TokenSymbol: airdrop.Token.Symbol,
+		Strategy:    strategy,
+		ExpectedUsd: expectedUsd,
+		RealizedSol: amount.Lamports(earnings).Sol(),
+		SolPrice:    solPrice,
+		TxHash:      txHash,
+	})
+	if err != nil {
+		logging.Warnf(c.logger, "Warning: Failed to record outcome: %v", err)
+	}
+}
+
+// recordJournal logs journal write failures without interrupting the operation
+func (c *AirdropClaimer) recordJournal(err error) {
+	if err != nil {
+		logging.Warnf(c.logger, "Warning: Failed to write operation journal: %v", err)
+	}
 }
 
-// CleanUp performs cleanup when the claimer is no longer needed
+// Start starts the claimer's background services, which stop when ctx is cancelled
+func (c *AirdropClaimer) Start(ctx context.Context) {
+	if c.priceService != nil {
+		c.priceService.Start(ctx)
+	}
+	// Events of claims and sales finishing after ctx is cancelled are still queued, CleanUp delivers them
+	c.webhookClient.StartQueue(context.Background())
+}
+
+// CleanUp performs cleanup when the claimer is no longer needed, after the context the claims ran with is done
 func (c *AirdropClaimer) CleanUp() {
+	// Sales waiting for their delay give up once the context is done, those already sent are recorded first
+	c.delayedSales.Wait()
+	c.webhookClient.StopQueue()
+
 	if c.priceService != nil {
 		c.priceService.Stop()
 		c.logger.Println("Stopped price service")
 	}
+
+	if c.journal != nil {
+		c.journal.Close()
+	}
+
+	if c.statsRecorder != nil {
+		if err := c.statsRecorder.Close(); err != nil {
+			logging.Warnf(c.logger, "Warning: Failed to close stats recorder: %v", err)
+		}
+	}
 }
 
 // GetSwapService returns the swap service instance
-func (c *AirdropClaimer) GetSwapService() *jupiter.SwapService {
+func (c *AirdropClaimer) GetSwapService() SwapProvider {
 	return c.swapSvc
 }
 
+// GetStatsRecorder returns the stats recorder instance
+func (c *AirdropClaimer) GetStatsRecorder() StatsStore {
+	return c.statsRecorder
+}
+
 // GetPriceService returns the price service instance
-func (c *AirdropClaimer) GetPriceService() *sol.PriceService {
+func (c *AirdropClaimer) GetPriceService() PriceProvider {
 	return c.priceService
 }
 
+// GetWebhookClient returns the webhook client instance
+func (c *AirdropClaimer) GetWebhookClient() *notifications.WebhookClient {
+	return c.webhookClient
+}
+
+// GetJournal returns the operation journal, nil when journaling is disabled
+func (c *AirdropClaimer) GetJournal() *journal.Journal {
+	return c.journal
+}
+
 // GetSolClient returns the Solana client instance
-func (c *AirdropClaimer) GetSolClient() *rpc.Client {
+func (c *AirdropClaimer) GetSolClient() sol.RPCClient {
 	return c.solClient
 }
+
+// GetSaleBreaker returns the breaker pausing the sales of failing tokens, nil when it is disabled
+func (c *AirdropClaimer) GetSaleBreaker() *CircuitBreaker {
+	return c.saleBreaker
+}
+
+// GetKillSwitch returns the kill switch, nil when neither a kill switch file nor URL is configured
+func (c *AirdropClaimer) GetKillSwitch() *KillSwitch {
+	return c.killSwitch
+}
+
+// sellClaimedTokens swaps claimed tokens for SOL
+func (c *AirdropClaimer) sellClaimedTokens(ctx context.Context, airdrop models.AirdropNode, amount uint64) (solana.Signature, error) {
+	return c.sellTokens(ctx, airdrop, amount, c.swapSvc.SwapTokenForSol)
+}
+
+// sellTokens runs the swap unless the kill switch was engaged after the claim or the swap quote stays
+// far below the airdrop's SOL value. A caller tracing quotes with jupiter.TraceQuote gets the age of the
+// quote the swap was sent with.
+func (c *AirdropClaimer) sellTokens(ctx context.Context, airdrop models.AirdropNode, amount uint64,
+	swap func(ctx context.Context, privateKeyBase58 string, inputMint string, amount uint64) (solana.Signature, error)) (solana.Signature, error) {
+	if err := c.killSwitch.Guard(); err != nil {
+		return solana.Signature{}, err
+	}
+	if err := c.quoteGuard.Wait(ctx, airdrop, amount); err != nil {
+		return solana.Signature{}, err
+	}
+	return swap(ctx, c.config.WalletPrivateKey, airdrop.Token.Address, amount)
+}
+
+// sellClaimedTokensForUsdc swaps claimed tokens for USDC. SOL based profit stats do not apply, the sale
+// is journaled and announced with the USD value it was claimed at.
+func (c *AirdropClaimer) sellClaimedTokensForUsdc(ctx context.Context, airdrop models.AirdropNode, amount uint64) {
+	logger := operation.Logger(ctx, c.logger)
+
+	logger.Printf("Auto-selling claimed tokens for USDC...")
+	c.recordJournal(c.journal.RecordIntent(journal.EntrySellIntent, airdrop))
+
+	swapSig, err := c.sellTokens(ctx, airdrop, amount, c.swapSvc.SwapTokenForUsdc)
+	if err != nil {
+		c.recordJournal(c.journal.RecordOutcome(journal.EntrySellOutcome, airdrop, "", err))
+		logging.Warnf(logger, "Warning: Failed to sell tokens for USDC: %v", err)
+		NotifySaleFailure(c.telegramClient, airdrop, err, operation.ID(ctx))
+		return
+	}
+
+	logger.Printf("🎉 Successfully sold tokens for USDC! Transaction: %s", swapSig)
+	c.recordJournal(c.journal.RecordOutcome(journal.EntrySellOutcome, airdrop, swapSig.String(), nil))
+	if c.telegramClient != nil {
+		c.telegramClient.SendTokenSoldForUsdcNotification(airdrop.Token.Name, airdrop.Token.Symbol, airdrop.TokenAmount(),
+			airdrop.AmountUsd.Float(), swapSig.String(), operation.ID(ctx))
+	}
+}
+
+// GetRateLimits returns the rate limits of the claimer's RPC and swap clients
+func (c *AirdropClaimer) GetRateLimits() []*retry.RateLimits {
+	return c.rateLimits
+}
+
+// GetVault returns the vault sweeper, nil when vault transfers are disabled
+func (c *AirdropClaimer) GetVault() *Vault {
+	return c.vault
+}
+
+// GetCostBasis returns the cost basis watch of kept tokens, nil when no tokens are kept
+func (c *AirdropClaimer) GetCostBasis() *CostBasisWatch {
+	return c.costBasis
+}
+
+// GetRentReclaimer returns the closer of empty token accounts, nil when rent reclamation is disabled
+func (c *AirdropClaimer) GetRentReclaimer() *RentReclaimer {
+	return c.rentReclaimer
+}
+
+// GetPositionCap returns the position cap check, nil when no cap is configured
+func (c *AirdropClaimer) GetPositionCap() *PositionCap {
+	return c.positionCap
+}
+
+// GetQuoteGuard returns the swap quote cross-check, nil when it is disabled
+func (c *AirdropClaimer) GetQuoteGuard() *QuoteGuard {
+	return c.quoteGuard
+}
+
+// GetProfitCheck returns the profit pre-check, nil when it is disabled
+func (c *AirdropClaimer) GetProfitCheck() *ProfitCheck {
+	return c.profitCheck
+}
+
+// GetServiceFee returns the service fee charger, nil when no service fee is configured
+func (c *AirdropClaimer) GetServiceFee() *ServiceFee {
+	return c.serviceFee
+}
+
+// claimComputeUnitLimit simulates the claim to measure its compute units, records the sample under the
+// operation type and returns the limit derived from the p95 of recorded samples of that type plus the
+// configured margin, fallback until enough samples were recorded
+func (c *AirdropClaimer) claimComputeUnitLimit(ctx context.Context, operationType string, fallback uint32, buildTx func(uint32) (*solana.Transaction, error)) uint32 {
+	limit := fallback
+	if c.statsRecorder != nil {
+		limit = c.statsRecorder.ComputeUnitLimit(operationType, c.config.ComputeUnitMargin, limit)
+	}
+
+	if !c.config.SimulateClaims {
+		return limit
+	}
+
+	tx, err := buildTx(sol.MaxComputeUnitLimit)
+	if err != nil {
+		logging.Warnf(c.logger, "Warning: Failed to build claim for simulation: %v", err)
+		return limit
+	}
+
+	result, err := c.solClient.SimulateTransactionWithOpts(ctx, tx, &rpc.SimulateTransactionOpts{
+		SigVerify:              false,
+		ReplaceRecentBlockhash: true,
+		Commitment:             rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		logging.Warnf(c.logger, "Warning: Failed to simulate claim, using %d compute units: %v", limit, err)
+		return limit
+	}
+	if result.Value == nil || result.Value.UnitsConsumed == nil {
+		return limit
+	}
+	if result.Value.Err != nil {
+		logging.Warnf(c.logger, "Warning: Claim simulation failed, using %d compute units: %v", limit, result.Value.Err)
+		return limit
+	}
+
+	consumed := *result.Value.UnitsConsumed
+	if c.statsRecorder != nil {
+		if err := c.statsRecorder.RecordComputeUnits(operationType, consumed); err != nil {
+			logging.Warnf(c.logger, "Warning: Failed to record compute units: %v", err)
+		}
+		limit = c.statsRecorder.ComputeUnitLimit(operationType, c.config.ComputeUnitMargin, limit)
+	}
+
+	// Never go below what this claim is known to need
+	if minimum := sol.ComputeUnitLimitWithMargin(consumed, c.config.ComputeUnitMargin); limit < minimum {
+		limit = minimum
+	}
+
+	c.logger.Printf("Claim simulation consumed %d compute units, setting limit to %d", consumed, limit)
+	return limit
+}
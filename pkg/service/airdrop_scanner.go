@@ -4,70 +4,128 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"strconv"
+	"math"
+	"strings"
+	"time"
 
 	"boop-airdrop-redeemer/pkg/api"
 	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
 	"boop-airdrop-redeemer/pkg/models"
+	"boop-airdrop-redeemer/pkg/retry"
+)
+
+// Scans log a summary of counts and the most valuable airdrops. One by one they log new airdrops and value
+// changes that are material, every change only with debug logging, so wallets with thousands of airdrops
+// whose prices move every cycle do not flood the logs.
+const (
+	// maxLoggedAirdrops is the most airdrops logged one by one per scan outside debug logging
+	maxLoggedAirdrops = 20
+	// materialValueChange is the relative change from the last logged USD value that is logged again
+	materialValueChange = 0.25
+	// minLoggedUsd is the value below which repriced airdrops are only counted
+	minLoggedUsd = 0.05
+	// topAirdropsLogged is the number of most valuable airdrops listed in the scan summary
+	topAirdropsLogged = 5
 )
 
 // AirdropScanner handles scanning for new airdrops
 type AirdropScanner struct {
-	client *api.BoopClient
-	store  AirdropStore
-	logger *log.Logger
+	client  *api.BoopClient
+	store   AirdropStore
+	logger  *log.Logger
+	verbose bool // Log every new and repriced airdrop, set by DEBUG
+
+	// USD value each airdrop was last logged with, to tell material changes from price noise
+	loggedUsd map[string]float64
+
+	// Degraded mode state, set while airdrops are served from the local cache
+	usingCache bool
+	cachedAt   time.Time
 }
 
 // NewAirdropScanner creates a new scanner with the provided dependencies
 func NewAirdropScanner(store AirdropStore, cfg *config.Config, logger *log.Logger) *AirdropScanner {
+	logger = logging.Component(logger, "scanner")
+
 	// Initialize Boop API client
 	client := api.NewBoopClient(cfg, logger)
 
 	return &AirdropScanner{
-		client: client,
-		store:  store,
-		logger: logger,
+		client:    client,
+		store:     store,
+		logger:    logger,
+		verbose:   cfg.Debug,
+		loggedUsd: make(map[string]float64),
 	}
 }
 
+// RateLimits returns the rate limits the scanner's API client ran into
+func (s *AirdropScanner) RateLimits() *retry.RateLimits {
+	return s.client.RateLimits()
+}
+
 // ScanAirdrops scans for all airdrops, includes previously seen airdrops but updates their values
 // Returns all valuable airdrops that meet the threshold
 func (s *AirdropScanner) ScanAirdrops(ctx context.Context, usdThreshold float64) ([]models.AirdropNode, error) {
-	// Fetch pending airdrops from API
-	allAirdrops, err := s.client.GetPendingAirdrops(ctx)
+	// Fetch pending airdrops from API, falling back to the cache during outages
+	allAirdrops, err := s.fetchAirdropsWithFallback(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch airdrops: %w", err)
+		return nil, err
 	}
 
-	valuableAirdrops := []models.AirdropNode{}
-	newAirdropCount := 0
+	// Save or update all airdrops at once, only new and changed ones are written and logged
+	changes := s.store.SaveAirdrops(allAirdrops)
+
+	valuableAirdrops := make([]models.AirdropNode, 0, len(allAirdrops))
+	newAirdropCount, updatedAirdropCount, loggedCount := 0, 0, 0
+	truncated := false
+	detailed := s.verbose || logging.DebugEnabled()
 
 	// Process all airdrops
-	for _, airdrop := range allAirdrops {
-		isNew := !s.store.HasAirdropWithID(airdrop.ID)
-		if isNew {
+	for i, airdrop := range allAirdrops {
+		switch changes[i] {
+		case AirdropNew:
 			newAirdropCount++
+		case AirdropUpdated:
+			updatedAirdropCount++
 		}
 
-		// Save or update airdrop in store regardless if it's new or existing
-		s.store.SaveAirdrop(airdrop)
+		// Check airdrop value, passing airdrops without a USD value on so they can be judged by their SOL value
+		amountUsd, ok := airdrop.UsdValue()
 
-		// Check airdrop value
-		amountUsd, err := strconv.ParseFloat(airdrop.AmountUsd, 64)
-		if err != nil {
-			s.logger.Printf("Error parsing amount USD for airdrop %s: %v", airdrop.ID, err)
+		reported := changes[i] == AirdropNew ||
+			(changes[i] == AirdropUpdated && (detailed || s.materialChange(airdrop.ID, amountUsd)))
+		logged := reported && (detailed || loggedCount < maxLoggedAirdrops)
+		truncated = truncated || (reported && !logged)
+		if logged {
+			s.loggedUsd[airdrop.ID] = amountUsd
+		}
+
+		if !ok {
+			if solValue, hasSol := airdrop.SolValue(); hasSol {
+				if logged {
+					s.logger.Printf("No USD value for airdrop: ID=%s, Token=%s (%s), Amount=%.5f SOL",
+						airdrop.ID, airdrop.Token.Name, airdrop.Token.Symbol, solValue)
+					loggedCount++
+				}
+				valuableAirdrops = append(valuableAirdrops, airdrop)
+			} else if logged {
+				s.logger.Printf("Airdrop %s has neither a USD nor a SOL value, skipping it", airdrop.ID)
+				loggedCount++
+			}
 			continue
 		}
 
 		// Log differently for new vs. updated airdrops
-		if isNew {
+		if logged && changes[i] == AirdropNew {
 			s.logger.Printf("Found new airdrop: ID=%s, Token=%s (%s), Amount=$%.2f",
 				airdrop.ID, airdrop.Token.Name, airdrop.Token.Symbol, amountUsd)
-		} else {
-			if amountUsd > 0.05 {
-				s.logger.Printf("Updated airdrop price: ID=%s, Token=%s (%s), Current value=$%.2f",
-					airdrop.ID, airdrop.Token.Name, airdrop.Token.Symbol, amountUsd)
-			}
+			loggedCount++
+		} else if logged {
+			s.logger.Printf("Updated airdrop price: ID=%s, Token=%s (%s), Current value=$%.2f",
+				airdrop.ID, airdrop.Token.Name, airdrop.Token.Symbol, amountUsd)
+			loggedCount++
 		}
 
 		// Add to valuable airdrops if it meets threshold
@@ -81,6 +139,15 @@ func (s *AirdropScanner) ScanAirdrops(ctx context.Context, usdThreshold float64)
 	} else {
 		s.logger.Printf("Found %d new airdrop(s)", newAirdropCount)
 	}
+	if updatedAirdropCount > 0 {
+		s.logger.Printf("Updated %d airdrop(s), %d unchanged", updatedAirdropCount, len(allAirdrops)-newAirdropCount-updatedAirdropCount)
+	}
+	if truncated {
+		s.logger.Printf("Only the first %d new or updated airdrops are logged, set LOG_LEVEL=debug to log all", maxLoggedAirdrops)
+	}
+	if top := topAirdrops(allAirdrops, topAirdropsLogged); len(top) > 0 {
+		s.logger.Printf("Most valuable airdrops: %s", formatAirdropValues(top))
+	}
 
 	if len(valuableAirdrops) > 0 {
 		s.logger.Printf("Found %d airdrop(s) meeting value threshold", len(valuableAirdrops))
@@ -89,6 +156,110 @@ func (s *AirdropScanner) ScanAirdrops(ctx context.Context, usdThreshold float64)
 	return valuableAirdrops, nil
 }
 
+// materialChange reports whether the airdrop's USD value moved enough since it was last logged to log it again
+func (s *AirdropScanner) materialChange(airdropID string, amountUsd float64) bool {
+	if amountUsd < minLoggedUsd {
+		return false
+	}
+	last, ok := s.loggedUsd[airdropID]
+	return !ok || math.Abs(amountUsd-last) >= materialValueChange*last
+}
+
+// topAirdrops returns up to n airdrops with the highest USD value, most valuable first
+func topAirdrops(airdrops []models.AirdropNode, n int) []models.AirdropNode {
+	top := make([]models.AirdropNode, 0, n+1)
+	for _, airdrop := range airdrops {
+		usd, ok := airdrop.UsdValue()
+		if !ok || usd <= 0 {
+			continue
+		}
+
+		// Insertion into the short sorted list, scans of thousands of airdrops need no full sort
+		i := len(top)
+		for i > 0 && top[i-1].AmountUsd.Float() < usd {
+			i--
+		}
+		if i >= n {
+			continue
+		}
+		top = append(top, models.AirdropNode{})
+		copy(top[i+1:], top[i:])
+		top[i] = airdrop
+		if len(top) > n {
+			top = top[:n]
+		}
+	}
+	return top
+}
+
+// formatAirdropValues lists airdrops as "SYMBOL $value"
+func formatAirdropValues(airdrops []models.AirdropNode) string {
+	parts := make([]string, len(airdrops))
+	for i, airdrop := range airdrops {
+		parts[i] = fmt.Sprintf("%s $%.2f", airdrop.Token.Symbol, airdrop.AmountUsd.Float())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// fetchAirdropsWithFallback fetches pending airdrops from the API. When the API fails for reasons other than
+// authentication, the last cached payload is returned instead so claiming can continue with still-valid proofs.
+func (s *AirdropScanner) fetchAirdropsWithFallback(ctx context.Context) ([]models.AirdropNode, error) {
+	allAirdrops, err := s.client.GetPendingAirdrops(ctx)
+	if err == nil {
+		if s.usingCache {
+			s.logger.Println("Boop API is available again, leaving degraded mode")
+		}
+		s.usingCache = false
+		return allAirdrops, nil
+	}
+
+	// Auth errors are handled by refreshing tokens, not by serving stale data
+	if api.IsAuthError(err) {
+		return nil, fmt.Errorf("failed to fetch airdrops: %w", err)
+	}
+
+	cached, cachedAt, cacheErr := s.client.GetCachedAirdrops()
+	if cacheErr != nil {
+		return nil, fmt.Errorf("failed to fetch airdrops: %w (no usable cache: %v)", err, cacheErr)
+	}
+
+	s.logger.Printf("DEGRADED MODE: Boop API unavailable (%v), using %d cached airdrop(s) from %s - USD values may be stale",
+		err, len(cached), cachedAt.Format("2006-01-02 15:04:05"))
+	s.usingCache = true
+	s.cachedAt = cachedAt
+
+	return cached, nil
+}
+
+// UsingCachedData reports whether the last scan was served from the cache and when that cache was fetched
+func (s *AirdropScanner) UsingCachedData() (bool, time.Time) {
+	return s.usingCache, s.cachedAt
+}
+
+// IsClaimed reports whether the store has a claim recorded for the airdrop
+func (s *AirdropScanner) IsClaimed(airdropID string) bool {
+	airdrop, err := s.store.ClaimAirdrop(airdropID)
+	return err == nil && airdrop.ClaimedAt != nil
+}
+
+// Airdrop returns the stored airdrop with the given id
+func (s *AirdropScanner) Airdrop(airdropID string) (models.AirdropNode, bool) {
+	airdrop, err := s.store.ClaimAirdrop(airdropID)
+	return airdrop, err == nil
+}
+
+// Airdrops returns every airdrop in the store
+func (s *AirdropScanner) Airdrops() []models.AirdropNode {
+	return s.store.GetAllAirdrops()
+}
+
+// MarkClaimed removes a claimed airdrop from the cache so it is not claimed again from cached data
+func (s *AirdropScanner) MarkClaimed(airdropID string) {
+	if err := s.client.RemoveCachedAirdrop(airdropID); err != nil {
+		logging.Warnf(s.logger, "Warning: Failed to remove airdrop %s from cache: %v", airdropID, err)
+	}
+}
+
 // ScanNewAirdrops scans for new airdrops and returns them (for backward compatibility)
 func (s *AirdropScanner) ScanNewAirdrops(ctx context.Context) ([]models.AirdropNode, error) {
 	// Fetch pending airdrops from API
@@ -103,11 +274,11 @@ func (s *AirdropScanner) ScanNewAirdrops(ctx context.Context) ([]models.AirdropN
 		// Check if we already know about this airdrop
 		if !s.store.HasAirdropWithID(airdrop.ID) {
 			// This is a new airdrop
-			amountUsd, err := strconv.ParseFloat(airdrop.AmountUsd, 64)
-			if err != nil {
-				s.logger.Printf("Error parsing amount usd for airdrop %s: %v", airdrop.ID, err)
+			if !airdrop.AmountUsd.Known() {
+				s.logger.Printf("Airdrop %s has no USD value, skipping it", airdrop.ID)
 				continue
 			}
+			amountUsd := airdrop.AmountUsd.Float()
 
 			s.logger.Printf("Found new airdrop: ID=%s, Token=%s (%s), Amount=$%.2f",
 				airdrop.ID, airdrop.Token.Name, airdrop.Token.Symbol, amountUsd)
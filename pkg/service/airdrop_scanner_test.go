@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"boop-airdrop-redeemer/pkg/amount"
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/models"
+)
+
+// benchmarkAirdrops is the number of pending airdrops of a heavily farming wallet
+const benchmarkAirdrops = 5000
+
+// testAirdrops returns count pending airdrops with a 14 node proof each, priced at version cents
+func testAirdrops(count, version int) []models.AirdropNode {
+	proof := make([]json.RawMessage, 14)
+	for i := range proof {
+		proof[i] = proofNode(byte(i))
+	}
+
+	airdrops := make([]models.AirdropNode, count)
+	for i := range airdrops {
+		airdrops[i] = models.AirdropNode{
+			ID:           fmt.Sprintf("airdrop-%d", i),
+			AmountLpt:    models.NewRawAmount(uint64(1_000_000_000 + i)),
+			AmountUsd:    models.NewUSD(float64(i%100) + float64(version%100)/100),
+			AmountSolLpt: models.NewLamportValue(amount.Lamports(1_000_000 + i)),
+			Proofs:       proof,
+			Token: models.Token{
+				Name:    fmt.Sprintf("Token %d", i),
+				Address: testKey(fmt.Sprint("mint-", i)).String(),
+				Symbol:  fmt.Sprintf("TK%d", i),
+			},
+		}
+	}
+	return airdrops
+}
+
+// airdropResponseBody encodes airdrops as a GraphQL response of the Boop API
+func airdropResponseBody(t testing.TB, airdrops []models.AirdropNode) []byte {
+	var response models.GraphQLResponse
+	response.Data.Account = &models.AccountData{}
+	response.Data.Account.StakingAirdrops.Nodes = airdrops
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+// newTestScanner returns a scanner reading the Boop API from a test server serving the current body
+func newTestScanner(t testing.TB, store AirdropStore, body *atomic.Pointer[[]byte]) *AirdropScanner {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(*body.Load())
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{
+		Endpoints:        config.Endpoints{GraphQLURL: server.URL},
+		AuthToken:        "test",
+		AirdropCachePath: filepath.Join(t.TempDir(), "airdrop_cache.json"),
+	}
+	return NewAirdropScanner(store, cfg, log.New(io.Discard, "", 0))
+}
+
+func TestAirdropStoresReportChanges(t *testing.T) {
+	sqliteStore, err := NewSQLiteAirdropStore(filepath.Join(t.TempDir(), "airdrops.db"), log.New(io.Discard, "", 0))
+	assert.NoError(t, err)
+	jsonStore, err := NewJSONAirdropStore(filepath.Join(t.TempDir(), "airdrops.json"), time.Minute, log.New(io.Discard, "", 0))
+	assert.NoError(t, err)
+	boltStore, err := NewBoltAirdropStore(filepath.Join(t.TempDir(), "airdrops.bolt"), log.New(io.Discard, "", 0))
+	assert.NoError(t, err)
+
+	stores := map[string]AirdropStore{StoreMemory: NewInMemoryAirdropStore(), StoreSQLite: sqliteStore, StoreJSON: jsonStore, StoreBolt: boltStore}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			airdrops := testAirdrops(3, 1)
+			changes := store.SaveAirdrops(airdrops)
+			assert.Equal(t, []AirdropChange{AirdropNew, AirdropNew, AirdropNew}, changes)
+
+			// Unchanged airdrops are not written again, a new price is an update
+			updated := testAirdrops(3, 1)
+			updated[1].AmountUsd = models.NewUSD(9.99)
+			changes = store.SaveAirdrops(updated)
+			assert.Equal(t, []AirdropChange{AirdropUnchanged, AirdropUpdated, AirdropUnchanged}, changes)
+
+			stored, err := store.ClaimAirdrop(updated[1].ID)
+			assert.NoError(t, err)
+			assert.Equal(t, 9.99, stored.AmountUsd.Float())
+
+			// Locally recorded claims are applied to the saved airdrops
+			assert.NoError(t, store.MarkClaimed(updated[2].ID, "tx"))
+			rescanned := testAirdrops(3, 1)
+			store.SaveAirdrops(rescanned)
+			assert.Nil(t, rescanned[0].ClaimedAt)
+			assert.NotNil(t, rescanned[2].ClaimedAt)
+			assert.Len(t, store.GetAllAirdrops(), 3)
+		})
+	}
+}
+
+func TestJSONAirdropStoreSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "airdrops.json")
+	store, err := NewJSONAirdropStore(path, time.Hour, log.New(io.Discard, "", 0))
+	assert.NoError(t, err)
+
+	store.SaveAirdrops(testAirdrops(3, 1))
+	assert.NoFileExists(t, path, "saves wait for the flush interval")
+
+	// Claims are written right away, with the pending saves
+	assert.NoError(t, store.MarkClaimed("airdrop-1", "tx"))
+	assert.NoFileExists(t, path+".tmp")
+
+	reopened, err := NewJSONAirdropStore(path, time.Hour, log.New(io.Discard, "", 0))
+	assert.NoError(t, err)
+	assert.Len(t, reopened.GetAllAirdrops(), 3)
+
+	rescanned := testAirdrops(3, 1)
+	changes := reopened.SaveAirdrops(rescanned)
+	assert.Equal(t, []AirdropChange{AirdropUnchanged, AirdropUnchanged, AirdropUnchanged}, changes)
+	assert.NotNil(t, rescanned[1].ClaimedAt)
+}
+
+func TestBoltAirdropStoreSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "airdrops.bolt")
+	store, err := NewBoltAirdropStore(path, log.New(io.Discard, "", 0))
+	require.NoError(t, err)
+	store.SaveAirdrops(testAirdrops(3, 1))
+	assert.NoError(t, store.MarkClaimed("airdrop-1", "tx"))
+	require.NoError(t, store.(*boltAirdropStore).Close())
+
+	reopened, err := NewBoltAirdropStore(path, log.New(io.Discard, "", 0))
+	require.NoError(t, err)
+	defer reopened.(*boltAirdropStore).Close()
+	assert.Len(t, reopened.GetAllAirdrops(), 3)
+
+	rescanned := testAirdrops(3, 1)
+	changes := reopened.SaveAirdrops(rescanned)
+	assert.Equal(t, []AirdropChange{AirdropUnchanged, AirdropUnchanged, AirdropUnchanged}, changes)
+	assert.NotNil(t, rescanned[1].ClaimedAt)
+}
+
+func TestScanAirdropsLogsChangesOnly(t *testing.T) {
+	var body atomic.Pointer[[]byte]
+	first := airdropResponseBody(t, testAirdrops(3, 1))
+	body.Store(&first)
+
+	store := NewInMemoryAirdropStore()
+	scanner := newTestScanner(t, store, &body)
+	var logs strings.Builder
+	scanner.logger = log.New(&logs, "", 0)
+
+	valuable, err := scanner.ScanAirdrops(context.Background(), 0.001)
+	assert.NoError(t, err)
+	assert.Len(t, valuable, 3)
+	assert.Equal(t, 3, strings.Count(logs.String(), "Found new airdrop"))
+
+	// The same payload again logs nothing per airdrop, and claims recorded in the store are applied
+	logs.Reset()
+	assert.NoError(t, store.MarkClaimed("airdrop-1", "tx"))
+	valuable, err = scanner.ScanAirdrops(context.Background(), 0.001)
+	assert.NoError(t, err)
+	assert.NotContains(t, logs.String(), "airdrop:")
+	assert.NotContains(t, logs.String(), "Updated airdrop price")
+	assert.Nil(t, valuable[0].ClaimedAt)
+	assert.NotNil(t, valuable[1].ClaimedAt)
+
+	// A repriced airdrop is logged once
+	logs.Reset()
+	repriced := testAirdrops(3, 1)
+	repriced[2].AmountUsd = models.NewUSD(7.5)
+	second := airdropResponseBody(t, repriced)
+	body.Store(&second)
+	_, err = scanner.ScanAirdrops(context.Background(), 0.001)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(logs.String(), "Updated airdrop price"))
+	assert.Contains(t, logs.String(), "Most valuable airdrops: TK2 $7.50, TK1 $1.01, TK0 $0.01")
+
+	// Price noise is only counted, unless every change is logged in verbose mode
+	logs.Reset()
+	repriced[2].AmountUsd = models.NewUSD(7.8)
+	third := airdropResponseBody(t, repriced)
+	body.Store(&third)
+	_, err = scanner.ScanAirdrops(context.Background(), 0.001)
+	assert.NoError(t, err)
+	assert.NotContains(t, logs.String(), "Updated airdrop price")
+	assert.Contains(t, logs.String(), "Updated 1 airdrop(s), 2 unchanged")
+
+	logs.Reset()
+	scanner.verbose = true
+	repriced[2].AmountUsd = models.NewUSD(7.9)
+	fourth := airdropResponseBody(t, repriced)
+	body.Store(&fourth)
+	_, err = scanner.ScanAirdrops(context.Background(), 0.001)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(logs.String(), "Updated airdrop price"))
+}
+
+// BenchmarkScanAirdrops measures a scan cycle of a wallet with thousands of pending airdrops whose prices
+// change between cycles, against both stores
+func BenchmarkScanAirdrops(b *testing.B) {
+	bodies := [][]byte{
+		airdropResponseBody(b, testAirdrops(benchmarkAirdrops, 1)),
+		airdropResponseBody(b, testAirdrops(benchmarkAirdrops, 2)),
+	}
+
+	for _, backend := range []string{StoreMemory, StoreSQLite, StoreJSON, StoreBolt} {
+		b.Run(backend, func(b *testing.B) {
+			store, err := NewAirdropStore(&config.Config{
+				AirdropStore:      backend,
+				AirdropStorePath:  filepath.Join(b.TempDir(), "airdrops.db"),
+				AirdropStoreFlush: time.Minute,
+			}, log.New(io.Discard, "", 0))
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			var body atomic.Pointer[[]byte]
+			body.Store(&bodies[0])
+			scanner := newTestScanner(b, store, &body)
+			if _, err := scanner.ScanAirdrops(context.Background(), 0.001); err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// Every other cycle sees new prices, the rest see the same payload again
+				body.Store(&bodies[i%2])
+				if _, err := scanner.ScanAirdrops(context.Background(), 0.001); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestSQLiteStoreWritesAirdropsWhenStoredOnesAreUnreadable(t *testing.T) {
+	store, err := NewSQLiteAirdropStore(filepath.Join(t.TempDir(), "airdrops.db"), log.New(io.Discard, "", 0))
+	require.NoError(t, err)
+	sqliteStore := store.(*sqliteAirdropStore)
+	_, err = sqliteStore.db.Exec(`ALTER TABLE airdrops DROP COLUMN claimed_at`)
+	require.NoError(t, err)
+
+	changes := store.SaveAirdrops(testAirdrops(2, 1))
+	assert.Equal(t, []AirdropChange{AirdropUpdated, AirdropUpdated}, changes)
+	var count int
+	require.NoError(t, sqliteStore.db.QueryRow(`SELECT COUNT(*) FROM airdrops`).Scan(&count))
+	assert.Equal(t, 2, count)
+}
@@ -4,21 +4,79 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"strconv"
+	"reflect"
 	"sync"
 	"time"
 
 	"boop-airdrop-redeemer/pkg/api"
 	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
 	"boop-airdrop-redeemer/pkg/models"
+	"boop-airdrop-redeemer/pkg/retry"
+	sol "boop-airdrop-redeemer/pkg/solana"
 )
 
 // AirdropStore is an interface for storing and checking airdrops
 type AirdropStore interface {
 	SaveAirdrop(airdrop models.AirdropNode)
+	SaveAirdrops(airdrops []models.AirdropNode) []AirdropChange
 	HasAirdropWithID(id string) bool
 	GetAllAirdrops() []models.AirdropNode
 	ClaimAirdrop(airdropID string) (models.AirdropNode, error)
+	MarkClaimed(airdropID, txHash string) error
+}
+
+// AirdropChange tells how a saved airdrop differs from the stored one
+type AirdropChange int
+
+const (
+	AirdropUnchanged AirdropChange = iota
+	AirdropNew
+	AirdropUpdated
+)
+
+// Airdrop store backends selected with AIRDROP_STORE
+const (
+	StoreMemory = "memory"
+	StoreSQLite = "sqlite"
+	StoreJSON   = "json"
+	StoreBolt   = "bolt"
+)
+
+// NewAirdropStore creates the airdrop store selected in the configuration
+func NewAirdropStore(cfg *config.Config, logger *log.Logger) (AirdropStore, error) {
+	switch cfg.AirdropStore {
+	case "", StoreMemory:
+		return NewInMemoryAirdropStore(), nil
+	case StoreSQLite:
+		return NewSQLiteAirdropStore(cfg.AirdropStorePath, logger)
+	case StoreJSON:
+		return NewJSONAirdropStore(cfg.AirdropStoreFile(), cfg.AirdropStoreFlush, logger)
+	case StoreBolt:
+		return NewBoltAirdropStore(cfg.AirdropStoreFile(), logger)
+	default:
+		return nil, fmt.Errorf("unknown AIRDROP_STORE %q, expected memory, sqlite, json or bolt", cfg.AirdropStore)
+	}
+}
+
+// FlushAirdropStore writes the changes a store buffers, as the JSON store does, before the process exits
+func FlushAirdropStore(store AirdropStore) error {
+	if flusher, ok := store.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// CloseAirdropStore flushes the store and closes its database, as the bbolt store holds a file lock until it
+// is closed
+func CloseAirdropStore(store AirdropStore) error {
+	if err := FlushAirdropStore(store); err != nil {
+		return err
+	}
+	if closer, ok := store.(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+	return nil
 }
 
 // AirdropMonitor monitors for new airdrops
@@ -26,6 +84,7 @@ type AirdropMonitor struct {
 	client    *api.BoopClient
 	store     AirdropStore
 	config    *config.Config
+	decimals  *sol.MintDecimals
 	stopCh    chan struct{}
 	waitGroup sync.WaitGroup
 	logger    *log.Logger
@@ -34,6 +93,7 @@ type AirdropMonitor struct {
 // inMemoryAirdropStore is a simple in-memory implementation of AirdropStore
 type inMemoryAirdropStore struct {
 	airdrops map[string]models.AirdropNode
+	claimed  map[string]time.Time
 	mu       sync.RWMutex
 }
 
@@ -41,14 +101,37 @@ type inMemoryAirdropStore struct {
 func NewInMemoryAirdropStore() AirdropStore {
 	return &inMemoryAirdropStore{
 		airdrops: make(map[string]models.AirdropNode),
+		claimed:  make(map[string]time.Time),
 	}
 }
 
 // SaveAirdrop stores an airdrop in memory
 func (s *inMemoryAirdropStore) SaveAirdrop(airdrop models.AirdropNode) {
+	s.SaveAirdrops([]models.AirdropNode{airdrop})
+}
+
+// SaveAirdrops stores the airdrops in memory, reporting which are new or changed. Locally recorded claims are
+// applied to the airdrops passed in.
+func (s *inMemoryAirdropStore) SaveAirdrops(airdrops []models.AirdropNode) []AirdropChange {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.airdrops[airdrop.ID] = airdrop
+
+	changes := make([]AirdropChange, len(airdrops))
+	for i, airdrop := range airdrops {
+		stored, exists := s.airdrops[airdrop.ID]
+		switch {
+		case !exists:
+			changes[i] = AirdropNew
+		case !reflect.DeepEqual(stored, airdrop):
+			changes[i] = AirdropUpdated
+		}
+		s.airdrops[airdrop.ID] = airdrop
+
+		if claimedAt, ok := s.claimed[airdrop.ID]; ok && airdrop.ClaimedAt == nil {
+			airdrops[i].ClaimedAt = claimedAt.Format(time.RFC3339)
+		}
+	}
+	return changes
 }
 
 // HasAirdropWithID checks if an airdrop with given ID exists
@@ -81,15 +164,28 @@ func (s *inMemoryAirdropStore) ClaimAirdrop(airdropID string) (models.AirdropNod
 		return models.AirdropNode{}, fmt.Errorf("airdrop with ID %s not found", airdropID)
 	}
 
+	if claimedAt, ok := s.claimed[airdropID]; ok && airdrop.ClaimedAt == nil {
+		airdrop.ClaimedAt = claimedAt.Format(time.RFC3339)
+	}
+
 	return airdrop, nil
 }
 
+// MarkClaimed records a confirmed claim so it is not attempted again while the process runs
+func (s *inMemoryAirdropStore) MarkClaimed(airdropID, txHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.claimed[airdropID] = time.Now()
+	return nil
+}
+
 // NewAirdropMonitor creates a new monitor with the provided dependencies
 func NewAirdropMonitor(client *api.BoopClient, store AirdropStore, cfg *config.Config, logger *log.Logger) *AirdropMonitor {
 	return &AirdropMonitor{
 		client:    client,
 		store:     store,
 		config:    cfg,
+		decimals:  sol.NewMintDecimals(sol.NewRPCClient(cfg.SolanaRpcURL, retry.NewDefault(cfg.RetryMaxAttempts, cfg.RetryBudget, logger))),
 		stopCh:    make(chan struct{}),
 		waitGroup: sync.WaitGroup{},
 		logger:    logger,
@@ -103,7 +199,7 @@ func (m *AirdropMonitor) Start(ctx context.Context) error {
 
 	// First check immediately
 	if err := m.checkAirdrops(ctx); err != nil {
-		m.logger.Printf("Error in initial airdrop check: %v", err)
+		logging.Errorf(m.logger, "Error in initial airdrop check: %v", err)
 	}
 
 	go func() {
@@ -115,7 +211,7 @@ func (m *AirdropMonitor) Start(ctx context.Context) error {
 			select {
 			case <-ticker.C:
 				if err := m.checkAirdrops(ctx); err != nil {
-					m.logger.Printf("Error checking airdrops: %v", err)
+					logging.Errorf(m.logger, "Error checking airdrops: %v", err)
 				}
 			case <-m.stopCh:
 				m.logger.Println("Stopping airdrop monitor...")
@@ -144,6 +240,7 @@ func (m *AirdropMonitor) checkAirdrops(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to fetch airdrops: %w", err)
 	}
+	resolveDecimals(ctx, m.decimals, airdrops, m.logger)
 
 	foundNew := false
 	for _, airdrop := range airdrops {
@@ -166,15 +263,10 @@ func (m *AirdropMonitor) checkAirdrops(ctx context.Context) error {
 func (m *AirdropMonitor) processNewAirdrop(airdrop models.AirdropNode) {
 	m.logger.Printf(">>> NEW AIRDROP DETECTED! <<<")
 
-	// Convert string amounts to float64
-	amountLpt, _ := strconv.ParseFloat(airdrop.AmountLpt, 64)
-	amountUsd, _ := strconv.ParseFloat(airdrop.AmountUsd, 64)
-
-	// Format with requested formatting
-	formattedLpt := amountLpt / 1e9
+	amountUsd := airdrop.AmountUsd.Float()
 
-	m.logger.Printf("  - ID: %s, Token: %s (%s), Amount LPT: %.2f, USD: ~$%.2f",
-		airdrop.ID, airdrop.Token.Name, airdrop.Token.Symbol, formattedLpt, amountUsd)
+	m.logger.Printf("  - ID: %s, Token: %s (%s), Amount: %s, USD: ~$%.2f",
+		airdrop.ID, airdrop.Token.Name, airdrop.Token.Symbol, airdrop.TokenAmount().Format(2), amountUsd)
 
 	// Here you could add additional notification methods
 	// For example: send email, push notification, etc.
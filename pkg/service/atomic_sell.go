@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+
+	"github.com/gagliardetto/solana-go"
+
+	"boop-airdrop-redeemer/pkg/amount"
+	"boop-airdrop-redeemer/pkg/jupiter"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/models"
+)
+
+// atomicSellInstructions returns the Jupiter swap selling the claimed amount for SOL, to be appended to the
+// claim instructions so the claim and the sale land in one transaction. It returns nil, and the tokens are
+// sold after the claim as usual, when the mode is off, the claim settings ask for anything but an immediate
+// sale for SOL, the quote looks wrong or the swap cannot be built.
+func (c *AirdropClaimer) atomicSellInstructions(ctx context.Context, airdrop models.AirdropNode, config ClaimConfig, owner solana.PublicKey, rawAmount uint64) *jupiter.SwapInstructions {
+	if !c.config.AtomicClaimAndSell || c.swapSvc == nil ||
+		!config.AutoSell || config.SellTarget != SellTargetSol || config.SellDelay > 0 ||
+		c.config.KeepsToken(airdrop.Token.Address) || !c.receiver.IsZero() ||
+		c.atomicFallbacks.contains(airdrop.Token.Address) {
+		return nil
+	}
+
+	// A bad quote is handled by the sale after the claim, which waits for it to recover
+	if err := c.quoteGuard.Check(airdrop, rawAmount); err != nil {
+		c.logger.Printf("Selling %s after the claim instead: %v", airdrop.Token.Symbol, err)
+		return nil
+	}
+
+	swap, err := c.swapSvc.SwapInstructionsForSol(ctx, owner, airdrop.Token.Address, rawAmount)
+	if err != nil {
+		logging.Warnf(c.logger, "Warning: Failed to build the sale of %s into the claim, selling after the claim: %v", airdrop.Token.Symbol, err)
+		return nil
+	}
+
+	c.logger.Printf("Selling %s in the claim transaction for about %s SOL", airdrop.Token.Symbol, amount.Lamports(swap.QuotedOut).FormatSol(5))
+	return swap
+}
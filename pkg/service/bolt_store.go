@@ -0,0 +1,278 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/models"
+)
+
+// boltAirdropsBucket holds one JSON encoded jsonAirdropRecord per airdrop ID
+var boltAirdropsBucket = []byte("airdrops")
+
+// boltOpenTimeout is how long opening waits for another process holding the database
+const boltOpenTimeout = 5 * time.Second
+
+// boltAirdropStore is an AirdropStore persisted in an embedded bbolt database, so seen and claimed airdrops
+// survive restarts without CGO, with every save written in a single transaction
+type boltAirdropStore struct {
+	db     *bolt.DB
+	logger *log.Logger
+	mu     sync.Mutex
+
+	// saved mirrors the digests and local claim times of the stored airdrops, so scans only write airdrops
+	// that changed
+	saved map[string]savedBoltAirdrop
+}
+
+// savedBoltAirdrop is the digest of a stored airdrop's data and its local claim time
+type savedBoltAirdrop struct {
+	digest    [sha256.Size]byte
+	claimedAt string
+}
+
+// NewBoltAirdropStore opens (or creates) the bbolt database at path
+func NewBoltAirdropStore(path string, logger *log.Logger) (AirdropStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create airdrop store directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open airdrop store: %w", err)
+	}
+
+	saved := make(map[string]savedBoltAirdrop)
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(boltAirdropsBucket)
+		if err != nil {
+			return err
+		}
+		return bucket.ForEach(func(id, value []byte) error {
+			record, err := decodeBoltRecord(value)
+			if err != nil {
+				return fmt.Errorf("airdrop %s: %w", id, err)
+			}
+			saved[string(id)] = savedBoltAirdrop{digest: sha256.Sum256(record.Data), claimedAt: record.ClaimedAt}
+			return nil
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to read airdrop store: %w", err)
+	}
+
+	return &boltAirdropStore{db: db, logger: logger, saved: saved}, nil
+}
+
+// SaveAirdrop inserts or updates an airdrop, keeping its first seen time and local claim record
+func (s *boltAirdropStore) SaveAirdrop(airdrop models.AirdropNode) {
+	s.SaveAirdrops([]models.AirdropNode{airdrop})
+}
+
+// SaveAirdrops inserts new and updates changed airdrops in one transaction, keeping their first seen time
+// and local claim record. Unchanged airdrops are not written. Locally recorded claims are applied to the
+// airdrops passed in.
+func (s *boltAirdropStore) SaveAirdrops(airdrops []models.AirdropNode) []AirdropChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changes := make([]AirdropChange, len(airdrops))
+	var pending []pendingAirdropRow
+	for i := range airdrops {
+		airdrop := &airdrops[i]
+		data, err := json.Marshal(airdrop)
+		if err != nil {
+			logging.Warnf(s.logger, "Warning: Failed to encode airdrop %s for the store: %v", airdrop.ID, err)
+			continue
+		}
+
+		stored, exists := s.saved[airdrop.ID]
+		if airdrop.ClaimedAt == nil && stored.claimedAt != "" {
+			airdrop.ClaimedAt = stored.claimedAt
+		}
+
+		digest := sha256.Sum256(data)
+		if exists && digest == stored.digest {
+			continue
+		}
+
+		changes[i] = AirdropUpdated
+		if !exists {
+			changes[i] = AirdropNew
+		}
+		pending = append(pending, pendingAirdropRow{index: i, data: data, digest: digest})
+	}
+	if len(pending) == 0 {
+		return changes
+	}
+
+	// Failed writes keep the old digests, so the airdrops are written again on the next save
+	if err := s.writeRecords(airdrops, pending); err != nil {
+		logging.Warnf(s.logger, "Warning: Failed to save %d airdrop(s): %v", len(pending), err)
+		return changes
+	}
+
+	for _, row := range pending {
+		stored := s.saved[airdrops[row.index].ID]
+		stored.digest = row.digest
+		s.saved[airdrops[row.index].ID] = stored
+	}
+	return changes
+}
+
+// writeRecords upserts the pending airdrops in a single transaction
+func (s *boltAirdropStore) writeRecords(airdrops []models.AirdropNode, pending []pendingAirdropRow) error {
+	now := time.Now()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltAirdropsBucket)
+		for _, row := range pending {
+			id := airdrops[row.index].ID
+			record := &jsonAirdropRecord{ID: id, FirstSeen: now}
+			if value := bucket.Get([]byte(id)); value != nil {
+				existing, err := decodeBoltRecord(value)
+				if err != nil {
+					return fmt.Errorf("airdrop %s: %w", id, err)
+				}
+				record = existing
+			}
+			record.Data = row.data
+			record.UpdatedAt = now
+
+			value, err := json.Marshal(record)
+			if err != nil {
+				return fmt.Errorf("airdrop %s: %w", id, err)
+			}
+			if err := bucket.Put([]byte(id), value); err != nil {
+				return fmt.Errorf("airdrop %s: %w", id, err)
+			}
+		}
+		return nil
+	})
+}
+
+// HasAirdropWithID checks if an airdrop with given ID exists
+func (s *boltAirdropStore) HasAirdropWithID(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, exists := s.saved[id]
+	return exists
+}
+
+// GetAllAirdrops returns all stored airdrops in the order they were first seen
+func (s *boltAirdropStore) GetAllAirdrops() []models.AirdropNode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var records []*jsonAirdropRecord
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltAirdropsBucket).ForEach(func(id, value []byte) error {
+			record, err := decodeBoltRecord(value)
+			if err != nil {
+				logging.Warnf(s.logger, "Warning: Skipping unreadable stored airdrop %s: %v", id, err)
+				return nil
+			}
+			records = append(records, record)
+			return nil
+		})
+	})
+	if err != nil {
+		logging.Warnf(s.logger, "Warning: Failed to list airdrops: %v", err)
+		return nil
+	}
+
+	sort.SliceStable(records, func(i, j int) bool { return records[i].FirstSeen.Before(records[j].FirstSeen) })
+	airdrops := make([]models.AirdropNode, 0, len(records))
+	for _, record := range records {
+		airdrop, err := record.airdrop()
+		if err != nil {
+			logging.Warnf(s.logger, "Warning: Skipping unreadable stored airdrop: %v", err)
+			continue
+		}
+		airdrops = append(airdrops, airdrop)
+	}
+	return airdrops
+}
+
+// ClaimAirdrop retrieves an airdrop by ID to claim it, reporting it as claimed when a claim was recorded locally
+func (s *boltAirdropStore) ClaimAirdrop(airdropID string) (models.AirdropNode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var airdrop models.AirdropNode
+	err := s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(boltAirdropsBucket).Get([]byte(airdropID))
+		if value == nil {
+			return fmt.Errorf("airdrop with ID %s not found", airdropID)
+		}
+		record, err := decodeBoltRecord(value)
+		if err != nil {
+			return fmt.Errorf("failed to load airdrop %s: %w", airdropID, err)
+		}
+		airdrop, err = record.airdrop()
+		if err != nil {
+			return fmt.Errorf("failed to load airdrop %s: %w", airdropID, err)
+		}
+		return nil
+	})
+	return airdrop, err
+}
+
+// MarkClaimed records a confirmed claim so it is not attempted again, also after a restart
+func (s *boltAirdropStore) MarkClaimed(airdropID, txHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	claimedAt := time.Now().Format(time.RFC3339)
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltAirdropsBucket)
+		value := bucket.Get([]byte(airdropID))
+		if value == nil {
+			return nil
+		}
+		record, err := decodeBoltRecord(value)
+		if err != nil {
+			return err
+		}
+		record.ClaimedAt = claimedAt
+		record.ClaimTx = txHash
+		if value, err = json.Marshal(record); err != nil {
+			return err
+		}
+		return bucket.Put([]byte(airdropID), value)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark airdrop %s as claimed: %w", airdropID, err)
+	}
+
+	if stored, ok := s.saved[airdropID]; ok {
+		stored.claimedAt = claimedAt
+		s.saved[airdropID] = stored
+	}
+	return nil
+}
+
+// Close closes the database, releasing its file lock
+func (s *boltAirdropStore) Close() error {
+	return s.db.Close()
+}
+
+// decodeBoltRecord decodes a stored airdrop record. The value is only valid during its transaction, so the
+// record is decoded into its own memory.
+func decodeBoltRecord(value []byte) (*jsonAirdropRecord, error) {
+	var record jsonAirdropRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return nil, fmt.Errorf("failed to decode stored airdrop: %w", err)
+	}
+	return &record, nil
+}
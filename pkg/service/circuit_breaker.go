@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/models"
+	"boop-airdrop-redeemer/pkg/notifications"
+	sol "boop-airdrop-redeemer/pkg/solana"
+)
+
+// ErrSalesPaused is returned instead of selling a token whose sales are paused after failing repeatedly
+var ErrSalesPaused = errors.New("sales paused after failing repeatedly")
+
+// TokenFailures is the run of failed sales of one token
+type TokenFailures struct {
+	Failures  int       `json:"failures"`            // Failed sales in a row
+	LastError string    `json:"lastError"`           // Error of the latest failure
+	OpenUntil time.Time `json:"openUntil,omitempty"` // Sales are paused until then, zero while they are not
+	Announced bool      `json:"announced,omitempty"` // The pause was notified, it is not again until a sale lands
+}
+
+// CircuitBreaker pauses the sales of tokens that keep failing, so a token Jupiter cannot swap is not retried every
+// cycle with an error notification each time. After the cooldown one sale is tried again, and its failure pauses
+// the token for another cooldown. The state is kept across restarts.
+type CircuitBreaker struct {
+	limit    int
+	cooldown time.Duration
+	state    sol.StateStore // nil when the state is not kept
+	logger   *log.Logger
+	now      func() time.Time
+
+	mu     sync.Mutex
+	tokens map[string]TokenFailures // By mint
+}
+
+// NewCircuitBreaker creates a breaker pausing a token for cooldown after limit failed sales in a row. The state
+// is loaded from and saved to state when it is not nil. It returns nil, selling every token as before, when
+// limit is 0 or less.
+func NewCircuitBreaker(limit int, cooldown time.Duration, state sol.StateStore, logger *log.Logger) (*CircuitBreaker, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	b := &CircuitBreaker{
+		limit:    limit,
+		cooldown: cooldown,
+		state:    state,
+		logger:   logger,
+		now:      time.Now,
+		tokens:   make(map[string]TokenFailures),
+	}
+
+	if state != nil {
+		if _, err := state.Load(&b.tokens); err != nil {
+			return nil, fmt.Errorf("failed to read sale failures: %w", err)
+		}
+	}
+	return b, nil
+}
+
+// Allow reports whether the token may be sold, false with the end of the pause while its sales are paused
+func (b *CircuitBreaker) Allow(mint string) (bool, time.Time) {
+	if b == nil {
+		return true, time.Time{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	token := b.tokens[mint]
+	if b.now().Before(token.OpenUntil) {
+		return false, token.OpenUntil
+	}
+	return true, time.Time{}
+}
+
+// RecordFailure records a failed sale and returns it as a SaleFailure
+func (b *CircuitBreaker) RecordFailure(mint string, err error) *SaleFailure {
+	if b == nil {
+		return &SaleFailure{Err: err, Failures: 1}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	token := b.tokens[mint]
+	token.Failures++
+	token.LastError = err.Error()
+	failure := &SaleFailure{Err: err, Failures: token.Failures}
+	if token.Failures >= b.limit {
+		token.OpenUntil = b.now().Add(b.cooldown)
+		failure.PausedUntil = token.OpenUntil
+		failure.FirstPause = !token.Announced
+		token.Announced = true
+		logging.Warnf(b.logger, "Warning: Pausing sales of %s for %s after %d failures in a row",
+			config.ShortenAddress(mint), b.cooldown, token.Failures)
+	}
+	b.tokens[mint] = token
+	b.saveLocked()
+	return failure
+}
+
+// RecordSuccess clears the failures of a token after it was sold
+func (b *CircuitBreaker) RecordSuccess(mint string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	token, ok := b.tokens[mint]
+	if !ok {
+		return
+	}
+	if token.Failures >= b.limit {
+		b.logger.Printf("Sales of %s recovered after %d failures", config.ShortenAddress(mint), token.Failures)
+	}
+	delete(b.tokens, mint)
+	b.saveLocked()
+}
+
+// saveLocked saves the state, b.mu must be held
+func (b *CircuitBreaker) saveLocked() {
+	if b.state == nil {
+		return
+	}
+	if err := b.state.Save(b.tokens); err != nil {
+		logging.Warnf(b.logger, "Warning: Failed to write sale failures: %v", err)
+	}
+}
+
+// SaleFailure is a failed sale, with its place in the run of failures of the token
+type SaleFailure struct {
+	Err         error
+	Failures    int       // Failed sales of the token in a row, this one included
+	PausedUntil time.Time // End of the pause this failure started, zero when the token is not paused
+	FirstPause  bool      // The sales of the token are paused for the first time since a sale landed
+}
+
+func (f *SaleFailure) Error() string { return f.Err.Error() }
+func (f *SaleFailure) Unwrap() error { return f.Err }
+
+type forceSaleKey struct{}
+
+// ForceSale returns a context whose sales are sent even while the sales of the token are paused, for sales
+// asked for by hand
+func ForceSale(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceSaleKey{}, true)
+}
+
+// guardedSwaps pauses the sales of tokens that keep failing, whichever seller sends them
+type guardedSwaps struct {
+	SwapProvider
+	breaker *CircuitBreaker
+}
+
+// guardSales returns swap with its sales going through the breaker, swap itself without a breaker
+func guardSales(swap SwapProvider, breaker *CircuitBreaker) SwapProvider {
+	if breaker == nil || swap == nil {
+		return swap
+	}
+	guarded := guardedSwaps{SwapProvider: swap, breaker: breaker}
+
+	// The position cap lists balances and scans prefetch prices through the same service
+	balances, listsBalances := swap.(BalanceProvider)
+	prefetcher, prefetches := swap.(PricePrefetcher)
+	if listsBalances && prefetches {
+		return guardedWalletSwaps{guardedSwaps: guarded, BalanceProvider: balances, PricePrefetcher: prefetcher}
+	}
+	return guarded
+}
+
+// guardedWalletSwaps is a guarded swap service that also lists balances and prefetches prices, as the Jupiter
+// swap service does
+type guardedWalletSwaps struct {
+	guardedSwaps
+	BalanceProvider
+	PricePrefetcher
+}
+
+// SwapTokenForSol sells the token for SOL unless its sales are paused
+func (g guardedSwaps) SwapTokenForSol(ctx context.Context, privateKeyBase58 string, inputMint string, amount uint64) (solana.Signature, error) {
+	return g.guard(ctx, inputMint, func() (solana.Signature, error) {
+		return g.SwapProvider.SwapTokenForSol(ctx, privateKeyBase58, inputMint, amount)
+	})
+}
+
+// SwapTokenForUsdc sells the token for USDC unless its sales are paused
+func (g guardedSwaps) SwapTokenForUsdc(ctx context.Context, privateKeyBase58 string, inputMint string, amount uint64) (solana.Signature, error) {
+	return g.guard(ctx, inputMint, func() (solana.Signature, error) {
+		return g.SwapProvider.SwapTokenForUsdc(ctx, privateKeyBase58, inputMint, amount)
+	})
+}
+
+func (g guardedSwaps) guard(ctx context.Context, mint string, swap func() (solana.Signature, error)) (solana.Signature, error) {
+	if forced, _ := ctx.Value(forceSaleKey{}).(bool); !forced {
+		if allowed, until := g.breaker.Allow(mint); !allowed {
+			return solana.Signature{}, fmt.Errorf("%w, retried after %s", ErrSalesPaused, until.Format("2006-01-02 15:04"))
+		}
+	}
+
+	sig, err := swap()
+	if err != nil {
+		return sig, g.breaker.RecordFailure(mint, err)
+	}
+	g.breaker.RecordSuccess(mint)
+	return sig, nil
+}
+
+// NotifySaleFailure tells Telegram about a failed sale: the first failure in a row, and the first pause of the
+// token's sales until one lands again. Sales skipped while paused are not notified, failures of sales not going
+// through a breaker always are.
+func NotifySaleFailure(telegramClient *notifications.TelegramClient, airdrop models.AirdropNode, err error, operationID string) {
+	// The quote guard alerts about bad quotes itself
+	if telegramClient == nil || !telegramClient.Enabled || errors.Is(err, ErrSalesPaused) || errors.Is(err, ErrQuoteBelowExpected) {
+		return
+	}
+
+	var failure *SaleFailure
+	if errors.As(err, &failure) {
+		if failure.FirstPause {
+			telegramClient.SendSalesPausedNotification(airdrop.Token.Name, airdrop.Token.Symbol, failure.Failures,
+				failure.Err.Error(), failure.PausedUntil)
+			return
+		}
+		if failure.Failures > 1 {
+			return
+		}
+	}
+
+	errorMsg := err.Error()
+	if len(errorMsg) > 100 {
+		errorMsg = errorMsg[:100] + "..."
+	}
+	telegramClient.SendTokenSaleErrorNotification(
+		airdrop.Token.Name,
+		airdrop.Token.Symbol,
+		airdrop.TokenAmount(),
+		airdrop.AmountUsd.Float(),
+		errorMsg,
+		10, // Max attempts
+		operationID,
+	)
+}
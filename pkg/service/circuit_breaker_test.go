@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"boop-airdrop-redeemer/pkg/jupiter"
+	sol "boop-airdrop-redeemer/pkg/solana"
+)
+
+func TestCircuitBreakerPausesFailingToken(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "sale_failures.json")
+	logger := log.New(io.Discard, "", 0)
+	breaker, err := NewCircuitBreaker(3, time.Hour, sol.NewFileState(path), logger)
+	require.NoError(t, err)
+	breaker.now = func() time.Time { return now }
+
+	swapErr := errors.New("no route")
+	for i := 1; i < 3; i++ {
+		failure := breaker.RecordFailure("mint", swapErr)
+		assert.Equal(t, i, failure.Failures)
+		assert.True(t, failure.PausedUntil.IsZero())
+	}
+	failure := breaker.RecordFailure("mint", swapErr)
+	assert.True(t, failure.FirstPause)
+	assert.Equal(t, now.Add(time.Hour), failure.PausedUntil)
+
+	allowed, until := breaker.Allow("mint")
+	assert.False(t, allowed)
+	assert.Equal(t, now.Add(time.Hour), until)
+	allowed, _ = breaker.Allow("other")
+	assert.True(t, allowed)
+
+	// The pause survives a restart
+	restarted, err := NewCircuitBreaker(3, time.Hour, sol.NewFileState(path), logger)
+	require.NoError(t, err)
+	restarted.now = func() time.Time { return now }
+	allowed, _ = restarted.Allow("mint")
+	assert.False(t, allowed)
+
+	// After the cooldown one sale is tried, failing again pauses the token at once without a new announcement
+	now = now.Add(time.Hour)
+	allowed, _ = breaker.Allow("mint")
+	assert.True(t, allowed)
+	failure = breaker.RecordFailure("mint", swapErr)
+	assert.Equal(t, 4, failure.Failures)
+	assert.False(t, failure.PausedUntil.IsZero())
+	assert.False(t, failure.FirstPause)
+
+	breaker.RecordSuccess("mint")
+	allowed, _ = breaker.Allow("mint")
+	assert.True(t, allowed)
+
+	disabled, err := NewCircuitBreaker(0, time.Hour, sol.NewFileState(path), logger)
+	require.NoError(t, err)
+	assert.Nil(t, disabled)
+	allowed, _ = disabled.Allow("mint")
+	assert.True(t, allowed)
+}
+
+// failingSwaps fails every sale
+type failingSwaps struct {
+	SwapProvider
+	calls int
+}
+
+func (f *failingSwaps) SwapTokenForSol(ctx context.Context, privateKeyBase58 string, inputMint string, amount uint64) (solana.Signature, error) {
+	f.calls++
+	return solana.Signature{}, errors.New("no route")
+}
+
+func TestGuardedSwapsSkipPausedTokens(t *testing.T) {
+	breaker, err := NewCircuitBreaker(2, time.Hour, nil, log.New(io.Discard, "", 0))
+	require.NoError(t, err)
+	swaps := &failingSwaps{}
+	guarded := guardSales(swaps, breaker)
+
+	for i := 0; i < 3; i++ {
+		guarded.SwapTokenForSol(context.Background(), "", "mint", 1)
+	}
+	_, err = guarded.SwapTokenForSol(context.Background(), "", "mint", 1)
+	assert.ErrorIs(t, err, ErrSalesPaused)
+	assert.Equal(t, 2, swaps.calls)
+
+	// A manual sale is still sent
+	_, err = guarded.SwapTokenForSol(ForceSale(context.Background()), "", "mint", 1)
+	var failure *SaleFailure
+	assert.ErrorAs(t, err, &failure)
+	assert.Equal(t, 3, swaps.calls)
+}
+
+// walletSwaps also lists balances and prefetches prices, like the Jupiter swap service
+type walletSwaps struct {
+	failingSwaps
+}
+
+func (w *walletSwaps) GetTokenBalances(context.Context, solana.PublicKey) (map[string]jupiter.TokenBalance, error) {
+	return nil, nil
+}
+
+func (w *walletSwaps) PrefetchPrices([]string) {}
+
+func TestGuardedSwapsKeepWalletMethods(t *testing.T) {
+	breaker, err := NewCircuitBreaker(2, time.Hour, nil, log.New(io.Discard, "", 0))
+	require.NoError(t, err)
+
+	guarded := guardSales(&walletSwaps{}, breaker)
+	assert.Implements(t, (*BalanceProvider)(nil), guarded)
+	assert.Implements(t, (*PricePrefetcher)(nil), guarded)
+	assert.NotImplements(t, (*BalanceProvider)(nil), guardSales(&failingSwaps{}, breaker))
+}
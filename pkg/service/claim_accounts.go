@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+
+	"boop-airdrop-redeemer/pkg/amount"
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/keys"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/models"
+	sol "boop-airdrop-redeemer/pkg/solana"
+	"boop-airdrop-redeemer/pkg/solana/associated_token_account_extended"
+)
+
+// ErrAlreadyClaimedOnChain is returned when the claim status account of an airdrop already exists
+var ErrAlreadyClaimedOnChain = errors.New("airdrop already claimed on chain")
+
+// ErrPoolUnderfunded is returned when the distributor's token pool holds less than the claim amount, the
+// claim would fail on chain and is deferred until the pool is funded
+var ErrPoolUnderfunded = errors.New("distributor pool underfunded")
+
+// ErrUnsupportedTokenProgram is returned instead of claiming a Token-2022 airdrop to the receiving wallet
+var ErrUnsupportedTokenProgram = errors.New("token program not supported by the receiving wallet transfer")
+
+// claimAccounts are the accounts a claim of one token by one wallet touches
+type claimAccounts struct {
+	claimant    solana.PublicKey
+	distributor solana.PublicKey
+	claimStatus solana.PublicKey
+	pool        solana.PublicKey
+	ata         solana.PublicKey
+}
+
+// claimAccountKey identifies the claim accounts of a wallet and token
+type claimAccountKey struct {
+	owner solana.PublicKey
+	mint  solana.PublicKey
+}
+
+// claimAccountDeriver derives claim accounts once per wallet and token, they never change
+type claimAccountDeriver struct {
+	mu      sync.Mutex
+	derived map[claimAccountKey]claimAccounts
+}
+
+// derive returns the claim accounts of the wallet and token
+func (d *claimAccountDeriver) derive(owner, mint solana.PublicKey) (claimAccounts, error) {
+	key := claimAccountKey{owner: owner, mint: mint}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if accounts, ok := d.derived[key]; ok {
+		return accounts, nil
+	}
+
+	accounts := claimAccounts{claimant: owner}
+	var err error
+
+	accounts.ata, _, err = solana.FindAssociatedTokenAddress(owner, mint)
+	if err != nil {
+		return claimAccounts{}, fmt.Errorf("failed to find associated token address: %w", err)
+	}
+
+	accounts.distributor, err = sol.FindMerkleDistributorPDA(BoopTokenDistributor, mint, BoopMerkleDistribution, 0)
+	if err != nil {
+		return claimAccounts{}, fmt.Errorf("failed to find merkle distributor pda: %w", err)
+	}
+
+	accounts.claimStatus, err = sol.FindClaimStatusPDA(owner, accounts.distributor, BoopMerkleDistribution)
+	if err != nil {
+		return claimAccounts{}, fmt.Errorf("failed to find claim status pda: %w", err)
+	}
+
+	accounts.pool, err = sol.FindBoopPoolAddress(mint, accounts.distributor, true)
+	if err != nil {
+		return claimAccounts{}, fmt.Errorf("failed to find boop pool address: %w", err)
+	}
+
+	if d.derived == nil {
+		d.derived = make(map[claimAccountKey]claimAccounts)
+	}
+	d.derived[key] = accounts
+	return accounts, nil
+}
+
+// claimCheckReuse is how long the accounts read for one claim's checks are reused without an account cache,
+// long enough for all checks of the claim and no later claim
+const claimCheckReuse = time.Minute
+
+// checkClaimAccounts reads all claim accounts in one call and refuses claims that cannot succeed: a missing
+// distributor, an existing claim status, which the program creates when the airdrop is claimed, or a pool
+// holding less than the claim amount. Without an account cache the accounts are read for this claim alone.
+// When the RPC client cannot read accounts, or they cannot be read, the claim goes ahead and the program decides.
+func (c *AirdropClaimer) checkClaimAccounts(ctx context.Context, airdrop models.AirdropNode, accounts claimAccounts, claimAmount uint64) error {
+	cache := c.accountCache
+	if cache == nil {
+		if c.accountClient == nil {
+			return nil
+		}
+		cache = sol.NewAccountCache(c.accountClient, claimCheckReuse)
+	}
+
+	if err := cache.Prefetch(ctx, accounts.distributor, accounts.claimStatus, accounts.pool, accounts.ata); err != nil {
+		logging.Warnf(c.logger, "Warning: Failed to prefetch claim accounts: %v", err)
+		return nil
+	}
+
+	if exists, err := cache.Exists(ctx, accounts.distributor); err == nil && !exists {
+		return fmt.Errorf("merkle distributor %s does not exist", accounts.distributor)
+	}
+	if claimed, err := sol.IsClaimedOnChain(ctx, cache, accounts.claimant, accounts.distributor, BoopMerkleDistribution); err == nil && claimed {
+		return fmt.Errorf("%w: claim status %s exists", ErrAlreadyClaimedOnChain, accounts.claimStatus)
+	}
+	return c.checkPoolBalance(ctx, cache, airdrop, accounts.pool, claimAmount)
+}
+
+// checkPoolBalance compares the pool's token balance with the claim amount. An underfunded pool is alerted
+// once per airdrop until a later check finds it funded.
+func (c *AirdropClaimer) checkPoolBalance(ctx context.Context, cache *sol.AccountCache, airdrop models.AirdropNode, pool solana.PublicKey, claimAmount uint64) error {
+	account, err := cache.Get(ctx, pool)
+	if err != nil || account == nil {
+		return nil
+	}
+
+	var balance token.Account
+	if err := bin.NewBinDecoder(account.Data.GetBinary()).Decode(&balance); err != nil {
+		logging.Warnf(c.logger, "Warning: Failed to decode pool %s: %v", pool, err)
+		return nil
+	}
+
+	if balance.Amount >= claimAmount {
+		c.poolAlerts.remove(airdrop.ID)
+		return nil
+	}
+
+	err = fmt.Errorf("%w: pool %s holds %d of the %d tokens claimed, deferring the claim", ErrPoolUnderfunded, pool, balance.Amount, claimAmount)
+	if c.poolAlerts.add(airdrop.ID) && c.telegramClient != nil {
+		c.telegramClient.SendPoolUnderfundedNotification(airdrop.Token.Name, airdrop.Token.Symbol,
+			amount.TokenAmount{Raw: balance.Amount, Decimals: airdrop.TokenDecimals()},
+			amount.TokenAmount{Raw: claimAmount, Decimals: airdrop.TokenDecimals()})
+	}
+	return err
+}
+
+// IsClaimedOnChain reports whether the wallet's claim status account for the airdrop exists. Claimed airdrops
+// are marked in the store, so they are skipped without reading the chain again. Without an account cache
+// nothing is read and the airdrop is reported unclaimed.
+func (c *AirdropClaimer) IsClaimedOnChain(ctx context.Context, airdrop models.AirdropNode) (bool, error) {
+	if c.accountCache == nil {
+		return false, nil
+	}
+
+	owner, err := keys.Parse(c.config.WalletPrivateKey)
+	if err != nil {
+		return false, err
+	}
+	mint, err := solana.PublicKeyFromBase58(airdrop.Token.Address)
+	if err != nil {
+		return false, fmt.Errorf("invalid token address %s: %w", airdrop.Token.Address, err)
+	}
+
+	accounts, err := c.claimAccounts.derive(owner.PublicKey(), mint)
+	if err != nil {
+		return false, err
+	}
+
+	claimed, err := sol.IsClaimedOnChain(ctx, c.accountCache, accounts.claimant, accounts.distributor, BoopMerkleDistribution)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	if err := c.store.MarkClaimed(airdrop.ID, ""); err != nil {
+		logging.Warnf(c.logger, "Warning: %v", err)
+	}
+	return true, nil
+}
+
+// invalidateClaimAccounts drops the accounts a sent claim may have changed
+func (c *AirdropClaimer) invalidateClaimAccounts(accounts claimAccounts) {
+	if c.accountCache == nil {
+		return
+	}
+	c.accountCache.Invalidate(accounts.claimStatus, accounts.pool, accounts.ata)
+}
+
+// checkReceivingWalletMint refuses claims of mints the transfer to the receiving wallet cannot move. The
+// transfer uses the SPL Token program, Token-2022 mints would fail the whole claim transaction. Without a
+// receiving wallet, or when mints cannot be read, nothing is checked.
+func (c *AirdropClaimer) checkReceivingWalletMint(ctx context.Context, mint solana.PublicKey) error {
+	if c.receiver.IsZero() || c.mintDecimals == nil {
+		return nil
+	}
+
+	program, err := c.mintDecimals.TokenProgram(ctx, mint)
+	if err != nil {
+		return fmt.Errorf("failed to read the token program of %s: %w", mint, err)
+	}
+	if !program.Equals(solana.TokenProgramID) {
+		return fmt.Errorf("%w: %s is owned by %s, claim it without RECEIVING_WALLET", ErrUnsupportedTokenProgram, mint, program)
+	}
+	return nil
+}
+
+// newReceivingWallet parses the configured receiving wallet, zero when claimed tokens stay in the claiming wallet
+func newReceivingWallet(cfg *config.Config, logger *log.Logger) solana.PublicKey {
+	if cfg.ReceivingWallet == "" {
+		return solana.PublicKey{}
+	}
+
+	receiver, err := solana.PublicKeyFromBase58(cfg.ReceivingWallet)
+	if err != nil {
+		logging.Warnf(logger, "WARNING: Invalid RECEIVING_WALLET, claimed tokens stay in the claiming wallet: %v", err)
+		return solana.PublicKey{}
+	}
+	if owner, err := solana.PublicKeyFromBase58(cfg.WalletAddress); err == nil && receiver.Equals(owner) {
+		return solana.PublicKey{}
+	}
+
+	logger.Printf("Claimed tokens are moved to receiving wallet %s and not sold", receiver)
+	return receiver
+}
+
+// receivingWalletInstructions moves the claimed tokens to the receiving wallet. The program only pays out to a
+// token account of the claimant, so the claimed amount is transferred right after the claim in the same
+// transaction: either both land or neither does. The claiming wallet pays for the receiver's token account.
+func receivingWalletInstructions(receiver, claimant, mint, claimantAta solana.PublicKey, amount uint64) ([]solana.Instruction, error) {
+	if receiver.IsZero() {
+		return nil, nil
+	}
+
+	receiverAta, _, err := solana.FindAssociatedTokenAddress(receiver, mint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find receiving wallet token address: %w", err)
+	}
+
+	return []solana.Instruction{
+		associated_token_account_extended.NewCreateIdempotentInstruction(claimant, receiver, mint).Build(),
+		token.NewTransferInstruction(amount, claimantAta, receiverAta, claimant, nil).Build(),
+	}, nil
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sol "boop-airdrop-redeemer/pkg/solana"
+
+	"boop-airdrop-redeemer/pkg/logging"
+)
+
+// ErrClaimInFlight is returned when a transaction this claimer did not send touched the claim status account
+// moments ago: another tool or the Boop web app may be claiming the same airdrop. The claim is retried later,
+// when the claim status account shows whether the other claim landed.
+var ErrClaimInFlight = errors.New("another claim of the airdrop may be in flight")
+
+// checkClaimRace backs off when a foreign transaction touched the claim status account within the race window.
+// When the transactions cannot be listed the claim goes ahead.
+func (c *AirdropClaimer) checkClaimRace(ctx context.Context, accounts claimAccounts) error {
+	if c.config.ClaimRaceWindow <= 0 || c.sigClient == nil {
+		return nil
+	}
+
+	signatures, err := sol.RecentSignatures(ctx, c.sigClient, accounts.claimStatus, time.Now().Add(-c.config.ClaimRaceWindow))
+	if err != nil {
+		logging.Warnf(c.logger, "Warning: Failed to scan recent claim transactions: %v", err)
+		return nil
+	}
+
+	for _, signature := range signatures {
+		if c.sentClaims.contains(signature.Signature.String()) {
+			continue
+		}
+
+		// Read the claim status again next time, the other claim may have landed by then
+		if c.accountCache != nil {
+			c.accountCache.Invalidate(accounts.claimStatus)
+		}
+		return fmt.Errorf("%w: transaction %s touched claim status %s", ErrClaimInFlight, signature.Signature, accounts.claimStatus)
+	}
+	return nil
+}
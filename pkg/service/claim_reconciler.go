@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/api"
+	"boop-airdrop-redeemer/pkg/journal"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/models"
+	sol "boop-airdrop-redeemer/pkg/solana"
+)
+
+// Claims the Boop API lists as claimed are compared with the stats every 6 hours. Older claims are left to
+// cmd/recover -backfill, the most recent ones may still be on their way through the claim flow.
+const (
+	claimReconcileInterval = 6 * time.Hour
+	claimReconcileWindow   = 7 * 24 * time.Hour
+	claimReconcileGrace    = 15 * time.Minute
+)
+
+// ClaimReconciler records the claims the Boop API lists as claimed that are missing from the stats, such as
+// claims made in the web app or by another instance of the bot
+type ClaimReconciler struct {
+	client    *api.BoopClient
+	stats     StatsStore
+	solClient sol.RPCClient
+	logger    *log.Logger
+
+	now     func() time.Time
+	lastRun time.Time
+}
+
+// NewClaimReconciler creates a reconciler sharing the scanner's API client and the claimer's stats and RPC
+// client, nil without stats
+func NewClaimReconciler(scanner *AirdropScanner, claimer *AirdropClaimer, logger *log.Logger) *ClaimReconciler {
+	stats := claimer.GetStatsRecorder()
+	if stats == nil {
+		return nil
+	}
+	return &ClaimReconciler{
+		client:    scanner.client,
+		stats:     stats,
+		solClient: claimer.GetSolClient(),
+		logger:    logger,
+		now:       time.Now,
+	}
+}
+
+// ReconcileIfDue reconciles the claims once every reconcile interval
+func (r *ClaimReconciler) ReconcileIfDue(ctx context.Context) {
+	if r == nil || r.now().Sub(r.lastRun) < claimReconcileInterval {
+		return
+	}
+	r.lastRun = r.now()
+
+	claimed, err := r.client.GetClaimedAirdrops(ctx)
+	if err != nil {
+		logging.Warnf(r.logger, "Warning: Failed to list claimed airdrops for reconciliation: %v", err)
+		return
+	}
+	if _, err := r.Reconcile(claimed); err != nil {
+		logging.Warnf(r.logger, "Warning: Failed to reconcile claims: %v", err)
+	}
+}
+
+// Reconcile records the claims of the last week missing from the stats, leaving out those claimed within the
+// grace period. Claims whose transaction cannot be read from the chain yet are tried again on the next run.
+func (r *ClaimReconciler) Reconcile(claimed []models.AirdropNode) (journal.RecoveryReport, error) {
+	now := r.now()
+	var settled []models.AirdropNode
+	for _, airdrop := range claimed {
+		claimedAt, ok := airdrop.ClaimTime()
+		if !ok || now.Sub(claimedAt) < claimReconcileGrace || now.Sub(claimedAt) > claimReconcileWindow {
+			continue
+		}
+		settled = append(settled, airdrop)
+	}
+	if len(settled) == 0 {
+		return journal.RecoveryReport{}, nil
+	}
+
+	report, err := journal.BackfillClaims(settled, r.stats, r.solClient, r.logger)
+	if err != nil {
+		return report, fmt.Errorf("failed to record claims: %w", err)
+	}
+	if report.ClaimsRestored > 0 {
+		r.logger.Printf("Recorded %d claim(s) the Boop API lists as claimed that were missing from the stats", report.ClaimsRestored)
+	}
+	return report, nil
+}
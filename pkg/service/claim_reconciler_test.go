@@ -0,0 +1,52 @@
+package service
+
+import (
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"boop-airdrop-redeemer/pkg/models"
+	sol "boop-airdrop-redeemer/pkg/solana"
+)
+
+func TestClaimReconcilerRecordsSettledClaimsOnce(t *testing.T) {
+	stats, err := sol.NewStatsRecorder(t.TempDir())
+	require.NoError(t, err)
+	defer stats.Close()
+	require.NoError(t, stats.RecordClaimStats("OWN", "1", 5000, 0, "tx-own", "own"))
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	claim := func(id, txHash string, age time.Duration) models.AirdropNode {
+		airdrop := testAirdrops(1, 1)[0]
+		airdrop.ID = id
+		airdrop.TxHash = txHash
+		airdrop.ClaimedAt = now.Add(-age).Format(time.RFC3339)
+		return airdrop
+	}
+	claimed := []models.AirdropNode{
+		claim("web", "tx-web", time.Hour),
+		claim("own", "tx-own", time.Hour),
+		claim("recent", "tx-recent", time.Minute), // May still be recorded by the claim flow
+		claim("old", "tx-old", 30*24*time.Hour),   // Left to cmd/recover -backfill
+	}
+
+	reconciler := &ClaimReconciler{stats: stats, logger: log.New(io.Discard, "", 0), now: func() time.Time { return now }}
+	report, err := reconciler.Reconcile(claimed)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.ClaimsRestored)
+	assert.Equal(t, 1, report.AlreadyPresent)
+
+	hashes, err := stats.GetTransactionHashes()
+	require.NoError(t, err)
+	assert.True(t, hashes["tx-web"])
+	assert.False(t, hashes["tx-recent"])
+	assert.False(t, hashes["tx-old"])
+
+	report, err = reconciler.Reconcile(claimed)
+	require.NoError(t, err)
+	assert.Zero(t, report.ClaimsRestored, "recorded claims are not recorded again")
+}
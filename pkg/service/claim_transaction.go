@@ -0,0 +1,66 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+
+	"boop-airdrop-redeemer/pkg/jupiter"
+	"boop-airdrop-redeemer/pkg/solana/associated_token_account_extended"
+	"boop-airdrop-redeemer/pkg/solana/boop"
+)
+
+// claimComputeUnitPrice is the priority fee of claim transactions in micro-lamports per compute unit
+const claimComputeUnitPrice = 375000
+
+// claimTransaction holds everything a claim transaction is built from, so the same inputs always produce the
+// same transaction
+type claimTransaction struct {
+	owner    solana.PublicKey
+	mint     solana.PublicKey
+	receiver solana.PublicKey // Zero when claimed tokens stay in the claiming wallet
+	accounts claimAccounts
+	amount   uint64
+	proofs   [][32]uint8
+	swap     *jupiter.SwapInstructions // Sale appended to the claim, nil when selling after the claim
+}
+
+// build creates the unsigned transaction: compute budget, token account creation, the claim and then the
+// optional transfer to the receiving wallet and sale
+func (t claimTransaction) build(computeUnitLimit uint32, blockhash solana.Hash) (*solana.Transaction, error) {
+	instrs := []solana.Instruction{
+		computebudget.NewSetComputeUnitLimitInstruction(computeUnitLimit).Build(),
+		computebudget.NewSetComputeUnitPriceInstruction(claimComputeUnitPrice).Build(),
+		associated_token_account_extended.NewCreateIdempotentInstruction(t.owner, t.owner, t.mint).Build(),
+		boop.NewNewClaimInstructionBuilder(
+			t.amount,
+			0,
+			t.proofs,
+			t.accounts.distributor,
+			t.accounts.claimStatus,
+			t.accounts.pool,
+			t.accounts.ata,
+			t.owner,
+		).Build(),
+	}
+
+	transferInstrs, err := receivingWalletInstructions(t.receiver, t.owner, t.mint, t.accounts.ata, t.amount)
+	if err != nil {
+		return nil, err
+	}
+	instrs = append(instrs, transferInstrs...)
+
+	// The sale's lookup tables keep the transaction within the size limit
+	txOpts := []solana.TransactionOption{solana.TransactionPayer(t.owner)}
+	if t.swap != nil {
+		instrs = append(instrs, t.swap.Instructions...)
+		txOpts = append(txOpts, solana.TransactionAddressTables(t.swap.AddressTables))
+	}
+
+	tx, err := solana.NewTransaction(instrs, blockhash, txOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	return tx, nil
+}
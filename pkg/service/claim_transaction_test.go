@@ -0,0 +1,166 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+
+	"boop-airdrop-redeemer/pkg/jupiter"
+	"boop-airdrop-redeemer/pkg/models"
+)
+
+// Run with -update after an intended change of the transaction builders to rewrite the snapshots
+var updateGolden = flag.Bool("update", false, "rewrite golden transaction snapshots")
+
+// testKey derives a fixed public key from a label
+func testKey(label string) solana.PublicKey {
+	return solana.PublicKeyFromBytes(sha256Sum(label))
+}
+
+// testClaim is a claim of 1,000,000 base units with a two node proof
+func testClaim(t *testing.T) claimTransaction {
+	owner := testKey("owner")
+	mint := testKey("mint")
+
+	var deriver claimAccountDeriver
+	accounts, err := deriver.derive(owner, mint)
+	assert.NoError(t, err)
+
+	airdrop := models.AirdropNode{Proofs: []json.RawMessage{proofNode(1), proofNode(2)}}
+	proofs, err := airdrop.ClaimProofs()
+	assert.NoError(t, err)
+
+	return claimTransaction{
+		owner:    owner,
+		mint:     mint,
+		accounts: accounts,
+		amount:   1_000_000,
+		proofs:   proofs,
+	}
+}
+
+func TestClaimTransactionGolden(t *testing.T) {
+	swap := testSwap(t)
+
+	tests := []struct {
+		name   string
+		modify func(claim *claimTransaction)
+	}{
+		{name: "claim"},
+		{name: "claim_receiving_wallet", modify: func(claim *claimTransaction) {
+			claim.receiver = testKey("receiver")
+		}},
+		{name: "claim_and_sell", modify: func(claim *claimTransaction) {
+			claim.swap = swap
+		}},
+	}
+
+	blockhash := solana.HashFromBytes(sha256Sum("blockhash"))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claim := testClaim(t)
+			if tt.modify != nil {
+				tt.modify(&claim)
+			}
+
+			tx, err := claim.build(defaultClaimComputeUnitLimit, blockhash)
+			assert.NoError(t, err)
+
+			assertGolden(t, tt.name, describeTransaction(t, tx))
+		})
+	}
+}
+
+// testSwap decodes the recorded Jupiter swap instructions with one lookup table holding the pool accounts
+func testSwap(t *testing.T) *jupiter.SwapInstructions {
+	data, err := os.ReadFile(filepath.Join("testdata", "swap_instructions.json"))
+	assert.NoError(t, err)
+
+	var resp jupiter.SwapInstructionsResponse
+	assert.NoError(t, json.Unmarshal(data, &resp))
+
+	instructions, err := resp.Instructions()
+	assert.NoError(t, err)
+
+	table := solana.MustPublicKeyFromBase58(resp.AddressLookupTableAddresses[0])
+	return &jupiter.SwapInstructions{
+		Instructions: instructions,
+		AddressTables: map[solana.PublicKey]solana.PublicKeySlice{
+			table: {
+				testKey("pool-a"),
+				testKey("pool-b"),
+				testKey("amm"),
+			},
+		},
+	}
+}
+
+// describeTransaction renders the message readably, followed by its exact serialization
+func describeTransaction(t *testing.T, tx *solana.Transaction) string {
+	var b strings.Builder
+	message := tx.Message
+
+	fmt.Fprintf(&b, "version: %d\n", message.GetVersion())
+	fmt.Fprintf(&b, "header: %d signed, %d readonly signed, %d readonly unsigned\n", message.Header.NumRequiredSignatures,
+		message.Header.NumReadonlySignedAccounts, message.Header.NumReadonlyUnsignedAccounts)
+	fmt.Fprintf(&b, "blockhash: %s\n", message.RecentBlockhash)
+
+	b.WriteString("accounts:\n")
+	for i, key := range message.AccountKeys {
+		fmt.Fprintf(&b, "  %d %s\n", i, key)
+	}
+	for _, lookup := range message.AddressTableLookups {
+		fmt.Fprintf(&b, "lookup table %s: writable %v, readonly %v\n", lookup.AccountKey, lookup.WritableIndexes, lookup.ReadonlyIndexes)
+	}
+
+	b.WriteString("instructions:\n")
+	for _, instruction := range message.Instructions {
+		fmt.Fprintf(&b, "  program %d, accounts %v\n    data %s\n", instruction.ProgramIDIndex, instruction.Accounts, hex.EncodeToString(instruction.Data))
+	}
+
+	serialized, err := message.MarshalBinary()
+	assert.NoError(t, err)
+	fmt.Fprintf(&b, "message: %s\n", base64.StdEncoding.EncodeToString(serialized))
+
+	return b.String()
+}
+
+// assertGolden compares got with testdata/<name>.golden, rewriting the file with -update
+func assertGolden(t *testing.T, name, got string) {
+	path := filepath.Join("testdata", name+".golden")
+	if *updateGolden {
+		assert.NoError(t, os.WriteFile(path, []byte(got), 0644))
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if assert.NoError(t, err, "missing snapshot, run go test -update") {
+		assert.Equal(t, string(want), got, "transaction changed, run go test -update if intended and review the diff")
+	}
+}
+
+// sha256Sum hashes a label into 32 fixed bytes
+func sha256Sum(label string) []byte {
+	sum := sha256.Sum256([]byte(label))
+	return sum[:]
+}
+
+// proofNode is a JSON proof node with every byte set to value
+func proofNode(value byte) json.RawMessage {
+	node := make([]int, 32)
+	for i := range node {
+		node[i] = int(value)
+	}
+	encoded, _ := json.Marshal(node)
+	return encoded
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/models"
+	"boop-airdrop-redeemer/pkg/operation"
+	sol "boop-airdrop-redeemer/pkg/solana"
+)
+
+// costBasisCheckInterval is how often the balances of kept tokens are compared with their open lots
+const costBasisCheckInterval = 15 * time.Minute
+
+// CostBasisWatch records the cost basis of kept claims and realizes the gain or loss when their tokens leave
+// the wallets. Sales by the token seller are realized with their proceeds. Any other drop of a kept token's
+// balance below its open lots, a sale or transfer outside the bot, is realized at the market price when the
+// balances are next checked. The claiming wallet, the receiving wallet and the vault all count as held.
+type CostBasisWatch struct {
+	stats        StatsStore
+	balances     BalanceProvider // nil when the swap service cannot list balances
+	prices       SwapProvider
+	mintDecimals *sol.MintDecimals // nil when the RPC client cannot read accounts
+	owners       []solana.PublicKey
+	logger       *log.Logger
+
+	mu        sync.Mutex     // Held while realizing, a check waits for a sale to be realized
+	selling   map[string]int // Sales in flight by mint, their balance drop is realized by the seller
+	lastCheck time.Time
+}
+
+// NewCostBasisWatch creates the cost basis watch, nil when no tokens are kept or no statistics are recorded
+func NewCostBasisWatch(cfg *config.Config, swap SwapProvider, stats StatsStore, mintDecimals *sol.MintDecimals, receiver solana.PublicKey, logger *log.Logger) *CostBasisWatch {
+	if len(cfg.KeepTokens) == 0 || stats == nil {
+		return nil
+	}
+
+	watch := &CostBasisWatch{
+		stats:        stats,
+		prices:       swap,
+		mintDecimals: mintDecimals,
+		logger:       logger,
+		selling:      make(map[string]int),
+	}
+	if balances, ok := swap.(BalanceProvider); ok {
+		watch.balances = balances
+	} else {
+		logging.Warnf(logger, "WARNING: Swap service cannot list token balances, kept tokens leaving the wallet outside the bot are not realized")
+	}
+
+	for _, address := range []string{cfg.WalletAddress, cfg.VaultWallet} {
+		if owner, err := solana.PublicKeyFromBase58(address); err == nil {
+			watch.owners = append(watch.owners, owner)
+		}
+	}
+	if !receiver.IsZero() {
+		watch.owners = append(watch.owners, receiver)
+	}
+	return watch
+}
+
+// RecordClaim records the USD value of a claimed kept token as its cost basis
+func (w *CostBasisWatch) RecordClaim(ctx context.Context, airdrop models.AirdropNode, claimTx string) {
+	if w == nil {
+		return
+	}
+
+	err := w.stats.RecordCostBasis(sol.CostBasisLot{
+		AirdropID:   airdrop.ID,
+		TokenSymbol: airdrop.Token.Symbol,
+		TokenMint:   airdrop.Token.Address,
+		Amount:      airdrop.AmountLpt.String(),
+		BasisUsd:    airdrop.AmountUsd.Float(),
+		ClaimTx:     claimTx,
+	})
+	if err != nil {
+		logging.Warnf(operation.Logger(ctx, w.logger), "Warning: Failed to record cost basis: %v", err)
+	}
+}
+
+// StartSale keeps the balance check off the mint until the returned function is called, the seller realizes
+// the sale itself
+func (w *CostBasisWatch) StartSale(mint string) func() {
+	if w == nil {
+		return func() {}
+	}
+
+	w.mu.Lock()
+	w.selling[mint]++
+	w.mu.Unlock()
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.selling[mint]--; w.selling[mint] <= 0 {
+			delete(w.selling, mint)
+		}
+	}
+}
+
+// RealizeSale realizes the sale of an airdrop's tokens by the bot against the open lots of its mint, first in
+// first out. The tokens of an airdrop that was not kept when it was claimed have no lot and realize nothing.
+func (w *CostBasisWatch) RealizeSale(ctx context.Context, airdrop models.AirdropNode, proceedsUsd float64, saleTx string) {
+	if w == nil {
+		return
+	}
+	logger := operation.Logger(ctx, w.logger)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	lots, err := w.stats.GetOpenCostBasisLots()
+	if err != nil {
+		logging.Warnf(logger, "Warning: Failed to read cost basis: %v", err)
+		return
+	}
+	kept := false
+	for _, lot := range lots {
+		kept = kept || lot.AirdropID == airdrop.ID
+	}
+	if !kept {
+		return
+	}
+
+	gains, err := w.stats.RealizeCostBasis(airdrop.Token.Address, airdrop.TokenAmount().Raw, proceedsUsd, saleTx)
+	if err != nil {
+		logging.Warnf(logger, "Warning: Failed to realize cost basis: %v", err)
+	}
+	w.logGains(logger, airdrop.Token.Symbol, gains)
+}
+
+// CheckIfDue compares the kept token balances with their open lots once every check interval
+func (w *CostBasisWatch) CheckIfDue(ctx context.Context) {
+	if w == nil || time.Since(w.lastCheck) < costBasisCheckInterval {
+		return
+	}
+	w.lastCheck = time.Now()
+
+	if err := w.Check(ctx); err != nil {
+		logging.Warnf(w.logger, "Warning: Cost basis check failed: %v", err)
+	}
+}
+
+// Check realizes the part of the open lots of each kept token that is no longer held, at the market price
+func (w *CostBasisWatch) Check(ctx context.Context) error {
+	if w == nil || w.balances == nil {
+		return nil
+	}
+
+	// Balances are read before the lots, a sale realized in between only lowers the lots
+	held := make(map[string]uint64)
+	prices := make(map[string]tokenValue)
+	for _, owner := range w.owners {
+		balances, err := w.balances.GetTokenBalances(ctx, owner)
+		if err != nil {
+			return fmt.Errorf("failed to get token balances of %s: %w", owner, err)
+		}
+		for mint, balance := range balances {
+			held[mint] += balance.Amount
+			if balance.UsdPrice > 0 {
+				prices[mint] = tokenValue{usdPrice: balance.UsdPrice, decimals: balance.Decimals}
+			}
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	lots, err := w.stats.GetOpenCostBasisLots()
+	if err != nil {
+		return fmt.Errorf("failed to read cost basis: %w", err)
+	}
+	open := make(map[string]uint64)
+	symbols := make(map[string]string)
+	for _, lot := range lots {
+		amount, _ := strconv.ParseUint(lot.Amount, 10, 64)
+		open[lot.TokenMint] += amount
+		symbols[lot.TokenMint] = lot.TokenSymbol
+	}
+
+	for mint, amount := range open {
+		if w.selling[mint] > 0 || held[mint] >= amount {
+			continue
+		}
+		disposed := amount - held[mint]
+
+		value, ok := prices[mint]
+		if !ok {
+			value, ok = w.marketValue(ctx, mint)
+		}
+		proceedsUsd := 0.0
+		if ok {
+			proceedsUsd = value.usd(disposed)
+		} else {
+			logging.Warnf(w.logger, "Warning: No price for %s, its disposal is realized without proceeds", symbols[mint])
+		}
+
+		w.logger.Printf("%d units of kept %s left the wallets outside the bot", disposed, symbols[mint])
+		gains, err := w.stats.RealizeCostBasis(mint, disposed, proceedsUsd, "")
+		if err != nil {
+			return fmt.Errorf("failed to realize cost basis of %s: %w", symbols[mint], err)
+		}
+		w.logGains(w.logger, symbols[mint], gains)
+	}
+	return nil
+}
+
+// tokenValue is the USD price of a token and the decimals its raw amounts have
+type tokenValue struct {
+	usdPrice float64
+	decimals uint8
+}
+
+// usd returns the USD value of raw units
+func (v tokenValue) usd(raw uint64) float64 {
+	return float64(raw) / math.Pow10(int(v.decimals)) * v.usdPrice
+}
+
+// marketValue looks up the price of a token no longer held by any of the wallets
+func (w *CostBasisWatch) marketValue(ctx context.Context, mint string) (tokenValue, bool) {
+	if w.prices == nil || w.mintDecimals == nil {
+		return tokenValue{}, false
+	}
+	price, err := w.prices.GetTokenUsdPrice(mint)
+	if err != nil {
+		return tokenValue{}, false
+	}
+	mintKey, err := solana.PublicKeyFromBase58(mint)
+	if err != nil {
+		return tokenValue{}, false
+	}
+	decimals, err := w.mintDecimals.Decimals(ctx, mintKey)
+	if err != nil {
+		return tokenValue{}, false
+	}
+	return tokenValue{usdPrice: price, decimals: decimals}, true
+}
+
+// logGains logs the total gain realized on the lots
+func (w *CostBasisWatch) logGains(logger *log.Logger, symbol string, gains []sol.RealizedGain) {
+	if len(gains) == 0 {
+		return
+	}
+	var total, basis float64
+	for _, gain := range gains {
+		total += gain.GainUsd()
+		basis += gain.BasisUsd
+	}
+	logger.Printf("Realized %+.2f USD on %s against a cost basis of $%.2f from %d lot(s)", total, symbol, basis, len(gains))
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/jupiter"
+	sol "boop-airdrop-redeemer/pkg/solana"
+)
+
+func TestCostBasisWatchRealizesTokensLeavingTheWallet(t *testing.T) {
+	stats, err := sol.NewStatsRecorder(t.TempDir())
+	require.NoError(t, err)
+	defer stats.Close()
+	require.NoError(t, stats.RecordCostBasis(sol.CostBasisLot{AirdropID: "kept", TokenSymbol: "KEEP", TokenMint: "keep", Amount: "1000000", BasisUsd: 10}))
+
+	cfg := &config.Config{WalletAddress: solana.NewWallet().PublicKey().String(), KeepTokens: []string{"keep"}}
+	swap := &balanceSwap{balances: map[string]jupiter.TokenBalance{
+		"keep": {Mint: "keep", Amount: 1_000_000, Decimals: 6, UsdPrice: 12},
+	}}
+	watch := NewCostBasisWatch(cfg, swap, stats, nil, solana.PublicKey{}, log.New(io.Discard, "", 0))
+
+	// Everything still held
+	require.NoError(t, watch.Check(context.Background()))
+	gains, err := stats.GetRealizedGains(time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, gains)
+
+	// A sale in flight is left to the seller
+	swap.balances["keep"] = jupiter.TokenBalance{Mint: "keep", Amount: 250_000, Decimals: 6, UsdPrice: 12}
+	done := watch.StartSale("keep")
+	require.NoError(t, watch.Check(context.Background()))
+	gains, err = stats.GetRealizedGains(time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, gains)
+	done()
+
+	// Three quarters left outside the bot, realized at the market price
+	require.NoError(t, watch.Check(context.Background()))
+	gains, err = stats.GetRealizedGains(time.Time{})
+	require.NoError(t, err)
+	require.Len(t, gains, 1)
+	assert.Equal(t, "750000", gains[0].Amount)
+	assert.InDelta(t, 9, gains[0].ProceedsUsd, 0.01)
+	assert.InDelta(t, 7.5, gains[0].BasisUsd, 0.01)
+
+	assert.Nil(t, NewCostBasisWatch(&config.Config{}, swap, stats, nil, solana.PublicKey{}, nil))
+}
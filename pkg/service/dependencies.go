@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/jupiter"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/retry"
+	sol "boop-airdrop-redeemer/pkg/solana"
+)
+
+// SwapProvider sells tokens for SOL and prices them
+type SwapProvider interface {
+	SwapTokenForSol(ctx context.Context, privateKeyBase58 string, inputMint string, amount uint64) (solana.Signature, error)
+	SwapTokenForUsdc(ctx context.Context, privateKeyBase58 string, inputMint string, amount uint64) (solana.Signature, error)
+	GetTokenUsdPrice(tokenMint string) (float64, error)
+	EstimateSwapOutputAmount(tokenMint string, amount uint64) (float64, error)
+	SwapInstructionsForSol(ctx context.Context, owner solana.PublicKey, inputMint string, amount uint64) (*jupiter.SwapInstructions, error)
+}
+
+// PricePrefetcher fetches the prices of several mints at once for the lookups that follow
+type PricePrefetcher interface {
+	PrefetchPrices(mints []string)
+}
+
+// StatsStore records transaction statistics and reports profit
+type StatsStore interface {
+	RecordClaimStats(tokenSymbol, tokenAmount string, fees uint64, rent int64, txHash, operationID string) error
+	RecordSwapStats(tokenSymbol, tokenAmount string, fees, earnings uint64, rent int64, txHash, operationID string) error
+	RecordEstimatedClaimStats(tokenSymbol, tokenAmount string, fees uint64, txHash, operationID string) error
+	RecordEstimatedSwapStats(tokenSymbol, tokenAmount string, fees, earnings uint64, txHash, operationID string) error
+	RecordServiceFeeStats(tokenSymbol string, serviceFee, fees uint64, txHash, operationID string) error
+	RecordVaultTransferStats(tokenSymbol, tokenAmount string, fees uint64, txHash string) error
+	RecordPlatformFeeStats(tokenSymbol string, fee uint64, txHash, operationID string) error
+	RecordRentReclaimStats(accounts int, fees uint64, rent int64, txHash string) error
+	GetVaultTransfers(since time.Time) ([]sol.TransactionStats, error)
+	RecordOutcome(outcome sol.Outcome) error
+	RecordAirdropValue(value sol.AirdropValue) error
+	RecordDecision(decision sol.Decision) error
+	RecordCostBasis(lot sol.CostBasisLot) error
+	RealizeCostBasis(mint string, raw uint64, proceedsUsd float64, saleTx string) ([]sol.RealizedGain, error)
+	GetOpenCostBasisLots() ([]sol.CostBasisLot, error)
+	GetRealizedGainStats(since time.Time) (sol.RealizedGainStats, error)
+	RecordComputeUnits(operation string, consumed uint64) error
+	ComputeUnitLimit(operation string, margin float64, fallback uint32) uint32
+	CalculateNetProfitFromClaimAndSwap(claim, swap sol.TransactionResult) float64
+	GetProfitSummary(includeEstimates bool) (sol.ProfitSummary, error)
+	GetRealizationSummary(since time.Time) (sol.RealizationSummary, error)
+	GetEfficiencySummary(since time.Time, includeEstimates bool) (sol.EfficiencySummary, error)
+	GetOutcomes(since time.Time) ([]sol.Outcome, error)
+	GetAirdropValues(since time.Time) ([]sol.AirdropValue, error)
+	GetTransactions(since time.Time) ([]sol.TransactionStats, error)
+	GetTransactionHashes() (map[string]bool, error)
+	RecordTransaction(stats sol.TransactionStats) error
+	GetProfitHistory(since time.Time, includeEstimates bool) ([]sol.ProfitPoint, error)
+	ReconcileEstimates(ctx context.Context, solClient sol.RPCClient) (int, error)
+	State(name string) sol.StateStore
+	Close() error
+}
+
+// PriceProvider provides the current SOL price
+type PriceProvider interface {
+	Start(ctx context.Context)
+	Stop()
+	GetPrice() (sol.PriceQuote, bool)
+}
+
+var (
+	_ SwapProvider    = (*jupiter.SwapService)(nil)
+	_ PricePrefetcher = (*jupiter.SwapService)(nil)
+	_ StatsStore      = (*sol.StatsRecorder)(nil)
+	_ PriceProvider   = (*sol.PriceService)(nil)
+)
+
+// ClaimerDeps holds the pluggable dependencies of AirdropClaimer.
+// A nil StatsRecorder or PriceService disables stats recording or USD pricing.
+type ClaimerDeps struct {
+	SolClient     sol.RPCClient
+	SwapService   SwapProvider
+	StatsRecorder StatsStore
+	PriceService  PriceProvider
+	RateLimits    []*retry.RateLimits // Rate limits of the RPC and swap clients, scans slow down while they are hit
+}
+
+// DefaultClaimerDeps builds the production dependencies from the configuration
+func DefaultClaimerDeps(cfg *config.Config, logger *log.Logger) ClaimerDeps {
+	// Initialize Solana RPC client
+	rpcRetrier := retry.NewDefault(cfg.RetryMaxAttempts, cfg.RetryBudget, logger)
+	solClient := sol.NewRPCClient(cfg.SolanaRpcURL, rpcRetrier)
+
+	// Initialize Jupiter swap service
+	swapService := jupiter.NewSwapService(solClient, logger)
+	jupiterRetrier := retry.NewDefault(cfg.RetryMaxAttempts, cfg.RetryBudget, logger)
+	swapService.SetRetrier(jupiterRetrier)
+	if cfg.SlippageAutoTune {
+		tuner, err := jupiter.NewSlippageTuner(cfg.SlippageMinBps, cfg.SlippageMaxBps, filepath.Join(cfg.StatsDataDir, "slippage.json"), logger)
+		if err != nil {
+			logging.Warnf(logger, "WARNING: Slippage tuning disabled: %v", err)
+		} else {
+			swapService.SetSlippageTuner(tuner)
+		}
+	}
+
+	if cfg.PriceCacheTTL > 0 {
+		swapService.SetPriceCache(cfg.PriceCacheTTL)
+	}
+	swapService.SetRebroadcast(cfg.RebroadcastInterval)
+	swapService.SetQuoteMaxAge(cfg.QuoteMaxAge)
+	if len(cfg.RouteHints) > 0 {
+		hints := make(map[string]jupiter.RouteHint, len(cfg.RouteHints))
+		for mint, hint := range cfg.RouteHints {
+			hints[mint] = jupiter.RouteHint(hint)
+		}
+		swapService.SetRouteHints(hints)
+	}
+	if cfg.SimulateSwaps {
+		swapService.SetSwapSimulation(cfg.SimulationTolerance)
+	}
+
+	deps := ClaimerDeps{
+		SolClient:   solClient,
+		SwapService: swapService,
+		RateLimits:  []*retry.RateLimits{rpcRetrier.RateLimits(), jupiterRetrier.RateLimits()},
+	}
+
+	// Initialize stats recorder, left nil on failure so it is treated as disabled
+	statsRecorder, err := sol.NewStatsRecorderWithBackend(cfg.StatsDataDir, cfg.StatsBackend)
+	if err != nil {
+		logging.Warnf(logger, "WARNING: Failed to initialize stats recorder: %v", err)
+	} else {
+		deps.StatsRecorder = statsRecorder
+	}
+
+	// Collect a platform fee on swaps to SOL into the operator's fee account
+	if cfg.JupiterPlatformFeeBps > 0 && cfg.JupiterFeeAccount != "" {
+		var feeStats jupiter.PlatformFeeRecorder
+		if deps.StatsRecorder != nil {
+			feeStats = statsRecorder
+		}
+		swapService.SetPlatformFee(cfg.JupiterFeeAccount, cfg.JupiterPlatformFeeBps, feeStats)
+		logger.Printf("Jupiter platform fee enabled: %d bps to %s", cfg.JupiterPlatformFeeBps, cfg.JupiterFeeAccount)
+	}
+
+	// Initialize price service
+	priceService := sol.NewPriceService(logger)
+	if cfg.SolPriceSource == "pyth" {
+		pythService, err := sol.NewPythPriceService(solClient, cfg.PythSolUsdAccount, logger)
+		if err != nil {
+			logging.Warnf(logger, "WARNING: Failed to initialize Pyth price source, using CoinGecko: %v", err)
+		} else {
+			priceService = pythService
+		}
+	}
+	deps.PriceService = priceService
+
+	return deps
+}
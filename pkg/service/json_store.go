@@ -0,0 +1,299 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/models"
+)
+
+// jsonStoreVersion is the format of the JSON store file, bumped on incompatible changes
+const jsonStoreVersion = 1
+
+// jsonStoreFile is the content of the JSON store file
+type jsonStoreFile struct {
+	Version  int                  `json:"version"`
+	Airdrops []*jsonAirdropRecord `json:"airdrops"`
+}
+
+// jsonAirdropRecord is an airdrop as last seen from the API, with the locally recorded claim kept separately
+type jsonAirdropRecord struct {
+	ID        string          `json:"id"`
+	Data      json.RawMessage `json:"data"`
+	FirstSeen time.Time       `json:"first_seen"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	ClaimedAt string          `json:"claimed_at,omitempty"`
+	ClaimTx   string          `json:"claim_tx,omitempty"`
+
+	digest [sha256.Size]byte
+}
+
+// jsonAirdropStore is an AirdropStore kept in memory and persisted to a JSON file, so seen and claimed
+// airdrops survive restarts without CGO. Saved airdrops are flushed at most once per flush interval,
+// claims are written right away.
+type jsonAirdropStore struct {
+	path          string
+	flushInterval time.Duration
+	logger        *log.Logger
+
+	mu         sync.Mutex
+	records    map[string]*jsonAirdropRecord
+	dirty      bool
+	flushTimer *time.Timer
+}
+
+// NewJSONAirdropStore loads (or creates) the JSON store file at path. Saved airdrops are written within
+// flushInterval, 0 writes them on every save.
+func NewJSONAirdropStore(path string, flushInterval time.Duration, logger *log.Logger) (AirdropStore, error) {
+	s := &jsonAirdropStore{
+		path:          path,
+		flushInterval: flushInterval,
+		logger:        logger,
+		records:       make(map[string]*jsonAirdropRecord),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the store file, a missing file is an empty store
+func (s *jsonAirdropStore) load() error {
+	content, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read airdrop store: %w", err)
+	}
+
+	var file jsonStoreFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return fmt.Errorf("failed to decode airdrop store %s: %w", s.path, err)
+	}
+	if file.Version != jsonStoreVersion {
+		return fmt.Errorf("airdrop store %s has version %d, expected %d", s.path, file.Version, jsonStoreVersion)
+	}
+
+	for _, record := range file.Airdrops {
+		if record == nil || record.ID == "" {
+			continue
+		}
+		// The file is indented, compact the data again so unchanged airdrops keep their digest
+		var data bytes.Buffer
+		if err := json.Compact(&data, record.Data); err != nil {
+			return fmt.Errorf("failed to decode airdrop %s in store %s: %w", record.ID, s.path, err)
+		}
+		record.Data = data.Bytes()
+		record.digest = sha256.Sum256(record.Data)
+		s.records[record.ID] = record
+	}
+	return nil
+}
+
+// SaveAirdrop inserts or updates an airdrop, keeping its first seen time and local claim record
+func (s *jsonAirdropStore) SaveAirdrop(airdrop models.AirdropNode) {
+	s.SaveAirdrops([]models.AirdropNode{airdrop})
+}
+
+// SaveAirdrops inserts new and updates changed airdrops, keeping their first seen time and local claim
+// record, and schedules a flush when any changed. Locally recorded claims are applied to the airdrops
+// passed in.
+func (s *jsonAirdropStore) SaveAirdrops(airdrops []models.AirdropNode) []AirdropChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changes := make([]AirdropChange, len(airdrops))
+	now := time.Now()
+	for i := range airdrops {
+		airdrop := &airdrops[i]
+		data, err := json.Marshal(airdrop)
+		if err != nil {
+			logging.Warnf(s.logger, "Warning: Failed to encode airdrop %s for the store: %v", airdrop.ID, err)
+			continue
+		}
+
+		record, exists := s.records[airdrop.ID]
+		if exists && airdrop.ClaimedAt == nil && record.ClaimedAt != "" {
+			airdrop.ClaimedAt = record.ClaimedAt
+		}
+
+		digest := sha256.Sum256(data)
+		if exists && digest == record.digest {
+			continue
+		}
+
+		changes[i] = AirdropUpdated
+		if !exists {
+			changes[i] = AirdropNew
+			record = &jsonAirdropRecord{ID: airdrop.ID, FirstSeen: now}
+			s.records[airdrop.ID] = record
+		}
+		record.Data = data
+		record.UpdatedAt = now
+		record.digest = digest
+		s.dirty = true
+	}
+
+	if s.dirty {
+		s.scheduleFlush()
+	}
+	return changes
+}
+
+// scheduleFlush writes the store after the flush interval unless a flush is already pending
+func (s *jsonAirdropStore) scheduleFlush() {
+	if s.flushInterval <= 0 {
+		if err := s.write(); err != nil {
+			logging.Warnf(s.logger, "Warning: Failed to write airdrop store: %v", err)
+		}
+		return
+	}
+	if s.flushTimer != nil {
+		return
+	}
+	s.flushTimer = time.AfterFunc(s.flushInterval, func() {
+		if err := s.Flush(); err != nil {
+			logging.Warnf(s.logger, "Warning: Failed to write airdrop store: %v", err)
+		}
+	})
+}
+
+// Flush writes pending changes to the store file
+func (s *jsonAirdropStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+		s.flushTimer = nil
+	}
+	if !s.dirty {
+		return nil
+	}
+	return s.write()
+}
+
+// write persists every record through a temporary file so a crash never leaves a partial store behind.
+// A failed write keeps the store dirty, so it is written again on the next flush.
+func (s *jsonAirdropStore) write() error {
+	file := jsonStoreFile{Version: jsonStoreVersion, Airdrops: s.sortedRecords()}
+	content, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode airdrop store: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create airdrop store directory: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write airdrop store: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to replace airdrop store: %w", err)
+	}
+
+	s.dirty = false
+	return nil
+}
+
+// sortedRecords returns the records in the order they were first seen
+func (s *jsonAirdropStore) sortedRecords() []*jsonAirdropRecord {
+	records := make([]*jsonAirdropRecord, 0, len(s.records))
+	for _, record := range s.records {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].FirstSeen.Equal(records[j].FirstSeen) {
+			return records[i].FirstSeen.Before(records[j].FirstSeen)
+		}
+		return records[i].ID < records[j].ID
+	})
+	return records
+}
+
+// HasAirdropWithID checks if an airdrop with given ID exists
+func (s *jsonAirdropStore) HasAirdropWithID(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, exists := s.records[id]
+	return exists
+}
+
+// GetAllAirdrops returns all stored airdrops in the order they were first seen
+func (s *jsonAirdropStore) GetAllAirdrops() []models.AirdropNode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var airdrops []models.AirdropNode
+	for _, record := range s.sortedRecords() {
+		airdrop, err := record.airdrop()
+		if err != nil {
+			logging.Warnf(s.logger, "Warning: Skipping unreadable stored airdrop: %v", err)
+			continue
+		}
+		airdrops = append(airdrops, airdrop)
+	}
+	return airdrops
+}
+
+// ClaimAirdrop retrieves an airdrop by ID to claim it, reporting it as claimed when a claim was recorded locally
+func (s *jsonAirdropStore) ClaimAirdrop(airdropID string) (models.AirdropNode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, exists := s.records[airdropID]
+	if !exists {
+		return models.AirdropNode{}, fmt.Errorf("airdrop with ID %s not found", airdropID)
+	}
+	airdrop, err := record.airdrop()
+	if err != nil {
+		return models.AirdropNode{}, fmt.Errorf("failed to load airdrop %s: %w", airdropID, err)
+	}
+	return airdrop, nil
+}
+
+// MarkClaimed records a confirmed claim and writes the store right away, so the claim is not attempted
+// again after a restart
+func (s *jsonAirdropStore) MarkClaimed(airdropID, txHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, exists := s.records[airdropID]
+	if !exists {
+		return nil
+	}
+	record.ClaimedAt = time.Now().Format(time.RFC3339)
+	record.ClaimTx = txHash
+	s.dirty = true
+
+	if err := s.write(); err != nil {
+		return fmt.Errorf("failed to mark airdrop %s as claimed: %w", airdropID, err)
+	}
+	return nil
+}
+
+// airdrop decodes the stored airdrop, applying the local claim time when the API data has none
+func (r *jsonAirdropRecord) airdrop() (models.AirdropNode, error) {
+	var airdrop models.AirdropNode
+	if err := json.Unmarshal(r.Data, &airdrop); err != nil {
+		return models.AirdropNode{}, fmt.Errorf("failed to decode stored airdrop: %w", err)
+	}
+	if airdrop.ClaimedAt == nil && r.ClaimedAt != "" {
+		airdrop.ClaimedAt = r.ClaimedAt
+	}
+	return airdrop, nil
+}
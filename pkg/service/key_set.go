@@ -0,0 +1,37 @@
+package service
+
+import "sync"
+
+// keySet is a set of keys safe for concurrent use, its zero value is empty and ready to use
+type keySet struct {
+	mu   sync.Mutex
+	keys map[string]bool
+}
+
+// add adds the key, reporting whether it was not in the set yet
+func (s *keySet) add(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys == nil {
+		s.keys = make(map[string]bool)
+	}
+	if s.keys[key] {
+		return false
+	}
+	s.keys[key] = true
+	return true
+}
+
+// remove removes the key
+func (s *keySet) remove(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, key)
+}
+
+// contains reports whether the key is in the set
+func (s *keySet) contains(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys[key]
+}
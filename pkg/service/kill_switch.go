@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
+)
+
+// maxKillSwitchReason is the longest kill switch reason kept from the file or remote flag
+const maxKillSwitchReason = 200
+
+// ErrKillSwitchEngaged is returned instead of signing a transaction while the kill switch is engaged
+var ErrKillSwitchEngaged = errors.New("kill switch engaged, signing is disabled")
+
+// KillSwitch stops all transaction signing while a local file exists or a remote flag is set,
+// so many deployed instances can be halted at once during an incident
+type KillSwitch struct {
+	path       string
+	url        string
+	httpClient *http.Client
+	logger     *log.Logger
+
+	mu           sync.Mutex
+	remoteReason string // non-empty while the remote flag is set
+	engaged      bool
+	reason       string
+}
+
+// NewKillSwitch creates a kill switch from the configuration, nil when neither a file nor a URL is configured
+func NewKillSwitch(cfg *config.Config, logger *log.Logger) *KillSwitch {
+	if cfg.KillSwitchFile == "" && cfg.KillSwitchURL == "" {
+		return nil
+	}
+
+	return &KillSwitch{
+		path:       cfg.KillSwitchFile,
+		url:        cfg.KillSwitchURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logger:     logger,
+	}
+}
+
+// Check reads the local file and the remote flag and reports whether the kill switch is engaged
+// and whether that changed since the last check. An unreachable remote flag keeps its last state.
+func (k *KillSwitch) Check(ctx context.Context) (engaged bool, reason string, changed bool) {
+	if k == nil {
+		return false, "", false
+	}
+
+	if k.url != "" {
+		remoteReason, err := k.fetchRemoteFlag(ctx)
+		k.mu.Lock()
+		if err != nil {
+			logging.Warnf(k.logger, "Warning: Failed to check remote kill switch, keeping last state: %v", err)
+		} else {
+			k.remoteReason = remoteReason
+		}
+		k.mu.Unlock()
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	wasEngaged := k.engaged
+	k.engaged, k.reason = k.currentLocked()
+	return k.engaged, k.reason, k.engaged != wasEngaged
+}
+
+// Guard returns ErrKillSwitchEngaged while the kill switch is engaged. The local file is read again
+// on every call, so dropping it stops signing immediately, even in the middle of a cycle.
+func (k *KillSwitch) Guard() error {
+	if k == nil {
+		return nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if engaged, reason := k.currentLocked(); engaged {
+		return fmt.Errorf("%w: %s", ErrKillSwitchEngaged, reason)
+	}
+	return nil
+}
+
+// currentLocked combines the local file with the last known remote flag, k.mu must be held
+func (k *KillSwitch) currentLocked() (bool, string) {
+	if k.path != "" {
+		if content, err := os.ReadFile(k.path); err == nil {
+			reason := killSwitchReason(string(content))
+			if reason == "" {
+				reason = fmt.Sprintf("kill switch file %s present", k.path)
+			}
+			return true, reason
+		}
+	}
+
+	if k.remoteReason != "" {
+		return true, k.remoteReason
+	}
+	return false, ""
+}
+
+// fetchRemoteFlag reads the remote flag. A 404 or an empty, "0", "false" or "off" body means the
+// flag is not set, any other body is the reason for engaging the kill switch.
+func (k *KillSwitch) fetchRemoteFlag(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create kill switch request: %w", err)
+	}
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch kill switch flag: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("kill switch flag returned status %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", fmt.Errorf("failed to read kill switch flag: %w", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(string(body))) {
+	case "", "0", "false", "off":
+		return "", nil
+	}
+
+	reason := killSwitchReason(string(body))
+	if strings.EqualFold(reason, "1") || strings.EqualFold(reason, "true") || strings.EqualFold(reason, "on") {
+		reason = "remote kill switch flag set"
+	}
+	return reason, nil
+}
+
+// killSwitchReason trims a reason read from the file or remote flag to a single short line
+func killSwitchReason(content string) string {
+	reason := strings.TrimSpace(content)
+	if i := strings.IndexAny(reason, "\r\n"); i >= 0 {
+		reason = strings.TrimSpace(reason[:i])
+	}
+	if len(reason) > maxKillSwitchReason {
+		reason = reason[:maxKillSwitchReason]
+	}
+	return reason
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+
+	"boop-airdrop-redeemer/pkg/amount"
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/jupiter"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/notifications"
+)
+
+// BalanceProvider lists the wallet's token balances valued in USD
+type BalanceProvider interface {
+	GetTokenBalances(ctx context.Context, owner solana.PublicKey) (map[string]jupiter.TokenBalance, error)
+}
+
+// PositionCap values the token positions of the wallet and alerts when one is worth more than the cap, so a
+// memecoin that is kept or could not be sold does not grow into an unbounded exposure. Optionally the part
+// above the cap is sold.
+type PositionCap struct {
+	capUsd         float64
+	sellDown       bool
+	interval       time.Duration
+	config         *config.Config
+	balances       BalanceProvider
+	swap           SwapProvider
+	telegramClient *notifications.TelegramClient
+	killSwitch     *KillSwitch
+	logger         *log.Logger
+
+	lastCheck time.Time
+	above     keySet // Mints above the cap and already alerted, alerted again after dropping below it
+}
+
+// NewPositionCap creates the position cap check, nil when no cap is configured
+func NewPositionCap(cfg *config.Config, swap SwapProvider, telegramClient *notifications.TelegramClient, killSwitch *KillSwitch, logger *log.Logger) *PositionCap {
+	if cfg.PositionCapUsd <= 0 {
+		return nil
+	}
+
+	balances, ok := swap.(BalanceProvider)
+	if !ok {
+		logging.Warnf(logger, "WARNING: Swap service cannot list token balances, position cap disabled")
+		return nil
+	}
+
+	logger.Printf("Position cap enabled: $%.2f per token, checked every %s", cfg.PositionCapUsd, cfg.PositionCheckInterval)
+
+	return &PositionCap{
+		capUsd:         cfg.PositionCapUsd,
+		sellDown:       cfg.PositionCapSell,
+		interval:       cfg.PositionCheckInterval,
+		config:         cfg,
+		balances:       balances,
+		swap:           swap,
+		telegramClient: telegramClient,
+		killSwitch:     killSwitch,
+		logger:         logger,
+	}
+}
+
+// CheckIfDue checks the positions once every check interval
+func (p *PositionCap) CheckIfDue(ctx context.Context) {
+	if p == nil || time.Since(p.lastCheck) < p.interval {
+		return
+	}
+	p.lastCheck = time.Now()
+
+	if err := p.Check(ctx); err != nil {
+		logging.Warnf(p.logger, "Warning: Position cap check failed: %v", err)
+	}
+}
+
+// Check values every token position of the wallet, alerting once for each position that rises above the cap
+// and selling the part above it when enabled. SOL and USDC are not positions.
+func (p *PositionCap) Check(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+
+	owner, err := solana.PublicKeyFromBase58(p.config.WalletAddress)
+	if err != nil {
+		return fmt.Errorf("invalid wallet address: %w", err)
+	}
+
+	balances, err := p.balances.GetTokenBalances(ctx, owner)
+	if err != nil {
+		return fmt.Errorf("failed to get token balances: %w", err)
+	}
+
+	mints := make([]string, 0, len(balances))
+	for mint := range balances {
+		mints = append(mints, mint)
+	}
+	sort.Strings(mints)
+
+	for _, mint := range mints {
+		balance := balances[mint]
+		if mint == jupiter.WrappedSolMint || mint == jupiter.QuoteCurrencyMint {
+			continue
+		}
+		if balance.UsdValue <= p.capUsd {
+			p.above.remove(mint)
+			continue
+		}
+
+		tokens := amount.TokenAmount{Raw: balance.Amount, Decimals: balance.Decimals}
+		sellDown := p.sellDown && !p.config.KeepsToken(mint)
+
+		if p.above.add(mint) {
+			p.logger.Printf("Position of %s is worth $%.2f, above the $%.2f cap", config.ShortenAddress(mint), balance.UsdValue, p.capUsd)
+			if p.telegramClient != nil {
+				p.telegramClient.SendPositionCapNotification(mint, tokens, balance.UsdValue, p.capUsd, sellDown)
+			}
+		}
+
+		if sellDown {
+			if err := p.sellExcess(ctx, balance); err != nil {
+				logging.Warnf(p.logger, "Warning: Failed to sell %s down to the position cap: %v", config.ShortenAddress(mint), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// sellExcess sells the share of the balance worth more than the cap
+func (p *PositionCap) sellExcess(ctx context.Context, balance jupiter.TokenBalance) error {
+	if err := p.killSwitch.Guard(); err != nil {
+		return err
+	}
+
+	excessUsd := balance.UsdValue - p.capUsd
+	sell := amount.TokenAmount{
+		Raw:      uint64(float64(balance.Amount) * excessUsd / balance.UsdValue),
+		Decimals: balance.Decimals,
+	}
+	if sell.Raw == 0 {
+		return nil
+	}
+
+	swap := p.swap.SwapTokenForSol
+	if NewClaimConfig(p.config).SellTarget == SellTargetUsdc {
+		swap = p.swap.SwapTokenForUsdc
+	}
+
+	signature, err := swap(ctx, p.config.WalletPrivateKey, balance.Mint, sell.Raw)
+	if err != nil {
+		return err
+	}
+
+	txHash := signature.String()
+	p.logger.Printf("Sold %s %s (about $%.2f) down to the position cap: %s",
+		sell.Format(4), config.ShortenAddress(balance.Mint), excessUsd, txHash)
+	if p.telegramClient != nil {
+		p.telegramClient.SendPositionSoldDownNotification(balance.Mint, sell, excessUsd, txHash)
+	}
+	return nil
+}
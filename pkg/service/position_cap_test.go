@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/jupiter"
+)
+
+// balanceSwap reports fixed balances and records the amounts sold
+type balanceSwap struct {
+	quoteOnlySwap
+	balances map[string]jupiter.TokenBalance
+	sold     map[string]uint64
+}
+
+func (s *balanceSwap) GetTokenBalances(context.Context, solana.PublicKey) (map[string]jupiter.TokenBalance, error) {
+	return s.balances, nil
+}
+
+func (s *balanceSwap) SwapTokenForSol(_ context.Context, _ string, mint string, amount uint64) (solana.Signature, error) {
+	s.sold[mint] += amount
+	return solana.Signature{}, nil
+}
+
+func TestPositionCapSellsDownToCap(t *testing.T) {
+	cfg := &config.Config{
+		WalletAddress:   solana.NewWallet().PublicKey().String(),
+		PositionCapUsd:  100,
+		PositionCapSell: true,
+		KeepTokens:      []string{"kept"},
+	}
+	swap := &balanceSwap{
+		balances: map[string]jupiter.TokenBalance{
+			"large":                   {Mint: "large", Amount: 1_000_000, UsdValue: 400},
+			"small":                   {Mint: "small", Amount: 1_000_000, UsdValue: 50},
+			"kept":                    {Mint: "kept", Amount: 1_000_000, UsdValue: 500},
+			jupiter.QuoteCurrencyMint: {Mint: jupiter.QuoteCurrencyMint, Amount: 1_000_000_000, UsdValue: 1000},
+		},
+		sold: make(map[string]uint64),
+	}
+
+	check := NewPositionCap(cfg, swap, nil, nil, log.New(io.Discard, "", 0))
+	assert.NoError(t, check.Check(context.Background()))
+
+	// Three quarters of the large position are above the cap, kept tokens and USDC are never sold
+	assert.Equal(t, map[string]uint64{"large": 750_000}, swap.sold)
+	assert.True(t, check.above.contains("kept"))
+	assert.False(t, check.above.contains("small"))
+
+	cfg.PositionCapUsd = 0
+	assert.Nil(t, NewPositionCap(cfg, swap, nil, nil, log.New(io.Discard, "", 0)))
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"fmt"
+	"log"
+
+	"boop-airdrop-redeemer/pkg/amount"
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/jupiter"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/models"
+	sol "boop-airdrop-redeemer/pkg/solana"
+)
+
+// estimatedSwapComputeUnits is the compute unit limit assumed for a Jupiter swap, routes through several pools
+// use up to this much
+const estimatedSwapComputeUnits = 400000
+
+// ClaimEstimate is the expected outcome of claiming an airdrop and selling it for SOL. The fees of a sale are
+// zero for tokens that are not sold, and the service fee is zero for sales to USDC.
+type ClaimEstimate struct {
+	Output     amount.Lamports // SOL the swap quote returns for the claimed tokens
+	ClaimFee   amount.Lamports // Base and priority fee of the claim transaction
+	SwapFee    amount.Lamports // Base and priority fee of the swap transaction
+	ServiceFee amount.Lamports // Operator's share of the output
+}
+
+// NetProfit returns the output minus all fees in SOL, negative when claiming loses money
+func (e ClaimEstimate) NetProfit() float64 {
+	return e.Output.Sol() - e.ClaimFee.Sol() - e.SwapFee.Sol() - e.ServiceFee.Sol()
+}
+
+// String summarizes the estimate for logs
+func (e ClaimEstimate) String() string {
+	return fmt.Sprintf("quote %s, claim fee %s, swap fee %s, service fee %s, net %.6f SOL",
+		e.Output, e.ClaimFee, e.SwapFee, e.ServiceFee, e.NetProfit())
+}
+
+// ProfitCheck estimates what claiming and selling an airdrop nets from a swap quote for the claimed amount and
+// the fees of both transactions, and holds back claims below the configured margin. The USD threshold alone
+// lets through airdrops whose fees eat their value, e.g. while priority fees are high.
+type ProfitCheck struct {
+	config            *config.Config
+	swap              SwapProvider
+	statsRecorder     StatsStore
+	minNetProfit      float64
+	computeUnitMargin float64
+	serviceFeeBps     int
+	logger            *log.Logger
+}
+
+// NewProfitCheck creates the profit pre-check from the configuration, nil when it is disabled
+func NewProfitCheck(cfg *config.Config, swap SwapProvider, statsRecorder StatsStore, logger *log.Logger) *ProfitCheck {
+	if !cfg.ProfitPrecheck || swap == nil {
+		return nil
+	}
+
+	return &ProfitCheck{
+		config:            cfg,
+		swap:              swap,
+		statsRecorder:     statsRecorder,
+		minNetProfit:      cfg.ClaimMinNetProfit,
+		computeUnitMargin: cfg.ComputeUnitMargin,
+		serviceFeeBps:     cfg.ServiceFeeBps,
+		logger:            logger,
+	}
+}
+
+// Estimate quotes the sale of the airdrop's tokens and estimates the fees of claiming and selling them
+func (p *ProfitCheck) Estimate(airdrop models.AirdropNode) (ClaimEstimate, error) {
+	if !airdrop.AmountLpt.Valid() {
+		return ClaimEstimate{}, fmt.Errorf("airdrop %s has no valid token amount", airdrop.ID)
+	}
+
+	quotedSol, err := p.swap.EstimateSwapOutputAmount(airdrop.Token.Address, airdrop.AmountLpt.Units())
+	if err != nil {
+		return ClaimEstimate{}, err
+	}
+
+	claimComputeUnits := uint32(defaultClaimComputeUnitLimit)
+	if p.statsRecorder != nil {
+		claimComputeUnits = p.statsRecorder.ComputeUnitLimit(sol.OperationClaim, p.computeUnitMargin, claimComputeUnits)
+	}
+
+	output := amount.FromSol(quotedSol)
+	estimate := ClaimEstimate{
+		Output:   output,
+		ClaimFee: transactionFee(claimComputeUnitPrice, uint64(claimComputeUnits)),
+	}
+
+	// Kept tokens, and tokens held or moved to the receiving wallet, are valued at the quote without selling them
+	claimConfig := NewClaimConfig(p.config)
+	if !claimConfig.AutoSell || p.config.KeepsToken(airdrop.Token.Address) {
+		return estimate, nil
+	}
+	estimate.SwapFee = transactionFee(jupiter.SwapComputeUnitPrice, estimatedSwapComputeUnits)
+	if claimConfig.SellTarget == SellTargetSol {
+		// The platform fee is only taken from SOL output
+		estimate.ServiceFee = output * amount.Lamports(p.serviceFeeBps) / 10_000
+	}
+	return estimate, nil
+}
+
+// Allows reports whether the estimated net profit of claiming the airdrop reaches the margin. Airdrops that
+// cannot be quoted are allowed, the quote guard checks the sale again after claiming.
+func (p *ProfitCheck) Allows(airdrop models.AirdropNode) bool {
+	if p == nil {
+		return true
+	}
+
+	estimate, err := p.Estimate(airdrop)
+	if err != nil {
+		logging.Warnf(p.logger, "Warning: Could not estimate the profit of claiming %s: %v", airdrop.Token.Symbol, err)
+		return true
+	}
+
+	if estimate.NetProfit() < p.minNetProfit {
+		p.logger.Printf("Skipping claim of %s: estimated net profit below %g SOL (%s)",
+			airdrop.Token.Symbol, p.minNetProfit, estimate)
+		return false
+	}
+	return true
+}
+
+// AllowsTogether reports whether the estimated net profits of claiming the airdrops add up to the margin, for
+// drops released together because they are only worth claiming as a batch. Airdrops that cannot be quoted are
+// left out of the sum.
+func (p *ProfitCheck) AllowsTogether(airdrops []models.AirdropNode) bool {
+	if p == nil || len(airdrops) == 0 {
+		return true
+	}
+
+	total, quoted := 0.0, 0
+	for _, airdrop := range airdrops {
+		estimate, err := p.Estimate(airdrop)
+		if err != nil {
+			logging.Warnf(p.logger, "Warning: Could not estimate the profit of claiming %s: %v", airdrop.Token.Symbol, err)
+			continue
+		}
+		total += estimate.NetProfit()
+		quoted++
+	}
+
+	if quoted > 0 && total < p.minNetProfit {
+		p.logger.Printf("Skipping claim of %d %s drop(s): estimated net profit of %.6f SOL below %g SOL",
+			len(airdrops), airdrops[0].Token.Symbol, total, p.minNetProfit)
+		return false
+	}
+	return true
+}
+
+// transactionFee returns the base fee of a transaction with one signature plus the priority fee for its
+// compute unit limit, rounded up to whole lamports
+func transactionFee(microLamportsPerUnit, computeUnits uint64) amount.Lamports {
+	priorityFee := (microLamportsPerUnit*computeUnits + 999_999) / 1_000_000
+	return amount.Lamports(sol.EstimatedTransactionFee + priorityFee)
+}
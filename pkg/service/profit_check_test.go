@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+
+	"boop-airdrop-redeemer/pkg/amount"
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/jupiter"
+	"boop-airdrop-redeemer/pkg/models"
+)
+
+// quoteOnlySwap answers swap quotes with a fixed SOL amount
+type quoteOnlySwap struct {
+	quote float64
+	err   error
+}
+
+func (s quoteOnlySwap) SwapTokenForSol(context.Context, string, string, uint64) (solana.Signature, error) {
+	return solana.Signature{}, errors.New("not implemented")
+}
+
+func (s quoteOnlySwap) SwapTokenForUsdc(context.Context, string, string, uint64) (solana.Signature, error) {
+	return solana.Signature{}, errors.New("not implemented")
+}
+
+func (s quoteOnlySwap) GetTokenUsdPrice(string) (float64, error) {
+	return 0, errors.New("not implemented")
+}
+
+func (s quoteOnlySwap) EstimateSwapOutputAmount(string, uint64) (float64, error) {
+	return s.quote, s.err
+}
+
+func (s quoteOnlySwap) SwapInstructionsForSol(context.Context, solana.PublicKey, string, uint64) (*jupiter.SwapInstructions, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestProfitCheckSkipsClaimsBelowMargin(t *testing.T) {
+	cfg := &config.Config{ProfitPrecheck: true, ClaimMinNetProfit: 0.001, ServiceFeeBps: 100, AutoSell: true}
+	logger := log.New(io.Discard, "", 0)
+	airdrop := models.AirdropNode{ID: "a1", AmountLpt: models.NewRawAmount(1_000_000), Token: models.Token{Symbol: "TEST"}}
+
+	check := NewProfitCheck(cfg, quoteOnlySwap{quote: 0.01}, nil, logger)
+	estimate, err := check.Estimate(airdrop)
+	assert.NoError(t, err)
+	assert.Equal(t, amount.Lamports(10_000_000), estimate.Output)
+	assert.Equal(t, amount.Lamports(5000+75_000), estimate.ClaimFee)
+	assert.Equal(t, amount.Lamports(5000+120_000), estimate.SwapFee)
+	assert.Equal(t, amount.Lamports(100_000), estimate.ServiceFee)
+	assert.InDelta(t, 0.009695, estimate.NetProfit(), 1e-12)
+	assert.True(t, check.Allows(airdrop))
+
+	// The quote covers the fees but not the margin
+	check = NewProfitCheck(cfg, quoteOnlySwap{quote: 0.001}, nil, logger)
+	assert.False(t, check.Allows(airdrop))
+
+	// Drops claimed together reach the margin with their net profits added up
+	assert.False(t, check.AllowsTogether([]models.AirdropNode{airdrop}))
+	assert.True(t, check.AllowsTogether([]models.AirdropNode{airdrop, airdrop}))
+
+	// Only the fees of what happens to the tokens are subtracted
+	usdcCfg := *cfg
+	usdcCfg.SellTarget = SellTargetUsdc
+	estimate, err = NewProfitCheck(&usdcCfg, quoteOnlySwap{quote: 0.01}, nil, logger).Estimate(airdrop)
+	assert.NoError(t, err)
+	assert.Equal(t, amount.Lamports(5000+120_000), estimate.SwapFee)
+	assert.Zero(t, estimate.ServiceFee)
+	heldCfg := *cfg
+	heldCfg.ReceivingWallet = "receiver"
+	estimate, err = NewProfitCheck(&heldCfg, quoteOnlySwap{quote: 0.01}, nil, logger).Estimate(airdrop)
+	assert.NoError(t, err)
+	assert.Zero(t, estimate.SwapFee)
+	assert.Zero(t, estimate.ServiceFee)
+
+	// Claims go ahead when no quote is available, and when the check is disabled
+	check = NewProfitCheck(cfg, quoteOnlySwap{err: errors.New("no route")}, nil, logger)
+	assert.True(t, check.Allows(airdrop))
+	cfg.ProfitPrecheck = false
+	assert.Nil(t, NewProfitCheck(cfg, quoteOnlySwap{}, nil, logger))
+	assert.True(t, (*ProfitCheck)(nil).Allows(airdrop))
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/models"
+	"boop-airdrop-redeemer/pkg/notifications"
+)
+
+// quoteRechecks is how many times a bad quote is fetched again before a sale right after claiming is given up
+const quoteRechecks = 3
+
+// ErrQuoteBelowExpected is returned instead of selling when the swap quote is far below the API's SOL value
+var ErrQuoteBelowExpected = errors.New("swap quote far below the expected SOL value")
+
+// QuoteGuard cross-checks swap quotes against the SOL value the Boop API reports for an airdrop. A quote
+// dramatically below it points at a stale price or a thin route, so the sale is delayed instead.
+type QuoteGuard struct {
+	swap           SwapProvider
+	maxShortfall   float64
+	recheckDelay   time.Duration
+	telegramClient *notifications.TelegramClient
+	logger         *log.Logger
+
+	mu      sync.Mutex
+	alerted map[string]bool
+}
+
+// NewQuoteGuard creates a quote guard from the configuration, nil when the check is disabled
+func NewQuoteGuard(cfg *config.Config, swap SwapProvider, telegramClient *notifications.TelegramClient, logger *log.Logger) *QuoteGuard {
+	if cfg.QuoteMaxShortfall <= 0 || swap == nil {
+		return nil
+	}
+
+	return &QuoteGuard{
+		swap:           swap,
+		maxShortfall:   cfg.QuoteMaxShortfall,
+		recheckDelay:   cfg.QuoteRecheckDelay,
+		telegramClient: telegramClient,
+		logger:         logger,
+		alerted:        make(map[string]bool),
+	}
+}
+
+// Check quotes the sale of amount tokens once and returns ErrQuoteBelowExpected when the quote falls short
+// of the API's SOL value by more than the allowed shortfall. Airdrops without a SOL value and failed quotes
+// pass, the swap itself handles routing errors.
+func (g *QuoteGuard) Check(airdrop models.AirdropNode, amount uint64) error {
+	if g == nil {
+		return nil
+	}
+
+	expectedSol, ok := airdrop.SolValue()
+	if !ok {
+		return nil
+	}
+
+	quotedSol, err := g.swap.EstimateSwapOutputAmount(airdrop.Token.Address, amount)
+	if err != nil {
+		logging.Warnf(g.logger, "Warning: Could not cross-check swap quote for %s: %v", airdrop.Token.Symbol, err)
+		return nil
+	}
+
+	if quotedSol >= expectedSol*(1-g.maxShortfall) {
+		g.mu.Lock()
+		delete(g.alerted, airdrop.ID)
+		g.mu.Unlock()
+		return nil
+	}
+
+	return fmt.Errorf("%w: quoted %.6f SOL for %s, API value %.6f SOL (%.0f%% lower)",
+		ErrQuoteBelowExpected, quotedSol, airdrop.Token.Symbol, expectedSol, (1-quotedSol/expectedSol)*100)
+}
+
+// Wait checks the quote and, while it is too low, fetches it again a few times before giving up.
+// It alerts once per airdrop when the sale is held back.
+func (g *QuoteGuard) Wait(ctx context.Context, airdrop models.AirdropNode, amount uint64) error {
+	if g == nil {
+		return nil
+	}
+
+	err := g.Check(airdrop, amount)
+	for attempt := 1; err != nil && attempt <= quoteRechecks; attempt++ {
+		g.logger.Printf("Delaying sale (%d/%d): %v", attempt, quoteRechecks, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(g.recheckDelay):
+		}
+		err = g.Check(airdrop, amount)
+	}
+
+	if err != nil {
+		g.Alert(airdrop, err)
+	}
+	return err
+}
+
+// Alert notifies about a sale held back by a bad quote, once per airdrop until its quote recovers
+func (g *QuoteGuard) Alert(airdrop models.AirdropNode, err error) {
+	if g == nil {
+		return
+	}
+
+	g.mu.Lock()
+	alerted := g.alerted[airdrop.ID]
+	g.alerted[airdrop.ID] = true
+	g.mu.Unlock()
+
+	g.logger.Printf("Not selling %s: %v", airdrop.Token.Symbol, err)
+	if alerted || g.telegramClient == nil || !g.telegramClient.Enabled {
+		return
+	}
+
+	expectedSol, _ := airdrop.SolValue()
+	g.telegramClient.SendBadQuoteNotification(airdrop.Token.Name, airdrop.Token.Symbol, airdrop.TokenAmount(), expectedSol, err.Error())
+}
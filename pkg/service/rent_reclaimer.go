@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"boop-airdrop-redeemer/pkg/amount"
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
+	sol "boop-airdrop-redeemer/pkg/solana"
+)
+
+const (
+	// accountsPerRead is how many token accounts are read with one getMultipleAccounts call
+	accountsPerRead = 100
+	// closesPerTransaction is how many token accounts are closed by one transaction
+	closesPerTransaction = 15
+)
+
+// RentReclaimer closes the wallet's empty token accounts of airdropped tokens, which keep about 0.002 SOL of
+// rent each after the tokens were sold. Only the associated token accounts of mints the bot has seen airdrops
+// of are closed, kept tokens are left alone.
+type RentReclaimer struct {
+	store         AirdropStore
+	interval      time.Duration
+	config        *config.Config
+	solClient     sol.RPCClient
+	accountClient sol.AccountClient
+	statsRecorder StatsStore
+	killSwitch    *KillSwitch
+	logger        *log.Logger
+
+	lastRun time.Time
+}
+
+// NewRentReclaimer creates the rent reclaimer, nil when it is disabled or the RPC client cannot read accounts
+func NewRentReclaimer(cfg *config.Config, store AirdropStore, solClient sol.RPCClient, statsRecorder StatsStore, killSwitch *KillSwitch, logger *log.Logger) *RentReclaimer {
+	if !cfg.ReclaimRent {
+		return nil
+	}
+
+	accountClient, ok := solClient.(sol.AccountClient)
+	if !ok {
+		logging.Warnf(logger, "WARNING: RPC client cannot read accounts, rent reclamation disabled")
+		return nil
+	}
+
+	logger.Printf("Rent reclamation enabled: empty token accounts are closed every %s", cfg.RentReclaimInterval)
+	return &RentReclaimer{
+		store:         store,
+		interval:      cfg.RentReclaimInterval,
+		config:        cfg,
+		solClient:     solClient,
+		accountClient: accountClient,
+		statsRecorder: statsRecorder,
+		killSwitch:    killSwitch,
+		logger:        logger,
+	}
+}
+
+// ReclaimIfDue closes empty token accounts once every reclaim interval
+func (r *RentReclaimer) ReclaimIfDue(ctx context.Context) {
+	if r == nil || time.Since(r.lastRun) < r.interval {
+		return
+	}
+	r.lastRun = time.Now()
+
+	if err := r.Reclaim(ctx); err != nil {
+		logging.Warnf(r.logger, "Warning: Rent reclamation failed: %v", err)
+	}
+}
+
+// Reclaim finds the empty token accounts of airdropped mints and closes them, sending their rent to the wallet
+func (r *RentReclaimer) Reclaim(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+
+	if err := r.killSwitch.Guard(); err != nil {
+		return err
+	}
+
+	owner, err := solana.PrivateKeyFromBase58(r.config.WalletPrivateKey)
+	if err != nil {
+		return fmt.Errorf("invalid private key: %w", err)
+	}
+
+	empty, err := r.findEmptyAccounts(ctx, owner.PublicKey(), r.candidateMints())
+	if err != nil {
+		return err
+	}
+	if len(empty) == 0 {
+		return nil
+	}
+
+	for start := 0; start < len(empty); start += closesPerTransaction {
+		if err := r.close(ctx, owner, empty[start:min(start+closesPerTransaction, len(empty))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// candidateMints returns the distinct mints of the stored airdrops, except kept tokens
+func (r *RentReclaimer) candidateMints() []solana.PublicKey {
+	seen := make(map[string]bool)
+	var mints []solana.PublicKey
+	for _, airdrop := range r.store.GetAllAirdrops() {
+		address := airdrop.Token.Address
+		if seen[address] || r.config.KeepsToken(address) {
+			continue
+		}
+		seen[address] = true
+
+		mint, err := solana.PublicKeyFromBase58(address)
+		if err != nil || mint.Equals(solana.SolMint) {
+			continue
+		}
+		mints = append(mints, mint)
+	}
+	return mints
+}
+
+// findEmptyAccounts returns the wallet's associated token accounts of the mints that exist and hold no tokens
+func (r *RentReclaimer) findEmptyAccounts(ctx context.Context, owner solana.PublicKey, mints []solana.PublicKey) ([]solana.PublicKey, error) {
+	addresses := make([]solana.PublicKey, 0, len(mints))
+	for _, mint := range mints {
+		ata, _, err := solana.FindAssociatedTokenAddress(owner, mint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find associated token address: %w", err)
+		}
+		addresses = append(addresses, ata)
+	}
+
+	var empty []solana.PublicKey
+	for start := 0; start < len(addresses); start += accountsPerRead {
+		batch := addresses[start:min(start+accountsPerRead, len(addresses))]
+		result, err := r.accountClient.GetMultipleAccountsWithOpts(ctx, batch, &rpc.GetMultipleAccountsOpts{
+			Encoding:   solana.EncodingBase64,
+			Commitment: rpc.CommitmentConfirmed,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token accounts: %w", err)
+		}
+		if result == nil || len(result.Value) != len(batch) {
+			return nil, fmt.Errorf("expected %d accounts, got an incomplete response", len(batch))
+		}
+
+		for i, account := range result.Value {
+			if isEmptyTokenAccount(account) {
+				empty = append(empty, batch[i])
+			}
+		}
+	}
+	return empty, nil
+}
+
+// isEmptyTokenAccount reports whether the account is an initialized SPL token account holding no tokens
+func isEmptyTokenAccount(account *rpc.Account) bool {
+	if account == nil || account.Data == nil || !account.Owner.Equals(solana.TokenProgramID) {
+		return false
+	}
+
+	var tokenAccount token.Account
+	if err := bin.NewBinDecoder(account.Data.GetBinary()).Decode(&tokenAccount); err != nil {
+		return false
+	}
+	return tokenAccount.Amount == 0 && tokenAccount.State == token.Initialized
+}
+
+// close closes the token accounts in one transaction and records the rent it recovered
+func (r *RentReclaimer) close(ctx context.Context, owner solana.PrivateKey, accounts []solana.PublicKey) error {
+	instructions := make([]solana.Instruction, 0, len(accounts))
+	for _, account := range accounts {
+		instructions = append(instructions, token.NewCloseAccountInstruction(account, owner.PublicKey(), owner.PublicKey(), nil).Build())
+	}
+
+	build := func(ctx context.Context) (*solana.Transaction, uint64, error) {
+		block, err := sol.BlockhashCache.GetBlockhash(r.solClient)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get blockhash: %w", err)
+		}
+
+		tx, err := solana.NewTransaction(instructions, block.Blockhash, solana.TransactionPayer(owner.PublicKey()))
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to create close transaction: %w", err)
+		}
+
+		if _, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+			if key.Equals(owner.PublicKey()) {
+				return &owner
+			}
+			return nil
+		}); err != nil {
+			return nil, 0, fmt.Errorf("failed to sign close transaction: %w", err)
+		}
+		return tx, block.LastValidBlockHeight, nil
+	}
+
+	sender := sol.NewSender(r.solClient, rpc.TransactionOpts{}, r.config.RebroadcastInterval, sol.LogSendStatus(r.logger, "Close"))
+	landing, err := sender.SendUntilLanded(ctx, transferAttempts, build)
+	if err != nil {
+		return fmt.Errorf("close transaction %s has unknown outcome: %w", landing.Signature, err)
+	}
+	if landing.Status != sol.LandingConfirmed {
+		return fmt.Errorf("close transaction %s %s", landing.Signature, landing.Status)
+	}
+	txHash := landing.Signature.String()
+
+	result, err := sol.AwaitTransactionResult(ctx, r.solClient, txHash, false)
+	if err != nil {
+		r.logger.Printf("Closed %d empty token account(s): %s (rent not read: %v)", len(accounts), txHash, err)
+		return nil
+	}
+	r.logger.Printf("Closed %d empty token account(s), reclaimed %.6f SOL of rent: %s",
+		len(accounts), float64(-result.Rent)/amount.LamportsPerSol, txHash)
+
+	if r.statsRecorder != nil {
+		if err := r.statsRecorder.RecordRentReclaimStats(len(accounts), result.Fee, result.Rent, txHash); err != nil {
+			logging.Warnf(r.logger, "Warning: Failed to record rent reclaim stats: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"boop-airdrop-redeemer/pkg/amount"
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/models"
+	"boop-airdrop-redeemer/pkg/notifications"
+	"boop-airdrop-redeemer/pkg/operation"
+	sol "boop-airdrop-redeemer/pkg/solana"
+)
+
+// minServiceFeeLamports skips fees too small to be worth a transfer transaction
+const minServiceFeeLamports = 100_000
+
+// transferAttempts is how often a transfer that expired without landing is rebuilt and sent again
+const transferAttempts = 3
+
+// ServiceFeeCharge is a completed service fee transfer
+type ServiceFeeCharge struct {
+	Lamports uint64
+	TxHash   string
+}
+
+// Sol returns the transferred fee in SOL
+func (c ServiceFeeCharge) Sol() float64 {
+	return amount.Lamports(c.Lamports).Sol()
+}
+
+// ServiceFee transfers the operator's share of realized SOL after a sale
+type ServiceFee struct {
+	bps            int
+	wallet         solana.PublicKey
+	config         *config.Config
+	solClient      sol.RPCClient
+	statsRecorder  StatsStore
+	telegramClient *notifications.TelegramClient
+	killSwitch     *KillSwitch
+	logger         *log.Logger
+}
+
+// NewServiceFee creates a service fee charger, returning nil when no fee is configured
+func NewServiceFee(cfg *config.Config, solClient sol.RPCClient, statsRecorder StatsStore, telegramClient *notifications.TelegramClient, killSwitch *KillSwitch, logger *log.Logger) *ServiceFee {
+	if cfg.ServiceFeeBps <= 0 {
+		return nil
+	}
+
+	if cfg.ServiceFeeBps > 10_000 {
+		logging.Warnf(logger, "WARNING: SERVICE_FEE_BPS %d exceeds 10000, service fee disabled", cfg.ServiceFeeBps)
+		return nil
+	}
+
+	wallet, err := solana.PublicKeyFromBase58(cfg.ServiceFeeWallet)
+	if err != nil {
+		logging.Warnf(logger, "WARNING: Invalid SERVICE_FEE_WALLET, service fee disabled: %v", err)
+		return nil
+	}
+
+	logger.Printf("Service fee enabled: %.2f%% of realized SOL to %s", float64(cfg.ServiceFeeBps)/100, wallet)
+
+	return &ServiceFee{
+		bps:            cfg.ServiceFeeBps,
+		wallet:         wallet,
+		config:         cfg,
+		solClient:      solClient,
+		statsRecorder:  statsRecorder,
+		telegramClient: telegramClient,
+		killSwitch:     killSwitch,
+		logger:         logger,
+	}
+}
+
+// Charge transfers the configured share of a sale's realized SOL to the operator wallet.
+// A nil ServiceFee or a fee below the minimum charges nothing.
+func (f *ServiceFee) Charge(ctx context.Context, airdrop models.AirdropNode, earnings uint64) (ServiceFeeCharge, error) {
+	if f == nil {
+		return ServiceFeeCharge{}, nil
+	}
+	logger := operation.Logger(ctx, f.logger)
+
+	lamports := earnings * uint64(f.bps) / 10_000
+	if lamports < minServiceFeeLamports {
+		logger.Printf("Service fee of %d lamports for %s is below the minimum, skipped", lamports, airdrop.Token.Symbol)
+		return ServiceFeeCharge{}, nil
+	}
+
+	if err := f.killSwitch.Guard(); err != nil {
+		return ServiceFeeCharge{}, err
+	}
+
+	payer, err := solana.PrivateKeyFromBase58(f.config.WalletPrivateKey)
+	if err != nil {
+		return ServiceFeeCharge{}, fmt.Errorf("invalid private key: %w", err)
+	}
+
+	build := func(ctx context.Context) (*solana.Transaction, uint64, error) {
+		block, err := sol.BlockhashCache.GetBlockhash(f.solClient)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get blockhash: %w", err)
+		}
+
+		tx, err := solana.NewTransaction(
+			[]solana.Instruction{
+				system.NewTransferInstruction(lamports, payer.PublicKey(), f.wallet).Build(),
+			},
+			block.Blockhash,
+			solana.TransactionPayer(payer.PublicKey()),
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to create service fee transaction: %w", err)
+		}
+
+		if _, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+			if key.Equals(payer.PublicKey()) {
+				return &payer
+			}
+			return nil
+		}); err != nil {
+			return nil, 0, fmt.Errorf("failed to sign service fee transaction: %w", err)
+		}
+		return tx, block.LastValidBlockHeight, nil
+	}
+
+	sender := sol.NewSender(f.solClient, rpc.TransactionOpts{}, f.config.RebroadcastInterval, sol.LogSendStatus(logger, "Service fee"))
+	landing, err := sender.SendUntilLanded(ctx, transferAttempts, build)
+	if err != nil {
+		return ServiceFeeCharge{}, fmt.Errorf("service fee transaction %s has unknown outcome: %w", landing.Signature, err)
+	}
+	if landing.Status != sol.LandingConfirmed {
+		return ServiceFeeCharge{}, fmt.Errorf("service fee transaction %s %s", landing.Signature, landing.Status)
+	}
+
+	charge := ServiceFeeCharge{Lamports: lamports, TxHash: landing.Signature.String()}
+	logger.Printf("Transferred service fee of %.6f SOL (%d bps) to %s: %s", charge.Sol(), f.bps, f.wallet, charge.TxHash)
+
+	if f.statsRecorder != nil {
+		fees, _, err := sol.AwaitTransactionFeesAndEarnings(ctx, f.solClient, charge.TxHash, false)
+		if err != nil {
+			logging.Warnf(logger, "Warning: Failed to get service fee transaction fees: %v", err)
+		}
+		if err := f.statsRecorder.RecordServiceFeeStats(airdrop.Token.Symbol, lamports, fees, charge.TxHash, operation.ID(ctx)); err != nil {
+			logging.Warnf(logger, "Warning: Failed to record service fee stats: %v", err)
+		}
+	}
+
+	return charge, nil
+}
+
+// Disclose announces the configured service fee, so wallet owners know about it before any sale
+func (f *ServiceFee) Disclose() {
+	if f == nil || f.telegramClient == nil {
+		return
+	}
+	f.telegramClient.SendServiceFeeDisclosure(f.bps, f.wallet.String())
+}
+
+// Notify discloses a charged service fee in Telegram
+func (f *ServiceFee) Notify(airdrop models.AirdropNode, charge ServiceFeeCharge, solPrice float64) {
+	if f == nil || charge.Lamports == 0 || f.telegramClient == nil {
+		return
+	}
+	f.telegramClient.SendServiceFeeNotification(airdrop.Token.Symbol, charge.Sol(), f.bps, f.wallet.String(), solPrice, charge.TxHash)
+}
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/models"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteMigrations are applied in order, each in its own transaction. Append new migrations, never edit applied ones.
+var sqliteMigrations = []string{
+	// 1: airdrops as last seen from the API, with the locally recorded claim kept separately
+	`CREATE TABLE airdrops (
+		id         TEXT PRIMARY KEY,
+		data       TEXT NOT NULL,
+		first_seen TEXT NOT NULL,
+		updated_at TEXT NOT NULL,
+		claimed_at TEXT,
+		claim_tx   TEXT
+	)`,
+}
+
+// sqliteAirdropStore is an AirdropStore persisted in a SQLite database, so seen and claimed
+// airdrops survive restarts
+type sqliteAirdropStore struct {
+	db     *sql.DB
+	logger *log.Logger
+	mu     sync.Mutex
+
+	// saved mirrors the stored rows, loaded on the first save, so scans only write airdrops that changed
+	saved map[string]savedAirdrop
+}
+
+// savedAirdrop is the digest of a stored airdrop's data and its local claim time
+type savedAirdrop struct {
+	digest    [sha256.Size]byte
+	claimedAt sql.NullString
+}
+
+// pendingAirdropRow is an encoded airdrop waiting to be written
+type pendingAirdropRow struct {
+	index  int // Position in the saved airdrops
+	data   []byte
+	digest [sha256.Size]byte
+}
+
+// NewSQLiteAirdropStore opens (or creates) the SQLite database at path and applies pending migrations
+func NewSQLiteAirdropStore(path string, logger *log.Logger) (AirdropStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create airdrop store directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open airdrop store: %w", err)
+	}
+	// A single connection serializes writers and keeps the WAL setting on every statement
+	db.SetMaxOpenConns(1)
+
+	if err := migrateSQLiteStore(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &sqliteAirdropStore{db: db, logger: logger}, nil
+}
+
+// migrateSQLiteStore applies the migrations the database has not seen yet
+func migrateSQLiteStore(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, applied_at TEXT NOT NULL)`); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	var version int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("failed to read airdrop store version: %w", err)
+	}
+	if version > len(sqliteMigrations) {
+		return fmt.Errorf("airdrop store version %d is newer than supported version %d", version, len(sqliteMigrations))
+	}
+
+	for i := version; i < len(sqliteMigrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to start migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(sqliteMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, i+1, time.Now().Format(time.RFC3339)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// SaveAirdrop inserts or updates an airdrop, keeping its first seen time and local claim record
+func (s *sqliteAirdropStore) SaveAirdrop(airdrop models.AirdropNode) {
+	s.SaveAirdrops([]models.AirdropNode{airdrop})
+}
+
+// SaveAirdrops inserts new and updates changed airdrops in one transaction, keeping their first seen time
+// and local claim record. Unchanged airdrops are not written. Locally recorded claims are applied to the
+// airdrops passed in. When the stored airdrops cannot be read, every airdrop is written and reported as
+// updated, since new ones cannot be told apart.
+func (s *sqliteAirdropStore) SaveAirdrops(airdrops []models.AirdropNode) []AirdropChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changes := make([]AirdropChange, len(airdrops))
+	if err := s.loadSaved(); err != nil {
+		logging.Warnf(s.logger, "Warning: Failed to read stored airdrops, writing all %d: %v", len(airdrops), err)
+		s.writeAll(airdrops, changes)
+		return changes
+	}
+
+	var pending []pendingAirdropRow
+	for i := range airdrops {
+		airdrop := &airdrops[i]
+		data, err := json.Marshal(airdrop)
+		if err != nil {
+			logging.Warnf(s.logger, "Warning: Failed to encode airdrop %s for the store: %v", airdrop.ID, err)
+			continue
+		}
+
+		stored, exists := s.saved[airdrop.ID]
+		if airdrop.ClaimedAt == nil && stored.claimedAt.Valid {
+			airdrop.ClaimedAt = stored.claimedAt.String
+		}
+
+		digest := sha256.Sum256(data)
+		if exists && digest == stored.digest {
+			continue
+		}
+
+		changes[i] = AirdropUpdated
+		if !exists {
+			changes[i] = AirdropNew
+		}
+		pending = append(pending, pendingAirdropRow{index: i, data: data, digest: digest})
+	}
+	if len(pending) == 0 {
+		return changes
+	}
+
+	// Failed writes keep the old digests, so the airdrops are written again on the next save
+	if err := s.writeRows(airdrops, pending); err != nil {
+		logging.Warnf(s.logger, "Warning: Failed to save %d airdrop(s): %v", len(pending), err)
+		return changes
+	}
+
+	for _, row := range pending {
+		stored := s.saved[airdrops[row.index].ID]
+		stored.digest = row.digest
+		s.saved[airdrops[row.index].ID] = stored
+	}
+	return changes
+}
+
+// writeAll writes every airdrop without comparing it with the stored one, the stored digests are read again on
+// the next save
+func (s *sqliteAirdropStore) writeAll(airdrops []models.AirdropNode, changes []AirdropChange) {
+	var pending []pendingAirdropRow
+	for i := range airdrops {
+		data, err := json.Marshal(&airdrops[i])
+		if err != nil {
+			logging.Warnf(s.logger, "Warning: Failed to encode airdrop %s for the store: %v", airdrops[i].ID, err)
+			continue
+		}
+		pending = append(pending, pendingAirdropRow{index: i, data: data})
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	if err := s.writeRows(airdrops, pending); err != nil {
+		logging.Warnf(s.logger, "Warning: Failed to save %d airdrop(s): %v", len(pending), err)
+		return
+	}
+	for _, row := range pending {
+		changes[row.index] = AirdropUpdated
+	}
+}
+
+// writeRows upserts the pending airdrop rows with a prepared statement in a single transaction
+func (s *sqliteAirdropStore) writeRows(airdrops []models.AirdropNode, pending []pendingAirdropRow) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO airdrops (id, data, first_seen, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now().Format(time.RFC3339)
+	for _, row := range pending {
+		if _, err := stmt.Exec(airdrops[row.index].ID, string(row.data), now, now); err != nil {
+			return fmt.Errorf("airdrop %s: %w", airdrops[row.index].ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// loadSaved reads the digest and claim time of every stored airdrop once
+func (s *sqliteAirdropStore) loadSaved() error {
+	if s.saved != nil {
+		return nil
+	}
+
+	rows, err := s.db.Query(`SELECT id, data, claimed_at FROM airdrops`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	saved := make(map[string]savedAirdrop)
+	for rows.Next() {
+		var id, data string
+		var claimedAt sql.NullString
+		if err := rows.Scan(&id, &data, &claimedAt); err != nil {
+			return err
+		}
+		saved[id] = savedAirdrop{digest: sha256.Sum256([]byte(data)), claimedAt: claimedAt}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	s.saved = saved
+	return nil
+}
+
+// HasAirdropWithID checks if an airdrop with given ID exists
+func (s *sqliteAirdropStore) HasAirdropWithID(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var exists int
+	err := s.db.QueryRow(`SELECT 1 FROM airdrops WHERE id = ?`, id).Scan(&exists)
+	if err != nil && err != sql.ErrNoRows {
+		logging.Warnf(s.logger, "Warning: Failed to look up airdrop %s: %v", id, err)
+	}
+	return err == nil
+}
+
+// GetAllAirdrops returns all stored airdrops
+func (s *sqliteAirdropStore) GetAllAirdrops() []models.AirdropNode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query(`SELECT data, claimed_at FROM airdrops ORDER BY first_seen`)
+	if err != nil {
+		logging.Warnf(s.logger, "Warning: Failed to list airdrops: %v", err)
+		return nil
+	}
+	defer rows.Close()
+
+	var airdrops []models.AirdropNode
+	for rows.Next() {
+		airdrop, err := scanStoredAirdrop(rows)
+		if err != nil {
+			logging.Warnf(s.logger, "Warning: Skipping unreadable stored airdrop: %v", err)
+			continue
+		}
+		airdrops = append(airdrops, airdrop)
+	}
+	return airdrops
+}
+
+// ClaimAirdrop retrieves an airdrop by ID to claim it, reporting it as claimed when a claim was recorded locally
+func (s *sqliteAirdropStore) ClaimAirdrop(airdropID string) (models.AirdropNode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	row := s.db.QueryRow(`SELECT data, claimed_at FROM airdrops WHERE id = ?`, airdropID)
+	airdrop, err := scanStoredAirdrop(row)
+	if err == sql.ErrNoRows {
+		return models.AirdropNode{}, fmt.Errorf("airdrop with ID %s not found", airdropID)
+	}
+	if err != nil {
+		return models.AirdropNode{}, fmt.Errorf("failed to load airdrop %s: %w", airdropID, err)
+	}
+	return airdrop, nil
+}
+
+// MarkClaimed records a confirmed claim so it is not attempted again, also after a restart
+func (s *sqliteAirdropStore) MarkClaimed(airdropID, txHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	claimedAt := time.Now().Format(time.RFC3339)
+	_, err := s.db.Exec(`UPDATE airdrops SET claimed_at = ?, claim_tx = ? WHERE id = ?`, claimedAt, txHash, airdropID)
+	if err != nil {
+		return fmt.Errorf("failed to mark airdrop %s as claimed: %w", airdropID, err)
+	}
+
+	if stored, ok := s.saved[airdropID]; ok {
+		stored.claimedAt = sql.NullString{String: claimedAt, Valid: true}
+		s.saved[airdropID] = stored
+	}
+	return nil
+}
+
+// scanStoredAirdrop decodes an airdrop row, applying the local claim time when the API data has none
+func scanStoredAirdrop(row interface{ Scan(...any) error }) (models.AirdropNode, error) {
+	var data string
+	var claimedAt sql.NullString
+	if err := row.Scan(&data, &claimedAt); err != nil {
+		return models.AirdropNode{}, err
+	}
+
+	var airdrop models.AirdropNode
+	if err := json.Unmarshal([]byte(data), &airdrop); err != nil {
+		return models.AirdropNode{}, fmt.Errorf("failed to decode stored airdrop: %w", err)
+	}
+	if airdrop.ClaimedAt == nil && claimedAt.Valid {
+		airdrop.ClaimedAt = claimedAt.String
+	}
+	return airdrop, nil
+}
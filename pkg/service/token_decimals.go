@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"github.com/gagliardetto/solana-go"
+
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/models"
+	sol "boop-airdrop-redeemer/pkg/solana"
+)
+
+// resolveDecimals sets the mint decimals of the airdrops, reading all unknown mints in one call. Airdrops whose
+// mint cannot be read keep models.DefaultTokenDecimals.
+func resolveDecimals(ctx context.Context, resolver *sol.MintDecimals, airdrops []models.AirdropNode, logger *log.Logger) {
+	if resolver == nil || len(airdrops) == 0 {
+		return
+	}
+
+	mints := make([]solana.PublicKey, len(airdrops))
+	for i, airdrop := range airdrops {
+		mints[i], _ = solana.PublicKeyFromBase58(airdrop.Token.Address)
+	}
+
+	if err := resolver.Resolve(ctx, mints...); err != nil {
+		logging.Warnf(logger, "Warning: Failed to read token decimals, assuming %d: %v", models.DefaultTokenDecimals, err)
+		return
+	}
+
+	for i := range airdrops {
+		if mints[i].IsZero() {
+			continue
+		}
+		if decimals, err := resolver.Decimals(ctx, mints[i]); err == nil {
+			airdrops[i].Decimals = &decimals
+		}
+	}
+}
+
+// ResolveDecimals sets the mint decimals of the airdrops so token amounts are shown in whole tokens
+func (c *AirdropClaimer) ResolveDecimals(ctx context.Context, airdrops []models.AirdropNode) {
+	resolveDecimals(ctx, c.mintDecimals, airdrops, c.logger)
+}
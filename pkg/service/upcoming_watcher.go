@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/api"
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/models"
+	sol "boop-airdrop-redeemer/pkg/solana"
+)
+
+// UpcomingAirdropWatcher finds airdrops the Boop API lists for the wallet before they show up as pending,
+// so the user can act before the claim window opens
+type UpcomingAirdropWatcher struct {
+	client   *api.BoopClient
+	store    AirdropStore
+	state    sol.StateStore // Keeps the reported airdrops across restarts
+	interval time.Duration
+	logger   *log.Logger
+
+	seen      map[string]bool // Airdrops reported while they are still upcoming
+	inexact   bool            // The schema does not tell upcoming from expired airdrops, warned once
+	lastCheck time.Time
+}
+
+// upcomingState is the saved state of the watcher
+type upcomingState struct {
+	Seen []string `json:"seen"`
+}
+
+// NewUpcomingAirdropWatcher creates a watcher sharing the scanner's API client and store, nil when disabled.
+// The airdrops already reported are kept with the statistics, in their database with the bolt backend.
+func NewUpcomingAirdropWatcher(cfg *config.Config, scanner *AirdropScanner, stats StatsStore, logger *log.Logger) *UpcomingAirdropWatcher {
+	if !cfg.WatchUpcomingAirdrops {
+		return nil
+	}
+
+	state := sol.NewFileState(filepath.Join(cfg.StatsDataDir, "upcoming_airdrops.json"))
+	if stats != nil {
+		state = stats.State("upcoming_airdrops")
+	}
+
+	watcher := &UpcomingAirdropWatcher{
+		client:   scanner.client,
+		store:    scanner.store,
+		state:    state,
+		interval: cfg.UpcomingCheckInterval,
+		logger:   logger,
+		seen:     make(map[string]bool),
+	}
+	var saved upcomingState
+	if _, err := state.Load(&saved); err != nil {
+		logging.Warnf(logger, "WARNING: Failed to read reported upcoming airdrops, they may be reported again: %v", err)
+	}
+	for _, id := range saved.Seen {
+		watcher.seen[id] = true
+	}
+
+	logger.Printf("Watching for upcoming airdrops every %s", cfg.UpcomingCheckInterval)
+	return watcher
+}
+
+// CheckIfDue returns the airdrops announced since the last check, once every check interval
+func (w *UpcomingAirdropWatcher) CheckIfDue(ctx context.Context) []models.AirdropNode {
+	if w == nil || time.Since(w.lastCheck) < w.interval {
+		return nil
+	}
+	w.lastCheck = time.Now()
+
+	upcoming, err := w.Check(ctx)
+	if err != nil {
+		logging.Warnf(w.logger, "Warning: Failed to check for upcoming airdrops: %v", err)
+	}
+	return upcoming
+}
+
+// Check lists the wallet's airdrops that are not claimable yet and returns those not reported before. Airdrops
+// no longer upcoming are forgotten, so the saved state only holds the current ones.
+func (w *UpcomingAirdropWatcher) Check(ctx context.Context) ([]models.AirdropNode, error) {
+	airdrops, exact, err := w.client.GetUpcomingAirdrops(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list upcoming airdrops: %w", err)
+	}
+	if !exact && !w.inexact {
+		w.inexact = true
+		logging.Warnf(w.logger, "Warning: The Boop API does not list claim statuses, expired airdrops may be reported as upcoming")
+	}
+
+	var upcoming []models.AirdropNode
+	current := make(map[string]bool, len(airdrops))
+	for _, airdrop := range airdrops {
+		// Pending airdrops are saved by the scanner and go through the normal claim flow
+		if w.store.HasAirdropWithID(airdrop.ID) {
+			continue
+		}
+		current[airdrop.ID] = true
+		if !w.seen[airdrop.ID] {
+			upcoming = append(upcoming, airdrop)
+		}
+	}
+
+	changed := len(upcoming) > 0 || len(current) != len(w.seen)
+	w.seen = current
+	if changed {
+		w.save()
+	}
+	return upcoming, nil
+}
+
+// save writes the reported airdrops
+func (w *UpcomingAirdropWatcher) save() {
+	state := upcomingState{Seen: make([]string, 0, len(w.seen))}
+	for id := range w.seen {
+		state.Seen = append(state.Seen, id)
+	}
+	sort.Strings(state.Seen)
+	if err := w.state.Save(state); err != nil {
+		logging.Warnf(w.logger, "Warning: Failed to save reported upcoming airdrops: %v", err)
+	}
+}
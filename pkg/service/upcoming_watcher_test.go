@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/models"
+)
+
+func TestUpcomingAirdropWatcherReportsOnceAcrossRestarts(t *testing.T) {
+	var body atomic.Pointer[[]byte]
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request models.GraphQLRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		if request.OperationName == "TypeIntrospection" {
+			io.WriteString(w, `{"data": {"__type": {"enumValues": [{"name": "PENDING"}, {"name": "CLAIMED"}, {"name": "EXPIRED"}, {"name": "UPCOMING"}]}}}`)
+			return
+		}
+		// Only the status that is neither claimable, claimed nor expired is fetched
+		assert.Equal(t, "UPCOMING", request.Variables["status"])
+		w.Write(*body.Load())
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Endpoints:             config.Endpoints{GraphQLURL: server.URL},
+		AuthToken:             "test",
+		StatsDataDir:          t.TempDir(),
+		WatchUpcomingAirdrops: true,
+	}
+	logger := log.New(io.Discard, "", 0)
+	store := NewInMemoryAirdropStore()
+	airdrops := testAirdrops(3, 1)
+	store.SaveAirdrops(airdrops[:1])
+	listed := airdropResponseBody(t, airdrops[:2])
+	body.Store(&listed)
+
+	watcher := NewUpcomingAirdropWatcher(cfg, NewAirdropScanner(store, cfg, logger), nil, logger)
+	upcoming, err := watcher.Check(context.Background())
+	require.NoError(t, err)
+	if assert.Len(t, upcoming, 1, "the pending airdrop goes through the claim flow") {
+		assert.Equal(t, airdrops[1].ID, upcoming[0].ID)
+	}
+
+	// A restart does not report the same airdrop again, a new one is reported
+	watcher = NewUpcomingAirdropWatcher(cfg, NewAirdropScanner(store, cfg, logger), nil, logger)
+	upcoming, err = watcher.Check(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, upcoming)
+
+	listed = airdropResponseBody(t, airdrops)
+	body.Store(&listed)
+	upcoming, err = watcher.Check(context.Background())
+	require.NoError(t, err)
+	if assert.Len(t, upcoming, 1) {
+		assert.Equal(t, airdrops[2].ID, upcoming[0].ID)
+	}
+}
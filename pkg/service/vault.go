@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"boop-airdrop-redeemer/pkg/amount"
+	"boop-airdrop-redeemer/pkg/config"
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/notifications"
+	sol "boop-airdrop-redeemer/pkg/solana"
+	"boop-airdrop-redeemer/pkg/solana/associated_token_account_extended"
+)
+
+// Vault moves balances of kept tokens from the hot wallet to a separate vault wallet
+type Vault struct {
+	wallet         solana.PublicKey
+	mints          []solana.PublicKey
+	minAmount      float64
+	interval       time.Duration
+	config         *config.Config
+	solClient      sol.RPCClient
+	accountClient  sol.AccountClient
+	statsRecorder  StatsStore
+	telegramClient *notifications.TelegramClient
+	killSwitch     *KillSwitch
+	logger         *log.Logger
+
+	lastSweep time.Time
+}
+
+// NewVault creates a vault sweeper, returning nil when no vault or no kept tokens are configured
+func NewVault(cfg *config.Config, solClient sol.RPCClient, statsRecorder StatsStore, telegramClient *notifications.TelegramClient, killSwitch *KillSwitch, logger *log.Logger) *Vault {
+	if cfg.VaultWallet == "" || len(cfg.KeepTokens) == 0 {
+		return nil
+	}
+
+	wallet, err := solana.PublicKeyFromBase58(cfg.VaultWallet)
+	if err != nil {
+		logging.Warnf(logger, "WARNING: Invalid VAULT_WALLET, vault transfers disabled: %v", err)
+		return nil
+	}
+
+	accountClient, ok := solClient.(sol.AccountClient)
+	if !ok {
+		logging.Warnf(logger, "WARNING: RPC client cannot read accounts, vault transfers disabled")
+		return nil
+	}
+
+	var mints []solana.PublicKey
+	for _, kept := range cfg.KeepTokens {
+		mint, err := solana.PublicKeyFromBase58(kept)
+		if err != nil {
+			logging.Warnf(logger, "WARNING: Ignoring invalid KEEP_TOKENS mint %s: %v", kept, err)
+			continue
+		}
+		mints = append(mints, mint)
+	}
+
+	logger.Printf("Vault transfers enabled: %d kept token(s) to %s every %s", len(mints), wallet, cfg.VaultTransferInterval)
+
+	return &Vault{
+		wallet:         wallet,
+		mints:          mints,
+		minAmount:      cfg.VaultMinAmount,
+		interval:       cfg.VaultTransferInterval,
+		config:         cfg,
+		solClient:      solClient,
+		accountClient:  accountClient,
+		statsRecorder:  statsRecorder,
+		telegramClient: telegramClient,
+		killSwitch:     killSwitch,
+		logger:         logger,
+	}
+}
+
+// SweepIfDue sweeps kept token balances once every transfer interval
+func (v *Vault) SweepIfDue(ctx context.Context) {
+	if v == nil || time.Since(v.lastSweep) < v.interval {
+		return
+	}
+	v.lastSweep = time.Now()
+
+	if err := v.Sweep(ctx); err != nil {
+		logging.Warnf(v.logger, "Warning: Vault transfer failed: %v", err)
+	}
+}
+
+// Sweep moves the whole balance of every kept token above the minimum amount to the vault,
+// one transaction per token
+func (v *Vault) Sweep(ctx context.Context) error {
+	if v == nil || len(v.mints) == 0 {
+		return nil
+	}
+
+	if err := v.killSwitch.Guard(); err != nil {
+		return err
+	}
+
+	owner, err := solana.PrivateKeyFromBase58(v.config.WalletPrivateKey)
+	if err != nil {
+		return fmt.Errorf("invalid private key: %w", err)
+	}
+
+	// Read every token account and its mint in one call
+	keys := make([]solana.PublicKey, 0, 2*len(v.mints))
+	for _, mint := range v.mints {
+		ata, _, err := solana.FindAssociatedTokenAddress(owner.PublicKey(), mint)
+		if err != nil {
+			return fmt.Errorf("failed to find associated token address: %w", err)
+		}
+		keys = append(keys, ata, mint)
+	}
+
+	result, err := v.accountClient.GetMultipleAccountsWithOpts(ctx, keys, &rpc.GetMultipleAccountsOpts{
+		Encoding:   solana.EncodingBase64,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get kept token accounts: %w", err)
+	}
+	if result == nil || len(result.Value) != len(keys) {
+		return fmt.Errorf("expected %d accounts, got an incomplete response", len(keys))
+	}
+
+	for i, mint := range v.mints {
+		tokenAccount, mintAccount := result.Value[2*i], result.Value[2*i+1]
+		if tokenAccount == nil || mintAccount == nil {
+			continue
+		}
+
+		var balance token.Account
+		if err := bin.NewBinDecoder(tokenAccount.Data.GetBinary()).Decode(&balance); err != nil {
+			logging.Warnf(v.logger, "Warning: Failed to decode token account %s: %v", keys[2*i], err)
+			continue
+		}
+		var mintInfo token.Mint
+		if err := bin.NewBinDecoder(mintAccount.Data.GetBinary()).Decode(&mintInfo); err != nil {
+			logging.Warnf(v.logger, "Warning: Failed to decode mint %s: %v", mint, err)
+			continue
+		}
+
+		tokens := amount.TokenAmount{Raw: balance.Amount, Decimals: mintInfo.Decimals}
+		if balance.Amount == 0 || tokens.Float() < v.minAmount {
+			continue
+		}
+
+		if err := v.transfer(ctx, owner, mint, keys[2*i], tokens); err != nil {
+			logging.Warnf(v.logger, "Warning: Failed to move %s to the vault: %v", config.ShortenAddress(mint.String()), err)
+		}
+	}
+
+	return nil
+}
+
+// transfer moves the tokens to the vault's associated token account
+func (v *Vault) transfer(ctx context.Context, owner solana.PrivateKey, mint, source solana.PublicKey, tokens amount.TokenAmount) error {
+	destination, _, err := solana.FindAssociatedTokenAddress(v.wallet, mint)
+	if err != nil {
+		return fmt.Errorf("failed to find vault token address: %w", err)
+	}
+
+	build := func(ctx context.Context) (*solana.Transaction, uint64, error) {
+		block, err := sol.BlockhashCache.GetBlockhash(v.solClient)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get blockhash: %w", err)
+		}
+
+		tx, err := solana.NewTransaction(
+			[]solana.Instruction{
+				associated_token_account_extended.NewCreateIdempotentInstruction(owner.PublicKey(), v.wallet, mint).Build(),
+				token.NewTransferCheckedInstruction(tokens.Raw, tokens.Decimals, source, mint, destination, owner.PublicKey(), nil).Build(),
+			},
+			block.Blockhash,
+			solana.TransactionPayer(owner.PublicKey()),
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to create vault transaction: %w", err)
+		}
+
+		if _, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+			if key.Equals(owner.PublicKey()) {
+				return &owner
+			}
+			return nil
+		}); err != nil {
+			return nil, 0, fmt.Errorf("failed to sign vault transaction: %w", err)
+		}
+		return tx, block.LastValidBlockHeight, nil
+	}
+
+	sender := sol.NewSender(v.solClient, rpc.TransactionOpts{}, v.config.RebroadcastInterval, sol.LogSendStatus(v.logger, "Vault"))
+	landing, err := sender.SendUntilLanded(ctx, transferAttempts, build)
+	if err != nil {
+		return fmt.Errorf("vault transaction %s has unknown outcome: %w", landing.Signature, err)
+	}
+	if landing.Status != sol.LandingConfirmed {
+		return fmt.Errorf("vault transaction %s %s", landing.Signature, landing.Status)
+	}
+
+	txHash := landing.Signature.String()
+	v.logger.Printf("Moved %s %s to vault %s: %s", tokens.Format(4), config.ShortenAddress(mint.String()), v.wallet, txHash)
+
+	if v.statsRecorder != nil {
+		fees, _, err := sol.AwaitTransactionFeesAndEarnings(ctx, v.solClient, txHash, false)
+		if err != nil {
+			logging.Warnf(v.logger, "Warning: Failed to get vault transaction fees: %v", err)
+		}
+		if err := v.statsRecorder.RecordVaultTransferStats(mint.String(), strconv.FormatUint(tokens.Raw, 10), fees, txHash); err != nil {
+			logging.Warnf(v.logger, "Warning: Failed to record vault transfer stats: %v", err)
+		}
+	}
+
+	if v.telegramClient != nil {
+		v.telegramClient.SendVaultTransferNotification(mint.String(), tokens, v.wallet.String(), txHash)
+	}
+
+	return nil
+}
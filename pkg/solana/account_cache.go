@@ -0,0 +1,130 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// maxAccountsPerRequest is the most accounts getMultipleAccounts accepts in one call
+const maxAccountsPerRequest = 100
+
+// AccountClient is the subset of the Solana RPC API used to read accounts
+type AccountClient interface {
+	GetMultipleAccountsWithOpts(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error)
+}
+
+var _ AccountClient = (*rpc.Client)(nil)
+
+// cachedAccount is an account read at fetchedAt, nil when the account does not exist
+type cachedAccount struct {
+	account   *rpc.Account
+	fetchedAt time.Time
+}
+
+// AccountCache keeps recently read accounts for a short time so hot reads, such as the distributor
+// and claim status accounts checked before every claim, cost one batched RPC call instead of many.
+// Missing accounts are cached too, callers invalidate accounts their own transactions change.
+type AccountCache struct {
+	client AccountClient
+	ttl    time.Duration
+
+	mu       sync.Mutex
+	accounts map[solana.PublicKey]cachedAccount
+}
+
+// NewAccountCache creates a cache keeping accounts for ttl
+func NewAccountCache(client AccountClient, ttl time.Duration) *AccountCache {
+	return &AccountCache{
+		client:   client,
+		ttl:      ttl,
+		accounts: make(map[solana.PublicKey]cachedAccount),
+	}
+}
+
+// Prefetch reads the given accounts that are not cached or have expired, in as few calls as possible
+func (c *AccountCache) Prefetch(ctx context.Context, keys ...solana.PublicKey) error {
+	now := time.Now()
+
+	c.mu.Lock()
+	var missing []solana.PublicKey
+	seen := make(map[solana.PublicKey]bool, len(keys))
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if cached, ok := c.accounts[key]; !ok || now.Sub(cached.fetchedAt) >= c.ttl {
+			missing = append(missing, key)
+		}
+	}
+	c.mu.Unlock()
+
+	for start := 0; start < len(missing); start += maxAccountsPerRequest {
+		end := start + maxAccountsPerRequest
+		if end > len(missing) {
+			end = len(missing)
+		}
+		batch := missing[start:end]
+
+		result, err := c.client.GetMultipleAccountsWithOpts(ctx, batch, &rpc.GetMultipleAccountsOpts{
+			Encoding:   solana.EncodingBase64,
+			Commitment: rpc.CommitmentConfirmed,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get accounts: %w", err)
+		}
+		if result == nil || len(result.Value) != len(batch) {
+			return fmt.Errorf("expected %d accounts, got an incomplete response", len(batch))
+		}
+
+		fetchedAt := time.Now()
+		c.mu.Lock()
+		for i, key := range batch {
+			c.accounts[key] = cachedAccount{account: result.Value[i], fetchedAt: fetchedAt}
+		}
+		c.mu.Unlock()
+	}
+
+	return nil
+}
+
+// Get returns the account, reading it when it is not cached. A nil account means it does not exist.
+func (c *AccountCache) Get(ctx context.Context, key solana.PublicKey) (*rpc.Account, error) {
+	if err := c.Prefetch(ctx, key); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.accounts[key].account, nil
+}
+
+// Exists reports whether the account exists
+func (c *AccountCache) Exists(ctx context.Context, key solana.PublicKey) (bool, error) {
+	account, err := c.Get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	return account != nil, nil
+}
+
+// Invalidate drops the given accounts, so the next read fetches them again
+func (c *AccountCache) Invalidate(keys ...solana.PublicKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		delete(c.accounts, key)
+	}
+}
+
+// InvalidateAll drops every cached account
+func (c *AccountCache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.accounts = make(map[solana.PublicKey]cachedAccount)
+}
@@ -0,0 +1,73 @@
+package solana
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAccountClient serves accounts from a map and counts the calls and accounts requested
+type fakeAccountClient struct {
+	accounts  map[solana.PublicKey]*rpc.Account
+	calls     int
+	requested int
+}
+
+func (f *fakeAccountClient) GetMultipleAccountsWithOpts(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error) {
+	f.calls++
+	f.requested += len(accounts)
+	result := &rpc.GetMultipleAccountsResult{Value: make([]*rpc.Account, len(accounts))}
+	for i, key := range accounts {
+		result.Value[i] = f.accounts[key]
+	}
+	return result, nil
+}
+
+func TestAccountCachePrefetchBatchesAndCaches(t *testing.T) {
+	existing := solana.NewWallet().PublicKey()
+	missing := solana.NewWallet().PublicKey()
+	client := &fakeAccountClient{accounts: map[solana.PublicKey]*rpc.Account{existing: {Lamports: 1}}}
+	cache := NewAccountCache(client, time.Minute)
+
+	assert.NoError(t, cache.Prefetch(context.Background(), existing, missing, existing))
+	assert.Equal(t, 1, client.calls)
+	assert.Equal(t, 2, client.requested)
+
+	exists, err := cache.Exists(context.Background(), existing)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = cache.Exists(context.Background(), missing)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	assert.Equal(t, 1, client.calls, "cached accounts, including missing ones, are not read again")
+}
+
+func TestAccountCacheInvalidateAndExpiry(t *testing.T) {
+	key := solana.NewWallet().PublicKey()
+	client := &fakeAccountClient{accounts: map[solana.PublicKey]*rpc.Account{}}
+	cache := NewAccountCache(client, time.Minute)
+
+	exists, _ := cache.Exists(context.Background(), key)
+	assert.False(t, exists)
+
+	// The account is created on chain, the cache still reports the old state until invalidated
+	client.accounts[key] = &rpc.Account{Lamports: 1}
+	exists, _ = cache.Exists(context.Background(), key)
+	assert.False(t, exists)
+
+	cache.Invalidate(key)
+	exists, _ = cache.Exists(context.Background(), key)
+	assert.True(t, exists)
+	assert.Equal(t, 2, client.calls)
+
+	// Expired entries are read again
+	expiring := NewAccountCache(client, 0)
+	_, _ = expiring.Get(context.Background(), key)
+	_, _ = expiring.Get(context.Background(), key)
+	assert.Equal(t, 4, client.calls)
+}
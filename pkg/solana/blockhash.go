@@ -12,7 +12,7 @@ import (
 type BlockhashCacheStruct struct {
 	mu        sync.Mutex
 	blockhash solana.Hash
-	Block     *rpc.LatestBlockhashResult
+	block     rpc.LatestBlockhashResult
 	expiry    time.Time
 	ttl       time.Duration
 }
@@ -25,12 +25,15 @@ func NewBlockhashCache(ttl time.Duration) *BlockhashCacheStruct {
 	}
 }
 
-func (c *BlockhashCacheStruct) GetBlockhash(node *rpc.Client) (*BlockhashCacheStruct, error) {
+// GetBlockhash returns the cached blockhash, fetching a new one once it expired. The result is a copy, the
+// cache may be refreshed by another caller while it is used.
+func (c *BlockhashCacheStruct) GetBlockhash(node RPCClient) (*rpc.LatestBlockhashResult, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if time.Now().Before(c.expiry) {
-		return c, nil
+		block := c.block
+		return &block, nil
 	}
 	block, err := node.GetLatestBlockhash(context.Background(), rpc.CommitmentConfirmed)
 	if err != nil {
@@ -38,8 +41,26 @@ func (c *BlockhashCacheStruct) GetBlockhash(node *rpc.Client) (*BlockhashCacheSt
 	}
 
 	c.blockhash = block.Value.Blockhash
-	c.Block = block.Value
+	c.block = *block.Value
 	c.expiry = time.Now().Add(c.ttl)
 
-	return c, nil
+	latest := c.block
+	return &latest, nil
+}
+
+// Latest fetches a new blockhash regardless of the cached one and caches it. Claims sent at the same time each
+// use their own, so none is built on a blockhash another claim already used for most of its lifetime.
+func (c *BlockhashCacheStruct) Latest(ctx context.Context, node RPCClient) (*rpc.LatestBlockhashResult, error) {
+	block, err := node.GetLatestBlockhash(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.blockhash = block.Value.Blockhash
+	c.block = *block.Value
+	c.expiry = time.Now().Add(c.ttl)
+
+	return block.Value, nil
 }
@@ -0,0 +1,216 @@
+package solana
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// boltTransactionsBucket holds the records keyed by timestamp and sequence, so they are read in time order
+	boltTransactionsBucket = []byte("transactions")
+	// boltTransactionHashesBucket maps transaction hash and type to the key of the record
+	boltTransactionHashesBucket = []byte("transaction_hashes")
+	// boltAirdropValuesBucket and boltDecisionsBucket hold the value history and decisions keyed like transactions
+	boltAirdropValuesBucket = []byte("airdrop_values")
+	boltDecisionsBucket     = []byte("decisions")
+	// boltStateBucket holds state documents by name
+	boltStateBucket = []byte("state")
+)
+
+// boltTransactionStore keeps transaction records in an embedded bbolt database ordered by time, so summaries
+// read only the period they cover without CGO
+type boltTransactionStore struct {
+	db *bolt.DB
+}
+
+// NewBoltTransactionStore opens (or creates) the bbolt database at path
+func NewBoltTransactionStore(path string) (TransactionStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create stats directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transaction store: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		buckets := [][]byte{boltTransactionsBucket, boltTransactionHashesBucket, boltAirdropValuesBucket, boltDecisionsBucket, boltStateBucket}
+		for _, bucket := range buckets {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create transaction buckets: %w", err)
+	}
+
+	return &boltTransactionStore{db: db}, nil
+}
+
+// Append stores the record under its timestamp and indexes its hash
+func (s *boltTransactionStore) Append(stats TransactionStats) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		records := tx.Bucket(boltTransactionsBucket)
+		sequence, err := records.NextSequence()
+		if err != nil {
+			return err
+		}
+		return putTransaction(tx, transactionKey(stats.Timestamp, sequence), stats)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record transaction %s: %w", stats.TxHash, err)
+	}
+	return nil
+}
+
+// Since seeks to the first record at or after since and reads from there
+func (s *boltTransactionStore) Since(since time.Time) ([]TransactionStats, error) {
+	var records []TransactionStats
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(boltTransactionsBucket).Cursor()
+		for key, value := cursor.Seek(transactionKey(since, 0)); key != nil; key, value = cursor.Next() {
+			var stats TransactionStats
+			if err := json.Unmarshal(value, &stats); err != nil {
+				return fmt.Errorf("failed to read transaction: %w", err)
+			}
+			// Keys have second precision, records earlier within the second of since are skipped here
+			if !stats.Timestamp.Before(since) {
+				records = append(records, stats)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transactions: %w", err)
+	}
+	return records, nil
+}
+
+// Replace updates the matching records in one database transaction, moving those whose timestamp changed
+func (s *boltTransactionStore) Replace(updated []TransactionStats) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		records := tx.Bucket(boltTransactionsBucket)
+		hashes := tx.Bucket(boltTransactionHashesBucket)
+		for _, stats := range updated {
+			key := hashes.Get(transactionHashKey(stats))
+			if key == nil {
+				continue
+			}
+			// Values are only valid until the bucket is modified
+			key = bytes.Clone(key)
+
+			// Keep the sequence, so records with the same timestamp stay in insertion order
+			sequence := binary.BigEndian.Uint64(key[8:])
+			if err := records.Delete(key); err != nil {
+				return err
+			}
+			if err := putTransaction(tx, transactionKey(stats.Timestamp, sequence), stats); err != nil {
+				return fmt.Errorf("failed to update transaction %s: %w", stats.TxHash, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit update: %w", err)
+	}
+	return nil
+}
+
+// Hashes reads the recorded hashes from the hash index
+func (s *boltTransactionStore) Hashes() (map[string]bool, error) {
+	hashes := make(map[string]bool)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltTransactionHashesBucket).ForEach(func(hashKey, _ []byte) error {
+			if end := bytes.IndexByte(hashKey, 0); end >= 0 {
+				hashes[string(hashKey[:end])] = true
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transaction hashes: %w", err)
+	}
+	return hashes, nil
+}
+
+// Close closes the database
+func (s *boltTransactionStore) Close() error {
+	return s.db.Close()
+}
+
+// putTransaction stores the record under key and points its hash index entry at it. Records without a hash
+// are not indexed, they would all share one index entry.
+func putTransaction(tx *bolt.Tx, key []byte, stats TransactionStats) error {
+	value, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(boltTransactionsBucket).Put(key, value); err != nil {
+		return err
+	}
+	if stats.TxHash == "" {
+		return nil
+	}
+	return tx.Bucket(boltTransactionHashesBucket).Put(transactionHashKey(stats), key)
+}
+
+// appendBoltRecord stores a JSON encoded record in a bucket keyed by its timestamp, like transactions
+func appendBoltRecord(db *bolt.DB, bucket []byte, timestamp time.Time, record any) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return db.Update(func(tx *bolt.Tx) error {
+		records := tx.Bucket(bucket)
+		sequence, err := records.NextSequence()
+		if err != nil {
+			return err
+		}
+		return records.Put(transactionKey(timestamp, sequence), value)
+	})
+}
+
+// boltRecordsSince decodes the records of a bucket timestamped since the given time, oldest first
+func boltRecordsSince[T any](db *bolt.DB, bucket []byte, since time.Time, timestamp func(T) time.Time) ([]T, error) {
+	var records []T
+	err := db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(bucket).Cursor()
+		// Keys have second resolution, the records earlier within the first second are skipped
+		for key, value := cursor.Seek(transactionKey(since, 0)); key != nil; key, value = cursor.Next() {
+			var record T
+			if err := json.Unmarshal(value, &record); err != nil {
+				return fmt.Errorf("failed to read %s record: %w", bucket, err)
+			}
+			if !timestamp(record).Before(since) {
+				records = append(records, record)
+			}
+		}
+		return nil
+	})
+	return records, err
+}
+
+// transactionKey orders records by unix seconds, with the sign bit flipped so times before 1970 sort first,
+// then by sequence
+func transactionKey(timestamp time.Time, sequence uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(timestamp.Unix())^(1<<63))
+	binary.BigEndian.PutUint64(key[8:], sequence)
+	return key
+}
+
+// transactionHashKey identifies a record by transaction hash and type, as Replace matches them
+func transactionHashKey(stats TransactionStats) []byte {
+	return []byte(stats.TxHash + "\x00" + string(stats.TxType))
+}
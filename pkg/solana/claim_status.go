@@ -0,0 +1,31 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// AccountExistenceChecker reports whether an account exists on chain, AccountCache is one
+type AccountExistenceChecker interface {
+	Exists(ctx context.Context, key solana.PublicKey) (bool, error)
+}
+
+var _ AccountExistenceChecker = (*AccountCache)(nil)
+
+// IsClaimedOnChain reports whether claimant already claimed from the merkle distributor. The distributor
+// program creates the claimant's claim status account on the first claim, so any later claim transaction
+// can only fail.
+func IsClaimedOnChain(ctx context.Context, accounts AccountExistenceChecker, claimant, distributor, programID solana.PublicKey) (bool, error) {
+	claimStatus, err := FindClaimStatusPDA(claimant, distributor, programID)
+	if err != nil {
+		return false, fmt.Errorf("failed to find claim status pda: %w", err)
+	}
+
+	exists, err := accounts.Exists(ctx, claimStatus)
+	if err != nil {
+		return false, fmt.Errorf("failed to read claim status %s: %w", claimStatus, err)
+	}
+	return exists, nil
+}
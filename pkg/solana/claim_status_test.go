@@ -0,0 +1,32 @@
+package solana
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsClaimedOnChain(t *testing.T) {
+	programID := solana.NewWallet().PublicKey()
+	distributor := solana.NewWallet().PublicKey()
+	claimed := solana.NewWallet().PublicKey()
+	unclaimed := solana.NewWallet().PublicKey()
+
+	claimStatus, err := FindClaimStatusPDA(claimed, distributor, programID)
+	assert.NoError(t, err)
+
+	client := &fakeAccountClient{accounts: map[solana.PublicKey]*rpc.Account{claimStatus: {Lamports: 1}}}
+	cache := NewAccountCache(client, time.Minute)
+
+	isClaimed, err := IsClaimedOnChain(context.Background(), cache, claimed, distributor, programID)
+	assert.NoError(t, err)
+	assert.True(t, isClaimed)
+
+	isClaimed, err = IsClaimedOnChain(context.Background(), cache, unclaimed, distributor, programID)
+	assert.NoError(t, err)
+	assert.False(t, isClaimed)
+}
@@ -0,0 +1,175 @@
+package solana
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Operation types whose compute unit usage is tracked
+const (
+	// OperationClaim is a single airdrop claim transaction
+	OperationClaim = "claim"
+	// OperationClaimAndSell is a claim transaction that also sells the claimed tokens
+	OperationClaimAndSell = "claim_and_sell"
+)
+
+const (
+	// MaxComputeUnitLimit is the highest compute unit limit a transaction may request
+	MaxComputeUnitLimit = 1_400_000
+	// computeUnitWindow is the number of recent samples per operation used for the percentile
+	computeUnitWindow = 200
+	// computeUnitMinSamples is the number of samples needed before history replaces the fallback limit
+	computeUnitMinSamples = 5
+)
+
+// computeUnitsFile stores simulated compute unit consumption per operation
+const computeUnitsFile = "compute_units.csv"
+
+// RecordComputeUnits records the compute units a simulated operation consumed
+func (s *StatsRecorder) RecordComputeUnits(operation string, consumed uint64) error {
+	if s == nil {
+		return fmt.Errorf("stats recorder is not initialized")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	filePath := filepath.Join(s.dataDir, computeUnitsFile)
+
+	fileExists := false
+	if _, err := os.Stat(filePath); err == nil {
+		fileExists = true
+	}
+
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open compute units file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	if !fileExists {
+		if err := writer.Write([]string{"Timestamp", "Operation", "Units Consumed"}); err != nil {
+			return fmt.Errorf("failed to write header: %w", err)
+		}
+	}
+
+	record := []string{
+		time.Now().Format(time.RFC3339),
+		operation,
+		strconv.FormatUint(consumed, 10),
+	}
+	if err := writer.Write(record); err != nil {
+		return fmt.Errorf("failed to write compute units: %w", err)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write compute units: %w", err)
+	}
+
+	// Samples not read yet are read from the file with this one
+	if s.computeUnits != nil {
+		s.computeUnits[operation] = appendComputeUnits(s.computeUnits[operation], consumed)
+	}
+	return nil
+}
+
+// ComputeUnitLimit derives the compute unit limit for an operation from the p95 of its recent
+// simulated consumption plus the given margin (0.2 = 20%). Returns fallback until enough samples exist.
+func (s *StatsRecorder) ComputeUnitLimit(operation string, margin float64, fallback uint32) uint32 {
+	if s == nil {
+		return fallback
+	}
+
+	samples := s.recentComputeUnits(operation)
+	if len(samples) < computeUnitMinSamples {
+		return fallback
+	}
+
+	return ComputeUnitLimitWithMargin(percentile(samples, 0.95), margin)
+}
+
+// ComputeUnitLimitWithMargin adds a safety margin to consumed compute units, capped at the maximum limit
+func ComputeUnitLimitWithMargin(consumed uint64, margin float64) uint32 {
+	limit := math.Ceil(float64(consumed) * (1 + margin))
+	if limit > MaxComputeUnitLimit {
+		return MaxComputeUnitLimit
+	}
+	return uint32(limit)
+}
+
+// recentComputeUnits returns the most recent samples recorded for an operation. The file is read on first use,
+// later samples are kept in memory as they are recorded.
+func (s *StatsRecorder) recentComputeUnits(operation string) []uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.computeUnits == nil {
+		samples, err := readComputeUnits(filepath.Join(s.dataDir, computeUnitsFile))
+		if err != nil {
+			return nil
+		}
+		s.computeUnits = samples
+	}
+	return append([]uint64(nil), s.computeUnits[operation]...)
+}
+
+// readComputeUnits reads the most recent samples of each operation from the compute units file
+func readComputeUnits(path string) (map[string][]uint64, error) {
+	samples := make(map[string][]uint64)
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return samples, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		if len(record) < 3 {
+			continue
+		}
+		consumed, err := strconv.ParseUint(record[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		samples[record[1]] = appendComputeUnits(samples[record[1]], consumed)
+	}
+	return samples, nil
+}
+
+// appendComputeUnits appends a sample, dropping the oldest beyond the window
+func appendComputeUnits(samples []uint64, consumed uint64) []uint64 {
+	samples = append(samples, consumed)
+	if len(samples) > computeUnitWindow {
+		samples = samples[len(samples)-computeUnitWindow:]
+	}
+	return samples
+}
+
+// percentile returns the nearest-rank percentile of the samples
+func percentile(samples []uint64, p float64) uint64 {
+	sorted := make([]uint64, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
@@ -0,0 +1,38 @@
+package solana
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeUnitLimitReadsHistoryOnce(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := NewStatsRecorder(dir)
+	require.NoError(t, err)
+	defer recorder.Close()
+
+	for _, consumed := range []uint64{50_000, 60_000, 70_000, 80_000} {
+		require.NoError(t, recorder.RecordComputeUnits(OperationClaim, consumed))
+	}
+	assert.Equal(t, uint32(200_000), recorder.ComputeUnitLimit(OperationClaim, 0.2, 200_000), "too few samples")
+
+	require.NoError(t, recorder.RecordComputeUnits(OperationClaim, 100_000))
+	assert.Equal(t, uint32(120_000), recorder.ComputeUnitLimit(OperationClaim, 0.2, 200_000))
+
+	// Later samples are kept in memory, the file is not read again
+	require.NoError(t, os.Remove(filepath.Join(dir, computeUnitsFile)))
+	for i := 0; i < computeUnitWindow; i++ {
+		require.NoError(t, recorder.RecordComputeUnits(OperationClaim, 40_000))
+	}
+	assert.Equal(t, uint32(48_000), recorder.ComputeUnitLimit(OperationClaim, 0.2, 200_000))
+	assert.Equal(t, uint32(200_000), recorder.ComputeUnitLimit(OperationClaimAndSell, 0.2, 200_000))
+
+	reopened, err := NewStatsRecorder(dir)
+	require.NoError(t, err)
+	defer reopened.Close()
+	assert.Equal(t, uint32(48_000), reopened.ComputeUnitLimit(OperationClaim, 0.2, 200_000))
+}
@@ -0,0 +1,178 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	// congestionBaselineWindow is the number of fee samples the normal fee level is derived from
+	congestionBaselineWindow = 60
+	// congestionFeeFloor keeps a near-zero baseline from flagging every small fee bump (micro-lamports per CU)
+	congestionFeeFloor = 50_000
+	// congestionRecoveryRatio is how far below the threshold fees must fall before claims resume
+	congestionRecoveryRatio = 0.8
+	// congestionSlowSlotTime is the average slot time above which the network counts as congested
+	congestionSlowSlotTime = 600 * time.Millisecond
+	// congestionPerformanceSamples is the number of 60 second performance samples used for the slot time
+	congestionPerformanceSamples uint = 5
+)
+
+// CongestionClient is the subset of the Solana RPC API used to measure network congestion
+type CongestionClient interface {
+	GetRecentPrioritizationFees(ctx context.Context, accounts solana.PublicKeySlice) ([]rpc.PriorizationFeeResult, error)
+	GetRecentPerformanceSamples(ctx context.Context, limit *uint) ([]*rpc.GetRecentPerformanceSamplesResult, error)
+}
+
+var _ CongestionClient = (*rpc.Client)(nil)
+
+// CongestionStatus describes the last measured network congestion
+type CongestionStatus struct {
+	Congested   bool
+	PriorityFee uint64        // 75th percentile priority fee of recent slots, micro-lamports per CU
+	BaselineFee uint64        // median of recent fee levels, micro-lamports per CU
+	Threshold   uint64        // fee level above which the network counts as congested
+	SlotTime    time.Duration // average slot time of recent performance samples, 0 when unknown
+	Since       time.Time     // when the current congestion state started
+	CheckedAt   time.Time
+}
+
+// Reason describes why the network counts as congested, or an empty string when it is not
+func (s CongestionStatus) Reason() string {
+	if !s.Congested {
+		return ""
+	}
+	if s.PriorityFee > s.Threshold {
+		return fmt.Sprintf("priority fees at %d µlamports/CU (baseline %d)", s.PriorityFee, s.BaselineFee)
+	}
+	return fmt.Sprintf("slow slots at %s", s.SlotTime.Round(time.Millisecond))
+}
+
+// CongestionMonitor tracks priority fee levels and slot times to tell when the network is congested
+type CongestionMonitor struct {
+	client        CongestionClient
+	accounts      solana.PublicKeySlice
+	feeMultiplier float64
+	maxFee        uint64
+
+	mu      sync.Mutex
+	samples []uint64
+	status  CongestionStatus
+}
+
+// NewCongestionMonitor creates a monitor that flags congestion when the recent fee level exceeds
+// feeMultiplier times its baseline or maxFee (0 disables the absolute limit). Fees are measured
+// for transactions writing the given accounts, or network wide when none are given.
+func NewCongestionMonitor(client CongestionClient, accounts solana.PublicKeySlice, feeMultiplier float64, maxFee uint64) *CongestionMonitor {
+	return &CongestionMonitor{
+		client:        client,
+		accounts:      accounts,
+		feeMultiplier: feeMultiplier,
+		maxFee:        maxFee,
+	}
+}
+
+// Check measures the current fee level and slot time and updates the congestion state
+func (m *CongestionMonitor) Check(ctx context.Context) (CongestionStatus, error) {
+	fees, err := m.client.GetRecentPrioritizationFees(ctx, m.accounts)
+	if err != nil {
+		return m.Status(), fmt.Errorf("failed to get recent prioritization fees: %w", err)
+	}
+	if len(fees) == 0 {
+		return m.Status(), fmt.Errorf("no recent prioritization fees returned")
+	}
+
+	values := make([]uint64, len(fees))
+	for i, fee := range fees {
+		values[i] = fee.PrioritizationFee
+	}
+	feeLevel := percentileUint64(values, 0.75)
+
+	// Slot times are optional, some RPC providers do not serve performance samples
+	var slotTime time.Duration
+	limit := congestionPerformanceSamples
+	if samples, err := m.client.GetRecentPerformanceSamples(ctx, &limit); err == nil {
+		slotTime = averageSlotTime(samples)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.samples = append(m.samples, feeLevel)
+	if len(m.samples) > congestionBaselineWindow {
+		m.samples = m.samples[len(m.samples)-congestionBaselineWindow:]
+	}
+
+	baseline := percentileUint64(m.samples, 0.5)
+	threshold := uint64(float64(baseline) * m.feeMultiplier)
+	if threshold < congestionFeeFloor {
+		threshold = congestionFeeFloor
+	}
+	if m.maxFee > 0 && threshold > m.maxFee {
+		threshold = m.maxFee
+	}
+
+	// Require fees and slot times to drop clearly below the limits before resuming
+	congested := feeLevel > threshold || (slotTime > 0 && slotTime > congestionSlowSlotTime)
+	if m.status.Congested && !congested {
+		congested = float64(feeLevel) > float64(threshold)*congestionRecoveryRatio ||
+			(slotTime > 0 && float64(slotTime) > float64(congestionSlowSlotTime)*congestionRecoveryRatio)
+	}
+
+	now := time.Now()
+	since := m.status.Since
+	if since.IsZero() || congested != m.status.Congested {
+		since = now
+	}
+
+	m.status = CongestionStatus{
+		Congested:   congested,
+		PriorityFee: feeLevel,
+		BaselineFee: baseline,
+		Threshold:   threshold,
+		SlotTime:    slotTime,
+		Since:       since,
+		CheckedAt:   now,
+	}
+	return m.status, nil
+}
+
+// Status returns the last measured congestion state
+func (m *CongestionMonitor) Status() CongestionStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status
+}
+
+// averageSlotTime returns the average slot duration over the given performance samples
+func averageSlotTime(samples []*rpc.GetRecentPerformanceSamplesResult) time.Duration {
+	var seconds, slots uint64
+	for _, sample := range samples {
+		if sample == nil {
+			continue
+		}
+		seconds += uint64(sample.SamplePeriodSecs)
+		slots += sample.NumSlots
+	}
+	if slots == 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second / time.Duration(slots)
+}
+
+// percentileUint64 returns the value at percentile p (0-1) of the given values
+func percentileUint64(values []uint64, p float64) uint64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := make([]uint64, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[int(float64(len(sorted)-1)*p)]
+}
@@ -0,0 +1,69 @@
+package solana
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeCongestionClient reports the same fee for every recent slot and a fixed slot time
+type fakeCongestionClient struct {
+	fee      uint64
+	slotTime time.Duration
+}
+
+func (f *fakeCongestionClient) GetRecentPrioritizationFees(ctx context.Context, accounts solana.PublicKeySlice) ([]rpc.PriorizationFeeResult, error) {
+	fees := make([]rpc.PriorizationFeeResult, 10)
+	for i := range fees {
+		fees[i] = rpc.PriorizationFeeResult{Slot: uint64(i), PrioritizationFee: f.fee}
+	}
+	return fees, nil
+}
+
+func (f *fakeCongestionClient) GetRecentPerformanceSamples(ctx context.Context, limit *uint) ([]*rpc.GetRecentPerformanceSamplesResult, error) {
+	slots := uint64(60 * time.Second / f.slotTime)
+	return []*rpc.GetRecentPerformanceSamplesResult{{NumSlots: slots, SamplePeriodSecs: 60}}, nil
+}
+
+func TestCongestionMonitorDefersUntilFeesNormalize(t *testing.T) {
+	client := &fakeCongestionClient{fee: 100_000, slotTime: 400 * time.Millisecond}
+	monitor := NewCongestionMonitor(client, nil, 3, 0)
+
+	for i := 0; i < 10; i++ {
+		status, err := monitor.Check(context.Background())
+		assert.NoError(t, err)
+		assert.False(t, status.Congested)
+	}
+
+	// A fee spike well above the baseline counts as congestion
+	client.fee = 1_000_000
+	status, err := monitor.Check(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, status.Congested)
+	assert.Equal(t, uint64(100_000), status.BaselineFee)
+	assert.Contains(t, status.Reason(), "priority fees")
+
+	// Just below the threshold is not yet normal
+	client.fee = 290_000
+	status, _ = monitor.Check(context.Background())
+	assert.True(t, status.Congested)
+
+	client.fee = 100_000
+	status, _ = monitor.Check(context.Background())
+	assert.False(t, status.Congested)
+}
+
+func TestCongestionMonitorSlowSlots(t *testing.T) {
+	client := &fakeCongestionClient{fee: 0, slotTime: time.Second}
+	monitor := NewCongestionMonitor(client, nil, 3, 0)
+
+	status, err := monitor.Check(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, status.Congested)
+	assert.Equal(t, time.Second, status.SlotTime)
+	assert.Contains(t, status.Reason(), "slow slots")
+}
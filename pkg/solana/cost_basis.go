@@ -0,0 +1,364 @@
+package solana
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Cost basis files in the data directory. Lots and realized gains are kept for as long as the tokens are held,
+// so they are not split by month like the other records.
+const (
+	costBasisFile     = "cost_basis.csv"
+	realizedGainsFile = "realized_gains.csv"
+)
+
+// CostBasisLot is the USD value of a claim of a kept token at claim time, its cost basis
+type CostBasisLot struct {
+	Timestamp   time.Time // When the tokens were claimed
+	AirdropID   string
+	TokenSymbol string
+	TokenMint   string
+	Amount      string // Raw token amount
+	BasisUsd    float64
+	ClaimTx     string
+}
+
+// RealizedGain is the sale of a cost basis lot, its gain or loss is the proceeds less the basis
+type RealizedGain struct {
+	Timestamp   time.Time // When the tokens were sold
+	AirdropID   string
+	TokenSymbol string
+	TokenMint   string
+	Amount      string
+	AcquiredAt  time.Time
+	BasisUsd    float64
+	ProceedsUsd float64
+	SaleTx      string
+}
+
+// GainUsd returns the realized gain, negative for a loss
+func (g RealizedGain) GainUsd() float64 {
+	return g.ProceedsUsd - g.BasisUsd
+}
+
+// costBasisHeader and realizedGainsHeader are the header rows of the cost basis files and of exports
+var (
+	costBasisHeader     = []string{"Acquired", "Airdrop", "Token", "Mint", "Amount", "Basis (USD)", "Claim Transaction"}
+	realizedGainsHeader = []string{
+		"Sold", "Airdrop", "Token", "Mint", "Amount", "Acquired", "Basis (USD)", "Proceeds (USD)", "Gain (USD)",
+		"Sale Transaction",
+	}
+)
+
+// RecordCostBasis records the cost basis of a claimed kept token
+func (s *StatsRecorder) RecordCostBasis(lot CostBasisLot) error {
+	if s == nil {
+		return fmt.Errorf("stats recorder is not initialized")
+	}
+
+	if lot.Timestamp.IsZero() {
+		lot.Timestamp = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return appendCSV(filepath.Join(s.dataDir, costBasisFile), costBasisHeader, costBasisRecord(lot))
+}
+
+// RealizeCostBasis records the disposal of raw units of a kept token against its open lots, first in first
+// out. A lot disposed of in part stays open with the rest of its amount and basis, the proceeds are shared by
+// amount. Units beyond the open lots were not kept claims and are not realized.
+func (s *StatsRecorder) RealizeCostBasis(mint string, raw uint64, proceedsUsd float64, saleTx string) ([]RealizedGain, error) {
+	if s == nil {
+		return nil, fmt.Errorf("stats recorder is not initialized")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lots, err := s.openCostBasisLots()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var realized []RealizedGain
+	remaining := raw
+	for _, lot := range lots {
+		if remaining == 0 {
+			break
+		}
+		lotAmount, _ := strconv.ParseUint(lot.Amount, 10, 64)
+		if lot.TokenMint != mint || lotAmount == 0 {
+			continue
+		}
+
+		disposed := min(remaining, lotAmount)
+		remaining -= disposed
+		gain := RealizedGain{
+			Timestamp:   now,
+			AirdropID:   lot.AirdropID,
+			TokenSymbol: lot.TokenSymbol,
+			TokenMint:   lot.TokenMint,
+			Amount:      strconv.FormatUint(disposed, 10),
+			AcquiredAt:  lot.Timestamp,
+			BasisUsd:    lot.BasisUsd * float64(disposed) / float64(lotAmount),
+			ProceedsUsd: proceedsUsd * float64(disposed) / float64(raw),
+			SaleTx:      saleTx,
+		}
+		if err := appendCSV(filepath.Join(s.dataDir, realizedGainsFile), realizedGainsHeader, realizedGainRecord(gain)); err != nil {
+			return realized, err
+		}
+		realized = append(realized, gain)
+	}
+	return realized, nil
+}
+
+// GetOpenCostBasisLots returns the lots whose tokens were not all disposed of yet, oldest first, with the
+// amount and basis still open
+func (s *StatsRecorder) GetOpenCostBasisLots() ([]CostBasisLot, error) {
+	if s == nil {
+		return nil, fmt.Errorf("stats recorder is not initialized")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.openCostBasisLots()
+}
+
+// openCostBasisLots returns the lots less what was realized of them, the caller holds the lock
+func (s *StatsRecorder) openCostBasisLots() ([]CostBasisLot, error) {
+	gains, err := s.readRealizedGains()
+	if err != nil {
+		return nil, err
+	}
+	disposed := make(map[string]uint64, len(gains))
+	for _, gain := range gains {
+		amount, _ := strconv.ParseUint(gain.Amount, 10, 64)
+		disposed[gain.AirdropID] += amount
+	}
+
+	lots, err := s.readCostBasisLots()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(lots, func(i, j int) bool { return lots[i].Timestamp.Before(lots[j].Timestamp) })
+
+	var open []CostBasisLot
+	for _, lot := range lots {
+		amount, err := strconv.ParseUint(lot.Amount, 10, 64)
+		if err != nil || disposed[lot.AirdropID] >= amount {
+			continue
+		}
+		left := amount - disposed[lot.AirdropID]
+		lot.BasisUsd = lot.BasisUsd * float64(left) / float64(amount)
+		lot.Amount = strconv.FormatUint(left, 10)
+		open = append(open, lot)
+	}
+	return open, nil
+}
+
+// RealizedGainStats sums gains realized on kept tokens
+type RealizedGainStats struct {
+	Disposals   int
+	BasisUsd    float64
+	ProceedsUsd float64
+}
+
+// GainUsd returns the realized gain, negative for a loss
+func (g RealizedGainStats) GainUsd() float64 {
+	return g.ProceedsUsd - g.BasisUsd
+}
+
+// GetRealizedGainStats sums the gains realized since the given time
+func (s *StatsRecorder) GetRealizedGainStats(since time.Time) (RealizedGainStats, error) {
+	gains, err := s.GetRealizedGains(since)
+	if err != nil {
+		return RealizedGainStats{}, err
+	}
+	var stats RealizedGainStats
+	for _, gain := range gains {
+		stats.Disposals++
+		stats.BasisUsd += gain.BasisUsd
+		stats.ProceedsUsd += gain.ProceedsUsd
+	}
+	return stats, nil
+}
+
+// GetRealizedGains returns the gains realized since the given time, oldest first
+func (s *StatsRecorder) GetRealizedGains(since time.Time) ([]RealizedGain, error) {
+	if s == nil {
+		return nil, fmt.Errorf("stats recorder is not initialized")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	gains, err := s.readRealizedGains()
+	if err != nil {
+		return nil, err
+	}
+	var result []RealizedGain
+	for _, gain := range gains {
+		if !gain.Timestamp.Before(since) {
+			result = append(result, gain)
+		}
+	}
+	return result, nil
+}
+
+// ExportRealizedGains writes realized gains as CSV with a header row, for tax reporting
+func ExportRealizedGains(w io.Writer, gains []RealizedGain) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(realizedGainsHeader); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+	for _, gain := range gains {
+		if err := writer.Write(realizedGainRecord(gain)); err != nil {
+			return fmt.Errorf("failed to write realized gain: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// readCostBasisLots reads all recorded lots, the caller holds the lock
+func (s *StatsRecorder) readCostBasisLots() ([]CostBasisLot, error) {
+	records, err := readCSV(filepath.Join(s.dataDir, costBasisFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var lots []CostBasisLot
+	for _, record := range records {
+		if len(record) < len(costBasisHeader) {
+			continue
+		}
+		// Skips the header row as well
+		acquired, err := time.Parse(time.RFC3339, record[0])
+		if err != nil {
+			continue
+		}
+		lots = append(lots, CostBasisLot{
+			Timestamp:   acquired,
+			AirdropID:   record[1],
+			TokenSymbol: record[2],
+			TokenMint:   record[3],
+			Amount:      record[4],
+			BasisUsd:    parseFloat(record[5]),
+			ClaimTx:     record[6],
+		})
+	}
+	return lots, nil
+}
+
+// readRealizedGains reads all realized gains, the caller holds the lock
+func (s *StatsRecorder) readRealizedGains() ([]RealizedGain, error) {
+	records, err := readCSV(filepath.Join(s.dataDir, realizedGainsFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var gains []RealizedGain
+	for _, record := range records {
+		if len(record) < len(realizedGainsHeader) {
+			continue
+		}
+		sold, err := time.Parse(time.RFC3339, record[0])
+		if err != nil {
+			continue
+		}
+		acquired, _ := time.Parse(time.RFC3339, record[5])
+		gains = append(gains, RealizedGain{
+			Timestamp:   sold,
+			AirdropID:   record[1],
+			TokenSymbol: record[2],
+			TokenMint:   record[3],
+			Amount:      record[4],
+			AcquiredAt:  acquired,
+			BasisUsd:    parseFloat(record[6]),
+			ProceedsUsd: parseFloat(record[7]),
+			SaleTx:      record[9],
+		})
+	}
+	return gains, nil
+}
+
+// costBasisRecord formats a lot as a CSV row
+func costBasisRecord(lot CostBasisLot) []string {
+	return []string{
+		lot.Timestamp.Format(time.RFC3339),
+		lot.AirdropID,
+		lot.TokenSymbol,
+		lot.TokenMint,
+		lot.Amount,
+		strconv.FormatFloat(lot.BasisUsd, 'f', -1, 64),
+		lot.ClaimTx,
+	}
+}
+
+// realizedGainRecord formats a realized gain as a CSV row
+func realizedGainRecord(gain RealizedGain) []string {
+	return []string{
+		gain.Timestamp.Format(time.RFC3339),
+		gain.AirdropID,
+		gain.TokenSymbol,
+		gain.TokenMint,
+		gain.Amount,
+		gain.AcquiredAt.Format(time.RFC3339),
+		strconv.FormatFloat(gain.BasisUsd, 'f', 2, 64),
+		strconv.FormatFloat(gain.ProceedsUsd, 'f', 2, 64),
+		strconv.FormatFloat(gain.GainUsd(), 'f', 2, 64),
+		gain.SaleTx,
+	}
+}
+
+// appendCSV appends a record to a CSV file, writing the header first when the file is new
+func appendCSV(filePath string, header, record []string) error {
+	fileExists := false
+	if _, err := os.Stat(filePath); err == nil {
+		fileExists = true
+	}
+
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filepath.Base(filePath), err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if !fileExists {
+		if err := writer.Write(header); err != nil {
+			return fmt.Errorf("failed to write header: %w", err)
+		}
+	}
+	if err := writer.Write(record); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// readCSV reads all records of a CSV file, none when it does not exist yet
+func readCSV(filePath string) ([][]string, error) {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", filepath.Base(filePath), err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(filePath), err)
+	}
+	return records, nil
+}
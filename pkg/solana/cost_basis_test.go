@@ -0,0 +1,64 @@
+package solana
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRealizeCostBasis(t *testing.T) {
+	recorder, err := NewStatsRecorder(t.TempDir())
+	require.NoError(t, err)
+	defer recorder.Close()
+
+	acquired := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, recorder.RecordCostBasis(CostBasisLot{Timestamp: acquired.Add(time.Hour), AirdropID: "later", TokenSymbol: "KEEP", TokenMint: "keep", Amount: "500", BasisUsd: 3}))
+	require.NoError(t, recorder.RecordCostBasis(CostBasisLot{Timestamp: acquired, AirdropID: "first", TokenSymbol: "KEEP", TokenMint: "keep", Amount: "1000", BasisUsd: 12.5, ClaimTx: "claim"}))
+
+	// Tokens of other mints have no basis
+	gains, err := recorder.RealizeCostBasis("other", 5, 1, "sale")
+	require.NoError(t, err)
+	assert.Empty(t, gains)
+
+	// The oldest lot is realized first, the second one in part
+	gains, err = recorder.RealizeCostBasis("keep", 1250, 15, "sale")
+	require.NoError(t, err)
+	require.Len(t, gains, 2)
+	assert.Equal(t, "first", gains[0].AirdropID)
+	assert.Equal(t, acquired, gains[0].AcquiredAt)
+	assert.InDelta(t, -0.5, gains[0].GainUsd(), 1e-9)
+	assert.Equal(t, "later", gains[1].AirdropID)
+	assert.Equal(t, "250", gains[1].Amount)
+	assert.InDelta(t, 1.5, gains[1].BasisUsd, 1e-9)
+	assert.InDelta(t, 3, gains[1].ProceedsUsd, 1e-9)
+
+	open, err := recorder.GetOpenCostBasisLots()
+	require.NoError(t, err)
+	require.Len(t, open, 1)
+	assert.Equal(t, "later", open[0].AirdropID)
+	assert.Equal(t, "250", open[0].Amount)
+	assert.InDelta(t, 1.5, open[0].BasisUsd, 1e-9)
+
+	// Units beyond the open lots are not realized
+	gains, err = recorder.RealizeCostBasis("keep", 1000, 10, "sale-again")
+	require.NoError(t, err)
+	require.Len(t, gains, 1)
+	assert.Equal(t, "250", gains[0].Amount)
+
+	stats, err := recorder.GetRealizedGainStats(time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Disposals)
+	assert.InDelta(t, 15.5, stats.BasisUsd, 0.01)
+
+	gains, err = recorder.GetRealizedGains(time.Time{})
+	require.NoError(t, err)
+	var export bytes.Buffer
+	require.NoError(t, ExportRealizedGains(&export, gains))
+	lines := strings.Split(strings.TrimSpace(export.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.Contains(t, lines[1], "12.50,12.00,-0.50,sale")
+}
@@ -0,0 +1,234 @@
+package solana
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Actions of a recorded decision
+const (
+	DecisionClaim = "claim"
+	DecisionSkip  = "skip"
+	DecisionSell  = "sell"
+	DecisionHold  = "hold"
+)
+
+// Decision is an audit record of a claim or sell decision with the inputs it was made on
+type Decision struct {
+	Timestamp        time.Time
+	AirdropID        string
+	TokenSymbol      string
+	TokenMint        string
+	Action           string // DecisionClaim, DecisionSkip, DecisionSell or DecisionHold
+	Reason           string
+	Check            string // Safety check that overruled the strategy, empty when the strategy decided
+	ValueUsd         float64
+	ValueSol         float64
+	StableFor        time.Duration // How long the value has been unchanged
+	ObservedFor      time.Duration // How long the value has been tracked
+	MinimumUsd       float64
+	StableMinimumUsd float64
+	StableDuration   time.Duration
+	QuoteAge         time.Duration // Age of the swap quote a sale was sent with, 0 for other decisions
+	Safety           string        // Safety screening result of the token, "passed" or the failed checks, empty when not screened
+	Outcome          string        // Transaction hash or error of a claim or sale that was sent, empty for other decisions
+}
+
+// decisionLogHeader is the header row of the monthly decision files and of exports
+var decisionLogHeader = []string{
+	"Timestamp", "Airdrop", "Token", "Mint", "Action", "Reason", "Check", "Value (USD)", "Value (SOL)",
+	"Stable For (s)", "Observed For (s)", "Threshold (USD)", "Stable Minimum (USD)", "Stable Duration (s)",
+	"Quote Age (ms)", "Safety", "Outcome",
+}
+
+// decisionLogBaseColumns is the column count of decision files written before the quote age was recorded, rows
+// written before the safety result and outcome stop after the quote age
+const decisionLogBaseColumns = 14
+
+// RecordDecision appends a decision to the monthly decision file
+func (s *StatsRecorder) RecordDecision(decision Decision) error {
+	if s == nil {
+		return fmt.Errorf("stats recorder is not initialized")
+	}
+
+	if decision.Timestamp.IsZero() {
+		decision.Timestamp = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.bolt != nil {
+		if err := appendBoltRecord(s.bolt, boltDecisionsBucket, decision.Timestamp, decision); err != nil {
+			return fmt.Errorf("failed to write decision: %w", err)
+		}
+		return nil
+	}
+
+	filePath := filepath.Join(s.dataDir, fmt.Sprintf("decisions_%s.csv", decision.Timestamp.Format("2006-01")))
+
+	fileExists := false
+	if _, err := os.Stat(filePath); err == nil {
+		fileExists = true
+	}
+
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open decision file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	if !fileExists {
+		if err := writer.Write(decisionLogHeader); err != nil {
+			return fmt.Errorf("failed to write header: %w", err)
+		}
+	}
+
+	if err := writer.Write(decisionRecord(decision)); err != nil {
+		return fmt.Errorf("failed to write decision: %w", err)
+	}
+
+	return nil
+}
+
+// GetDecisions returns all recorded decisions since the given time, oldest first
+func (s *StatsRecorder) GetDecisions(since time.Time) ([]Decision, error) {
+	if s == nil {
+		return nil, fmt.Errorf("stats recorder is not initialized")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.bolt != nil {
+		return boltRecordsSince(s.bolt, boltDecisionsBucket, since, func(decision Decision) time.Time {
+			return decision.Timestamp
+		})
+	}
+
+	files, err := filepath.Glob(filepath.Join(s.dataDir, "decisions_*.csv"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find decision files: %w", err)
+	}
+
+	var decisions []Decision
+	for _, filePath := range files {
+		file, err := os.Open(filePath)
+		if err != nil {
+			continue
+		}
+		reader := csv.NewReader(file)
+		reader.FieldsPerRecord = -1 // Files started before the newer columns mix row lengths
+		records, err := reader.ReadAll()
+		file.Close()
+		if err != nil {
+			continue
+		}
+
+		for _, record := range records {
+			if len(record) < decisionLogBaseColumns {
+				continue
+			}
+
+			// Skips the header row as well
+			timestamp, err := time.Parse(time.RFC3339, record[0])
+			if err != nil || timestamp.Before(since) {
+				continue
+			}
+
+			decisions = append(decisions, Decision{
+				Timestamp:        timestamp,
+				AirdropID:        record[1],
+				TokenSymbol:      record[2],
+				TokenMint:        record[3],
+				Action:           record[4],
+				Reason:           record[5],
+				Check:            record[6],
+				ValueUsd:         parseFloat(record[7]),
+				ValueSol:         parseFloat(record[8]),
+				StableFor:        parseSeconds(record[9]),
+				ObservedFor:      parseSeconds(record[10]),
+				MinimumUsd:       parseFloat(record[11]),
+				StableMinimumUsd: parseFloat(record[12]),
+				StableDuration:   parseSeconds(record[13]),
+			})
+			if len(record) > decisionLogBaseColumns {
+				decisions[len(decisions)-1].QuoteAge = time.Duration(parseFloat(record[14])) * time.Millisecond
+			}
+			if len(record) >= len(decisionLogHeader) {
+				decisions[len(decisions)-1].Safety = record[15]
+				decisions[len(decisions)-1].Outcome = record[16]
+			}
+		}
+	}
+
+	sort.SliceStable(decisions, func(i, j int) bool {
+		return decisions[i].Timestamp.Before(decisions[j].Timestamp)
+	})
+
+	return decisions, nil
+}
+
+// ExportDecisions writes decisions as CSV with a header row, for analysis outside the monthly files
+func ExportDecisions(w io.Writer, decisions []Decision) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(decisionLogHeader); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+	for _, decision := range decisions {
+		if err := writer.Write(decisionRecord(decision)); err != nil {
+			return fmt.Errorf("failed to write decision: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// decisionRecord formats a decision as a CSV row
+func decisionRecord(decision Decision) []string {
+	return []string{
+		decision.Timestamp.Format(time.RFC3339),
+		decision.AirdropID,
+		decision.TokenSymbol,
+		decision.TokenMint,
+		decision.Action,
+		decision.Reason,
+		decision.Check,
+		strconv.FormatFloat(decision.ValueUsd, 'f', -1, 64),
+		strconv.FormatFloat(decision.ValueSol, 'f', -1, 64),
+		formatSeconds(decision.StableFor),
+		formatSeconds(decision.ObservedFor),
+		strconv.FormatFloat(decision.MinimumUsd, 'f', -1, 64),
+		strconv.FormatFloat(decision.StableMinimumUsd, 'f', -1, 64),
+		formatSeconds(decision.StableDuration),
+		strconv.FormatInt(decision.QuoteAge.Milliseconds(), 10),
+		decision.Safety,
+		decision.Outcome,
+	}
+}
+
+// formatSeconds formats a duration as whole seconds
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(d/time.Second), 10)
+}
+
+// parseSeconds parses whole seconds written by formatSeconds, 0 when invalid
+func parseSeconds(s string) time.Duration {
+	seconds, _ := strconv.ParseInt(s, 10, 64)
+	return time.Duration(seconds) * time.Second
+}
+
+// parseFloat parses a float column, 0 when invalid
+func parseFloat(s string) float64 {
+	value, _ := strconv.ParseFloat(s, 64)
+	return value
+}
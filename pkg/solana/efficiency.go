@@ -0,0 +1,91 @@
+package solana
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"boop-airdrop-redeemer/pkg/amount"
+)
+
+// EfficiencyStats compares the fees spent on claiming and selling with the proceeds of the sales, in SOL
+type EfficiencyStats struct {
+	Sales       int
+	FeesSol     float64
+	ProceedsSol float64
+}
+
+// Ratio returns fees over proceeds, +Inf when fees were paid without proceeds and 0 when neither were. A rising
+// ratio means more of each sale goes to fees, a sign the thresholds or the fee settings need retuning.
+func (e EfficiencyStats) Ratio() float64 {
+	if e.ProceedsSol <= 0 {
+		if e.FeesSol > 0 {
+			return math.Inf(1)
+		}
+		return 0
+	}
+	return e.FeesSol / e.ProceedsSol
+}
+
+// EfficiencySummary groups efficiency stats overall, per UTC day (2006-01-02), per ISO week (2006-W01) and
+// per token
+type EfficiencySummary struct {
+	Total   EfficiencyStats
+	ByDay   map[string]EfficiencyStats
+	ByWeek  map[string]EfficiencyStats
+	ByToken map[string]EfficiencyStats
+}
+
+// GetEfficiencySummary aggregates the fees and proceeds recorded since the given time. Fees are the network
+// fees of claims and sales and the service fees paid, proceeds are the SOL the sales earned. Estimated records
+// are only counted when includeEstimates is set.
+func (s *StatsRecorder) GetEfficiencySummary(since time.Time, includeEstimates bool) (EfficiencySummary, error) {
+	summary := EfficiencySummary{
+		ByDay:   make(map[string]EfficiencyStats),
+		ByWeek:  make(map[string]EfficiencyStats),
+		ByToken: make(map[string]EfficiencyStats),
+	}
+
+	stats, err := s.GetTransactions(since)
+	if err != nil {
+		return summary, err
+	}
+
+	for _, stat := range stats {
+		if stat.Estimated && !includeEstimates {
+			continue
+		}
+
+		var add EfficiencyStats
+		switch stat.TxType {
+		case TypeClaim, TypeServiceFee:
+			add.FeesSol = amount.Lamports(stat.Expenses).Sol()
+		case TypeSwap:
+			add.Sales = 1
+			add.FeesSol = amount.Lamports(stat.Expenses).Sol()
+			add.ProceedsSol = amount.Lamports(stat.GrossProfit).Sol()
+		default:
+			continue
+		}
+
+		at := stat.Timestamp.UTC()
+		day := at.Format("2006-01-02")
+		year, isoWeek := at.ISOWeek()
+		week := fmt.Sprintf("%d-W%02d", year, isoWeek)
+
+		summary.Total = addEfficiency(summary.Total, add)
+		summary.ByDay[day] = addEfficiency(summary.ByDay[day], add)
+		summary.ByWeek[week] = addEfficiency(summary.ByWeek[week], add)
+		summary.ByToken[stat.TokenSymbol] = addEfficiency(summary.ByToken[stat.TokenSymbol], add)
+	}
+
+	return summary, nil
+}
+
+// addEfficiency adds the fees and proceeds of one transaction to aggregated efficiency stats
+func addEfficiency(stats, add EfficiencyStats) EfficiencyStats {
+	stats.Sales += add.Sales
+	stats.FeesSol += add.FeesSol
+	stats.ProceedsSol += add.ProceedsSol
+	return stats
+}
@@ -0,0 +1,56 @@
+package solana
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetEfficiencySummary(t *testing.T) {
+	recorder, err := NewStatsRecorder(t.TempDir())
+	require.NoError(t, err)
+	defer recorder.Close()
+
+	monday := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -7)
+	for monday.Weekday() != time.Monday {
+		monday = monday.AddDate(0, 0, -1)
+	}
+	records := []TransactionStats{
+		{Timestamp: monday.Add(time.Hour), TxType: TypeClaim, TokenSymbol: "AAA", Expenses: 5_000_000, TxHash: "claim-a"},
+		{Timestamp: monday.Add(2 * time.Hour), TxType: TypeSwap, TokenSymbol: "AAA", Expenses: 5_000_000, GrossProfit: 100_000_000, TxHash: "swap-a"},
+		{Timestamp: monday.Add(2 * time.Hour), TxType: TypeServiceFee, TokenSymbol: "AAA", Expenses: 10_000_000, TxHash: "fee-a"},
+		{Timestamp: monday.AddDate(0, 0, 7), TxType: TypeSwap, TokenSymbol: "BBB", Expenses: 1_000_000, GrossProfit: 50_000_000, TxHash: "swap-b"},
+		{Timestamp: monday.AddDate(0, 0, 7), TxType: TypeSwap, TokenSymbol: "BBB", Expenses: 1_000_000, GrossProfit: 50_000_000, TxHash: "swap-est", Estimated: true},
+		{Timestamp: monday.AddDate(0, 0, 7), TxType: TypeRentReclaim, Expenses: 1_000_000, TxHash: "reclaim"},
+	}
+	for _, record := range records {
+		require.NoError(t, recorder.RecordTransaction(record))
+	}
+
+	summary, err := recorder.GetEfficiencySummary(monday.Add(-time.Hour), false)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, summary.Total.Sales)
+	assert.InDelta(t, 0.021, summary.Total.FeesSol, 1e-9)
+	assert.InDelta(t, 0.15, summary.Total.ProceedsSol, 1e-9)
+	assert.InDelta(t, 0.14, summary.Total.Ratio(), 1e-9)
+
+	assert.InDelta(t, 0.2, summary.ByToken["AAA"].Ratio(), 1e-9)
+	assert.InDelta(t, 0.02, summary.ByToken["BBB"].Ratio(), 1e-9)
+	assert.Len(t, summary.ByDay, 2)
+	assert.Len(t, summary.ByWeek, 2)
+	year, week := monday.ISOWeek()
+	assert.Equal(t, 1, summary.ByWeek[fmt.Sprintf("%d-W%02d", year, week)].Sales)
+
+	// Estimated sales count when asked for
+	summary, err = recorder.GetEfficiencySummary(monday.Add(-time.Hour), true)
+	require.NoError(t, err)
+	assert.Equal(t, 3, summary.Total.Sales)
+
+	assert.True(t, math.IsInf(EfficiencyStats{FeesSol: 1}.Ratio(), 1))
+	assert.Zero(t, EfficiencyStats{}.Ratio())
+}
@@ -3,29 +3,202 @@ package solana
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"strconv"
+	"time"
 
 	solana_go "github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 )
 
-func GetTransactionFeesAndEarnings(node *rpc.Client, txHash string, checkEarnings bool) (uint64, uint64, error) {
-	maxSupportedTransactionVersion := uint64(0)
+// ErrTransactionUnavailable is returned by AwaitTransactionResult when the RPC node did not serve the
+// transaction in time. Its stats can be recorded as estimates and reconciled later.
+var ErrTransactionUnavailable = errors.New("transaction not available")
+
+// EstimatedTransactionFee is the base fee of a transaction with one signature in lamports, recorded when the
+// actual fee cannot be read
+const EstimatedTransactionFee = 5000
+
+// Backoff of AwaitTransactionResult. RPC nodes usually serve a confirmed transaction within a few
+// seconds, lagging nodes are given up to a minute.
+var (
+	txLookupInitialDelay = 500 * time.Millisecond
+	txLookupMaxDelay     = 8 * time.Second
+	txLookupTimeout      = time.Minute
+)
+
+// TransactionResult is what a confirmed transaction cost and earned its signer, in lamports
+type TransactionResult struct {
+	Fee      uint64
+	Earnings uint64 // Wrapped SOL transferred to the signer, only read with checkEarnings
+	Rent     int64  // Rent paid for accounts the transaction created less rent reclaimed from accounts it closed
+}
+
+// GetTransactionFeesAndEarnings reads the fee of a confirmed transaction and, with checkEarnings, the
+// wrapped SOL transferred to its signer
+func GetTransactionFeesAndEarnings(node RPCClient, txHash string, checkEarnings bool) (uint64, uint64, error) {
+	result, err := GetTransactionResult(node, txHash, checkEarnings)
+	return result.Fee, result.Earnings, err
+}
+
+// GetTransactionResult reads the fee and rent of a confirmed transaction and, with checkEarnings, the wrapped
+// SOL transferred to its signer
+func GetTransactionResult(node RPCClient, txHash string, checkEarnings bool) (TransactionResult, error) {
+	signature, err := solana_go.SignatureFromBase58(txHash)
+	if err != nil {
+		return TransactionResult{}, fmt.Errorf("invalid transaction signature %q: %w", txHash, err)
+	}
+
+	txResult, err := getParsedTransaction(context.Background(), node, signature)
+	if err != nil {
+		return TransactionResult{}, err
+	}
+
+	return parseTransactionResult(txResult, checkEarnings)
+}
 
-	var swapTxResult *rpc.GetParsedTransactionResult
-	var err error
+// AwaitTransactionFeesAndEarnings is AwaitTransactionResult without the rent
+func AwaitTransactionFeesAndEarnings(ctx context.Context, node RPCClient, txHash string, checkEarnings bool) (uint64, uint64, error) {
+	result, err := AwaitTransactionResult(ctx, node, txHash, checkEarnings)
+	return result.Fee, result.Earnings, err
+}
+
+// AwaitTransactionResult is GetTransactionResult for a transaction that just landed. It polls with exponential
+// backoff while the RPC node has not indexed the transaction yet or the request fails, and returns
+// ErrTransactionUnavailable when it is still missing after txLookupTimeout or ctx is cancelled.
+// Malformed results are returned right away, polling again would not fix them.
+func AwaitTransactionResult(ctx context.Context, node RPCClient, txHash string, checkEarnings bool) (TransactionResult, error) {
+	signature, err := solana_go.SignatureFromBase58(txHash)
+	if err != nil {
+		return TransactionResult{}, fmt.Errorf("invalid transaction signature %q: %w", txHash, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, txLookupTimeout)
+	defer cancel()
+
+	delay := txLookupInitialDelay
+	for {
+		txResult, err := getParsedTransaction(ctx, node, signature)
+		if err == nil {
+			return parseTransactionResult(txResult, checkEarnings)
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(err, rpc.ErrNotFound) || errors.Is(err, ctx.Err()) {
+				return TransactionResult{}, fmt.Errorf("%w: %s", ErrTransactionUnavailable, txHash)
+			}
+			return TransactionResult{}, fmt.Errorf("%w: %s: %v", ErrTransactionUnavailable, txHash, err)
+		case <-time.After(delay):
+		}
+
+		delay = min(2*delay, txLookupMaxDelay)
+	}
+}
+
+// getParsedTransaction fetches a transaction at confirmed commitment. rpc.ErrNotFound means the node has not
+// indexed it (yet).
+func getParsedTransaction(ctx context.Context, node RPCClient, signature solana_go.Signature) (*rpc.GetParsedTransactionResult, error) {
+	maxSupportedTransactionVersion := uint64(0)
 
-	swapTxResult, err = node.GetParsedTransaction(
-		context.Background(),
-		solana_go.MustSignatureFromBase58(txHash),
+	return node.GetParsedTransaction(
+		ctx,
+		signature,
 		&rpc.GetParsedTransactionOpts{
 			Commitment:                     "confirmed",
 			MaxSupportedTransactionVersion: &maxSupportedTransactionVersion,
 		},
 	)
+}
 
+// parseTransactionResult extracts the fee, earnings and rent from a parsed transaction
+func parseTransactionResult(txResult *rpc.GetParsedTransactionResult, checkEarnings bool) (TransactionResult, error) {
+	fee, earnings, err := parseFeesAndEarnings(txResult, checkEarnings)
 	if err != nil {
-		return 0, 0, err
+		return TransactionResult{Fee: fee}, err
+	}
+	return TransactionResult{Fee: fee, Earnings: earnings, Rent: parseRent(txResult)}, nil
+}
+
+// parseRent returns the rent the transaction paid for the accounts it created, less the rent it reclaimed into
+// the signer's wallet by closing the signer's token accounts. The signer pays for every account the bot's
+// transactions create, ATAs for the claimed or bought tokens most of all. Wrapped SOL held by those accounts
+// is not rent, it is counted by the earnings. Results without account balances have no rent.
+func parseRent(txResult *rpc.GetParsedTransactionResult) int64 {
+	if txResult == nil || txResult.Meta == nil || txResult.Transaction == nil {
+		return 0
+	}
+	meta := txResult.Meta
+	accounts := txResult.Transaction.Message.AccountKeys
+	if len(meta.PreBalances) != len(accounts) || len(meta.PostBalances) != len(accounts) {
+		return 0
+	}
+
+	var signer solana_go.PublicKey
+	for _, account := range accounts {
+		if account.Signer {
+			signer = account.PublicKey
+			break
+		}
+	}
+
+	preTokens := tokenBalancesByAccount(meta.PreTokenBalances)
+	postTokens := tokenBalancesByAccount(meta.PostTokenBalances)
+
+	var rent int64
+	for i, account := range accounts {
+		if account.Signer {
+			continue
+		}
+
+		pre, post := meta.PreBalances[i], meta.PostBalances[i]
+		switch {
+		case pre == 0 && post > 0:
+			// Created, a wrapped SOL account is funded with its rent and the SOL it wraps
+			if wrapped := wrappedSol(postTokens[i]); wrapped < post {
+				rent += int64(post - wrapped)
+			}
+		case pre > 0 && post == 0:
+			// Closed, the rent goes back to the closing authority, only the signer's own accounts are its rent
+			token, ok := preTokens[i]
+			if !ok || token.Owner == nil || !token.Owner.Equals(signer) {
+				continue
+			}
+			if wrapped := wrappedSol(token); wrapped < pre {
+				rent -= int64(pre - wrapped)
+			}
+		}
+	}
+	return rent
+}
+
+// tokenBalancesByAccount indexes token balances by the index of their account in the transaction
+func tokenBalancesByAccount(balances []rpc.TokenBalance) map[int]rpc.TokenBalance {
+	byAccount := make(map[int]rpc.TokenBalance, len(balances))
+	for _, balance := range balances {
+		byAccount[int(balance.AccountIndex)] = balance
+	}
+	return byAccount
+}
+
+// wrappedSol returns the lamports a wrapped SOL token balance holds, zero for other tokens
+func wrappedSol(balance rpc.TokenBalance) uint64 {
+	if !balance.Mint.Equals(solana_go.SolMint) || balance.UiTokenAmount == nil {
+		return 0
+	}
+	lamports, err := strconv.ParseUint(balance.UiTokenAmount.Amount, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return lamports
+}
+
+// parseFeesAndEarnings extracts the fee and earnings from a parsed transaction. Malformed results are
+// reported as errors instead of zero fees or earnings.
+func parseFeesAndEarnings(swapTxResult *rpc.GetParsedTransactionResult, checkEarnings bool) (uint64, uint64, error) {
+	if swapTxResult == nil || swapTxResult.Meta == nil {
+		return 0, 0, fmt.Errorf("transaction result has no metadata")
 	}
 
 	fee := swapTxResult.Meta.Fee
@@ -33,6 +206,10 @@ func GetTransactionFeesAndEarnings(node *rpc.Client, txHash string, checkEarning
 	var earnings uint64 = 0
 
 	if checkEarnings {
+		if swapTxResult.Transaction == nil {
+			return fee, 0, fmt.Errorf("transaction result has no transaction")
+		}
+
 		// Find the signer's wallet
 		var signerWallet solana_go.PublicKey
 		for _, account := range swapTxResult.Transaction.Message.AccountKeys {
@@ -46,7 +223,7 @@ func GetTransactionFeesAndEarnings(node *rpc.Client, txHash string, checkEarning
 		for _, innerInsts := range swapTxResult.Meta.InnerInstructions {
 			for _, inst := range innerInsts.Instructions {
 				// Look for spl-token program instructions
-				if inst.Program != "spl-token" {
+				if inst == nil || inst.Program != "spl-token" {
 					continue
 				}
 
@@ -110,6 +287,9 @@ func GetTransactionFeesAndEarnings(node *rpc.Client, txHash string, checkEarning
 					// Check if the destination is an associated token account for the signer
 					// that was created in this transaction
 					for _, mainInst := range swapTxResult.Transaction.Message.Instructions {
+						if mainInst == nil {
+							continue
+						}
 						if mainInst.Program == "spl-associated-token-account" || mainInst.Program == "spl-token" {
 							var mainParsedData map[string]interface{}
 							if mainInst.Parsed != nil {
@@ -166,9 +346,13 @@ func GetTransactionFeesAndEarnings(node *rpc.Client, txHash string, checkEarning
 				if isForSigner {
 					// Convert amount string to uint64
 					amountU64, err := strconv.ParseUint(amount, 10, 64)
-					if err == nil {
-						earnings += amountU64
+					if err != nil {
+						return fee, 0, fmt.Errorf("invalid transfer amount %q: %w", amount, err)
+					}
+					if earnings+amountU64 < earnings {
+						return fee, 0, fmt.Errorf("transfer amounts overflow")
 					}
+					earnings += amountU64
 				}
 			}
 		}
@@ -1,9 +1,15 @@
 package solana
 
 import (
+	"context"
+	"encoding/json"
+	"strings"
 	"testing"
+	"time"
 
+	solana_go "github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestGetTransactionResult(t *testing.T) {
@@ -18,3 +24,175 @@ func TestGetTransactionResult(t *testing.T) {
 	t.Logf("transaction result: %v", result)
 	t.Logf("earnings: %v", earnings)
 }
+
+// parsedSwapResult is a swap paying 0.5 wrapped SOL to the signer's token account, which is closed to the signer
+const parsedSwapResult = `{
+	"meta": {
+		"fee": 5000,
+		"innerInstructions": [{"index": 2, "instructions": [
+			{"program": "spl-token", "parsed": {"type": "transferChecked", "info": {
+				"mint": "So11111111111111111111111111111111111111112",
+				"destination": "Hf1WkyjFPoGcXqpbgk8bX9XeMECSUMXkBNdvGiu5AXNH",
+				"tokenAmount": {"amount": "500000000"}
+			}}}
+		]}]
+	},
+	"transaction": {"message": {
+		"accountKeys": [{"pubkey": "7xLk17EQQ5KLDLDe44wCmupJKJjTGd8hs3eSVVhCx932", "signer": true, "writable": true}],
+		"instructions": [
+			{"program": "spl-token", "parsed": {"type": "closeAccount", "info": {
+				"account": "Hf1WkyjFPoGcXqpbgk8bX9XeMECSUMXkBNdvGiu5AXNH",
+				"destination": "7xLk17EQQ5KLDLDe44wCmupJKJjTGd8hs3eSVVhCx932"
+			}}}
+		]
+	}}
+}`
+
+func TestParseFeesAndEarnings(t *testing.T) {
+	var result rpc.GetParsedTransactionResult
+	assert.NoError(t, json.Unmarshal([]byte(parsedSwapResult), &result))
+
+	fee, earnings, err := parseFeesAndEarnings(&result, true)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5000), fee)
+	assert.Equal(t, uint64(500_000_000), earnings)
+
+	// A transfer amount that is not a number is an error, not zero earnings
+	malformed := strings.Replace(parsedSwapResult, `"500000000"`, `"5e8"`, 1)
+	assert.NoError(t, json.Unmarshal([]byte(malformed), &result))
+	_, _, err = parseFeesAndEarnings(&result, true)
+	assert.Error(t, err)
+
+	_, _, err = parseFeesAndEarnings(&rpc.GetParsedTransactionResult{}, false)
+	assert.Error(t, err, "a result without metadata has no fee")
+}
+
+// parsedClaimResult creates a token account and a wrapped SOL account holding 0.5 SOL for the signer, closes
+// a token account of the signer and one of another owner
+const parsedClaimResult = `{
+	"meta": {
+		"fee": 5000,
+		"preBalances": [1000000000, 0, 0, 2039280, 2039280],
+		"postBalances": [997955720, 2039280, 502039280, 0, 0],
+		"preTokenBalances": [
+			{"accountIndex": 3, "mint": "Hf1WkyjFPoGcXqpbgk8bX9XeMECSUMXkBNdvGiu5AXNH", "owner": "7xLk17EQQ5KLDLDe44wCmupJKJjTGd8hs3eSVVhCx932", "uiTokenAmount": {"amount": "0"}},
+			{"accountIndex": 4, "mint": "Hf1WkyjFPoGcXqpbgk8bX9XeMECSUMXkBNdvGiu5AXNH", "owner": "Hf1WkyjFPoGcXqpbgk8bX9XeMECSUMXkBNdvGiu5AXNH", "uiTokenAmount": {"amount": "0"}}
+		],
+		"postTokenBalances": [
+			{"accountIndex": 1, "mint": "Hf1WkyjFPoGcXqpbgk8bX9XeMECSUMXkBNdvGiu5AXNH", "owner": "7xLk17EQQ5KLDLDe44wCmupJKJjTGd8hs3eSVVhCx932", "uiTokenAmount": {"amount": "1000"}},
+			{"accountIndex": 2, "mint": "So11111111111111111111111111111111111111112", "owner": "7xLk17EQQ5KLDLDe44wCmupJKJjTGd8hs3eSVVhCx932", "uiTokenAmount": {"amount": "500000000"}}
+		]
+	},
+	"transaction": {"message": {
+		"accountKeys": [
+			{"pubkey": "7xLk17EQQ5KLDLDe44wCmupJKJjTGd8hs3eSVVhCx932", "signer": true, "writable": true},
+			{"pubkey": "9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM", "signer": false, "writable": true},
+			{"pubkey": "2wmVCSfPxGPjrnMMn7rchp4uaeoTqN39mXFC2zhPdri9", "signer": false, "writable": true},
+			{"pubkey": "DRpbCBMxVnDK7maPM5tGv6MvB3v1sRMC86PZ8okm21hy", "signer": false, "writable": true},
+			{"pubkey": "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v", "signer": false, "writable": true}
+		],
+		"instructions": []
+	}}
+}`
+
+func TestParseRent(t *testing.T) {
+	var result rpc.GetParsedTransactionResult
+	assert.NoError(t, json.Unmarshal([]byte(parsedClaimResult), &result))
+
+	// Two accounts created, the wrapped SOL is not rent. Only the signer's closed account is reclaimed.
+	assert.Equal(t, int64(2*2_039_280-2_039_280), parseRent(&result))
+
+	// Results without balances have no rent
+	assert.NoError(t, json.Unmarshal([]byte(parsedSwapResult), &result))
+	assert.Zero(t, parseRent(&result))
+}
+
+// fakeIndexingClient serves a parsed transaction once it has been requested more than missing times
+type fakeIndexingClient struct {
+	RPCClient
+	missing int
+	result  string
+	calls   int
+}
+
+func (f *fakeIndexingClient) GetParsedTransaction(ctx context.Context, sig solana_go.Signature, opts *rpc.GetParsedTransactionOpts) (*rpc.GetParsedTransactionResult, error) {
+	f.calls++
+	if f.calls <= f.missing {
+		return nil, rpc.ErrNotFound
+	}
+	var result rpc.GetParsedTransactionResult
+	if err := json.Unmarshal([]byte(f.result), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// shortenTxLookup makes AwaitTransactionFeesAndEarnings poll quickly for the duration of the test
+func shortenTxLookup(t *testing.T, timeout time.Duration) {
+	initial, maxDelay, previous := txLookupInitialDelay, txLookupMaxDelay, txLookupTimeout
+	t.Cleanup(func() { txLookupInitialDelay, txLookupMaxDelay, txLookupTimeout = initial, maxDelay, previous })
+	txLookupInitialDelay, txLookupMaxDelay, txLookupTimeout = time.Millisecond, 4*time.Millisecond, timeout
+}
+
+func TestAwaitTransactionFeesAndEarningsPollsUntilIndexed(t *testing.T) {
+	shortenTxLookup(t, time.Second)
+	node := &fakeIndexingClient{missing: 3, result: parsedSwapResult}
+
+	fee, earnings, err := AwaitTransactionFeesAndEarnings(context.Background(), node, solana_go.Signature{1}.String(), true)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5000), fee)
+	assert.Equal(t, uint64(500_000_000), earnings)
+	assert.Equal(t, 4, node.calls)
+}
+
+func TestAwaitTransactionFeesAndEarningsGivesUp(t *testing.T) {
+	shortenTxLookup(t, 20*time.Millisecond)
+	node := &fakeIndexingClient{missing: 1 << 20, result: parsedSwapResult}
+
+	_, _, err := AwaitTransactionFeesAndEarnings(context.Background(), node, solana_go.Signature{1}.String(), true)
+	assert.ErrorIs(t, err, ErrTransactionUnavailable)
+	assert.Greater(t, node.calls, 1)
+}
+
+func TestReconcileEstimatesReplacesEstimatedClaims(t *testing.T) {
+	recorder, err := NewStatsRecorder(t.TempDir())
+	assert.NoError(t, err)
+
+	txHash := solana_go.Signature{1}.String()
+	assert.NoError(t, recorder.RecordEstimatedClaimStats("TEST", "1000", EstimatedTransactionFee, txHash, ""))
+
+	reconciled, err := recorder.ReconcileEstimates(context.Background(), &fakeIndexingClient{result: `{"meta": {"fee": 7000}}`})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, reconciled)
+
+	stats, err := recorder.GetTransactions(time.Time{})
+	assert.NoError(t, err)
+	if assert.Len(t, stats, 1) {
+		assert.False(t, stats[0].Estimated)
+		assert.Equal(t, uint64(7000), stats[0].Expenses)
+		assert.Equal(t, uint64(0), stats[0].NetProfit)
+	}
+}
+
+func FuzzParseFeesAndEarnings(f *testing.F) {
+	f.Add([]byte(parsedSwapResult), true)
+	f.Add([]byte(`{"meta": {"fee": 5000}}`), false)
+	f.Add([]byte(`{"meta": {"innerInstructions": [{"instructions": [null]}]}, "transaction": {"message": {"instructions": [null]}}}`), true)
+	f.Add([]byte(`{"meta": null, "transaction": null}`), true)
+
+	f.Fuzz(func(t *testing.T, data []byte, checkEarnings bool) {
+		var result rpc.GetParsedTransactionResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			return
+		}
+
+		// Any decodable response must produce values or an error, never a panic
+		fee, earnings, err := parseFeesAndEarnings(&result, checkEarnings)
+		if err == nil && result.Meta != nil && fee != result.Meta.Fee {
+			t.Fatalf("fee %d does not match the metadata fee %d", fee, result.Meta.Fee)
+		}
+		if !checkEarnings && earnings != 0 {
+			t.Fatalf("earnings %d reported without checking earnings", earnings)
+		}
+	})
+}
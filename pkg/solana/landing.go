@@ -0,0 +1,122 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// LandingStatus is the definitive state of a sent transaction
+type LandingStatus int
+
+const (
+	// LandingConfirmed means the transaction landed and succeeded
+	LandingConfirmed LandingStatus = iota
+	// LandingFailed means the transaction landed but its execution failed, so nothing changed on-chain
+	LandingFailed
+	// LandingExpired means the blockhash expired without the transaction landing, so it can never land
+	LandingExpired
+)
+
+// String returns a readable name for the landing status
+func (s LandingStatus) String() string {
+	switch s {
+	case LandingConfirmed:
+		return "confirmed"
+	case LandingFailed:
+		return "failed"
+	case LandingExpired:
+		return "expired"
+	default:
+		return "unknown"
+	}
+}
+
+// landingPollInterval is how often signature status and block height are polled
+const landingPollInterval = 2 * time.Second
+
+// Landing is the outcome of sending a transaction and waiting for it to land or expire
+type Landing struct {
+	Signature solana.Signature
+	Status    LandingStatus
+	TxErr     interface{} // Execution error when Status is LandingFailed
+	SendErr   error       // Error returned by the send call, the transaction may still have landed
+}
+
+// Retryable reports whether the operation definitely did not take effect and can be sent again
+func (l Landing) Retryable() bool {
+	return l.Status != LandingConfirmed
+}
+
+// SendAndAwaitLanding sends a signed transaction and waits until it is confirmed, failed, or its blockhash
+// has expired at lastValidBlockHeight. A send error does not end the wait, since the transaction may have
+// reached the cluster anyway. An error is only returned when the outcome is unknown (e.g. ctx cancelled).
+// The transaction is sent once, use a Sender to rebroadcast it while waiting.
+func SendAndAwaitLanding(ctx context.Context, client RPCClient, tx *solana.Transaction, lastValidBlockHeight uint64, opts rpc.TransactionOpts) (Landing, error) {
+	return Sender{Client: client, Opts: opts}.SendAndAwait(ctx, tx, lastValidBlockHeight)
+}
+
+// AwaitLanding polls a signature until it reaches confirmed commitment or the block height passes
+// lastValidBlockHeight, after which the transaction can no longer land
+func AwaitLanding(ctx context.Context, client RPCClient, sig solana.Signature, lastValidBlockHeight uint64) (LandingStatus, interface{}, error) {
+	ticker := time.NewTicker(landingPollInterval)
+	defer ticker.Stop()
+
+	for {
+		status, err := signatureStatus(ctx, client, sig, false)
+		if err == nil && isConfirmed(status) {
+			return landingStatus(status)
+		}
+
+		height, err := client.GetBlockHeight(ctx, rpc.CommitmentConfirmed)
+		if err == nil && height > lastValidBlockHeight {
+			// The transaction may have landed just before expiry, check the full history once more
+			status, err := signatureStatus(ctx, client, sig, true)
+			if err != nil {
+				return 0, nil, fmt.Errorf("failed to check expired transaction %s: %w", sig, err)
+			}
+			if isConfirmed(status) {
+				return landingStatus(status)
+			}
+			if status == nil {
+				return LandingExpired, nil, nil
+			}
+			// Processed but not yet confirmed, keep waiting for the cluster to decide
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, nil, fmt.Errorf("stopped waiting for transaction %s: %w", sig, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// signatureStatus looks up a signature, returning nil when the cluster has not seen it
+func signatureStatus(ctx context.Context, client RPCClient, sig solana.Signature, searchHistory bool) (*rpc.SignatureStatusesResult, error) {
+	result, err := client.GetSignatureStatuses(ctx, searchHistory, sig)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil || len(result.Value) == 0 {
+		return nil, nil
+	}
+	return result.Value[0], nil
+}
+
+// isConfirmed reports whether a signature status has reached confirmed commitment
+func isConfirmed(status *rpc.SignatureStatusesResult) bool {
+	return status != nil && (status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed ||
+		status.ConfirmationStatus == rpc.ConfirmationStatusFinalized)
+}
+
+// landingStatus converts a confirmed signature status into a landing status
+func landingStatus(status *rpc.SignatureStatusesResult) (LandingStatus, interface{}, error) {
+	if status.Err != nil {
+		return LandingFailed, status.Err, nil
+	}
+	return LandingConfirmed, nil, nil
+}
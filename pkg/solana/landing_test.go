@@ -0,0 +1,114 @@
+package solana
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeLandingClient reports a fixed signature status and block height
+type fakeLandingClient struct {
+	RPCClient
+	status  *rpc.SignatureStatusesResult
+	height  uint64
+	sendErr error
+}
+
+func (f *fakeLandingClient) SendTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts rpc.TransactionOpts) (solana.Signature, error) {
+	return tx.Signatures[0], f.sendErr
+}
+
+func (f *fakeLandingClient) GetSignatureStatuses(ctx context.Context, searchTransactionHistory bool, sigs ...solana.Signature) (*rpc.GetSignatureStatusesResult, error) {
+	return &rpc.GetSignatureStatusesResult{Value: []*rpc.SignatureStatusesResult{f.status}}, nil
+}
+
+func (f *fakeLandingClient) GetBlockHeight(ctx context.Context, commitment rpc.CommitmentType) (uint64, error) {
+	return f.height, nil
+}
+
+func signedTestTransaction() *solana.Transaction {
+	return &solana.Transaction{Signatures: []solana.Signature{{1, 2, 3}}}
+}
+
+func TestSendAndAwaitLandingConfirmedDespiteSendError(t *testing.T) {
+	client := &fakeLandingClient{
+		status:  &rpc.SignatureStatusesResult{ConfirmationStatus: rpc.ConfirmationStatusConfirmed},
+		height:  100,
+		sendErr: errors.New("timeout"),
+	}
+
+	landing, err := SendAndAwaitLanding(context.Background(), client, signedTestTransaction(), 150, rpc.TransactionOpts{})
+	assert.NoError(t, err)
+	assert.Equal(t, LandingConfirmed, landing.Status)
+	assert.False(t, landing.Retryable())
+	assert.Error(t, landing.SendErr)
+}
+
+func TestSendAndAwaitLandingFailed(t *testing.T) {
+	client := &fakeLandingClient{
+		status: &rpc.SignatureStatusesResult{ConfirmationStatus: rpc.ConfirmationStatusConfirmed, Err: "slippage"},
+		height: 100,
+	}
+
+	landing, err := SendAndAwaitLanding(context.Background(), client, signedTestTransaction(), 150, rpc.TransactionOpts{})
+	assert.NoError(t, err)
+	assert.Equal(t, LandingFailed, landing.Status)
+	assert.True(t, landing.Retryable())
+}
+
+func TestSendAndAwaitLandingExpired(t *testing.T) {
+	client := &fakeLandingClient{height: 151}
+
+	landing, err := SendAndAwaitLanding(context.Background(), client, signedTestTransaction(), 150, rpc.TransactionOpts{})
+	assert.NoError(t, err)
+	assert.Equal(t, LandingExpired, landing.Status)
+	assert.True(t, landing.Retryable())
+}
+
+func TestAwaitLandingUnknownOnCancel(t *testing.T) {
+	// Seen but never confirmed, and the blockhash has not expired yet
+	client := &fakeLandingClient{
+		status: &rpc.SignatureStatusesResult{ConfirmationStatus: rpc.ConfirmationStatusProcessed},
+		height: 100,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := AwaitLanding(ctx, client, solana.Signature{1}, 150)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestSenderRebuildsExpiredTransactions(t *testing.T) {
+	client := &fakeLandingClient{height: 151}
+
+	var stages []SendStage
+	sender := NewSender(client, rpc.TransactionOpts{}, 0, func(event SendEvent) {
+		stages = append(stages, event.Stage)
+	})
+
+	builds := 0
+	landing, err := sender.SendUntilLanded(context.Background(), 2, func(ctx context.Context) (*solana.Transaction, uint64, error) {
+		builds++
+		return signedTestTransaction(), 150, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, LandingExpired, landing.Status)
+	assert.Equal(t, 2, builds)
+	assert.Equal(t, []SendStage{StageSent, StageExpired, StageRebuilding, StageSent, StageExpired}, stages)
+
+	// A landed transaction is never rebuilt, even when it failed
+	client.status = &rpc.SignatureStatusesResult{ConfirmationStatus: rpc.ConfirmationStatusConfirmed, Err: "slippage"}
+	builds = 0
+	landing, err = sender.SendUntilLanded(context.Background(), 3, func(ctx context.Context) (*solana.Transaction, uint64, error) {
+		builds++
+		return signedTestTransaction(), 150, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, LandingFailed, landing.Status)
+	assert.Equal(t, 1, builds)
+}
@@ -0,0 +1,125 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// mintMissTTL is how long a mint that does not exist or is not a token mint is not read again, it may be
+// created later
+const mintMissTTL = 10 * time.Minute
+
+// MintDecimals resolves the decimals and token programs of token mints. Neither ever changes, so every mint is
+// read once. Mints that could not be read are tried again after mintMissTTL.
+type MintDecimals struct {
+	client AccountClient
+	now    func() time.Time
+
+	mu       sync.Mutex
+	decimals map[solana.PublicKey]uint8
+	programs map[solana.PublicKey]solana.PublicKey // Token program owning each mint
+	misses   map[solana.PublicKey]time.Time        // When each unreadable mint may be read again
+}
+
+// NewMintDecimals creates a resolver reading mints through client
+func NewMintDecimals(client AccountClient) *MintDecimals {
+	return &MintDecimals{
+		client:   client,
+		now:      time.Now,
+		decimals: make(map[solana.PublicKey]uint8),
+		programs: make(map[solana.PublicKey]solana.PublicKey),
+		misses:   make(map[solana.PublicKey]time.Time),
+	}
+}
+
+// Resolve reads the mints that are not known yet, in as few calls as possible
+func (m *MintDecimals) Resolve(ctx context.Context, mints ...solana.PublicKey) error {
+	m.mu.Lock()
+	now := m.now()
+	var missing []solana.PublicKey
+	seen := make(map[solana.PublicKey]bool, len(mints))
+	for _, mint := range mints {
+		_, known := m.decimals[mint]
+		if !known && !seen[mint] && !now.Before(m.misses[mint]) {
+			missing = append(missing, mint)
+		}
+		seen[mint] = true
+	}
+	m.mu.Unlock()
+
+	for start := 0; start < len(missing); start += maxAccountsPerRequest {
+		end := start + maxAccountsPerRequest
+		if end > len(missing) {
+			end = len(missing)
+		}
+		batch := missing[start:end]
+
+		result, err := m.client.GetMultipleAccountsWithOpts(ctx, batch, &rpc.GetMultipleAccountsOpts{
+			Encoding:   solana.EncodingBase64,
+			Commitment: rpc.CommitmentConfirmed,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get mints: %w", err)
+		}
+		if result == nil || len(result.Value) != len(batch) {
+			return fmt.Errorf("expected %d mints, got an incomplete response", len(batch))
+		}
+
+		m.mu.Lock()
+		for i, mint := range batch {
+			if result.Value[i] == nil {
+				m.misses[mint] = now.Add(mintMissTTL)
+				continue
+			}
+			var mintInfo token.Mint
+			if err := bin.NewBinDecoder(result.Value[i].Data.GetBinary()).Decode(&mintInfo); err != nil {
+				m.misses[mint] = now.Add(mintMissTTL)
+				continue
+			}
+			delete(m.misses, mint)
+			m.decimals[mint] = mintInfo.Decimals
+			m.programs[mint] = result.Value[i].Owner
+		}
+		m.mu.Unlock()
+	}
+
+	return nil
+}
+
+// Decimals returns the decimals of the mint, reading it when it is not known yet
+func (m *MintDecimals) Decimals(ctx context.Context, mint solana.PublicKey) (uint8, error) {
+	if err := m.Resolve(ctx, mint); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	decimals, ok := m.decimals[mint]
+	if !ok {
+		return 0, fmt.Errorf("mint %s does not exist or is not a token mint", mint)
+	}
+	return decimals, nil
+}
+
+// TokenProgram returns the token program owning the mint, the SPL Token or the Token-2022 program, reading the
+// mint when it is not known yet
+func (m *MintDecimals) TokenProgram(ctx context.Context, mint solana.PublicKey) (solana.PublicKey, error) {
+	if err := m.Resolve(ctx, mint); err != nil {
+		return solana.PublicKey{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	program, ok := m.programs[mint]
+	if !ok {
+		return solana.PublicKey{}, fmt.Errorf("mint %s does not exist or is not a token mint", mint)
+	}
+	return program, nil
+}
@@ -0,0 +1,90 @@
+package solana
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// mintAccount encodes a token mint with the given decimals
+func mintAccount(t *testing.T, decimals uint8) *rpc.Account {
+	var buf bytes.Buffer
+	assert.NoError(t, bin.NewBinEncoder(&buf).Encode(&token.Mint{Decimals: decimals, IsInitialized: true}))
+	return &rpc.Account{Lamports: 1, Data: rpc.DataBytesOrJSONFromBytes(buf.Bytes())}
+}
+
+func TestMintDecimalsResolvesOnce(t *testing.T) {
+	usdc := solana.NewWallet().PublicKey()
+	boop := solana.NewWallet().PublicKey()
+	missing := solana.NewWallet().PublicKey()
+	client := &fakeAccountClient{accounts: map[solana.PublicKey]*rpc.Account{
+		usdc: mintAccount(t, 6),
+		boop: mintAccount(t, 9),
+	}}
+	resolver := NewMintDecimals(client)
+
+	assert.NoError(t, resolver.Resolve(context.Background(), usdc, boop, usdc))
+	assert.Equal(t, 1, client.calls)
+	assert.Equal(t, 2, client.requested)
+
+	decimals, err := resolver.Decimals(context.Background(), usdc)
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(6), decimals)
+
+	decimals, err = resolver.Decimals(context.Background(), boop)
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(9), decimals)
+	assert.Equal(t, 1, client.calls, "known mints are not read again")
+
+	_, err = resolver.Decimals(context.Background(), missing)
+	assert.Error(t, err)
+	assert.Equal(t, 2, client.calls)
+}
+
+func TestMintDecimalsRetriesMissingMintsAfterTTL(t *testing.T) {
+	mint := solana.NewWallet().PublicKey()
+	client := &fakeAccountClient{accounts: map[solana.PublicKey]*rpc.Account{}}
+	resolver := NewMintDecimals(client)
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	resolver.now = func() time.Time { return now }
+
+	_, err := resolver.Decimals(context.Background(), mint)
+	assert.Error(t, err)
+	_, err = resolver.Decimals(context.Background(), mint)
+	assert.Error(t, err)
+	assert.Equal(t, 1, client.calls, "a missing mint is not read again within the TTL")
+
+	// The mint was created meanwhile
+	client.accounts[mint] = mintAccount(t, 6)
+	now = now.Add(mintMissTTL)
+	decimals, err := resolver.Decimals(context.Background(), mint)
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(6), decimals)
+	assert.Equal(t, 2, client.calls)
+}
+
+func TestMintDecimalsTokenProgram(t *testing.T) {
+	classic := solana.NewWallet().PublicKey()
+	extended := solana.NewWallet().PublicKey()
+	classicAccount, extendedAccount := mintAccount(t, 6), mintAccount(t, 9)
+	classicAccount.Owner = solana.TokenProgramID
+	extendedAccount.Owner = solana.Token2022ProgramID
+	resolver := NewMintDecimals(&fakeAccountClient{accounts: map[solana.PublicKey]*rpc.Account{
+		classic:  classicAccount,
+		extended: extendedAccount,
+	}})
+
+	program, err := resolver.TokenProgram(context.Background(), classic)
+	assert.NoError(t, err)
+	assert.Equal(t, solana.TokenProgramID, program)
+	program, err = resolver.TokenProgram(context.Background(), extended)
+	assert.NoError(t, err)
+	assert.Equal(t, solana.Token2022ProgramID, program)
+}
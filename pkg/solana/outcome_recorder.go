@@ -0,0 +1,211 @@
+package solana
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Strategies under which an airdrop was realized
+const (
+	// StrategyClaimAndSell sells the tokens right after claiming
+	StrategyClaimAndSell = "claim_and_sell"
+	// StrategyStableSell sells already claimed tokens once their price has been stable
+	StrategyStableSell = "stable_sell"
+)
+
+// Outcome records what an airdrop was expected to be worth when it was claimed and what its sale realized
+type Outcome struct {
+	Timestamp   time.Time
+	AirdropID   string
+	TokenSymbol string
+	Strategy    string
+	ExpectedUsd float64 // USD value reported when the claim decision was made
+	RealizedSol float64 // SOL received from the sale, before fees
+	SolPrice    float64 // SOL price at sale time, 0 when unknown
+	TxHash      string
+}
+
+// RealizedUsd returns the USD value of the sale proceeds, or 0 when the SOL price was unknown
+func (o Outcome) RealizedUsd() float64 {
+	return o.RealizedSol * o.SolPrice
+}
+
+// RealizationStats aggregates expected versus realized values for a group of outcomes
+type RealizationStats struct {
+	Count       int
+	ExpectedUsd float64
+	RealizedUsd float64
+}
+
+// Ratio returns realized over expected USD, 0 when nothing was expected
+func (r RealizationStats) Ratio() float64 {
+	if r.ExpectedUsd <= 0 {
+		return 0
+	}
+	return r.RealizedUsd / r.ExpectedUsd
+}
+
+// RealizationSummary groups realization stats overall, per strategy and per token
+type RealizationSummary struct {
+	Total      RealizationStats
+	ByStrategy map[string]RealizationStats
+	ByToken    map[string]RealizationStats
+}
+
+// outcomeFileHeader is the header row of the monthly outcome files
+var outcomeFileHeader = []string{
+	"Timestamp", "Airdrop", "Token", "Strategy",
+	"Expected (USD)", "Realized (SOL)", "SOL Price (USD)", "Transaction Hash",
+}
+
+// RecordOutcome records the expected and realized value of a sold airdrop
+func (s *StatsRecorder) RecordOutcome(outcome Outcome) error {
+	if s == nil {
+		return fmt.Errorf("stats recorder is not initialized")
+	}
+
+	if outcome.Timestamp.IsZero() {
+		outcome.Timestamp = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	filePath := filepath.Join(s.dataDir, fmt.Sprintf("outcomes_%s.csv", outcome.Timestamp.Format("2006-01")))
+
+	fileExists := false
+	if _, err := os.Stat(filePath); err == nil {
+		fileExists = true
+	}
+
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open outcome file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	if !fileExists {
+		if err := writer.Write(outcomeFileHeader); err != nil {
+			return fmt.Errorf("failed to write header: %w", err)
+		}
+	}
+
+	record := []string{
+		outcome.Timestamp.Format(time.RFC3339),
+		outcome.AirdropID,
+		outcome.TokenSymbol,
+		outcome.Strategy,
+		fmt.Sprintf("%.6f", outcome.ExpectedUsd),
+		fmt.Sprintf("%.9f", outcome.RealizedSol),
+		fmt.Sprintf("%.4f", outcome.SolPrice),
+		outcome.TxHash,
+	}
+	if err := writer.Write(record); err != nil {
+		return fmt.Errorf("failed to write outcome: %w", err)
+	}
+
+	return nil
+}
+
+// GetOutcomes returns all recorded outcomes since the given time, oldest first
+func (s *StatsRecorder) GetOutcomes(since time.Time) ([]Outcome, error) {
+	if s == nil {
+		return nil, fmt.Errorf("stats recorder is not initialized")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := filepath.Glob(filepath.Join(s.dataDir, "outcomes_*.csv"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find outcome files: %w", err)
+	}
+
+	var outcomes []Outcome
+	for _, filePath := range files {
+		file, err := os.Open(filePath)
+		if err != nil {
+			continue
+		}
+		records, err := csv.NewReader(file).ReadAll()
+		file.Close()
+		if err != nil {
+			continue
+		}
+
+		for _, record := range records {
+			if len(record) < len(outcomeFileHeader) {
+				continue
+			}
+
+			// Skips the header row as well
+			timestamp, err := time.Parse(time.RFC3339, record[0])
+			if err != nil || timestamp.Before(since) {
+				continue
+			}
+
+			expectedUsd, _ := strconv.ParseFloat(record[4], 64)
+			realizedSol, _ := strconv.ParseFloat(record[5], 64)
+			solPrice, _ := strconv.ParseFloat(record[6], 64)
+
+			outcomes = append(outcomes, Outcome{
+				Timestamp:   timestamp,
+				AirdropID:   record[1],
+				TokenSymbol: record[2],
+				Strategy:    record[3],
+				ExpectedUsd: expectedUsd,
+				RealizedSol: realizedSol,
+				SolPrice:    solPrice,
+				TxHash:      record[7],
+			})
+		}
+	}
+
+	sort.Slice(outcomes, func(i, j int) bool {
+		return outcomes[i].Timestamp.Before(outcomes[j].Timestamp)
+	})
+
+	return outcomes, nil
+}
+
+// GetRealizationSummary aggregates outcomes since the given time.
+// Outcomes without a known SOL price cannot be valued in USD and are left out.
+func (s *StatsRecorder) GetRealizationSummary(since time.Time) (RealizationSummary, error) {
+	summary := RealizationSummary{
+		ByStrategy: make(map[string]RealizationStats),
+		ByToken:    make(map[string]RealizationStats),
+	}
+
+	outcomes, err := s.GetOutcomes(since)
+	if err != nil {
+		return summary, err
+	}
+
+	for _, outcome := range outcomes {
+		if outcome.SolPrice <= 0 || outcome.ExpectedUsd <= 0 {
+			continue
+		}
+
+		summary.Total = addOutcome(summary.Total, outcome)
+		summary.ByStrategy[outcome.Strategy] = addOutcome(summary.ByStrategy[outcome.Strategy], outcome)
+		summary.ByToken[outcome.TokenSymbol] = addOutcome(summary.ByToken[outcome.TokenSymbol], outcome)
+	}
+
+	return summary, nil
+}
+
+// addOutcome adds an outcome to aggregated realization stats
+func addOutcome(stats RealizationStats, outcome Outcome) RealizationStats {
+	stats.Count++
+	stats.ExpectedUsd += outcome.ExpectedUsd
+	stats.RealizedUsd += outcome.RealizedUsd()
+	return stats
+}
@@ -1,11 +1,16 @@
 package solana
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"sync"
 	"time"
+
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/network"
 )
 
 // PriceResponse represents a response from a price API
@@ -15,6 +20,9 @@ type PriceResponse struct {
 	} `json:"solana"`
 }
 
+// Maximum age of a SOL price before it is reported as stale
+const maxPriceAge = 30 * time.Minute
+
 // PriceService tracks the current SOL price
 type PriceService struct {
 	currentPrice   float64
@@ -23,7 +31,10 @@ type PriceService struct {
 	mu             sync.RWMutex
 	logger         *log.Logger
 	apiURL         string
-	stopChan       chan struct{}
+	pythReader     *PythPriceReader
+	ctx            context.Context
+	cancel         context.CancelFunc
+	wg             sync.WaitGroup
 }
 
 // NewPriceService creates a new price service
@@ -33,79 +44,197 @@ func NewPriceService(logger *log.Logger) *PriceService {
 		updateInterval: 10 * time.Minute,
 		logger:         logger,
 		apiURL:         "https://api.coingecko.com/api/v3/simple/price?ids=solana&vs_currencies=usd",
-		stopChan:       make(chan struct{}),
 	}
 }
 
-// Start begins the price update service
-func (p *PriceService) Start() {
+// NewPythPriceService creates a price service that reads SOL/USD from a Pyth on-chain feed
+// over the existing RPC connection instead of calling CoinGecko
+func NewPythPriceService(solClient AccountInfoClient, feedAccount string, logger *log.Logger) (*PriceService, error) {
+	reader, err := NewPythPriceReader(solClient, feedAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	p := NewPriceService(logger)
+	p.pythReader = reader
+	// On-chain reads are not rate limited, so the price can be refreshed more often
+	p.updateInterval = time.Minute
+	return p, nil
+}
+
+// Start begins the price update service. The update loop stops when ctx is cancelled or Stop is called.
+func (p *PriceService) Start(ctx context.Context) {
+	loopCtx, cancel := context.WithCancel(ctx)
+	p.mu.Lock()
+	p.ctx, p.cancel = loopCtx, cancel
+	p.mu.Unlock()
+
 	// Fetch price immediately
-	p.updatePrice()
+	p.updatePrice(loopCtx)
 
 	// Start update loop
+	p.wg.Add(1)
 	go func() {
+		defer p.wg.Done()
 		ticker := time.NewTicker(p.updateInterval)
 		defer ticker.Stop()
 
 		for {
 			select {
 			case <-ticker.C:
-				p.updatePrice()
-			case <-p.stopChan:
+				p.updatePrice(loopCtx)
+			case <-loopCtx.Done():
 				return
 			}
 		}
 	}()
 }
 
-// Stop terminates the price update service
+// Stop terminates the price update service and waits for the update loop to exit.
+// It is safe to call more than once and after the start context was cancelled.
 func (p *PriceService) Stop() {
-	close(p.stopChan)
+	p.mu.RLock()
+	cancel := p.cancel
+	p.mu.RUnlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	p.wg.Wait()
 }
 
-// GetCurrentPrice returns the current SOL price
-func (p *PriceService) GetCurrentPrice() float64 {
+// context returns the context of the running service, or a background context when it was never started
+func (p *PriceService) context() context.Context {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	// If price is not available or too old, update it synchronously
-	if p.currentPrice == 0 || time.Since(p.lastUpdated) > 30*time.Minute {
-		p.mu.RUnlock() // Unlock before updating
-		p.updatePrice()
-		p.mu.RLock() // Lock again to read the updated price
+	if p.ctx == nil {
+		return context.Background()
 	}
+	return p.ctx
+}
 
-	return p.currentPrice
+// PriceQuote is a SOL price together with its provenance
+type PriceQuote struct {
+	Price     float64
+	Source    string
+	UpdatedAt time.Time
+	Stale     bool
 }
 
-// updatePrice fetches the latest SOL price from the API
-func (p *PriceService) updatePrice() {
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+// Age returns how long ago the quote was fetched
+func (q PriceQuote) Age() time.Duration {
+	return time.Since(q.UpdatedAt)
+}
+
+// GetPrice returns the current SOL price quote. The boolean is false when no price has been fetched yet,
+// in which case callers must not derive USD values from it.
+func (p *PriceService) GetPrice() (PriceQuote, bool) {
+	// If price is not available or too old, try to update it synchronously
+	if quote := p.snapshot(); quote.Price == 0 || quote.Stale {
+		p.updatePrice(p.context())
+	}
+
+	quote := p.snapshot()
+	if quote.Price <= 0 {
+		return quote, false
+	}
+	if quote.Stale {
+		logging.Warnf(p.logger, "Warning: SOL price from %s is stale (%s old)", quote.Source, quote.Age().Round(time.Second))
 	}
 
-	resp, err := client.Get(p.apiURL)
+	return quote, true
+}
+
+// snapshot returns the stored price without triggering an update
+func (p *PriceService) snapshot() PriceQuote {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return PriceQuote{
+		Price:     p.currentPrice,
+		Source:    p.sourceName(),
+		UpdatedAt: p.lastUpdated,
+		Stale:     p.currentPrice > 0 && time.Since(p.lastUpdated) > maxPriceAge,
+	}
+}
+
+// sourceName returns the name of the configured price source
+func (p *PriceService) sourceName() string {
+	if p.pythReader != nil {
+		return "pyth"
+	}
+	return "coingecko"
+}
+
+// updatePrice fetches the latest SOL price from the configured source
+func (p *PriceService) updatePrice(ctx context.Context) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	var price float64
+	var err error
+	if p.pythReader != nil {
+		price, err = p.fetchPythPrice(ctx)
+	} else {
+		price, err = p.fetchAPIPrice(ctx)
+	}
 	if err != nil {
-		p.logger.Printf("Error fetching SOL price: %v", err)
+		// Errors caused by shutdown are expected and not worth logging
+		if ctx.Err() == nil {
+			logging.Errorf(p.logger, "Error fetching SOL price: %v", err)
+		}
 		return
 	}
+
+	p.mu.Lock()
+	p.currentPrice = price
+	p.lastUpdated = time.Now()
+	p.mu.Unlock()
+
+	p.logger.Printf("Updated SOL price: $%.2f", price)
+}
+
+// fetchAPIPrice fetches the SOL price from the HTTP price API
+func (p *PriceService) fetchAPIPrice(ctx context.Context) (float64, error) {
+	client := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: network.Transport(network.CoinGecko),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.apiURL, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		p.logger.Printf("Error fetching SOL price: HTTP %d", resp.StatusCode)
-		return
+		return 0, fmt.Errorf("HTTP %d", resp.StatusCode)
 	}
 
 	var priceData PriceResponse
 	if err := json.NewDecoder(resp.Body).Decode(&priceData); err != nil {
-		p.logger.Printf("Error parsing SOL price data: %v", err)
-		return
+		return 0, fmt.Errorf("failed to parse price data: %w", err)
 	}
 
-	p.mu.Lock()
-	p.currentPrice = priceData.Solana.Usd
-	p.lastUpdated = time.Now()
-	p.mu.Unlock()
+	return priceData.Solana.Usd, nil
+}
+
+// fetchPythPrice reads the SOL price from the Pyth on-chain feed
+func (p *PriceService) fetchPythPrice(ctx context.Context) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	price, err := p.pythReader.GetPrice(ctx)
+	if err != nil {
+		return 0, err
+	}
 
-	p.logger.Printf("Updated SOL price: $%.2f", p.currentPrice)
+	return price.Price, nil
 }
@@ -0,0 +1,101 @@
+package solana
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestPriceService creates a price service backed by a local price API
+func newTestPriceService(t *testing.T) *PriceService {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Avoid keep-alive connection goroutines being counted as leaks
+		w.Header().Set("Connection", "close")
+		w.Write([]byte(`{"solana":{"usd":150.5}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	p := NewPriceService(log.New(io.Discard, "", 0))
+	p.apiURL = server.URL
+	p.updateInterval = 10 * time.Millisecond
+	return p
+}
+
+// waitForGoroutines waits until the goroutine count drops back to the baseline
+func waitForGoroutines(baseline int) int {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if n := runtime.NumGoroutine(); n <= baseline {
+			return n
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return runtime.NumGoroutine()
+}
+
+func TestPriceServiceStopsOnContextCancel(t *testing.T) {
+	p := newTestPriceService(t)
+	baseline := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.Start(ctx)
+
+	quote, ok := p.GetPrice()
+	assert.True(t, ok)
+	assert.Equal(t, 150.5, quote.Price)
+	assert.Equal(t, "coingecko", quote.Source)
+
+	cancel()
+	p.wg.Wait()
+
+	assert.LessOrEqual(t, waitForGoroutines(baseline), baseline, "price update loop leaked after context cancel")
+}
+
+func TestPriceServiceStopIsIdempotent(t *testing.T) {
+	p := newTestPriceService(t)
+	baseline := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.Start(ctx)
+
+	// Stop before and after the parent context is cancelled must not panic or block
+	p.Stop()
+	cancel()
+	p.Stop()
+
+	assert.LessOrEqual(t, waitForGoroutines(baseline), baseline, "price update loop leaked after Stop")
+}
+
+func TestPriceServiceStopWithoutStart(t *testing.T) {
+	p := newTestPriceService(t)
+
+	// Stopping a service that was never started is a no-op
+	p.Stop()
+}
+
+func TestReconcileEstimatesHonorsCancelledContext(t *testing.T) {
+	recorder, err := NewStatsRecorder(t.TempDir())
+	assert.NoError(t, err)
+	assert.NoError(t, recorder.RecordEstimatedSwapStats("TEST", "1000", 5000, 1_000_000, "estimated-tx", ""))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// A cancelled context stops before any RPC call, so a nil client is never used
+	reconciled, err := recorder.ReconcileEstimates(ctx, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, reconciled)
+
+	summary, err := recorder.GetProfitSummary(false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, summary.Estimated)
+	assert.Equal(t, 0.0, summary.LastWeek)
+}
@@ -0,0 +1,120 @@
+package solana
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// PythSolUsdFeedAccount is the sponsored Pyth push-oracle SOL/USD price feed account (shard 0) on mainnet
+const PythSolUsdFeedAccount = "7UVimffxr9ow1uXYxsr4LHAcV58mLzhmwaeKvJ1pjLiE"
+
+// Maximum age of a Pyth price before it is considered stale
+const pythMaxPriceAge = 5 * time.Minute
+
+// PythPrice is a price read from a Pyth PriceUpdateV2 account
+type PythPrice struct {
+	Price       float64
+	Confidence  float64
+	PublishTime time.Time
+}
+
+// AccountInfoClient is the RPC call the Pyth price reader needs
+type AccountInfoClient interface {
+	GetAccountInfoWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetAccountInfoOpts) (*rpc.GetAccountInfoResult, error)
+}
+
+var _ AccountInfoClient = (*rpc.Client)(nil)
+
+// PythPriceReader reads prices from Pyth price feed accounts over the existing RPC connection
+type PythPriceReader struct {
+	solClient   AccountInfoClient
+	feedAccount solana.PublicKey
+}
+
+// NewPythPriceReader creates a new Pyth price reader for the given feed account
+func NewPythPriceReader(solClient AccountInfoClient, feedAccount string) (*PythPriceReader, error) {
+	if feedAccount == "" {
+		feedAccount = PythSolUsdFeedAccount
+	}
+
+	account, err := solana.PublicKeyFromBase58(feedAccount)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Pyth feed account: %w", err)
+	}
+
+	return &PythPriceReader{
+		solClient:   solClient,
+		feedAccount: account,
+	}, nil
+}
+
+// GetPrice fetches and decodes the latest price from the feed account
+func (r *PythPriceReader) GetPrice(ctx context.Context) (*PythPrice, error) {
+	info, err := r.solClient.GetAccountInfoWithOpts(ctx, r.feedAccount, &rpc.GetAccountInfoOpts{
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Pyth feed account: %w", err)
+	}
+	if info == nil || info.Value == nil {
+		return nil, fmt.Errorf("Pyth feed account %s not found", r.feedAccount)
+	}
+
+	price, err := ParsePythPriceUpdate(info.Value.Data.GetBinary())
+	if err != nil {
+		return nil, err
+	}
+
+	if age := time.Since(price.PublishTime); age > pythMaxPriceAge {
+		return nil, fmt.Errorf("Pyth price is stale, published %s ago", age.Round(time.Second))
+	}
+
+	return price, nil
+}
+
+// ParsePythPriceUpdate decodes a Pyth receiver PriceUpdateV2 account
+func ParsePythPriceUpdate(data []byte) (*PythPrice, error) {
+	// Layout: discriminator (8), write authority (32), verification level (1 or 2),
+	// feed id (32), price i64, conf u64, exponent i32, publish time i64, ...
+	offset := 8 + 32
+	if len(data) < offset+1 {
+		return nil, fmt.Errorf("Pyth price account too short: %d bytes", len(data))
+	}
+
+	switch data[offset] {
+	case 0: // Partial { num_signatures: u8 }
+		offset += 2
+	case 1: // Full
+		offset++
+	default:
+		return nil, fmt.Errorf("unknown Pyth verification level %d", data[offset])
+	}
+
+	offset += 32 // feed id
+	if len(data) < offset+8+8+4+8 {
+		return nil, fmt.Errorf("Pyth price account too short: %d bytes", len(data))
+	}
+
+	rawPrice := int64(binary.LittleEndian.Uint64(data[offset:]))
+	rawConf := binary.LittleEndian.Uint64(data[offset+8:])
+	exponent := int32(binary.LittleEndian.Uint32(data[offset+16:]))
+	publishTime := int64(binary.LittleEndian.Uint64(data[offset+20:]))
+
+	scale := math.Pow10(int(exponent))
+	price := float64(rawPrice) * scale
+	if price <= 0 {
+		return nil, fmt.Errorf("invalid Pyth price %d (exponent %d)", rawPrice, exponent)
+	}
+
+	return &PythPrice{
+		Price:       price,
+		Confidence:  float64(rawConf) * scale,
+		PublishTime: time.Unix(publishTime, 0),
+	}, nil
+}
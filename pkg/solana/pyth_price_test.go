@@ -0,0 +1,118 @@
+package solana
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pythPriceAccount builds a PriceUpdateV2 account with the given verification level bytes and price message,
+// followed by the EMA price, EMA confidence and posted slot real accounts carry
+func pythPriceAccount(level []byte, price int64, conf uint64, exponent int32, publishTime int64) []byte {
+	data := make([]byte, 8+32) // Discriminator and write authority
+	data = append(data, level...)
+	data = append(data, make([]byte, 32)...) // Feed id
+	data = binary.LittleEndian.AppendUint64(data, uint64(price))
+	data = binary.LittleEndian.AppendUint64(data, conf)
+	data = binary.LittleEndian.AppendUint32(data, uint32(exponent))
+	data = binary.LittleEndian.AppendUint64(data, uint64(publishTime))
+	data = binary.LittleEndian.AppendUint64(data, uint64(publishTime-1)) // Previous publish time
+	data = binary.LittleEndian.AppendUint64(data, uint64(price))         // EMA price
+	data = binary.LittleEndian.AppendUint64(data, conf)                  // EMA confidence
+	return binary.LittleEndian.AppendUint64(data, 300_000_000)           // Posted slot
+}
+
+var (
+	pythFull    = []byte{1}
+	pythPartial = []byte{0, 5} // Partial with 5 signatures, the price message starts a byte later
+)
+
+func TestParsePythPriceUpdate(t *testing.T) {
+	const publishTime = 1_760_000_000
+	full := pythPriceAccount(pythFull, 15_050_000_000, 5_000_000, -8, publishTime)
+
+	tests := []struct {
+		name       string
+		data       []byte
+		price      float64
+		confidence float64
+		err        string
+	}{
+		{name: "full", data: full, price: 150.5, confidence: 0.05},
+		{name: "partial", data: pythPriceAccount(pythPartial, 15_050_000_000, 5_000_000, -8, publishTime), price: 150.5, confidence: 0.05},
+		{name: "negative exponent", data: pythPriceAccount(pythFull, 1_234_567, 10, -5, publishTime), price: 12.34567, confidence: 0.0001},
+		{name: "positive exponent", data: pythPriceAccount(pythFull, 3, 1, 2, publishTime), price: 300, confidence: 100},
+		{name: "truncated price", data: full[:8+32+1+32+20], err: "too short"},
+		{name: "truncated header", data: full[:8+32], err: "too short"},
+		{name: "unknown verification level", data: pythPriceAccount([]byte{2}, 1, 1, 0, publishTime), err: "verification level 2"},
+		{name: "zero price", data: pythPriceAccount(pythFull, 0, 1, -8, publishTime), err: "invalid Pyth price"},
+		{name: "negative price", data: pythPriceAccount(pythFull, -15_050_000_000, 1, -8, publishTime), err: "invalid Pyth price"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, err := ParsePythPriceUpdate(tt.data)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, tt.price, price.Price, 1e-9)
+			assert.InDelta(t, tt.confidence, price.Confidence, 1e-9)
+			assert.Equal(t, time.Unix(publishTime, 0), price.PublishTime)
+		})
+	}
+}
+
+// fakeAccountInfoClient serves one account's data, nil when the account does not exist
+type fakeAccountInfoClient struct {
+	data []byte
+}
+
+func (f *fakeAccountInfoClient) GetAccountInfoWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetAccountInfoOpts) (*rpc.GetAccountInfoResult, error) {
+	if f.data == nil {
+		return &rpc.GetAccountInfoResult{}, nil
+	}
+	return &rpc.GetAccountInfoResult{Value: &rpc.Account{Data: rpc.DataBytesOrJSONFromBytes(f.data)}}, nil
+}
+
+func TestPythPriceReaderGetPrice(t *testing.T) {
+	client := &fakeAccountInfoClient{}
+	reader, err := NewPythPriceReader(client, "")
+	require.NoError(t, err)
+
+	_, err = reader.GetPrice(context.Background())
+	assert.ErrorContains(t, err, "not found")
+
+	client.data = pythPriceAccount(pythFull, 15_050_000_000, 5_000_000, -8, time.Now().Unix())
+	price, err := reader.GetPrice(context.Background())
+	require.NoError(t, err)
+	assert.InDelta(t, 150.5, price.Price, 1e-9)
+
+	client.data = pythPriceAccount(pythFull, 15_050_000_000, 5_000_000, -8, time.Now().Add(-2*pythMaxPriceAge).Unix())
+	_, err = reader.GetPrice(context.Background())
+	assert.ErrorContains(t, err, "stale")
+}
+
+func TestPythPriceServiceReadsFeed(t *testing.T) {
+	client := &fakeAccountInfoClient{data: pythPriceAccount(pythFull, 15_050_000_000, 5_000_000, -8, time.Now().Unix())}
+	p, err := NewPythPriceService(client, "", log.New(io.Discard, "", 0))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.Start(ctx)
+	defer p.Stop()
+	defer cancel()
+
+	quote, ok := p.GetPrice()
+	assert.True(t, ok)
+	assert.InDelta(t, 150.5, quote.Price, 1e-9)
+	assert.Equal(t, "pyth", quote.Source)
+}
@@ -0,0 +1,46 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// recentSignaturesLimit is how many of the newest transactions touching an account are looked at
+const recentSignaturesLimit = 10
+
+// SignatureClient is the subset of the Solana RPC API used to list transactions touching an account
+type SignatureClient interface {
+	GetSignaturesForAddressWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error)
+}
+
+var _ SignatureClient = (*rpc.Client)(nil)
+
+// RecentSignatures returns the transactions touching address that landed after since, newest first.
+// Transactions without a block time yet are the most recent ones and are always included.
+func RecentSignatures(ctx context.Context, client SignatureClient, address solana.PublicKey, since time.Time) ([]*rpc.TransactionSignature, error) {
+	limit := recentSignaturesLimit
+	signatures, err := client.GetSignaturesForAddressWithOpts(ctx, address, &rpc.GetSignaturesForAddressOpts{
+		Limit:      &limit,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signatures for %s: %w", address, err)
+	}
+
+	var recent []*rpc.TransactionSignature
+	for _, signature := range signatures {
+		if signature == nil {
+			continue
+		}
+		if signature.BlockTime != nil && signature.BlockTime.Time().Before(since) {
+			// Signatures are ordered newest first, the rest are older still
+			break
+		}
+		recent = append(recent, signature)
+	}
+	return recent, nil
+}
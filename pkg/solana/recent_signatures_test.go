@@ -0,0 +1,41 @@
+package solana
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSignatureClient returns fixed signatures for every address
+type fakeSignatureClient struct {
+	signatures []*rpc.TransactionSignature
+}
+
+func (f *fakeSignatureClient) GetSignaturesForAddressWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error) {
+	return f.signatures, nil
+}
+
+func blockTime(t time.Time) *solana.UnixTimeSeconds {
+	seconds := solana.UnixTimeSeconds(t.Unix())
+	return &seconds
+}
+
+func TestRecentSignaturesStopsAtOlderTransactions(t *testing.T) {
+	now := time.Now()
+	pending := &rpc.TransactionSignature{Signature: solana.Signature{1}}
+	recent := &rpc.TransactionSignature{Signature: solana.Signature{2}, BlockTime: blockTime(now.Add(-30 * time.Second))}
+	old := &rpc.TransactionSignature{Signature: solana.Signature{3}, BlockTime: blockTime(now.Add(-time.Hour))}
+	client := &fakeSignatureClient{signatures: []*rpc.TransactionSignature{pending, recent, old}}
+
+	signatures, err := RecentSignatures(context.Background(), client, solana.NewWallet().PublicKey(), now.Add(-2*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, []*rpc.TransactionSignature{pending, recent}, signatures)
+
+	signatures, err = RecentSignatures(context.Background(), client, solana.NewWallet().PublicKey(), now)
+	assert.NoError(t, err)
+	assert.Equal(t, []*rpc.TransactionSignature{pending}, signatures)
+}
@@ -0,0 +1,36 @@
+package solana
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+
+	"boop-airdrop-redeemer/pkg/retry"
+)
+
+// rpcTimeout bounds each RPC request with its retries, the timeout of rpc.New
+const rpcTimeout = 5 * time.Minute
+
+// RPCClient is the subset of the Solana RPC API used to build, send and inspect transactions.
+// *rpc.Client implements it, so alternative implementations (multi-RPC, mocks) can be substituted.
+type RPCClient interface {
+	GetLatestBlockhash(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error)
+	SendTransactionWithOpts(ctx context.Context, transaction *solana.Transaction, opts rpc.TransactionOpts) (solana.Signature, error)
+	GetParsedTransaction(ctx context.Context, txSig solana.Signature, opts *rpc.GetParsedTransactionOpts) (*rpc.GetParsedTransactionResult, error)
+	GetSignatureStatuses(ctx context.Context, searchTransactionHistory bool, transactionSignatures ...solana.Signature) (*rpc.GetSignatureStatusesResult, error)
+	GetBlockHeight(ctx context.Context, commitment rpc.CommitmentType) (uint64, error)
+	SimulateTransactionWithOpts(ctx context.Context, transaction *solana.Transaction, opts *rpc.SimulateTransactionOpts) (*rpc.SimulateTransactionResponse, error)
+}
+
+var _ RPCClient = (*rpc.Client)(nil)
+
+// NewRPCClient creates an RPC client for url whose requests are retried on network, rate limit and server
+// errors. Sending a signed transaction again cannot execute it twice, so sends are retried too.
+func NewRPCClient(url string, retrier *retry.Retrier) *rpc.Client {
+	httpClient := &http.Client{Timeout: rpcTimeout, Transport: retry.NewIdempotentTransport(nil, retrier)}
+	return rpc.NewWithCustomRPCClient(jsonrpc.NewClientWithOpts(url, &jsonrpc.RPCClientOpts{HTTPClient: httpClient}))
+}
@@ -0,0 +1,252 @@
+package solana
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// StatsSchemaVersion is the version of the stats directory layout written by this build
+const StatsSchemaVersion = 4
+
+// schemaFile holds the schema manifest of a stats directory
+const schemaFile = "schema.json"
+
+// schemaManifest records which schema version a stats directory uses.
+// The checksum detects a hand-edited or truncated manifest.
+type schemaManifest struct {
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Checksum  string    `json:"checksum"`
+}
+
+// statsMigration upgrades a stats directory from one schema version to the next
+type statsMigration struct {
+	description string
+	apply       func(dataDir string) error
+}
+
+// statsMigrations lists the migrations by the version they upgrade from
+var statsMigrations = map[int]statsMigration{
+	1: {"add Source column to transaction files", migrateTransactionSourceColumn},
+	2: {"add Operation column to transaction files", migrateTransactionOperationColumn},
+	3: {"add Rent column to transaction files", migrateTransactionRentColumn},
+}
+
+// migrateStatsDir brings a stats directory up to StatsSchemaVersion, backing up the files first.
+// Directories written by a newer build are refused instead of being rewritten.
+func migrateStatsDir(dataDir string) (int, error) {
+	version, err := readSchemaVersion(dataDir)
+	if err != nil {
+		return 0, err
+	}
+
+	if version > StatsSchemaVersion {
+		return version, fmt.Errorf("stats in %s use schema version %d, newer than supported version %d", dataDir, version, StatsSchemaVersion)
+	}
+	if version == StatsSchemaVersion {
+		return version, nil
+	}
+
+	// A new directory starts at the current version
+	if version == 0 {
+		return StatsSchemaVersion, writeSchemaVersion(dataDir, StatsSchemaVersion)
+	}
+
+	if err := backupStatsDir(dataDir, version); err != nil {
+		return version, err
+	}
+
+	for ; version < StatsSchemaVersion; version++ {
+		migration, ok := statsMigrations[version]
+		if !ok {
+			return version, fmt.Errorf("no migration from stats schema version %d", version)
+		}
+		if err := migration.apply(dataDir); err != nil {
+			return version, fmt.Errorf("failed to %s: %w", migration.description, err)
+		}
+		// Record each step so an interrupted upgrade resumes where it stopped
+		if err := writeSchemaVersion(dataDir, version+1); err != nil {
+			return version, err
+		}
+	}
+
+	return version, nil
+}
+
+// readSchemaVersion returns the schema version of a stats directory.
+// Directories without a manifest are version 1 when they already hold stats, 0 when empty.
+func readSchemaVersion(dataDir string) (int, error) {
+	content, err := os.ReadFile(filepath.Join(dataDir, schemaFile))
+	if os.IsNotExist(err) {
+		files, err := filepath.Glob(filepath.Join(dataDir, "*.csv"))
+		if err != nil {
+			return 0, fmt.Errorf("failed to list stats files: %w", err)
+		}
+		if len(files) > 0 {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read stats schema: %w", err)
+	}
+
+	var manifest schemaManifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return 0, fmt.Errorf("failed to parse stats schema: %w", err)
+	}
+	if manifest.Checksum != manifestChecksum(manifest) {
+		return 0, fmt.Errorf("stats schema manifest %s failed its checksum", filepath.Join(dataDir, schemaFile))
+	}
+
+	return manifest.Version, nil
+}
+
+// writeSchemaVersion writes the schema manifest atomically
+func writeSchemaVersion(dataDir string, version int) error {
+	manifest := schemaManifest{
+		Version:   version,
+		UpdatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	manifest.Checksum = manifestChecksum(manifest)
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode stats schema: %w", err)
+	}
+
+	path := filepath.Join(dataDir, schemaFile)
+	if err := os.WriteFile(path+".tmp", content, 0644); err != nil {
+		return fmt.Errorf("failed to write stats schema: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to replace stats schema: %w", err)
+	}
+	return nil
+}
+
+// manifestChecksum hashes the manifest fields other than the checksum itself
+func manifestChecksum(manifest schemaManifest) string {
+	sum := sha256.Sum256([]byte(strconv.Itoa(manifest.Version) + "|" + manifest.UpdatedAt.UTC().Format(time.RFC3339)))
+	return hex.EncodeToString(sum[:])
+}
+
+// backupStatsDir copies every stats file into backups/<time>-v<version> before a migration
+func backupStatsDir(dataDir string, version int) error {
+	files, err := filepath.Glob(filepath.Join(dataDir, "*.csv"))
+	if err != nil {
+		return fmt.Errorf("failed to list stats files: %w", err)
+	}
+
+	backupDir := filepath.Join(dataDir, "backups", fmt.Sprintf("%s-v%d", time.Now().Format("20060102-150405"), version))
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	for _, path := range files {
+		if err := copyFile(path, filepath.Join(backupDir, filepath.Base(path))); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", filepath.Base(path), err)
+		}
+	}
+	return nil
+}
+
+// copyFile copies a file, syncing the copy to disk
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// migrateTransactionSourceColumn rewrites transaction files from before the Source column,
+// marking their records as measured
+func migrateTransactionSourceColumn(dataDir string) error {
+	return addTransactionColumn(dataDir, 9, sourceMeasured)
+}
+
+// migrateTransactionOperationColumn rewrites transaction files from before the Operation column, their records
+// belong to no operation
+func migrateTransactionOperationColumn(dataDir string) error {
+	return addTransactionColumn(dataDir, 10, "")
+}
+
+// migrateTransactionRentColumn rewrites transaction files from before the Rent column, rent was not accounted
+// for then
+func migrateTransactionRentColumn(dataDir string) error {
+	return addTransactionColumn(dataDir, 11, formatSignedSol(0))
+}
+
+// addTransactionColumn rewrites transaction files to the first columns of the current header, appending value
+// to records one column short. Values are copied verbatim so no precision is lost.
+func addTransactionColumn(dataDir string, columns int, value string) error {
+	files, err := filepath.Glob(filepath.Join(dataDir, "transactions_*.csv"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range files {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		reader := csv.NewReader(file)
+		reader.FieldsPerRecord = -1
+		records, err := reader.ReadAll()
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+		}
+
+		for i, record := range records {
+			switch {
+			case i == 0:
+				records[i] = transactionFileHeader[:columns]
+			case len(record) == columns-1:
+				records[i] = append(record, value)
+			}
+		}
+
+		tmpPath := path + ".tmp"
+		out, err := os.Create(tmpPath)
+		if err != nil {
+			return err
+		}
+		writer := csv.NewWriter(out)
+		writer.WriteAll(records)
+		if err := writer.Error(); err != nil {
+			out.Close()
+			return err
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+		if err := os.Rename(tmpPath, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
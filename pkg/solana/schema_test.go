@@ -0,0 +1,63 @@
+package solana
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsMigrationAddsSourceColumn(t *testing.T) {
+	dir := t.TempDir()
+	legacy := "Timestamp,Type,Token,Amount,Expenses (SOL),Gross Profit (SOL),Net Profit (SOL),Transaction Hash\n" +
+		"2025-05-01T10:00:00Z,SWAP,TEST,1000,0.000005000,0.100000000,0.099995000,legacy-tx\n"
+	path := filepath.Join(dir, "transactions_2025-05.csv")
+	assert.NoError(t, os.WriteFile(path, []byte(legacy), 0644))
+
+	recorder, err := NewStatsRecorder(dir)
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), ",Source,Operation,Rent (SOL)\n")
+	assert.Contains(t, string(content), "0.099995000,legacy-tx,measured,,0.000000000\n")
+
+	stats, err := recorder.GetTransactions(time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, stats, 1)
+	assert.False(t, stats[0].Estimated)
+
+	// The original file is kept in a backup
+	backups, err := filepath.Glob(filepath.Join(dir, "backups", "*-v1", "transactions_2025-05.csv"))
+	assert.NoError(t, err)
+	assert.Len(t, backups, 1)
+
+	version, err := readSchemaVersion(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, StatsSchemaVersion, version)
+}
+
+func TestStatsFromNewerSchemaAreRefused(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, writeSchemaVersion(dir, StatsSchemaVersion+1))
+
+	_, err := NewStatsRecorder(dir)
+	assert.Error(t, err)
+}
+
+func TestStatsSchemaManifestChecksum(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, writeSchemaVersion(dir, StatsSchemaVersion))
+
+	path := filepath.Join(dir, schemaFile)
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	tampered := strings.Replace(string(content), `"version": 4`, `"version": 3`, 1)
+	assert.NoError(t, os.WriteFile(path, []byte(tampered), 0644))
+
+	_, err = readSchemaVersion(dir)
+	assert.Error(t, err)
+}
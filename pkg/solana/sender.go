@@ -0,0 +1,188 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"boop-airdrop-redeemer/pkg/logging"
+)
+
+// SendStage is a step in sending a transaction, reported to the status callback of a Sender
+type SendStage string
+
+const (
+	// StageSent is the first broadcast of a transaction
+	StageSent SendStage = "sent"
+	// StageRebroadcast is a repeated broadcast of a transaction that has not landed yet
+	StageRebroadcast SendStage = "rebroadcast"
+	// StageConfirmed means the transaction landed and succeeded
+	StageConfirmed SendStage = "confirmed"
+	// StageFailed means the transaction landed but its execution failed
+	StageFailed SendStage = "failed"
+	// StageExpired means the blockhash expired without the transaction landing
+	StageExpired SendStage = "expired"
+	// StageRebuilding means an expired transaction is built again with a fresh blockhash
+	StageRebuilding SendStage = "rebuilding"
+)
+
+// SendEvent reports the progress of a transaction to the status callback
+type SendEvent struct {
+	Stage      SendStage
+	Attempt    int // Build attempt, starting at 1
+	Signature  solana.Signature
+	Broadcasts int   // Times the transaction was broadcast so far
+	Err        error // Send error of a broadcast, or the execution error of a failed transaction
+}
+
+// BuildFunc builds and signs a transaction with a fresh blockhash, returning it with the block height after
+// which it can no longer land
+type BuildFunc func(ctx context.Context) (*solana.Transaction, uint64, error)
+
+// Sender broadcasts signed transactions and keeps rebroadcasting them until they land or their blockhash
+// expires, since RPC nodes drop transactions under load and a single send often never reaches the leader
+type Sender struct {
+	Client      RPCClient
+	Opts        rpc.TransactionOpts // Options of the first broadcast, rebroadcasts always skip preflight
+	Rebroadcast time.Duration       // Interval between broadcasts, 0 sends each transaction once
+	OnStatus    func(SendEvent)     // Called on each stage, rebroadcasts are reported from another goroutine
+}
+
+// NewSender creates a sender rebroadcasting every interval
+func NewSender(client RPCClient, opts rpc.TransactionOpts, rebroadcast time.Duration, onStatus func(SendEvent)) Sender {
+	return Sender{Client: client, Opts: opts, Rebroadcast: rebroadcast, OnStatus: onStatus}
+}
+
+// SendAndAwait broadcasts a signed transaction until it is confirmed, failed, or its blockhash has expired at
+// lastValidBlockHeight, see SendAndAwaitLanding
+func (s Sender) SendAndAwait(ctx context.Context, tx *solana.Transaction, lastValidBlockHeight uint64) (Landing, error) {
+	return s.sendAndAwait(ctx, 1, tx, lastValidBlockHeight)
+}
+
+// SendUntilLanded builds and sends a transaction, building it again with a fresh blockhash each time it
+// expires without landing, up to maxAttempts builds. A landed transaction is never rebuilt, whether it
+// succeeded or failed. The landing of the last attempt is returned.
+func (s Sender) SendUntilLanded(ctx context.Context, maxAttempts int, build BuildFunc) (Landing, error) {
+	var landing Landing
+	for attempt := 1; attempt <= max(maxAttempts, 1); attempt++ {
+		if attempt > 1 {
+			s.report(SendEvent{Stage: StageRebuilding, Attempt: attempt, Signature: landing.Signature})
+		}
+
+		tx, lastValidBlockHeight, err := build(ctx)
+		if err != nil {
+			return landing, fmt.Errorf("failed to build transaction: %w", err)
+		}
+
+		landing, err = s.sendAndAwait(ctx, attempt, tx, lastValidBlockHeight)
+		if err != nil || landing.Status != LandingExpired {
+			return landing, err
+		}
+	}
+	return landing, nil
+}
+
+// sendAndAwait broadcasts the transaction, rebroadcasting it while waiting for it to land
+func (s Sender) sendAndAwait(ctx context.Context, attempt int, tx *solana.Transaction, lastValidBlockHeight uint64) (Landing, error) {
+	if len(tx.Signatures) == 0 {
+		return Landing{}, fmt.Errorf("transaction is not signed")
+	}
+
+	landing := Landing{Signature: tx.Signatures[0]}
+	var broadcasts atomic.Int64
+
+	_, err := s.Client.SendTransactionWithOpts(ctx, tx, s.Opts)
+	landing.SendErr = err
+	broadcasts.Add(1)
+	s.report(SendEvent{Stage: StageSent, Attempt: attempt, Signature: landing.Signature, Broadcasts: 1, Err: err})
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	if s.Rebroadcast > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.rebroadcast(ctx, stop, attempt, tx, &broadcasts)
+		}()
+	}
+
+	status, txErr, err := AwaitLanding(ctx, s.Client, landing.Signature, lastValidBlockHeight)
+	// No rebroadcast is reported after the outcome
+	close(stop)
+	wg.Wait()
+	if err != nil {
+		return landing, err
+	}
+	landing.Status = status
+	landing.TxErr = txErr
+
+	event := SendEvent{Attempt: attempt, Signature: landing.Signature, Broadcasts: int(broadcasts.Load())}
+	switch status {
+	case LandingConfirmed:
+		event.Stage = StageConfirmed
+	case LandingFailed:
+		event.Stage = StageFailed
+		event.Err = fmt.Errorf("%v", txErr)
+	default:
+		event.Stage = StageExpired
+	}
+	s.report(event)
+
+	return landing, nil
+}
+
+// rebroadcast sends the transaction again every interval until stopped
+func (s Sender) rebroadcast(ctx context.Context, stop <-chan struct{}, attempt int, tx *solana.Transaction, broadcasts *atomic.Int64) {
+	ticker := time.NewTicker(s.Rebroadcast)
+	defer ticker.Stop()
+
+	// Preflight would reject a transaction the cluster has already processed
+	opts := rpc.TransactionOpts{SkipPreflight: true}
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		_, err := s.Client.SendTransactionWithOpts(ctx, tx, opts)
+		s.report(SendEvent{Stage: StageRebroadcast, Attempt: attempt, Signature: tx.Signatures[0], Broadcasts: int(broadcasts.Add(1)), Err: err})
+	}
+}
+
+func (s Sender) report(event SendEvent) {
+	if s.OnStatus != nil {
+		s.OnStatus(event)
+	}
+}
+
+// LogSendStatus returns a status callback logging each stage of sending a transaction of the given kind, such
+// as "Claim". Rebroadcasts are not logged one by one, the final stage tells how often the transaction was sent.
+func LogSendStatus(logger *log.Logger, kind string) func(SendEvent) {
+	return func(event SendEvent) {
+		switch event.Stage {
+		case StageSent:
+			if event.Err != nil {
+				logger.Printf("%s transaction %s sent (attempt %d), send error: %v", kind, event.Signature, event.Attempt, event.Err)
+			} else {
+				logger.Printf("%s transaction %s sent (attempt %d)", kind, event.Signature, event.Attempt)
+			}
+		case StageConfirmed:
+			logger.Printf("%s transaction %s confirmed after %d broadcast(s)", kind, event.Signature, event.Broadcasts)
+		case StageFailed:
+			logging.Warnf(logger, "%s transaction %s failed after %d broadcast(s): %v", kind, event.Signature, event.Broadcasts, event.Err)
+		case StageExpired:
+			logger.Printf("%s transaction %s expired without landing after %d broadcast(s)", kind, event.Signature, event.Broadcasts)
+		case StageRebuilding:
+			logger.Printf("%s transaction expired, rebuilding it with a fresh blockhash (attempt %d)", kind, event.Attempt)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package solana
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// transactionMigrations are applied in order, each in its own transaction. Append new migrations, never edit applied ones.
+var transactionMigrations = []string{
+	// 1: transaction records, amounts in lamports and timestamps in unix seconds
+	`CREATE TABLE transactions (
+		id           INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp    INTEGER NOT NULL,
+		type         TEXT NOT NULL,
+		token        TEXT NOT NULL,
+		amount       TEXT NOT NULL,
+		expenses     INTEGER NOT NULL,
+		gross_profit INTEGER NOT NULL,
+		net_profit   INTEGER NOT NULL,
+		tx_hash      TEXT NOT NULL,
+		estimated    INTEGER NOT NULL
+	);
+	CREATE INDEX transactions_timestamp ON transactions (timestamp);
+	CREATE INDEX transactions_tx_hash ON transactions (tx_hash, type)`,
+	// 2: claim or sale operation a transaction belongs to
+	`ALTER TABLE transactions ADD COLUMN operation_id TEXT NOT NULL DEFAULT ''`,
+	// 3: rent paid less rent reclaimed, negative when more was reclaimed
+	`ALTER TABLE transactions ADD COLUMN rent INTEGER NOT NULL DEFAULT 0`,
+}
+
+// sqliteTransactionStore keeps transaction records in a SQLite database indexed by time and hash, so
+// summaries read only the period they cover
+type sqliteTransactionStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteTransactionStore opens (or creates) the SQLite database at path and applies pending migrations
+func NewSQLiteTransactionStore(path string) (TransactionStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create stats directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transaction store: %w", err)
+	}
+	// A single connection serializes writers and keeps the WAL setting on every statement
+	db.SetMaxOpenConns(1)
+
+	if err := migrateTransactionStore(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &sqliteTransactionStore{db: db}, nil
+}
+
+// migrateTransactionStore applies the migrations the database has not seen yet
+func migrateTransactionStore(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, applied_at TEXT NOT NULL)`); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	var version int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("failed to read transaction store version: %w", err)
+	}
+	if version > len(transactionMigrations) {
+		return fmt.Errorf("transaction store version %d is newer than supported version %d", version, len(transactionMigrations))
+	}
+
+	for i := version; i < len(transactionMigrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to start migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(transactionMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, i+1, time.Now().Format(time.RFC3339)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// Append inserts the record
+func (s *sqliteTransactionStore) Append(stats TransactionStats) error {
+	_, err := s.db.Exec(`INSERT INTO transactions
+		(timestamp, type, token, amount, expenses, gross_profit, net_profit, tx_hash, estimated, operation_id, rent)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		stats.Timestamp.Unix(), string(stats.TxType), stats.TokenSymbol, stats.TokenAmount,
+		int64(stats.Expenses), int64(stats.GrossProfit), int64(stats.NetProfit), stats.TxHash, stats.Estimated,
+		stats.OperationID, stats.Rent)
+	if err != nil {
+		return fmt.Errorf("failed to record transaction %s: %w", stats.TxHash, err)
+	}
+	return nil
+}
+
+// Since queries the records at or after since through the timestamp index
+func (s *sqliteTransactionStore) Since(since time.Time) ([]TransactionStats, error) {
+	rows, err := s.db.Query(`SELECT timestamp, type, token, amount, expenses, gross_profit, net_profit, tx_hash, estimated,
+		operation_id, rent FROM transactions WHERE timestamp >= ? ORDER BY timestamp, id`, since.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var records []TransactionStats
+	for rows.Next() {
+		var (
+			stats                            TransactionStats
+			timestamp                        int64
+			txType                           string
+			expenses, grossProfit, netProfit int64
+		)
+		if err := rows.Scan(&timestamp, &txType, &stats.TokenSymbol, &stats.TokenAmount,
+			&expenses, &grossProfit, &netProfit, &stats.TxHash, &stats.Estimated, &stats.OperationID, &stats.Rent); err != nil {
+			return nil, fmt.Errorf("failed to read transaction: %w", err)
+		}
+		stats.Timestamp = time.Unix(timestamp, 0)
+		stats.TxType = TransactionType(txType)
+		stats.Expenses = uint64(expenses)
+		stats.GrossProfit = uint64(grossProfit)
+		stats.NetProfit = uint64(netProfit)
+		records = append(records, stats)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read transactions: %w", err)
+	}
+	return records, nil
+}
+
+// Replace updates the matching records in one database transaction
+func (s *sqliteTransactionStore) Replace(updated []TransactionStats) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start update: %w", err)
+	}
+	for _, stats := range updated {
+		_, err := tx.Exec(`UPDATE transactions SET timestamp = ?, token = ?, amount = ?, expenses = ?,
+			gross_profit = ?, net_profit = ?, rent = ?, estimated = ? WHERE tx_hash = ? AND type = ?`,
+			stats.Timestamp.Unix(), stats.TokenSymbol, stats.TokenAmount, int64(stats.Expenses),
+			int64(stats.GrossProfit), int64(stats.NetProfit), stats.Rent, stats.Estimated, stats.TxHash, string(stats.TxType))
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to update transaction %s: %w", stats.TxHash, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit update: %w", err)
+	}
+	return nil
+}
+
+// Hashes reads the recorded hashes from the hash index
+func (s *sqliteTransactionStore) Hashes() (map[string]bool, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT tx_hash FROM transactions`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transaction hashes: %w", err)
+	}
+	defer rows.Close()
+
+	hashes := make(map[string]bool)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("failed to read transaction hash: %w", err)
+		}
+		hashes[hash] = true
+	}
+	return hashes, rows.Err()
+}
+
+// Close closes the database
+func (s *sqliteTransactionStore) Close() error {
+	return s.db.Close()
+}
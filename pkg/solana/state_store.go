@@ -0,0 +1,95 @@
+package solana
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// StateStore keeps one JSON encoded state document, such as the sale failures of the circuit breaker
+type StateStore interface {
+	// Load decodes the saved state into v, false when nothing was saved yet
+	Load(v any) (bool, error)
+	// Save replaces the saved state with v
+	Save(v any) error
+}
+
+// fileState keeps the state in a JSON file, replaced atomically on save
+type fileState struct {
+	path string
+}
+
+// NewFileState returns a state kept in the JSON file at path
+func NewFileState(path string) StateStore {
+	return fileState{path: path}
+}
+
+// Load reads the state file
+func (f fileState) Load(v any) (bool, error) {
+	content, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(content, v)
+}
+
+// Save writes the state to a temporary file and renames it over the state file
+func (f fileState) Save(v any) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return err
+	}
+	tmpPath := f.path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, f.path)
+}
+
+// boltState keeps the state under its name in the state bucket of the bolt backend
+type boltState struct {
+	db   *bolt.DB
+	name []byte
+}
+
+// Load reads the state from the database
+func (b boltState) Load(v any) (bool, error) {
+	var content []byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		content = append(content, tx.Bucket(boltStateBucket).Get(b.name)...)
+		return nil
+	})
+	if err != nil || content == nil {
+		return false, err
+	}
+	return true, json.Unmarshal(content, v)
+}
+
+// Save writes the state to the database
+func (b boltState) Save(v any) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltStateBucket).Put(b.name, content)
+	})
+}
+
+// State returns the state saved under name: in the database of the bolt backend, otherwise in name.json of
+// the data directory
+func (s *StatsRecorder) State(name string) StateStore {
+	if s.bolt != nil {
+		return boltState{db: s.bolt, name: []byte(name)}
+	}
+	return NewFileState(filepath.Join(s.dataDir, fmt.Sprintf("%s.json", name)))
+}
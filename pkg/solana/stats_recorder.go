@@ -1,14 +1,19 @@
 package solana
 
 import (
+	"context"
 	"encoding/csv"
 	"fmt"
 	"os"
-	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"boop-airdrop-redeemer/pkg/amount"
 )
 
 // TransactionType represents the type of transaction
@@ -19,6 +24,14 @@ const (
 	TypeClaim TransactionType = "CLAIM"
 	// TypeSwap represents a swap/sell transaction
 	TypeSwap TransactionType = "SWAP"
+	// TypeServiceFee represents a service fee transfer to the operator wallet
+	TypeServiceFee TransactionType = "SERVICE_FEE"
+	// TypeVaultTransfer represents a transfer of kept tokens to the vault wallet
+	TypeVaultTransfer TransactionType = "VAULT_TRANSFER"
+	// TypePlatformFee represents a Jupiter platform fee collected on a swap
+	TypePlatformFee TransactionType = "PLATFORM_FEE"
+	// TypeRentReclaim represents closing empty token accounts to recover their rent
+	TypeRentReclaim TransactionType = "RENT_RECLAIM"
 )
 
 // TransactionStats stores statistics for a transaction
@@ -31,6 +44,22 @@ type TransactionStats struct {
 	NetProfit   uint64 // gross profit - expenses (for swaps)
 	TxHash      string
 	TxType      TransactionType
+	Estimated   bool   // Fees/earnings were estimated rather than read from the chain
+	OperationID string // Claim or sale the transaction belongs to, empty for other transactions
+	Rent        int64  // Rent paid for created accounts less rent reclaimed from closed ones, in lamports
+}
+
+// Values of the Source column in transaction files
+const (
+	sourceMeasured  = "measured"
+	sourceEstimated = "estimated"
+)
+
+// transactionFileHeader is the header row of the monthly transaction files
+var transactionFileHeader = []string{
+	"Timestamp", "Type", "Token", "Amount",
+	"Expenses (SOL)", "Gross Profit (SOL)", "Net Profit (SOL)",
+	"Transaction Hash", "Source", "Operation", "Rent (SOL)",
 }
 
 // ProfitSummary contains summary profit statistics
@@ -38,28 +67,65 @@ type ProfitSummary struct {
 	Last24h       float64 // Profit in SOL for last 24 hours
 	LastWeek      float64 // Profit in SOL for last week
 	ProjectedWeek float64 // Projected weekly profit based on recent performance
+	Estimated     int     // Number of estimated swap records in the last week
 }
 
-// StatsRecorder handles recording transaction statistics
+// StatsRecorder handles recording transaction statistics. Transactions go to a pluggable store. With the
+// bolt backend airdrop values, decisions and saved state go to the same database, otherwise they stay in files
+// of the data directory like outcomes and compute units.
 type StatsRecorder struct {
-	dataDir string
-	mu      sync.Mutex
+	dataDir      string
+	transactions TransactionStore
+	bolt         *bolt.DB            // The database of the bolt backend, nil with other backends
+	computeUnits map[string][]uint64 // Recent compute unit samples by operation, read from their file once
+	mu           sync.Mutex
 }
 
-// NewStatsRecorder creates a new statistics recorder
+// NewStatsRecorder creates a new statistics recorder keeping transactions in monthly CSV files
 func NewStatsRecorder(dataDir string) (*StatsRecorder, error) {
+	return NewStatsRecorderWithBackend(dataDir, StatsBackendCSV)
+}
+
+// NewStatsRecorderWithBackend creates a new statistics recorder keeping transactions in the given backend
+func NewStatsRecorderWithBackend(dataDir, backend string) (*StatsRecorder, error) {
 	// Create data directory if it doesn't exist
 	if err := os.MkdirAll(dataDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create data directory: %w", err)
 	}
 
-	return &StatsRecorder{
-		dataDir: dataDir,
-	}, nil
+	// Upgrade files written by older versions before anything reads or appends to them
+	if _, err := migrateStatsDir(dataDir); err != nil {
+		return nil, fmt.Errorf("failed to migrate stats: %w", err)
+	}
+
+	transactions, err := OpenTransactionStore(dataDir, backend)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder := &StatsRecorder{
+		dataDir:      dataDir,
+		transactions: transactions,
+	}
+	if store, ok := transactions.(*boltTransactionStore); ok {
+		recorder.bolt = store.db
+	}
+	return recorder, nil
+}
+
+// Close releases the transaction store
+func (s *StatsRecorder) Close() error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.transactions.Close()
 }
 
-// RecordClaimStats records statistics for a claim transaction
-func (s *StatsRecorder) RecordClaimStats(tokenSymbol, tokenAmount string, fees uint64, txHash string) error {
+// RecordClaimStats records statistics for a claim transaction, its rent counts against the profit
+func (s *StatsRecorder) RecordClaimStats(tokenSymbol, tokenAmount string, fees uint64, rent int64, txHash, operationID string) error {
 	return s.recordStats(TransactionStats{
 		Timestamp:   time.Now(),
 		TokenSymbol: tokenSymbol,
@@ -69,11 +135,110 @@ func (s *StatsRecorder) RecordClaimStats(tokenSymbol, tokenAmount string, fees u
 		NetProfit:   0,
 		TxHash:      txHash,
 		TxType:      TypeClaim,
+		OperationID: operationID,
+		Rent:        rent,
 	})
 }
 
 // RecordSwapStats records statistics for a swap transaction
-func (s *StatsRecorder) RecordSwapStats(tokenSymbol, tokenAmount string, fees, earnings uint64, txHash string) error {
+func (s *StatsRecorder) RecordSwapStats(tokenSymbol, tokenAmount string, fees, earnings uint64, rent int64, txHash, operationID string) error {
+	return s.recordStats(TransactionStats{
+		Timestamp:   time.Now(),
+		TokenSymbol: tokenSymbol,
+		TokenAmount: tokenAmount,
+		Expenses:    fees,
+		GrossProfit: earnings,
+		NetProfit:   swapNetProfit(earnings, fees, rent),
+		TxHash:      txHash,
+		TxType:      TypeSwap,
+		OperationID: operationID,
+		Rent:        rent,
+	})
+}
+
+// swapNetProfit returns the earnings of a swap less its fees and rent, zero when they exceed the earnings
+func swapNetProfit(earnings, fees uint64, rent int64) uint64 {
+	netProfit := int64(earnings) - int64(fees) - rent
+	if netProfit < 0 {
+		return 0
+	}
+	return uint64(netProfit)
+}
+
+// RecordServiceFeeStats records a service fee transfer, the fee and its transaction fees count as expenses
+func (s *StatsRecorder) RecordServiceFeeStats(tokenSymbol string, serviceFee, fees uint64, txHash, operationID string) error {
+	return s.recordStats(TransactionStats{
+		Timestamp:   time.Now(),
+		TokenSymbol: tokenSymbol,
+		Expenses:    serviceFee + fees,
+		TxHash:      txHash,
+		TxType:      TypeServiceFee,
+		OperationID: operationID,
+	})
+}
+
+// RecordPlatformFeeStats records a Jupiter platform fee collected into the operator's fee account.
+// The swap's earnings exclude the fee, so it is counted as profit of its own.
+func (s *StatsRecorder) RecordPlatformFeeStats(tokenSymbol string, fee uint64, txHash, operationID string) error {
+	return s.recordStats(TransactionStats{
+		Timestamp:   time.Now(),
+		TokenSymbol: tokenSymbol,
+		GrossProfit: fee,
+		NetProfit:   fee,
+		TxHash:      txHash,
+		TxType:      TypePlatformFee,
+		OperationID: operationID,
+	})
+}
+
+// RecordVaultTransferStats records a transfer of kept tokens to the vault wallet, its fees count as expenses
+func (s *StatsRecorder) RecordVaultTransferStats(tokenSymbol, tokenAmount string, fees uint64, txHash string) error {
+	return s.recordStats(TransactionStats{
+		Timestamp:   time.Now(),
+		TokenSymbol: tokenSymbol,
+		TokenAmount: tokenAmount,
+		Expenses:    fees,
+		TxHash:      txHash,
+		TxType:      TypeVaultTransfer,
+	})
+}
+
+// RecordRentReclaimStats records closing empty token accounts. rent is negative for the lamports recovered,
+// which count as profit less the transaction fees.
+func (s *StatsRecorder) RecordRentReclaimStats(accounts int, fees uint64, rent int64, txHash string) error {
+	return s.recordStats(TransactionStats{
+		Timestamp:   time.Now(),
+		TokenAmount: strconv.Itoa(accounts),
+		Expenses:    fees,
+		TxHash:      txHash,
+		TxType:      TypeRentReclaim,
+		Rent:        rent,
+	})
+}
+
+// GetVaultTransfers returns the vault transfers recorded since the given time
+func (s *StatsRecorder) GetVaultTransfers(since time.Time) ([]TransactionStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, err := s.transactions.Since(since)
+	if err != nil {
+		return nil, err
+	}
+
+	var transfers []TransactionStats
+	for _, stat := range stats {
+		if stat.TxType == TypeVaultTransfer && stat.Timestamp.After(since) {
+			transfers = append(transfers, stat)
+		}
+	}
+
+	return transfers, nil
+}
+
+// RecordEstimatedSwapStats records a swap whose fees and earnings could not be read from the chain.
+// Such records are excluded from profit reports by default and replaced by ReconcileEstimates.
+func (s *StatsRecorder) RecordEstimatedSwapStats(tokenSymbol, tokenAmount string, fees, earnings uint64, txHash, operationID string) error {
 	netProfit := int64(earnings) - int64(fees)
 	if netProfit < 0 {
 		netProfit = 0
@@ -88,23 +253,132 @@ func (s *StatsRecorder) RecordSwapStats(tokenSymbol, tokenAmount string, fees, e
 		NetProfit:   uint64(netProfit),
 		TxHash:      txHash,
 		TxType:      TypeSwap,
+		Estimated:   true,
+		OperationID: operationID,
 	})
 }
 
+// RecordEstimatedClaimStats records a claim whose fees could not be read from the chain, to be replaced by
+// ReconcileEstimates like estimated swaps.
+func (s *StatsRecorder) RecordEstimatedClaimStats(tokenSymbol, tokenAmount string, fees uint64, txHash, operationID string) error {
+	return s.recordStats(TransactionStats{
+		Timestamp:   time.Now(),
+		TokenSymbol: tokenSymbol,
+		TokenAmount: tokenAmount,
+		Expenses:    fees,
+		TxHash:      txHash,
+		TxType:      TypeClaim,
+		Estimated:   true,
+		OperationID: operationID,
+	})
+}
+
+// Bounds of one reconciliation run, it runs every cycle and must not hold up claims or flood the RPC node
+const (
+	reconcileWindow = 7 * 24 * time.Hour     // Older estimates are left as they are, nodes may no longer serve them
+	reconcileBatch  = 20                     // Records looked up per run, newest first
+	reconcilePause  = 250 * time.Millisecond // Between two lookups
+)
+
+// ReconcileEstimates replaces estimated claim and swap records of the last week with fees, earnings and rent read
+// from the chain, at most reconcileBatch of them per call. Records whose transactions cannot be fetched yet are
+// left as estimates. Returns the number of records updated. Stops early, keeping the records reconciled so far,
+// when ctx is cancelled. The transactions are fetched without holding the recorder, so records are written
+// meanwhile.
+func (s *StatsRecorder) ReconcileEstimates(ctx context.Context, solClient RPCClient) (int, error) {
+	if s == nil {
+		return 0, fmt.Errorf("stats recorder is not initialized")
+	}
+
+	s.mu.Lock()
+	stats, err := s.transactions.Since(time.Now().Add(-reconcileWindow))
+	s.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	var estimated []TransactionStats
+	for i := len(stats) - 1; i >= 0 && len(estimated) < reconcileBatch; i-- {
+		if stat := stats[i]; stat.Estimated && (stat.TxType == TypeSwap || stat.TxType == TypeClaim) {
+			estimated = append(estimated, stat)
+		}
+	}
+
+	var updated []TransactionStats
+	for i, stat := range estimated {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(reconcilePause):
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		isSwap := stat.TxType == TypeSwap
+		result, err := GetTransactionResult(solClient, stat.TxHash, isSwap)
+		if err != nil {
+			continue
+		}
+
+		stat.Expenses = result.Fee
+		stat.Rent = result.Rent
+		if isSwap {
+			stat.GrossProfit = result.Earnings
+			stat.NetProfit = swapNetProfit(result.Earnings, result.Fee, result.Rent)
+		}
+		stat.Estimated = false
+		updated = append(updated, stat)
+	}
+
+	if len(updated) > 0 {
+		s.mu.Lock()
+		err := s.transactions.Replace(updated)
+		s.mu.Unlock()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(updated), ctx.Err()
+}
+
+// RecordTransaction records a fully populated transaction, e.g. one rebuilt during recovery.
+// The record is written to the monthly file matching its own timestamp.
+func (s *StatsRecorder) RecordTransaction(stats TransactionStats) error {
+	if stats.Timestamp.IsZero() {
+		stats.Timestamp = time.Now()
+	}
+	return s.recordStats(stats)
+}
+
+// GetTransactionHashes returns the set of transaction hashes already recorded
+func (s *StatsRecorder) GetTransactionHashes() (map[string]bool, error) {
+	if s == nil {
+		return nil, fmt.Errorf("stats recorder is not initialized")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.transactions.Hashes()
+}
+
 // CalculateNetProfitFromClaimAndSwap calculates the net profit from a claim+swap transaction pair
-func (s *StatsRecorder) CalculateNetProfitFromClaimAndSwap(claimFees, swapFees, swapEarnings uint64) float64 {
-	// Calculate net profit in lamports (earnings - all fees)
-	netProfitLamports := int64(swapEarnings) - int64(claimFees) - int64(swapFees)
+func (s *StatsRecorder) CalculateNetProfitFromClaimAndSwap(claim, swap TransactionResult) float64 {
+	// Calculate net profit in lamports (earnings - all fees and rent)
+	netProfitLamports := int64(swap.Earnings) - int64(claim.Fee) - int64(swap.Fee) - claim.Rent - swap.Rent
 	if netProfitLamports < 0 {
 		netProfitLamports = 0
 	}
 
-	// Convert to SOL
-	return float64(netProfitLamports) / 1_000_000_000
+	return amount.Lamports(netProfitLamports).Sol()
 }
 
-// GetProfitSummary calculates profit statistics for different time periods
-func (s *StatsRecorder) GetProfitSummary() (ProfitSummary, error) {
+// GetProfitSummary calculates profit statistics for different time periods.
+// Estimated swap records are only counted when includeEstimates is set.
+func (s *StatsRecorder) GetProfitSummary(includeEstimates bool) (ProfitSummary, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -121,37 +395,35 @@ func (s *StatsRecorder) GetProfitSummary() (ProfitSummary, error) {
 	var profit24h, profitWeek float64
 	var recentTransactions int
 
-	// Read transaction files
-	files, err := s.getTransactionFiles()
+	// Only the last week is read, indexed backends skip older records
+	stats, err := s.transactions.Since(lastWeek)
 	if err != nil {
 		return summary, err
 	}
 
-	for _, file := range files {
-		stats, err := s.readTransactionFile(file)
-		if err != nil {
-			continue
+	// Process each transaction
+	for _, stat := range stats {
+		if stat.TxType == TypeSwap && stat.Estimated && stat.Timestamp.After(lastWeek) {
+			summary.Estimated++
 		}
 
-		// Process each transaction
-		for _, stat := range stats {
-			// Convert net profit to SOL
-			netProfitSol := float64(stat.NetProfit) / 1_000_000_000
+		profit, ok := transactionProfit(stat, includeEstimates)
+		if !ok {
+			continue
+		}
 
-			// Only count swap transactions for profit
+		// Last 24 hours, projected from swaps only
+		if stat.Timestamp.After(last24h) {
+			profit24h += profit
 			if stat.TxType == TypeSwap {
-				// Last 24 hours
-				if stat.Timestamp.After(last24h) {
-					profit24h += netProfitSol
-					recentTransactions++
-				}
-
-				// Last week
-				if stat.Timestamp.After(lastWeek) {
-					profitWeek += netProfitSol
-				}
+				recentTransactions++
 			}
 		}
+
+		// Last week
+		if stat.Timestamp.After(lastWeek) {
+			profitWeek += profit
+		}
 	}
 
 	summary.Last24h = profit24h
@@ -169,19 +441,64 @@ func (s *StatsRecorder) GetProfitSummary() (ProfitSummary, error) {
 	return summary, nil
 }
 
-// getTransactionFiles returns a list of transaction file paths
-func (s *StatsRecorder) getTransactionFiles() ([]string, error) {
-	// Get all CSV files in the data directory
-	pattern := filepath.Join(s.dataDir, "transactions_*.csv")
-	matches, err := filepath.Glob(pattern)
+// ProfitPoint is the profit of one transaction and the cumulative profit up to it, in SOL
+type ProfitPoint struct {
+	Timestamp  time.Time
+	Profit     float64
+	Cumulative float64
+}
+
+// GetProfitHistory returns the cumulative profit since the given time, one point per transaction that
+// counts towards the profit, oldest first. Estimated swap records are only counted when includeEstimates is set.
+func (s *StatsRecorder) GetProfitHistory(since time.Time, includeEstimates bool) ([]ProfitPoint, error) {
+	stats, err := s.GetTransactions(since)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find transaction files: %w", err)
+		return nil, err
+	}
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].Timestamp.Before(stats[j].Timestamp) })
+
+	var points []ProfitPoint
+	cumulative := 0.0
+	for _, stat := range stats {
+		profit, ok := transactionProfit(stat, includeEstimates)
+		if !ok {
+			continue
+		}
+		cumulative += profit
+		points = append(points, ProfitPoint{Timestamp: stat.Timestamp, Profit: profit, Cumulative: cumulative})
+	}
+	return points, nil
+}
+
+// transactionProfit returns what a transaction adds to the profit in SOL: swap earnings and platform fees
+// collected add, service fees paid to the operator and rent paid by claims subtract, rent reclaimed from closed
+// accounts adds less the fees of closing them. Other transactions, and
+// estimated swaps unless includeEstimates is set, do not count.
+func transactionProfit(stat TransactionStats, includeEstimates bool) (float64, bool) {
+	switch stat.TxType {
+	case TypeClaim:
+		if stat.Rent == 0 {
+			return 0, false
+		}
+		return -float64(stat.Rent) / amount.LamportsPerSol, true
+	case TypeServiceFee:
+		return -amount.Lamports(stat.Expenses).Sol(), true
+	case TypeRentReclaim:
+		return float64(-stat.Rent-int64(stat.Expenses)) / amount.LamportsPerSol, true
+	case TypePlatformFee:
+		return amount.Lamports(stat.NetProfit).Sol(), true
+	case TypeSwap:
+		if stat.Estimated && !includeEstimates {
+			return 0, false
+		}
+		return amount.Lamports(stat.NetProfit).Sol(), true
+	default:
+		return 0, false
 	}
-	return matches, nil
 }
 
 // readTransactionFile reads and parses a transaction file
-func (s *StatsRecorder) readTransactionFile(filePath string) ([]TransactionStats, error) {
+func readTransactionFile(filePath string) ([]TransactionStats, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open transaction file: %w", err)
@@ -189,6 +506,8 @@ func (s *StatsRecorder) readTransactionFile(filePath string) ([]TransactionStats
 	defer file.Close()
 
 	reader := csv.NewReader(file)
+	// Files written before the Source column was added have fewer fields
+	reader.FieldsPerRecord = -1
 	records, err := reader.ReadAll()
 	if err != nil {
 		return nil, fmt.Errorf("failed to read CSV data: %w", err)
@@ -223,6 +542,9 @@ func (s *StatsRecorder) readTransactionFile(filePath string) ([]TransactionStats
 				GrossProfit: grossProfit,
 				NetProfit:   netProfit,
 				TxHash:      record[7],
+				Estimated:   len(record) > 8 && record[8] == sourceEstimated,
+				OperationID: field(record, 9),
+				Rent:        parseSignedSOLToLamports(field(record, 10)),
 			})
 		}
 	}
@@ -230,22 +552,39 @@ func (s *StatsRecorder) readTransactionFile(filePath string) ([]TransactionStats
 	return stats, nil
 }
 
-// parseSOLToLamports converts a SOL string value to lamports (uint64)
+// parseSOLToLamports converts a SOL string value to lamports, exactly for the 9 decimals the files are written
+// with. Values that are not plain decimals are read as floats, 0 when they cannot be read at all.
 func parseSOLToLamports(solValue string) uint64 {
-	// Remove any non-numeric characters except for the decimal point
 	solValue = strings.TrimSpace(solValue)
+	if lamports, err := amount.ParseSol(solValue); err == nil {
+		return uint64(lamports)
+	}
 
-	// Parse the float value
 	value, err := strconv.ParseFloat(solValue, 64)
 	if err != nil {
 		return 0
 	}
+	return uint64(amount.FromSol(value))
+}
 
-	// Convert to lamports (1 SOL = 1,000,000,000 lamports)
-	return uint64(value * 1_000_000_000)
+// parseSignedSOLToLamports converts a SOL string value that may be negative to lamports
+func parseSignedSOLToLamports(solValue string) int64 {
+	solValue = strings.TrimSpace(solValue)
+	if negative, ok := strings.CutPrefix(solValue, "-"); ok {
+		return -int64(parseSOLToLamports(negative))
+	}
+	return int64(parseSOLToLamports(solValue))
 }
 
-// recordStats writes statistics to the CSV file
+// formatSignedSol formats lamports that may be negative as SOL with all 9 decimals
+func formatSignedSol(lamports int64) string {
+	if lamports < 0 {
+		return "-" + amount.Lamports(-lamports).FormatSol(amount.SolDecimals)
+	}
+	return amount.Lamports(lamports).FormatSol(amount.SolDecimals)
+}
+
+// recordStats appends statistics to the transaction store
 func (s *StatsRecorder) recordStats(stats TransactionStats) error {
 	if s == nil {
 		return fmt.Errorf("stats recorder is not initialized")
@@ -254,58 +593,65 @@ func (s *StatsRecorder) recordStats(stats TransactionStats) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Create filename based on year and month
-	filename := fmt.Sprintf("transactions_%s.csv", time.Now().Format("2006-01"))
-	filepath := filepath.Join(s.dataDir, filename)
-
-	// Check if file exists
-	fileExists := false
-	if _, err := os.Stat(filepath); err == nil {
-		fileExists = true
-	}
+	return s.transactions.Append(stats)
+}
 
-	// Open file in append mode
-	file, err := os.OpenFile(filepath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+// rewriteTransactionFile replaces a transaction file with the given records
+func rewriteTransactionFile(filePath string, stats []TransactionStats) error {
+	tmpPath := filePath + ".tmp"
+	file, err := os.Create(tmpPath)
 	if err != nil {
-		return fmt.Errorf("failed to open stats file: %w", err)
+		return fmt.Errorf("failed to create stats file: %w", err)
 	}
-	defer file.Close()
 
 	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	// Write header if file is new
-	if !fileExists {
-		header := []string{
-			"Timestamp", "Type", "Token", "Amount",
-			"Expenses (SOL)", "Gross Profit (SOL)", "Net Profit (SOL)",
-			"Transaction Hash",
-		}
-		if err := writer.Write(header); err != nil {
-			return fmt.Errorf("failed to write header: %w", err)
-		}
+	writer.Write(transactionFileHeader)
+	for _, stat := range stats {
+		writer.Write(formatTransactionRecord(stat))
 	}
+	writer.Flush()
 
-	// Format values for CSV
-	expensesSol := float64(stats.Expenses) / 1_000_000_000 // Convert lamports to SOL
-	grossProfitSol := float64(stats.GrossProfit) / 1_000_000_000
-	netProfitSol := float64(stats.NetProfit) / 1_000_000_000
+	if err := writer.Error(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write stats file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write stats file: %w", err)
+	}
 
-	// Write record
-	record := []string{
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return fmt.Errorf("failed to replace stats file: %w", err)
+	}
+
+	return nil
+}
+
+// formatTransactionRecord formats a transaction as a CSV row
+func formatTransactionRecord(stats TransactionStats) []string {
+	source := sourceMeasured
+	if stats.Estimated {
+		source = sourceEstimated
+	}
+
+	return []string{
 		stats.Timestamp.Format(time.RFC3339),
 		string(stats.TxType),
 		stats.TokenSymbol,
 		stats.TokenAmount,
-		fmt.Sprintf("%.9f", expensesSol),
-		fmt.Sprintf("%.9f", grossProfitSol),
-		fmt.Sprintf("%.9f", netProfitSol),
+		amount.Lamports(stats.Expenses).FormatSol(amount.SolDecimals),
+		amount.Lamports(stats.GrossProfit).FormatSol(amount.SolDecimals),
+		amount.Lamports(stats.NetProfit).FormatSol(amount.SolDecimals),
 		stats.TxHash,
+		source,
+		stats.OperationID,
+		formatSignedSol(stats.Rent),
 	}
+}
 
-	if err := writer.Write(record); err != nil {
-		return fmt.Errorf("failed to write record: %w", err)
+// field returns the i-th field of a record, empty for files written before the column was added
+func field(record []string, i int) string {
+	if i < len(record) {
+		return record[i]
 	}
-
-	return nil
+	return ""
 }
@@ -0,0 +1,41 @@
+package solana
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	solana_go "github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsRecorderReconcileStopsOnContextCancel(t *testing.T) {
+	stats, err := NewStatsRecorder(t.TempDir())
+	require.NoError(t, err)
+	for i := byte(1); i <= 5; i++ {
+		require.NoError(t, stats.RecordEstimatedSwapStats("BOOP", "1", 5000, 0, solana_go.Signature{i}.String(), ""))
+	}
+	baseline := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := stats.ReconcileEstimates(ctx, &fakeIndexingClient{missing: 1 << 20})
+		done <- err
+	}()
+
+	// Cancel while the reconciliation pauses between lookups, it would take a second to get through all five
+	time.Sleep(reconcilePause / 2)
+	cancel()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(reconcilePause * 2):
+		t.Fatal("stats reconciliation did not stop on context cancel")
+	}
+
+	require.NoError(t, stats.Close())
+	assert.LessOrEqual(t, waitForGoroutines(baseline), baseline, "stats reconciliation leaked after context cancel")
+}
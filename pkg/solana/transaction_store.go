@@ -0,0 +1,219 @@
+package solana
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Stats backends storing transaction records
+const (
+	StatsBackendCSV    = "csv"    // One CSV file per month
+	StatsBackendSQLite = "sqlite" // Indexed SQLite database
+	StatsBackendBolt   = "bolt"   // Embedded bbolt database, without CGO
+)
+
+// TransactionStore persists the transaction records of a StatsRecorder. The recorder serializes all calls.
+type TransactionStore interface {
+	// Append stores a new record
+	Append(stats TransactionStats) error
+	// Since returns the records at or after since, oldest first
+	Since(since time.Time) ([]TransactionStats, error)
+	// Replace overwrites the stored records with the same transaction hash and type
+	Replace(updated []TransactionStats) error
+	// Hashes returns the set of recorded transaction hashes
+	Hashes() (map[string]bool, error)
+	Close() error
+}
+
+// OpenTransactionStore opens the transaction store of a stats directory for the given backend
+func OpenTransactionStore(dataDir, backend string) (TransactionStore, error) {
+	switch strings.ToLower(backend) {
+	case "", StatsBackendCSV:
+		return NewCSVTransactionStore(dataDir), nil
+	case StatsBackendSQLite:
+		return NewSQLiteTransactionStore(filepath.Join(dataDir, "transactions.db"))
+	case StatsBackendBolt:
+		return NewBoltTransactionStore(filepath.Join(dataDir, "transactions.bolt"))
+	default:
+		return nil, fmt.Errorf("unknown stats backend %q, expected %q, %q or %q", backend, StatsBackendCSV, StatsBackendSQLite, StatsBackendBolt)
+	}
+}
+
+// CopyTransactions copies the records of from that to does not have yet, returning how many were copied
+func CopyTransactions(from, to TransactionStore) (int, error) {
+	records, err := from.Since(time.Time{})
+	if err != nil {
+		return 0, err
+	}
+
+	existing, err := to.Since(time.Time{})
+	if err != nil {
+		return 0, err
+	}
+	type recordKey struct {
+		hash   string
+		txType TransactionType
+	}
+	seen := make(map[recordKey]bool, len(existing))
+	for _, record := range existing {
+		seen[recordKey{record.TxHash, record.TxType}] = true
+	}
+
+	copied := 0
+	for _, record := range records {
+		key := recordKey{record.TxHash, record.TxType}
+		if seen[key] {
+			continue
+		}
+		if err := to.Append(record); err != nil {
+			return copied, err
+		}
+		seen[key] = true
+		copied++
+	}
+	return copied, nil
+}
+
+// csvTransactionStore keeps records in one CSV file per month, named after the month of each record
+type csvTransactionStore struct {
+	dataDir string
+}
+
+// NewCSVTransactionStore creates a store for the monthly CSV files in dataDir
+func NewCSVTransactionStore(dataDir string) TransactionStore {
+	return &csvTransactionStore{dataDir: dataDir}
+}
+
+// Append adds the record to the file of its month
+func (c *csvTransactionStore) Append(stats TransactionStats) error {
+	filePath := c.monthFile(stats.Timestamp)
+
+	// Check if file exists
+	fileExists := false
+	if _, err := os.Stat(filePath); err == nil {
+		fileExists = true
+	}
+
+	// Open file in append mode
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open stats file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	// Write header if file is new
+	if !fileExists {
+		if err := writer.Write(transactionFileHeader); err != nil {
+			return fmt.Errorf("failed to write header: %w", err)
+		}
+	}
+
+	if err := writer.Write(formatTransactionRecord(stats)); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+
+	return nil
+}
+
+// Since reads the files of the months overlapping the period, unreadable files are skipped
+func (c *csvTransactionStore) Since(since time.Time) ([]TransactionStats, error) {
+	files, err := c.files()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []TransactionStats
+	for _, file := range files {
+		// Files hold the records of one month, skip months that ended before the period, with a day of slack for
+		// records written in another time zone
+		if month, ok := fileMonth(file); ok && !month.AddDate(0, 1, 1).After(since) {
+			continue
+		}
+
+		stats, err := readTransactionFile(file)
+		if err != nil {
+			continue
+		}
+		for _, stat := range stats {
+			if !stat.Timestamp.Before(since) {
+				records = append(records, stat)
+			}
+		}
+	}
+	return records, nil
+}
+
+// Replace rewrites the files holding the updated records
+func (c *csvTransactionStore) Replace(updated []TransactionStats) error {
+	byFile := make(map[string][]TransactionStats)
+	for _, stat := range updated {
+		file := c.monthFile(stat.Timestamp)
+		byFile[file] = append(byFile[file], stat)
+	}
+
+	for file, updates := range byFile {
+		stats, err := readTransactionFile(file)
+		if err != nil {
+			return err
+		}
+		for i, stat := range stats {
+			for _, update := range updates {
+				if stat.TxHash == update.TxHash && stat.TxType == update.TxType {
+					stats[i] = update
+				}
+			}
+		}
+		if err := rewriteTransactionFile(file, stats); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Hashes reads the hashes of every file
+func (c *csvTransactionStore) Hashes() (map[string]bool, error) {
+	records, err := c.Since(time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make(map[string]bool, len(records))
+	for _, record := range records {
+		hashes[record.TxHash] = true
+	}
+	return hashes, nil
+}
+
+// Close does nothing, files are closed after every operation
+func (c *csvTransactionStore) Close() error {
+	return nil
+}
+
+// files returns the transaction file paths in month order
+func (c *csvTransactionStore) files() ([]string, error) {
+	pattern := filepath.Join(c.dataDir, "transactions_*.csv")
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find transaction files: %w", err)
+	}
+	return matches, nil
+}
+
+// monthFile returns the path of the file holding records of the month of t
+func (c *csvTransactionStore) monthFile(t time.Time) string {
+	return filepath.Join(c.dataDir, fmt.Sprintf("transactions_%s.csv", t.Format("2006-01")))
+}
+
+// fileMonth returns the start of the month a transaction file holds
+func fileMonth(path string) (time.Time, bool) {
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "transactions_"), ".csv")
+	month, err := time.ParseInLocation("2006-01", name, time.Local)
+	return month, err == nil
+}
@@ -0,0 +1,215 @@
+package solana
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionStoreBackends(t *testing.T) {
+	for _, backend := range []string{StatsBackendCSV, StatsBackendSQLite, StatsBackendBolt} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := OpenTransactionStore(t.TempDir(), backend)
+			assert.NoError(t, err)
+			defer store.Close()
+
+			now := time.Now().Truncate(time.Second)
+			old := TransactionStats{Timestamp: now.AddDate(0, -2, 0), TokenSymbol: "OLD", Expenses: 5000, TxHash: "old-tx", TxType: TypeClaim}
+			swap := TransactionStats{Timestamp: now.Add(-time.Hour), TokenSymbol: "NEW", TokenAmount: "1000", Expenses: 5000,
+				GrossProfit: 100_000_000, NetProfit: 99_995_000, TxHash: "swap-tx", TxType: TypeSwap, Estimated: true,
+				OperationID: "a1b2c3d4e5f6"}
+			assert.NoError(t, store.Append(old))
+			assert.NoError(t, store.Append(swap))
+
+			recent, err := store.Since(now.Add(-24 * time.Hour))
+			assert.NoError(t, err)
+			if assert.Len(t, recent, 1) {
+				assert.Equal(t, swap.TxHash, recent[0].TxHash)
+				assert.True(t, recent[0].Timestamp.Equal(swap.Timestamp))
+				assert.Equal(t, swap.NetProfit, recent[0].NetProfit)
+				assert.True(t, recent[0].Estimated)
+				assert.Equal(t, swap.OperationID, recent[0].OperationID)
+			}
+
+			reconciled := recent[0]
+			reconciled.NetProfit = 80_000_000
+			reconciled.Estimated = false
+			assert.NoError(t, store.Replace([]TransactionStats{reconciled}))
+
+			all, err := store.Since(time.Time{})
+			assert.NoError(t, err)
+			if assert.Len(t, all, 2) {
+				assert.Equal(t, "old-tx", all[0].TxHash)
+				assert.Equal(t, uint64(80_000_000), all[1].NetProfit)
+				assert.False(t, all[1].Estimated)
+			}
+
+			hashes, err := store.Hashes()
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]bool{"old-tx": true, "swap-tx": true}, hashes)
+		})
+	}
+}
+
+func TestTransactionRecordsKeepEveryLamport(t *testing.T) {
+	stat := TransactionStats{Expenses: 3, GrossProfit: 9_007_199_254_740_993, NetProfit: 9_007_199_254_740_990}
+	record := formatTransactionRecord(stat)
+	assert.Equal(t, []string{"0.000000003", "9007199.254740993", "9007199.254740990"}, record[4:7])
+
+	assert.Equal(t, stat.Expenses, parseSOLToLamports(record[4]))
+	assert.Equal(t, stat.GrossProfit, parseSOLToLamports(record[5]))
+	assert.Equal(t, stat.NetProfit, parseSOLToLamports(record[6]))
+	assert.Equal(t, uint64(1_500), parseSOLToLamports(" 1.5e-6 "))
+}
+
+func TestCopyTransactionsSkipsExisting(t *testing.T) {
+	dir := t.TempDir()
+	csvStore := NewCSVTransactionStore(dir)
+	now := time.Now().Truncate(time.Second)
+	assert.NoError(t, csvStore.Append(TransactionStats{Timestamp: now, TxHash: "claim-tx", TxType: TypeClaim}))
+	assert.NoError(t, csvStore.Append(TransactionStats{Timestamp: now, TxHash: "claim-tx", TxType: TypeServiceFee}))
+
+	sqliteStore, err := NewSQLiteTransactionStore(filepath.Join(dir, "transactions.db"))
+	assert.NoError(t, err)
+	defer sqliteStore.Close()
+
+	copied, err := CopyTransactions(csvStore, sqliteStore)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, copied)
+
+	// Running the migration again copies nothing
+	copied, err = CopyTransactions(csvStore, sqliteStore)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, copied)
+}
+
+func TestStatsRecorderWithSQLiteBackend(t *testing.T) {
+	recorder, err := NewStatsRecorderWithBackend(t.TempDir(), StatsBackendSQLite)
+	assert.NoError(t, err)
+	defer recorder.Close()
+
+	assert.NoError(t, recorder.RecordSwapStats("TEST", "1000", 5000, 1_000_000_000, 0, "swap-tx", ""))
+	assert.NoError(t, recorder.RecordEstimatedSwapStats("TEST", "1000", 5000, 1_000_000_000, "estimated-tx", ""))
+
+	summary, err := recorder.GetProfitSummary(false)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.999995, summary.Last24h, 1e-9)
+	assert.Equal(t, 1, summary.Estimated)
+
+	hashes, err := recorder.GetTransactionHashes()
+	assert.NoError(t, err)
+	assert.Len(t, hashes, 2)
+}
+
+func TestStatsRecorderWithBoltBackend(t *testing.T) {
+	recorder, err := NewStatsRecorderWithBackend(t.TempDir(), StatsBackendBolt)
+	assert.NoError(t, err)
+	defer recorder.Close()
+
+	now := time.Now().Truncate(time.Second)
+	assert.NoError(t, recorder.RecordAirdropValue(AirdropValue{Timestamp: now.Add(-48 * time.Hour), AirdropID: "old", AmountUsd: 1}))
+	assert.NoError(t, recorder.RecordAirdropValue(AirdropValue{Timestamp: now, AirdropID: "new", AmountUsd: 2.5}))
+	values, err := recorder.GetAirdropValues(now.Add(-time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, values, 1) {
+		assert.Equal(t, "new", values[0].AirdropID)
+		assert.Equal(t, 2.5, values[0].AmountUsd)
+	}
+
+	assert.NoError(t, recorder.RecordDecision(Decision{Timestamp: now, AirdropID: "new", Action: "claim", QuoteAge: time.Second}))
+	decisions, err := recorder.GetDecisions(time.Time{})
+	assert.NoError(t, err)
+	if assert.Len(t, decisions, 1) {
+		assert.Equal(t, "claim", decisions[0].Action)
+		assert.Equal(t, time.Second, decisions[0].QuoteAge)
+	}
+
+	// Records without a hash are stored but not indexed
+	assert.NoError(t, recorder.RecordTransaction(TransactionStats{Timestamp: now, TxType: TypeClaim}))
+	hashes, err := recorder.GetTransactionHashes()
+	assert.NoError(t, err)
+	assert.Empty(t, hashes)
+
+	state := recorder.State("sale_failures")
+	var saved map[string]int
+	found, err := state.Load(&saved)
+	assert.NoError(t, err)
+	assert.False(t, found)
+	assert.NoError(t, state.Save(map[string]int{"mint": 3}))
+	found, err = state.Load(&saved)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, map[string]int{"mint": 3}, saved)
+}
+
+func TestProfitHistoryIsCumulative(t *testing.T) {
+	recorder, err := NewStatsRecorder(t.TempDir())
+	assert.NoError(t, err)
+
+	assert.NoError(t, recorder.RecordClaimStats("TEST", "1000", 5000, 0, "claim-tx", ""))
+	assert.NoError(t, recorder.RecordSwapStats("TEST", "1000", 5000, 1_000_000_000, 0, "swap-tx", ""))
+	assert.NoError(t, recorder.RecordEstimatedSwapStats("TEST", "1000", 5000, 1_000_000_000, "estimated-tx", ""))
+	assert.NoError(t, recorder.RecordSwapStats("TEST", "1000", 5000, 500_000_000, 0, "swap-tx-2", ""))
+
+	points, err := recorder.GetProfitHistory(time.Time{}, false)
+	assert.NoError(t, err)
+	if assert.Len(t, points, 2, "claims and estimates do not count") {
+		assert.InDelta(t, 0.999995, points[0].Cumulative, 1e-9)
+		assert.InDelta(t, 0.499995, points[1].Profit, 1e-9)
+		assert.InDelta(t, 1.49999, points[1].Cumulative, 1e-9)
+	}
+
+	points, err = recorder.GetProfitHistory(time.Time{}, true)
+	assert.NoError(t, err)
+	assert.Len(t, points, 3)
+}
+
+func TestRentCountsAgainstProfit(t *testing.T) {
+	for _, backend := range []string{StatsBackendCSV, StatsBackendSQLite, StatsBackendBolt} {
+		recorder, err := NewStatsRecorderWithBackend(t.TempDir(), backend)
+		assert.NoError(t, err)
+
+		// The claim creates the token account, the sale closes it again
+		assert.NoError(t, recorder.RecordClaimStats("TEST", "1000", 5000, 2_039_280, "claim-tx", ""))
+		assert.NoError(t, recorder.RecordSwapStats("TEST", "1000", 5000, 1_000_000_000, -2_039_280, "swap-tx", ""))
+
+		stats, err := recorder.GetTransactions(time.Time{})
+		assert.NoError(t, err)
+		if assert.Len(t, stats, 2, backend) {
+			assert.Equal(t, int64(2_039_280), stats[0].Rent, backend)
+			assert.Equal(t, int64(-2_039_280), stats[1].Rent, backend)
+		}
+
+		points, err := recorder.GetProfitHistory(time.Time{}, false)
+		assert.NoError(t, err)
+		if assert.Len(t, points, 2, backend) {
+			assert.InDelta(t, -0.00203928, points[0].Profit, 1e-9, backend)
+			assert.InDelta(t, 0.999995, points[1].Cumulative, 1e-9, backend)
+		}
+		assert.NoError(t, recorder.Close())
+	}
+}
+
+func TestReclaimedRentCountsAsProfit(t *testing.T) {
+	for _, backend := range []string{StatsBackendCSV, StatsBackendSQLite, StatsBackendBolt} {
+		recorder, err := NewStatsRecorderWithBackend(t.TempDir(), backend)
+		assert.NoError(t, err)
+
+		// Two empty token accounts closed, their rent less the fee is recovered
+		assert.NoError(t, recorder.RecordRentReclaimStats(2, 5000, -2*2_039_280, "close-tx"))
+
+		points, err := recorder.GetProfitHistory(time.Time{}, false)
+		assert.NoError(t, err)
+		if assert.Len(t, points, 1, backend) {
+			assert.InDelta(t, 0.00407356, points[0].Profit, 1e-9, backend)
+		}
+		assert.NoError(t, recorder.Close())
+	}
+}
+
+func TestUnknownStatsBackend(t *testing.T) {
+	_, err := NewStatsRecorderWithBackend(t.TempDir(), "parquet")
+	assert.Error(t, err)
+}
@@ -0,0 +1,157 @@
+package solana
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// AirdropValue is an observation of an airdrop's USD value, recorded when it is first seen and whenever it changes
+type AirdropValue struct {
+	Timestamp   time.Time
+	AirdropID   string
+	TokenSymbol string
+	TokenMint   string
+	AmountUsd   float64
+	Claimed     bool
+}
+
+// valueHistoryHeader is the header row of the monthly airdrop value history files
+var valueHistoryHeader = []string{"Timestamp", "Airdrop", "Token", "Mint", "Value (USD)", "Claimed"}
+
+// RecordAirdropValue appends an airdrop value observation to the monthly history file
+func (s *StatsRecorder) RecordAirdropValue(value AirdropValue) error {
+	if s == nil {
+		return fmt.Errorf("stats recorder is not initialized")
+	}
+
+	if value.Timestamp.IsZero() {
+		value.Timestamp = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.bolt != nil {
+		if err := appendBoltRecord(s.bolt, boltAirdropValuesBucket, value.Timestamp, value); err != nil {
+			return fmt.Errorf("failed to write value history: %w", err)
+		}
+		return nil
+	}
+
+	filePath := filepath.Join(s.dataDir, fmt.Sprintf("airdrop_values_%s.csv", value.Timestamp.Format("2006-01")))
+
+	fileExists := false
+	if _, err := os.Stat(filePath); err == nil {
+		fileExists = true
+	}
+
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open value history file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	if !fileExists {
+		if err := writer.Write(valueHistoryHeader); err != nil {
+			return fmt.Errorf("failed to write header: %w", err)
+		}
+	}
+
+	record := []string{
+		value.Timestamp.Format(time.RFC3339),
+		value.AirdropID,
+		value.TokenSymbol,
+		value.TokenMint,
+		strconv.FormatFloat(value.AmountUsd, 'f', -1, 64),
+		strconv.FormatBool(value.Claimed),
+	}
+	if err := writer.Write(record); err != nil {
+		return fmt.Errorf("failed to write value history: %w", err)
+	}
+
+	return nil
+}
+
+// GetAirdropValues returns all airdrop value observations since the given time, oldest first
+func (s *StatsRecorder) GetAirdropValues(since time.Time) ([]AirdropValue, error) {
+	if s == nil {
+		return nil, fmt.Errorf("stats recorder is not initialized")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.bolt != nil {
+		return boltRecordsSince(s.bolt, boltAirdropValuesBucket, since, func(value AirdropValue) time.Time {
+			return value.Timestamp
+		})
+	}
+
+	files, err := filepath.Glob(filepath.Join(s.dataDir, "airdrop_values_*.csv"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find value history files: %w", err)
+	}
+
+	var values []AirdropValue
+	for _, filePath := range files {
+		file, err := os.Open(filePath)
+		if err != nil {
+			continue
+		}
+		records, err := csv.NewReader(file).ReadAll()
+		file.Close()
+		if err != nil {
+			continue
+		}
+
+		for _, record := range records {
+			if len(record) < len(valueHistoryHeader) {
+				continue
+			}
+
+			// Skips the header row as well
+			timestamp, err := time.Parse(time.RFC3339, record[0])
+			if err != nil || timestamp.Before(since) {
+				continue
+			}
+
+			amountUsd, _ := strconv.ParseFloat(record[4], 64)
+			claimed, _ := strconv.ParseBool(record[5])
+
+			values = append(values, AirdropValue{
+				Timestamp:   timestamp,
+				AirdropID:   record[1],
+				TokenSymbol: record[2],
+				TokenMint:   record[3],
+				AmountUsd:   amountUsd,
+				Claimed:     claimed,
+			})
+		}
+	}
+
+	sort.SliceStable(values, func(i, j int) bool {
+		return values[i].Timestamp.Before(values[j].Timestamp)
+	})
+
+	return values, nil
+}
+
+// GetTransactions returns all recorded transactions since the given time
+func (s *StatsRecorder) GetTransactions(since time.Time) ([]TransactionStats, error) {
+	if s == nil {
+		return nil, fmt.Errorf("stats recorder is not initialized")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.transactions.Since(since)
+}
@@ -0,0 +1,12 @@
+// Package strategy holds the claim rules shared by the auto claimer, its shadow evaluation and the backtest,
+// and the per-token performance learned from history that biases them
+package strategy
+
+import "time"
+
+// Strategy is a claim configuration: the value threshold and the stable price rule
+type Strategy struct {
+	MinimumUsdThreshold float64       // Claim immediately at or above this value
+	StableMinimumUsd    float64       // Claim below the threshold when above this value and stable
+	StableDuration      time.Duration // How long the value must be unchanged and observed before a stable claim
+}
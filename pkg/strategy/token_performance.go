@@ -0,0 +1,195 @@
+package strategy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	sol "boop-airdrop-redeemer/pkg/solana"
+)
+
+const (
+	// tokenPerformanceMinSamples is the number of observed waits needed before a token gets a bias
+	tokenPerformanceMinSamples = 3
+	// tokenPerformanceDumpChange is the average value change over the wait at which a token counts as dumping
+	tokenPerformanceDumpChange = -0.10
+)
+
+// TokenPerformance is what history says about a token: how its airdrop values moved during the
+// stability wait and how much of the expected value its sales realized
+type TokenPerformance struct {
+	Symbol            string  `json:"symbol"`
+	WaitSamples       int     `json:"waitSamples"`       // Airdrops observed for the whole stability wait
+	AvgWaitChange     float64 `json:"avgWaitChange"`     // Average relative value change over the wait, the learned bias
+	ClaimAndSellCount int     `json:"claimAndSellCount"` // Sales right after claiming
+	ClaimAndSellRatio float64 `json:"claimAndSellRatio"` // Realized over expected USD of those sales
+	StableSellCount   int     `json:"stableSellCount"`   // Sales after a stable price period
+	StableSellRatio   float64 `json:"stableSellRatio"`   // Realized over expected USD of those sales
+	SkipStableWait    bool    `json:"skipStableWait"`    // Waiting historically lost value, claim without waiting
+}
+
+// TokenPerformanceModel holds the learned per-token performance, keyed by token symbol
+type TokenPerformanceModel struct {
+	UpdatedAt   time.Time                   `json:"updatedAt"`
+	WaitMinutes float64                     `json:"waitMinutes"`
+	Tokens      map[string]TokenPerformance `json:"tokens"`
+}
+
+// LearnTokenPerformance measures, per token symbol, how airdrop values changed over the given wait
+// and how much of the expected value was realized by each sell strategy. Tokens whose values dropped
+// by 10% or more on average over at least 3 waits are flagged to skip the stability wait.
+func LearnTokenPerformance(history []sol.AirdropValue, outcomes []sol.Outcome, wait time.Duration) *TokenPerformanceModel {
+	model := &TokenPerformanceModel{
+		UpdatedAt:   time.Now(),
+		WaitMinutes: wait.Minutes(),
+		Tokens:      make(map[string]TokenPerformance),
+	}
+
+	byAirdrop := make(map[string][]sol.AirdropValue)
+	for _, value := range history {
+		byAirdrop[value.AirdropID] = append(byAirdrop[value.AirdropID], value)
+	}
+
+	waitChanges := make(map[string][]float64)
+	for _, values := range byAirdrop {
+		if change, ok := valueChangeOver(values, wait); ok {
+			symbol := values[0].TokenSymbol
+			waitChanges[symbol] = append(waitChanges[symbol], change)
+		}
+	}
+
+	for symbol, changes := range waitChanges {
+		performance := model.Tokens[symbol]
+		performance.Symbol = symbol
+		performance.WaitSamples = len(changes)
+		for _, change := range changes {
+			performance.AvgWaitChange += change / float64(len(changes))
+		}
+		performance.SkipStableWait = performance.WaitSamples >= tokenPerformanceMinSamples &&
+			performance.AvgWaitChange <= tokenPerformanceDumpChange
+		model.Tokens[symbol] = performance
+	}
+
+	realized := make(map[string]map[string]sol.RealizationStats)
+	for _, outcome := range outcomes {
+		if outcome.SolPrice <= 0 || outcome.ExpectedUsd <= 0 {
+			continue
+		}
+		if realized[outcome.TokenSymbol] == nil {
+			realized[outcome.TokenSymbol] = make(map[string]sol.RealizationStats)
+		}
+		totals := realized[outcome.TokenSymbol][outcome.Strategy]
+		totals.Count++
+		totals.ExpectedUsd += outcome.ExpectedUsd
+		totals.RealizedUsd += outcome.RealizedUsd()
+		realized[outcome.TokenSymbol][outcome.Strategy] = totals
+	}
+
+	for symbol, byStrategy := range realized {
+		performance := model.Tokens[symbol]
+		performance.Symbol = symbol
+		if totals, ok := byStrategy[sol.StrategyClaimAndSell]; ok {
+			performance.ClaimAndSellCount = totals.Count
+			performance.ClaimAndSellRatio = totals.Ratio()
+		}
+		if totals, ok := byStrategy[sol.StrategyStableSell]; ok {
+			performance.StableSellCount = totals.Count
+			performance.StableSellRatio = totals.Ratio()
+		}
+		model.Tokens[symbol] = performance
+	}
+
+	return model
+}
+
+// valueChangeOver returns the relative value change of an airdrop between its first observation and
+// the given wait later. History only holds value changes, so the value at the end of the wait is the
+// last one recorded before it, and airdrops without a later observation are left out.
+func valueChangeOver(values []sol.AirdropValue, wait time.Duration) (float64, bool) {
+	first := values[0]
+	if first.AmountUsd <= 0 {
+		return 0, false
+	}
+
+	end := first.Timestamp.Add(wait)
+	atEnd := first
+	observedPastWait := false
+	for _, value := range values[1:] {
+		if value.Timestamp.After(end) {
+			observedPastWait = true
+			break
+		}
+		atEnd = value
+	}
+	if !observedPastWait && !atEnd.Timestamp.Equal(end) {
+		return 0, false
+	}
+
+	return atEnd.AmountUsd/first.AmountUsd - 1, true
+}
+
+// SkipStableWait reports whether history says the stability wait loses value for the token.
+// A nil model never skips the wait.
+func (m *TokenPerformanceModel) SkipStableWait(symbol string) (TokenPerformance, bool) {
+	if m == nil {
+		return TokenPerformance{}, false
+	}
+	performance, ok := m.Tokens[symbol]
+	return performance, ok && performance.SkipStableWait
+}
+
+// Sorted returns the tokens ordered by learned bias, the fastest dumping first
+func (m *TokenPerformanceModel) Sorted() []TokenPerformance {
+	if m == nil {
+		return nil
+	}
+	tokens := make([]TokenPerformance, 0, len(m.Tokens))
+	for _, performance := range m.Tokens {
+		tokens = append(tokens, performance)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if tokens[i].AvgWaitChange != tokens[j].AvgWaitChange {
+			return tokens[i].AvgWaitChange < tokens[j].AvgWaitChange
+		}
+		return tokens[i].Symbol < tokens[j].Symbol
+	})
+	return tokens
+}
+
+// LoadTokenPerformanceModel reads a model saved with Save
+func LoadTokenPerformanceModel(path string) (*TokenPerformanceModel, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token performance model: %w", err)
+	}
+
+	var model TokenPerformanceModel
+	if err := json.Unmarshal(content, &model); err != nil {
+		return nil, fmt.Errorf("failed to decode token performance model: %w", err)
+	}
+	return &model, nil
+}
+
+// Save writes the model as indented JSON through a temporary file so it stays readable for inspection
+func (m *TokenPerformanceModel) Save(path string) error {
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode token performance model: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create token performance directory: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write token performance model: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace token performance model: %w", err)
+	}
+	return nil
+}
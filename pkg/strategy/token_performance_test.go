@@ -0,0 +1,64 @@
+package strategy
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sol "boop-airdrop-redeemer/pkg/solana"
+)
+
+func TestLearnTokenPerformance(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	wait := 10 * time.Minute
+
+	var history []sol.AirdropValue
+	for i, id := range []string{"d1", "d2", "d3"} {
+		at := start.Add(time.Duration(i) * time.Hour)
+		history = append(history,
+			sol.AirdropValue{Timestamp: at, AirdropID: id, TokenSymbol: "DUMP", AmountUsd: 1},
+			sol.AirdropValue{Timestamp: at.Add(5 * time.Minute), AirdropID: id, TokenSymbol: "DUMP", AmountUsd: 0.8},
+			sol.AirdropValue{Timestamp: at.Add(15 * time.Minute), AirdropID: id, TokenSymbol: "DUMP", AmountUsd: 0.5},
+		)
+	}
+	// Holds its value, and an airdrop never observed past the wait is left out
+	history = append(history,
+		sol.AirdropValue{Timestamp: start, AirdropID: "h1", TokenSymbol: "HOLD", AmountUsd: 2},
+		sol.AirdropValue{Timestamp: start.Add(20 * time.Minute), AirdropID: "h1", TokenSymbol: "HOLD", AmountUsd: 2},
+		sol.AirdropValue{Timestamp: start, AirdropID: "h2", TokenSymbol: "HOLD", AmountUsd: 2},
+	)
+	outcomes := []sol.Outcome{
+		{TokenSymbol: "HOLD", Strategy: sol.StrategyStableSell, ExpectedUsd: 2, RealizedSol: 0.01, SolPrice: 150},
+		{TokenSymbol: "HOLD", Strategy: sol.StrategyClaimAndSell, ExpectedUsd: 2, RealizedSol: 0.01},
+	}
+
+	model := LearnTokenPerformance(history, outcomes, wait)
+
+	dump, skip := model.SkipStableWait("DUMP")
+	assert.True(t, skip)
+	assert.Equal(t, 3, dump.WaitSamples)
+	assert.InDelta(t, -0.2, dump.AvgWaitChange, 1e-9)
+
+	hold, skip := model.SkipStableWait("HOLD")
+	assert.False(t, skip)
+	assert.Equal(t, 1, hold.WaitSamples)
+	assert.Equal(t, 1, hold.StableSellCount)
+	assert.InDelta(t, 0.75, hold.StableSellRatio, 1e-9)
+	assert.Zero(t, hold.ClaimAndSellCount, "outcomes without a SOL price are not valued")
+
+	sorted := model.Sorted()
+	require.Len(t, sorted, 2)
+	assert.Equal(t, "DUMP", sorted[0].Symbol)
+
+	_, skip = (*TokenPerformanceModel)(nil).SkipStableWait("DUMP")
+	assert.False(t, skip)
+
+	path := filepath.Join(t.TempDir(), "token_performance.json")
+	require.NoError(t, model.Save(path))
+	loaded, err := LoadTokenPerformanceModel(path)
+	require.NoError(t, err)
+	assert.Equal(t, model.Tokens, loaded.Tokens)
+}
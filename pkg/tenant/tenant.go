@@ -0,0 +1,191 @@
+package tenant
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/gagliardetto/solana-go"
+
+	"boop-airdrop-redeemer/pkg/config"
+)
+
+// walletIDLength is how many leading address characters name a wallet of a single owner
+const walletIDLength = 8
+
+// validID restricts tenant IDs to names that are safe as directory names
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Tenant is one user served by a shared deployment. Unset fields inherit the environment configuration.
+type Tenant struct {
+	ID                  string   `json:"id"`
+	Label               string   `json:"label,omitempty"`
+	PrivateKey          string   `json:"privateKey,omitempty"`    // Base58 private key, prefer PrivateKeyEnv
+	PrivateKeyEnv       string   `json:"privateKeyEnv,omitempty"` // Environment variable holding the private key
+	MinimumUsdThreshold *float64 `json:"minimumUsdThreshold,omitempty"`
+	TelegramChatID      string   `json:"telegramChatId,omitempty"`
+	ServiceFeeBps       *int     `json:"serviceFeeBps,omitempty"`
+
+	sharedChat bool // Wallets of a single owner keep the environment Telegram chat
+}
+
+// Load reads and validates the tenants file. Tenants must have unique IDs, wallets and Telegram chats,
+// so one tenant can never receive another tenant's notifications or share its stats.
+func Load(path string) ([]Tenant, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenants file: %w", err)
+	}
+
+	var tenants []Tenant
+	if err := json.Unmarshal(content, &tenants); err != nil {
+		return nil, fmt.Errorf("failed to parse tenants file: %w", err)
+	}
+	if len(tenants) == 0 {
+		return nil, fmt.Errorf("tenants file %s has no tenants", path)
+	}
+
+	if err := validate(tenants); err != nil {
+		return nil, err
+	}
+	return tenants, nil
+}
+
+// FromWallets turns the private keys of one owner into tenants sharing the environment Telegram chat.
+// Each wallet is named by its label, or its shortened address when no label is given, and keeps its
+// stats and claimed airdrops in a directory named after the start of its address.
+func FromWallets(privateKeys, labels []string) ([]Tenant, error) {
+	tenants := make([]Tenant, 0, len(privateKeys))
+	for i, key := range privateKeys {
+		privateKey, err := solana.PrivateKeyFromBase58(key)
+		if err != nil {
+			return nil, fmt.Errorf("wallet %d has an invalid private key: %w", i+1, err)
+		}
+
+		address := privateKey.PublicKey().String()
+		t := Tenant{
+			ID:         address[:walletIDLength],
+			Label:      config.ShortenAddress(address),
+			PrivateKey: key,
+			sharedChat: true,
+		}
+		if i < len(labels) && labels[i] != "" {
+			t.Label = labels[i]
+		}
+		tenants = append(tenants, t)
+	}
+
+	if err := validate(tenants); err != nil {
+		return nil, err
+	}
+	return tenants, nil
+}
+
+// validate checks that tenants have valid, unique IDs and wallets, and that no two tenants share
+// a Telegram chat unless they belong to the same owner
+func validate(tenants []Tenant) error {
+	ids := make(map[string]bool)
+	wallets := make(map[string]string)
+	chats := make(map[string]string)
+	for i, t := range tenants {
+		if !validID.MatchString(t.ID) {
+			return fmt.Errorf("tenant %d has invalid id %q", i+1, t.ID)
+		}
+		if ids[t.ID] {
+			return fmt.Errorf("duplicate tenant id %q", t.ID)
+		}
+		ids[t.ID] = true
+
+		privateKey, err := t.privateKey()
+		if err != nil {
+			return err
+		}
+		wallet := privateKey.PublicKey().String()
+		if other, exists := wallets[wallet]; exists {
+			return fmt.Errorf("tenants %q and %q use the same wallet", other, t.ID)
+		}
+		wallets[wallet] = t.ID
+
+		if t.TelegramChatID != "" && !t.sharedChat {
+			if other, exists := chats[t.TelegramChatID]; exists {
+				return fmt.Errorf("tenants %q and %q use the same Telegram chat", other, t.ID)
+			}
+			chats[t.TelegramChatID] = t.ID
+		}
+	}
+
+	return nil
+}
+
+// Name returns the tenant label, or its ID when no label is set
+func (t Tenant) Name() string {
+	if t.Label != "" {
+		return t.Label
+	}
+	return t.ID
+}
+
+// Config authenticates the tenant's wallet and builds its configuration from the environment
+// with the tenant's overrides. Stats, journal and airdrop cache are kept in per-tenant locations.
+func (t Tenant) Config() (*config.Config, error) {
+	privateKey, err := t.privateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := config.NewConfigWithPrivateKey(privateKey.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tenant %q: %w", t.ID, err)
+	}
+
+	cfg.WalletLabel = t.Name()
+	if t.MinimumUsdThreshold != nil {
+		cfg.MinimumUsdThreshold = *t.MinimumUsdThreshold
+	}
+	if t.ServiceFeeBps != nil {
+		cfg.ServiceFeeBps = *t.ServiceFeeBps
+	}
+
+	// Never fall back to the shared chat, a tenant without its own chat gets no Telegram messages
+	if !t.sharedChat {
+		cfg.TelegramChatID = t.TelegramChatID
+		if t.TelegramChatID == "" {
+			cfg.EnableTelegram = false
+		}
+	}
+
+	cfg.StatsDataDir = filepath.Join(cfg.StatsDataDir, t.ID)
+	cfg.JournalPath = tenantPath(cfg.JournalPath, t.ID)
+	cfg.AirdropCachePath = tenantPath(cfg.AirdropCachePath, t.ID)
+	cfg.AirdropStorePath = tenantPath(cfg.AirdropStorePath, t.ID)
+	cfg.LogFiles.EventLogFile = tenantPath(cfg.LogFiles.EventLogFile, t.ID)
+
+	return cfg, nil
+}
+
+// privateKey resolves the tenant's private key from the environment or the file
+func (t Tenant) privateKey() (solana.PrivateKey, error) {
+	key := t.PrivateKey
+	if t.PrivateKeyEnv != "" {
+		key = os.Getenv(t.PrivateKeyEnv)
+	}
+	if key == "" {
+		return nil, fmt.Errorf("tenant %q has no private key", t.ID)
+	}
+
+	privateKey, err := solana.PrivateKeyFromBase58(key)
+	if err != nil {
+		return nil, fmt.Errorf("tenant %q has an invalid private key: %w", t.ID, err)
+	}
+	return privateKey, nil
+}
+
+// tenantPath moves a file into a tenant subdirectory next to it, keeping empty (disabled) paths empty
+func tenantPath(path, id string) string {
+	if path == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(path), id, filepath.Base(path))
+}
@@ -0,0 +1,258 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"boop-airdrop-redeemer/pkg/logging"
+	"boop-airdrop-redeemer/pkg/solana/associated_token_account_extended"
+)
+
+// Fee paid per signature on Solana, used to leave exactly enough SOL for the final sweep
+const lamportsPerSignature = 5000
+
+// Number of token accounts moved per transaction, keeps legacy transactions under the size limit
+const tokensPerTransaction = 4
+
+// TokenAccount is an SPL token account owned by a wallet
+type TokenAccount struct {
+	Address  solana.PublicKey
+	Mint     solana.PublicKey
+	Amount   uint64
+	Decimals uint8
+}
+
+// Migrator moves all assets from one wallet to another
+type Migrator struct {
+	solClient *rpc.Client
+	logger    *log.Logger
+}
+
+// NewMigrator creates a new wallet migrator
+func NewMigrator(solClient *rpc.Client, logger *log.Logger) *Migrator {
+	return &Migrator{
+		solClient: solClient,
+		logger:    logger,
+	}
+}
+
+// GetTokenAccounts lists all SPL token accounts owned by the wallet, including empty ones
+func (m *Migrator) GetTokenAccounts(ctx context.Context, owner solana.PublicKey) ([]TokenAccount, error) {
+	accounts, err := m.solClient.GetTokenAccountsByOwner(
+		ctx,
+		owner,
+		&rpc.GetTokenAccountsConfig{
+			ProgramId: &solana.TokenProgramID,
+		},
+		&rpc.GetTokenAccountsOpts{
+			Encoding: solana.EncodingJSONParsed,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token accounts: %w", err)
+	}
+
+	var result []TokenAccount
+	for _, account := range accounts.Value {
+		data := account.Account.Data.GetRawJSON()
+		if data == nil {
+			continue
+		}
+
+		var parsed struct {
+			Parsed struct {
+				Info struct {
+					Mint        string `json:"mint"`
+					TokenAmount struct {
+						Amount   string `json:"amount"`
+						Decimals uint8  `json:"decimals"`
+					} `json:"tokenAmount"`
+				} `json:"info"`
+			} `json:"parsed"`
+		}
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			logging.Warnf(m.logger, "⚠️ Failed to parse token account %s: %v", account.Pubkey, err)
+			continue
+		}
+
+		mint, err := solana.PublicKeyFromBase58(parsed.Parsed.Info.Mint)
+		if err != nil {
+			continue
+		}
+		amount, _ := strconv.ParseUint(parsed.Parsed.Info.TokenAmount.Amount, 10, 64)
+
+		result = append(result, TokenAccount{
+			Address:  account.Pubkey,
+			Mint:     mint,
+			Amount:   amount,
+			Decimals: parsed.Parsed.Info.TokenAmount.Decimals,
+		})
+	}
+
+	return result, nil
+}
+
+// SweepTokens transfers every token balance to the new owner's associated token accounts and
+// closes the old accounts, sending their rent to the new owner
+func (m *Migrator) SweepTokens(ctx context.Context, oldWallet solana.PrivateKey, newOwner solana.PublicKey) ([]solana.Signature, error) {
+	accounts, err := m.GetTokenAccounts(ctx, oldWallet.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+
+	var signatures []solana.Signature
+	for start := 0; start < len(accounts); start += tokensPerTransaction {
+		end := start + tokensPerTransaction
+		if end > len(accounts) {
+			end = len(accounts)
+		}
+
+		var instrs []solana.Instruction
+		for _, account := range accounts[start:end] {
+			if account.Amount > 0 {
+				destination, _, err := solana.FindAssociatedTokenAddress(newOwner, account.Mint)
+				if err != nil {
+					return signatures, fmt.Errorf("failed to find associated token address: %w", err)
+				}
+
+				instrs = append(instrs,
+					associated_token_account_extended.NewCreateIdempotentInstruction(
+						oldWallet.PublicKey(),
+						newOwner,
+						account.Mint,
+					).Build(),
+					token.NewTransferCheckedInstruction(
+						account.Amount,
+						account.Decimals,
+						account.Address,
+						account.Mint,
+						destination,
+						oldWallet.PublicKey(),
+						nil,
+					).Build(),
+				)
+			}
+
+			instrs = append(instrs, token.NewCloseAccountInstruction(
+				account.Address,
+				newOwner,
+				oldWallet.PublicKey(),
+				nil,
+			).Build())
+
+			m.logger.Printf("Moving %d units of %s (account %s)", account.Amount, account.Mint, account.Address)
+		}
+
+		sig, err := m.sendAndConfirm(ctx, oldWallet, instrs)
+		if err != nil {
+			return signatures, fmt.Errorf("failed to sweep token accounts: %w", err)
+		}
+		signatures = append(signatures, sig)
+	}
+
+	return signatures, nil
+}
+
+// SweepSol transfers the remaining SOL balance minus the transaction fee to the new owner
+func (m *Migrator) SweepSol(ctx context.Context, oldWallet solana.PrivateKey, newOwner solana.PublicKey) (solana.Signature, uint64, error) {
+	balance, err := m.solClient.GetBalance(ctx, oldWallet.PublicKey(), rpc.CommitmentConfirmed)
+	if err != nil {
+		return solana.Signature{}, 0, fmt.Errorf("failed to get SOL balance: %w", err)
+	}
+
+	if balance.Value <= lamportsPerSignature {
+		return solana.Signature{}, 0, fmt.Errorf("balance of %d lamports does not cover the transfer fee", balance.Value)
+	}
+
+	amount := balance.Value - lamportsPerSignature
+	instrs := []solana.Instruction{
+		system.NewTransferInstruction(amount, oldWallet.PublicKey(), newOwner).Build(),
+	}
+
+	sig, err := m.sendAndConfirm(ctx, oldWallet, instrs)
+	if err != nil {
+		return solana.Signature{}, 0, fmt.Errorf("failed to sweep SOL: %w", err)
+	}
+
+	return sig, amount, nil
+}
+
+// sendAndConfirm signs and sends a transaction, then waits until it is confirmed
+func (m *Migrator) sendAndConfirm(ctx context.Context, payer solana.PrivateKey, instrs []solana.Instruction) (solana.Signature, error) {
+	block, err := m.solClient.GetLatestBlockhash(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to get blockhash: %w", err)
+	}
+
+	tx, err := solana.NewTransaction(
+		instrs,
+		block.Value.Blockhash,
+		solana.TransactionPayer(payer.PublicKey()),
+	)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	if _, err = tx.Sign(
+		func(key solana.PublicKey) *solana.PrivateKey {
+			if payer.PublicKey().Equals(key) {
+				return &payer
+			}
+			return nil
+		},
+	); err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+
+	sig, err := m.solClient.SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{
+		SkipPreflight:       false,
+		PreflightCommitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to send transaction: %w", err)
+	}
+
+	m.logger.Printf("Sent transaction %s, waiting for confirmation...", sig)
+
+	if err := m.waitForConfirmation(ctx, sig, 90*time.Second); err != nil {
+		return sig, err
+	}
+
+	return sig, nil
+}
+
+// waitForConfirmation polls the signature status until it is confirmed, failed or the timeout elapses
+func (m *Migrator) waitForConfirmation(ctx context.Context, sig solana.Signature, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+		statuses, err := m.solClient.GetSignatureStatuses(ctx, true, sig)
+		if err == nil && len(statuses.Value) > 0 && statuses.Value[0] != nil {
+			status := statuses.Value[0]
+			if status.Err != nil {
+				return fmt.Errorf("transaction %s failed: %v", sig, status.Err)
+			}
+			if status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed ||
+				status.ConfirmationStatus == rpc.ConfirmationStatusFinalized {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+
+	return fmt.Errorf("transaction %s was not confirmed within %s", sig, timeout)
+}